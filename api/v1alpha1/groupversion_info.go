@@ -20,4 +20,5 @@ func init() {
 	SchemeBuilder.Register(&ConstraintReport{}, &ConstraintReportList{})
 	SchemeBuilder.Register(&ConstraintProfile{}, &ConstraintProfileList{})
 	SchemeBuilder.Register(&NotificationPolicy{}, &NotificationPolicyList{})
+	SchemeBuilder.Register(&DiscoveryStatus{}, &DiscoveryStatusList{})
 }
@@ -38,6 +38,12 @@ type ConstraintReportStatus struct {
 	// same data as Constraints but in a richer, typed format with structured remediation.
 	MachineReadable *MachineReadableReport `json:"machineReadable,omitempty"`
 
+	// FlowDrops lists the traffic paths to or from this namespace that
+	// policies are dropping, busiest first. Only populated when Hubble flow
+	// observation is enabled.
+	// +optional
+	FlowDrops []FlowDropEntry `json:"flowDrops,omitempty"`
+
 	// LastUpdated is when this report was last reconciled.
 	LastUpdated metav1.Time `json:"lastUpdated"`
 }
@@ -225,6 +231,80 @@ type MissingResourceEntry struct {
 	Remediation RemediationInfo `json:"remediation"`
 }
 
+// FlowDropEntry aggregates the dropped flows of one traffic path: a source
+// and destination workload, a port, and the policy that dropped them.
+type FlowDropEntry struct {
+	// Source is the workload the flows came from.
+	Source FlowEndpoint `json:"source"`
+
+	// Destination is the workload or external address the flows were sent to.
+	Destination FlowEndpoint `json:"destination"`
+
+	// Port is the destination port.
+	Port int32 `json:"port"`
+
+	// Protocol is the L4 protocol (TCP, UDP, ICMPv4, ...).
+	Protocol string `json:"protocol,omitempty"`
+
+	// Direction is INGRESS or EGRESS, the side on which the policy dropped
+	// the flows. Empty when Hubble did not report it.
+	// +optional
+	Direction string `json:"direction,omitempty"`
+
+	// PolicyRef identifies the policy that dropped the flows. Omitted when
+	// the policy is not visible at the report's detail level.
+	// +optional
+	PolicyRef *ObjectReference `json:"policyRef,omitempty"`
+
+	// Count is the number of drops since FirstSeen.
+	Count int64 `json:"count"`
+
+	// RecentCount is the number of drops within the aggregation window.
+	RecentCount int64 `json:"recentCount"`
+
+	// Window is the span of RecentCount (e.g., "1h0m0s").
+	Window string `json:"window"`
+
+	// FirstSeen is when the first drop was observed.
+	FirstSeen metav1.Time `json:"firstSeen"`
+
+	// LastSeen is when the most recent drop was observed.
+	LastSeen metav1.Time `json:"lastSeen"`
+
+	// Samples are a few of the most recent dropped flows, oldest first.
+	// +optional
+	Samples []FlowSample `json:"samples,omitempty"`
+}
+
+// FlowEndpoint identifies one end of a dropped traffic path. Fields in other
+// namespaces are omitted unless the report's detail level allows them.
+type FlowEndpoint struct {
+	Namespace string `json:"namespace,omitempty"`
+
+	// Workload is the owning workload's name, the pod name when it has no
+	// workload, or the IP address of an endpoint outside the cluster.
+	Workload string `json:"workload,omitempty"`
+
+	// Service is the Service the flows were addressed to.
+	Service string `json:"service,omitempty"`
+}
+
+// FlowSample is a single dropped flow.
+type FlowSample struct {
+	Time metav1.Time `json:"time"`
+
+	// TraceID links the sample to its Hubble flow.
+	// +optional
+	TraceID string `json:"traceID,omitempty"`
+
+	SourcePod       string `json:"sourcePod,omitempty"`
+	SourceIP        string `json:"sourceIP,omitempty"`
+	SourcePort      int32  `json:"sourcePort,omitempty"`
+	DestinationPod  string `json:"destinationPod,omitempty"`
+	DestinationIP   string `json:"destinationIP,omitempty"`
+	DestinationPort int32  `json:"destinationPort,omitempty"`
+}
+
 // +kubebuilder:object:root=true
 type ConstraintReportList struct {
 	metav1.TypeMeta `json:",inline"`
@@ -253,7 +333,9 @@ type ConstraintProfileSpec struct {
 
 	// Adapter is the name of the adapter to use for parsing.
 	// Use "generic" for unknown CRDs or the name of a built-in adapter.
-	Adapter string `json:"adapter"`
+	// May be omitted when Plugin is set.
+	// +optional
+	Adapter string `json:"adapter,omitempty"`
 
 	// Enabled controls whether this resource type is watched.
 	// +kubebuilder:default=true
@@ -264,19 +346,42 @@ type ConstraintProfileSpec struct {
 	// +optional
 	Severity string `json:"severity,omitempty"`
 
-	// DebounceSeconds overrides the default debounce interval for notifications.
+	// DebounceSeconds delays parsing an object of this GVR after it changes,
+	// so that repeated updates within the window are parsed once. Overrides
+	// the controller's --discovery-debounce.
 	// +optional
 	DebounceSeconds *int `json:"debounceSeconds,omitempty"`
 
 	// FieldPaths configures custom extraction paths for the generic adapter.
-	// Only used when Adapter is "generic". Each path is dot-delimited
-	// (e.g., "spec.workloadSelector").
+	// Only used when Adapter is "generic". Paths are dot-delimited
+	// (e.g., "spec.workloadSelector") or JSONPath expressions
+	// (e.g., "{.spec.rules[*]}").
 	// +optional
 	FieldPaths *FieldPaths `json:"fieldPaths,omitempty"`
+
+	// Plugin points the profile at an out-of-process adapter plugin. The
+	// controller connects to the plugin's Unix socket and uses it to parse
+	// this GVR. When Adapter is empty, the plugin's adapter name is used.
+	// +optional
+	Plugin *PluginReference `json:"plugin,omitempty"`
+}
+
+// PluginReference identifies an out-of-process adapter plugin.
+type PluginReference struct {
+	// Socket is the path of the plugin's Unix socket inside the controller
+	// pod, typically on a volume shared with a sidecar.
+	// Example: "/var/run/nightjar/plugins/example.sock"
+	// +kubebuilder:validation:MinLength=1
+	Socket string `json:"socket"`
 }
 
 // FieldPaths configures how the generic adapter extracts constraint data from
-// arbitrary CRDs. Each field is a dot-delimited path into the object's structure.
+// arbitrary CRDs. Each field is either a dot-delimited path ("spec.action") or a
+// JSONPath expression using kubectl syntax ("{.spec.metrics[0].name}").
+//
+// When RulesPath is set, the object fans out into one constraint per rule and
+// all other paths are evaluated against each rule. Prefix a path with "$" to
+// evaluate it against the whole object instead (e.g., "$.spec.namespaceSelector").
 type FieldPaths struct {
 	// SelectorPath is the path to the workload label selector.
 	// Example: "spec.workloadSelector"
@@ -297,6 +402,46 @@ type FieldPaths struct {
 	// Example: "spec.description"
 	// +optional
 	SummaryPath string `json:"summaryPath,omitempty"`
+
+	// RulesPath is the path to a list of rules. Each element produces its own
+	// constraint. Example: "{.spec.rules[*]}"
+	// +optional
+	RulesPath string `json:"rulesPath,omitempty"`
+
+	// NamePath is the path to a rule's name, used to name per-rule constraints.
+	// Only used with RulesPath. Example: "name"
+	// +optional
+	NamePath string `json:"namePath,omitempty"`
+
+	// PortsPath is the path to the ports a constraint applies to. Elements may
+	// be numbers, strings, or objects with port/number and protocol fields.
+	// Example: "{.ports[*]}"
+	// +optional
+	PortsPath string `json:"portsPath,omitempty"`
+
+	// SeverityPath is the path to a field whose value determines severity.
+	// Values are translated through SeverityMapping, or used directly when
+	// they are already Critical, Warning or Info.
+	// Example: "enforcement"
+	// +optional
+	SeverityPath string `json:"severityPath,omitempty"`
+
+	// SeverityMapping maps values found at SeverityPath to a severity.
+	// Example: {"Enforce": "Critical", "Audit": "Warning"}
+	// +optional
+	SeverityMapping map[string]string `json:"severityMapping,omitempty"`
+
+	// ConstraintTypePath is the path to a field whose value determines the
+	// constraint type. Values are translated through ConstraintTypeMapping, or
+	// used directly when they are already a known constraint type.
+	// Example: "type"
+	// +optional
+	ConstraintTypePath string `json:"constraintTypePath,omitempty"`
+
+	// ConstraintTypeMapping maps values found at ConstraintTypePath to a
+	// constraint type. Example: {"egress": "NetworkEgress"}
+	// +optional
+	ConstraintTypeMapping map[string]string `json:"constraintTypeMapping,omitempty"`
 }
 
 type GVRReference struct {
@@ -392,3 +537,88 @@ type NotificationPolicyList struct {
 	metav1.ListMeta `json:"metadata,omitempty"`
 	Items           []NotificationPolicy `json:"items"`
 }
+
+// ---
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster,shortName=ds
+// +kubebuilder:printcolumn:name="Watched",type=integer,JSONPath=`.status.watchedCount`
+// +kubebuilder:printcolumn:name="Parse Errors",type=integer,JSONPath=`.status.parseErrorCount`
+// +kubebuilder:printcolumn:name="Dry Run",type=boolean,JSONPath=`.status.dryRun`
+// +kubebuilder:printcolumn:name="Last Scan",type=date,JSONPath=`.status.lastScanTime`
+
+// DiscoveryStatus reports which resource types the discovery engine watches,
+// why, and how parsing them is going. The controller maintains a single
+// instance named "cluster".
+type DiscoveryStatus struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Status DiscoveryStatusStatus `json:"status,omitempty"`
+}
+
+type DiscoveryStatusStatus struct {
+	// DryRun is true when the controller runs with --discovery-dry-run.
+	// Decisions then describe what would be watched; no informers run.
+	DryRun bool `json:"dryRun,omitempty"`
+
+	// WatchedCount is the number of resource types watched (or, in dry-run
+	// mode, that would be watched).
+	WatchedCount int `json:"watchedCount"`
+
+	// ParseErrorCount is the total number of failed parses across all types.
+	ParseErrorCount int64 `json:"parseErrorCount"`
+
+	// LastScanTime is when API discovery last ran.
+	// +optional
+	LastScanTime *metav1.Time `json:"lastScanTime,omitempty"`
+
+	// LastScanComplete is false when the last API discovery returned a
+	// partial result, for example because an aggregated API was unavailable.
+	LastScanComplete bool `json:"lastScanComplete"`
+
+	// Resources lists the decision taken for every served resource type,
+	// plus types registered only through a ConstraintProfile.
+	Resources []DiscoveryDecision `json:"resources,omitempty"`
+}
+
+// DiscoveryDecision explains whether one resource type is watched.
+type DiscoveryDecision struct {
+	Group    string `json:"group,omitempty"`
+	Version  string `json:"version"`
+	Resource string `json:"resource"`
+
+	// Watched is true when the type is (or in dry-run mode would be) watched.
+	Watched bool `json:"watched"`
+
+	// Reason names the rule that decided.
+	// +kubebuilder:validation:Enum=ConstraintProfile;ProfileDisabled;PolicyAnnotation;AdapterRegistered;NativeConstraint;PolicyGroup;NameHint;NoMatch
+	Reason string `json:"reason"`
+
+	// Message describes the decision, e.g. which name hint matched.
+	Message string `json:"message,omitempty"`
+
+	// Confidence is how certain the decision is, from 0 to 100. Explicit
+	// configuration scores 100, name heuristics score lowest.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	Confidence int `json:"confidence"`
+
+	// ParseErrors is the number of objects of this type that failed to parse.
+	ParseErrors int64 `json:"parseErrors,omitempty"`
+
+	// LastParseError is the most recent parse error.
+	LastParseError string `json:"lastParseError,omitempty"`
+
+	// LastParseErrorTime is when LastParseError occurred.
+	// +optional
+	LastParseErrorTime *metav1.Time `json:"lastParseErrorTime,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+type DiscoveryStatusList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DiscoveryStatus `json:"items"`
+}
@@ -99,6 +99,11 @@ func (in *ConstraintProfileSpec) DeepCopyInto(out *ConstraintProfileSpec) {
 	if in.FieldPaths != nil {
 		in, out := &in.FieldPaths, &out.FieldPaths
 		*out = new(FieldPaths)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Plugin != nil {
+		in, out := &in.Plugin, &out.Plugin
+		*out = new(PluginReference)
 		**out = **in
 	}
 }
@@ -186,6 +191,13 @@ func (in *ConstraintReportStatus) DeepCopyInto(out *ConstraintReportStatus) {
 		*out = new(MachineReadableReport)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.FlowDrops != nil {
+		in, out := &in.FlowDrops, &out.FlowDrops
+		*out = make([]FlowDropEntry, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	in.LastUpdated.DeepCopyInto(&out.LastUpdated)
 }
 
@@ -199,9 +211,126 @@ func (in *ConstraintReportStatus) DeepCopy() *ConstraintReportStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DiscoveryDecision) DeepCopyInto(out *DiscoveryDecision) {
+	*out = *in
+	if in.LastParseErrorTime != nil {
+		in, out := &in.LastParseErrorTime, &out.LastParseErrorTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DiscoveryDecision.
+func (in *DiscoveryDecision) DeepCopy() *DiscoveryDecision {
+	if in == nil {
+		return nil
+	}
+	out := new(DiscoveryDecision)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DiscoveryStatus) DeepCopyInto(out *DiscoveryStatus) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DiscoveryStatus.
+func (in *DiscoveryStatus) DeepCopy() *DiscoveryStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DiscoveryStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DiscoveryStatus) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DiscoveryStatusList) DeepCopyInto(out *DiscoveryStatusList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]DiscoveryStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DiscoveryStatusList.
+func (in *DiscoveryStatusList) DeepCopy() *DiscoveryStatusList {
+	if in == nil {
+		return nil
+	}
+	out := new(DiscoveryStatusList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DiscoveryStatusList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DiscoveryStatusStatus) DeepCopyInto(out *DiscoveryStatusStatus) {
+	*out = *in
+	if in.LastScanTime != nil {
+		in, out := &in.LastScanTime, &out.LastScanTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make([]DiscoveryDecision, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DiscoveryStatusStatus.
+func (in *DiscoveryStatusStatus) DeepCopy() *DiscoveryStatusStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DiscoveryStatusStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *FieldPaths) DeepCopyInto(out *FieldPaths) {
 	*out = *in
+	if in.SeverityMapping != nil {
+		in, out := &in.SeverityMapping, &out.SeverityMapping
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ConstraintTypeMapping != nil {
+		in, out := &in.ConstraintTypeMapping, &out.ConstraintTypeMapping
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FieldPaths.
@@ -214,6 +343,68 @@ func (in *FieldPaths) DeepCopy() *FieldPaths {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FlowDropEntry) DeepCopyInto(out *FlowDropEntry) {
+	*out = *in
+	out.Source = in.Source
+	out.Destination = in.Destination
+	if in.PolicyRef != nil {
+		in, out := &in.PolicyRef, &out.PolicyRef
+		*out = new(ObjectReference)
+		**out = **in
+	}
+	in.FirstSeen.DeepCopyInto(&out.FirstSeen)
+	in.LastSeen.DeepCopyInto(&out.LastSeen)
+	if in.Samples != nil {
+		in, out := &in.Samples, &out.Samples
+		*out = make([]FlowSample, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FlowDropEntry.
+func (in *FlowDropEntry) DeepCopy() *FlowDropEntry {
+	if in == nil {
+		return nil
+	}
+	out := new(FlowDropEntry)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FlowEndpoint) DeepCopyInto(out *FlowEndpoint) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FlowEndpoint.
+func (in *FlowEndpoint) DeepCopy() *FlowEndpoint {
+	if in == nil {
+		return nil
+	}
+	out := new(FlowEndpoint)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FlowSample) DeepCopyInto(out *FlowSample) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FlowSample.
+func (in *FlowSample) DeepCopy() *FlowSample {
+	if in == nil {
+		return nil
+	}
+	out := new(FlowSample)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GVRReference) DeepCopyInto(out *GVRReference) {
 	*out = *in
@@ -452,6 +643,21 @@ func (in *ObjectReference) DeepCopy() *ObjectReference {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PluginReference) DeepCopyInto(out *PluginReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PluginReference.
+func (in *PluginReference) DeepCopy() *PluginReference {
+	if in == nil {
+		return nil
+	}
+	out := new(PluginReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RemediationInfo) DeepCopyInto(out *RemediationInfo) {
 	*out = *in
@@ -3,7 +3,10 @@ package main
 import (
 	"context"
 	"flag"
+	"io"
+	"net"
 	"os"
+	"strconv"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -16,59 +19,61 @@ import (
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/metadata"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 
 	v1alpha1 "github.com/nightjarctl/nightjar/api/v1alpha1"
+	"github.com/nightjarctl/nightjar/internal/accesslog"
 	"github.com/nightjarctl/nightjar/internal/adapters"
 	"github.com/nightjarctl/nightjar/internal/adapters/gatekeeper"
+	"github.com/nightjarctl/nightjar/internal/adapters/istio"
 	"github.com/nightjarctl/nightjar/internal/adapters/kyverno"
 	"github.com/nightjarctl/nightjar/internal/adapters/limitrange"
 	"github.com/nightjarctl/nightjar/internal/adapters/networkpolicy"
+	"github.com/nightjarctl/nightjar/internal/adapters/plugin"
 	"github.com/nightjarctl/nightjar/internal/adapters/resourcequota"
 	"github.com/nightjarctl/nightjar/internal/adapters/webhookconfig"
 	internalapi "github.com/nightjarctl/nightjar/internal/api"
+	"github.com/nightjarctl/nightjar/internal/audit"
+	"github.com/nightjarctl/nightjar/internal/config"
 	internalcontroller "github.com/nightjarctl/nightjar/internal/controller"
 	"github.com/nightjarctl/nightjar/internal/correlator"
+	"github.com/nightjarctl/nightjar/internal/dedupe"
 	discoveryengine "github.com/nightjarctl/nightjar/internal/discovery"
+	"github.com/nightjarctl/nightjar/internal/flowstats"
 	"github.com/nightjarctl/nightjar/internal/hubble"
 	"github.com/nightjarctl/nightjar/internal/indexer"
 	"github.com/nightjarctl/nightjar/internal/mcp"
 	"github.com/nightjarctl/nightjar/internal/notifier"
+	"github.com/nightjarctl/nightjar/internal/readiness"
 	"github.com/nightjarctl/nightjar/internal/requirements"
+	"github.com/nightjarctl/nightjar/internal/scope"
+	"github.com/nightjarctl/nightjar/internal/servicemap"
+	"github.com/nightjarctl/nightjar/internal/shard"
+	"github.com/nightjarctl/nightjar/internal/snapshot"
 	"github.com/nightjarctl/nightjar/internal/types"
+	"github.com/nightjarctl/nightjar/internal/workload"
 )
 
 var scheme = runtime.NewScheme()
 
+// leaderElectionID names the leader election Lease.
+const leaderElectionID = "nightjar-leader"
+
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 	utilruntime.Must(v1alpha1.AddToScheme(scheme))
 }
 
 func main() {
-	var (
-		metricsAddr            string
-		healthAddr             string
-		leaderElect            bool
-		rescanInterval         time.Duration
-		hubbleAddr             string
-		hubbleEnabled          bool
-		additionalPolicyGroups string
-		additionalNameHints    string
-		checkCRDAnnotations    bool
-	)
-
-	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
-	flag.StringVar(&healthAddr, "health-probe-bind-address", ":8081", "The address the health probe endpoint binds to.")
-	flag.BoolVar(&leaderElect, "leader-elect", true, "Enable leader election for controller manager.")
-	flag.DurationVar(&rescanInterval, "rescan-interval", 5*time.Minute, "How often to rescan for new CRDs.")
-	flag.StringVar(&hubbleAddr, "hubble-relay-address", "hubble-relay.kube-system.svc:4245", "Hubble Relay gRPC address.")
-	flag.BoolVar(&hubbleEnabled, "hubble-enabled", false, "Enable Hubble flow observation for real-time traffic drop detection.")
-	flag.StringVar(&additionalPolicyGroups, "additional-policy-groups", "", "Comma-separated list of additional API groups to treat as policy sources.")
-	flag.StringVar(&additionalNameHints, "additional-name-hints", "", "Comma-separated list of additional resource name substrings for heuristic detection.")
-	flag.BoolVar(&checkCRDAnnotations, "check-crd-annotations", true, "Check CRDs for nightjar.io/is-policy annotation during discovery scan.")
+	// Flags default to the built-in configuration; a --config file replaces
+	// it, and flags given explicitly override the file.
+	var configFile string
+	cfg := config.Default()
+	flag.StringVar(&configFile, "config", "", "Path of a NightjarConfig file, e.g. mounted from a ConfigMap. It is watched for changes; flags given explicitly override it.")
+	bindFlags(flag.CommandLine, &cfg)
 	flag.Parse()
 
 	// Setup logger
@@ -80,29 +85,38 @@ func main() {
 	}
 	defer logger.Sync()
 
+	var configWatcher *config.Watcher
+	if configFile != "" {
+		configWatcher, err = config.NewWatcher(configFile, logger, config.WatcherOptions{
+			Override: overrideFromFlags,
+		})
+		if err != nil {
+			logger.Fatal("Invalid configuration", zap.Error(err))
+		}
+		cfg = configWatcher.Current()
+	} else if err := cfg.Validate(); err != nil {
+		logger.Fatal("Invalid configuration", zap.Error(err))
+	}
+
 	logger.Info("Starting Nightjar",
 		zap.String("version", "dev"),
-		zap.Bool("leader_elect", leaderElect),
-		zap.Duration("rescan_interval", rescanInterval),
-		zap.Bool("hubble_enabled", hubbleEnabled),
+		zap.String("config", configFile),
+		zap.Bool("leader_elect", cfg.Controller.LeaderElect),
+		zap.Duration("rescan_interval", cfg.Discovery.RescanInterval.Duration),
+		zap.Bool("hubble_enabled", cfg.Hubble.Enabled),
+		zap.Bool("shard_enabled", cfg.Sharding.Enabled),
 	)
 
-	// Build constraint indexer with annotator + report reconciler callbacks.
+	// Build constraint indexer. Components follow it through subscriptions.
 	// Created before the manager so its API handlers can be registered on the
 	// metrics server via ExtraHandlers.
-	var annotatorRef atomic.Pointer[notifier.WorkloadAnnotator]
-	var reportReconcilerRef atomic.Pointer[notifier.ReportReconciler]
+	var engineRef atomic.Pointer[discoveryengine.Engine]
 	idx := indexer.New(func(event indexer.IndexEvent) {
 		logger.Debug("Index event",
 			zap.String("type", event.Type),
+			zap.Uint64("revision", event.Revision),
 			zap.String("constraint", event.Constraint.Name),
 		)
-		if a := annotatorRef.Load(); a != nil {
-			a.OnIndexChange(event)
-		}
-		if rr := reportReconcilerRef.Load(); rr != nil {
-			rr.OnIndexChange(event)
-		}
 	})
 
 	// Build adapter registry
@@ -113,6 +127,22 @@ func main() {
 	mustRegister(logger, registry, webhookconfig.New())
 	mustRegister(logger, registry, gatekeeper.New())
 	mustRegister(logger, registry, kyverno.New())
+	mustRegister(logger, registry, istio.New())
+
+	// Connect out-of-process adapter plugins. Unreachable plugins (e.g. a
+	// sidecar that is still starting) stay pending and are retried.
+	pluginManager := plugin.NewManager(registry, logger, plugin.ManagerOptions{
+		CallTimeout:    cfg.AdapterPlugins.Timeout.Duration,
+		HealthInterval: cfg.AdapterPlugins.HealthInterval.Duration,
+	})
+	for _, socket := range cfg.AdapterPlugins.Sockets {
+		name, err := pluginManager.Acquire(context.Background(), "flag", socket)
+		if err != nil {
+			logger.Warn("Adapter plugin unavailable, will retry", zap.String("socket", socket), zap.Error(err))
+			continue
+		}
+		logger.Info("Adapter plugin registered", zap.String("adapter", name), zap.String("socket", socket))
+	}
 
 	logger.Info("Adapter registry initialized",
 		zap.Int("adapter_count", len(registry.All())),
@@ -121,15 +151,107 @@ func main() {
 
 	// Setup controller-runtime manager with API handlers on the metrics server.
 	// The webhook queries the controller at this address for constraint data.
-	cfg := ctrl.GetConfigOrDie()
-	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+	restConfig := ctrl.GetConfigOrDie()
+
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		logger.Fatal("Failed to create clientset", zap.Error(err))
+	}
+
+	// Build the namespace scope. A nil scope puts every namespace in scope.
+	var nsScope *scope.Scope
+	if cfg.NamespaceScope.ConfigMap != "" {
+		scopeNamespace, scopeName, _ := strings.Cut(cfg.NamespaceScope.ConfigMap, "/")
+		nsScope = scope.New(clientset, logger, scope.Options{
+			Namespace:     scopeNamespace,
+			ConfigMapName: scopeName,
+		})
+	}
+
+	// Join the shard group. The scope is limited to this replica's shard, so
+	// every component that follows the scope follows the shard too; queries
+	// about other shards' namespaces are forwarded to their owner.
+	var (
+		membership *shard.Membership
+		apiShards  *shard.Forwarder
+		mcpShards  *shard.Forwarder
+	)
+	if cfg.Sharding.Enabled {
+		identity, host := podIdentity(logger)
+		membership = shard.New(clientset, logger, shard.Options{
+			Namespace: cfg.Sharding.LeaseNamespace,
+			Group:     cfg.Sharding.Group,
+			Identity:  identity,
+			Host:      host,
+		})
+		if nsScope == nil {
+			nsScope = scope.New(clientset, logger, scope.Options{DisableConfigMap: true})
+		}
+		nsScope.SetPartition(membership)
+		membership.OnChange(nsScope.Repartition)
+		apiShards = shard.NewForwarder(membership, portOf(logger, cfg.Controller.MetricsBindAddress), logger)
+		mcpShards = shard.NewForwarder(membership, mcp.DefaultServerOptions().Port, logger)
+	}
+
+	apiHandlers := internalapi.ExtraHandlers(idx, logger, internalapi.CapabilitiesHandlerOptions{
+		Adapters: internalapi.DefaultAdapters(),
+		Discovery: func() v1alpha1.DiscoveryStatusStatus {
+			if e := engineRef.Load(); e != nil {
+				return e.Status()
+			}
+			return v1alpha1.DiscoveryStatusStatus{}
+		},
+		Scope:  nsScope,
+		Shards: apiShards,
+	})
+
+	// Audit events and Envoy access logs arrive at whichever replica the
+	// Service picks. Each replica relays what it does not own to the owning
+	// shard or, without sharding, to the leader, whose relay server accepts
+	// them. The relay server listens apart from the metrics server and only
+	// serves the controller's own ServiceAccount.
+	var leader *shard.Leader
+	if membership == nil && cfg.Controller.LeaderElect && (cfg.Audit.WebhookAddress != "" || cfg.AccessLog.ALSAddress != "") {
+		identity, host := podIdentity(logger)
+		leader = shard.NewLeader(clientset, podNamespace(), leaderElectionID, shard.Member{Identity: identity, Host: host})
+	}
+	var (
+		relayShards *shard.Forwarder
+		relayServer *shard.RelayServer
+	)
+	if membership != nil || leader != nil {
+		var router shard.Router = membership
+		if membership == nil {
+			router = leader
+		}
+		relayShards = shard.NewForwarder(router, portOf(logger, cfg.Controller.RelayBindAddress), logger)
+		relayShards.SetTokenFile(cfg.Controller.RelayTokenFile)
+		relayServer = shard.NewRelayServer(shard.RelayServerOptions{
+			Address:   cfg.Controller.RelayBindAddress,
+			Client:    clientset,
+			TokenFile: cfg.Controller.RelayTokenFile,
+			Logger:    logger,
+		})
+	}
+	var auditRelay *audit.Relay
+	if relayShards != nil && (cfg.Audit.WebhookAddress != "" || cfg.Audit.File != "") {
+		auditRelay = audit.NewRelay(0, logger)
+		relayServer.Handle(audit.RelayPath, auditRelay)
+	}
+	var accessLogRelay *accesslog.Relay
+	if relayShards != nil && cfg.AccessLog.ALSAddress != "" {
+		accessLogRelay = accesslog.NewRelay(0, logger)
+		relayServer.Handle(accesslog.RelayPath, accessLogRelay)
+	}
+
+	mgr, err := ctrl.NewManager(restConfig, ctrl.Options{
 		Scheme:                 scheme,
-		LeaderElection:         leaderElect,
-		LeaderElectionID:       "nightjar-leader",
-		HealthProbeBindAddress: healthAddr,
+		LeaderElection:         cfg.Controller.LeaderElect,
+		LeaderElectionID:       leaderElectionID,
+		HealthProbeBindAddress: cfg.Controller.HealthProbeBindAddress,
 		Metrics: metricsserver.Options{
-			BindAddress:   metricsAddr,
-			ExtraHandlers: internalapi.ExtraHandlers(idx, logger, internalapi.CapabilitiesHandlerOptions{Adapters: internalapi.DefaultAdapters()}),
+			BindAddress:   cfg.Controller.MetricsBindAddress,
+			ExtraHandlers: apiHandlers,
 		},
 	})
 	if err != nil {
@@ -145,21 +267,16 @@ func main() {
 	}
 
 	// Build clients
-	discoveryClient, err := discovery.NewDiscoveryClientForConfig(cfg)
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(restConfig)
 	if err != nil {
 		logger.Fatal("Failed to create discovery client", zap.Error(err))
 	}
 
-	dynamicClient, err := dynamic.NewForConfig(cfg)
+	dynamicClient, err := dynamic.NewForConfig(restConfig)
 	if err != nil {
 		logger.Fatal("Failed to create dynamic client", zap.Error(err))
 	}
 
-	clientset, err := kubernetes.NewForConfig(cfg)
-	if err != nil {
-		logger.Fatal("Failed to create clientset", zap.Error(err))
-	}
-
 	// Build discovery engine
 	engine := discoveryengine.NewEngine(
 		logger,
@@ -167,24 +284,66 @@ func main() {
 		dynamicClient,
 		registry,
 		idx,
-		rescanInterval,
+		cfg.Discovery.RescanInterval.Duration,
 	)
 
 	// Configure discovery heuristics
-	if additionalPolicyGroups != "" {
-		engine.SetAdditionalGroups(splitCSV(additionalPolicyGroups))
+	if len(cfg.Discovery.AdditionalPolicyGroups) > 0 {
+		engine.SetAdditionalGroups(cfg.Discovery.AdditionalPolicyGroups)
 	}
-	if additionalNameHints != "" {
-		engine.SetAdditionalHints(splitCSV(additionalNameHints))
+	if len(cfg.Discovery.AdditionalNameHints) > 0 {
+		engine.SetAdditionalHints(cfg.Discovery.AdditionalNameHints)
+	}
+	engine.SetCheckAnnotation(cfg.Discovery.CheckCRDAnnotations)
+	engine.SetScope(nsScope)
+	engine.SetScopedCache(cfg.Sharding.Enabled)
+	engine.SetQueueOptions(discoveryengine.QueueOptions{
+		Debounce:  cfg.Discovery.Debounce.Duration,
+		RateLimit: cfg.Discovery.ParseQPS,
+		Burst:     cfg.Discovery.ParseBurst,
+	})
+	if cfg.Discovery.DryRun {
+		logger.Info("Discovery dry run: decisions are reported in DiscoveryStatus/cluster, no informers are started")
+		engine.SetDryRun(true)
+	}
+	engineRef.Store(engine)
+
+	// Restore the index snapshot before anything writes to the index, so
+	// constraints are served at once after a restart. The index stays
+	// stale until the engine has synced and the snapshot is reconciled.
+	var snapshotStore snapshot.Store
+	switch {
+	case cfg.IndexSnapshot.File != "":
+		snapshotStore = snapshot.NewFileStore(cfg.IndexSnapshot.File)
+	case cfg.IndexSnapshot.ConfigMap != "":
+		snapshotNamespace, snapshotName, _ := strings.Cut(cfg.IndexSnapshot.ConfigMap, "/")
+		snapshotStore = snapshot.NewConfigMapStore(clientset, snapshotNamespace, snapshotName)
+	}
+	var persister *snapshot.Persister
+	if snapshotStore != nil {
+		persister = snapshot.New(idx, snapshotStore, engine, logger, snapshot.Options{Interval: cfg.IndexSnapshot.Interval.Duration})
+		restoreCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		if _, err := persister.Restore(restoreCtx); err != nil {
+			logger.Warn("Failed to restore index snapshot, starting empty", zap.Error(err))
+		}
+		cancel()
+	}
+
+	// Hold reconcilers and notifiers until every watched GVR has synced, so
+	// they never act on a partial index. Every replica serves reads from its
+	// own index, so each is ready once that index is complete.
+	gate := readiness.New(idx, engine, logger, readiness.DefaultOptions())
+	if err := mgr.AddReadyzCheck("informers-synced", gate.Check); err != nil {
+		logger.Fatal("Unable to set up informer sync readiness check", zap.Error(err))
 	}
-	engine.SetCheckAnnotation(checkCRDAnnotations)
 
 	// Setup ConstraintProfile reconciler (controller-runtime reconciler because
 	// it watches a Nightjar-owned typed CRD, not external unstructured objects).
 	profileReconciler := &internalcontroller.ConstraintProfileReconciler{
-		Client: mgr.GetClient(),
-		Logger: logger,
-		Engine: engine,
+		Client:  mgr.GetClient(),
+		Logger:  logger,
+		Engine:  engine,
+		Plugins: pluginManager,
 	}
 	if err := profileReconciler.SetupWithManager(mgr); err != nil {
 		logger.Fatal("Failed to set up ConstraintProfile controller", zap.Error(err))
@@ -195,31 +354,148 @@ func main() {
 
 	// Build Hubble client (optional)
 	var hubbleClient *hubble.Client
-	if hubbleEnabled {
-		var clientErr error
-		hubbleClient, clientErr = hubble.NewClient(ctx, hubble.ClientOptions{
-			RelayAddress: hubbleAddr,
+	if cfg.Hubble.Enabled {
+		hubbleOpts := hubble.ClientOptions{
+			RelayAddress: cfg.Hubble.RelayAddress,
 			Logger:       logger,
-		})
+		}
+		if tls := cfg.Hubble.TLS; tls.Enabled {
+			hubbleOpts.TLS = &hubble.TLSOptions{
+				CAFile:     tls.CAFile,
+				CertFile:   tls.CertFile,
+				KeyFile:    tls.KeyFile,
+				ServerName: tls.ServerName,
+			}
+		}
+		var clientErr error
+		hubbleClient, clientErr = hubble.NewClient(ctx, hubbleOpts)
 		if clientErr != nil {
 			logger.Fatal("Failed to create Hubble client", zap.Error(clientErr))
 		}
-		logger.Info("Hubble client created", zap.String("relay_address", hubbleAddr))
+		logger.Info("Hubble client created",
+			zap.String("relay_address", cfg.Hubble.RelayAddress),
+			zap.Bool("tls", cfg.Hubble.TLS.Enabled))
+	}
+
+	// Build access log sources (optional): L7 denials reported by Envoy
+	// proxies over the Access Log Service or in a followed JSON log file
+	var accessLogServer *accesslog.Server
+	var accessLogReader *accesslog.Reader
+	var accessLogs []hubble.FlowSource
+	if cfg.AccessLog.ALSAddress != "" {
+		accessLogServer = accesslog.NewServer(accesslog.ServerOptions{
+			Address: cfg.AccessLog.ALSAddress,
+			Shards:  relayShards,
+			Logger:  logger,
+		})
+		accessLogs = append(accessLogs, accessLogServer)
+	}
+	if accessLogRelay != nil {
+		accessLogs = append(accessLogs, accessLogRelay)
+	}
+	if cfg.AccessLog.File != "" {
+		f, err := os.Open(cfg.AccessLog.File)
+		if err != nil {
+			logger.Fatal("Failed to open access log file", zap.Error(err))
+		}
+		// Follow new lines only; a pipe cannot seek and is read as is,
+		// and only a regular file is reopened when rotated.
+		_, _ = f.Seek(0, io.SeekEnd)
+		var path string
+		if info, err := f.Stat(); err == nil && info.Mode().IsRegular() {
+			path = cfg.AccessLog.File
+		}
+		accessLogReader = accesslog.NewReader(f, accesslog.ReaderOptions{
+			Follow: true,
+			Path:   path,
+			Logger: logger,
+		})
+		accessLogs = append(accessLogs, accessLogReader)
+		logger.Info("Following access log file", zap.String("file", cfg.AccessLog.File))
 	}
 
+	// Build audit log sources (optional): admission denials reported by the
+	// API server's audit webhook backend or in a followed audit log file
+	var auditReceiver *audit.Receiver
+	var auditReader *audit.FileReader
+	var auditSources []audit.DenialSource
+	if cfg.Audit.WebhookAddress != "" {
+		auditReceiver = audit.NewReceiver(audit.ReceiverOptions{
+			Address:      cfg.Audit.WebhookAddress,
+			CertFile:     cfg.Audit.TLSCertFile,
+			KeyFile:      cfg.Audit.TLSKeyFile,
+			ClientCAFile: cfg.Audit.TLSClientCAFile,
+			Shards:       relayShards,
+			Logger:       logger,
+		})
+		auditSources = append(auditSources, auditReceiver)
+	}
+	if cfg.Audit.File != "" {
+		auditReader = audit.NewFileReader(cfg.Audit.File, audit.FileReaderOptions{
+			Shards: relayShards,
+			Logger: logger,
+		})
+		auditSources = append(auditSources, auditReader)
+	}
+	if auditRelay != nil {
+		auditSources = append(auditSources, auditRelay)
+	}
+
+	// Build service map (resolves flow drop destinations to Services) and
+	// flow drop statistics
+	var serviceMap *servicemap.ServiceMap
+	var flowStats *flowstats.Store
+	if hubbleClient != nil || len(accessLogs) > 0 {
+		serviceMap = servicemap.New(clientset, logger)
+		flowStats = flowstats.NewStore(flowstats.StoreOptions{
+			Window:     cfg.Hubble.FlowStats.Window.Duration,
+			MaxEntries: cfg.Hubble.FlowStats.MaxEntries,
+			Logger:     logger,
+		})
+	}
+
+	// Build workload resolver: notifications target the top-level workload
+	// of a Pod, e.g. its Deployment
+	metadataClient, err := metadata.NewForConfig(restConfig)
+	if err != nil {
+		logger.Fatal("Failed to create metadata client", zap.Error(err))
+	}
+	workloads := workload.NewResolver(metadataClient, logger)
+
+	// Persist deduplication state, so a restart or a new leader does not
+	// notify every active issue again. Shard owners share the store.
+	var dedupeStore dedupe.Store
+	switch {
+	case cfg.Dedupe.File != "":
+		dedupeStore = dedupe.NewFileStore(cfg.Dedupe.File)
+	case cfg.Dedupe.ConfigMap != "":
+		dedupeNamespace, dedupeName, _ := strings.Cut(cfg.Dedupe.ConfigMap, "/")
+		dedupeStore = dedupe.NewConfigMapStore(clientset, dedupeNamespace, dedupeName)
+	}
+	dedupeOpts := dedupe.Options{Interval: cfg.Dedupe.Interval.Duration}
+
 	// Build correlator
 	corr := correlator.NewWithOptions(idx, clientset, logger, correlator.CorrelatorOptions{
-		HubbleClient: hubbleClient,
+		HubbleClient:  hubbleClient,
+		AccessLogs:    accessLogs,
+		AuditSources:  auditSources,
+		Scope:         nsScope,
+		ServiceMap:    serviceMap,
+		FlowStats:     flowStats,
+		Workloads:     workloads,
+		DedupeStore:   dedupeStore,
+		DedupeOptions: dedupeOpts,
 	})
 
 	// Build notification dispatcher
-	dispatcherOpts := notifier.DefaultDispatcherOptions()
+	dispatcherOpts := dispatcherOptions(cfg)
 	dispatcher := notifier.NewDispatcher(clientset, logger, dispatcherOpts)
+	dispatcher.SetDedupeStore(dedupeStore, dedupeOpts)
 
 	// Build workload annotator
-	annotatorOpts := notifier.DefaultWorkloadAnnotatorOptions()
+	annotatorOpts := annotatorOptions(cfg)
+	annotatorOpts.Scope = nsScope
 	annotator := notifier.NewWorkloadAnnotator(dynamicClient, idx, logger, annotatorOpts)
-	annotatorRef.Store(annotator)
 
 	// Build requirements evaluator context
 	evalCtx := requirements.NewDynamicEvalContext(dynamicClient)
@@ -237,34 +513,197 @@ func main() {
 	mcpOpts := mcp.DefaultServerOptions()
 	mcpOpts.Logger = logger
 	mcpOpts.Evaluator = mcpEvaluator
+	mcpOpts.Scope = nsScope
+	mcpOpts.Shards = mcpShards
+	mcpOpts.FlowStats = flowStats
 	mcpServer := mcp.NewServer(idx, mcpOpts)
+	forwardIndexEvents(idx, mcpServer.OnIndexChange)
 
 	// Build report reconciler
-	reconcilerOpts := notifier.DefaultReportReconcilerOptions()
+	reconcilerOpts := reconcilerOptions(cfg)
+	reconcilerOpts.Scope = nsScope
+	reconcilerOpts.FlowStats = flowStats
 	reportReconciler := notifier.NewReportReconciler(
 		mgr.GetClient(), idx, logger, reconcilerOpts,
 		reconcilerEvaluator, dynamicClient,
 	)
-	reportReconcilerRef.Store(reportReconciler)
+
+	// Apply notification, annotation and report settings from config file
+	// changes without a restart
+	if configWatcher != nil {
+		configWatcher.OnChange(func(c config.NightjarConfig) {
+			dispatcher.SetOptions(dispatcherOptions(c))
+			annotator.SetOptions(annotatorOptions(c))
+			reportReconciler.SetOptions(reconcilerOptions(c))
+		})
+	}
+
+	// Read paths run on every replica: the scope, discovery, the index and
+	// the APIs and MCP server that serve it. Writers to the cluster (Events,
+	// ConstraintReports, workload annotations, DiscoveryStatus, the index
+	// snapshot) run only on the leader. When sharded, each replica holds
+	// only its own namespaces, so the per-namespace writers run on every
+	// replica for its shard.
+	perShard := cfg.Sharding.Enabled
+
+	// Add runnable to watch the config file for changes
+	if configWatcher != nil {
+		if err := mgr.Add(&runnableFunc{fn: configWatcher.Start, everyReplica: true}); err != nil {
+			logger.Fatal("Failed to add config watcher to manager", zap.Error(err))
+		}
+	}
+
+	// Add runnable to maintain this replica's shard membership
+	if membership != nil {
+		if err := mgr.Add(&runnableFunc{fn: membership.Start, everyReplica: true}); err != nil {
+			logger.Fatal("Failed to add shard membership to manager", zap.Error(err))
+		}
+	}
+
+	// Add runnable to watch the namespace scope ConfigMap and namespace labels.
+	// Components that act per namespace wait for it to sync first. A sharded
+	// scope waits for the shard group, so namespaces are assigned from the
+	// start.
+	if nsScope != nil {
+		if err := mgr.Add(&runnableFunc{fn: func(ctx context.Context) error {
+			if membership != nil && !membership.WaitForSync(ctx) {
+				return nil
+			}
+			return nsScope.Start(ctx)
+		}, everyReplica: true}); err != nil {
+			logger.Fatal("Failed to add namespace scope watcher to manager", zap.Error(err))
+		}
+	}
 
 	// Add runnable to start discovery engine
 	if err := mgr.Add(&runnableFunc{fn: func(ctx context.Context) error {
 		return engine.Start(ctx)
-	}}); err != nil {
+	}, everyReplica: true}); err != nil {
 		logger.Fatal("Failed to add discovery engine to manager", zap.Error(err))
 	}
 
-	// Add runnable to start correlator
+	// Add runnables to reconcile the restored snapshot on every replica and
+	// to save new ones from the leader
+	if persister != nil {
+		if err := mgr.Add(&runnableFunc{fn: persister.Reconcile, everyReplica: true}); err != nil {
+			logger.Fatal("Failed to add index snapshot reconciler to manager", zap.Error(err))
+		}
+		if err := mgr.Add(&runnableFunc{fn: persister.Start}); err != nil {
+			logger.Fatal("Failed to add index snapshot persister to manager", zap.Error(err))
+		}
+	}
+
+	// Add runnable to open the readiness gate once informers have synced
+	if err := mgr.Add(&runnableFunc{fn: gate.Start, everyReplica: true}); err != nil {
+		logger.Fatal("Failed to add readiness gate to manager", zap.Error(err))
+	}
+
+	// Add runnable to publish discovery decisions as DiscoveryStatus/cluster
+	statusPublisher := &internalcontroller.DiscoveryStatusPublisher{
+		Client: mgr.GetClient(),
+		Logger: logger.Named("discovery-status"),
+		Engine: engine,
+	}
+	if err := mgr.Add(&runnableFunc{fn: statusPublisher.Start}); err != nil {
+		logger.Fatal("Failed to add DiscoveryStatus publisher to manager", zap.Error(err))
+	}
+
+	// Add runnable to health-check adapter plugins
 	if err := mgr.Add(&runnableFunc{fn: func(ctx context.Context) error {
+		return pluginManager.Start(ctx)
+	}, everyReplica: true}); err != nil {
+		logger.Fatal("Failed to add adapter plugin manager to manager", zap.Error(err))
+	}
+
+	// Add runnable to watch the Istio mesh outbound traffic policy
+	if cfg.Istio.MeshConfig != "" {
+		meshNamespace, meshName, _ := strings.Cut(cfg.Istio.MeshConfig, "/")
+		meshWatcher := istio.NewMeshConfigWatcher(clientset, idx, logger, istio.MeshConfigOptions{
+			Namespace:     meshNamespace,
+			ConfigMapName: meshName,
+		})
+		if err := mgr.Add(&runnableFunc{fn: func(ctx context.Context) error {
+			return meshWatcher.Start(ctx)
+		}, everyReplica: true}); err != nil {
+			logger.Fatal("Failed to add Istio mesh config watcher to manager", zap.Error(err))
+		}
+	}
+
+	// Add runnable to start the service map alongside the correlator
+	if serviceMap != nil {
+		if err := mgr.Add(&runnableFunc{fn: func(ctx context.Context) error {
+			return serviceMap.Start(ctx)
+		}, everyReplica: perShard}); err != nil {
+			logger.Fatal("Failed to add service map to manager", zap.Error(err))
+		}
+	}
+
+	// Add runnable to expire flow drop statistics
+	if flowStats != nil {
+		if err := mgr.Add(&runnableFunc{fn: flowStats.Start, everyReplica: perShard}); err != nil {
+			logger.Fatal("Failed to add flow drop statistics to manager", zap.Error(err))
+		}
+	}
+
+	// Add runnables to receive access logs. Proxies stream to any ready
+	// replica, so every replica serves the Access Log Service and relays
+	// what it does not own.
+	if accessLogServer != nil {
+		if err := mgr.Add(&runnableFunc{fn: accessLogServer.Start, everyReplica: true}); err != nil {
+			logger.Fatal("Failed to add Access Log Service to manager", zap.Error(err))
+		}
+	}
+	if accessLogReader != nil {
+		if err := mgr.Add(&runnableFunc{fn: accessLogReader.Run, everyReplica: perShard}); err != nil {
+			logger.Fatal("Failed to add access log reader to manager", zap.Error(err))
+		}
+	}
+
+	// Add runnable to serve the items other replicas relay to this one
+	if auditRelay != nil || accessLogRelay != nil {
+		if err := mgr.Add(&runnableFunc{fn: relayServer.Start, everyReplica: true}); err != nil {
+			logger.Fatal("Failed to add relay server to manager", zap.Error(err))
+		}
+	}
+
+	// Add runnables to receive audit events. The API server posts to any
+	// ready replica, so every replica serves the webhook and relays what it
+	// does not own; one reader on the leader follows the file.
+	if auditReceiver != nil {
+		if err := mgr.Add(&runnableFunc{fn: auditReceiver.Start, everyReplica: true}); err != nil {
+			logger.Fatal("Failed to add audit webhook to manager", zap.Error(err))
+		}
+	}
+	if auditReader != nil {
+		if err := mgr.Add(&runnableFunc{fn: auditReader.Run}); err != nil {
+			logger.Fatal("Failed to add audit log reader to manager", zap.Error(err))
+		}
+	}
+
+	// Add runnable to cache the owners of Pods, ReplicaSets and Jobs
+	if err := mgr.Add(&runnableFunc{fn: workloads.Start, everyReplica: perShard}); err != nil {
+		logger.Fatal("Failed to add workload resolver to manager", zap.Error(err))
+	}
+
+	// Add runnable to start correlator. It matches events against the index,
+	// so it starts once the index is complete.
+	if err := mgr.Add(&runnableFunc{fn: func(ctx context.Context) error {
+		if !nsScope.WaitForSync(ctx) || !gate.WaitForReady(ctx) || !workloads.WaitForSync(ctx) {
+			return nil
+		}
 		return corr.Start(ctx)
-	}}); err != nil {
+	}, everyReplica: perShard}); err != nil {
 		logger.Fatal("Failed to add correlator to manager", zap.Error(err))
 	}
 
 	// Add runnable to start workload annotator
 	if err := mgr.Add(&runnableFunc{fn: func(ctx context.Context) error {
+		if !nsScope.WaitForSync(ctx) {
+			return nil
+		}
+		gate.Forward(ctx, annotator.OnIndexChange)
 		return annotator.Start(ctx)
-	}}); err != nil {
+	}, everyReplica: perShard}); err != nil {
 		logger.Fatal("Failed to add workload annotator to manager", zap.Error(err))
 	}
 
@@ -285,12 +724,13 @@ func main() {
 				}
 			}
 		}
-	}}); err != nil {
+	}, everyReplica: perShard}); err != nil {
 		logger.Fatal("Failed to add dispatcher to manager", zap.Error(err))
 	}
 
-	// Add runnable to log flow drop notifications (consumer for Hubble correlation)
-	if hubbleEnabled {
+	// Add runnable to log flow drop notifications (consumer for Hubble and
+	// access log correlation)
+	if cfg.Hubble.Enabled || len(accessLogs) > 0 {
 		if err := mgr.Add(&runnableFunc{fn: func(ctx context.Context) error {
 			for {
 				select {
@@ -300,31 +740,88 @@ func main() {
 					if !ok {
 						return nil
 					}
-					logger.Info("Flow drop correlated",
+					fields := []zap.Field{
 						zap.String("source_pod", notification.SourcePodName),
+						zap.String("source_workload", notification.SourceWorkload),
 						zap.String("dest_pod", notification.DestPodName),
+						zap.String("dest_workload", notification.DestWorkload),
+						zap.Stringers("dest_services", notification.DestServices),
 						zap.String("constraint", notification.Constraint.Name),
+						zap.Bool("policy_match", notification.PolicyMatch),
+						zap.String("direction", string(notification.FlowDrop.Direction)),
 						zap.Uint32("dest_port", notification.DestPort),
 						zap.String("protocol", notification.Protocol),
-					)
+					}
+					if notification.Coalesced > 0 {
+						fields = append(fields, zap.Int("more_drops", notification.Coalesced))
+					}
+					if l7 := notification.FlowDrop.L7; l7 != nil {
+						fields = append(fields,
+							zap.String("source_namespace", notification.SourceNamespace),
+							zap.String("method", l7.Method),
+							zap.String("path", l7.Path),
+							zap.Uint32("response_code", l7.ResponseCode),
+							zap.String("details", l7.Details),
+						)
+					}
+					logger.Info("Flow drop correlated", fields...)
 				}
 			}
-		}}); err != nil {
+		}, everyReplica: perShard}); err != nil {
 			logger.Fatal("Failed to add flow drop consumer to manager", zap.Error(err))
 		}
 	}
 
+	// Add runnable to log admission denial notifications (consumer for audit
+	// log correlation)
+	if len(auditSources) > 0 {
+		if err := mgr.Add(&runnableFunc{fn: func(ctx context.Context) error {
+			for {
+				select {
+				case <-ctx.Done():
+					return nil
+				case notification, ok := <-corr.AdmissionDenialNotifications():
+					if !ok {
+						return nil
+					}
+					d := notification.Denial
+					fields := []zap.Field{
+						zap.String("user", d.User),
+						zap.String("verb", d.Verb),
+						zap.Stringer("object", d.Object),
+						zap.String("constraint", notification.Constraint.Name),
+						zap.Bool("policy_match", notification.PolicyMatch),
+						zap.String("plugin", d.Plugin),
+						zap.String("webhook", d.Webhook),
+						zap.Int32("code", d.Code),
+						zap.String("message", d.Message),
+					}
+					if notification.Coalesced > 0 {
+						fields = append(fields, zap.Int("more_denials", notification.Coalesced))
+					}
+					logger.Info("Admission denial correlated", fields...)
+				}
+			}
+		}, everyReplica: perShard}); err != nil {
+			logger.Fatal("Failed to add admission denial consumer to manager", zap.Error(err))
+		}
+	}
+
 	// Add runnable to start MCP server
 	if err := mgr.Add(&runnableFunc{fn: func(ctx context.Context) error {
 		return mcpServer.Start(ctx)
-	}}); err != nil {
+	}, everyReplica: true}); err != nil {
 		logger.Fatal("Failed to add MCP server to manager", zap.Error(err))
 	}
 
 	// Add runnable to start report reconciler
 	if err := mgr.Add(&runnableFunc{fn: func(ctx context.Context) error {
+		if !nsScope.WaitForSync(ctx) {
+			return nil
+		}
+		gate.Forward(ctx, reportReconciler.OnIndexChange)
 		return reportReconciler.Start(ctx)
-	}}); err != nil {
+	}, everyReplica: perShard}); err != nil {
 		logger.Fatal("Failed to add report reconciler to manager", zap.Error(err))
 	}
 
@@ -332,7 +829,7 @@ func main() {
 	if err := mgr.Add(&runnableFunc{fn: func(ctx context.Context) error {
 		reconcilerEvaluator.StartCleanup(ctx)
 		return nil
-	}}); err != nil {
+	}, everyReplica: perShard}); err != nil {
 		logger.Fatal("Failed to add evaluator cleanup to manager", zap.Error(err))
 	}
 
@@ -351,6 +848,110 @@ func main() {
 	engine.Stop()
 }
 
+// bindFlags registers a flag for each setting of cfg, defaulting to its
+// current value. Parsing the command line into a loaded config therefore
+// overrides only the settings whose flags were given.
+func bindFlags(fs *flag.FlagSet, cfg *config.NightjarConfig) {
+	fs.StringVar(&cfg.Controller.MetricsBindAddress, "metrics-bind-address", cfg.Controller.MetricsBindAddress, "The address the metric endpoint binds to.")
+	fs.StringVar(&cfg.Controller.HealthProbeBindAddress, "health-probe-bind-address", cfg.Controller.HealthProbeBindAddress, "The address the health probe endpoint binds to.")
+	fs.BoolVar(&cfg.Controller.LeaderElect, "leader-elect", cfg.Controller.LeaderElect, "Enable leader election for controller manager.")
+	fs.StringVar(&cfg.Controller.RelayBindAddress, "relay-bind-address", cfg.Controller.RelayBindAddress, "The address replicas relay audit events and access logs to. Requests must be authenticated as the controller's ServiceAccount.")
+	fs.StringVar(&cfg.Controller.RelayTokenFile, "relay-token-file", cfg.Controller.RelayTokenFile, "ServiceAccount token for the nightjar-relay audience, presented to other replicas' relay servers.")
+	fs.DurationVar(&cfg.Discovery.RescanInterval.Duration, "rescan-interval", cfg.Discovery.RescanInterval.Duration, "How often to rescan for new CRDs.")
+	fs.StringVar(&cfg.Hubble.RelayAddress, "hubble-relay-address", cfg.Hubble.RelayAddress, "Hubble Relay gRPC address.")
+	fs.BoolVar(&cfg.Hubble.Enabled, "hubble-enabled", cfg.Hubble.Enabled, "Enable Hubble flow observation for real-time traffic drop detection.")
+	fs.BoolVar(&cfg.Hubble.TLS.Enabled, "hubble-tls-enabled", cfg.Hubble.TLS.Enabled, "Connect to Hubble Relay over TLS.")
+	fs.StringVar(&cfg.Hubble.TLS.CAFile, "hubble-tls-ca-file", cfg.Hubble.TLS.CAFile, "PEM CA bundle that verifies Hubble Relay's certificate. Empty uses the system roots.")
+	fs.StringVar(&cfg.Hubble.TLS.CertFile, "hubble-tls-cert-file", cfg.Hubble.TLS.CertFile, "PEM client certificate for mutual TLS to Hubble Relay. Reloaded when the file changes.")
+	fs.StringVar(&cfg.Hubble.TLS.KeyFile, "hubble-tls-key-file", cfg.Hubble.TLS.KeyFile, "PEM client key for mutual TLS to Hubble Relay. Reloaded when the file changes.")
+	fs.StringVar(&cfg.Hubble.TLS.ServerName, "hubble-tls-server-name", cfg.Hubble.TLS.ServerName, "Name verified in Hubble Relay's certificate. Default: the host of --hubble-relay-address.")
+	fs.DurationVar(&cfg.Hubble.FlowStats.Window.Duration, "hubble-flow-stats-window", cfg.Hubble.FlowStats.Window.Duration, "Span of the rolling flow drop counts per workload, port and policy. Drop paths not seen within it expire.")
+	fs.IntVar(&cfg.Hubble.FlowStats.MaxEntries, "hubble-flow-stats-max-entries", cfg.Hubble.FlowStats.MaxEntries, "Maximum flow drop paths held; the least recently seen is evicted to make room.")
+	fs.StringVar(&cfg.AccessLog.ALSAddress, "access-log-als-address", cfg.AccessLog.ALSAddress, "Listen address of the Envoy gRPC Access Log Service for L7 denial detection, e.g. :8094. Empty disables.")
+	fs.StringVar(&cfg.AccessLog.File, "access-log-file", cfg.AccessLog.File, "JSON Envoy access log file to follow for L7 denials. Empty disables.")
+	fs.StringVar(&cfg.Audit.WebhookAddress, "audit-webhook-address", cfg.Audit.WebhookAddress, "Listen address of the Kubernetes audit webhook backend for admission denial detection, e.g. :8095. Empty disables.")
+	fs.StringVar(&cfg.Audit.TLSCertFile, "audit-webhook-tls-cert-file", cfg.Audit.TLSCertFile, "TLS certificate file of the audit webhook. Requires --audit-webhook-tls-key-file.")
+	fs.StringVar(&cfg.Audit.TLSKeyFile, "audit-webhook-tls-key-file", cfg.Audit.TLSKeyFile, "TLS key file of the audit webhook. Requires --audit-webhook-tls-cert-file.")
+	fs.StringVar(&cfg.Audit.TLSClientCAFile, "audit-webhook-tls-client-ca-file", cfg.Audit.TLSClientCAFile, "CA bundle the API server's client certificate is verified against. Required with TLS.")
+	fs.StringVar(&cfg.Audit.File, "audit-log-file", cfg.Audit.File, "JSON Kubernetes audit log file to follow for admission denials. Empty disables.")
+	fs.Var((*csvFlag)(&cfg.Discovery.AdditionalPolicyGroups), "additional-policy-groups", "Comma-separated list of additional API groups to treat as policy sources.")
+	fs.Var((*csvFlag)(&cfg.Discovery.AdditionalNameHints), "additional-name-hints", "Comma-separated list of additional resource name substrings for heuristic detection.")
+	fs.BoolVar(&cfg.Discovery.CheckCRDAnnotations, "check-crd-annotations", cfg.Discovery.CheckCRDAnnotations, "Check CRDs for nightjar.io/is-policy annotation during discovery scan.")
+	fs.StringVar(&cfg.Istio.MeshConfig, "istio-mesh-config", cfg.Istio.MeshConfig, "Namespace/name of the Istio mesh ConfigMap used to detect outboundTrafficPolicy REGISTRY_ONLY. Empty disables.")
+	fs.Var((*csvFlag)(&cfg.AdapterPlugins.Sockets), "adapter-plugins", "Comma-separated list of Unix socket paths of out-of-process adapter plugins.")
+	fs.DurationVar(&cfg.AdapterPlugins.Timeout.Duration, "adapter-plugin-timeout", cfg.AdapterPlugins.Timeout.Duration, "Timeout for each call to an adapter plugin.")
+	fs.DurationVar(&cfg.AdapterPlugins.HealthInterval.Duration, "adapter-plugin-health-interval", cfg.AdapterPlugins.HealthInterval.Duration, "How often adapter plugins are health-checked and unreachable plugins retried.")
+	fs.DurationVar(&cfg.Discovery.Debounce.Duration, "discovery-debounce", cfg.Discovery.Debounce.Duration, "Delay before parsing a changed object; further changes within the window are coalesced. Overridden per GVR by ConstraintProfile debounceSeconds.")
+	fs.Float64Var(&cfg.Discovery.ParseQPS, "discovery-parse-qps", cfg.Discovery.ParseQPS, "Maximum object parses per second per GVR.")
+	fs.IntVar(&cfg.Discovery.ParseBurst, "discovery-parse-burst", cfg.Discovery.ParseBurst, "Object parses allowed above --discovery-parse-qps in a burst.")
+	fs.BoolVar(&cfg.Discovery.DryRun, "discovery-dry-run", cfg.Discovery.DryRun, "Report which resource types would be watched, and why, without starting informers.")
+	fs.StringVar(&cfg.NamespaceScope.ConfigMap, "namespace-scope-config", cfg.NamespaceScope.ConfigMap, "Namespace/name of the ConfigMap selecting the namespaces Nightjar watches, annotates and reports on. Empty puts every namespace in scope.")
+	fs.StringVar(&cfg.IndexSnapshot.File, "index-snapshot-file", cfg.IndexSnapshot.File, "Path of a file, e.g. on a PersistentVolume, to persist the constraint index to for warm restarts. Empty disables.")
+	fs.StringVar(&cfg.IndexSnapshot.ConfigMap, "index-snapshot-configmap", cfg.IndexSnapshot.ConfigMap, "Namespace/name prefix of ConfigMaps to persist the constraint index to for warm restarts. Empty disables.")
+	fs.DurationVar(&cfg.IndexSnapshot.Interval.Duration, "index-snapshot-interval", cfg.IndexSnapshot.Interval.Duration, "How often the constraint index snapshot is saved.")
+	fs.StringVar(&cfg.Dedupe.File, "dedupe-file", cfg.Dedupe.File, "Path of a file, e.g. on a PersistentVolume, to persist notification deduplication state to across restarts. Empty disables.")
+	fs.StringVar(&cfg.Dedupe.ConfigMap, "dedupe-configmap", cfg.Dedupe.ConfigMap, "Namespace/name prefix of the ConfigMaps to persist notification deduplication state to across restarts and leader changes. Empty disables.")
+	fs.DurationVar(&cfg.Dedupe.Interval.Duration, "dedupe-interval", cfg.Dedupe.Interval.Duration, "How often notification deduplication state is synced with its store.")
+	fs.BoolVar(&cfg.Sharding.Enabled, "shard-enabled", cfg.Sharding.Enabled, "Partition namespaces across the controller replicas by consistent hashing. Each replica watches, indexes and acts on only its own namespaces and forwards queries about others to their owner.")
+	fs.StringVar(&cfg.Sharding.Group, "shard-group", cfg.Sharding.Group, "Name of the shard group; replicas with the same group share the namespaces.")
+	fs.StringVar(&cfg.Sharding.LeaseNamespace, "shard-lease-namespace", cfg.Sharding.LeaseNamespace, "Namespace of the Leases that record shard membership.")
+}
+
+// overrideFromFlags sets the settings of cfg whose flags were given on the
+// command line.
+func overrideFromFlags(cfg *config.NightjarConfig) {
+	fs := flag.NewFlagSet("config", flag.ContinueOnError)
+	bindFlags(fs, cfg)
+	flag.Visit(func(f *flag.Flag) {
+		if fs.Lookup(f.Name) != nil {
+			_ = fs.Set(f.Name, f.Value.String())
+		}
+	})
+}
+
+// csvFlag is a flag.Value holding a comma-separated list.
+type csvFlag []string
+
+func (f *csvFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	return strings.Join(*f, ",")
+}
+
+func (f *csvFlag) Set(s string) error {
+	*f = splitCSV(s)
+	return nil
+}
+
+// dispatcherOptions returns the dispatcher options of cfg.
+func dispatcherOptions(cfg config.NightjarConfig) notifier.DispatcherOptions {
+	return notifier.DispatcherOptions{
+		SuppressDuplicateMinutes: cfg.Notifications.SuppressDuplicateMinutes,
+		RateLimitPerMinute:       cfg.Notifications.RateLimitPerMinute,
+		RemediationContact:       cfg.Notifications.RemediationContact,
+	}
+}
+
+// annotatorOptions returns the workload annotator options of cfg, without
+// a scope.
+func annotatorOptions(cfg config.NightjarConfig) notifier.WorkloadAnnotatorOptions {
+	return notifier.WorkloadAnnotatorOptions{
+		DebounceDuration: cfg.Annotator.Debounce.Duration,
+		Workers:          cfg.Annotator.Workers,
+	}
+}
+
+// reconcilerOptions returns the report reconciler options of cfg, without
+// a scope.
+func reconcilerOptions(cfg config.NightjarConfig) notifier.ReportReconcilerOptions {
+	return notifier.ReportReconcilerOptions{
+		DebounceDuration:   cfg.Reports.Debounce.Duration,
+		DefaultDetailLevel: cfg.Reports.DefaultDetailLevel,
+		DefaultContact:     cfg.Reports.DefaultContact,
+	}
+}
+
 // registerRequirementRules registers all built-in requirement rules on the evaluator.
 func registerRequirementRules(eval *requirements.Evaluator) {
 	eval.RegisterRule(requirements.NewPrometheusMonitorRule())
@@ -359,6 +960,18 @@ func registerRequirementRules(eval *requirements.Evaluator) {
 	eval.RegisterRule(requirements.NewCertIssuerRule())
 }
 
+// forwardIndexEvents subscribes to every index change and passes the events
+// to fn in order for the lifetime of the process. Each subscriber has its own
+// goroutine, so a slow one delays neither the indexer nor the others.
+func forwardIndexEvents(idx *indexer.Indexer, fn func(indexer.IndexEvent)) {
+	sub := idx.Subscribe(types.ConstraintQuery{})
+	go func() {
+		for event := range sub.Events() {
+			fn(event)
+		}
+	}()
+}
+
 // mustRegister registers an adapter or exits on failure.
 func mustRegister(logger *zap.Logger, registry *adapters.Registry, adapter types.Adapter) {
 	if err := registry.Register(adapter); err != nil {
@@ -372,12 +985,67 @@ func mustRegister(logger *zap.Logger, registry *adapters.Registry, adapter types
 // runnableFunc is a helper to convert a function to a controller-runtime Runnable.
 type runnableFunc struct {
 	fn func(context.Context) error
+
+	// everyReplica runs fn on every replica instead of only on the elected
+	// leader. Set it for read paths; anything that writes to the cluster
+	// must stay leader-only.
+	everyReplica bool
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable.
+func (r *runnableFunc) NeedLeaderElection() bool {
+	return !r.everyReplica
 }
 
 func (r *runnableFunc) Start(ctx context.Context) error {
 	return r.fn(ctx)
 }
 
+// podIdentity returns this replica's identity and the host other replicas
+// reach it at: the pod name and IP from the downward API, falling back to
+// the hostname.
+func podIdentity(logger *zap.Logger) (identity, host string) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		logger.Fatal("Failed to read hostname for shard identity", zap.Error(err))
+	}
+	identity, host = os.Getenv("POD_NAME"), os.Getenv("POD_IP")
+	if identity == "" {
+		identity = hostname
+	}
+	if host == "" {
+		host = hostname
+	}
+	return identity, host
+}
+
+// podNamespace returns the namespace this replica runs in, which holds the
+// leader election Lease: POD_NAMESPACE from the downward API, or else the
+// service account's namespace, as controller-runtime reads it.
+func podNamespace() string {
+	if ns := os.Getenv("POD_NAMESPACE"); ns != "" {
+		return ns
+	}
+	data, err := os.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/namespace")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// portOf returns the port of a bind address such as ":8080".
+func portOf(logger *zap.Logger, addr string) int {
+	_, port, err := net.SplitHostPort(addr)
+	if err == nil {
+		var n int
+		if n, err = strconv.Atoi(port); err == nil {
+			return n
+		}
+	}
+	logger.Fatal("Invalid bind address, expected host:port", zap.String("address", addr), zap.Error(err))
+	return 0
+}
+
 // splitCSV splits a comma-separated string into trimmed, non-empty items.
 func splitCSV(s string) []string {
 	var result []string
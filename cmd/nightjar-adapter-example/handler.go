@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/nightjarctl/nightjar/internal/util"
+	"github.com/nightjarctl/nightjar/pkg/adapterplugin"
+)
+
+// egressAllowlistGVR is the example in-house policy CRD this plugin parses.
+var egressAllowlistGVR = schema.GroupVersionResource{
+	Group:    "example.nightjar.io",
+	Version:  "v1",
+	Resource: "egressallowlists",
+}
+
+// handler parses EgressAllowlist objects: each destination in spec.destinations
+// becomes an NetworkEgress constraint allowing traffic from the selected pods.
+//
+//	spec:
+//	  podSelector: {matchLabels: {app: checkout}}
+//	  enforcement: Enforce | Audit
+//	  destinations:
+//	  - name: stripe
+//	    host: api.stripe.com
+//	    ports: [443]
+type handler struct{}
+
+func (handler) Name() string {
+	return "example-egress-allowlist"
+}
+
+func (handler) Handles() []schema.GroupVersionResource {
+	return []schema.GroupVersionResource{egressAllowlistGVR}
+}
+
+func (handler) Parse(_ context.Context, obj *unstructured.Unstructured) ([]adapterplugin.Constraint, error) {
+	if obj.GetKind() != "EgressAllowlist" {
+		return nil, fmt.Errorf("unsupported kind %q", obj.GetKind())
+	}
+	name, namespace := obj.GetName(), obj.GetNamespace()
+
+	destinations := util.SafeNestedSlice(obj.Object, "spec", "destinations")
+	if len(destinations) == 0 {
+		return nil, fmt.Errorf("egressallowlist %s/%s: spec.destinations is empty", namespace, name)
+	}
+
+	severity := "Warning"
+	effect := "restrict"
+	if util.SafeNestedString(obj.Object, "spec", "enforcement") == "Audit" {
+		severity = "Info"
+		effect = "audit"
+	}
+	selector := util.SafeNestedLabelSelector(obj.Object, "spec", "podSelector")
+
+	var affected []string
+	if namespace != "" {
+		affected = []string{namespace}
+	}
+
+	constraints := make([]adapterplugin.Constraint, 0, len(destinations))
+	for i, raw := range destinations {
+		dest, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		host := util.SafeStringFromMap(dest, "host")
+		destName := util.SafeStringFromMap(dest, "name")
+		if destName == "" {
+			destName = fmt.Sprintf("destination-%d", i)
+		}
+
+		var ports []string
+		for _, p := range util.SafeNestedSlice(dest, "ports") {
+			ports = append(ports, fmt.Sprint(p))
+		}
+
+		summary := fmt.Sprintf("Egress allowed only to %s", host)
+		if len(ports) > 0 {
+			summary += " on port(s) " + strings.Join(ports, ", ")
+		}
+
+		constraints = append(constraints, adapterplugin.Constraint{
+			UID:                fmt.Sprintf("%s-%s", obj.GetUID(), destName),
+			Name:               fmt.Sprintf("%s/%s", name, destName),
+			Namespace:          namespace,
+			AffectedNamespaces: affected,
+			WorkloadSelector:   selector,
+			ConstraintType:     "NetworkEgress",
+			Effect:             effect,
+			Severity:           severity,
+			Summary:            summary,
+			RemediationHint:    "Ask the platform team to add your destination to the EgressAllowlist",
+			Details: map[string]interface{}{
+				"host":  host,
+				"ports": ports,
+			},
+			Tags: []string{"network", "egress", "allowlist"},
+		})
+	}
+	return constraints, nil
+}
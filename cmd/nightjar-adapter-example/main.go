@@ -0,0 +1,49 @@
+// Command nightjar-adapter-example is a reference out-of-process adapter
+// plugin. It parses the example EgressAllowlist CRD and serves it over the
+// adapter plugin protocol on a Unix socket. Run it as a sidecar of the
+// controller and pass the socket with --adapter-plugins.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/nightjarctl/nightjar/pkg/adapterplugin"
+)
+
+// version is set at build time.
+var version = "dev"
+
+func main() {
+	var socket string
+	flag.StringVar(&socket, "socket", "/var/run/nightjar/plugins/example.sock", "Unix socket to serve the adapter plugin on.")
+	flag.Parse()
+
+	logConfig := zap.NewProductionConfig()
+	logConfig.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	logger, err := logConfig.Build()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create logger: %v\n", err)
+		os.Exit(1)
+	}
+	defer logger.Sync()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	logger.Info("Starting example adapter plugin",
+		zap.String("socket", socket),
+		zap.String("version", version))
+
+	srv := adapterplugin.NewServer(handler{}, adapterplugin.ServerOptions{AdapterVersion: version})
+	if err := srv.Serve(ctx, socket); err != nil {
+		logger.Fatal("Plugin server error", zap.Error(err))
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+
+	"github.com/nightjarctl/nightjar/pkg/adapterplugin"
+	"github.com/nightjarctl/nightjar/pkg/adapterplugin/conformance"
+)
+
+func loadFixture(t *testing.T, name string) *unstructured.Unstructured {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	obj := &unstructured.Unstructured{}
+	require.NoError(t, yaml.Unmarshal(data, &obj.Object))
+	return obj
+}
+
+// startPlugin serves the example handler on a short socket path; Unix socket
+// paths are limited to ~100 bytes, which t.TempDir() can exceed.
+func startPlugin(t *testing.T) string {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "njp")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	socket := filepath.Join(dir, "example.sock")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- adapterplugin.NewServer(handler{}, adapterplugin.ServerOptions{AdapterVersion: "test"}).Serve(ctx, socket)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(socket)
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	return socket
+}
+
+func TestConformance(t *testing.T) {
+	conformance.Run(t, conformance.Options{
+		Socket:   startPlugin(t),
+		Fixtures: []*unstructured.Unstructured{loadFixture(t, "egressallowlist.yaml")},
+	})
+}
+
+func TestHandler_Parse(t *testing.T) {
+	constraints, err := handler{}.Parse(context.Background(), loadFixture(t, "egressallowlist.yaml"))
+	require.NoError(t, err)
+	require.Len(t, constraints, 2)
+
+	c := constraints[1]
+	assert.Equal(t, "checkout-egress/tax", c.Name)
+	assert.Equal(t, "NetworkEgress", c.ConstraintType)
+	assert.Equal(t, "Warning", c.Severity)
+	assert.Equal(t, "Egress allowed only to tax.example.com on port(s) 443, 8443", c.Summary)
+	assert.Equal(t, "checkout", c.WorkloadSelector.MatchLabels["app"])
+}
+
+func TestHandler_Parse_Errors(t *testing.T) {
+	obj := loadFixture(t, "egressallowlist.yaml")
+	unstructured.RemoveNestedField(obj.Object, "spec", "destinations")
+	_, err := handler{}.Parse(context.Background(), obj)
+	assert.Error(t, err)
+
+	obj.SetKind("Other")
+	_, err = handler{}.Parse(context.Background(), obj)
+	assert.Error(t, err)
+}
@@ -0,0 +1,405 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+
+	"github.com/nightjarctl/nightjar/internal/adapters/cilium"
+	"github.com/nightjarctl/nightjar/internal/adapters/networkpolicy"
+	"github.com/nightjarctl/nightjar/internal/correlator"
+	"github.com/nightjarctl/nightjar/internal/hubble"
+	"github.com/nightjarctl/nightjar/internal/indexer"
+	"github.com/nightjarctl/nightjar/internal/types"
+)
+
+var (
+	dropsFile     string
+	dropsPolicies []string
+	dropsRealTime bool
+	dropsSpeed    float64
+)
+
+// maxDropExamples is the number of example flows listed per policy.
+const maxDropExamples = 3
+
+// policyGVRs are the network policy resources read from the cluster when no
+// policy files are given.
+var policyGVRs = []schema.GroupVersionResource{
+	{Group: "networking.k8s.io", Version: "v1", Resource: "networkpolicies"},
+	{Group: "cilium.io", Version: "v2", Resource: "ciliumnetworkpolicies"},
+	{Group: "cilium.io", Version: "v2", Resource: "ciliumclusterwidenetworkpolicies"},
+}
+
+// policyParser parses a network policy manifest into constraints.
+type policyParser interface {
+	Parse(ctx context.Context, obj *unstructured.Unstructured) ([]types.Constraint, error)
+}
+
+// policyParsers maps the policy kinds to the adapters that parse them.
+var policyParsers = map[string]policyParser{
+	"NetworkPolicy":                  networkpolicy.New(),
+	"CiliumNetworkPolicy":            cilium.New(),
+	"CiliumClusterwideNetworkPolicy": cilium.New(),
+}
+
+func dropsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "drops",
+		Short: "Explain which policies caused the drops in a flow capture",
+		Long: `Replay a recorded flow capture and correlate its policy drops with
+network policies, without a running controller.
+
+The capture is the output of 'hubble observe -o jsonpb' or a Cilium flow
+export file. Policies are read from the given manifests, or from the cluster
+when --policies is not set.
+
+Examples:
+  # Analyze a capture against the cluster's policies
+  hubble observe --verdict DROPPED -o jsonpb > capture.json
+  nightjar drops -f capture.json
+
+  # Analyze offline against policy manifests
+  nightjar drops -f capture.json --policies ./policies/
+
+  # Read the capture from stdin, replaying at twice the recorded pace
+  cat capture.json | nightjar drops -f - --real-time --speed 2`,
+		RunE: runDrops,
+	}
+
+	cmd.Flags().StringVarP(&dropsFile, "filename", "f", "", "Flow capture to analyze, or - for stdin (required)")
+	cmd.Flags().StringSliceVar(&dropsPolicies, "policies", nil, "Policy manifest files or directories (default: read from the cluster)")
+	cmd.Flags().BoolVar(&dropsRealTime, "real-time", false, "Replay flows at their recorded pace instead of as fast as possible")
+	cmd.Flags().Float64Var(&dropsSpeed, "speed", 1, "Playback speed multiplier for --real-time")
+	cmd.MarkFlagRequired("filename")
+
+	return cmd
+}
+
+func runDrops(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var in io.Reader = os.Stdin
+	if dropsFile != "-" {
+		f, err := os.Open(dropsFile)
+		if err != nil {
+			return fmt.Errorf("failed to open capture: %w", err)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	var (
+		constraints []types.Constraint
+		err         error
+	)
+	if len(dropsPolicies) > 0 {
+		constraints, err = loadPolicyFiles(ctx, dropsPolicies)
+	} else {
+		constraints, err = loadClusterPolicies(ctx)
+	}
+	if err != nil {
+		return err
+	}
+
+	result, err := analyzeDrops(ctx, in, constraints, hubble.ReplayOptions{
+		RealTime: dropsRealTime,
+		Speed:    dropsSpeed,
+	})
+	if err != nil {
+		return err
+	}
+
+	return outputResult(result, outputFmt)
+}
+
+// analyzeDrops replays the capture in r through a correlator indexing the
+// given constraints and summarizes the drops per policy.
+func analyzeDrops(ctx context.Context, r io.Reader, constraints []types.Constraint, opts hubble.ReplayOptions) (DropsResult, error) {
+	idx := indexer.New(nil)
+	for _, c := range constraints {
+		idx.Upsert(c)
+	}
+
+	// Count the policy drops on their way to the correlator, which reports
+	// only the ones it can attribute to a policy.
+	replayer := hubble.NewReplayer(r, opts)
+	source := &countingSource{in: replayer.DroppedFlows(), out: make(chan hubble.FlowDrop)}
+	c := correlator.NewWithOptions(idx, nil, zap.NewNop(), correlator.CorrelatorOptions{
+		FlowSource: source,
+		Replay:     true,
+	})
+
+	runErr := make(chan error, 1)
+	go func() { runErr <- replayer.Run(ctx) }()
+	go source.run(ctx)
+	go c.CorrelateFlows(ctx)
+
+	// A policy yields a constraint per direction and per spec, so a drop
+	// can match several constraints of one policy; it is counted once.
+	byPolicy := map[string]*PolicyDropInfo{}
+	correlated := map[string]bool{}
+	counted := map[string]bool{}
+	for n := range c.FlowDropNotifications() {
+		drop := dropKey(n.FlowDrop)
+		correlated[drop] = true
+
+		kind := policyKind(n.Constraint)
+		key := kind + "/" + n.Constraint.Namespace + "/" + n.Constraint.Name
+		if counted[drop+"|"+key] {
+			continue
+		}
+		counted[drop+"|"+key] = true
+
+		info, ok := byPolicy[key]
+		if !ok {
+			info = &PolicyDropInfo{Name: n.Constraint.Name, Namespace: n.Constraint.Namespace, Kind: kind}
+			byPolicy[key] = info
+		}
+		info.Drops++
+		if n.PolicyMatch {
+			info.PolicyMatches++
+		}
+		if len(info.Examples) < maxDropExamples {
+			info.Examples = append(info.Examples, dropExample(n))
+		}
+	}
+	if err := <-runErr; err != nil {
+		return DropsResult{}, err
+	}
+	if err := ctx.Err(); err != nil {
+		return DropsResult{}, fmt.Errorf("replay interrupted: %w", err)
+	}
+
+	stats := replayer.Stats()
+	result := DropsResult{
+		Flows:        int(stats.Flows),
+		Drops:        int(stats.Drops),
+		PolicyDrops:  source.policyDrops,
+		Uncorrelated: source.policyDrops - len(correlated),
+		InvalidLines: int(stats.Invalid),
+		Policies:     make([]PolicyDropInfo, 0, len(byPolicy)),
+	}
+	for _, info := range byPolicy {
+		result.Policies = append(result.Policies, *info)
+	}
+	sort.Slice(result.Policies, func(i, j int) bool {
+		a, b := result.Policies[i], result.Policies[j]
+		if a.Drops != b.Drops {
+			return a.Drops > b.Drops
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+	return result, nil
+}
+
+// countingSource forwards flow drops and counts the policy drops among them.
+type countingSource struct {
+	in          <-chan hubble.FlowDrop
+	out         chan hubble.FlowDrop
+	policyDrops int // read after out is closed
+}
+
+func (s *countingSource) DroppedFlows() <-chan hubble.FlowDrop {
+	return s.out
+}
+
+func (s *countingSource) run(ctx context.Context) {
+	defer close(s.out)
+	for drop := range s.in {
+		if drop.DropReason.IsPolicyDrop() {
+			s.policyDrops++
+		}
+		select {
+		case s.out <- drop:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// dropKey identifies a drop across the notifications it produced.
+func dropKey(drop hubble.FlowDrop) string {
+	if drop.TraceID != "" {
+		return drop.TraceID
+	}
+	return fmt.Sprintf("%s/%s/%s/%s/%d/%d", drop.Time, drop.Source.Namespace, drop.Source.PodName,
+		drop.Destination.PodName, drop.L4.SourcePort, drop.L4.DestinationPort)
+}
+
+// policyKind returns the kind of the policy a constraint was parsed from.
+func policyKind(c types.Constraint) string {
+	if c.RawObject != nil && c.RawObject.GetKind() != "" {
+		return c.RawObject.GetKind()
+	}
+	return c.Source.Resource
+}
+
+func dropExample(n correlator.FlowDropNotification) DropExample {
+	return DropExample{
+		Time:        n.FlowDrop.Time,
+		Source:      podRef(n.SourceNamespace, n.SourcePodName, n.FlowDrop.IP.Source),
+		Destination: podRef(n.DestNamespace, n.DestPodName, n.FlowDrop.IP.Destination),
+		Port:        n.DestPort,
+		Protocol:    n.Protocol,
+		Direction:   string(n.FlowDrop.Direction),
+	}
+}
+
+// podRef formats an endpoint as namespace/pod, or its IP outside the cluster.
+func podRef(namespace, pod, ip string) string {
+	if pod == "" {
+		return ip
+	}
+	return namespace + "/" + pod
+}
+
+// loadPolicyFiles parses the network policies in the given manifest files and
+// directories. Other resources in the manifests are ignored.
+func loadPolicyFiles(ctx context.Context, paths []string) ([]types.Constraint, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read policies: %w", err)
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read policies: %w", err)
+		}
+		for _, e := range entries {
+			switch filepath.Ext(e.Name()) {
+			case ".yaml", ".yml", ".json":
+				if !e.IsDir() {
+					files = append(files, filepath.Join(path, e.Name()))
+				}
+			}
+		}
+	}
+
+	var constraints []types.Constraint
+	for _, file := range files {
+		objs, err := decodeManifests(file)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range objs {
+			if obj.GetNamespace() == "" && obj.GetKind() != "CiliumClusterwideNetworkPolicy" {
+				obj.SetNamespace("default")
+			}
+			parsed, err := parsePolicy(ctx, obj)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", file, err)
+			}
+			constraints = append(constraints, parsed...)
+		}
+	}
+	return constraints, nil
+}
+
+// decodeManifests reads the objects in a YAML or JSON file, expanding lists.
+func decodeManifests(file string) ([]*unstructured.Unstructured, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policies: %w", err)
+	}
+	defer f.Close()
+
+	var objs []*unstructured.Unstructured
+	decoder := utilyaml.NewYAMLOrJSONDecoder(f, 4096)
+	for {
+		var doc map[string]interface{}
+		if err := decoder.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				return objs, nil
+			}
+			return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+		}
+		if len(doc) == 0 {
+			continue
+		}
+		obj := &unstructured.Unstructured{Object: doc}
+		if !obj.IsList() {
+			objs = append(objs, obj)
+			continue
+		}
+		list, err := obj.ToList()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+		}
+		for i := range list.Items {
+			objs = append(objs, &list.Items[i])
+		}
+	}
+}
+
+// loadClusterPolicies parses the network policies in the cluster. Policy
+// kinds whose CRDs are not installed are skipped.
+func loadClusterPolicies(ctx context.Context) ([]types.Constraint, error) {
+	client, err := getClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client: %w", err)
+	}
+
+	var constraints []types.Constraint
+	for _, gvr := range policyGVRs {
+		list, err := client.Resource(gvr).Namespace("").List(ctx, metav1.ListOptions{})
+		if err != nil {
+			if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to list %s: %w", gvr.Resource, err)
+		}
+		for i := range list.Items {
+			parsed, err := parsePolicy(ctx, &list.Items[i])
+			if err != nil {
+				return nil, err
+			}
+			constraints = append(constraints, parsed...)
+		}
+	}
+	return constraints, nil
+}
+
+// parsePolicy parses a network policy with its adapter. Each constraint gets
+// a UID of its own, as the adapters share the object's UID between the
+// ingress and egress constraints of a policy and manifests have none.
+func parsePolicy(ctx context.Context, obj *unstructured.Unstructured) ([]types.Constraint, error) {
+	parser, ok := policyParsers[obj.GetKind()]
+	if !ok {
+		return nil, nil
+	}
+	constraints, err := parser.Parse(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
+	for i := range constraints {
+		constraints[i].UID = k8stypes.UID(fmt.Sprintf("%s/%s/%s#%d",
+			strings.ToLower(obj.GetKind()), obj.GetNamespace(), obj.GetName(), i))
+	}
+	return constraints, nil
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+// dropsCapture holds two drops Hubble attributed to a policy, one matched
+// by selector, one without any policy, and one non-policy drop.
+const dropsCapture = `{"flow":{"time":"2026-03-02T10:00:00Z","uuid":"ingress","verdict":"DROPPED","IP":{"source":"10.0.1.5","destination":"10.0.2.9"},"l4":{"TCP":{"source_port":43210,"destination_port":5432}},"source":{"namespace":"web","labels":["k8s:app=frontend"],"pod_name":"frontend-0"},"destination":{"namespace":"db","labels":["k8s:app=postgres"],"pod_name":"postgres-0"},"drop_reason_desc":"POLICY_DENIED","traffic_direction":"INGRESS","ingress_denied_by":[{"name":"db-ingress","namespace":"db","labels":["k8s:io.cilium.k8s.policy.derived-from=CiliumNetworkPolicy"]}]},"node_name":"node-a"}
+{"flow":{"time":"2026-03-02T10:00:01Z","uuid":"egress","verdict":"DROPPED","l4":{"UDP":{"source_port":5353,"destination_port":53}},"source":{"namespace":"web","labels":["k8s:app=frontend"],"pod_name":"frontend-0"},"destination":{"namespace":"kube-system","pod_name":"coredns-0"},"drop_reason_desc":"POLICY_DENIED","traffic_direction":"EGRESS","egress_denied_by":[{"name":"web-egress","namespace":"web","labels":["k8s:io.cilium.k8s.policy.derived-from=NetworkPolicy"]}]},"node_name":"node-a"}
+{"flow":{"time":"2026-03-02T10:00:02Z","uuid":"selector","verdict":"DROPPED","l4":{"TCP":{"source_port":43211,"destination_port":5432}},"source":{"namespace":"web","labels":["k8s:app=frontend"],"pod_name":"frontend-1"},"destination":{"namespace":"db","labels":["k8s:app=postgres"],"pod_name":"postgres-0"},"drop_reason_desc":"POLICY_DENY","traffic_direction":"INGRESS"},"node_name":"node-a"}
+{"flow":{"time":"2026-03-02T10:00:03Z","uuid":"unmatched","verdict":"DROPPED","source":{"namespace":"web","pod_name":"frontend-0"},"destination":{"namespace":"batch","labels":["k8s:app=worker"],"pod_name":"worker-0"},"drop_reason_desc":"POLICY_DENIED","traffic_direction":"INGRESS"},"node_name":"node-a"}
+{"flow":{"time":"2026-03-02T10:00:04Z","uuid":"no-map","verdict":"DROPPED","source":{"namespace":"web","pod_name":"frontend-0"},"destination":{"namespace":"db","pod_name":"postgres-0"},"drop_reason_desc":"CT_NO_MAP_FOUND"},"node_name":"node-a"}
+`
+
+const dbPolicies = `apiVersion: cilium.io/v2
+kind: CiliumNetworkPolicy
+metadata:
+  name: db-ingress
+  namespace: db
+spec:
+  endpointSelector:
+    matchLabels:
+      app: postgres
+  ingress:
+  - fromEndpoints:
+    - matchLabels:
+        app: api
+  egress:
+  - toEntities:
+    - kube-apiserver
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: ignored
+data:
+  key: value
+`
+
+const webPolicies = `{"apiVersion": "v1", "kind": "List", "items": [
+  {"apiVersion": "networking.k8s.io/v1", "kind": "NetworkPolicy",
+   "metadata": {"name": "web-egress", "namespace": "web"},
+   "spec": {"podSelector": {"matchLabels": {"app": "frontend"}}, "policyTypes": ["Egress"]}}
+]}`
+
+func writePolicies(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "db.yaml"), []byte(dbPolicies), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "web.json"), []byte(webPolicies), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("not a manifest"), 0o600))
+	return dir
+}
+
+// runDropsJSON runs cmd, which must be created before the flag variables
+// are set, and decodes its JSON output.
+func runDropsJSON(t *testing.T, cmd *cobra.Command) DropsResult {
+	t.Helper()
+	outputFmt = "json"
+	output := captureStdout(t, func() {
+		require.NoError(t, runDrops(cmd, nil))
+	})
+	var result DropsResult
+	require.NoError(t, json.Unmarshal([]byte(output), &result))
+	return result
+}
+
+func TestRunDrops_PolicyFiles(t *testing.T) {
+	cmd := dropsCmd()
+	capture := filepath.Join(t.TempDir(), "capture.json")
+	require.NoError(t, os.WriteFile(capture, []byte(dropsCapture), 0o600))
+	dropsFile, dropsPolicies = capture, []string{writePolicies(t)}
+	t.Cleanup(func() { dropsFile, dropsPolicies = "", nil })
+
+	result := runDropsJSON(t, cmd)
+
+	assert.Equal(t, 5, result.Flows)
+	assert.Equal(t, 5, result.Drops)
+	assert.Equal(t, 4, result.PolicyDrops)
+	assert.Equal(t, 1, result.Uncorrelated)
+	require.Len(t, result.Policies, 2)
+
+	db := result.Policies[0]
+	assert.Equal(t, "db-ingress", db.Name)
+	assert.Equal(t, "db", db.Namespace)
+	assert.Equal(t, "CiliumNetworkPolicy", db.Kind)
+	assert.Equal(t, 2, db.Drops, "the ingress drop is counted once, not per constraint")
+	assert.Equal(t, 1, db.PolicyMatches)
+	require.Len(t, db.Examples, 2)
+	assert.Equal(t, "web/frontend-0", db.Examples[0].Source)
+	assert.Equal(t, "db/postgres-0", db.Examples[0].Destination)
+	assert.Equal(t, uint32(5432), db.Examples[0].Port)
+	assert.Equal(t, "INGRESS", db.Examples[0].Direction)
+
+	web := result.Policies[1]
+	assert.Equal(t, "web-egress", web.Name)
+	assert.Equal(t, "NetworkPolicy", web.Kind)
+	assert.Equal(t, 1, web.Drops)
+	assert.Equal(t, 1, web.PolicyMatches)
+}
+
+func TestRunDrops_Stdin(t *testing.T) {
+	cmd := dropsCmd()
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	_, err = w.WriteString(dropsCapture)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	origStdin := os.Stdin
+	os.Stdin = r
+	t.Cleanup(func() { os.Stdin = origStdin })
+
+	dropsFile, dropsPolicies = "-", []string{filepath.Join(writePolicies(t), "web.json")}
+	t.Cleanup(func() { dropsFile, dropsPolicies = "", nil })
+
+	result := runDropsJSON(t, cmd)
+	assert.Equal(t, 3, result.Uncorrelated)
+	require.Len(t, result.Policies, 1)
+	assert.Equal(t, "web-egress", result.Policies[0].Name)
+}
+
+func TestRunDrops_ClusterPolicies(t *testing.T) {
+	cmd := dropsCmd()
+	s := runtime.NewScheme()
+	listKinds := map[schema.GroupVersionResource]string{}
+	for _, gvr := range policyGVRs {
+		listKinds[gvr] = "PolicyList"
+	}
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(s, listKinds)
+	np := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "networking.k8s.io/v1",
+		"kind":       "NetworkPolicy",
+		"metadata":   map[string]interface{}{"name": "web-egress", "namespace": "web"},
+		"spec": map[string]interface{}{
+			"podSelector": map[string]interface{}{},
+			"policyTypes": []interface{}{"Egress"},
+		},
+	}}
+	_, err := client.Resource(policyGVRs[0]).Namespace("web").Create(context.Background(), np, metav1.CreateOptions{})
+	require.NoError(t, err)
+	setFakeClient(t, client)
+
+	capture := filepath.Join(t.TempDir(), "capture.json")
+	require.NoError(t, os.WriteFile(capture, []byte(dropsCapture), 0o600))
+	dropsFile, dropsPolicies = capture, nil
+	t.Cleanup(func() { dropsFile = "" })
+
+	result := runDropsJSON(t, cmd)
+	require.Len(t, result.Policies, 1)
+	assert.Equal(t, "web-egress", result.Policies[0].Name)
+	assert.Equal(t, 3, result.Uncorrelated)
+}
+
+func TestRunDrops_Errors(t *testing.T) {
+	cmd := dropsCmd()
+	dropsFile, dropsPolicies = filepath.Join(t.TempDir(), "missing.json"), []string{writePolicies(t)}
+	t.Cleanup(func() { dropsFile, dropsPolicies = "", nil })
+	assert.ErrorContains(t, runDrops(cmd, nil), "failed to open capture")
+
+	dropsFile = "-"
+	invalid := filepath.Join(t.TempDir(), "invalid.yaml")
+	require.NoError(t, os.WriteFile(invalid, []byte("kind: NetworkPolicy\nmetadata:\n  name: broken\n"), 0o600))
+	dropsPolicies = []string{invalid}
+	assert.ErrorContains(t, runDrops(cmd, nil), "missing spec")
+}
+
+func TestOutputDropsTable(t *testing.T) {
+	output := captureStdout(t, func() {
+		require.NoError(t, outputTable(DropsResult{
+			Flows: 10, Drops: 4, PolicyDrops: 3, Uncorrelated: 1,
+			Policies: []PolicyDropInfo{{
+				Name: "db-ingress", Namespace: "db", Kind: "CiliumNetworkPolicy", Drops: 2, PolicyMatches: 1,
+				Examples: []DropExample{{Source: "web/frontend-0", Destination: "db/postgres-0", Port: 5432, Protocol: "TCP"}},
+			}},
+		}))
+	})
+
+	assert.Contains(t, output, "UNCORRELATED:")
+	lines := strings.Split(output, "\n")
+	var row string
+	for _, l := range lines {
+		if strings.HasPrefix(l, "CiliumNetworkPolicy") {
+			row = l
+		}
+	}
+	assert.Contains(t, row, "db-ingress")
+	assert.Contains(t, row, "policy+selector")
+	assert.Contains(t, row, "web/frontend-0 -> db/postgres-0:5432/TCP")
+}
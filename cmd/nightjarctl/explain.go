@@ -6,10 +6,19 @@ import (
 	"strings"
 
 	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+
+	"github.com/nightjarctl/nightjar/internal/adapters/istio"
 )
 
+// serviceEntryGVRs are the ServiceEntry versions the istio adapter indexes,
+// newest first.
+var serviceEntryGVRs = istio.New().Handles()
+
 var (
 	explainNamespace string
 	explainWorkload  string
@@ -63,6 +72,12 @@ func runExplain(cmd *cobra.Command, args []string) error {
 	// Extract all constraints
 	constraints := extractConstraints(report, "", "", "")
 
+	// Istio REGISTRY_ONLY egress failures are not caused by any NetworkPolicy,
+	// so they get a dedicated explanation naming the unregistered host.
+	if result, ok := explainRegistryOnly(ctx, client, errorMessage, constraints); ok {
+		return outputResult(result, outputFmt)
+	}
+
 	// Match error to constraints
 	matchingConstraints, confidence, explanation := matchError(errorMessage, constraints)
 
@@ -163,3 +178,104 @@ func matchError(errorMessage string, constraints []ConstraintInfo) ([]Constraint
 
 	return matches, confidence, explanation
 }
+
+// explainRegistryOnly explains an egress failure caused by the Istio mesh
+// running with outboundTrafficPolicy REGISTRY_ONLY. It returns false when the
+// mesh is not REGISTRY_ONLY, the error does not look like a BlackHoleCluster
+// drop, or the called host is already registered by a visible ServiceEntry.
+func explainRegistryOnly(ctx context.Context, client dynamic.Interface, errorMessage string, constraints []ConstraintInfo) (ExplainResult, bool) {
+	var meshPolicy *ConstraintInfo
+	for i := range constraints {
+		if istio.IsOutboundPolicy(constraints[i].Tags) {
+			meshPolicy = &constraints[i]
+			break
+		}
+	}
+	if meshPolicy == nil || !istio.IsRegistryOnlySymptom(errorMessage) {
+		return ExplainResult{}, false
+	}
+
+	result := ExplainResult{
+		ErrorMessage:        errorMessage,
+		Confidence:          "high",
+		MatchingConstraints: []ConstraintInfo{*meshPolicy},
+	}
+
+	host, port := istio.ExtractExternalHost(errorMessage)
+	if host == "" {
+		result.Confidence = "medium"
+		result.Explanation = "The mesh outboundTrafficPolicy is REGISTRY_ONLY, so calls to hosts without a ServiceEntry " +
+			"are sent to BlackHoleCluster (502). Add a ServiceEntry for the host your workload calls."
+		if meshPolicy.Remediation != nil {
+			result.RemediationSteps = meshPolicy.Remediation.Steps
+		}
+		return result, true
+	}
+
+	registered, err := hostRegistered(ctx, client, host, explainNamespace)
+	if err != nil {
+		// Without ServiceEntry visibility we cannot rule out that the host is registered.
+		result.Confidence = "medium"
+	} else if registered {
+		return ExplainResult{}, false
+	}
+
+	result.Explanation = fmt.Sprintf("Host %s is not registered; add a ServiceEntry. "+
+		"The mesh outboundTrafficPolicy is REGISTRY_ONLY, so calls to hosts without a ServiceEntry "+
+		"are sent to BlackHoleCluster (502). No NetworkPolicy is involved.", host)
+	result.RemediationSteps = []RemediationStep{
+		{
+			Type:              "yaml_patch",
+			Description:       fmt.Sprintf("Create a ServiceEntry registering %s", host),
+			Template:          istio.ServiceEntryTemplate(host, port, explainNamespace),
+			RequiresPrivilege: "namespace-admin",
+		},
+		{
+			Type:              "kubectl",
+			Description:       "List ServiceEntries visible to your namespace",
+			Command:           "kubectl get serviceentries -A",
+			RequiresPrivilege: "developer",
+		},
+	}
+	return result, true
+}
+
+// hostRegistered reports whether any ServiceEntry visible to namespace
+// registers host.
+func hostRegistered(ctx context.Context, client dynamic.Interface, host, namespace string) (bool, error) {
+	list, err := listServiceEntries(ctx, client)
+	if err != nil {
+		return false, err
+	}
+
+	adapter := istio.New()
+	for i := range list.Items {
+		parsed, err := adapter.Parse(ctx, &list.Items[i])
+		if err != nil {
+			continue
+		}
+		for _, c := range parsed {
+			if istio.VisibleTo(c, namespace) && istio.HostMatches(host, istio.RegisteredHosts(c)) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// listServiceEntries lists ServiceEntries at the newest version the API
+// server serves; Istio releases before 1.22 do not serve v1.
+func listServiceEntries(ctx context.Context, client dynamic.Interface) (*unstructured.UnstructuredList, error) {
+	var err error
+	for _, gvr := range serviceEntryGVRs {
+		var list *unstructured.UnstructuredList
+		list, err = client.Resource(gvr).List(ctx, metav1.ListOptions{})
+		if err == nil {
+			return list, nil
+		}
+		if !apierrors.IsNotFound(err) {
+			break
+		}
+	}
+	return nil, fmt.Errorf("failed to list ServiceEntries: %w", err)
+}
@@ -12,6 +12,7 @@
 //	nightjar check -f manifest.yaml
 //	nightjar remediate -n my-namespace my-constraint
 //	nightjar status
+//	nightjar drops -f capture.json
 package main
 
 import (
@@ -46,6 +47,7 @@ providing structured JSON output that matches MCP response schemas.`,
 	rootCmd.AddCommand(checkCmd())
 	rootCmd.AddCommand(remediateCmd())
 	rootCmd.AddCommand(statusCmd())
+	rootCmd.AddCommand(dropsCmd())
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -6,6 +6,7 @@ import (
 	"os"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/tools/clientcmd"
@@ -44,6 +45,7 @@ type RemediationStep struct {
 	Type              string `json:"type"`
 	Description       string `json:"description"`
 	Command           string `json:"command,omitempty"`
+	Template          string `json:"template,omitempty"`
 	RequiresPrivilege string `json:"requiresPrivilege,omitempty"`
 }
 
@@ -97,6 +99,38 @@ type StatusResult struct {
 	NamespaceCount     int                `json:"namespaceCount"`
 }
 
+// DropsResult is the result of a drops command.
+type DropsResult struct {
+	Flows        int              `json:"flows"`
+	Drops        int              `json:"drops"`
+	PolicyDrops  int              `json:"policyDrops"`
+	Uncorrelated int              `json:"uncorrelated"`
+	InvalidLines int              `json:"invalidLines,omitempty"`
+	Policies     []PolicyDropInfo `json:"policies"`
+}
+
+// PolicyDropInfo summarizes the drops correlated with a policy.
+type PolicyDropInfo struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	Kind      string `json:"kind"`
+	Drops     int    `json:"drops"`
+	// PolicyMatches counts the drops Hubble attributed to the policy by
+	// name; the rest were matched by the policy's workload selector.
+	PolicyMatches int           `json:"policyMatches"`
+	Examples      []DropExample `json:"examples,omitempty"`
+}
+
+// DropExample is a dropped flow listed in drops results.
+type DropExample struct {
+	Time        time.Time `json:"time"`
+	Source      string    `json:"source"`
+	Destination string    `json:"destination"`
+	Port        uint32    `json:"port,omitempty"`
+	Protocol    string    `json:"protocol,omitempty"`
+	Direction   string    `json:"direction,omitempty"`
+}
+
 // getClientFunc is the function used to create a Kubernetes dynamic client.
 // It can be overridden in tests to inject a fake client.
 var getClientFunc = defaultGetClient
@@ -162,6 +196,8 @@ func outputTable(result interface{}) error {
 		return outputRemediateTable(w, r)
 	case StatusResult:
 		return outputStatusTable(w, r)
+	case DropsResult:
+		return outputDropsTable(w, r)
 	default:
 		// Fall back to JSON for unknown types
 		return outputJSON(result)
@@ -201,6 +237,9 @@ func outputExplainTable(w *tabwriter.Writer, r ExplainResult) error {
 			if step.Command != "" {
 				fmt.Fprintf(w, "   Command: %s\n", step.Command)
 			}
+			if step.Template != "" {
+				fmt.Fprintf(w, "   Template:\n%s\n", indent(step.Template, "     "))
+			}
 		}
 	}
 
@@ -275,6 +314,52 @@ func outputStatusTable(w *tabwriter.Writer, r StatusResult) error {
 	return nil
 }
 
+func outputDropsTable(w *tabwriter.Writer, r DropsResult) error {
+	fmt.Fprintf(w, "FLOWS:\t%d\n", r.Flows)
+	fmt.Fprintf(w, "DROPS:\t%d\n", r.Drops)
+	fmt.Fprintf(w, "POLICY DROPS:\t%d\n", r.PolicyDrops)
+	fmt.Fprintf(w, "UNCORRELATED:\t%d\n", r.Uncorrelated)
+	if r.InvalidLines > 0 {
+		fmt.Fprintf(w, "INVALID LINES:\t%d\n", r.InvalidLines)
+	}
+	fmt.Fprintln(w)
+
+	if len(r.Policies) > 0 {
+		fmt.Fprintln(w, "KIND\tNAMESPACE\tNAME\tDROPS\tMATCHED BY\tEXAMPLE")
+		for _, p := range r.Policies {
+			matchedBy := "selector"
+			if p.PolicyMatches == p.Drops {
+				matchedBy = "policy"
+			} else if p.PolicyMatches > 0 {
+				matchedBy = "policy+selector"
+			}
+			example := ""
+			if len(p.Examples) > 0 {
+				e := p.Examples[0]
+				example = fmt.Sprintf("%s -> %s", e.Source, e.Destination)
+				if e.Port != 0 {
+					example += fmt.Sprintf(":%d/%s", e.Port, e.Protocol)
+				}
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\n",
+				p.Kind, p.Namespace, p.Name, p.Drops, matchedBy, example)
+		}
+	}
+
+	return nil
+}
+
+// indent prefixes every non-empty line of s with prefix.
+func indent(s, prefix string) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
 // severityColor returns ANSI color code for severity (used in table output).
 func severityColor(severity string) string {
 	switch strings.ToLower(severity) {
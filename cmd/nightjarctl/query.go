@@ -171,6 +171,7 @@ func parseConstraintMap(cMap map[string]interface{}) ConstraintInfo {
 						Type:              safeString(stepMap, "type"),
 						Description:       safeString(stepMap, "description"),
 						Command:           safeString(stepMap, "command"),
+						Template:          safeString(stepMap, "template"),
 						RequiresPrivilege: safeString(stepMap, "requiresPrivilege"),
 					}
 					info.Remediation.Steps = append(info.Remediation.Steps, step)
@@ -7,12 +7,16 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/dynamic"
 	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/nightjarctl/nightjar/internal/adapters/istio"
 )
 
 var constraintReportGVR = schema.GroupVersionResource{
@@ -38,13 +42,20 @@ func makeFakeClient(objects ...*unstructured.Unstructured) dynamic.Interface {
 	gvrToListKind := map[schema.GroupVersionResource]string{
 		constraintReportGVR: "ConstraintReportList",
 	}
+	for _, gvr := range serviceEntryGVRs {
+		gvrToListKind[gvr] = "ServiceEntryList"
+	}
 
 	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(s, gvrToListKind)
 
 	ctx := context.Background()
 	for _, obj := range objects {
 		ns := obj.GetNamespace()
-		client.Resource(constraintReportGVR).Namespace(ns).Create(ctx, obj, metav1.CreateOptions{})
+		gvr := constraintReportGVR
+		if obj.GetKind() == "ServiceEntry" {
+			gvr = obj.GroupVersionKind().GroupVersion().WithResource(istio.ServiceEntryResource)
+		}
+		client.Resource(gvr).Namespace(ns).Create(ctx, obj, metav1.CreateOptions{})
 	}
 
 	return client
@@ -219,6 +230,89 @@ func TestRunExplain_Success_WithRemediation(t *testing.T) {
 	assert.Contains(t, output, "remediationSteps")
 }
 
+func TestRunExplain_IstioRegistryOnly(t *testing.T) {
+	report := makeConstraintReport("shop", []map[string]interface{}{
+		{"name": "deny-egress", "type": "NetworkEgress", "severity": "Warning", "tags": []interface{}{"network"}},
+		{
+			"name":     "istio-outbound-registry-only",
+			"type":     "MeshPolicy",
+			"severity": "Warning",
+			"tags":     []interface{}{"istio", "egress", "registry-only"},
+		},
+	})
+	serviceEntry := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "networking.istio.io/v1",
+		"kind":       "ServiceEntry",
+		"metadata":   map[string]interface{}{"name": "stripe", "namespace": "shop"},
+		"spec":       map[string]interface{}{"hosts": []interface{}{"api.stripe.com"}},
+	}}
+	setFakeClient(t, makeFakeClient(report, serviceEntry))
+
+	cmd := explainCmd()
+	explainNamespace = "shop"
+	explainWorkload = ""
+	outputFmt = "json"
+
+	output := captureStdout(t, func() {
+		err := runExplain(cmd, []string{`Get "https://api.github.com/user": 502 Bad Gateway (upstream cluster: BlackHoleCluster)`})
+		require.NoError(t, err)
+	})
+	assert.Contains(t, output, "Host api.github.com is not registered; add a ServiceEntry")
+	assert.Contains(t, output, "kind: ServiceEntry")
+	assert.NotContains(t, output, "deny-egress")
+
+	// A host registered by a visible ServiceEntry falls back to generic matching.
+	output = captureStdout(t, func() {
+		err := runExplain(cmd, []string{`Post "https://api.stripe.com/v1/charges": 502 Bad Gateway (upstream cluster: BlackHoleCluster)`})
+		require.NoError(t, err)
+	})
+	assert.NotContains(t, output, "is not registered")
+}
+
+func TestRunExplain_IstioRegistryOnly_OlderServiceEntryVersion(t *testing.T) {
+	report := makeConstraintReport("shop", []map[string]interface{}{
+		{
+			"name":     "istio-outbound-registry-only",
+			"type":     "MeshPolicy",
+			"severity": "Warning",
+			"tags":     []interface{}{"istio", "egress", "registry-only"},
+		},
+	})
+	serviceEntry := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "networking.istio.io/v1beta1",
+		"kind":       "ServiceEntry",
+		"metadata":   map[string]interface{}{"name": "stripe", "namespace": "shop"},
+		"spec":       map[string]interface{}{"hosts": []interface{}{"api.stripe.com"}},
+	}}
+	client := makeFakeClient(report, serviceEntry).(*dynamicfake.FakeDynamicClient)
+	// The API server predates networking.istio.io/v1.
+	client.PrependReactor("list", istio.ServiceEntryResource, func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetResource().Version != "v1" {
+			return false, nil, nil
+		}
+		return true, nil, apierrors.NewNotFound(action.GetResource().GroupResource(), "")
+	})
+	setFakeClient(t, client)
+
+	cmd := explainCmd()
+	explainNamespace = "shop"
+	explainWorkload = ""
+	outputFmt = "json"
+
+	output := captureStdout(t, func() {
+		err := runExplain(cmd, []string{`Post "https://api.stripe.com/v1/charges": 502 Bad Gateway (upstream cluster: BlackHoleCluster)`})
+		require.NoError(t, err)
+	})
+	assert.NotContains(t, output, "is not registered")
+
+	output = captureStdout(t, func() {
+		err := runExplain(cmd, []string{`Get "https://api.github.com/user": 502 Bad Gateway (upstream cluster: BlackHoleCluster)`})
+		require.NoError(t, err)
+	})
+	assert.Contains(t, output, "Host api.github.com is not registered; add a ServiceEntry")
+	assert.Contains(t, output, `"confidence": "high"`)
+}
+
 // ---------------------------------------------------------------------------
 // runCheck success path
 // ---------------------------------------------------------------------------
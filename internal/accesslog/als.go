@@ -0,0 +1,263 @@
+package accesslog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+
+	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	datapb "github.com/envoyproxy/go-control-plane/envoy/data/accesslog/v3"
+	alspb "github.com/envoyproxy/go-control-plane/envoy/service/accesslog/v3"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/nightjarctl/nightjar/internal/hubble"
+	"github.com/nightjarctl/nightjar/internal/shard"
+)
+
+// ServerOptions configures a Server.
+type ServerOptions struct {
+	// Address is the address to listen on, e.g. ":8094"
+	Address string
+
+	// BufferSize is the size of the flow drop channel buffer
+	BufferSize int
+
+	// Shards relays the denials of namespaces another replica owns to it,
+	// e.g. to the leader or the owning shard. Nil keeps every denial.
+	Shards *shard.Forwarder
+
+	// Logger for the server
+	Logger *zap.Logger
+}
+
+// DefaultServerOptions returns default options for the Server.
+func DefaultServerOptions() ServerOptions {
+	return ServerOptions{
+		Address:    ":8094",
+		BufferSize: 1000,
+		Logger:     zap.NewNop(),
+	}
+}
+
+// Server implements Envoy's gRPC Access Log Service and emits the policy
+// denials among the entries it receives as FlowDrop events.
+//
+// Proxies connect to any replica behind the Service, so every replica runs
+// a Server and relays the denials it does not own with ServerOptions.Shards.
+// Like hubble.Client, it drops denials when the buffer is full rather than
+// applying backpressure to the proxies.
+type Server struct {
+	alspb.UnimplementedAccessLogServiceServer
+
+	opts   ServerOptions
+	logger *zap.Logger
+	drops  chan hubble.FlowDrop
+
+	// mu guards closed, so no stream sends on drops after Serve closed it.
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewServer creates a Server. Call Start or Serve to accept streams.
+func NewServer(opts ServerOptions) *Server {
+	if opts.Logger == nil {
+		opts.Logger = zap.NewNop()
+	}
+	if opts.Address == "" {
+		opts.Address = DefaultServerOptions().Address
+	}
+	if opts.BufferSize == 0 {
+		opts.BufferSize = DefaultServerOptions().BufferSize
+	}
+
+	return &Server{
+		opts:   opts,
+		logger: opts.Logger.Named("accesslog-server"),
+		drops:  make(chan hubble.FlowDrop, opts.BufferSize),
+	}
+}
+
+// DroppedFlows returns a channel of policy denials as flow drop events.
+// The channel is closed when Serve returns.
+func (s *Server) DroppedFlows() <-chan hubble.FlowDrop {
+	return s.drops
+}
+
+// Start listens on the configured address and serves until ctx is
+// cancelled.
+func (s *Server) Start(ctx context.Context) error {
+	lis, err := net.Listen("tcp", s.opts.Address)
+	if err != nil {
+		return fmt.Errorf("listening on %s: %w", s.opts.Address, err)
+	}
+	return s.Serve(ctx, lis)
+}
+
+// Serve serves the Access Log Service on lis until ctx is cancelled.
+func (s *Server) Serve(ctx context.Context, lis net.Listener) error {
+	defer s.close()
+
+	gs := grpc.NewServer()
+	alspb.RegisterAccessLogServiceServer(gs, s)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- gs.Serve(lis)
+	}()
+	s.logger.Info("Access Log Service listening", zap.String("address", lis.Addr().String()))
+
+	select {
+	case <-ctx.Done():
+		// Proxies keep their streams open, so waiting for them to finish
+		// would block shutdown.
+		gs.Stop()
+		return nil
+	case err := <-errCh:
+		return err
+	}
+}
+
+// StreamAccessLogs implements alspb.AccessLogServiceServer. Envoy sends the
+// node identifier with the first message of a stream only.
+func (s *Server) StreamAccessLogs(stream alspb.AccessLogService_StreamAccessLogsServer) error {
+	var proxy Proxy
+	for {
+		msg, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if id := msg.GetIdentifier(); id != nil {
+			proxy = nodeProxy(id.GetNode())
+		}
+
+		for _, e := range msg.GetHttpLogs().GetLogEntry() {
+			s.handle(stream.Context(), httpEntry(e, proxy))
+		}
+		for _, e := range msg.GetTcpLogs().GetLogEntry() {
+			s.handle(stream.Context(), commonEntry(e.GetCommonProperties(), proxy))
+		}
+	}
+}
+
+// handle emits the entry if it is a denial, or relays it to the replica
+// that owns it.
+func (s *Server) handle(ctx context.Context, e Entry) {
+	if !e.Denied() {
+		entriesTotal.WithLabelValues(sourceALS, resultAllowed).Inc()
+		return
+	}
+
+	drop := e.FlowDrop()
+	local, err := relay(ctx, s.opts.Shards, drop)
+	switch {
+	case err != nil:
+		entriesTotal.WithLabelValues(sourceALS, resultUnrelayed).Inc()
+		s.logger.Warn("Failed to relay access log denial",
+			zap.String("namespace", e.Proxy.Namespace),
+			zap.String("pod", e.Proxy.PodName),
+			zap.Error(err))
+		return
+	case !local:
+		entriesTotal.WithLabelValues(sourceALS, resultRelayed).Inc()
+		return
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return
+	}
+	offer(s.drops, drop, sourceALS, s.logger)
+}
+
+func (s *Server) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.drops)
+	}
+}
+
+// httpEntry converts an HTTP access log entry.
+func httpEntry(e *datapb.HTTPAccessLogEntry, proxy Proxy) Entry {
+	entry := commonEntry(e.GetCommonProperties(), proxy)
+	req, resp := e.GetRequest(), e.GetResponse()
+	if m := req.GetRequestMethod(); m != corepb.RequestMethod_METHOD_UNSPECIFIED {
+		entry.Method = m.String()
+	}
+	entry.Authority = req.GetAuthority()
+	entry.Path = req.GetPath()
+	entry.RequestID = req.GetRequestId()
+	entry.ResponseCode = resp.GetResponseCode().GetValue()
+	entry.ResponseCodeDetails = resp.GetResponseCodeDetails()
+	return entry
+}
+
+// commonEntry converts the properties shared by HTTP and TCP entries.
+func commonEntry(c *datapb.AccessLogCommon, proxy Proxy) Entry {
+	entry := Entry{
+		Proxy:                        proxy,
+		UpstreamCluster:              c.GetUpstreamCluster(),
+		ConnectionTerminationDetails: c.GetConnectionTerminationDetails(),
+		DownstreamRemoteAddress:      socketAddress(c.GetDownstreamRemoteAddress()),
+		DownstreamLocalAddress:       socketAddress(c.GetDownstreamLocalAddress()),
+	}
+	if t := c.GetStartTime(); t != nil {
+		entry.Time = t.AsTime()
+	}
+	if c.GetResponseFlags().GetUnauthorizedDetails().GetReason() == datapb.ResponseFlags_Unauthorized_EXTERNAL_SERVICE {
+		entry.ResponseFlags = []string{FlagUnauthorizedExternal}
+	}
+	for _, san := range c.GetTlsProperties().GetPeerCertificateProperties().GetSubjectAltName() {
+		if uri := san.GetUri(); uri != "" {
+			entry.PeerPrincipal = uri
+			break
+		}
+	}
+	return entry
+}
+
+// socketAddress formats an Envoy socket address as host:port.
+func socketAddress(a *corepb.Address) string {
+	sa := a.GetSocketAddress()
+	if sa == nil {
+		return ""
+	}
+	return net.JoinHostPort(sa.GetAddress(), strconv.FormatUint(uint64(sa.GetPortValue()), 10))
+}
+
+// nodeProxy identifies the proxy from the node metadata Istio sets on its
+// sidecars: NAMESPACE, NAME (the pod), LABELS and WORKLOAD_NAME.
+func nodeProxy(node *corepb.Node) Proxy {
+	md := node.GetMetadata().GetFields()
+	return Proxy{
+		Namespace: md["NAMESPACE"].GetStringValue(),
+		PodName:   md["NAME"].GetStringValue(),
+		Workload:  md["WORKLOAD_NAME"].GetStringValue(),
+		Labels:    structLabels(md["LABELS"].GetStructValue()),
+	}
+}
+
+// structLabels returns the string fields of a label struct.
+func structLabels(s *structpb.Struct) map[string]string {
+	if len(s.GetFields()) == 0 {
+		return nil
+	}
+	labels := make(map[string]string, len(s.GetFields()))
+	for k, v := range s.GetFields() {
+		if sv, ok := v.GetKind().(*structpb.Value_StringValue); ok {
+			labels[k] = sv.StringValue
+		}
+	}
+	return labels
+}
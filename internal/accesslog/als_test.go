@@ -0,0 +1,267 @@
+package accesslog
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	datapb "github.com/envoyproxy/go-control-plane/envoy/data/accesslog/v3"
+	alspb "github.com/envoyproxy/go-control-plane/envoy/service/accesslog/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/nightjarctl/nightjar/internal/hubble"
+	"github.com/nightjarctl/nightjar/internal/shard"
+)
+
+// serveALS starts s on an in-memory listener and returns a client for it.
+func serveALS(t *testing.T, s *Server) (alspb.AccessLogServiceClient, context.CancelFunc) {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Serve(ctx, lis) }()
+	t.Cleanup(func() {
+		cancel()
+		require.NoError(t, <-done)
+	})
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return alspb.NewAccessLogServiceClient(conn), cancel
+}
+
+func socket(host string, port uint32) *corepb.Address {
+	return &corepb.Address{Address: &corepb.Address_SocketAddress{SocketAddress: &corepb.SocketAddress{
+		Address:       host,
+		PortSpecifier: &corepb.SocketAddress_PortValue{PortValue: port},
+	}}}
+}
+
+func TestServer_StreamAccessLogs(t *testing.T) {
+	s := NewServer(ServerOptions{})
+	client, _ := serveALS(t, s)
+
+	stream, err := client.StreamAccessLogs(context.Background())
+	require.NoError(t, err)
+
+	labels, err := structpb.NewStruct(map[string]interface{}{"app": "orders"})
+	require.NoError(t, err)
+	node := &corepb.Node{Id: "sidecar~10.0.2.9~orders-0.shop~shop.svc.cluster.local", Metadata: &structpb.Struct{Fields: map[string]*structpb.Value{
+		"NAMESPACE":     structpb.NewStringValue("shop"),
+		"NAME":          structpb.NewStringValue("orders-0"),
+		"WORKLOAD_NAME": structpb.NewStringValue("orders"),
+		"LABELS":        structpb.NewStructValue(labels),
+	}}}
+	common := func(cluster string) *datapb.AccessLogCommon {
+		return &datapb.AccessLogCommon{
+			UpstreamCluster:         cluster,
+			DownstreamRemoteAddress: socket("10.0.1.5", 41236),
+			DownstreamLocalAddress:  socket("10.0.2.9", 8080),
+			TlsProperties: &datapb.TLSProperties{PeerCertificateProperties: &datapb.TLSProperties_CertificateProperties{
+				SubjectAltName: []*datapb.TLSProperties_CertificateProperties_SubjectAltName{
+					{San: &datapb.TLSProperties_CertificateProperties_SubjectAltName_Uri{Uri: "spiffe://cluster.local/ns/frontend/sa/web"}},
+				},
+			}},
+		}
+	}
+
+	require.NoError(t, stream.Send(&alspb.StreamAccessLogsMessage{
+		Identifier: &alspb.StreamAccessLogsMessage_Identifier{Node: node, LogName: "envoy_als"},
+		LogEntries: &alspb.StreamAccessLogsMessage_HttpLogs{HttpLogs: &alspb.StreamAccessLogsMessage_HTTPAccessLogEntries{
+			LogEntry: []*datapb.HTTPAccessLogEntry{
+				{
+					CommonProperties: common("inbound|8080||"),
+					Request:          &datapb.HTTPRequestProperties{RequestMethod: corepb.RequestMethod_GET, Path: "/healthz"},
+					Response:         &datapb.HTTPResponseProperties{ResponseCode: wrapperspb.UInt32(200), ResponseCodeDetails: "via_upstream"},
+				},
+				{
+					CommonProperties: common("inbound|8080||"),
+					Request:          &datapb.HTTPRequestProperties{RequestMethod: corepb.RequestMethod_DELETE, Path: "/api/orders/7", RequestId: "req-1"},
+					Response: &datapb.HTTPResponseProperties{
+						ResponseCode:        wrapperspb.UInt32(403),
+						ResponseCodeDetails: "rbac_access_denied_matched_policy[ns[shop]-policy[deny-delete]-rule[0]]",
+					},
+				},
+			},
+		}},
+	}))
+
+	// Later messages of the stream carry no identifier.
+	uaex := common("outbound|443||payments.billing.svc.cluster.local")
+	uaex.ResponseFlags = &datapb.ResponseFlags{UnauthorizedDetails: &datapb.ResponseFlags_Unauthorized{
+		Reason: datapb.ResponseFlags_Unauthorized_EXTERNAL_SERVICE,
+	}}
+	require.NoError(t, stream.Send(&alspb.StreamAccessLogsMessage{
+		LogEntries: &alspb.StreamAccessLogsMessage_TcpLogs{TcpLogs: &alspb.StreamAccessLogsMessage_TCPAccessLogEntries{
+			LogEntry: []*datapb.TCPAccessLogEntry{{CommonProperties: uaex}},
+		}},
+	}))
+
+	var drops []hubble.FlowDrop
+	for len(drops) < 2 {
+		select {
+		case drop := <-s.DroppedFlows():
+			drops = append(drops, drop)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("received %d of 2 denials", len(drops))
+		}
+	}
+
+	rbac := drops[0]
+	assert.Equal(t, hubble.DirectionIngress, rbac.Direction)
+	assert.Equal(t, "frontend", rbac.Source.Namespace)
+	assert.Equal(t, hubble.Endpoint{
+		Namespace: "shop",
+		PodName:   "orders-0",
+		Labels:    map[string]string{"app": "orders"},
+		Workloads: []hubble.WorkloadRef{{Name: "orders"}},
+	}, rbac.Destination)
+	assert.Equal(t, hubble.IPInfo{Source: "10.0.1.5", Destination: "10.0.2.9"}, rbac.IP)
+	assert.Equal(t, "DELETE", rbac.L7.Method)
+	assert.Equal(t, "req-1", rbac.L7.RequestID)
+	assert.Equal(t, "deny-delete", rbac.PolicyName)
+
+	tcp := drops[1]
+	assert.Equal(t, hubble.DirectionEgress, tcp.Direction)
+	assert.Equal(t, "orders-0", tcp.Source.PodName)
+	assert.Equal(t, []string{FlagUnauthorizedExternal}, tcp.L7.ResponseFlags)
+}
+
+func TestServer_ClosesChannelOnShutdown(t *testing.T) {
+	s := NewServer(ServerOptions{})
+	_, cancel := serveALS(t, s)
+	cancel()
+
+	select {
+	case _, ok := <-s.DroppedFlows():
+		assert.False(t, ok)
+	case <-time.After(2 * time.Second):
+		t.Fatal("channel not closed after shutdown")
+	}
+}
+
+// namespaceRouter assigns namespaces to members by a fixed table.
+type namespaceRouter struct {
+	self   shard.Member
+	owners map[string]shard.Member
+}
+
+func (r namespaceRouter) Self() shard.Member { return r.self }
+
+func (r namespaceRouter) Owner(namespace string) (shard.Member, bool) {
+	m, ok := r.owners[namespace]
+	return m, ok
+}
+
+func (r namespaceRouter) Members() []shard.Member { return nil }
+
+func TestServer_RelaysToOwner(t *testing.T) {
+	relay := NewRelay(10, nil)
+	mux := http.NewServeMux()
+	mux.Handle(RelayPath, relay)
+	ownerSrv := httptest.NewServer(mux)
+	defer ownerSrv.Close()
+	host, port, err := net.SplitHostPort(ownerSrv.Listener.Addr().String())
+	require.NoError(t, err)
+	portNum, err := strconv.Atoi(port)
+	require.NoError(t, err)
+
+	self, other := shard.Member{Identity: "a"}, shard.Member{Identity: "b", Host: host}
+	router := namespaceRouter{self: self, owners: map[string]shard.Member{"shop": other, "frontend": other, "billing": self}}
+	s := NewServer(ServerOptions{Shards: shard.NewForwarder(router, portNum, zap.NewNop())})
+	denied := func(ns string) Entry {
+		return Entry{
+			Proxy:               Proxy{Namespace: ns, PodName: "orders-0"},
+			UpstreamCluster:     "inbound|8080||",
+			Method:              "DELETE",
+			Path:                "/api/orders/7",
+			ResponseCode:        403,
+			ResponseCodeDetails: "rbac_access_denied_matched_policy[none]",
+		}
+	}
+
+	// Another replica owns shop: the denial is relayed, not kept.
+	s.handle(context.Background(), denied("shop"))
+	assert.Empty(t, s.DroppedFlows())
+	select {
+	case drop := <-relay.DroppedFlows():
+		assert.Equal(t, "shop", drop.Destination.Namespace)
+		assert.Equal(t, "DELETE", drop.L7.Method)
+		assert.Equal(t, hubble.DropReasonPolicyL7, drop.DropReason)
+	case <-time.After(2 * time.Second):
+		t.Fatal("denial was not relayed")
+	}
+
+	// This replica owns billing.
+	s.handle(context.Background(), denied("billing"))
+	assert.Len(t, s.DroppedFlows(), 1)
+	assert.Empty(t, relay.DroppedFlows())
+}
+
+func TestServer_RelayRequiresToken(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "tokenreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenReview).DeepCopy()
+		review.Status.Authenticated = true
+		review.Status.Audiences = review.Spec.Audiences
+		review.Status.User.Username = "system:serviceaccount:nightjar-system:nightjar"
+		return true, review, nil
+	})
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("token"), 0o600))
+
+	// The owner accepts relayed denials on its relay server only.
+	relay := NewRelay(10, nil)
+	relayServer := shard.NewRelayServer(shard.RelayServerOptions{Client: client, TokenFile: tokenFile})
+	relayServer.Handle(RelayPath, relay)
+	ownerSrv := httptest.NewServer(relayServer)
+	defer ownerSrv.Close()
+	host, port, err := net.SplitHostPort(ownerSrv.Listener.Addr().String())
+	require.NoError(t, err)
+	portNum, err := strconv.Atoi(port)
+	require.NoError(t, err)
+
+	router := namespaceRouter{self: shard.Member{Identity: "a"}, owners: map[string]shard.Member{"shop": {Identity: "b", Host: host}}}
+	shards := shard.NewForwarder(router, portNum, zap.NewNop())
+	s := NewServer(ServerOptions{Shards: shards})
+	denied := Entry{
+		Proxy:               Proxy{Namespace: "shop", PodName: "orders-0"},
+		UpstreamCluster:     "inbound|8080||",
+		ResponseCode:        403,
+		ResponseCodeDetails: "rbac_access_denied_matched_policy[none]",
+	}
+
+	// Without a token the owner rejects the relay.
+	s.handle(context.Background(), denied)
+	assert.Empty(t, relay.DroppedFlows())
+
+	shards.SetTokenFile(tokenFile)
+	s.handle(context.Background(), denied)
+	select {
+	case drop := <-relay.DroppedFlows():
+		assert.Equal(t, "shop", drop.Destination.Namespace)
+	case <-time.After(2 * time.Second):
+		t.Fatal("denial was not relayed")
+	}
+}
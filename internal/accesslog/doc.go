@@ -0,0 +1,52 @@
+// Package accesslog detects L7 policy denials in Envoy access logs.
+//
+// # Overview
+//
+// Istio AuthorizationPolicies and Cilium L7 rules are enforced by an Envoy
+// proxy, which rejects a request with 403 "RBAC: access denied" or closes
+// the connection. The packets themselves are forwarded, so these denials
+// never show up as Hubble drops or Kubernetes Events. The access log of the
+// proxy records them; this package reads it and reports every denial as a
+// hubble.FlowDrop with DropReasonPolicyL7 and the request in FlowDrop.L7, so
+// the correlator matches and notifies it like a dropped flow.
+//
+// An entry is a denial when its response flags contain UAEX (denied by an
+// external authorizer) or RBAC, when a 403 carries an rbac_access_denied
+// response code detail, or when a TCP connection was closed with an
+// rbac_access_denied termination detail.
+//
+// # Sources
+//
+// A Server implements Envoy's gRPC Access Log Service. Register it as an
+// Istio extension provider (envoyHttpAls and envoyTcpAls) and enable it with
+// a Telemetry resource; the proxy identity is taken from the node metadata
+// Istio sends on every stream.
+//
+// Proxies stream to whichever replica the Service picks, so every replica
+// runs a Server and relays the denials of namespaces it does not own to the
+// leader or the owning shard, whose relay server (see shard.RelayServer)
+// emits them from a Relay.
+//
+// A Reader reads JSON access log lines, as written by Istio with
+// meshConfig.accessLogEncoding JSON, from a file or stream. The proxy is
+// identified by the kubernetes.namespace_name, pod_name and labels fields a
+// log shipper adds, or by ReaderOptions.Proxy for the log of a single pod:
+//
+//	kubectl logs deploy/payments -c istio-proxy -f | nightjar ...
+//
+// A followed file is reopened when it is rotated, i.e. replaced or
+// truncated.
+//
+// # Attribution
+//
+// Istio names the DENY policy that matched in the detail, e.g.
+// rbac_access_denied_matched_policy[ns[shop]-policy[deny-admin]-rule[0]],
+// which becomes FlowDrop.DeniedBy. A request that no ALLOW policy admitted
+// reports [none] and is matched by workload selector instead.
+//
+// The direction follows the upstream cluster: requests on an inbound|
+// cluster were denied on ingress to the proxy's workload, and requests on an
+// outbound| cluster on egress from it. The peer's namespace comes from its
+// SPIFFE identity, ns/<namespace>/sa/<account>, or from the outbound
+// cluster's service host.
+package accesslog
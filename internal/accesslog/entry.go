@@ -0,0 +1,193 @@
+package accesslog
+
+import (
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nightjarctl/nightjar/internal/hubble"
+)
+
+// Response flags that mark a request denied by policy.
+const (
+	// FlagUnauthorizedExternal is set when an external authorizer, e.g. an
+	// Istio CUSTOM AuthorizationPolicy, denied the request.
+	FlagUnauthorizedExternal = "UAEX"
+
+	// FlagRBAC is set by proxies that report RBAC denials as a flag.
+	FlagRBAC = "RBAC"
+)
+
+// rbacDeniedDetail prefixes the response code and connection termination
+// details of requests denied by Envoy's RBAC filters.
+const rbacDeniedDetail = "rbac_access_denied"
+
+// authorizationPolicyKind is the kind of the policies Istio names in RBAC
+// denial details.
+const authorizationPolicyKind = "AuthorizationPolicy"
+
+// matchedPolicy extracts the namespace and name of the DENY policy from an
+// Istio RBAC detail, e.g. rbac_access_denied_matched_policy[ns[shop]-policy[deny-admin]-rule[0]].
+var matchedPolicy = regexp.MustCompile(`^rbac_access_denied_matched_policy\[ns\[([^\]]*)\]-policy\[([^\]]*)\]`)
+
+// Proxy identifies the workload whose Envoy proxy wrote an access log entry.
+type Proxy struct {
+	Namespace string
+	PodName   string
+	Labels    map[string]string
+
+	// Workload is the name of the pod's workload, e.g. its Deployment
+	Workload string
+}
+
+// Entry is an access log entry of an Envoy proxy, normalized from the gRPC
+// Access Log Service or a JSON log line.
+type Entry struct {
+	Time  time.Time
+	Proxy Proxy
+
+	// Method, Authority and Path are empty for TCP connections
+	Method    string
+	Authority string
+	Path      string
+
+	// ResponseCode is 0 for TCP connections
+	ResponseCode                 uint32
+	ResponseFlags                []string
+	ResponseCodeDetails          string
+	ConnectionTerminationDetails string
+
+	// UpstreamCluster is the Envoy cluster the request was routed to, e.g.
+	// inbound|8080|| or outbound|80||api.shop.svc.cluster.local
+	UpstreamCluster string
+
+	// DownstreamRemoteAddress is the peer's address and
+	// DownstreamLocalAddress the address it connected to, as host:port
+	DownstreamRemoteAddress string
+	DownstreamLocalAddress  string
+
+	// PeerPrincipal is the URI SAN of the peer's certificate, e.g.
+	// spiffe://cluster.local/ns/shop/sa/frontend
+	PeerPrincipal string
+
+	RequestID string
+}
+
+// Denied reports whether the entry records a request or connection denied
+// by policy.
+func (e Entry) Denied() bool {
+	for _, f := range e.ResponseFlags {
+		if f == FlagUnauthorizedExternal || f == FlagRBAC {
+			return true
+		}
+	}
+	if e.ResponseCode == 403 && strings.HasPrefix(e.ResponseCodeDetails, rbacDeniedDetail) {
+		return true
+	}
+	return e.ResponseCode == 0 && strings.HasPrefix(e.ConnectionTerminationDetails, rbacDeniedDetail)
+}
+
+// FlowDrop converts a denied entry to a flow drop. The proxy's workload is
+// the destination of inbound requests and the source of outbound ones.
+func (e Entry) FlowDrop() hubble.FlowDrop {
+	details := e.ResponseCodeDetails
+	if details == "" {
+		details = e.ConnectionTerminationDetails
+	}
+
+	drop := hubble.FlowDrop{
+		Time:       e.Time,
+		DropReason: hubble.DropReasonPolicyL7,
+		Direction:  e.direction(),
+		L7: &hubble.L7Info{
+			Method:        e.Method,
+			Authority:     e.Authority,
+			Path:          e.Path,
+			ResponseCode:  e.ResponseCode,
+			ResponseFlags: e.ResponseFlags,
+			Details:       details,
+			RequestID:     e.RequestID,
+		},
+	}
+	if drop.Time.IsZero() {
+		drop.Time = time.Now()
+	}
+
+	srcIP, srcPort := splitAddress(e.DownstreamRemoteAddress)
+	dstIP, dstPort := splitAddress(e.DownstreamLocalAddress)
+	drop.IP = hubble.IPInfo{Source: srcIP, Destination: dstIP}
+	drop.L4 = hubble.L4Info{Protocol: hubble.ProtocolTCP, SourcePort: srcPort, DestinationPort: dstPort}
+
+	proxy := hubble.Endpoint{
+		Namespace: e.Proxy.Namespace,
+		PodName:   e.Proxy.PodName,
+		Labels:    e.Proxy.Labels,
+	}
+	if e.Proxy.Workload != "" {
+		proxy.Workloads = []hubble.WorkloadRef{{Name: e.Proxy.Workload}}
+	}
+	if drop.Direction == hubble.DirectionEgress {
+		drop.Source = proxy
+		drop.Destination = hubble.Endpoint{Namespace: clusterNamespace(e.UpstreamCluster)}
+	} else {
+		drop.Source = hubble.Endpoint{Namespace: principalNamespace(e.PeerPrincipal)}
+		drop.Destination = proxy
+	}
+
+	if m := matchedPolicy.FindStringSubmatch(details); m != nil {
+		drop.PolicyName = m[2]
+		drop.DeniedBy = []hubble.PolicyRef{{Kind: authorizationPolicyKind, Namespace: m[1], Name: m[2]}}
+	}
+	return drop
+}
+
+// direction derives the enforcement direction from the upstream cluster.
+// Sidecars route requests to their own workload through inbound| clusters;
+// everything else left the workload, or the cluster is unknown and the
+// denial is taken as ingress, where AuthorizationPolicies are enforced.
+func (e Entry) direction() hubble.TrafficDirection {
+	if strings.HasPrefix(e.UpstreamCluster, "outbound|") {
+		return hubble.DirectionEgress
+	}
+	return hubble.DirectionIngress
+}
+
+// principalNamespace returns the namespace of a SPIFFE identity, e.g. shop
+// for spiffe://cluster.local/ns/shop/sa/frontend.
+func principalNamespace(principal string) string {
+	_, path, ok := strings.Cut(strings.TrimPrefix(principal, "spiffe://"), "/ns/")
+	if !ok {
+		return ""
+	}
+	ns, _, _ := strings.Cut(path, "/")
+	return ns
+}
+
+// clusterNamespace returns the namespace of the Service an outbound cluster
+// routes to, e.g. shop for outbound|80||api.shop.svc.cluster.local.
+func clusterNamespace(cluster string) string {
+	parts := strings.Split(cluster, "|")
+	if len(parts) != 4 {
+		return ""
+	}
+	labels := strings.Split(parts[3], ".")
+	if len(labels) < 3 || labels[2] != "svc" {
+		return ""
+	}
+	return labels[1]
+}
+
+// splitAddress splits host:port, returning a zero port if there is none.
+func splitAddress(addr string) (string, uint32) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr, 0
+	}
+	p, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return host, 0
+	}
+	return host, uint32(p)
+}
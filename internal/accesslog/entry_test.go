@@ -0,0 +1,89 @@
+package accesslog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nightjarctl/nightjar/internal/hubble"
+)
+
+func TestEntry_Denied(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry Entry
+		want  bool
+	}{
+		{name: "allowed", entry: Entry{ResponseCode: 200, ResponseCodeDetails: "via_upstream"}},
+		{name: "ext authz", entry: Entry{ResponseCode: 403, ResponseFlags: []string{"UAEX"}}, want: true},
+		{name: "rbac flag", entry: Entry{ResponseCode: 403, ResponseFlags: []string{"DC", "RBAC"}}, want: true},
+		{name: "rbac detail", entry: Entry{ResponseCode: 403, ResponseCodeDetails: "rbac_access_denied_matched_policy[none]"}, want: true},
+		{name: "403 from upstream", entry: Entry{ResponseCode: 403, ResponseCodeDetails: "via_upstream"}},
+		{name: "rbac detail without 403", entry: Entry{ResponseCode: 503, ResponseCodeDetails: "rbac_access_denied_matched_policy[none]"}},
+		{name: "tcp rbac", entry: Entry{ConnectionTerminationDetails: "rbac_access_denied_matched_policy[none]"}, want: true},
+		{name: "tcp closed", entry: Entry{ConnectionTerminationDetails: "downstream_remote_disconnect"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.entry.Denied())
+		})
+	}
+}
+
+func TestEntry_FlowDrop_Inbound(t *testing.T) {
+	e := Entry{
+		Proxy:                   Proxy{Namespace: "shop", PodName: "orders-0", Labels: map[string]string{"app": "orders"}, Workload: "orders"},
+		Method:                  "DELETE",
+		Path:                    "/api/orders/7",
+		ResponseCode:            403,
+		ResponseCodeDetails:     "rbac_access_denied_matched_policy[ns[shop]-policy[deny-delete]-rule[0]]",
+		UpstreamCluster:         "inbound|8080||",
+		DownstreamRemoteAddress: "10.0.1.5:41236",
+		DownstreamLocalAddress:  "10.0.2.9:8080",
+		PeerPrincipal:           "spiffe://cluster.local/ns/frontend/sa/web",
+	}
+
+	drop := e.FlowDrop()
+
+	assert.Equal(t, hubble.DropReasonPolicyL7, drop.DropReason)
+	assert.Equal(t, hubble.DirectionIngress, drop.Direction)
+	assert.Equal(t, "frontend", drop.Source.Namespace)
+	assert.Equal(t, "orders-0", drop.Destination.PodName)
+	assert.Equal(t, "orders", drop.Destination.Labels["app"])
+	assert.Equal(t, []hubble.WorkloadRef{{Name: "orders"}}, drop.Destination.Workloads)
+	assert.Equal(t, hubble.IPInfo{Source: "10.0.1.5", Destination: "10.0.2.9"}, drop.IP)
+	assert.Equal(t, uint32(8080), drop.L4.DestinationPort)
+	assert.Equal(t, "deny-delete", drop.PolicyName)
+	assert.Equal(t, []hubble.PolicyRef{{Kind: "AuthorizationPolicy", Namespace: "shop", Name: "deny-delete"}}, drop.DeniedBy)
+	assert.Equal(t, "DELETE", drop.L7.Method)
+	assert.Equal(t, uint32(403), drop.L7.ResponseCode)
+	assert.False(t, drop.Time.IsZero())
+}
+
+func TestEntry_FlowDrop_Outbound(t *testing.T) {
+	e := Entry{
+		Proxy:                        Proxy{Namespace: "shop", PodName: "orders-0"},
+		ConnectionTerminationDetails: "rbac_access_denied_matched_policy[none]",
+		UpstreamCluster:              "outbound|443||payments.billing.svc.cluster.local",
+	}
+
+	drop := e.FlowDrop()
+
+	assert.Equal(t, hubble.DirectionEgress, drop.Direction)
+	assert.Equal(t, "orders-0", drop.Source.PodName)
+	assert.Equal(t, "billing", drop.Destination.Namespace)
+	assert.Empty(t, drop.DeniedBy)
+	assert.Equal(t, "rbac_access_denied_matched_policy[none]", drop.L7.Details)
+}
+
+func TestPrincipalNamespace(t *testing.T) {
+	assert.Equal(t, "frontend", principalNamespace("spiffe://cluster.local/ns/frontend/sa/web"))
+	assert.Equal(t, "", principalNamespace("spiffe://cluster.local/sa/web"))
+	assert.Equal(t, "", principalNamespace(""))
+}
+
+func TestClusterNamespace(t *testing.T) {
+	assert.Equal(t, "billing", clusterNamespace("outbound|443||payments.billing.svc.cluster.local"))
+	assert.Equal(t, "", clusterNamespace("outbound|443||api.example.com"))
+	assert.Equal(t, "", clusterNamespace("PassthroughCluster"))
+}
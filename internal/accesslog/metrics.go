@@ -0,0 +1,30 @@
+package accesslog
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Label values of nightjar_access_log_entries_total.
+const (
+	sourceALS   = "als"
+	sourceFile  = "file"
+	sourceRelay = "relay"
+
+	resultDenied    = "denied"
+	resultAllowed   = "allowed"
+	resultInvalid   = "invalid"
+	resultDropped   = "dropped"
+	resultRelayed   = "relayed"
+	resultUnrelayed = "unrelayed"
+)
+
+// Access log metrics, served on the controller-runtime metrics endpoint.
+var entriesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "nightjar_access_log_entries_total",
+	Help: "Envoy access log entries read, by source (als, file, relay) and result (denied, allowed, invalid, dropped, relayed to another replica, unrelayed because it was unreachable).",
+}, []string{"source", "result"})
+
+func init() {
+	ctrlmetrics.Registry.MustRegister(entriesTotal)
+}
@@ -0,0 +1,335 @@
+package accesslog
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/nightjarctl/nightjar/internal/hubble"
+	"github.com/nightjarctl/nightjar/internal/util"
+)
+
+// ReaderOptions configures a Reader.
+type ReaderOptions struct {
+	// Proxy identifies the proxy for lines without Kubernetes metadata,
+	// e.g. when reading the log of a single istio-proxy container.
+	Proxy Proxy
+
+	// Follow keeps reading at the end of the input, like tail -f, until the
+	// context is cancelled.
+	Follow bool
+
+	// Path is the file the input was opened from. A followed file is
+	// reopened when it is rotated, i.e. replaced or truncated, and read
+	// from the start of the new file. Empty for pipes and streams.
+	Path string
+
+	// PollInterval is how often a followed input is checked for new lines
+	PollInterval time.Duration
+
+	// BufferSize is the size of the flow drop channel buffer
+	BufferSize int
+
+	// Logger for the reader
+	Logger *zap.Logger
+}
+
+// DefaultReaderOptions returns default options for the Reader.
+func DefaultReaderOptions() ReaderOptions {
+	return ReaderOptions{
+		PollInterval: time.Second,
+		BufferSize:   1000,
+		Logger:       zap.NewNop(),
+	}
+}
+
+// ReaderStats contains access log statistics.
+type ReaderStats struct {
+	// Lines is the number of non-empty lines read.
+	Lines uint64
+	// Entries is the number of access log entries decoded.
+	Entries uint64
+	// Denials is the number of entries denied by policy.
+	Denials uint64
+	// Invalid is the number of lines that are not JSON access log entries.
+	Invalid uint64
+	// Rotations is the number of times the file at Path was reopened.
+	Rotations uint64
+}
+
+// Reader reads JSON access log lines and emits the policy denials among
+// them as FlowDrop events.
+//
+// A followed file at ReaderOptions.Path is reopened when it is rotated. The
+// reader closes the files it reopens; the input passed to NewReader stays
+// the caller's.
+//
+// Lines that are not JSON, such as the proxy's own log messages in a
+// container log, are skipped and counted in ReaderStats.Invalid.
+type Reader struct {
+	opts   ReaderOptions
+	logger *zap.Logger
+	r      io.Reader
+	drops  chan hubble.FlowDrop
+
+	mu    sync.Mutex
+	stats ReaderStats
+}
+
+// NewReader creates a Reader that reads access log lines from r. Call Run
+// to start reading.
+func NewReader(r io.Reader, opts ReaderOptions) *Reader {
+	if opts.Logger == nil {
+		opts.Logger = zap.NewNop()
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DefaultReaderOptions().PollInterval
+	}
+	if opts.BufferSize == 0 {
+		opts.BufferSize = DefaultReaderOptions().BufferSize
+	}
+
+	return &Reader{
+		opts:   opts,
+		logger: opts.Logger.Named("accesslog-reader"),
+		r:      r,
+		drops:  make(chan hubble.FlowDrop, opts.BufferSize),
+	}
+}
+
+// DroppedFlows returns a channel of policy denials as flow drop events.
+// The channel is closed when Run returns.
+func (r *Reader) DroppedFlows() <-chan hubble.FlowDrop {
+	return r.drops
+}
+
+// Stats returns access log statistics.
+func (r *Reader) Stats() ReaderStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.stats
+}
+
+// Run reads the input until it is exhausted, or with Follow until ctx is
+// cancelled. It waits for the consumer instead of dropping denials when the
+// buffer is full, and returns an error only if reading fails.
+func (r *Reader) Run(ctx context.Context) error {
+	defer close(r.drops)
+
+	var (
+		br      = bufio.NewReader(r.r)
+		partial []byte
+	)
+	f, _ := r.r.(*os.File)
+	defer func() {
+		if f != nil && f != r.r {
+			f.Close()
+		}
+	}()
+	for {
+		line, readErr := br.ReadBytes('\n')
+		if readErr != nil && !errors.Is(readErr, io.EOF) {
+			return fmt.Errorf("read access log: %w", readErr)
+		}
+
+		// A followed file may end in a line that is still being written;
+		// keep it until the rest arrives.
+		if r.opts.Follow && readErr != nil {
+			partial = append(partial, line...)
+			if !r.poll(ctx) {
+				return nil
+			}
+			if f == nil || r.opts.Path == "" {
+				continue
+			}
+			rotated, err := util.ReopenIfRotated(f, r.opts.Path)
+			if err != nil {
+				r.logger.Debug("access log not reopened", zap.Error(err))
+				continue
+			}
+			if rotated != nil {
+				if rotated != f && f != r.r {
+					f.Close()
+				}
+				f, br, partial = rotated, bufio.NewReader(rotated), nil
+				r.count(func(s *ReaderStats) { s.Rotations++ })
+				r.logger.Info("Access log rotated, reopened", zap.String("path", r.opts.Path))
+			}
+			continue
+		}
+		if len(partial) > 0 {
+			line = append(partial, line...)
+			partial = nil
+		}
+
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			if !r.handleLine(ctx, line) {
+				return nil
+			}
+		}
+
+		if readErr != nil {
+			stats := r.Stats()
+			r.logger.Info("access log finished",
+				zap.Uint64("entries", stats.Entries),
+				zap.Uint64("denials", stats.Denials),
+				zap.Uint64("invalid", stats.Invalid))
+			return nil
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// handleLine decodes a line and emits it if it is a denial. It returns
+// false if ctx was cancelled while waiting for the consumer.
+func (r *Reader) handleLine(ctx context.Context, line []byte) bool {
+	r.count(func(s *ReaderStats) { s.Lines++ })
+
+	entry, err := decodeEntry(line, r.opts.Proxy)
+	if err != nil {
+		r.count(func(s *ReaderStats) { s.Invalid++ })
+		entriesTotal.WithLabelValues(sourceFile, resultInvalid).Inc()
+		r.logger.Debug("skipping line that is not an access log entry", zap.Uint64("line", r.Stats().Lines), zap.Error(err))
+		return true
+	}
+	r.count(func(s *ReaderStats) { s.Entries++ })
+
+	if !entry.Denied() {
+		entriesTotal.WithLabelValues(sourceFile, resultAllowed).Inc()
+		return true
+	}
+	entriesTotal.WithLabelValues(sourceFile, resultDenied).Inc()
+	select {
+	case r.drops <- entry.FlowDrop():
+		r.count(func(s *ReaderStats) { s.Denials++ })
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// poll waits for more input to be appended. It returns false if ctx was
+// cancelled.
+func (r *Reader) poll(ctx context.Context) bool {
+	timer := time.NewTimer(r.opts.PollInterval)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (r *Reader) count(fn func(*ReaderStats)) {
+	r.mu.Lock()
+	fn(&r.stats)
+	r.mu.Unlock()
+}
+
+// jsonEntry is an access log line in Istio's JSON encoding. Log shippers
+// such as Fluent Bit add the pod's metadata under "kubernetes".
+type jsonEntry struct {
+	StartTime                    string     `json:"start_time"`
+	Method                       string     `json:"method"`
+	Authority                    string     `json:"authority"`
+	Path                         string     `json:"path"`
+	ResponseCode                 jsonNumber `json:"response_code"`
+	ResponseFlags                string     `json:"response_flags"`
+	ResponseCodeDetails          string     `json:"response_code_details"`
+	ConnectionTerminationDetails string     `json:"connection_termination_details"`
+	UpstreamCluster              string     `json:"upstream_cluster"`
+	DownstreamRemoteAddress      string     `json:"downstream_remote_address"`
+	DownstreamLocalAddress       string     `json:"downstream_local_address"`
+	DownstreamPeerURISAN         string     `json:"downstream_peer_uri_san"`
+	RequestID                    string     `json:"request_id"`
+
+	Kubernetes *struct {
+		NamespaceName string            `json:"namespace_name"`
+		PodName       string            `json:"pod_name"`
+		Labels        map[string]string `json:"labels"`
+	} `json:"kubernetes"`
+}
+
+// jsonNumber accepts a number encoded as a JSON number or string, as
+// Envoy's JSON formatter does depending on the format string.
+type jsonNumber uint32
+
+func (n *jsonNumber) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "" || s == "-" || s == "null" {
+		*n = 0
+		return nil
+	}
+	v, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid number %s", data)
+	}
+	*n = jsonNumber(v)
+	return nil
+}
+
+// decodeEntry decodes one JSON access log line. Lines without a response
+// code, flags or upstream cluster are rejected as not being access logs.
+func decodeEntry(line []byte, proxy Proxy) (Entry, error) {
+	var j jsonEntry
+	if err := json.Unmarshal(line, &j); err != nil {
+		return Entry{}, err
+	}
+	if j.ResponseCode == 0 && j.ResponseFlags == "" && j.UpstreamCluster == "" {
+		return Entry{}, errors.New("no access log fields")
+	}
+
+	e := Entry{
+		Proxy:                        proxy,
+		Method:                       field(j.Method),
+		Authority:                    field(j.Authority),
+		Path:                         field(j.Path),
+		ResponseCode:                 uint32(j.ResponseCode),
+		ResponseFlags:                splitFlags(j.ResponseFlags),
+		ResponseCodeDetails:          field(j.ResponseCodeDetails),
+		ConnectionTerminationDetails: field(j.ConnectionTerminationDetails),
+		UpstreamCluster:              field(j.UpstreamCluster),
+		DownstreamRemoteAddress:      field(j.DownstreamRemoteAddress),
+		DownstreamLocalAddress:       field(j.DownstreamLocalAddress),
+		PeerPrincipal:                field(j.DownstreamPeerURISAN),
+		RequestID:                    field(j.RequestID),
+	}
+	if t, err := time.Parse(time.RFC3339Nano, j.StartTime); err == nil {
+		e.Time = t
+	}
+	if k := j.Kubernetes; k != nil && k.NamespaceName != "" {
+		e.Proxy = Proxy{Namespace: k.NamespaceName, PodName: k.PodName, Labels: k.Labels}
+	}
+	return e, nil
+}
+
+// field returns an access log field, mapping Envoy's "-" placeholder for
+// unset values to empty.
+func field(s string) string {
+	if s == "-" {
+		return ""
+	}
+	return s
+}
+
+// splitFlags splits Envoy's comma-separated response flags.
+func splitFlags(s string) []string {
+	if s = field(s); s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
@@ -0,0 +1,178 @@
+package accesslog
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nightjarctl/nightjar/internal/hubble"
+)
+
+// readAll runs r to completion and returns the denials.
+func readAll(t *testing.T, r *Reader) []hubble.FlowDrop {
+	t.Helper()
+	done := make(chan error, 1)
+	go func() { done <- r.Run(context.Background()) }()
+
+	var drops []hubble.FlowDrop
+	for drop := range r.DroppedFlows() {
+		drops = append(drops, drop)
+	}
+	require.NoError(t, <-done)
+	return drops
+}
+
+func TestReader_IstioProxyLog(t *testing.T) {
+	f, err := os.Open("testdata/istio-proxy.log")
+	require.NoError(t, err)
+	defer f.Close()
+
+	r := NewReader(f, ReaderOptions{Proxy: Proxy{Namespace: "shop", PodName: "orders-0"}})
+	drops := readAll(t, r)
+
+	require.Len(t, drops, 3)
+	rbac := drops[0]
+	assert.Equal(t, "req-denied", rbac.L7.RequestID)
+	assert.Equal(t, time.Date(2026, 3, 2, 10, 0, 2, 0, time.UTC), rbac.Time.UTC())
+	assert.Equal(t, hubble.DirectionIngress, rbac.Direction)
+	assert.Equal(t, "frontend", rbac.Source.Namespace)
+	assert.Equal(t, "orders-0", rbac.Destination.PodName)
+	assert.Equal(t, "deny-delete", rbac.PolicyName)
+	assert.Equal(t, "orders.shop.svc.cluster.local", rbac.L7.Authority)
+
+	extAuthz := drops[1]
+	assert.Equal(t, hubble.DirectionEgress, extAuthz.Direction)
+	assert.Equal(t, []string{"UAEX"}, extAuthz.L7.ResponseFlags)
+	assert.Equal(t, uint32(403), extAuthz.L7.ResponseCode)
+	assert.Equal(t, "billing", extAuthz.Destination.Namespace)
+
+	tcp := drops[2]
+	assert.Empty(t, tcp.L7.Method)
+	assert.Equal(t, uint32(5432), tcp.L4.DestinationPort)
+
+	assert.Equal(t, ReaderStats{Lines: 6, Entries: 4, Denials: 3, Invalid: 2}, r.Stats())
+}
+
+func TestReader_KubernetesMetadata(t *testing.T) {
+	line := `{"response_code":403,"response_code_details":"rbac_access_denied_matched_policy[none]","upstream_cluster":"inbound|80||",` +
+		`"kubernetes":{"namespace_name":"shop","pod_name":"cart-1","labels":{"app":"cart"}}}`
+	r := NewReader(strings.NewReader(line), ReaderOptions{Proxy: Proxy{Namespace: "default"}})
+
+	drops := readAll(t, r)
+
+	require.Len(t, drops, 1)
+	assert.Equal(t, "shop", drops[0].Destination.Namespace)
+	assert.Equal(t, "cart-1", drops[0].Destination.PodName)
+	assert.Equal(t, map[string]string{"app": "cart"}, drops[0].Destination.Labels)
+}
+
+func TestReader_Follow(t *testing.T) {
+	pr, pw := io.Pipe()
+	r := NewReader(pr, ReaderOptions{Follow: true, PollInterval: 10 * time.Millisecond})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- r.Run(ctx) }()
+
+	// Write a line in two parts; it is emitted once complete.
+	go func() {
+		_, _ = pw.Write([]byte(`{"response_code":403,"response_flags":"UAEX",`))
+		_, _ = pw.Write([]byte(`"upstream_cluster":"inbound|80||"}` + "\n"))
+		pw.Close()
+	}()
+
+	select {
+	case drop := <-r.DroppedFlows():
+		assert.Equal(t, []string{"UAEX"}, drop.L7.ResponseFlags)
+	case <-time.After(2 * time.Second):
+		t.Fatal("denial was not read")
+	}
+
+	// Reading continues past the end of the input until cancelled.
+	select {
+	case err := <-done:
+		t.Fatalf("Run returned before cancellation: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestReader_FollowRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "access.log")
+	require.NoError(t, os.WriteFile(path, nil, 0o600))
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	r := NewReader(f, ReaderOptions{Follow: true, Path: path, PollInterval: 10 * time.Millisecond})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- r.Run(ctx) }()
+	defer func() {
+		cancel()
+		require.NoError(t, <-done)
+	}()
+
+	appendLine := func(requestID string) {
+		t.Helper()
+		w, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		require.NoError(t, err)
+		_, err = w.WriteString(`{"response_code":403,"response_flags":"UAEX","upstream_cluster":"inbound|80||","request_id":"` + requestID + `"}` + "\n")
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+	}
+	receive := func() string {
+		t.Helper()
+		select {
+		case drop := <-r.DroppedFlows():
+			return drop.L7.RequestID
+		case <-time.After(2 * time.Second):
+			t.Fatal("denial was not read")
+			return ""
+		}
+	}
+
+	appendLine("r1")
+	assert.Equal(t, "r1", receive())
+
+	// Rename and recreate, as logrotate does by default.
+	require.NoError(t, os.Rename(path, filepath.Join(dir, "access.log.1")))
+	appendLine("r2")
+	assert.Equal(t, "r2", receive())
+
+	// Truncate in place, as copytruncate does.
+	require.NoError(t, os.Truncate(path, 0))
+	time.Sleep(50 * time.Millisecond)
+	appendLine("r3")
+	assert.Equal(t, "r3", receive())
+
+	assert.Equal(t, uint64(2), r.Stats().Rotations)
+}
+
+func TestReader_ContextCancelled(t *testing.T) {
+	line := `{"response_code":403,"response_flags":"UAEX","upstream_cluster":"inbound|80||"}` + "\n"
+	r := NewReader(strings.NewReader(strings.Repeat(line, 10)), ReaderOptions{BufferSize: 1})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Nobody consumes, so Run must return on cancellation.
+	require.NoError(t, r.Run(ctx))
+}
+
+func TestJSONNumber(t *testing.T) {
+	for input, want := range map[string]uint32{`403`: 403, `"403"`: 403, `"-"`: 0, `null`: 0} {
+		var n jsonNumber
+		require.NoError(t, n.UnmarshalJSON([]byte(input)), input)
+		assert.Equal(t, want, uint32(n), input)
+	}
+	var n jsonNumber
+	assert.Error(t, n.UnmarshalJSON([]byte(`"abc"`)))
+}
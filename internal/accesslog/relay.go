@@ -0,0 +1,99 @@
+package accesslog
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/nightjarctl/nightjar/internal/hubble"
+	"github.com/nightjarctl/nightjar/internal/shard"
+)
+
+// RelayPath is the path on the relay server replicas relay access log
+// denials to.
+const RelayPath = "/api/v1/relay/access-log-denials"
+
+// maxRelayBytes bounds the size of a relayed denial.
+const maxRelayBytes = 1 << 20
+
+// Relay is the FlowSource of the access log denials other replicas relay to
+// this one: a Server behind a Service hands the denials of namespaces it
+// does not own to their owner. Mount it on the shard.RelayServer at RelayPath.
+//
+// Like the Server, it drops denials when the buffer is full.
+type Relay struct {
+	logger *zap.Logger
+	drops  chan hubble.FlowDrop
+}
+
+// NewRelay creates a Relay buffering up to bufferSize denials.
+func NewRelay(bufferSize int, logger *zap.Logger) *Relay {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	if bufferSize == 0 {
+		bufferSize = DefaultServerOptions().BufferSize
+	}
+	return &Relay{
+		logger: logger.Named("accesslog-relay"),
+		drops:  make(chan hubble.FlowDrop, bufferSize),
+	}
+}
+
+// DroppedFlows returns the channel of relayed denials. It is never closed.
+func (r *Relay) DroppedFlows() <-chan hubble.FlowDrop {
+	return r.drops
+}
+
+// ServeHTTP accepts a denial relayed by another replica.
+func (r *Relay) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var drop hubble.FlowDrop
+	if err := json.NewDecoder(io.LimitReader(req.Body, maxRelayBytes)).Decode(&drop); err != nil {
+		entriesTotal.WithLabelValues(sourceRelay, resultInvalid).Inc()
+		http.Error(w, "decoding access log denial: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	offer(r.drops, drop, sourceRelay, r.logger)
+	w.WriteHeader(http.StatusOK)
+}
+
+// relay hands drop to the replicas owning the namespaces of its endpoints,
+// if those are other ones, and reports whether this replica owns any.
+func relay(ctx context.Context, shards *shard.Forwarder, drop hubble.FlowDrop) (bool, error) {
+	if shards == nil {
+		return true, nil
+	}
+	body, err := json.Marshal(drop)
+	if err != nil {
+		return false, err
+	}
+	var namespaces []string
+	for _, ns := range []string{drop.Source.Namespace, drop.Destination.Namespace} {
+		if ns != "" {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	return shards.Relay(ctx, RelayPath, body, namespaces...)
+}
+
+// offer emits drop on drops, or drops it when the buffer is full.
+func offer(drops chan<- hubble.FlowDrop, drop hubble.FlowDrop, source string, logger *zap.Logger) {
+	select {
+	case drops <- drop:
+		entriesTotal.WithLabelValues(source, resultDenied).Inc()
+	default:
+		entriesTotal.WithLabelValues(source, resultDropped).Inc()
+		logger.Warn("Access log denial dropped, buffer full",
+			zap.String("source_namespace", drop.Source.Namespace),
+			zap.String("source_pod", drop.Source.PodName),
+			zap.String("dest_namespace", drop.Destination.Namespace),
+			zap.String("dest_pod", drop.Destination.PodName))
+	}
+}
@@ -101,7 +101,7 @@ func (a *Adapter) Parse(ctx context.Context, obj *unstructured.Unstructured) ([]
 				Summary:            buildIngressSummary(name, ingress, ingressDeny, isClusterWide),
 				RemediationHint:    buildRemediationHint(name, namespace, isClusterWide),
 				Details:            extractIngressDetails(ingress, ingressDeny),
-				RawObject:          obj.DeepCopy(),
+				RawObject:          obj,
 			}
 			constraints = append(constraints, c)
 		}
@@ -132,7 +132,7 @@ func (a *Adapter) Parse(ctx context.Context, obj *unstructured.Unstructured) ([]
 				Summary:            buildEgressSummary(name, egress, egressDeny, isClusterWide),
 				RemediationHint:    buildRemediationHint(name, namespace, isClusterWide),
 				Details:            extractEgressDetails(egress, egressDeny),
-				RawObject:          obj.DeepCopy(),
+				RawObject:          obj,
 			}
 			constraints = append(constraints, c)
 		}
@@ -152,7 +152,7 @@ func (a *Adapter) Parse(ctx context.Context, obj *unstructured.Unstructured) ([]
 				Summary:            fmt.Sprintf("CiliumNetworkPolicy %q denies all traffic to selected pods", name),
 				RemediationHint:    buildRemediationHint(name, namespace, isClusterWide),
 				Details:            map[string]interface{}{"deniesAll": true},
-				RawObject:          obj.DeepCopy(),
+				RawObject:          obj,
 			}
 			constraints = append(constraints, c)
 		}
@@ -103,7 +103,7 @@ func (a *Adapter) Parse(ctx context.Context, obj *unstructured.Unstructured) ([]
 		Remediation:        remediation,
 		Details:            details,
 		Tags:               tags,
-		RawObject:          obj.DeepCopy(),
+		RawObject:          obj,
 	}
 
 	return []types.Constraint{constraint}, nil
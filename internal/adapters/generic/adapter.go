@@ -8,6 +8,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8stypes "k8s.io/apimachinery/pkg/types"
 
 	v1alpha1 "github.com/nightjarctl/nightjar/api/v1alpha1"
 	"github.com/nightjarctl/nightjar/internal/types"
@@ -67,34 +68,14 @@ func (a *Adapter) ParseWithGVR(ctx context.Context, obj *unstructured.Unstructur
 
 	// Extract severity from annotation or default to Info
 	severity := types.SeverityInfo
-	if severityStr := getAnnotation(annotations, annotationSeverity); severityStr != "" {
-		switch severityStr {
-		case "Critical", "critical":
-			severity = types.SeverityCritical
-		case "Warning", "warning":
-			severity = types.SeverityWarning
-		case "Info", "info":
-			severity = types.SeverityInfo
-		}
+	if sev, ok := parseSeverity(getAnnotation(annotations, annotationSeverity)); ok {
+		severity = sev
 	}
 
 	// Extract constraint type from annotation or default to Unknown
 	constraintType := types.ConstraintTypeUnknown
-	if typeStr := getAnnotation(annotations, annotationType); typeStr != "" {
-		switch typeStr {
-		case "NetworkIngress":
-			constraintType = types.ConstraintTypeNetworkIngress
-		case "NetworkEgress":
-			constraintType = types.ConstraintTypeNetworkEgress
-		case "Admission":
-			constraintType = types.ConstraintTypeAdmission
-		case "ResourceLimit":
-			constraintType = types.ConstraintTypeResourceLimit
-		case "MeshPolicy":
-			constraintType = types.ConstraintTypeMeshPolicy
-		case "MissingResource":
-			constraintType = types.ConstraintTypeMissing
-		}
+	if ct, ok := parseConstraintType(getAnnotation(annotations, annotationType)); ok {
+		constraintType = ct
 	}
 
 	// Build details by extracting common fields
@@ -160,7 +141,7 @@ func (a *Adapter) ParseWithGVR(ctx context.Context, obj *unstructured.Unstructur
 		Summary:            summary,
 		RemediationHint:    "This constraint was auto-discovered. Contact your platform team for details.",
 		Details:            details,
-		RawObject:          obj.DeepCopy(),
+		RawObject:          obj,
 	}
 
 	return []types.Constraint{c}, nil
@@ -168,7 +149,8 @@ func (a *Adapter) ParseWithGVR(ctx context.Context, obj *unstructured.Unstructur
 
 // ParseWithConfig parses a CRD using optional field-path configuration from a
 // ConstraintProfile. When cfg.FieldPaths is non-nil, the configured paths are
-// used instead of the default hardcoded selector probing.
+// used instead of the default hardcoded selector probing. When
+// cfg.FieldPaths.RulesPath is set, one constraint is produced per matched rule.
 func (a *Adapter) ParseWithConfig(ctx context.Context, obj *unstructured.Unstructured, gvr schema.GroupVersionResource, cfg ParseConfig) ([]types.Constraint, error) {
 	constraints, err := a.ParseWithGVR(ctx, obj, gvr)
 	if err != nil {
@@ -183,13 +165,8 @@ func (a *Adapter) ParseWithConfig(ctx context.Context, obj *unstructured.Unstruc
 	// Apply severity override from ConstraintProfile, but only when
 	// no per-object annotation is present (annotation takes highest precedence).
 	if cfg.SeverityOverride != "" && getAnnotation(obj.GetAnnotations(), annotationSeverity) == "" {
-		switch cfg.SeverityOverride {
-		case "Critical":
-			c.Severity = types.SeverityCritical
-		case "Warning":
-			c.Severity = types.SeverityWarning
-		case "Info":
-			c.Severity = types.SeverityInfo
+		if sev, ok := parseSeverity(cfg.SeverityOverride); ok {
+			c.Severity = sev
 		}
 	}
 
@@ -197,37 +174,113 @@ func (a *Adapter) ParseWithConfig(ctx context.Context, obj *unstructured.Unstruc
 		return constraints, nil
 	}
 
-	// Re-extract selectors using configured paths
-	if cfg.FieldPaths.SelectorPath != "" {
-		fields := splitFieldPath(cfg.FieldPaths.SelectorPath)
-		if sel := util.SafeNestedLabelSelector(obj.Object, fields...); sel != nil {
-			c.WorkloadSelector = sel
-		}
+	paths, err := compileFieldPaths(cfg.FieldPaths)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s/%s: invalid field paths: %w", obj.GetKind(), obj.GetNamespace(), obj.GetName(), err)
 	}
-	if cfg.FieldPaths.NamespaceSelectorPath != "" {
-		fields := splitFieldPath(cfg.FieldPaths.NamespaceSelectorPath)
-		if sel := util.SafeNestedLabelSelector(obj.Object, fields...); sel != nil {
-			c.NamespaceSelector = sel
+
+	if paths.rules == nil {
+		applyFieldPaths(c, obj, obj.Object, paths)
+		return constraints, nil
+	}
+
+	return expandRules(*c, obj, paths), nil
+}
+
+// expandRules fans an object out into one constraint per element matched by
+// the rules path. Per-rule paths are evaluated against each rule. An object
+// with no matching rules keeps its single object-level constraint.
+func expandRules(base types.Constraint, obj *unstructured.Unstructured, paths *compiledPaths) []types.Constraint {
+	rules := paths.rules.values(obj.Object, obj.Object)
+	if len(rules) == 1 {
+		// "spec.rules" matches the list itself rather than its elements.
+		if list, ok := rules[0].([]interface{}); ok {
+			rules = list
 		}
 	}
+	if len(rules) == 0 {
+		applyFieldPaths(&base, obj, obj.Object, paths)
+		return []types.Constraint{base}
+	}
 
-	// Extract effect from configured path
-	if cfg.FieldPaths.EffectPath != "" {
-		fields := splitFieldPath(cfg.FieldPaths.EffectPath)
-		if effect := util.SafeNestedString(obj.Object, fields...); effect != "" {
-			c.Effect = effect
+	constraints := make([]types.Constraint, 0, len(rules))
+	named := make(map[string]bool, len(rules))
+	for i, rule := range rules {
+		// Named rules keep their UID when rules are added, removed or
+		// reordered; unnamed and duplicate-named rules are keyed by index.
+		uid := k8stypes.UID(fmt.Sprintf("%s-rule-%d", base.UID, i))
+		ruleName := paths.name.firstString(obj.Object, rule)
+		if ruleName == "" {
+			ruleName = fmt.Sprintf("rule-%d", i)
+		} else if !named[ruleName] {
+			named[ruleName] = true
+			uid = k8stypes.UID(fmt.Sprintf("%s-rule:%s", base.UID, ruleName))
 		}
+
+		c := base
+		c.UID = uid
+		c.Name = fmt.Sprintf("%s/%s", base.Name, ruleName)
+		c.Summary = fmt.Sprintf("%s (rule %q)", base.Summary, ruleName)
+		c.Details = make(map[string]interface{}, len(base.Details)+2)
+		for k, v := range base.Details {
+			c.Details[k] = v
+		}
+		c.Details["ruleIndex"] = i
+		c.Details["ruleName"] = ruleName
+
+		applyFieldPaths(&c, obj, rule, paths)
+		constraints = append(constraints, c)
+	}
+	return constraints
+}
+
+// applyFieldPaths overrides constraint fields with values extracted by the
+// configured paths. current is the object itself or, with a rules path, the
+// rule being extracted. Annotations keep precedence over extracted summary,
+// severity and constraint type.
+func applyFieldPaths(c *types.Constraint, obj *unstructured.Unstructured, current interface{}, paths *compiledPaths) {
+	root := obj.Object
+	annotations := obj.GetAnnotations()
+
+	if sel := paths.selector.labelSelector(root, current); sel != nil {
+		c.WorkloadSelector = sel
+	}
+	if sel := paths.namespaceSelector.labelSelector(root, current); sel != nil {
+		c.NamespaceSelector = sel
 	}
 
-	// Extract summary from configured path (annotation still takes precedence)
-	if cfg.FieldPaths.SummaryPath != "" && getAnnotation(obj.GetAnnotations(), annotationSummary) == "" {
-		fields := splitFieldPath(cfg.FieldPaths.SummaryPath)
-		if summary := util.SafeNestedString(obj.Object, fields...); summary != "" {
+	if effect := paths.effect.firstString(root, current); effect != "" {
+		c.Effect = effect
+	}
+
+	if getAnnotation(annotations, annotationSummary) == "" {
+		if summary := paths.summary.firstString(root, current); summary != "" {
 			c.Summary = summary
 		}
 	}
 
-	return constraints, nil
+	if ports := extractPorts(paths.ports, root, current); len(ports) > 0 {
+		if c.Details == nil {
+			c.Details = map[string]interface{}{}
+		}
+		c.Details["ports"] = ports
+	}
+
+	if getAnnotation(annotations, annotationSeverity) == "" {
+		if raw := paths.severity.firstString(root, current); raw != "" {
+			if sev, ok := resolveSeverity(raw, paths.severityMapping); ok {
+				c.Severity = sev
+			}
+		}
+	}
+
+	if getAnnotation(annotations, annotationType) == "" {
+		if raw := paths.constraintType.firstString(root, current); raw != "" {
+			if ct, ok := resolveConstraintType(raw, paths.constraintTypeMapping); ok {
+				c.ConstraintType = ct
+			}
+		}
+	}
 }
 
 // Parse implements the Adapter interface but requires GVR context.
@@ -263,6 +316,38 @@ func extractSelectors(spec map[string]interface{}) (workload, namespace *metav1.
 	return workload, namespace
 }
 
+// parseSeverity converts a severity name to a Severity.
+func parseSeverity(s string) (types.Severity, bool) {
+	switch s {
+	case "Critical", "critical":
+		return types.SeverityCritical, true
+	case "Warning", "warning":
+		return types.SeverityWarning, true
+	case "Info", "info":
+		return types.SeverityInfo, true
+	}
+	return "", false
+}
+
+// parseConstraintType converts a constraint type name to a ConstraintType.
+func parseConstraintType(s string) (types.ConstraintType, bool) {
+	switch s {
+	case "NetworkIngress":
+		return types.ConstraintTypeNetworkIngress, true
+	case "NetworkEgress":
+		return types.ConstraintTypeNetworkEgress, true
+	case "Admission":
+		return types.ConstraintTypeAdmission, true
+	case "ResourceLimit":
+		return types.ConstraintTypeResourceLimit, true
+	case "MeshPolicy":
+		return types.ConstraintTypeMeshPolicy, true
+	case "MissingResource":
+		return types.ConstraintTypeMissing, true
+	}
+	return "", false
+}
+
 // getAnnotation safely retrieves an annotation value.
 func getAnnotation(annotations map[string]string, key string) string {
 	if annotations == nil {
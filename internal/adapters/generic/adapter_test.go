@@ -487,3 +487,142 @@ func TestParseWithGVR_AllConstraintTypes(t *testing.T) {
 		})
 	}
 }
+
+func multiRuleConfig() ParseConfig {
+	return ParseConfig{
+		FieldPaths: &v1alpha1.FieldPaths{
+			RulesPath:             "{.spec.rules[*]}",
+			NamePath:              "name",
+			SelectorPath:          "podSelector",
+			NamespaceSelectorPath: "$.spec.namespaceSelector",
+			EffectPath:            "action",
+			PortsPath:             "{.ports[*]}",
+			SeverityPath:          "enforcement",
+			SeverityMapping:       map[string]string{"Enforce": "Critical", "Audit": "Warning"},
+			ConstraintTypePath:    "direction",
+			ConstraintTypeMapping: map[string]string{"egress": "NetworkEgress", "ingress": "NetworkIngress"},
+		},
+	}
+}
+
+func TestParseWithConfig_RulesPath(t *testing.T) {
+	a := New()
+	obj := loadFixture(t, "testdata/multi_rule_crd.yaml")
+	gvr := schema.GroupVersionResource{Group: "firewall.corp.io", Version: "v1", Resource: "egressfirewalls"}
+
+	constraints, err := a.ParseWithConfig(context.Background(), obj, gvr, multiRuleConfig())
+	require.NoError(t, err)
+	require.Len(t, constraints, 3)
+
+	stripe := constraints[0]
+	assert.Equal(t, "uid-multi-rule-rule:allow-stripe", string(stripe.UID))
+	assert.Equal(t, "payments-egress/allow-stripe", stripe.Name)
+	assert.Equal(t, "Allow", stripe.Effect)
+	assert.Equal(t, types.SeverityWarning, stripe.Severity)
+	assert.Equal(t, types.ConstraintTypeNetworkEgress, stripe.ConstraintType)
+	assert.Equal(t, []string{"443/TCP"}, stripe.Details["ports"])
+	require.NotNil(t, stripe.WorkloadSelector)
+	assert.Equal(t, "checkout", stripe.WorkloadSelector.MatchLabels["app"])
+	require.NotNil(t, stripe.NamespaceSelector, "$-prefixed paths read from the object root")
+	assert.Equal(t, "payments", stripe.NamespaceSelector.MatchLabels["team"])
+	assert.Contains(t, stripe.Summary, `rule "allow-stripe"`)
+
+	smtp := constraints[1]
+	assert.Equal(t, "payments-egress/deny-smtp", smtp.Name)
+	assert.Equal(t, types.SeverityCritical, smtp.Severity)
+	assert.Equal(t, []string{"25/TCP", "587/TCP"}, smtp.Details["ports"])
+	assert.Equal(t, "mailer", smtp.WorkloadSelector.MatchLabels["app"])
+
+	unnamed := constraints[2]
+	assert.Equal(t, "payments-egress/rule-2", unnamed.Name)
+	assert.Equal(t, "uid-multi-rule-rule-2", string(unnamed.UID))
+	assert.Equal(t, types.ConstraintTypeNetworkIngress, unnamed.ConstraintType)
+	assert.Nil(t, unnamed.WorkloadSelector)
+	assert.Equal(t, 2, unnamed.Details["ruleIndex"])
+
+	// Rules must not share a Details map.
+	_, hasPorts := unnamed.Details["ports"]
+	assert.False(t, hasPorts)
+}
+
+func TestParseWithConfig_RulesPathReorderKeepsUIDs(t *testing.T) {
+	a := New()
+	obj := loadFixture(t, "testdata/multi_rule_crd.yaml")
+	gvr := schema.GroupVersionResource{Group: "firewall.corp.io", Version: "v1", Resource: "egressfirewalls"}
+
+	before, err := a.ParseWithConfig(context.Background(), obj, gvr, multiRuleConfig())
+	require.NoError(t, err)
+	require.Len(t, before, 3)
+
+	rules, _, _ := unstructured.NestedSlice(obj.Object, "spec", "rules")
+	rules[0], rules[1] = rules[1], rules[0]
+	require.NoError(t, unstructured.SetNestedSlice(obj.Object, rules, "spec", "rules"))
+
+	after, err := a.ParseWithConfig(context.Background(), obj, gvr, multiRuleConfig())
+	require.NoError(t, err)
+	require.Len(t, after, 3)
+	assert.Equal(t, before[0].UID, after[1].UID, "allow-stripe keeps its UID")
+	assert.Equal(t, before[1].UID, after[0].UID, "deny-smtp keeps its UID")
+}
+
+func TestParseWithConfig_RulesPathDottedList(t *testing.T) {
+	a := New()
+	obj := loadFixture(t, "testdata/multi_rule_crd.yaml")
+	gvr := schema.GroupVersionResource{Group: "firewall.corp.io", Version: "v1", Resource: "egressfirewalls"}
+
+	cfg := ParseConfig{FieldPaths: &v1alpha1.FieldPaths{RulesPath: "spec.rules", NamePath: "name"}}
+	constraints, err := a.ParseWithConfig(context.Background(), obj, gvr, cfg)
+	require.NoError(t, err)
+	assert.Len(t, constraints, 3, "a dotted path to a list expands its elements")
+}
+
+func TestParseWithConfig_RulesPathNoMatches(t *testing.T) {
+	a := New()
+	obj := loadFixture(t, "testdata/unknown_crd.yaml")
+	gvr := schema.GroupVersionResource{Group: "custom.io", Version: "v1", Resource: "things"}
+
+	cfg := ParseConfig{FieldPaths: &v1alpha1.FieldPaths{RulesPath: "{.spec.doesNotExist[*]}"}}
+	constraints, err := a.ParseWithConfig(context.Background(), obj, gvr, cfg)
+	require.NoError(t, err)
+	require.Len(t, constraints, 1)
+	assert.Equal(t, obj.GetUID(), constraints[0].UID)
+}
+
+func TestParseWithConfig_JSONPathIndex(t *testing.T) {
+	a := New()
+	obj := loadFixture(t, "testdata/multi_rule_crd.yaml")
+	gvr := schema.GroupVersionResource{Group: "firewall.corp.io", Version: "v1", Resource: "egressfirewalls"}
+
+	cfg := ParseConfig{FieldPaths: &v1alpha1.FieldPaths{SummaryPath: "spec.rules[1].name"}}
+	constraints, err := a.ParseWithConfig(context.Background(), obj, gvr, cfg)
+	require.NoError(t, err)
+	require.Len(t, constraints, 1)
+	assert.Equal(t, "deny-smtp", constraints[0].Summary)
+}
+
+func TestParseWithConfig_AnnotationsOverrideRulePaths(t *testing.T) {
+	a := New()
+	obj := loadFixture(t, "testdata/multi_rule_crd.yaml")
+	obj.SetAnnotations(map[string]string{
+		"nightjar.io/severity":        "Info",
+		"nightjar.io/constraint-type": "Admission",
+	})
+	gvr := schema.GroupVersionResource{Group: "firewall.corp.io", Version: "v1", Resource: "egressfirewalls"}
+
+	constraints, err := a.ParseWithConfig(context.Background(), obj, gvr, multiRuleConfig())
+	require.NoError(t, err)
+	for _, c := range constraints {
+		assert.Equal(t, types.SeverityInfo, c.Severity)
+		assert.Equal(t, types.ConstraintTypeAdmission, c.ConstraintType)
+	}
+}
+
+func TestParseWithConfig_InvalidPath(t *testing.T) {
+	a := New()
+	obj := loadFixture(t, "testdata/multi_rule_crd.yaml")
+	gvr := schema.GroupVersionResource{Group: "firewall.corp.io", Version: "v1", Resource: "egressfirewalls"}
+
+	cfg := ParseConfig{FieldPaths: &v1alpha1.FieldPaths{RulesPath: "{.spec.rules[*"}}
+	_, err := a.ParseWithConfig(context.Background(), obj, gvr, cfg)
+	assert.ErrorContains(t, err, "rulesPath")
+}
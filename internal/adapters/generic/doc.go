@@ -18,9 +18,18 @@
 //  6. Look for spec.rules (common pattern in policy CRDs) → count rules
 //  7. Look for spec.parameters → note as "parameterized policy"
 //
+// # ConstraintProfile field paths
+//
+// ParseWithConfig applies v1alpha1.FieldPaths from a ConstraintProfile. Paths
+// are dotted ("spec.action") or JSONPath ("{.spec.rules[*]}"). With RulesPath
+// set, the object fans out into one Constraint per rule (UID "{uid}-rule:{rule}",
+// or "{uid}-rule-{i}" for rules without a unique name; Name "{name}/{rule}")
+// and the remaining paths are evaluated against each rule; "$"-prefixed paths
+// read from the whole object.
+//
 // # Output
 //
-// Always produces exactly ONE Constraint:
+// Without RulesPath, always produces exactly ONE Constraint:
 //   - ConstraintType: Unknown (override via annotation)
 //   - Severity: Info (override via annotation)
 //   - Summary: from annotation, or "{Kind} {name} in {namespace}"
@@ -0,0 +1,252 @@
+package generic
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/jsonpath"
+
+	v1alpha1 "github.com/nightjarctl/nightjar/api/v1alpha1"
+	"github.com/nightjarctl/nightjar/internal/types"
+	"github.com/nightjarctl/nightjar/internal/util"
+)
+
+// fieldPath is a compiled ConstraintProfile path. Plain dotted paths
+// ("spec.action") are treated as the JSONPath "{.spec.action}".
+type fieldPath struct {
+	expr string
+
+	// fromRoot is set for "$"-prefixed paths, which are evaluated against the
+	// whole object even when extracting per-rule fields.
+	fromRoot bool
+
+	jp *jsonpath.JSONPath
+}
+
+// compileFieldPath parses a dotted path or JSONPath expression.
+// Returns nil for an empty expression.
+func compileFieldPath(expr string) (*fieldPath, error) {
+	body := strings.TrimSpace(expr)
+	if body == "" {
+		return nil, nil
+	}
+	if strings.HasPrefix(body, "{") && strings.HasSuffix(body, "}") {
+		body = strings.TrimSpace(body[1 : len(body)-1])
+	}
+
+	fromRoot := false
+	switch {
+	case strings.HasPrefix(body, "$"):
+		fromRoot = true
+		body = body[1:]
+	case strings.HasPrefix(body, "@"):
+		body = body[1:]
+	}
+
+	switch {
+	case !strings.ContainsAny(body, "[]*?()@$,'\""):
+		// Plain dotted path; tolerate leading, trailing and doubled dots.
+		if parts := splitFieldPath(body); len(parts) > 0 {
+			body = "." + strings.Join(parts, ".")
+		} else {
+			body = "@"
+		}
+	case !strings.HasPrefix(body, ".") && !strings.HasPrefix(body, "["):
+		body = "." + body
+	}
+
+	jp := jsonpath.New(expr).AllowMissingKeys(true)
+	if err := jp.Parse("{" + body + "}"); err != nil {
+		return nil, fmt.Errorf("invalid path %q: %w", expr, err)
+	}
+	return &fieldPath{expr: expr, fromRoot: fromRoot, jp: jp}, nil
+}
+
+// values evaluates the path and returns every matched value. Relative paths
+// are evaluated against current; "$"-prefixed paths against root.
+func (p *fieldPath) values(root, current interface{}) []interface{} {
+	if p == nil {
+		return nil
+	}
+	data := current
+	if p.fromRoot {
+		data = root
+	}
+
+	results, err := p.jp.FindResults(data)
+	if err != nil {
+		return nil
+	}
+	var out []interface{}
+	for _, result := range results {
+		for _, v := range result {
+			if v.IsValid() && v.CanInterface() && v.Interface() != nil {
+				out = append(out, v.Interface())
+			}
+		}
+	}
+	return out
+}
+
+// first returns the first matched value, or nil.
+func (p *fieldPath) first(root, current interface{}) interface{} {
+	if vals := p.values(root, current); len(vals) > 0 {
+		return vals[0]
+	}
+	return nil
+}
+
+// firstString returns the first matched scalar rendered as a string.
+func (p *fieldPath) firstString(root, current interface{}) string {
+	switch v := p.first(root, current).(type) {
+	case string:
+		return v
+	case int64, float64, bool:
+		return fmt.Sprint(v)
+	default:
+		return ""
+	}
+}
+
+// labelSelector returns the first matched value converted to a label selector.
+func (p *fieldPath) labelSelector(root, current interface{}) *metav1.LabelSelector {
+	v, ok := p.first(root, current).(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	return util.SafeNestedLabelSelector(map[string]interface{}{"selector": v}, "selector")
+}
+
+// compiledPaths holds the compiled form of a v1alpha1.FieldPaths.
+type compiledPaths struct {
+	selector          *fieldPath
+	namespaceSelector *fieldPath
+	effect            *fieldPath
+	summary           *fieldPath
+	rules             *fieldPath
+	name              *fieldPath
+	ports             *fieldPath
+	severity          *fieldPath
+	constraintType    *fieldPath
+
+	severityMapping       map[string]string
+	constraintTypeMapping map[string]string
+}
+
+// compileFieldPaths compiles every path in fp, reporting all invalid paths
+// and mapping values at once.
+func compileFieldPaths(fp *v1alpha1.FieldPaths) (*compiledPaths, error) {
+	cp := &compiledPaths{
+		severityMapping:       fp.SeverityMapping,
+		constraintTypeMapping: fp.ConstraintTypeMapping,
+	}
+
+	var errs []error
+	for _, f := range []struct {
+		name string
+		expr string
+		dst  **fieldPath
+	}{
+		{"selectorPath", fp.SelectorPath, &cp.selector},
+		{"namespaceSelectorPath", fp.NamespaceSelectorPath, &cp.namespaceSelector},
+		{"effectPath", fp.EffectPath, &cp.effect},
+		{"summaryPath", fp.SummaryPath, &cp.summary},
+		{"rulesPath", fp.RulesPath, &cp.rules},
+		{"namePath", fp.NamePath, &cp.name},
+		{"portsPath", fp.PortsPath, &cp.ports},
+		{"severityPath", fp.SeverityPath, &cp.severity},
+		{"constraintTypePath", fp.ConstraintTypePath, &cp.constraintType},
+	} {
+		p, err := compileFieldPath(f.expr)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", f.name, err))
+			continue
+		}
+		*f.dst = p
+	}
+
+	for value, severity := range fp.SeverityMapping {
+		if _, ok := parseSeverity(severity); !ok {
+			errs = append(errs, fmt.Errorf("severityMapping[%q]: unknown severity %q", value, severity))
+		}
+	}
+	for value, ct := range fp.ConstraintTypeMapping {
+		if _, ok := parseConstraintType(ct); !ok {
+			errs = append(errs, fmt.Errorf("constraintTypeMapping[%q]: unknown constraint type %q", value, ct))
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return cp, nil
+}
+
+// ValidateFieldPaths reports whether every path and mapping in fp is valid.
+// A nil fp is valid.
+func ValidateFieldPaths(fp *v1alpha1.FieldPaths) error {
+	if fp == nil {
+		return nil
+	}
+	_, err := compileFieldPaths(fp)
+	return err
+}
+
+// extractPorts renders the values matched by the ports path as
+// "port/protocol" strings. Elements may be numbers, strings, lists, or maps
+// with port/number and protocol fields.
+func extractPorts(p *fieldPath, root, current interface{}) []string {
+	var ports []string
+	var add func(v interface{})
+	add = func(v interface{}) {
+		switch val := v.(type) {
+		case []interface{}:
+			for _, item := range val {
+				add(item)
+			}
+		case map[string]interface{}:
+			port := val["port"]
+			if port == nil {
+				port = val["number"]
+			}
+			if port == nil {
+				return
+			}
+			protocol := util.SafeStringFromMap(val, "protocol")
+			if protocol == "" {
+				protocol = "TCP"
+			}
+			ports = append(ports, fmt.Sprintf("%v/%s", port, protocol))
+		case string:
+			if val != "" {
+				ports = append(ports, val)
+			}
+		case int64, float64:
+			ports = append(ports, fmt.Sprintf("%v/TCP", val))
+		}
+	}
+	for _, v := range p.values(root, current) {
+		add(v)
+	}
+	return ports
+}
+
+// resolveSeverity maps a raw value through the mapping, falling back to the
+// value itself when it already names a severity.
+func resolveSeverity(raw string, mapping map[string]string) (types.Severity, bool) {
+	if mapped, ok := mapping[raw]; ok {
+		raw = mapped
+	}
+	return parseSeverity(raw)
+}
+
+// resolveConstraintType maps a raw value through the mapping, falling back to
+// the value itself when it already names a constraint type.
+func resolveConstraintType(raw string, mapping map[string]string) (types.ConstraintType, bool) {
+	if mapped, ok := mapping[raw]; ok {
+		raw = mapped
+	}
+	return parseConstraintType(raw)
+}
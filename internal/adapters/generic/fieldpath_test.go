@@ -0,0 +1,72 @@
+package generic
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	v1alpha1 "github.com/nightjarctl/nightjar/api/v1alpha1"
+)
+
+func TestCompileFieldPath(t *testing.T) {
+	root := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"action": "deny",
+			"rules": []interface{}{
+				map[string]interface{}{"name": "a"},
+				map[string]interface{}{"name": "b"},
+			},
+		},
+	}
+	rule := map[string]interface{}{"name": "rule-local"}
+
+	tests := []struct {
+		name     string
+		expr     string
+		current  interface{}
+		expected []interface{}
+	}{
+		{"dotted", "spec.action", root, []interface{}{"deny"}},
+		{"dotted with stray dots", ".spec.action.", root, []interface{}{"deny"}},
+		{"jsonpath template", "{.spec.action}", root, []interface{}{"deny"}},
+		{"jsonpath wildcard", "{.spec.rules[*].name}", root, []interface{}{"a", "b"}},
+		{"dotted index", "spec.rules[1].name", root, []interface{}{"b"}},
+		{"relative to rule", "name", rule, []interface{}{"rule-local"}},
+		{"explicit current", "@.name", rule, []interface{}{"rule-local"}},
+		{"root from rule", "$.spec.action", rule, []interface{}{"deny"}},
+		{"missing key", "spec.missing", root, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := compileFieldPath(tt.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, p.values(root, tt.current))
+		})
+	}
+}
+
+func TestCompileFieldPath_Empty(t *testing.T) {
+	p, err := compileFieldPath("  ")
+	require.NoError(t, err)
+	assert.Nil(t, p)
+	assert.Nil(t, p.values(nil, nil), "nil paths match nothing")
+}
+
+func TestValidateFieldPaths(t *testing.T) {
+	assert.NoError(t, ValidateFieldPaths(nil))
+	assert.NoError(t, ValidateFieldPaths(&v1alpha1.FieldPaths{
+		RulesPath:       "{.spec.rules[*]}",
+		SeverityMapping: map[string]string{"Enforce": "Critical"},
+	}))
+
+	err := ValidateFieldPaths(&v1alpha1.FieldPaths{
+		SelectorPath:          "{.spec.selector[}",
+		SeverityMapping:       map[string]string{"Enforce": "Fatal"},
+		ConstraintTypeMapping: map[string]string{"egress": "Firewall"},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "selectorPath")
+	assert.Contains(t, err.Error(), `severityMapping["Enforce"]`)
+	assert.Contains(t, err.Error(), `constraintTypeMapping["egress"]`)
+}
@@ -0,0 +1,192 @@
+package istio
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/nightjarctl/nightjar/internal/adapters/generic"
+	"github.com/nightjarctl/nightjar/internal/types"
+	"github.com/nightjarctl/nightjar/internal/util"
+)
+
+const (
+	// Group is the Istio networking API group.
+	Group = "networking.istio.io"
+
+	// ServiceEntryResource is the plural resource name of ServiceEntry.
+	ServiceEntryResource = "serviceentries"
+
+	serviceEntryKind = "ServiceEntry"
+)
+
+// serviceEntryVersions are the served ServiceEntry versions, newest first.
+var serviceEntryVersions = []string{"v1", "v1beta1", "v1alpha3"}
+
+// Adapter parses Istio ServiceEntry resources into allowed-egress constraints.
+type Adapter struct {
+	generic *generic.Adapter
+}
+
+func New() *Adapter {
+	return &Adapter{generic: generic.New()}
+}
+
+func (a *Adapter) Name() string {
+	return "istio"
+}
+
+func (a *Adapter) Handles() []schema.GroupVersionResource {
+	gvrs := make([]schema.GroupVersionResource, 0, len(serviceEntryVersions))
+	for _, v := range serviceEntryVersions {
+		gvrs = append(gvrs, schema.GroupVersionResource{Group: Group, Version: v, Resource: ServiceEntryResource})
+	}
+	return gvrs
+}
+
+// IsServiceEntrySource reports whether a constraint was parsed from a ServiceEntry.
+func IsServiceEntrySource(c types.Constraint) bool {
+	return c.Source.Group == Group && c.Source.Resource == ServiceEntryResource
+}
+
+func (a *Adapter) Parse(ctx context.Context, obj *unstructured.Unstructured) ([]types.Constraint, error) {
+	if obj.GetKind() != serviceEntryKind {
+		// Group matching routes every networking.istio.io resource here;
+		// keep the generic behaviour for the kinds we don't model.
+		return a.generic.Parse(ctx, obj)
+	}
+
+	name := obj.GetName()
+	namespace := obj.GetNamespace()
+
+	spec := util.SafeNestedMap(obj.Object, "spec")
+	if spec == nil {
+		return nil, fmt.Errorf("serviceentry %s/%s: missing spec", namespace, name)
+	}
+
+	hosts := util.SafeNestedStringSlice(spec, "hosts")
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("serviceentry %s/%s: spec.hosts is empty", namespace, name)
+	}
+
+	location := util.SafeStringFromMap(spec, "location")
+	if location == "" {
+		location = "MESH_EXTERNAL"
+	}
+	exportTo := util.SafeNestedStringSlice(spec, "exportTo")
+	ports := extractPorts(spec)
+
+	details := map[string]interface{}{
+		"hosts":    hosts,
+		"location": location,
+	}
+	if len(ports) > 0 {
+		details["ports"] = ports
+	}
+	if len(exportTo) > 0 {
+		details["exportTo"] = exportTo
+	}
+	if resolution := util.SafeStringFromMap(spec, "resolution"); resolution != "" {
+		details["resolution"] = resolution
+	}
+
+	gv, err := schema.ParseGroupVersion(obj.GetAPIVersion())
+	if err != nil || gv.Version == "" {
+		gv = schema.GroupVersion{Group: Group, Version: serviceEntryVersions[0]}
+	}
+
+	c := types.Constraint{
+		UID:                obj.GetUID(),
+		Source:             gv.WithResource(ServiceEntryResource),
+		Name:               name,
+		Namespace:          namespace,
+		AffectedNamespaces: affectedNamespaces(namespace, exportTo),
+		ConstraintType:     types.ConstraintTypeMeshPolicy,
+		Effect:             "allow",
+		Severity:           types.SeverityInfo,
+		Summary:            fmt.Sprintf("ServiceEntry %q registers %s in the mesh", name, strings.Join(hosts, ", ")),
+		RemediationHint:    "Add the missing host to a ServiceEntry to allow mesh egress to it",
+		Details:            details,
+		Tags:               []string{"istio", "service-entry", "egress"},
+		RawObject:          obj,
+	}
+
+	return []types.Constraint{c}, nil
+}
+
+// VisibleTo reports whether a ServiceEntry constraint is visible to workloads
+// in the given namespace, following Istio exportTo semantics: empty or "*"
+// exports mesh-wide, "." exports to the ServiceEntry's own namespace only.
+func VisibleTo(c types.Constraint, namespace string) bool {
+	exportTo, _ := c.Details["exportTo"].([]string)
+	if len(exportTo) == 0 {
+		return true
+	}
+	for _, e := range exportTo {
+		switch e {
+		case "*":
+			return true
+		case ".":
+			if c.Namespace == namespace {
+				return true
+			}
+		default:
+			if e == namespace {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RegisteredHosts returns the hosts of a ServiceEntry constraint.
+func RegisteredHosts(c types.Constraint) []string {
+	hosts, _ := c.Details["hosts"].([]string)
+	return hosts
+}
+
+// affectedNamespaces returns the ServiceEntry namespace plus any namespaces
+// it is explicitly exported to.
+func affectedNamespaces(namespace string, exportTo []string) []string {
+	var result []string
+	if namespace != "" {
+		result = append(result, namespace)
+	}
+	for _, e := range exportTo {
+		if e == "." || e == "*" || e == namespace {
+			continue
+		}
+		result = append(result, e)
+	}
+	return result
+}
+
+// extractPorts renders spec.ports as "number/protocol" strings.
+func extractPorts(spec map[string]interface{}) []string {
+	var ports []string
+	for _, raw := range util.SafeNestedSlice(spec, "ports") {
+		portMap, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		var number int64
+		switch v := portMap["number"].(type) {
+		case int64:
+			number = v
+		case float64:
+			number = int64(v)
+		}
+		if number == 0 {
+			continue
+		}
+		protocol := util.SafeStringFromMap(portMap, "protocol")
+		if protocol == "" {
+			protocol = "TCP"
+		}
+		ports = append(ports, fmt.Sprintf("%d/%s", number, protocol))
+	}
+	return ports
+}
@@ -0,0 +1,109 @@
+package istio
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/yaml"
+
+	"github.com/nightjarctl/nightjar/internal/types"
+)
+
+func loadTestData(t *testing.T, filename string) *unstructured.Unstructured {
+	t.Helper()
+	path := filepath.Join("testdata", filename)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err, "failed to read testdata file")
+
+	obj := &unstructured.Unstructured{}
+	err = yaml.Unmarshal(data, &obj.Object)
+	require.NoError(t, err, "failed to unmarshal testdata")
+
+	return obj
+}
+
+func TestAdapter_Name(t *testing.T) {
+	assert.Equal(t, "istio", New().Name())
+}
+
+func TestAdapter_Handles(t *testing.T) {
+	gvrs := New().Handles()
+	require.Len(t, gvrs, 3)
+	for _, gvr := range gvrs {
+		assert.Equal(t, Group, gvr.Group)
+		assert.Equal(t, ServiceEntryResource, gvr.Resource)
+	}
+	assert.Equal(t, "v1", gvrs[0].Version)
+}
+
+func TestAdapter_Parse_ServiceEntry(t *testing.T) {
+	obj := loadTestData(t, "serviceentry_external.yaml")
+
+	constraints, err := New().Parse(context.Background(), obj)
+	require.NoError(t, err)
+	require.Len(t, constraints, 1)
+
+	c := constraints[0]
+	assert.Equal(t, "stripe-api", c.Name)
+	assert.Equal(t, "payments", c.Namespace)
+	assert.Equal(t, types.ConstraintTypeMeshPolicy, c.ConstraintType)
+	assert.Equal(t, "allow", c.Effect)
+	assert.Equal(t, types.SeverityInfo, c.Severity)
+	assert.Equal(t, "v1", c.Source.Version)
+	assert.Equal(t, []string{"payments"}, c.AffectedNamespaces)
+	assert.Equal(t, []string{"api.stripe.com", "*.stripe.network"}, RegisteredHosts(c))
+	assert.Equal(t, []string{"443/TLS"}, c.Details["ports"])
+	assert.Equal(t, "MESH_EXTERNAL", c.Details["location"])
+	assert.Equal(t, "DNS", c.Details["resolution"])
+	assert.Contains(t, c.Tags, "service-entry")
+	assert.True(t, IsServiceEntrySource(c))
+	assert.NotNil(t, c.RawObject)
+}
+
+func TestAdapter_Parse_ExportTo(t *testing.T) {
+	obj := loadTestData(t, "serviceentry_exportto.yaml")
+
+	constraints, err := New().Parse(context.Background(), obj)
+	require.NoError(t, err)
+	require.Len(t, constraints, 1)
+
+	c := constraints[0]
+	assert.Equal(t, "v1beta1", c.Source.Version)
+	assert.Equal(t, []string{"platform", "build"}, c.AffectedNamespaces)
+	assert.Equal(t, "MESH_EXTERNAL", c.Details["location"], "location defaults to MESH_EXTERNAL")
+
+	assert.True(t, VisibleTo(c, "platform"))
+	assert.True(t, VisibleTo(c, "build"))
+	assert.False(t, VisibleTo(c, "payments"))
+}
+
+func TestAdapter_Parse_MissingHosts(t *testing.T) {
+	obj := loadTestData(t, "serviceentry_external.yaml")
+	unstructured.RemoveNestedField(obj.Object, "spec", "hosts")
+
+	_, err := New().Parse(context.Background(), obj)
+	assert.Error(t, err)
+}
+
+func TestAdapter_Parse_OtherKindsUseGeneric(t *testing.T) {
+	obj := loadTestData(t, "virtualservice.yaml")
+
+	constraints, err := New().Parse(context.Background(), obj)
+	require.NoError(t, err)
+	require.NotEmpty(t, constraints)
+	assert.False(t, IsServiceEntrySource(constraints[0]))
+	assert.NotContains(t, constraints[0].Tags, "service-entry")
+}
+
+func TestVisibleTo_MeshWide(t *testing.T) {
+	c := types.Constraint{Namespace: "payments", Details: map[string]interface{}{}}
+	assert.True(t, VisibleTo(c, "anything"), "no exportTo exports mesh-wide")
+
+	c.Details["exportTo"] = []string{"*"}
+	assert.True(t, VisibleTo(c, "anything"))
+}
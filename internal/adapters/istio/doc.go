@@ -0,0 +1,36 @@
+// Package istio provides a constraint adapter for Istio ServiceEntry objects
+// and models the mesh-wide outbound traffic policy.
+//
+// # Parsing
+//
+// Handles GVRs:
+//   - {"networking.istio.io", "v1", "serviceentries"}
+//   - {"networking.istio.io", "v1beta1", "serviceentries"}
+//   - {"networking.istio.io", "v1alpha3", "serviceentries"}
+//
+// Each ServiceEntry produces ONE Constraint that records an allowed egress
+// destination:
+//   - ConstraintType: MeshPolicy
+//   - Effect: allow
+//   - Severity: Info
+//   - Details: {"hosts": [...], "ports": [...], "location": "MESH_EXTERNAL", "exportTo": [...]}
+//
+// Other resources in the networking.istio.io group (VirtualService,
+// DestinationRule, ...) reach this adapter through group matching and are
+// delegated to the generic adapter, so they keep their auto-discovered constraints.
+//
+// # Outbound traffic policy
+//
+// When the mesh runs with `outboundTrafficPolicy.mode: REGISTRY_ONLY`, calls to
+// hosts without a ServiceEntry are routed to BlackHoleCluster and fail with 502.
+// No NetworkPolicy is involved, so the MeshConfigWatcher reads the mesh
+// ConfigMap (default istio-system/istio, key "mesh") and indexes a synthetic
+// cluster-scoped NetworkEgress constraint while REGISTRY_ONLY is active:
+//
+//	outboundTrafficPolicy:
+//	  mode: REGISTRY_ONLY
+//
+// The explain helpers (ExtractExternalHost, IsRegistryOnlySymptom, HostMatches,
+// ServiceEntryTemplate) let `nightjar explain` and the MCP nightjar_explain tool
+// name the unregistered host and offer a ServiceEntry template.
+package istio
@@ -0,0 +1,118 @@
+package istio
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// registryOnlySymptoms are error fragments specific to Istio routing an
+// unregistered host to BlackHoleCluster. Generic upstream failures such as
+// "502 Bad Gateway" or "connection reset by peer" are left out: nearly every
+// outage produces them.
+var registryOnlySymptoms = []string{"blackholecluster", "registry_only"}
+
+// hostPattern matches DNS names with at least one dot and an alphabetic TLD,
+// optionally followed by a port.
+var hostPattern = regexp.MustCompile(`(?i)\b((?:[a-z0-9](?:[a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z]{2,63})(?::(\d{1,5}))?\b`)
+
+// clusterLocalSuffixes are host suffixes that never need a ServiceEntry.
+var clusterLocalSuffixes = []string{".svc", ".cluster.local", ".local", ".internal"}
+
+// IsRegistryOnlySymptom reports whether an error message looks like a call
+// dropped by a REGISTRY_ONLY mesh.
+func IsRegistryOnlySymptom(message string) bool {
+	lower := strings.ToLower(message)
+	for _, s := range registryOnlySymptoms {
+		if strings.Contains(lower, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExtractExternalHost returns the first external (non cluster-local) host
+// named in an error message and the port it was called on. The port defaults
+// to 80 for http:// URLs and 443 otherwise. Returns "" if no host is found.
+func ExtractExternalHost(message string) (string, int) {
+	for _, m := range hostPattern.FindAllStringSubmatchIndex(message, -1) {
+		host := strings.ToLower(message[m[2]:m[3]])
+		if isClusterLocal(host) {
+			continue
+		}
+
+		port := 443
+		if m[4] >= 0 {
+			if p, err := strconv.Atoi(message[m[4]:m[5]]); err == nil && p > 0 && p < 65536 {
+				port = p
+			}
+		} else if strings.HasSuffix(strings.ToLower(message[:m[2]]), "http://") {
+			port = 80
+		}
+		return host, port
+	}
+	return "", 0
+}
+
+// HostMatches reports whether host is covered by any ServiceEntry host,
+// including wildcard entries such as "*.stripe.com".
+func HostMatches(host string, registered []string) bool {
+	host = strings.ToLower(host)
+	for _, r := range registered {
+		r = strings.ToLower(r)
+		if r == host {
+			return true
+		}
+		if strings.HasPrefix(r, "*.") && strings.HasSuffix(host, r[1:]) {
+			return true
+		}
+	}
+	return false
+}
+
+// ServiceEntryTemplate renders a MESH_EXTERNAL ServiceEntry for the given host.
+func ServiceEntryTemplate(host string, port int, namespace string) string {
+	protocol, portName := "TLS", "tls"
+	switch port {
+	case 80:
+		protocol, portName = "HTTP", "http"
+	case 443:
+	default:
+		protocol, portName = "TCP", "tcp"
+	}
+
+	return fmt.Sprintf(`apiVersion: networking.istio.io/v1
+kind: ServiceEntry
+metadata:
+  name: %s
+  namespace: %s
+spec:
+  hosts:
+  - %s
+  location: MESH_EXTERNAL
+  resolution: DNS
+  ports:
+  - number: %d
+    name: %s-%d
+    protocol: %s
+`, serviceEntryName(host), namespace, host, port, portName, port, protocol)
+}
+
+// serviceEntryName derives a valid object name from a host.
+func serviceEntryName(host string) string {
+	if strings.HasPrefix(host, "{") {
+		return "external-host"
+	}
+	name := strings.NewReplacer(".", "-", "*", "wildcard").Replace(strings.ToLower(host))
+	return strings.Trim(name, "-")
+}
+
+func isClusterLocal(host string) bool {
+	for _, suffix := range clusterLocalSuffixes {
+		if strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	return false
+}
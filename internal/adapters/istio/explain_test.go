@@ -0,0 +1,60 @@
+package istio
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/yaml"
+)
+
+func TestIsRegistryOnlySymptom(t *testing.T) {
+	assert.True(t, IsRegistryOnlySymptom("route to BlackHoleCluster"))
+	assert.True(t, IsRegistryOnlySymptom("outbound traffic policy is REGISTRY_ONLY"))
+	assert.False(t, IsRegistryOnlySymptom("HTTP 502 Bad Gateway"), "any upstream outage")
+	assert.False(t, IsRegistryOnlySymptom("upstream connect error or disconnect/reset before headers"))
+	assert.False(t, IsRegistryOnlySymptom("read tcp 10.0.0.1:443: connection reset by peer"))
+	assert.False(t, IsRegistryOnlySymptom("denied by admission webhook"))
+}
+
+func TestExtractExternalHost(t *testing.T) {
+	tests := []struct {
+		message string
+		host    string
+		port    int
+	}{
+		{`Post "https://api.stripe.com/v1/charges": EOF 502`, "api.stripe.com", 443},
+		{`GET http://example.org/health returned 502`, "example.org", 80},
+		{`dial tcp api.partner.io:8443: connection reset by peer`, "api.partner.io", 8443},
+		{`reviews.bookinfo.svc.cluster.local: 502 then api.github.com failed`, "api.github.com", 443},
+		{`upstream connect error`, "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.message, func(t *testing.T) {
+			host, port := ExtractExternalHost(tt.message)
+			assert.Equal(t, tt.host, host)
+			assert.Equal(t, tt.port, port)
+		})
+	}
+}
+
+func TestHostMatches(t *testing.T) {
+	registered := []string{"api.stripe.com", "*.googleapis.com"}
+	assert.True(t, HostMatches("API.stripe.com", registered))
+	assert.True(t, HostMatches("storage.googleapis.com", registered))
+	assert.False(t, HostMatches("googleapis.com", registered))
+	assert.False(t, HostMatches("api.github.com", registered))
+}
+
+func TestServiceEntryTemplate(t *testing.T) {
+	tmpl := ServiceEntryTemplate("api.github.com", 443, "ci")
+
+	var obj map[string]interface{}
+	assert.NoError(t, yaml.Unmarshal([]byte(tmpl), &obj), "template must be valid YAML")
+	assert.Equal(t, "ServiceEntry", obj["kind"])
+	assert.Contains(t, tmpl, "name: api-github-com")
+	assert.Contains(t, tmpl, "namespace: ci")
+	assert.Contains(t, tmpl, "protocol: TLS")
+
+	assert.Contains(t, ServiceEntryTemplate("example.org", 80, "ci"), "protocol: HTTP")
+	assert.Contains(t, ServiceEntryTemplate("db.example.org", 5432, "ci"), "protocol: TCP")
+}
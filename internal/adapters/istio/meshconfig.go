@@ -0,0 +1,214 @@
+package istio
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/yaml"
+
+	"github.com/nightjarctl/nightjar/internal/indexer"
+	"github.com/nightjarctl/nightjar/internal/types"
+)
+
+// Outbound traffic policy modes from Istio MeshConfig.
+const (
+	OutboundModeAllowAny     = "ALLOW_ANY"
+	OutboundModeRegistryOnly = "REGISTRY_ONLY"
+)
+
+// RegistryOnlyTag marks the synthetic constraint that models a REGISTRY_ONLY mesh.
+const RegistryOnlyTag = "registry-only"
+
+// IsOutboundPolicy reports whether tags mark the synthetic REGISTRY_ONLY
+// outbound-policy constraint. The tag survives into ConstraintReports, where
+// the UID does not, so CLI and MCP both identify the constraint by it.
+func IsOutboundPolicy(tags []string) bool {
+	return slices.Contains(tags, RegistryOnlyTag)
+}
+
+// OutboundPolicyUID is the stable UID of the synthetic outbound-policy constraint.
+const OutboundPolicyUID = k8stypes.UID("istio-mesh:outbound-traffic-policy")
+
+// meshConfigKey is the ConfigMap data key holding the MeshConfig YAML.
+const meshConfigKey = "mesh"
+
+var configMapGVR = schema.GroupVersionResource{Group: "", Version: "v1", Resource: "configmaps"}
+
+// meshConfig is the subset of Istio MeshConfig that Nightjar reads.
+type meshConfig struct {
+	OutboundTrafficPolicy struct {
+		Mode string `json:"mode"`
+	} `json:"outboundTrafficPolicy"`
+}
+
+// ParseOutboundMode extracts outboundTrafficPolicy.mode from the MeshConfig
+// YAML stored in an istio ConfigMap. Istio defaults to ALLOW_ANY when unset.
+func ParseOutboundMode(cm *corev1.ConfigMap) (string, error) {
+	raw, ok := cm.Data[meshConfigKey]
+	if !ok || raw == "" {
+		return OutboundModeAllowAny, nil
+	}
+	var mc meshConfig
+	if err := yaml.Unmarshal([]byte(raw), &mc); err != nil {
+		return "", fmt.Errorf("configmap %s/%s: invalid mesh config: %w", cm.Namespace, cm.Name, err)
+	}
+	if mc.OutboundTrafficPolicy.Mode == "" {
+		return OutboundModeAllowAny, nil
+	}
+	return mc.OutboundTrafficPolicy.Mode, nil
+}
+
+// OutboundPolicyConstraint builds the synthetic cluster-scoped constraint that
+// represents a REGISTRY_ONLY mesh: egress to any host without a ServiceEntry
+// is routed to BlackHoleCluster. It is a mesh policy rather than an egress
+// network policy, so the correlator does not attribute flow drops to it.
+func OutboundPolicyConstraint(cm *corev1.ConfigMap) types.Constraint {
+	source := fmt.Sprintf("%s/%s", cm.Namespace, cm.Name)
+	return types.Constraint{
+		UID:            OutboundPolicyUID,
+		Source:         configMapGVR,
+		Name:           "istio-outbound-registry-only",
+		ConstraintType: types.ConstraintTypeMeshPolicy,
+		Effect:         "deny",
+		Severity:       types.SeverityWarning,
+		Summary:        "Istio outboundTrafficPolicy is REGISTRY_ONLY: egress to hosts without a ServiceEntry fails with 502 (BlackHoleCluster)",
+		RemediationHint: "Register external hosts with an Istio ServiceEntry, or ask your platform team " +
+			"to switch the mesh outboundTrafficPolicy to ALLOW_ANY",
+		Details: map[string]interface{}{
+			"outboundTrafficPolicy": OutboundModeRegistryOnly,
+			"meshConfig":            source,
+		},
+		Remediation: []types.RemediationStep{
+			{
+				Type:              "yaml_patch",
+				Description:       "Create a ServiceEntry for the external host your workload calls",
+				Template:          ServiceEntryTemplate("{host}", 443, "{namespace}"),
+				RequiresPrivilege: "namespace-admin",
+			},
+			{
+				Type:              "kubectl",
+				Description:       "List ServiceEntries visible to your namespace",
+				Command:           "kubectl get serviceentries -A",
+				RequiresPrivilege: "developer",
+			},
+		},
+		Tags: []string{"istio", "egress", RegistryOnlyTag},
+	}
+}
+
+// MeshConfigOptions configures the MeshConfigWatcher.
+type MeshConfigOptions struct {
+	// Namespace of the mesh ConfigMap. Default: "istio-system".
+	Namespace string
+
+	// ConfigMapName is the name of the mesh ConfigMap. Default: "istio".
+	ConfigMapName string
+
+	// ResyncPeriod is the informer resync period. Default: 10 minutes.
+	ResyncPeriod time.Duration
+}
+
+// DefaultMeshConfigOptions returns sensible defaults.
+func DefaultMeshConfigOptions() MeshConfigOptions {
+	return MeshConfigOptions{
+		Namespace:     "istio-system",
+		ConfigMapName: "istio",
+		ResyncPeriod:  10 * time.Minute,
+	}
+}
+
+// MeshConfigWatcher watches the Istio mesh ConfigMap and keeps the synthetic
+// outbound-policy constraint in the index in sync with outboundTrafficPolicy.mode.
+type MeshConfigWatcher struct {
+	logger  *zap.Logger
+	client  kubernetes.Interface
+	indexer *indexer.Indexer
+	opts    MeshConfigOptions
+}
+
+// NewMeshConfigWatcher creates a new MeshConfigWatcher.
+func NewMeshConfigWatcher(client kubernetes.Interface, idx *indexer.Indexer, logger *zap.Logger, opts MeshConfigOptions) *MeshConfigWatcher {
+	defaults := DefaultMeshConfigOptions()
+	if opts.Namespace == "" {
+		opts.Namespace = defaults.Namespace
+	}
+	if opts.ConfigMapName == "" {
+		opts.ConfigMapName = defaults.ConfigMapName
+	}
+	if opts.ResyncPeriod == 0 {
+		opts.ResyncPeriod = defaults.ResyncPeriod
+	}
+	return &MeshConfigWatcher{
+		logger:  logger.Named("istio-meshconfig"),
+		client:  client,
+		indexer: idx,
+		opts:    opts,
+	}
+}
+
+// Start watches the mesh ConfigMap. Blocks until context is cancelled.
+func (w *MeshConfigWatcher) Start(ctx context.Context) error {
+	w.logger.Info("Starting Istio mesh config watcher",
+		zap.String("namespace", w.opts.Namespace),
+		zap.String("configmap", w.opts.ConfigMapName))
+
+	factory := informers.NewSharedInformerFactoryWithOptions(
+		w.client,
+		w.opts.ResyncPeriod,
+		informers.WithNamespace(w.opts.Namespace),
+		informers.WithTweakListOptions(func(o *metav1.ListOptions) {
+			o.FieldSelector = fields.OneTermEqualSelector("metadata.name", w.opts.ConfigMapName).String()
+		}),
+	)
+	informer := factory.Core().V1().ConfigMaps().Informer()
+	if _, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			w.handle(obj)
+		},
+		UpdateFunc: func(_, newObj interface{}) {
+			w.handle(newObj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			w.indexer.Delete(OutboundPolicyUID)
+		},
+	}); err != nil {
+		return fmt.Errorf("adding mesh config event handler: %w", err)
+	}
+
+	factory.Start(ctx.Done())
+	<-ctx.Done()
+	factory.Shutdown()
+	return nil
+}
+
+// handle reconciles the outbound-policy constraint from a ConfigMap.
+func (w *MeshConfigWatcher) handle(obj interface{}) {
+	cm, ok := obj.(*corev1.ConfigMap)
+	if !ok || cm.Name != w.opts.ConfigMapName {
+		return
+	}
+
+	mode, err := ParseOutboundMode(cm)
+	if err != nil {
+		w.logger.Warn("Failed to parse Istio mesh config", zap.Error(err))
+		return
+	}
+
+	if mode == OutboundModeRegistryOnly {
+		w.indexer.Upsert(OutboundPolicyConstraint(cm))
+		w.logger.Info("Istio mesh outbound traffic policy is REGISTRY_ONLY")
+		return
+	}
+	w.indexer.Delete(OutboundPolicyUID)
+}
@@ -0,0 +1,99 @@
+package istio
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/yaml"
+
+	"github.com/nightjarctl/nightjar/internal/indexer"
+	"github.com/nightjarctl/nightjar/internal/types"
+)
+
+func loadConfigMap(t *testing.T, filename string) *corev1.ConfigMap {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", filename))
+	require.NoError(t, err)
+	cm := &corev1.ConfigMap{}
+	require.NoError(t, yaml.Unmarshal(data, cm))
+	return cm
+}
+
+func hasConstraint(idx *indexer.Indexer, uid k8stypes.UID) bool {
+	for _, c := range idx.All() {
+		if c.UID == uid {
+			return true
+		}
+	}
+	return false
+}
+
+func meshConfigMap(mesh string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "istio", Namespace: "istio-system"},
+		Data:       map[string]string{"mesh": mesh},
+	}
+}
+
+func TestParseOutboundMode(t *testing.T) {
+	mode, err := ParseOutboundMode(loadConfigMap(t, "mesh_registry_only.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, OutboundModeRegistryOnly, mode)
+
+	mode, err = ParseOutboundMode(meshConfigMap("accessLogFile: /dev/stdout"))
+	require.NoError(t, err)
+	assert.Equal(t, OutboundModeAllowAny, mode, "unset mode defaults to ALLOW_ANY")
+
+	mode, err = ParseOutboundMode(&corev1.ConfigMap{})
+	require.NoError(t, err)
+	assert.Equal(t, OutboundModeAllowAny, mode)
+
+	_, err = ParseOutboundMode(meshConfigMap("outboundTrafficPolicy: [unclosed"))
+	assert.Error(t, err)
+}
+
+func TestOutboundPolicyConstraint(t *testing.T) {
+	c := OutboundPolicyConstraint(loadConfigMap(t, "mesh_registry_only.yaml"))
+
+	assert.Equal(t, OutboundPolicyUID, c.UID)
+	assert.Empty(t, c.Namespace, "mesh policy is cluster-scoped")
+	assert.Equal(t, types.ConstraintTypeMeshPolicy, c.ConstraintType)
+	assert.Equal(t, "deny", c.Effect)
+	assert.Equal(t, "istio-system/istio", c.Details["meshConfig"])
+	assert.Contains(t, c.Tags, RegistryOnlyTag)
+	require.NotEmpty(t, c.Remediation)
+	assert.Contains(t, c.Remediation[0].Template, "kind: ServiceEntry")
+}
+
+func TestMeshConfigWatcher(t *testing.T) {
+	cm := loadConfigMap(t, "mesh_registry_only.yaml")
+	client := fake.NewSimpleClientset(cm)
+	idx := indexer.New(nil)
+
+	w := NewMeshConfigWatcher(client, idx, zap.NewNop(), MeshConfigOptions{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = w.Start(ctx) }()
+
+	assert.Eventually(t, func() bool {
+		return hasConstraint(idx, OutboundPolicyUID)
+	}, 5*time.Second, 10*time.Millisecond, "REGISTRY_ONLY should index the mesh policy")
+
+	updated := meshConfigMap("outboundTrafficPolicy:\n  mode: ALLOW_ANY")
+	_, err := client.CoreV1().ConfigMaps("istio-system").Update(ctx, updated, metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		return !hasConstraint(idx, OutboundPolicyUID)
+	}, 5*time.Second, 10*time.Millisecond, "ALLOW_ANY should remove the mesh policy")
+}
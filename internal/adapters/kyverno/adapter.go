@@ -14,12 +14,6 @@ import (
 	"github.com/nightjarctl/nightjar/internal/util"
 )
 
-// sharedRawObject is used to avoid deep-copying the policy object for each rule.
-// The first rule gets the deep copy, subsequent rules share a reference.
-type sharedRawObject struct {
-	obj *unstructured.Unstructured
-}
-
 var (
 	gvrClusterPolicy = schema.GroupVersionResource{
 		Group:    "kyverno.io",
@@ -76,9 +70,6 @@ func (a *Adapter) Parse(ctx context.Context, obj *unstructured.Unstructured) ([]
 		return nil, fmt.Errorf("kyverno policy %s: no rules defined", name)
 	}
 
-	// Create a single deep copy to share across all rules (memory optimization)
-	shared := &sharedRawObject{obj: obj.DeepCopy()}
-
 	var constraints []types.Constraint
 
 	for i, ruleRaw := range rulesSlice {
@@ -87,7 +78,7 @@ func (a *Adapter) Parse(ctx context.Context, obj *unstructured.Unstructured) ([]
 			continue
 		}
 
-		c, err := a.parseRule(obj, rule, i, globalAction, isClusterPolicy, shared)
+		c, err := a.parseRule(obj, rule, i, globalAction, isClusterPolicy)
 		if err != nil {
 			// Log but continue parsing other rules
 			continue
@@ -105,7 +96,7 @@ func (a *Adapter) Parse(ctx context.Context, obj *unstructured.Unstructured) ([]
 }
 
 // parseRule parses a single Kyverno rule into a Constraint.
-func (a *Adapter) parseRule(obj *unstructured.Unstructured, rule map[string]interface{}, index int, globalAction string, isClusterPolicy bool, shared *sharedRawObject) (*types.Constraint, error) {
+func (a *Adapter) parseRule(obj *unstructured.Unstructured, rule map[string]interface{}, index int, globalAction string, isClusterPolicy bool) (*types.Constraint, error) {
 	policyName := obj.GetName()
 	namespace := obj.GetNamespace()
 
@@ -182,7 +173,7 @@ func (a *Adapter) parseRule(obj *unstructured.Unstructured, rule map[string]inte
 		Remediation:        remediation,
 		Details:            details,
 		Tags:               tags,
-		RawObject:          shared.obj, // Share the deep copy across all rules
+		RawObject:          obj, // All rules share the informer cache object
 	}, nil
 }
 
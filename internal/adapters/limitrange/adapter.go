@@ -98,7 +98,7 @@ func (a *Adapter) Parse(ctx context.Context, obj *unstructured.Unstructured) ([]
 			Summary:            buildLimitSummary(limitType, details),
 			RemediationHint:    fmt.Sprintf("Ensure your %ss specify resource requests/limits within these bounds", strings.ToLower(limitType)),
 			Details:            details,
-			RawObject:          obj.DeepCopy(),
+			RawObject:          obj,
 		}
 
 		constraints = append(constraints, c)
@@ -65,7 +65,7 @@ func (a *Adapter) Parse(ctx context.Context, obj *unstructured.Unstructured) ([]
 			Summary:            buildIngressSummary(name, spec),
 			RemediationHint:    fmt.Sprintf("Review NetworkPolicy %s/%s or contact your platform team", namespace, name),
 			Details:            extractIngressDetails(spec),
-			RawObject:          obj.DeepCopy(),
+			RawObject:          obj,
 		}
 		constraints = append(constraints, c)
 	}
@@ -85,7 +85,7 @@ func (a *Adapter) Parse(ctx context.Context, obj *unstructured.Unstructured) ([]
 			Summary:            buildEgressSummary(name, spec),
 			RemediationHint:    fmt.Sprintf("Review NetworkPolicy %s/%s or contact your platform team", namespace, name),
 			Details:            extractEgressDetails(spec),
-			RawObject:          obj.DeepCopy(),
+			RawObject:          obj,
 		}
 		constraints = append(constraints, c)
 	}
@@ -0,0 +1,316 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+
+	"github.com/nightjarctl/nightjar/internal/types"
+	"github.com/nightjarctl/nightjar/pkg/adapterplugin"
+)
+
+// ErrUnavailable is returned by Parse while the plugin is failing health checks.
+var ErrUnavailable = errors.New("adapter plugin unavailable")
+
+// Options configures a plugin Adapter.
+type Options struct {
+	// CallTimeout bounds every Handshake, Parse and health check call.
+	// Default: 5 seconds.
+	CallTimeout time.Duration
+
+	// Logger for the adapter.
+	Logger *zap.Logger
+}
+
+// DefaultOptions returns sensible defaults.
+func DefaultOptions() Options {
+	return Options{
+		CallTimeout: 5 * time.Second,
+		Logger:      zap.NewNop(),
+	}
+}
+
+// Adapter is a types.Adapter backed by an out-of-process plugin.
+type Adapter struct {
+	socket string
+	opts   Options
+	logger *zap.Logger
+
+	mu              sync.RWMutex
+	conn            *grpc.ClientConn
+	client          adapterplugin.AdapterPluginClient
+	health          healthpb.HealthClient
+	name            string
+	handles         []schema.GroupVersionResource
+	protocolVersion int
+	adapterVersion  string
+	healthy         bool
+}
+
+// Dial connects to the plugin listening on socket and performs the handshake.
+func Dial(ctx context.Context, socket string, opts Options) (*Adapter, error) {
+	defaults := DefaultOptions()
+	if opts.CallTimeout == 0 {
+		opts.CallTimeout = defaults.CallTimeout
+	}
+	if opts.Logger == nil {
+		opts.Logger = defaults.Logger
+	}
+
+	a := &Adapter{
+		socket: socket,
+		opts:   opts,
+		logger: opts.Logger.Named("adapter-plugin").With(zap.String("socket", socket)),
+	}
+	if err := a.connect(ctx); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// connect dials the socket, negotiates a protocol version and records the
+// plugin's identity. An existing connection is replaced.
+func (a *Adapter) connect(ctx context.Context) error {
+	conn, err := grpc.NewClient("unix://"+a.socket, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return fmt.Errorf("dialing plugin %s: %w", a.socket, err)
+	}
+	client := adapterplugin.NewAdapterPluginClient(conn)
+
+	callCtx, cancel := context.WithTimeout(ctx, a.opts.CallTimeout)
+	defer cancel()
+	resp, err := client.Handshake(callCtx, &adapterplugin.HandshakeRequest{
+		ProtocolVersions: adapterplugin.SupportedProtocolVersions,
+	})
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("handshake with plugin %s: %w", a.socket, err)
+	}
+	if _, ok := adapterplugin.NegotiateVersion([]int{resp.ProtocolVersion}, adapterplugin.SupportedProtocolVersions); !ok {
+		conn.Close()
+		return fmt.Errorf("plugin %s selected unsupported protocol version %d", a.socket, resp.ProtocolVersion)
+	}
+	if resp.Name == "" {
+		conn.Close()
+		return fmt.Errorf("plugin %s returned an empty adapter name", a.socket)
+	}
+
+	handles := make([]schema.GroupVersionResource, 0, len(resp.Handles))
+	for _, gvr := range resp.Handles {
+		handles = append(handles, gvr.ToSchema())
+	}
+
+	a.mu.Lock()
+	old := a.conn
+	if a.name != "" && a.name != resp.Name {
+		a.logger.Warn("Plugin changed its adapter name on reconnect; keeping the original",
+			zap.String("registered", a.name), zap.String("reported", resp.Name))
+	} else {
+		a.name = resp.Name
+	}
+	a.conn = conn
+	a.client = client
+	a.health = healthpb.NewHealthClient(conn)
+	a.handles = handles
+	a.protocolVersion = resp.ProtocolVersion
+	a.adapterVersion = resp.AdapterVersion
+	a.healthy = true
+	a.mu.Unlock()
+
+	if old != nil {
+		old.Close()
+	}
+
+	a.logger.Info("Connected to adapter plugin",
+		zap.String("adapter", resp.Name),
+		zap.String("adapterVersion", resp.AdapterVersion),
+		zap.Int("protocolVersion", resp.ProtocolVersion),
+		zap.Int("handledGVRs", len(handles)))
+	return nil
+}
+
+// Name implements types.Adapter.
+func (a *Adapter) Name() string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.name
+}
+
+// Handles implements types.Adapter.
+func (a *Adapter) Handles() []schema.GroupVersionResource {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return append([]schema.GroupVersionResource(nil), a.handles...)
+}
+
+// Socket returns the Unix socket path of the plugin.
+func (a *Adapter) Socket() string {
+	return a.socket
+}
+
+// ProtocolVersion returns the protocol version agreed during the handshake.
+func (a *Adapter) ProtocolVersion() int {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.protocolVersion
+}
+
+// Healthy reports whether the last health check succeeded.
+func (a *Adapter) Healthy() bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.healthy
+}
+
+// Parse implements types.Adapter by forwarding the object to the plugin.
+func (a *Adapter) Parse(ctx context.Context, obj *unstructured.Unstructured) ([]types.Constraint, error) {
+	a.mu.RLock()
+	client, healthy, version, handles := a.client, a.healthy, a.protocolVersion, a.handles
+	a.mu.RUnlock()
+	if !healthy {
+		return nil, fmt.Errorf("%s: %w", a.Name(), ErrUnavailable)
+	}
+
+	raw, err := obj.MarshalJSON()
+	if err != nil {
+		return nil, fmt.Errorf("encoding %s/%s for plugin: %w", obj.GetNamespace(), obj.GetName(), err)
+	}
+	gvr := sourceGVR(obj, handles)
+
+	callCtx, cancel := context.WithTimeout(ctx, a.opts.CallTimeout)
+	defer cancel()
+	resp, err := client.Parse(callCtx, &adapterplugin.ParseRequest{
+		ProtocolVersion: version,
+		GVR:             adapterplugin.FromSchema(gvr),
+		Object:          raw,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: parsing %s/%s: %w", a.Name(), obj.GetNamespace(), obj.GetName(), err)
+	}
+
+	constraints := make([]types.Constraint, 0, len(resp.Constraints))
+	for i, wc := range resp.Constraints {
+		c := toConstraint(wc, gvr, obj)
+		if c.UID == "" {
+			c.UID = obj.GetUID()
+			if len(resp.Constraints) > 1 {
+				c.UID = k8stypes.UID(fmt.Sprintf("%s-%d", obj.GetUID(), i))
+			}
+		}
+		constraints = append(constraints, c)
+	}
+	return constraints, nil
+}
+
+// Check runs a grpc.health.v1 check against the plugin and records the result.
+func (a *Adapter) Check(ctx context.Context) error {
+	a.mu.RLock()
+	health := a.health
+	a.mu.RUnlock()
+
+	callCtx, cancel := context.WithTimeout(ctx, a.opts.CallTimeout)
+	defer cancel()
+	resp, err := health.Check(callCtx, &healthpb.HealthCheckRequest{Service: adapterplugin.ServiceName})
+	if err == nil && resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		err = fmt.Errorf("plugin reports %s", resp.GetStatus())
+	}
+
+	a.mu.Lock()
+	a.healthy = err == nil
+	a.mu.Unlock()
+	return err
+}
+
+// Reconnect re-dials the plugin and repeats the handshake, e.g. after the
+// sidecar restarted.
+func (a *Adapter) Reconnect(ctx context.Context) error {
+	return a.connect(ctx)
+}
+
+// Close closes the connection to the plugin.
+func (a *Adapter) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.healthy = false
+	if a.conn == nil {
+		return nil
+	}
+	err := a.conn.Close()
+	a.conn = nil
+	return err
+}
+
+// sourceGVR picks the handled GVR matching the object's group and version.
+func sourceGVR(obj *unstructured.Unstructured, handles []schema.GroupVersionResource) schema.GroupVersionResource {
+	gvk := obj.GroupVersionKind()
+	for _, gvr := range handles {
+		if gvr.Group == gvk.Group && gvr.Version == gvk.Version {
+			return gvr
+		}
+	}
+	for _, gvr := range handles {
+		if gvr.Group == gvk.Group {
+			return gvr
+		}
+	}
+	return schema.GroupVersionResource{Group: gvk.Group, Version: gvk.Version}
+}
+
+// toConstraint converts a wire constraint, normalizing unknown types and
+// severities to Unknown and Info.
+func toConstraint(wc adapterplugin.Constraint, source schema.GroupVersionResource, raw *unstructured.Unstructured) types.Constraint {
+	c := types.Constraint{
+		UID:                k8stypes.UID(wc.UID),
+		Source:             source,
+		Name:               wc.Name,
+		Namespace:          wc.Namespace,
+		AffectedNamespaces: wc.AffectedNamespaces,
+		NamespaceSelector:  wc.NamespaceSelector,
+		WorkloadSelector:   wc.WorkloadSelector,
+		ConstraintType:     types.ConstraintTypeUnknown,
+		Effect:             wc.Effect,
+		Severity:           types.SeverityInfo,
+		Details:            wc.Details,
+		Summary:            wc.Summary,
+		RemediationHint:    wc.RemediationHint,
+		Tags:               wc.Tags,
+		RawObject:          raw,
+	}
+
+	switch ct := types.ConstraintType(wc.ConstraintType); ct {
+	case types.ConstraintTypeNetworkIngress, types.ConstraintTypeNetworkEgress, types.ConstraintTypeAdmission,
+		types.ConstraintTypeResourceLimit, types.ConstraintTypeMeshPolicy, types.ConstraintTypeMissing:
+		c.ConstraintType = ct
+	}
+	switch sev := types.Severity(wc.Severity); sev {
+	case types.SeverityCritical, types.SeverityWarning:
+		c.Severity = sev
+	}
+
+	for _, rt := range wc.ResourceTargets {
+		c.ResourceTargets = append(c.ResourceTargets, types.ResourceTarget{APIGroups: rt.APIGroups, Resources: rt.Resources})
+	}
+	for _, step := range wc.Remediation {
+		c.Remediation = append(c.Remediation, types.RemediationStep{
+			Type:              step.Type,
+			Description:       step.Description,
+			Command:           step.Command,
+			Patch:             step.Patch,
+			Template:          step.Template,
+			URL:               step.URL,
+			Contact:           step.Contact,
+			RequiresPrivilege: step.RequiresPrivilege,
+		})
+	}
+	return c
+}
@@ -0,0 +1,222 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/nightjarctl/nightjar/internal/types"
+	"github.com/nightjarctl/nightjar/pkg/adapterplugin"
+)
+
+var testGVR = schema.GroupVersionResource{Group: "example.nightjar.io", Version: "v1", Resource: "egressallowlists"}
+
+// fakeHandler is a configurable adapterplugin.Handler.
+type fakeHandler struct {
+	name  string
+	delay time.Duration
+
+	mu    sync.Mutex
+	err   error
+	calls int
+}
+
+func (h *fakeHandler) Name() string { return h.name }
+
+func (h *fakeHandler) Handles() []schema.GroupVersionResource {
+	return []schema.GroupVersionResource{testGVR}
+}
+
+func (h *fakeHandler) Parse(ctx context.Context, obj *unstructured.Unstructured) ([]adapterplugin.Constraint, error) {
+	h.mu.Lock()
+	h.calls++
+	err := h.err
+	h.mu.Unlock()
+
+	if h.delay > 0 {
+		select {
+		case <-time.After(h.delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []adapterplugin.Constraint{
+		{Name: obj.GetName() + "/a", ConstraintType: "NetworkEgress", Severity: "Warning", Summary: "a"},
+		{Name: obj.GetName() + "/b", ConstraintType: "Bogus", Severity: "Bogus", Summary: "b"},
+	}, nil
+}
+
+// socketPath returns a short socket path; Unix socket paths are limited to
+// ~100 bytes, which t.TempDir() can exceed.
+func socketPath(t *testing.T) string {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "njp")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return filepath.Join(dir, "p.sock")
+}
+
+// servePlugin serves h on socket until the returned stop func is called.
+func servePlugin(t *testing.T, socket string, h adapterplugin.Handler, opts adapterplugin.ServerOptions) (*adapterplugin.Server, func()) {
+	t.Helper()
+	srv := adapterplugin.NewServer(h, opts)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = srv.Serve(ctx, socket)
+	}()
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			cancel()
+			<-done
+		})
+	}
+	t.Cleanup(stop)
+
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(socket)
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	return srv, stop
+}
+
+func testObject() *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "example.nightjar.io/v1",
+		"kind":       "EgressAllowlist",
+		"metadata": map[string]interface{}{
+			"name":      "checkout",
+			"namespace": "shop",
+			"uid":       "uid-1",
+		},
+	}}
+}
+
+func TestAdapter_Parse(t *testing.T) {
+	socket := socketPath(t)
+	servePlugin(t, socket, &fakeHandler{name: "example"}, adapterplugin.ServerOptions{AdapterVersion: "1.2.3"})
+
+	a, err := Dial(context.Background(), socket, Options{})
+	require.NoError(t, err)
+	defer a.Close()
+
+	assert.Equal(t, "example", a.Name())
+	assert.Equal(t, []schema.GroupVersionResource{testGVR}, a.Handles())
+	assert.Equal(t, adapterplugin.ProtocolVersion, a.ProtocolVersion())
+	assert.True(t, a.Healthy())
+
+	constraints, err := a.Parse(context.Background(), testObject())
+	require.NoError(t, err)
+	require.Len(t, constraints, 2)
+
+	c := constraints[0]
+	assert.Equal(t, "checkout/a", c.Name)
+	assert.Equal(t, testGVR, c.Source)
+	assert.Equal(t, types.ConstraintTypeNetworkEgress, c.ConstraintType)
+	assert.Equal(t, types.SeverityWarning, c.Severity)
+	assert.Equal(t, "uid-1-0", string(c.UID))
+	require.NotNil(t, c.RawObject)
+	assert.Equal(t, "checkout", c.RawObject.GetName())
+
+	// Unknown types and severities are normalized.
+	assert.Equal(t, types.ConstraintTypeUnknown, constraints[1].ConstraintType)
+	assert.Equal(t, types.SeverityInfo, constraints[1].Severity)
+	assert.Equal(t, "uid-1-1", string(constraints[1].UID))
+}
+
+func TestAdapter_ParseError(t *testing.T) {
+	socket := socketPath(t)
+	servePlugin(t, socket, &fakeHandler{name: "example", err: errors.New("bad spec")}, adapterplugin.ServerOptions{})
+
+	a, err := Dial(context.Background(), socket, Options{})
+	require.NoError(t, err)
+	defer a.Close()
+
+	_, err = a.Parse(context.Background(), testObject())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "bad spec")
+}
+
+func TestAdapter_ParseTimeout(t *testing.T) {
+	socket := socketPath(t)
+	servePlugin(t, socket, &fakeHandler{name: "slow", delay: 2 * time.Second}, adapterplugin.ServerOptions{})
+
+	a, err := Dial(context.Background(), socket, Options{CallTimeout: 100 * time.Millisecond})
+	require.NoError(t, err)
+	defer a.Close()
+
+	start := time.Now()
+	_, err = a.Parse(context.Background(), testObject())
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestAdapter_UnhealthyAndRecovery(t *testing.T) {
+	socket := socketPath(t)
+	h := &fakeHandler{name: "example"}
+	srv, _ := servePlugin(t, socket, h, adapterplugin.ServerOptions{})
+
+	a, err := Dial(context.Background(), socket, Options{})
+	require.NoError(t, err)
+	defer a.Close()
+
+	srv.SetServing(false)
+	require.Error(t, a.Check(context.Background()))
+	assert.False(t, a.Healthy())
+
+	_, err = a.Parse(context.Background(), testObject())
+	assert.ErrorIs(t, err, ErrUnavailable)
+	assert.Equal(t, 0, h.calls, "unhealthy plugin must not be called")
+
+	srv.SetServing(true)
+	require.NoError(t, a.Check(context.Background()))
+	assert.True(t, a.Healthy())
+	_, err = a.Parse(context.Background(), testObject())
+	assert.NoError(t, err)
+}
+
+func TestAdapter_Reconnect(t *testing.T) {
+	socket := socketPath(t)
+	_, stop := servePlugin(t, socket, &fakeHandler{name: "example"}, adapterplugin.ServerOptions{})
+
+	a, err := Dial(context.Background(), socket, Options{CallTimeout: time.Second})
+	require.NoError(t, err)
+	defer a.Close()
+
+	stop()
+	require.Error(t, a.Check(context.Background()))
+
+	servePlugin(t, socket, &fakeHandler{name: "example"}, adapterplugin.ServerOptions{})
+	require.NoError(t, a.Reconnect(context.Background()))
+	require.NoError(t, a.Check(context.Background()))
+	_, err = a.Parse(context.Background(), testObject())
+	assert.NoError(t, err)
+}
+
+func TestDial_VersionMismatch(t *testing.T) {
+	socket := socketPath(t)
+	servePlugin(t, socket, &fakeHandler{name: "future"}, adapterplugin.ServerOptions{ProtocolVersions: []int{99}})
+
+	_, err := Dial(context.Background(), socket, Options{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "handshake")
+}
+
+func TestDial_NoPlugin(t *testing.T) {
+	_, err := Dial(context.Background(), socketPath(t), Options{CallTimeout: 200 * time.Millisecond})
+	assert.Error(t, err)
+}
@@ -0,0 +1,20 @@
+// Package plugin runs out-of-process adapter plugins inside the controller.
+//
+// Adapter wraps one plugin (see pkg/adapterplugin for the protocol) as a
+// types.Adapter: Parse calls are forwarded over gRPC on a Unix socket, bounded
+// by a per-call timeout, and the returned wire constraints are converted to
+// types.Constraint with Source and RawObject filled in.
+//
+// Manager owns the plugin lifecycle:
+//   - Acquire dials a plugin, negotiates the protocol version and registers
+//     the adapter in the registry under the name reported by the plugin.
+//     Owners are the --adapter-plugins flag and ConstraintProfiles with
+//     spec.plugin set.
+//   - Unreachable plugins stay pending and are retried every health interval,
+//     so sidecars may start after the controller.
+//   - Every health interval, registered plugins are checked with
+//     grpc.health.v1. While a plugin is unhealthy, Parse fails fast with
+//     ErrUnavailable; the Manager reconnects and re-registers it once it is
+//     reachable again.
+//   - Release unregisters a plugin when its last owner goes away.
+package plugin
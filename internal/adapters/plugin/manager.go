@@ -0,0 +1,329 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/nightjarctl/nightjar/internal/adapters"
+)
+
+// ManagerOptions configures the plugin Manager.
+type ManagerOptions struct {
+	// CallTimeout bounds every call to a plugin. Default: 5 seconds.
+	CallTimeout time.Duration
+
+	// HealthInterval is how often plugins are health-checked and pending
+	// plugins are retried. Default: 30 seconds.
+	HealthInterval time.Duration
+}
+
+// DefaultManagerOptions returns sensible defaults.
+func DefaultManagerOptions() ManagerOptions {
+	return ManagerOptions{
+		CallTimeout:    5 * time.Second,
+		HealthInterval: 30 * time.Second,
+	}
+}
+
+// Status describes one plugin known to the Manager.
+type Status struct {
+	Name            string `json:"name,omitempty"`
+	Socket          string `json:"socket"`
+	Healthy         bool   `json:"healthy"`
+	ProtocolVersion int    `json:"protocolVersion,omitempty"`
+	Error           string `json:"error,omitempty"`
+}
+
+// entry tracks a plugin socket and the owners (flags, ConstraintProfiles)
+// that requested it. adapter is nil while the plugin is unreachable.
+type entry struct {
+	socket  string
+	owners  map[string]bool
+	adapter *Adapter
+	lastErr error
+}
+
+// Manager connects to adapter plugins, registers them in the adapter
+// registry and keeps them healthy. A plugin is registered while at least one
+// owner holds it.
+type Manager struct {
+	registry *adapters.Registry
+	logger   *zap.Logger
+	opts     ManagerOptions
+
+	mu      sync.Mutex
+	plugins map[string]*entry // socket → entry
+}
+
+// NewManager creates a new Manager.
+func NewManager(registry *adapters.Registry, logger *zap.Logger, opts ManagerOptions) *Manager {
+	defaults := DefaultManagerOptions()
+	if opts.CallTimeout == 0 {
+		opts.CallTimeout = defaults.CallTimeout
+	}
+	if opts.HealthInterval == 0 {
+		opts.HealthInterval = defaults.HealthInterval
+	}
+	return &Manager{
+		registry: registry,
+		logger:   logger.Named("plugin-manager"),
+		opts:     opts,
+		plugins:  make(map[string]*entry),
+	}
+}
+
+// Acquire records owner as a user of the plugin at socket, connecting and
+// registering it if needed, and returns the plugin's adapter name. An owner
+// holds at most one plugin; acquiring a new socket releases the previous one.
+// If the plugin cannot be reached, it stays pending and is retried on every
+// health interval; the connection error is returned. The plugin is dialed
+// without holding the lock, so a plugin that hangs does not block other
+// owners, Release or Status.
+func (m *Manager) Acquire(ctx context.Context, owner, socket string) (string, error) {
+	m.mu.Lock()
+	for s, e := range m.plugins {
+		if s != socket && e.owners[owner] {
+			m.releaseLocked(owner, e)
+		}
+	}
+
+	e, ok := m.plugins[socket]
+	if !ok {
+		e = &entry{socket: socket, owners: map[string]bool{}}
+		m.plugins[socket] = e
+	}
+	e.owners[owner] = true
+	if e.adapter != nil {
+		name := e.adapter.Name()
+		m.mu.Unlock()
+		return name, nil
+	}
+	m.mu.Unlock()
+
+	a, err := m.dial(ctx, e)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.currentLocked(e, nil) {
+		// Released, or connected by CheckAll or another owner, meanwhile.
+		if a != nil {
+			a.Close()
+		}
+		if m.plugins[socket] == e && e.adapter != nil {
+			return e.adapter.Name(), nil
+		}
+		return "", fmt.Errorf("adapter plugin %s was released while connecting", socket)
+	}
+	if err != nil {
+		e.lastErr = err
+	} else {
+		m.registerLocked(e, a)
+	}
+	if e.adapter == nil {
+		return "", e.lastErr
+	}
+	return e.adapter.Name(), nil
+}
+
+// Release drops owner from every plugin it holds. Plugins without owners are
+// unregistered and disconnected.
+func (m *Manager) Release(owner string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, e := range m.plugins {
+		if e.owners[owner] {
+			m.releaseLocked(owner, e)
+		}
+	}
+}
+
+func (m *Manager) releaseLocked(owner string, e *entry) {
+	delete(e.owners, owner)
+	if len(e.owners) > 0 {
+		return
+	}
+	if e.adapter != nil {
+		name := e.adapter.Name()
+		if err := m.registry.Unregister(name); err != nil {
+			m.logger.Warn("Failed to unregister adapter plugin", zap.String("adapter", name), zap.Error(err))
+		}
+		e.adapter.Close()
+		m.logger.Info("Adapter plugin released", zap.String("adapter", name), zap.String("socket", e.socket))
+	}
+	delete(m.plugins, e.socket)
+}
+
+func (m *Manager) dial(ctx context.Context, e *entry) (*Adapter, error) {
+	return Dial(ctx, e.socket, Options{CallTimeout: m.opts.CallTimeout, Logger: m.logger})
+}
+
+// registerLocked registers a connected plugin as e's adapter.
+func (m *Manager) registerLocked(e *entry, a *Adapter) {
+	if err := m.registry.Register(a); err != nil {
+		a.Close()
+		e.lastErr = fmt.Errorf("registering adapter plugin %s: %w", e.socket, err)
+		return
+	}
+	e.adapter = a
+	e.lastErr = nil
+}
+
+// Start health-checks plugins and retries pending ones every HealthInterval.
+// Blocks until ctx is cancelled, then closes all plugin connections.
+func (m *Manager) Start(ctx context.Context) error {
+	ticker := time.NewTicker(m.opts.HealthInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			m.closeAll()
+			return nil
+		case <-ticker.C:
+			m.CheckAll(ctx)
+		}
+	}
+}
+
+// CheckAll runs one round of health checks. Unhealthy plugins are reconnected
+// and re-registered so GVR changes made by a restarted plugin take effect.
+// Plugins are dialed and checked without holding the lock, so a plugin that
+// hangs does not block Acquire, Release or Status; the result for a plugin
+// released in the meantime is discarded.
+func (m *Manager) CheckAll(ctx context.Context) {
+	type check struct {
+		entry   *entry
+		adapter *Adapter
+	}
+	m.mu.Lock()
+	checks := make([]check, 0, len(m.plugins))
+	for _, e := range m.plugins {
+		checks = append(checks, check{entry: e, adapter: e.adapter})
+	}
+	m.mu.Unlock()
+
+	for _, c := range checks {
+		if c.adapter == nil {
+			m.retry(ctx, c.entry)
+		} else {
+			m.check(ctx, c.entry, c.adapter)
+		}
+	}
+}
+
+// currentLocked reports whether e is still held and uses adapter a, nil
+// for a pending plugin. Caller must hold m.mu.
+func (m *Manager) currentLocked(e *entry, a *Adapter) bool {
+	return m.plugins[e.socket] == e && e.adapter == a
+}
+
+// retry dials a pending plugin and registers it, unless it was released or
+// connected by Acquire meanwhile.
+func (m *Manager) retry(ctx context.Context, e *entry) {
+	a, err := m.dial(ctx, e)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.currentLocked(e, nil) {
+		if a != nil {
+			a.Close()
+		}
+		return
+	}
+	if err != nil {
+		e.lastErr = err
+	} else {
+		m.registerLocked(e, a)
+	}
+	if e.lastErr != nil {
+		m.logger.Warn("Adapter plugin still unreachable", zap.String("socket", e.socket), zap.Error(e.lastErr))
+	}
+}
+
+// check health-checks a connected plugin, and reconnects and re-registers
+// it if the check fails.
+func (m *Manager) check(ctx context.Context, e *entry, a *Adapter) {
+	err := a.Check(ctx)
+	if err == nil {
+		m.setLastErr(e, a, nil)
+		return
+	}
+	m.setLastErr(e, a, err)
+	m.logger.Warn("Adapter plugin failed health check",
+		zap.String("adapter", a.Name()), zap.Error(err))
+
+	if err := a.Reconnect(ctx); err != nil {
+		m.setLastErr(e, a, err)
+		return
+	}
+	if err := a.Check(ctx); err != nil {
+		m.setLastErr(e, a, err)
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.currentLocked(e, a) {
+		// Released while reconnecting; close the new connection.
+		a.Close()
+		return
+	}
+	name := a.Name()
+	_ = m.registry.Unregister(name)
+	if err := m.registry.Register(a); err != nil {
+		e.lastErr = fmt.Errorf("re-registering adapter plugin %s: %w", name, err)
+		return
+	}
+	e.lastErr = nil
+	m.logger.Info("Adapter plugin recovered", zap.String("adapter", name))
+}
+
+// setLastErr records the result of checking a, if e still uses it. An
+// adapter released meanwhile is closed again, in case Reconnect opened a
+// new connection after the release closed it.
+func (m *Manager) setLastErr(e *entry, a *Adapter, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.currentLocked(e, a) {
+		a.Close()
+		return
+	}
+	e.lastErr = err
+}
+
+// Status returns the state of every plugin, sorted by socket.
+func (m *Manager) Status() []Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := make([]Status, 0, len(m.plugins))
+	for _, e := range m.plugins {
+		st := Status{Socket: e.socket}
+		if e.adapter != nil {
+			st.Name = e.adapter.Name()
+			st.Healthy = e.adapter.Healthy()
+			st.ProtocolVersion = e.adapter.ProtocolVersion()
+		}
+		if e.lastErr != nil {
+			st.Error = e.lastErr.Error()
+		}
+		result = append(result, st)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Socket < result[j].Socket })
+	return result
+}
+
+func (m *Manager) closeAll() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, e := range m.plugins {
+		if e.adapter != nil {
+			e.adapter.Close()
+		}
+	}
+}
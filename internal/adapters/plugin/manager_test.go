@@ -0,0 +1,207 @@
+package plugin
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/nightjarctl/nightjar/internal/adapters"
+	"github.com/nightjarctl/nightjar/pkg/adapterplugin"
+)
+
+func newTestManager() (*Manager, *adapters.Registry) {
+	registry := adapters.NewRegistry()
+	return NewManager(registry, zap.NewNop(), ManagerOptions{CallTimeout: 500 * time.Millisecond}), registry
+}
+
+func TestManager_AcquireRelease(t *testing.T) {
+	socket := socketPath(t)
+	servePlugin(t, socket, &fakeHandler{name: "example"}, adapterplugin.ServerOptions{})
+	m, registry := newTestManager()
+
+	name, err := m.Acquire(context.Background(), "flag", socket)
+	require.NoError(t, err)
+	assert.Equal(t, "example", name)
+	assert.NotNil(t, registry.ForName("example"))
+	assert.NotNil(t, registry.ForGVR(testGVR))
+
+	// A second owner shares the connection.
+	name, err = m.Acquire(context.Background(), "profile/p", socket)
+	require.NoError(t, err)
+	assert.Equal(t, "example", name)
+	require.Len(t, m.Status(), 1)
+
+	m.Release("flag")
+	assert.NotNil(t, registry.ForName("example"), "still held by profile/p")
+
+	m.Release("profile/p")
+	assert.Nil(t, registry.ForName("example"))
+	assert.Empty(t, m.Status())
+}
+
+func TestManager_AcquireSwitchesSocket(t *testing.T) {
+	first, second := socketPath(t), socketPath(t)
+	servePlugin(t, first, &fakeHandler{name: "one"}, adapterplugin.ServerOptions{})
+	servePlugin(t, second, &fakeHandler{name: "two"}, adapterplugin.ServerOptions{})
+	m, registry := newTestManager()
+
+	_, err := m.Acquire(context.Background(), "profile/p", first)
+	require.NoError(t, err)
+	_, err = m.Acquire(context.Background(), "profile/p", second)
+	require.NoError(t, err)
+
+	assert.Nil(t, registry.ForName("one"))
+	assert.NotNil(t, registry.ForName("two"))
+}
+
+func TestManager_PendingPluginRetried(t *testing.T) {
+	socket := socketPath(t)
+	m, registry := newTestManager()
+
+	_, err := m.Acquire(context.Background(), "flag", socket)
+	require.Error(t, err)
+	status := m.Status()
+	require.Len(t, status, 1)
+	assert.False(t, status[0].Healthy)
+	assert.NotEmpty(t, status[0].Error)
+
+	servePlugin(t, socket, &fakeHandler{name: "late"}, adapterplugin.ServerOptions{})
+	m.CheckAll(context.Background())
+
+	assert.NotNil(t, registry.ForName("late"))
+	status = m.Status()
+	assert.True(t, status[0].Healthy)
+	assert.Equal(t, "late", status[0].Name)
+	assert.Empty(t, status[0].Error)
+}
+
+func TestManager_CheckAllRecoversRestartedPlugin(t *testing.T) {
+	socket := socketPath(t)
+	_, stop := servePlugin(t, socket, &fakeHandler{name: "example"}, adapterplugin.ServerOptions{})
+	m, registry := newTestManager()
+
+	_, err := m.Acquire(context.Background(), "flag", socket)
+	require.NoError(t, err)
+
+	stop()
+	m.CheckAll(context.Background())
+	assert.False(t, m.Status()[0].Healthy)
+
+	servePlugin(t, socket, &fakeHandler{name: "example"}, adapterplugin.ServerOptions{})
+	m.CheckAll(context.Background())
+	assert.True(t, m.Status()[0].Healthy)
+	assert.NotNil(t, registry.ForName("example"))
+}
+
+func TestManager_CheckAllDoesNotBlockStatus(t *testing.T) {
+	socket := socketPath(t)
+	m, _ := newTestManager()
+	_, err := m.Acquire(context.Background(), "flag", socket)
+	require.Error(t, err)
+
+	// A listener that never completes the handshake keeps the retry
+	// waiting until the call timeout.
+	listenWithoutHandshake(t, socket)
+
+	done := make(chan struct{})
+	go func() {
+		m.CheckAll(context.Background())
+		close(done)
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	status := make(chan []Status, 1)
+	go func() { status <- m.Status() }()
+	select {
+	case s := <-status:
+		require.Len(t, s, 1)
+		assert.False(t, s[0].Healthy)
+	case <-done:
+		t.Fatal("the retry did not wait for the handshake")
+	case <-time.After(200 * time.Millisecond):
+		t.Fatal("Status blocked on a plugin being dialed")
+	}
+	<-done
+}
+
+func TestManager_AcquireDoesNotBlockOtherOwners(t *testing.T) {
+	hung, healthy := socketPath(t), socketPath(t)
+	servePlugin(t, healthy, &fakeHandler{name: "example"}, adapterplugin.ServerOptions{})
+	m, _ := newTestManager()
+
+	// A listener that never completes the handshake keeps Acquire
+	// waiting until the call timeout.
+	listenWithoutHandshake(t, hung)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := m.Acquire(context.Background(), "profile/hung", hung)
+		done <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	acquired := make(chan string, 1)
+	go func() {
+		name, _ := m.Acquire(context.Background(), "profile/ok", healthy)
+		m.Release("profile/ok")
+		_ = m.Status()
+		acquired <- name
+	}()
+	select {
+	case name := <-acquired:
+		assert.Equal(t, "example", name)
+	case <-done:
+		t.Fatal("Acquire did not wait for the handshake")
+	case <-time.After(300 * time.Millisecond):
+		t.Fatal("Acquire, Release or Status blocked on a plugin being dialed")
+	}
+	require.Error(t, <-done)
+}
+
+func TestManager_NotServingStaysUnhealthy(t *testing.T) {
+	socket := socketPath(t)
+	srv, _ := servePlugin(t, socket, &fakeHandler{name: "example"}, adapterplugin.ServerOptions{})
+	m, _ := newTestManager()
+
+	_, err := m.Acquire(context.Background(), "flag", socket)
+	require.NoError(t, err)
+
+	srv.SetServing(false)
+	m.CheckAll(context.Background())
+	assert.False(t, m.Status()[0].Healthy)
+}
+
+func TestManager_DuplicateName(t *testing.T) {
+	first, second := socketPath(t), socketPath(t)
+	servePlugin(t, first, &fakeHandler{name: "same"}, adapterplugin.ServerOptions{})
+	servePlugin(t, second, &fakeHandler{name: "same"}, adapterplugin.ServerOptions{})
+	m, _ := newTestManager()
+
+	_, err := m.Acquire(context.Background(), "a", first)
+	require.NoError(t, err)
+	_, err = m.Acquire(context.Background(), "b", second)
+	assert.Error(t, err)
+}
+
+// listenWithoutHandshake accepts connections on socket and never answers,
+// so dialing it waits until the call timeout.
+func listenWithoutHandshake(t *testing.T, socket string) {
+	t.Helper()
+	l, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { conn.Close() })
+		}
+	}()
+}
@@ -134,7 +134,7 @@ func (a *Adapter) Parse(ctx context.Context, obj *unstructured.Unstructured) ([]
 		Summary:            buildSummary(resources),
 		RemediationHint:    "Request quota increase or reduce resource usage",
 		Details:            map[string]interface{}{"resources": resources},
-		RawObject:          obj.DeepCopy(),
+		RawObject:          obj,
 	}
 
 	return []types.Constraint{c}, nil
@@ -123,7 +123,7 @@ func (a *Adapter) Parse(ctx context.Context, obj *unstructured.Unstructured) ([]
 			Summary:           buildWebhookSummary(webhookType, webhookName, operations, resources),
 			RemediationHint:   fmt.Sprintf("Check webhook %q logs if requests are being rejected", webhookName),
 			Details:           details,
-			RawObject:         obj.DeepCopy(),
+			RawObject:         obj,
 		}
 
 		constraints = append(constraints, c)
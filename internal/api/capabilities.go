@@ -8,7 +8,10 @@ import (
 
 	"go.uber.org/zap"
 
+	"github.com/nightjarctl/nightjar/api/v1alpha1"
 	"github.com/nightjarctl/nightjar/internal/indexer"
+	"github.com/nightjarctl/nightjar/internal/scope"
+	"github.com/nightjarctl/nightjar/internal/shard"
 	"github.com/nightjarctl/nightjar/internal/types"
 )
 
@@ -38,6 +41,10 @@ type CapabilitiesResponse struct {
 	// MCPStatus describes MCP server status.
 	MCPStatus *MCPStatus `json:"mcpStatus,omitempty"`
 
+	// Discovery explains which resource types are watched and why, with
+	// parse error counts. Same content as the DiscoveryStatus resource.
+	Discovery *v1alpha1.DiscoveryStatusStatus `json:"discovery,omitempty"`
+
 	// LastScanTime is when constraints were last scanned.
 	LastScanTime string `json:"lastScanTime"`
 
@@ -76,6 +83,7 @@ type CapabilitiesHandler struct {
 	adapters     []AdapterInfo
 	hubbleStatus *HubbleStatus
 	mcpStatus    *MCPStatus
+	discovery    func() v1alpha1.DiscoveryStatusStatus
 	startTime    time.Time
 	lastScanTime time.Time
 }
@@ -85,6 +93,18 @@ type CapabilitiesHandlerOptions struct {
 	Adapters     []AdapterInfo
 	HubbleStatus *HubbleStatus
 	MCPStatus    *MCPStatus
+
+	// Discovery returns the discovery engine's decisions. Optional; when
+	// set, WatchedResources counts the types discovery actually watches.
+	Discovery func() v1alpha1.DiscoveryStatusStatus
+
+	// Scope limits /api/v1/constraints to namespaces in scope. Optional.
+	Scope *scope.Scope
+
+	// Shards forwards /api/v1/constraints queries to the shards owning
+	// their namespace, and gathers cluster-wide queries from every shard.
+	// Optional.
+	Shards *shard.Forwarder
 }
 
 // NewCapabilitiesHandler creates a new CapabilitiesHandler.
@@ -99,6 +119,7 @@ func NewCapabilitiesHandler(
 		adapters:     opts.Adapters,
 		hubbleStatus: opts.HubbleStatus,
 		mcpStatus:    opts.MCPStatus,
+		discovery:    opts.Discovery,
 		startTime:    time.Now(),
 	}
 }
@@ -161,6 +182,16 @@ func (h *CapabilitiesHandler) buildResponse() CapabilitiesResponse {
 	}
 
 	lastScan := h.lastScanTime
+
+	var discoveryStatus *v1alpha1.DiscoveryStatusStatus
+	if h.discovery != nil {
+		status := h.discovery()
+		discoveryStatus = &status
+		watchedTotal = status.WatchedCount
+		if status.LastScanTime != nil {
+			lastScan = status.LastScanTime.Time
+		}
+	}
 	if lastScan.IsZero() {
 		lastScan = time.Now()
 	}
@@ -174,6 +205,7 @@ func (h *CapabilitiesHandler) buildResponse() CapabilitiesResponse {
 		WatchedResources: watchedTotal,
 		HubbleStatus:     h.hubbleStatus,
 		MCPStatus:        h.mcpStatus,
+		Discovery:        discoveryStatus,
 		LastScanTime:     lastScan.UTC().Format(time.RFC3339),
 		UpSince:          h.startTime.UTC().Format(time.RFC3339),
 	}
@@ -196,6 +228,8 @@ func resourceToAdapterName(resource string) string {
 		return "gatekeeper"
 	case "clusterpolicies", "policies":
 		return "kyverno"
+	case "serviceentries":
+		return "istio"
 	default:
 		return "generic"
 	}
@@ -285,6 +319,8 @@ func RegisterHandlers(mux *http.ServeMux, idx *indexer.Indexer, logger *zap.Logg
 	capHandler := NewCapabilitiesHandler(idx, logger, opts)
 	healthHandler := NewHealthHandler(idx, logger)
 	constraintsHandler := NewConstraintsHandler(idx, logger)
+	constraintsHandler.nsScope = opts.Scope
+	constraintsHandler.shards = opts.Shards
 
 	mux.Handle("/api/v1/capabilities", capHandler)
 	mux.Handle("/api/v1/constraints", constraintsHandler)
@@ -298,6 +334,8 @@ func ExtraHandlers(idx *indexer.Indexer, logger *zap.Logger, opts CapabilitiesHa
 	capHandler := NewCapabilitiesHandler(idx, logger, opts)
 	healthHandler := NewHealthHandler(idx, logger)
 	constraintsHandler := NewConstraintsHandler(idx, logger)
+	constraintsHandler.nsScope = opts.Scope
+	constraintsHandler.shards = opts.Shards
 
 	return map[string]http.Handler{
 		"/api/v1/capabilities": capHandler,
@@ -5,13 +5,16 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	k8stypes "k8s.io/apimachinery/pkg/types"
 
+	"github.com/nightjarctl/nightjar/api/v1alpha1"
 	"github.com/nightjarctl/nightjar/internal/indexer"
 	"github.com/nightjarctl/nightjar/internal/types"
 )
@@ -170,6 +173,46 @@ func TestCapabilitiesHandler_WithHubble(t *testing.T) {
 	assert.Equal(t, "hubble-relay.kube-system:4245", response.HubbleStatus.Address)
 }
 
+func TestCapabilitiesHandler_WithDiscovery(t *testing.T) {
+	idx := setupTestIndexer()
+	scanTime := metav1.NewTime(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+
+	opts := CapabilitiesHandlerOptions{
+		Adapters: DefaultAdapters(),
+		Discovery: func() v1alpha1.DiscoveryStatusStatus {
+			return v1alpha1.DiscoveryStatusStatus{
+				WatchedCount:     1,
+				ParseErrorCount:  2,
+				LastScanTime:     &scanTime,
+				LastScanComplete: true,
+				Resources: []v1alpha1.DiscoveryDecision{
+					{Group: "kyverno.io", Version: "v1", Resource: "clusterpolicies", Watched: true, Reason: "AdapterRegistered", Confidence: 95, ParseErrors: 2},
+					{Group: "apps", Version: "v1", Resource: "deployments", Reason: "NoMatch", Confidence: 70},
+				},
+			}
+		},
+	}
+
+	handler := NewCapabilitiesHandler(idx, zap.NewNop(), opts)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/capabilities", nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	var response CapabilitiesResponse
+	err := json.NewDecoder(w.Body).Decode(&response)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, response.WatchedResources, "watched count comes from discovery")
+	assert.Equal(t, "2026-03-01T12:00:00Z", response.LastScanTime)
+	require.NotNil(t, response.Discovery)
+	assert.Equal(t, int64(2), response.Discovery.ParseErrorCount)
+	require.Len(t, response.Discovery.Resources, 2)
+	assert.Equal(t, "AdapterRegistered", response.Discovery.Resources[0].Reason)
+	assert.False(t, response.Discovery.Resources[1].Watched)
+}
+
 func TestHealthHandler(t *testing.T) {
 	idx := setupTestIndexer()
 	handler := NewHealthHandler(idx, zap.NewNop())
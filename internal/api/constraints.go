@@ -5,8 +5,11 @@ import (
 	"net/http"
 
 	"go.uber.org/zap"
+	k8stypes "k8s.io/apimachinery/pkg/types"
 
 	"github.com/nightjarctl/nightjar/internal/indexer"
+	"github.com/nightjarctl/nightjar/internal/scope"
+	"github.com/nightjarctl/nightjar/internal/shard"
 	"github.com/nightjarctl/nightjar/internal/types"
 )
 
@@ -14,12 +17,18 @@ import (
 // The webhook's ConstraintClient.Query() decodes this exact shape.
 type ConstraintsResponse struct {
 	Constraints []types.Constraint `json:"constraints"`
+
+	// Stale is set while the constraints were restored from a snapshot and
+	// have not yet been reconciled against the cluster.
+	Stale bool `json:"stale,omitempty"`
 }
 
 // ConstraintsHandler handles GET /api/v1/constraints.
 type ConstraintsHandler struct {
 	logger  *zap.Logger
 	indexer *indexer.Indexer
+	nsScope *scope.Scope     // nil serves every namespace
+	shards  *shard.Forwarder // nil serves every query locally
 }
 
 // NewConstraintsHandler creates a new ConstraintsHandler.
@@ -45,12 +54,36 @@ func (h *ConstraintsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	namespace := r.URL.Query().Get("namespace")
 
+	// In a sharded controller, the owning shard answers for a namespace.
+	if h.shards.Forward(w, r, namespace, nil) {
+		return
+	}
+
+	// Out-of-scope namespaces get no constraints, so the webhook stays silent
+	// for them.
 	var constraints []types.Constraint
 	if namespace != "" {
-		constraints = h.indexer.ByNamespace(namespace)
+		if h.nsScope.Allows(namespace) {
+			constraints = h.indexer.ByNamespace(namespace)
+		}
 	} else {
 		constraints = h.indexer.All()
 	}
+	stale := h.indexer.Stale()
+
+	// Cluster-wide queries gather every shard's constraints. Cluster-scoped
+	// constraints are indexed by every shard and deduplicated by UID.
+	if namespace == "" {
+		remote, err := h.shards.Gather(r)
+		if err != nil {
+			h.logger.Warn("Failed to gather constraints from other shards", zap.Error(err))
+			http.Error(w, "gathering constraints from other shards: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+		if len(remote) > 0 {
+			constraints, stale = mergeShardResponses(constraints, stale, remote)
+		}
+	}
 
 	// Strip RawObject to avoid sending full unstructured objects over the wire.
 	stripped := make([]types.Constraint, len(constraints))
@@ -60,7 +93,33 @@ func (h *ConstraintsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(ConstraintsResponse{Constraints: stripped}); err != nil {
+	resp := ConstraintsResponse{Constraints: stripped, Stale: stale}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
 		h.logger.Error("Failed to encode constraints response", zap.Error(err))
 	}
 }
+
+// mergeShardResponses adds the constraints of other shards' responses to the
+// local ones, skipping UIDs already present. The result is stale if any
+// shard's index is.
+func mergeShardResponses(local []types.Constraint, stale bool, remote [][]byte) ([]types.Constraint, bool) {
+	seen := make(map[k8stypes.UID]bool, len(local))
+	for _, c := range local {
+		seen[c.UID] = true
+	}
+	merged := local
+	for _, body := range remote {
+		var resp ConstraintsResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			continue
+		}
+		stale = stale || resp.Stale
+		for _, c := range resp.Constraints {
+			if !seen[c.UID] {
+				seen[c.UID] = true
+				merged = append(merged, c)
+			}
+		}
+	}
+	return merged, stale
+}
@@ -4,12 +4,19 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
 
+	"github.com/nightjarctl/nightjar/internal/indexer"
+	"github.com/nightjarctl/nightjar/internal/scope"
+	"github.com/nightjarctl/nightjar/internal/shard"
 	"github.com/nightjarctl/nightjar/internal/types"
 )
 
@@ -167,3 +174,114 @@ func TestExtraHandlers(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, w.Code)
 }
+
+func TestConstraintsHandler_OutOfScopeNamespace(t *testing.T) {
+	nsScope := scope.New(k8sfake.NewSimpleClientset(), zap.NewNop(), scope.Options{})
+	require.NoError(t, nsScope.Update(scope.Config{ExcludeNamespaces: []string{"team-alpha"}}))
+	handler := ExtraHandlers(setupTestIndexer(), zap.NewNop(), CapabilitiesHandlerOptions{Scope: nsScope})["/api/v1/constraints"]
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/constraints?namespace=team-alpha", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response ConstraintsResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+	assert.Empty(t, response.Constraints, "the webhook gets nothing for excluded namespaces")
+}
+
+func TestConstraintsHandler_Stale(t *testing.T) {
+	idx := setupTestIndexer()
+	handler := NewConstraintsHandler(idx, zap.NewNop())
+
+	get := func() ConstraintsResponse {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/constraints?namespace=team-alpha", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		var response ConstraintsResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+		return response
+	}
+
+	assert.False(t, get().Stale)
+
+	idx.SetStale(true)
+	response := get()
+	assert.True(t, response.Stale)
+	assert.Len(t, response.Constraints, 3, "stale constraints are still served")
+}
+
+// shardRouter assigns team-beta to shard b and everything else to shard a.
+type shardRouter struct {
+	self, b shard.Member
+}
+
+func (r shardRouter) Self() shard.Member { return r.self }
+
+func (r shardRouter) Owner(namespace string) (shard.Member, bool) {
+	if namespace == "team-beta" {
+		return r.b, true
+	}
+	return shard.Member{Identity: "a", Host: "127.0.0.1"}, true
+}
+
+func (r shardRouter) Members() []shard.Member {
+	return []shard.Member{{Identity: "a", Host: "127.0.0.1"}, r.b}
+}
+
+func TestConstraintsHandler_Sharded(t *testing.T) {
+	// Shard b indexes team-beta's constraints and the replicated
+	// cluster-scoped one.
+	remoteIdx := indexer.New(nil)
+	remoteIdx.Upsert(types.Constraint{UID: "beta-netpol", Name: "beta-netpol", Namespace: "team-beta"})
+	remoteIdx.Upsert(types.Constraint{UID: "webhook-1", Name: "test-webhook", AffectedNamespaces: []string{"team-alpha", "team-beta"}})
+	remoteIdx.SetStale(true)
+	remote := httptest.NewServer(NewConstraintsHandler(remoteIdx, zap.NewNop()))
+	defer remote.Close()
+	u, err := url.Parse(remote.URL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(u.Port())
+	require.NoError(t, err)
+
+	router := shardRouter{
+		self: shard.Member{Identity: "a", Host: "127.0.0.1"},
+		b:    shard.Member{Identity: "b", Host: "127.0.0.1"},
+	}
+	handler := ExtraHandlers(setupTestIndexer(), zap.NewNop(), CapabilitiesHandlerOptions{
+		Shards: shard.NewForwarder(router, port, zap.NewNop()),
+	})["/api/v1/constraints"]
+	get := func(query string, forwarded bool) ConstraintsResponse {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/constraints"+query, nil)
+		if forwarded {
+			req.Header.Set(shard.ForwardedHeader, "b")
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		var response ConstraintsResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+		return response
+	}
+	uids := func(response ConstraintsResponse) []string {
+		var out []string
+		for _, c := range response.Constraints {
+			out = append(out, string(c.UID))
+		}
+		sort.Strings(out)
+		return out
+	}
+
+	assert.Equal(t, []string{"beta-netpol", "webhook-1"}, uids(get("?namespace=team-beta", false)), "the owning shard answers")
+	assert.Equal(t, []string{"netpol-1", "quota-1", "webhook-1"}, uids(get("?namespace=team-alpha", false)))
+
+	all := get("", false)
+	assert.Equal(t, []string{"beta-netpol", "netpol-1", "quota-1", "webhook-1"}, uids(all), "cluster-wide queries gather every shard")
+	assert.True(t, all.Stale, "stale if any shard is")
+
+	assert.Len(t, get("", true).Constraints, 3, "forwarded queries are served locally")
+
+	remote.Close()
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/constraints?namespace=team-beta", nil))
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+}
@@ -0,0 +1,225 @@
+package audit
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Admission plugins that deny requests, as reported in Denial.Plugin.
+const (
+	PluginWebhook                   = "ValidatingAdmissionWebhook"
+	PluginValidatingAdmissionPolicy = "ValidatingAdmissionPolicy"
+	PluginResourceQuota             = "ResourceQuota"
+	PluginLimitRanger               = "LimitRanger"
+	PluginPodSecurity               = "PodSecurity"
+)
+
+// Patterns of the messages admission plugins deny requests with.
+var (
+	// admission webhook "validation.gatekeeper.sh" denied the request: ...
+	webhookDenied = regexp.MustCompile(`admission webhook "([^"]+)" denied the request`)
+
+	// ValidatingAdmissionPolicy 'require-team' with binding 'require-team-binding' denied request: ...
+	policyDenied = regexp.MustCompile(`ValidatingAdmissionPolicy '([^']+)' with binding '([^']+)' denied request`)
+
+	// exceeded quota: compute-resources, requested: ... or failed quota: compute-resources: must specify ...
+	quotaDenied = regexp.MustCompile(`(?:exceeded|failed) quota: ([^,:\s]+)`)
+
+	// maximum cpu usage per Container is 2, but limit is 4
+	limitRangeDenied = regexp.MustCompile(`(?:maximum|minimum) \S+ usage per (?:Container|Pod|PersistentVolumeClaim) is|limit to request ratio per`)
+
+	// violates PodSecurity "restricted:latest"
+	podSecurityDenied = regexp.MustCompile(`violates PodSecurity "[^"]+"`)
+
+	// Gatekeeper prefixes each violation with the constraint name:
+	// [require-team-label] you must provide labels: {"team"}
+	gatekeeperViolation = regexp.MustCompile(`\[([a-z0-9][-a-z0-9.]*)\] `)
+)
+
+// kyvernoBlocked introduces Kyverno's list of failed policies and rules.
+const kyvernoBlocked = "was blocked due to the following policies"
+
+// ObjectRef identifies the object a denied request targeted.
+type ObjectRef struct {
+	APIGroup    string `json:"apiGroup,omitempty"`
+	APIVersion  string `json:"apiVersion,omitempty"`
+	Resource    string `json:"resource,omitempty"`
+	Subresource string `json:"subresource,omitempty"`
+	Namespace   string `json:"namespace,omitempty"`
+	Name        string `json:"name,omitempty"`
+}
+
+// String returns the object as resource.group namespace/name.
+func (o ObjectRef) String() string {
+	resource := o.Resource
+	if o.APIGroup != "" {
+		resource += "." + o.APIGroup
+	}
+	if o.Namespace == "" {
+		return resource + " " + o.Name
+	}
+	return resource + " " + o.Namespace + "/" + o.Name
+}
+
+// Denial is a request an admission plugin rejected, read from the audit log.
+type Denial struct {
+	AuditID string
+	Time    time.Time
+
+	// User is the requesting user, e.g. system:serviceaccount:argocd:argocd-application-controller
+	User      string
+	UserAgent string
+
+	// Verb is the API verb, e.g. create or update
+	Verb   string
+	Object ObjectRef
+
+	// Code is the HTTP status code of the response
+	Code    int32
+	Reason  string
+	Message string
+
+	// Plugin is the admission plugin that denied the request
+	Plugin string
+
+	// Webhook is the denying webhook's name, for PluginWebhook
+	Webhook string
+
+	// Policies are the policies named in the message: the
+	// ValidatingAdmissionPolicy, Gatekeeper constraints, Kyverno policies
+	// and policy/rule pairs, or the ResourceQuota
+	Policies []string
+}
+
+// event is the subset of an audit.k8s.io/v1 Event read by this package.
+type event struct {
+	Kind      string `json:"kind"`
+	AuditID   string `json:"auditID"`
+	Stage     string `json:"stage"`
+	Verb      string `json:"verb"`
+	UserAgent string `json:"userAgent"`
+	User      struct {
+		Username string `json:"username"`
+	} `json:"user"`
+	ImpersonatedUser *struct {
+		Username string `json:"username"`
+	} `json:"impersonatedUser"`
+	ObjectRef      *ObjectRef `json:"objectRef"`
+	ResponseStatus *struct {
+		Code    int32  `json:"code"`
+		Reason  string `json:"reason"`
+		Message string `json:"message"`
+	} `json:"responseStatus"`
+	StageTimestamp time.Time `json:"stageTimestamp"`
+}
+
+// eventList is an audit.k8s.io/v1 EventList, the body of audit webhook
+// requests.
+type eventList struct {
+	Kind  string  `json:"kind"`
+	Items []event `json:"items"`
+}
+
+// stageResponseComplete is the audit stage that carries the response.
+const stageResponseComplete = "ResponseComplete"
+
+// denial returns the admission denial an audit event records, if any.
+// Requests failing for other reasons, e.g. authorization, are ignored.
+func (e event) denial() (Denial, bool) {
+	status := e.ResponseStatus
+	if e.Stage != stageResponseComplete || status == nil || status.Code < 400 || e.ObjectRef == nil {
+		return Denial{}, false
+	}
+
+	d := Denial{
+		AuditID:   e.AuditID,
+		Time:      e.StageTimestamp,
+		User:      e.User.Username,
+		UserAgent: e.UserAgent,
+		Verb:      e.Verb,
+		Object:    *e.ObjectRef,
+		Code:      status.Code,
+		Reason:    status.Reason,
+		Message:   status.Message,
+	}
+	if e.ImpersonatedUser != nil && e.ImpersonatedUser.Username != "" {
+		d.User = e.ImpersonatedUser.Username
+	}
+
+	msg := status.Message
+	switch {
+	case webhookDenied.MatchString(msg):
+		d.Plugin = PluginWebhook
+		d.Webhook = webhookDenied.FindStringSubmatch(msg)[1]
+		d.Policies = webhookPolicies(msg)
+	case policyDenied.MatchString(msg):
+		d.Plugin = PluginValidatingAdmissionPolicy
+		d.Policies = []string{policyDenied.FindStringSubmatch(msg)[1]}
+	case quotaDenied.MatchString(msg):
+		d.Plugin = PluginResourceQuota
+		d.Policies = []string{quotaDenied.FindStringSubmatch(msg)[1]}
+	case limitRangeDenied.MatchString(msg):
+		d.Plugin = PluginLimitRanger
+	case podSecurityDenied.MatchString(msg):
+		d.Plugin = PluginPodSecurity
+	default:
+		return Denial{}, false
+	}
+	return d, true
+}
+
+// webhookPolicies returns the policies a webhook's denial message names:
+// Gatekeeper's [constraint] prefixes, or Kyverno's policy and rule list.
+func webhookPolicies(msg string) []string {
+	var policies []string
+	seen := map[string]bool{}
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			policies = append(policies, name)
+		}
+	}
+
+	if _, list, ok := strings.Cut(msg, kyvernoBlocked); ok {
+		// policy:
+		//   rule: message
+		var policy string
+		for _, line := range strings.Split(list, "\n") {
+			trimmed := strings.TrimSpace(line)
+			if trimmed == "" {
+				continue
+			}
+			name, _, _ := strings.Cut(trimmed, ":")
+			if line[0] != ' ' && line[0] != '\t' {
+				policy = name
+				add(policy)
+			} else if policy != "" {
+				add(policy + "/" + name)
+			}
+		}
+		return policies
+	}
+
+	for _, m := range gatekeeperViolation.FindAllStringSubmatch(msg, -1) {
+		add(m[1])
+	}
+	return policies
+}
+
+// decodeEvents decodes an audit.k8s.io/v1 EventList or a single Event.
+func decodeEvents(data []byte) ([]event, error) {
+	var list eventList
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+	if list.Kind == "EventList" {
+		return list.Items, nil
+	}
+	var e event
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+	return []event{e}, nil
+}
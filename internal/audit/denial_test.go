@@ -0,0 +1,162 @@
+package audit
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// auditEvent returns an audit event for a create of a Deployment that
+// failed with code and message.
+func auditEvent(code int32, message string) event {
+	var e event
+	data := `{"kind":"Event","apiVersion":"audit.k8s.io/v1","auditID":"a1","stage":"ResponseComplete","verb":"create",` +
+		`"user":{"username":"system:serviceaccount:argocd:argocd-application-controller"},"userAgent":"argocd-application-controller/v2.11",` +
+		`"objectRef":{"resource":"deployments","namespace":"shop","name":"orders","apiGroup":"apps","apiVersion":"v1"},` +
+		`"stageTimestamp":"2026-03-02T10:00:02.000000Z"}`
+	if err := json.Unmarshal([]byte(data), &e); err != nil {
+		panic(err)
+	}
+	e.ResponseStatus = &struct {
+		Code    int32  `json:"code"`
+		Reason  string `json:"reason"`
+		Message string `json:"message"`
+	}{Code: code, Message: message}
+	return e
+}
+
+func TestEvent_Denial(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     int32
+		message  string
+		plugin   string
+		webhook  string
+		policies []string
+	}{
+		{
+			name:     "gatekeeper",
+			code:     403,
+			message:  `admission webhook "validation.gatekeeper.sh" denied the request: [require-team] you must provide labels: {"team"}` + "\n" + `[no-latest] image tag latest is not allowed`,
+			plugin:   PluginWebhook,
+			webhook:  "validation.gatekeeper.sh",
+			policies: []string{"require-team", "no-latest"},
+		},
+		{
+			name: "kyverno",
+			code: 400,
+			message: "admission webhook \"validate.kyverno.svc-fail\" denied the request: \n\n" +
+				"resource Deployment/shop/orders was blocked due to the following policies \n\n" +
+				"require-labels:\n  check-team: 'validation error: label team is required. rule check-team failed at path /metadata/labels/team/'\n" +
+				"disallow-latest:\n  validate-image-tag: 'validation error: Using a mutable image tag e.g. latest is not allowed.'\n",
+			plugin:   PluginWebhook,
+			webhook:  "validate.kyverno.svc-fail",
+			policies: []string{"require-labels", "require-labels/check-team", "disallow-latest", "disallow-latest/validate-image-tag"},
+		},
+		{
+			name:     "validating admission policy",
+			code:     422,
+			message:  `deployments.apps "orders" is forbidden: ValidatingAdmissionPolicy 'require-team' with binding 'require-team-binding' denied request: missing team label`,
+			plugin:   PluginValidatingAdmissionPolicy,
+			policies: []string{"require-team"},
+		},
+		{
+			name:     "quota exceeded",
+			code:     403,
+			message:  `pods "orders-0" is forbidden: exceeded quota: compute-resources, requested: limits.cpu=2, used: limits.cpu=8, limited: limits.cpu=8`,
+			plugin:   PluginResourceQuota,
+			policies: []string{"compute-resources"},
+		},
+		{
+			name:     "quota failed",
+			code:     403,
+			message:  `pods "orders-0" is forbidden: failed quota: compute-resources: must specify limits.cpu for: orders`,
+			plugin:   PluginResourceQuota,
+			policies: []string{"compute-resources"},
+		},
+		{
+			name:    "limit range",
+			code:    403,
+			message: `pods "orders-0" is forbidden: maximum cpu usage per Container is 2, but limit is 4`,
+			plugin:  PluginLimitRanger,
+		},
+		{
+			name:    "pod security",
+			code:    403,
+			message: `pods "orders-0" is forbidden: violates PodSecurity "restricted:latest": allowPrivilegeEscalation != false`,
+			plugin:  PluginPodSecurity,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, ok := auditEvent(tt.code, tt.message).denial()
+
+			require.True(t, ok)
+			assert.Equal(t, tt.plugin, d.Plugin)
+			assert.Equal(t, tt.webhook, d.Webhook)
+			assert.Equal(t, tt.policies, d.Policies)
+			assert.Equal(t, tt.code, d.Code)
+			assert.Equal(t, "system:serviceaccount:argocd:argocd-application-controller", d.User)
+			assert.Equal(t, "deployments.apps shop/orders", d.Object.String())
+			assert.Equal(t, "a1", d.AuditID)
+			assert.False(t, d.Time.IsZero())
+		})
+	}
+}
+
+func TestEvent_Denial_Ignored(t *testing.T) {
+	assert := assert.New(t)
+
+	_, ok := auditEvent(201, "").denial()
+	assert.False(ok, "success")
+
+	_, ok = auditEvent(403, `deployments.apps is forbidden: User "dev" cannot create resource "deployments"`).denial()
+	assert.False(ok, "authorization")
+
+	_, ok = auditEvent(409, `deployments.apps "orders" already exists`).denial()
+	assert.False(ok, "conflict")
+
+	e := auditEvent(403, `exceeded quota: compute-resources`)
+	e.Stage = "ResponseStarted"
+	_, ok = e.denial()
+	assert.False(ok, "stage")
+
+	e = auditEvent(403, `exceeded quota: compute-resources`)
+	e.ObjectRef = nil
+	_, ok = e.denial()
+	assert.False(ok, "no object")
+}
+
+func TestEvent_Denial_Impersonated(t *testing.T) {
+	e := auditEvent(403, `exceeded quota: compute-resources`)
+	e.ImpersonatedUser = &struct {
+		Username string `json:"username"`
+	}{Username: "jane"}
+
+	d, ok := e.denial()
+
+	require.True(t, ok)
+	assert.Equal(t, "jane", d.User)
+}
+
+func TestDecodeEvents(t *testing.T) {
+	events, err := decodeEvents([]byte(`{"kind":"EventList","apiVersion":"audit.k8s.io/v1","items":[{"auditID":"a1"},{"auditID":"a2"}]}`))
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, "a2", events[1].AuditID)
+
+	events, err = decodeEvents([]byte(`{"kind":"Event","auditID":"a3"}`))
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "a3", events[0].AuditID)
+
+	_, err = decodeEvents([]byte(`not json`))
+	assert.Error(t, err)
+}
+
+func TestObjectRef_String(t *testing.T) {
+	assert.Equal(t, "namespaces shop", ObjectRef{Resource: "namespaces", Name: "shop"}.String())
+	assert.Equal(t, "pods shop/orders-0", ObjectRef{Resource: "pods", Namespace: "shop", Name: "orders-0"}.String())
+}
@@ -0,0 +1,51 @@
+// Package audit detects admission denials in the Kubernetes audit log.
+//
+// # Overview
+//
+// When a request is rejected by a validating webhook, a
+// ValidatingAdmissionPolicy, a ResourceQuota or a LimitRange, the API server
+// returns the error to the client and creates no Event, so a controller
+// watching Events never sees it. The audit log records every request with
+// its response; this package reads it and reports each response with
+// responseStatus.code >= 400 whose message comes from an admission plugin
+// as a Denial, with the requesting user and the target object.
+//
+// Only events at the ResponseComplete stage are considered. Requests that
+// fail authentication, authorization or validation are ignored, as their
+// messages do not match any admission plugin.
+//
+// # Sources
+//
+// A Receiver is an audit webhook backend. Point the API server at it with
+// --audit-webhook-config-file, a kubeconfig whose cluster server is the
+// receiver's URL; it accepts EventList batches on any path.
+//
+// A FileReader follows the file written by the log backend,
+// --audit-log-path with --audit-log-format=json, and reopens it when it is
+// rotated.
+//
+// Either way the audit policy must log the ResponseComplete stage of write
+// requests at the Metadata level or above; responseStatus is included at
+// every level.
+//
+// # Replicas
+//
+// Denials are correlated by the replica that owns the target namespace:
+// the leader, or with sharding the owning shard. The API server posts to
+// whichever replica the Service picks, so every replica runs a Receiver and
+// relays the denials it does not own to their owner's relay server (see
+// shard.RelayServer), where a Relay emits them. The FileReader runs on the leader only and relays the
+// same way.
+//
+// # Attribution
+//
+// The plugin and the policies it names are read from the message:
+//
+//	admission webhook "validation.gatekeeper.sh" denied the request: [require-team] ...
+//	ValidatingAdmissionPolicy 'require-team' with binding 'require-team-binding' denied request: ...
+//	exceeded quota: compute-resources, requested: ...
+//	maximum cpu usage per Container is 2, but limit is 4
+//
+// Gatekeeper prefixes each violation with the constraint name, and Kyverno
+// lists the failed policies and rules, which become Denial.Policies.
+package audit
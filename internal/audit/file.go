@@ -0,0 +1,237 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/nightjarctl/nightjar/internal/shard"
+	"github.com/nightjarctl/nightjar/internal/util"
+)
+
+// FileReaderOptions configures a FileReader.
+type FileReaderOptions struct {
+	// FromStart reads the events already in the file. By default reading
+	// starts at the end, so a restart does not report old denials again.
+	FromStart bool
+
+	// PollInterval is how often the file is checked for new lines and
+	// rotation
+	PollInterval time.Duration
+
+	// BufferSize is the size of the denial channel buffer
+	BufferSize int
+
+	// Shards relays the denials of namespaces another replica owns to it.
+	// With sharding, only the leader follows the file. Nil keeps every
+	// denial.
+	Shards *shard.Forwarder
+
+	// Logger for the reader
+	Logger *zap.Logger
+}
+
+// DefaultFileReaderOptions returns default options for the FileReader.
+func DefaultFileReaderOptions() FileReaderOptions {
+	return FileReaderOptions{
+		PollInterval: time.Second,
+		BufferSize:   1000,
+		Logger:       zap.NewNop(),
+	}
+}
+
+// FileReaderStats contains audit log file statistics.
+type FileReaderStats struct {
+	// Events is the number of audit events decoded.
+	Events uint64
+	// Denials is the number of admission denials emitted or relayed.
+	Denials uint64
+	// Invalid is the number of lines that are not audit events.
+	Invalid uint64
+	// Rotations is the number of times the file was reopened.
+	Rotations uint64
+}
+
+// FileReader follows an audit log file written by the API server's log
+// backend, one JSON event per line, and emits the admission denials.
+//
+// When the file is rotated, i.e. replaced or truncated, the reader reopens
+// it and continues from the start of the new file.
+type FileReader struct {
+	path    string
+	opts    FileReaderOptions
+	logger  *zap.Logger
+	denials chan Denial
+
+	mu    sync.Mutex
+	stats FileReaderStats
+}
+
+// NewFileReader creates a FileReader for the audit log at path. Call Run
+// to start reading.
+func NewFileReader(path string, opts FileReaderOptions) *FileReader {
+	if opts.Logger == nil {
+		opts.Logger = zap.NewNop()
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DefaultFileReaderOptions().PollInterval
+	}
+	if opts.BufferSize == 0 {
+		opts.BufferSize = DefaultFileReaderOptions().BufferSize
+	}
+
+	return &FileReader{
+		path:    path,
+		opts:    opts,
+		logger:  opts.Logger.Named("audit-file"),
+		denials: make(chan Denial, opts.BufferSize),
+	}
+}
+
+// Denials returns the channel of admission denials. The channel is closed
+// when Run returns.
+func (r *FileReader) Denials() <-chan Denial {
+	return r.denials
+}
+
+// Stats returns audit log file statistics.
+func (r *FileReader) Stats() FileReaderStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.stats
+}
+
+// Run follows the file until ctx is cancelled. It waits for the consumer
+// instead of dropping denials when the buffer is full, and returns an error
+// if the file cannot be opened or read.
+func (r *FileReader) Run(ctx context.Context) error {
+	defer close(r.denials)
+
+	f, err := os.Open(r.path)
+	if err != nil {
+		return fmt.Errorf("open audit log: %w", err)
+	}
+	defer func() { f.Close() }()
+
+	if !r.opts.FromStart {
+		if _, err := f.Seek(0, io.SeekEnd); err != nil {
+			return fmt.Errorf("seek audit log: %w", err)
+		}
+	}
+	r.logger.Info("Following audit log", zap.String("path", r.path))
+
+	var (
+		br      = bufio.NewReader(f)
+		partial []byte
+	)
+	for {
+		line, readErr := br.ReadBytes('\n')
+		if readErr != nil && !errors.Is(readErr, io.EOF) {
+			return fmt.Errorf("read audit log: %w", readErr)
+		}
+
+		// The last line may still be being written; keep it until the
+		// rest arrives.
+		if readErr != nil {
+			partial = append(partial, line...)
+			if !r.poll(ctx) {
+				return nil
+			}
+			rotated, err := util.ReopenIfRotated(f, r.path)
+			if err != nil {
+				r.logger.Debug("audit log not reopened", zap.Error(err))
+				continue
+			}
+			if rotated != nil {
+				if rotated != f {
+					f.Close()
+				}
+				f, br, partial = rotated, bufio.NewReader(rotated), nil
+				r.count(func(s *FileReaderStats) { s.Rotations++ })
+				r.logger.Info("Audit log rotated, reopened", zap.String("path", r.path))
+			}
+			continue
+		}
+		if len(partial) > 0 {
+			line = append(partial, line...)
+			partial = nil
+		}
+
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			if !r.handleLine(ctx, line) {
+				return nil
+			}
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// handleLine decodes a line and emits it if it is a denial. It returns
+// false if ctx was cancelled while waiting for the consumer.
+func (r *FileReader) handleLine(ctx context.Context, line []byte) bool {
+	var e event
+	if err := json.Unmarshal(line, &e); err != nil || e.Kind != "Event" {
+		r.count(func(s *FileReaderStats) { s.Invalid++ })
+		eventsTotal.WithLabelValues(sourceFile, resultInvalid).Inc()
+		return true
+	}
+	r.count(func(s *FileReaderStats) { s.Events++ })
+
+	d, ok := e.denial()
+	if !ok {
+		eventsTotal.WithLabelValues(sourceFile, resultIgnored).Inc()
+		return true
+	}
+	local, err := relay(ctx, r.opts.Shards, d)
+	if err != nil {
+		eventsTotal.WithLabelValues(sourceFile, resultUnrelayed).Inc()
+		r.logger.Warn("Failed to relay admission denial",
+			zap.String("audit_id", d.AuditID),
+			zap.Stringer("object", d.Object),
+			zap.Error(err))
+		return ctx.Err() == nil
+	}
+	if !local {
+		eventsTotal.WithLabelValues(sourceFile, resultRelayed).Inc()
+		r.count(func(s *FileReaderStats) { s.Denials++ })
+		return true
+	}
+	eventsTotal.WithLabelValues(sourceFile, resultDenied).Inc()
+	select {
+	case r.denials <- d:
+		r.count(func(s *FileReaderStats) { s.Denials++ })
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// poll waits for more input to be appended. It returns false if ctx was
+// cancelled.
+func (r *FileReader) poll(ctx context.Context) bool {
+	timer := time.NewTimer(r.opts.PollInterval)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (r *FileReader) count(fn func(*FileReaderStats)) {
+	r.mu.Lock()
+	fn(&r.stats)
+	r.mu.Unlock()
+}
@@ -0,0 +1,109 @@
+package audit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func deniedLine(auditID string) string {
+	return `{"kind":"Event","apiVersion":"audit.k8s.io/v1","auditID":"` + auditID + `","stage":"ResponseComplete","verb":"create",` +
+		`"user":{"username":"jane"},"objectRef":{"resource":"pods","namespace":"shop","name":"orders-0"},` +
+		`"responseStatus":{"code":403,"message":"pods \"orders-0\" is forbidden: maximum cpu usage per Container is 2, but limit is 4"}}` + "\n"
+}
+
+func appendFile(t *testing.T, path, data string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	require.NoError(t, err)
+	_, err = f.WriteString(data)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+}
+
+// startReader runs r until the test ends.
+func startReader(t *testing.T, r *FileReader) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- r.Run(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		require.NoError(t, <-done)
+	})
+}
+
+func receive(t *testing.T, r *FileReader) Denial {
+	t.Helper()
+	select {
+	case d := <-r.Denials():
+		return d
+	case <-time.After(2 * time.Second):
+		t.Fatal("denial was not read")
+		return Denial{}
+	}
+}
+
+func TestFileReader_FromStart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	appendFile(t, path, "not json\n"+deniedLine("a1")+`{"kind":"Event","stage":"RequestReceived"}`+"\n")
+
+	r := NewFileReader(path, FileReaderOptions{FromStart: true, PollInterval: 10 * time.Millisecond})
+	startReader(t, r)
+
+	d := receive(t, r)
+	assert.Equal(t, "a1", d.AuditID)
+	assert.Equal(t, PluginLimitRanger, d.Plugin)
+	assert.Eventually(t, func() bool {
+		return r.Stats() == FileReaderStats{Events: 2, Denials: 1, Invalid: 1}
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestFileReader_SkipsExisting(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	appendFile(t, path, deniedLine("old"))
+
+	r := NewFileReader(path, FileReaderOptions{PollInterval: 10 * time.Millisecond})
+	startReader(t, r)
+
+	// Give the reader time to open the file before appending.
+	time.Sleep(50 * time.Millisecond)
+	appendFile(t, path, deniedLine("new"))
+	assert.Equal(t, "new", receive(t, r).AuditID)
+}
+
+func TestFileReader_Rotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "audit.log")
+	appendFile(t, path, "")
+
+	r := NewFileReader(path, FileReaderOptions{PollInterval: 10 * time.Millisecond})
+	startReader(t, r)
+	time.Sleep(50 * time.Millisecond)
+
+	appendFile(t, path, deniedLine("a1"))
+	assert.Equal(t, "a1", receive(t, r).AuditID)
+
+	// Rename and recreate, as the API server's log backend does.
+	require.NoError(t, os.Rename(path, filepath.Join(dir, "audit-2026-03-02T10-00-00.log")))
+	appendFile(t, path, deniedLine("a2"))
+	assert.Equal(t, "a2", receive(t, r).AuditID)
+
+	// Truncate in place, as copytruncate does.
+	require.NoError(t, os.Truncate(path, 0))
+	time.Sleep(50 * time.Millisecond)
+	appendFile(t, path, deniedLine("a3"))
+	assert.Equal(t, "a3", receive(t, r).AuditID)
+
+	assert.Equal(t, uint64(2), r.Stats().Rotations)
+}
+
+func TestFileReader_MissingFile(t *testing.T) {
+	r := NewFileReader(filepath.Join(t.TempDir(), "missing.log"), FileReaderOptions{})
+	assert.Error(t, r.Run(context.Background()))
+}
@@ -0,0 +1,30 @@
+package audit
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Label values of nightjar_audit_events_total.
+const (
+	sourceWebhook = "webhook"
+	sourceFile    = "file"
+	sourceRelay   = "relay"
+
+	resultDenied    = "denied"
+	resultIgnored   = "ignored"
+	resultInvalid   = "invalid"
+	resultDropped   = "dropped"
+	resultRelayed   = "relayed"
+	resultUnrelayed = "unrelayed"
+)
+
+// Audit metrics, served on the controller-runtime metrics endpoint.
+var eventsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "nightjar_audit_events_total",
+	Help: "Kubernetes audit events read, by source (webhook, file, relay) and result (denied, ignored, invalid, dropped, relayed to another replica, unrelayed because it was unreachable).",
+}, []string{"source", "result"})
+
+func init() {
+	ctrlmetrics.Registry.MustRegister(eventsTotal)
+}
@@ -0,0 +1,200 @@
+package audit
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/nightjarctl/nightjar/internal/shard"
+)
+
+// maxRequestBytes bounds the size of an audit webhook request body. The API
+// server batches up to 400 events per request by default.
+const maxRequestBytes = 16 << 20
+
+// DenialSource is a source of admission denials, e.g. a Receiver or a
+// FileReader.
+type DenialSource interface {
+	Denials() <-chan Denial
+}
+
+// ReceiverOptions configures a Receiver.
+type ReceiverOptions struct {
+	// Address is the address to listen on, e.g. ":8095"
+	Address string
+
+	// CertFile and KeyFile serve TLS. They are read once at startup.
+	// Required by Start.
+	CertFile string
+	KeyFile  string
+
+	// ClientCAFile requires the API server to present a client certificate
+	// signed by one of the CAs in it. Required by Start, so only the API
+	// server can post audit events.
+	ClientCAFile string
+
+	// Shards relays the denials of namespaces another replica owns to it,
+	// e.g. to the leader or the owning shard. Nil keeps every denial.
+	Shards *shard.Forwarder
+
+	// BufferSize is the size of the denial channel buffer
+	BufferSize int
+
+	// Logger for the receiver
+	Logger *zap.Logger
+}
+
+// DefaultReceiverOptions returns default options for the Receiver.
+func DefaultReceiverOptions() ReceiverOptions {
+	return ReceiverOptions{
+		Address:    ":8095",
+		BufferSize: 1000,
+		Logger:     zap.NewNop(),
+	}
+}
+
+// Receiver is an audit webhook backend: the API server posts batches of
+// audit events to it, and it emits the admission denials among them.
+//
+// The API server posts to any replica behind the Service, so every replica
+// runs a Receiver and relays the denials it does not own with
+// ReceiverOptions.Shards. A batch whose denials could not be relayed fails
+// with 503, and the API server retries it; denials already emitted are
+// deduplicated by the correlator. Denials are dropped when the buffer is
+// full; the request still succeeds, so the API server does not retry the
+// batch.
+type Receiver struct {
+	opts    ReceiverOptions
+	logger  *zap.Logger
+	denials chan Denial
+}
+
+// NewReceiver creates a Receiver. Call Start to listen, or mount it as an
+// http.Handler.
+func NewReceiver(opts ReceiverOptions) *Receiver {
+	if opts.Logger == nil {
+		opts.Logger = zap.NewNop()
+	}
+	if opts.Address == "" {
+		opts.Address = DefaultReceiverOptions().Address
+	}
+	if opts.BufferSize == 0 {
+		opts.BufferSize = DefaultReceiverOptions().BufferSize
+	}
+
+	return &Receiver{
+		opts:    opts,
+		logger:  opts.Logger.Named("audit-receiver"),
+		denials: make(chan Denial, opts.BufferSize),
+	}
+}
+
+// Denials returns the channel of admission denials. It is never closed, as
+// the handler may be serving a request when the server stops.
+func (r *Receiver) Denials() <-chan Denial {
+	return r.denials
+}
+
+// Start listens on the configured address and serves until ctx is
+// cancelled. It serves TLS only, and only to clients presenting a
+// certificate signed by ClientCAFile; a handler mounted elsewhere must
+// authenticate its callers itself.
+func (r *Receiver) Start(ctx context.Context) error {
+	if r.opts.CertFile == "" || r.opts.KeyFile == "" || r.opts.ClientCAFile == "" {
+		return errors.New("audit webhook requires a serving certificate, key and client CA")
+	}
+	pem, err := os.ReadFile(r.opts.ClientCAFile)
+	if err != nil {
+		return fmt.Errorf("reading client CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return fmt.Errorf("no certificates in client CA file %s", r.opts.ClientCAFile)
+	}
+	lis, err := net.Listen("tcp", r.opts.Address)
+	if err != nil {
+		return fmt.Errorf("listening on %s: %w", r.opts.Address, err)
+	}
+
+	srv := &http.Server{
+		Handler:           r,
+		ReadHeaderTimeout: 10 * time.Second,
+		TLSConfig: &tls.Config{
+			ClientAuth: tls.RequireAndVerifyClientCert,
+			ClientCAs:  pool,
+			MinVersion: tls.VersionTLS12,
+		},
+	}
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.ServeTLS(lis, r.opts.CertFile, r.opts.KeyFile) }()
+	r.logger.Info("Audit webhook listening", zap.String("address", lis.Addr().String()))
+
+	select {
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return srv.Shutdown(shutdownCtx)
+	case err := <-errCh:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	}
+}
+
+// ServeHTTP accepts an audit.k8s.io/v1 EventList, or a single Event.
+func (r *Receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, req.Body, maxRequestBytes))
+	if err != nil {
+		http.Error(w, "reading body: "+err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	events, err := decodeEvents(body)
+	if err != nil {
+		eventsTotal.WithLabelValues(sourceWebhook, resultInvalid).Inc()
+		http.Error(w, "decoding audit events: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var relayErr error
+	for _, e := range events {
+		d, ok := e.denial()
+		if !ok {
+			eventsTotal.WithLabelValues(sourceWebhook, resultIgnored).Inc()
+			continue
+		}
+		local, err := relay(req.Context(), r.opts.Shards, d)
+		switch {
+		case err != nil:
+			eventsTotal.WithLabelValues(sourceWebhook, resultUnrelayed).Inc()
+			r.logger.Warn("Failed to relay admission denial",
+				zap.String("audit_id", d.AuditID),
+				zap.Stringer("object", d.Object),
+				zap.Error(err))
+			relayErr = err
+		case !local:
+			eventsTotal.WithLabelValues(sourceWebhook, resultRelayed).Inc()
+		default:
+			offer(r.denials, d, sourceWebhook, r.logger)
+		}
+	}
+	if relayErr != nil {
+		http.Error(w, "relaying admission denials: "+relayErr.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,132 @@
+package audit
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/nightjarctl/nightjar/internal/shard"
+)
+
+const auditBatch = `{"kind":"EventList","apiVersion":"audit.k8s.io/v1","items":[
+{"kind":"Event","auditID":"a1","stage":"ResponseComplete","verb":"get","user":{"username":"jane"},
+ "objectRef":{"resource":"pods","namespace":"shop","name":"orders-0"},"responseStatus":{"code":200}},
+{"kind":"Event","auditID":"a2","stage":"ResponseComplete","verb":"create","user":{"username":"jane"},
+ "objectRef":{"resource":"pods","namespace":"shop","name":"orders-1"},
+ "responseStatus":{"code":403,"reason":"Forbidden","message":"pods \"orders-1\" is forbidden: exceeded quota: pods, requested: pods=1, used: pods=10, limited: pods=10"}}
+]}`
+
+func TestReceiver_ServeHTTP(t *testing.T) {
+	r := NewReceiver(ReceiverOptions{})
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL+"/audit", "application/json", strings.NewReader(auditBatch))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	select {
+	case d := <-r.Denials():
+		assert.Equal(t, "a2", d.AuditID)
+		assert.Equal(t, "jane", d.User)
+		assert.Equal(t, PluginResourceQuota, d.Plugin)
+		assert.Equal(t, []string{"pods"}, d.Policies)
+		assert.Equal(t, "Forbidden", d.Reason)
+	case <-time.After(2 * time.Second):
+		t.Fatal("denial was not received")
+	}
+	assert.Empty(t, r.Denials())
+}
+
+func TestReceiver_StartRequiresClientAuth(t *testing.T) {
+	r := NewReceiver(ReceiverOptions{Address: "127.0.0.1:0"})
+	assert.ErrorContains(t, r.Start(context.Background()), "requires a serving certificate, key and client CA")
+
+	r = NewReceiver(ReceiverOptions{Address: "127.0.0.1:0", CertFile: "tls.crt", KeyFile: "tls.key"})
+	assert.Error(t, r.Start(context.Background()), "TLS without a client CA")
+}
+
+func TestReceiver_ServeHTTP_Errors(t *testing.T) {
+	r := NewReceiver(ReceiverOptions{})
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+
+	resp, err = http.Post(srv.URL, "application/json", strings.NewReader(`{`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestReceiver_DropsWhenFull(t *testing.T) {
+	r := NewReceiver(ReceiverOptions{BufferSize: 1})
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	for i := 0; i < 3; i++ {
+		resp, err := http.Post(srv.URL, "application/json", strings.NewReader(auditBatch))
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+	assert.Len(t, r.Denials(), 1)
+}
+
+// ownerRouter assigns every namespace to owner.
+type ownerRouter struct{ self, owner shard.Member }
+
+func (r ownerRouter) Self() shard.Member                { return r.self }
+func (r ownerRouter) Owner(string) (shard.Member, bool) { return r.owner, r.owner.Identity != "" }
+func (r ownerRouter) Members() []shard.Member           { return []shard.Member{r.owner} }
+
+func TestReceiver_RelaysToOwner(t *testing.T) {
+	// The owner accepts relayed denials on its relay server.
+	relay := NewRelay(10, nil)
+	mux := http.NewServeMux()
+	mux.Handle(RelayPath, relay)
+	ownerSrv := httptest.NewServer(mux)
+	defer ownerSrv.Close()
+	host, port, err := net.SplitHostPort(ownerSrv.Listener.Addr().String())
+	require.NoError(t, err)
+	portNum, err := strconv.Atoi(port)
+	require.NoError(t, err)
+
+	router := ownerRouter{self: shard.Member{Identity: "standby"}, owner: shard.Member{Identity: "leader", Host: host}}
+	r := NewReceiver(ReceiverOptions{Shards: shard.NewForwarder(router, portNum, zap.NewNop())})
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL, "application/json", strings.NewReader(auditBatch))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Empty(t, r.Denials(), "the standby keeps nothing")
+	select {
+	case d := <-relay.Denials():
+		assert.Equal(t, "a2", d.AuditID)
+		assert.Equal(t, "shop", d.Object.Namespace)
+		assert.Equal(t, []string{"pods"}, d.Policies)
+	case <-time.After(2 * time.Second):
+		t.Fatal("denial was not relayed")
+	}
+
+	// Without an owner, the API server is asked to retry the batch.
+	r = NewReceiver(ReceiverOptions{Shards: shard.NewForwarder(ownerRouter{self: router.self}, portNum, zap.NewNop())})
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(auditBatch)))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
@@ -0,0 +1,89 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/nightjarctl/nightjar/internal/shard"
+)
+
+// RelayPath is the path on the relay server replicas relay admission
+// denials to.
+const RelayPath = "/api/v1/relay/admission-denials"
+
+// Relay is the DenialSource of the admission denials other replicas relay
+// to this one: a Receiver behind a Service hands the denials of namespaces
+// it does not own to their owner, and a FileReader on the leader hands them
+// to the owning shard. Mount it on the shard.RelayServer at RelayPath.
+//
+// Like the Receiver, it drops denials when the buffer is full.
+type Relay struct {
+	logger  *zap.Logger
+	denials chan Denial
+}
+
+// NewRelay creates a Relay buffering up to bufferSize denials.
+func NewRelay(bufferSize int, logger *zap.Logger) *Relay {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	if bufferSize == 0 {
+		bufferSize = DefaultReceiverOptions().BufferSize
+	}
+	return &Relay{
+		logger:  logger.Named("audit-relay"),
+		denials: make(chan Denial, bufferSize),
+	}
+}
+
+// Denials returns the channel of relayed admission denials. It is never
+// closed.
+func (r *Relay) Denials() <-chan Denial {
+	return r.denials
+}
+
+// ServeHTTP accepts a denial relayed by another replica.
+func (r *Relay) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var d Denial
+	if err := json.NewDecoder(io.LimitReader(req.Body, maxRequestBytes)).Decode(&d); err != nil {
+		eventsTotal.WithLabelValues(sourceRelay, resultInvalid).Inc()
+		http.Error(w, "decoding admission denial: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	offer(r.denials, d, sourceRelay, r.logger)
+	w.WriteHeader(http.StatusOK)
+}
+
+// relay hands d to the replica owning its namespace, if that is another
+// one, and reports whether this replica owns it.
+func relay(ctx context.Context, shards *shard.Forwarder, d Denial) (bool, error) {
+	if shards == nil {
+		return true, nil
+	}
+	body, err := json.Marshal(d)
+	if err != nil {
+		return false, err
+	}
+	return shards.Relay(ctx, RelayPath, body, d.Object.Namespace)
+}
+
+// offer emits d on denials, or drops it when the buffer is full.
+func offer(denials chan<- Denial, d Denial, source string, logger *zap.Logger) {
+	select {
+	case denials <- d:
+		eventsTotal.WithLabelValues(source, resultDenied).Inc()
+	default:
+		eventsTotal.WithLabelValues(source, resultDropped).Inc()
+		logger.Warn("Admission denial dropped, buffer full",
+			zap.String("audit_id", d.AuditID),
+			zap.Stringer("object", d.Object))
+	}
+}
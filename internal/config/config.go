@@ -0,0 +1,462 @@
+// Package config defines NightjarConfig, the versioned configuration file of
+// the controller.
+//
+// The file is usually mounted from a ConfigMap. Every setting has a default
+// and a command-line flag; flags given explicitly override the file. The
+// file is validated at startup and re-read while the controller runs: changes
+// to notification, annotation and report settings are applied in place, and
+// changes to anything else are reported as needing a restart.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/nightjarctl/nightjar/internal/types"
+)
+
+const (
+	// APIVersion is the only supported apiVersion of a NightjarConfig.
+	APIVersion = "nightjar.io/v1alpha1"
+
+	// Kind is the kind of a NightjarConfig.
+	Kind = "NightjarConfig"
+)
+
+// NightjarConfig configures every subsystem of the controller.
+type NightjarConfig struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+
+	Controller     ControllerConfig     `json:"controller"`
+	Discovery      DiscoveryConfig      `json:"discovery"`
+	NamespaceScope NamespaceScopeConfig `json:"namespaceScope"`
+	Sharding       ShardingConfig       `json:"sharding"`
+	IndexSnapshot  IndexSnapshotConfig  `json:"indexSnapshot"`
+	Dedupe         DedupeConfig         `json:"dedupe"`
+	AdapterPlugins AdapterPluginsConfig `json:"adapterPlugins"`
+	Istio          IstioConfig          `json:"istio"`
+	Hubble         HubbleConfig         `json:"hubble"`
+	AccessLog      AccessLogConfig      `json:"accessLog"`
+	Audit          AuditConfig          `json:"audit"`
+
+	// Notifications, Annotator and Reports are applied without a restart.
+	Notifications NotificationsConfig `json:"notifications"`
+	Annotator     AnnotatorConfig     `json:"annotator"`
+	Reports       ReportsConfig       `json:"reports"`
+}
+
+// ControllerConfig configures the controller manager.
+type ControllerConfig struct {
+	MetricsBindAddress     string `json:"metricsBindAddress"`
+	HealthProbeBindAddress string `json:"healthProbeBindAddress"`
+	LeaderElect            bool   `json:"leaderElect"`
+
+	// RelayBindAddress is the listen address of the internal API replicas
+	// relay audit events and access logs to. It only accepts requests
+	// authenticated as the controller's own ServiceAccount.
+	RelayBindAddress string `json:"relayBindAddress"`
+
+	// RelayTokenFile holds this replica's ServiceAccount token for the
+	// nightjar-relay audience, presented to other replicas' relay
+	// listeners.
+	RelayTokenFile string `json:"relayTokenFile"`
+}
+
+// DiscoveryConfig configures the discovery engine.
+type DiscoveryConfig struct {
+	RescanInterval         metav1.Duration `json:"rescanInterval"`
+	AdditionalPolicyGroups []string        `json:"additionalPolicyGroups,omitempty"`
+	AdditionalNameHints    []string        `json:"additionalNameHints,omitempty"`
+	CheckCRDAnnotations    bool            `json:"checkCRDAnnotations"`
+	Debounce               metav1.Duration `json:"debounce"`
+	ParseQPS               float64         `json:"parseQPS"`
+	ParseBurst             int             `json:"parseBurst"`
+	DryRun                 bool            `json:"dryRun"`
+}
+
+// NamespaceScopeConfig names the namespace scope ConfigMap.
+type NamespaceScopeConfig struct {
+	// ConfigMap is namespace/name. Empty puts every namespace in scope.
+	ConfigMap string `json:"configMap,omitempty"`
+}
+
+// ShardingConfig configures namespace sharding across replicas.
+type ShardingConfig struct {
+	Enabled        bool   `json:"enabled"`
+	Group          string `json:"group"`
+	LeaseNamespace string `json:"leaseNamespace"`
+}
+
+// IndexSnapshotConfig configures index snapshots. Set at most one of File
+// and ConfigMap.
+type IndexSnapshotConfig struct {
+	File      string          `json:"file,omitempty"`
+	ConfigMap string          `json:"configMap,omitempty"`
+	Interval  metav1.Duration `json:"interval"`
+}
+
+// DedupeConfig configures persistence of notification deduplication state.
+// Set at most one of File and ConfigMap.
+type DedupeConfig struct {
+	File      string          `json:"file,omitempty"`
+	ConfigMap string          `json:"configMap,omitempty"`
+	Interval  metav1.Duration `json:"interval"`
+}
+
+// AdapterPluginsConfig configures out-of-process adapter plugins.
+type AdapterPluginsConfig struct {
+	Sockets        []string        `json:"sockets,omitempty"`
+	Timeout        metav1.Duration `json:"timeout"`
+	HealthInterval metav1.Duration `json:"healthInterval"`
+}
+
+// IstioConfig configures the Istio adapter.
+type IstioConfig struct {
+	// MeshConfig is the namespace/name of the mesh ConfigMap. Empty disables
+	// outboundTrafficPolicy detection.
+	MeshConfig string `json:"meshConfig"`
+}
+
+// HubbleConfig configures Hubble flow observation.
+type HubbleConfig struct {
+	Enabled      bool                  `json:"enabled"`
+	RelayAddress string                `json:"relayAddress"`
+	TLS          HubbleTLSConfig       `json:"tls"`
+	FlowStats    HubbleFlowStatsConfig `json:"flowStats"`
+}
+
+// HubbleFlowStatsConfig configures the aggregation of correlated flow drops.
+type HubbleFlowStatsConfig struct {
+	// Window is the span of the rolling drop counts. Drop paths not seen
+	// within it expire.
+	Window metav1.Duration `json:"window"`
+
+	// MaxEntries bounds the number of drop paths held.
+	MaxEntries int `json:"maxEntries"`
+}
+
+// HubbleTLSConfig configures TLS to Hubble Relay. The files are re-read on
+// every reconnect, so rotated Secrets need no restart.
+type HubbleTLSConfig struct {
+	Enabled bool `json:"enabled"`
+
+	// CAFile is the PEM bundle that verifies the relay. Empty uses the
+	// system roots.
+	CAFile string `json:"caFile"`
+
+	// CertFile and KeyFile are the client certificate and key for mutual
+	// TLS. Set both or neither.
+	CertFile string `json:"certFile"`
+	KeyFile  string `json:"keyFile"`
+
+	// ServerName overrides the name verified in the relay's certificate.
+	ServerName string `json:"serverName"`
+}
+
+// AccessLogConfig configures L7 denial detection from Envoy access logs.
+// Denials are counted in the flow drop statistics configured under hubble.
+type AccessLogConfig struct {
+	// ALSAddress is the listen address of the Envoy gRPC Access Log
+	// Service, e.g. ":8094". Empty disables it.
+	ALSAddress string `json:"alsAddress"`
+
+	// File is a JSON access log file followed for new lines. Empty
+	// disables it.
+	File string `json:"file"`
+}
+
+// AuditConfig configures admission denial detection from the Kubernetes
+// audit log.
+type AuditConfig struct {
+	// WebhookAddress is the listen address of the audit webhook backend,
+	// e.g. ":8095". Empty disables it.
+	WebhookAddress string `json:"webhookAddress"`
+
+	// TLSCertFile and TLSKeyFile serve the webhook over TLS. Required with
+	// WebhookAddress.
+	TLSCertFile string `json:"tlsCertFile"`
+	TLSKeyFile  string `json:"tlsKeyFile"`
+
+	// TLSClientCAFile holds the CAs the API server's client certificate is
+	// verified against. Required with WebhookAddress, so only the API
+	// server can post audit events.
+	TLSClientCAFile string `json:"tlsClientCAFile"`
+
+	// File is a JSON audit log file followed for new events, reopened when
+	// rotated. Empty disables it.
+	File string `json:"file"`
+}
+
+// NotificationsConfig configures the Event dispatcher.
+type NotificationsConfig struct {
+	SuppressDuplicateMinutes int    `json:"suppressDuplicateMinutes"`
+	RateLimitPerMinute       int    `json:"rateLimitPerMinute"`
+	RemediationContact       string `json:"remediationContact"`
+}
+
+// AnnotatorConfig configures the workload annotator.
+type AnnotatorConfig struct {
+	Debounce metav1.Duration `json:"debounce"`
+
+	// Workers takes effect on restart.
+	Workers int `json:"workers"`
+}
+
+// ReportsConfig configures the ConstraintReport reconciler.
+type ReportsConfig struct {
+	Debounce           metav1.Duration   `json:"debounce"`
+	DefaultDetailLevel types.DetailLevel `json:"defaultDetailLevel"`
+	DefaultContact     string            `json:"defaultContact"`
+}
+
+// Default returns the configuration used when no file is given.
+func Default() NightjarConfig {
+	return NightjarConfig{
+		APIVersion: APIVersion,
+		Kind:       Kind,
+		Controller: ControllerConfig{
+			MetricsBindAddress:     ":8080",
+			HealthProbeBindAddress: ":8081",
+			RelayBindAddress:       ":8096",
+			RelayTokenFile:         "/var/run/secrets/nightjar/relay/token",
+			LeaderElect:            true,
+		},
+		Discovery: DiscoveryConfig{
+			RescanInterval:      metav1.Duration{Duration: 5 * time.Minute},
+			CheckCRDAnnotations: true,
+			ParseQPS:            20,
+			ParseBurst:          50,
+		},
+		Sharding: ShardingConfig{
+			Group:          "nightjar",
+			LeaseNamespace: "nightjar-system",
+		},
+		IndexSnapshot: IndexSnapshotConfig{
+			Interval: metav1.Duration{Duration: 5 * time.Minute},
+		},
+		Dedupe: DedupeConfig{
+			Interval: metav1.Duration{Duration: 30 * time.Second},
+		},
+		AdapterPlugins: AdapterPluginsConfig{
+			Timeout:        metav1.Duration{Duration: 5 * time.Second},
+			HealthInterval: metav1.Duration{Duration: 30 * time.Second},
+		},
+		Istio: IstioConfig{
+			MeshConfig: "istio-system/istio",
+		},
+		Hubble: HubbleConfig{
+			RelayAddress: "hubble-relay.kube-system.svc:4245",
+			FlowStats: HubbleFlowStatsConfig{
+				Window:     metav1.Duration{Duration: time.Hour},
+				MaxEntries: 10000,
+			},
+		},
+		Notifications: NotificationsConfig{
+			SuppressDuplicateMinutes: 60,
+			RateLimitPerMinute:       100,
+			RemediationContact:       "your platform team",
+		},
+		Annotator: AnnotatorConfig{
+			Debounce: metav1.Duration{Duration: 30 * time.Second},
+			Workers:  5,
+		},
+		Reports: ReportsConfig{
+			Debounce:           metav1.Duration{Duration: 10 * time.Second},
+			DefaultDetailLevel: types.DetailLevelSummary,
+			DefaultContact:     "your platform team",
+		},
+	}
+}
+
+// Parse decodes a NightjarConfig over the defaults, so omitted settings keep
+// their default. Unknown fields are rejected. The result is not validated.
+func Parse(data []byte) (NightjarConfig, error) {
+	cfg := Default()
+	cfg.APIVersion, cfg.Kind = "", ""
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return NightjarConfig{}, fmt.Errorf("invalid NightjarConfig: %w", err)
+	}
+	return cfg, nil
+}
+
+// Validate reports every invalid setting, each prefixed with its path in
+// the file, e.g. "discovery.parseQPS: must be greater than 0".
+func (c *NightjarConfig) Validate() error {
+	var errs []error
+	invalid := func(field, format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%s: %s", field, fmt.Sprintf(format, args...)))
+	}
+	positive := func(field string, d metav1.Duration) {
+		if d.Duration <= 0 {
+			invalid(field, "must be greater than 0, got %s", d.Duration)
+		}
+	}
+	namespacedName := func(field, value string) {
+		if value == "" {
+			return
+		}
+		namespace, name, ok := strings.Cut(value, "/")
+		if !ok || namespace == "" || name == "" || strings.Contains(name, "/") {
+			invalid(field, "expected namespace/name, got %q", value)
+		}
+	}
+
+	if c.APIVersion != APIVersion {
+		invalid("apiVersion", "unsupported version %q, expected %q", c.APIVersion, APIVersion)
+	}
+	if c.Kind != Kind {
+		invalid("kind", "expected %q, got %q", Kind, c.Kind)
+	}
+
+	if c.Controller.MetricsBindAddress == "" {
+		invalid("controller.metricsBindAddress", "must not be empty")
+	}
+	if c.Controller.HealthProbeBindAddress == "" {
+		invalid("controller.healthProbeBindAddress", "must not be empty")
+	}
+	if _, _, err := net.SplitHostPort(c.Controller.RelayBindAddress); err != nil {
+		invalid("controller.relayBindAddress", "expected host:port, got %q", c.Controller.RelayBindAddress)
+	}
+	if c.Controller.RelayTokenFile == "" {
+		invalid("controller.relayTokenFile", "must not be empty")
+	}
+
+	positive("discovery.rescanInterval", c.Discovery.RescanInterval)
+	if c.Discovery.Debounce.Duration < 0 {
+		invalid("discovery.debounce", "must not be negative, got %s", c.Discovery.Debounce.Duration)
+	}
+	if c.Discovery.ParseQPS <= 0 {
+		invalid("discovery.parseQPS", "must be greater than 0, got %g", c.Discovery.ParseQPS)
+	}
+	if c.Discovery.ParseBurst <= 0 {
+		invalid("discovery.parseBurst", "must be greater than 0, got %d", c.Discovery.ParseBurst)
+	}
+
+	namespacedName("namespaceScope.configMap", c.NamespaceScope.ConfigMap)
+
+	if c.Sharding.Enabled {
+		if c.Sharding.Group == "" {
+			invalid("sharding.group", "must not be empty when sharding is enabled")
+		}
+		if c.Sharding.LeaseNamespace == "" {
+			invalid("sharding.leaseNamespace", "must not be empty when sharding is enabled")
+		}
+		if c.IndexSnapshot.File != "" || c.IndexSnapshot.ConfigMap != "" {
+			invalid("indexSnapshot", "the index snapshot holds one replica's index and cannot be used with sharding")
+		}
+		if c.Dedupe.File != "" {
+			invalid("dedupe.file", "a file cannot be shared between shards; use configMap")
+		}
+	}
+
+	if c.IndexSnapshot.File != "" && c.IndexSnapshot.ConfigMap != "" {
+		invalid("indexSnapshot", "set at most one of file and configMap")
+	}
+	namespacedName("indexSnapshot.configMap", c.IndexSnapshot.ConfigMap)
+	positive("indexSnapshot.interval", c.IndexSnapshot.Interval)
+
+	if c.Dedupe.File != "" && c.Dedupe.ConfigMap != "" {
+		invalid("dedupe", "set at most one of file and configMap")
+	}
+	namespacedName("dedupe.configMap", c.Dedupe.ConfigMap)
+	positive("dedupe.interval", c.Dedupe.Interval)
+
+	positive("adapterPlugins.timeout", c.AdapterPlugins.Timeout)
+	positive("adapterPlugins.healthInterval", c.AdapterPlugins.HealthInterval)
+
+	namespacedName("istio.meshConfig", c.Istio.MeshConfig)
+
+	if c.Hubble.Enabled && c.Hubble.RelayAddress == "" {
+		invalid("hubble.relayAddress", "must not be empty when Hubble is enabled")
+	}
+	if tls := c.Hubble.TLS; tls.Enabled {
+		if (tls.CertFile == "") != (tls.KeyFile == "") {
+			invalid("hubble.tls", "set both certFile and keyFile, or neither")
+		}
+	} else if tls.CAFile != "" || tls.CertFile != "" || tls.KeyFile != "" || tls.ServerName != "" {
+		invalid("hubble.tls", "files and serverName are set but TLS is not enabled")
+	}
+	positive("hubble.flowStats.window", c.Hubble.FlowStats.Window)
+	if c.Hubble.FlowStats.MaxEntries <= 0 {
+		invalid("hubble.flowStats.maxEntries", "must be greater than 0, got %d", c.Hubble.FlowStats.MaxEntries)
+	}
+
+	if addr := c.AccessLog.ALSAddress; addr != "" {
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			invalid("accessLog.alsAddress", "expected host:port, got %q", addr)
+		}
+	}
+
+	if addr := c.Audit.WebhookAddress; addr != "" {
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			invalid("audit.webhookAddress", "expected host:port, got %q", addr)
+		}
+	}
+	if (c.Audit.TLSCertFile == "") != (c.Audit.TLSKeyFile == "") {
+		invalid("audit", "set both tlsCertFile and tlsKeyFile, or neither")
+	} else if c.Audit.WebhookAddress != "" && c.Audit.TLSCertFile == "" {
+		invalid("audit", "webhookAddress requires tlsCertFile, tlsKeyFile and tlsClientCAFile, so only the API server can post audit events")
+	} else if c.Audit.TLSCertFile != "" && c.Audit.WebhookAddress == "" {
+		invalid("audit", "tlsCertFile and tlsKeyFile are set but webhookAddress is not")
+	} else if c.Audit.TLSCertFile != "" && c.Audit.TLSClientCAFile == "" {
+		invalid("audit.tlsClientCAFile", "required with TLS, so only the API server can post audit events")
+	}
+	if c.Audit.TLSClientCAFile != "" && c.Audit.TLSCertFile == "" {
+		invalid("audit.tlsClientCAFile", "is set but TLS is not")
+	}
+
+	if c.Notifications.SuppressDuplicateMinutes < 0 {
+		invalid("notifications.suppressDuplicateMinutes", "must not be negative, got %d", c.Notifications.SuppressDuplicateMinutes)
+	}
+	if c.Notifications.RateLimitPerMinute <= 0 {
+		invalid("notifications.rateLimitPerMinute", "must be greater than 0, got %d", c.Notifications.RateLimitPerMinute)
+	}
+
+	positive("annotator.debounce", c.Annotator.Debounce)
+	if c.Annotator.Workers <= 0 {
+		invalid("annotator.workers", "must be greater than 0, got %d", c.Annotator.Workers)
+	}
+
+	positive("reports.debounce", c.Reports.Debounce)
+	switch c.Reports.DefaultDetailLevel {
+	case types.DetailLevelSummary, types.DetailLevelDetailed, types.DetailLevelFull:
+	default:
+		invalid("reports.defaultDetailLevel", "must be one of summary, detailed, full, got %q", c.Reports.DefaultDetailLevel)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid NightjarConfig: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// RestartRequired returns the top-level sections that differ between old
+// and new in settings that are only read at startup, e.g. "hubble".
+func RestartRequired(old, new NightjarConfig) []string {
+	old, new = withoutLiveSettings(old), withoutLiveSettings(new)
+	ov, nv := reflect.ValueOf(old), reflect.ValueOf(new)
+	var sections []string
+	for i := 0; i < ov.NumField(); i++ {
+		if !reflect.DeepEqual(ov.Field(i).Interface(), nv.Field(i).Interface()) {
+			name, _, _ := strings.Cut(ov.Type().Field(i).Tag.Get("json"), ",")
+			sections = append(sections, name)
+		}
+	}
+	return sections
+}
+
+// withoutLiveSettings clears the settings applied without a restart.
+func withoutLiveSettings(c NightjarConfig) NightjarConfig {
+	c.Notifications = NotificationsConfig{}
+	c.Annotator.Debounce = metav1.Duration{}
+	c.Reports = ReportsConfig{}
+	return c
+}
@@ -0,0 +1,143 @@
+package config
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nightjarctl/nightjar/internal/types"
+)
+
+func TestDefault_IsValid(t *testing.T) {
+	cfg := Default()
+	require.NoError(t, cfg.Validate())
+}
+
+func TestParse_OverDefaults(t *testing.T) {
+	data, err := os.ReadFile("testdata/nightjar-config.yaml")
+	require.NoError(t, err)
+	cfg, err := Parse(data)
+	require.NoError(t, err)
+	require.NoError(t, cfg.Validate())
+
+	assert.Equal(t, 10*time.Minute, cfg.Discovery.RescanInterval.Duration)
+	assert.Equal(t, []string{"policy.example.com"}, cfg.Discovery.AdditionalPolicyGroups)
+	assert.Equal(t, 50.0, cfg.Discovery.ParseQPS)
+	assert.Equal(t, "nightjar-system/nightjar-scope", cfg.NamespaceScope.ConfigMap)
+	assert.True(t, cfg.Hubble.Enabled)
+	assert.Equal(t, "#platform-oncall", cfg.Notifications.RemediationContact)
+	assert.Equal(t, time.Minute, cfg.Annotator.Debounce.Duration)
+	assert.Equal(t, types.DetailLevelDetailed, cfg.Reports.DefaultDetailLevel)
+
+	// Omitted settings keep their defaults.
+	defaults := Default()
+	assert.Equal(t, defaults.Discovery.ParseBurst, cfg.Discovery.ParseBurst)
+	assert.True(t, cfg.Discovery.CheckCRDAnnotations)
+	assert.Equal(t, defaults.Hubble.RelayAddress, cfg.Hubble.RelayAddress)
+	assert.Equal(t, defaults.Annotator.Workers, cfg.Annotator.Workers)
+}
+
+func TestParse_RejectsUnknownFields(t *testing.T) {
+	_, err := Parse([]byte("apiVersion: nightjar.io/v1alpha1\nkind: NightjarConfig\nhubble:\n  relayAdress: relay:4245\n"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "relayAdress")
+}
+
+func TestValidate_ReportsEveryError(t *testing.T) {
+	cfg, err := Parse([]byte(`
+apiVersion: nightjar.io/v1beta9
+kind: NightjarConfig
+controller:
+  relayBindAddress: "8096"
+discovery:
+  parseQPS: 0
+namespaceScope:
+  configMap: nightjar-scope
+indexSnapshot:
+  file: /var/lib/nightjar/index.snap
+dedupe:
+  file: /var/lib/nightjar/dedupe/dedupe.json
+  configMap: nightjar-dedupe
+sharding:
+  enabled: true
+hubble:
+  tls:
+    enabled: true
+    certFile: /var/run/nightjar/hubble/client/tls.crt
+  flowStats:
+    maxEntries: 0
+accessLog:
+  alsAddress: "8094"
+audit:
+  tlsKeyFile: /var/run/nightjar/audit/tls.key
+  tlsClientCAFile: /var/run/nightjar/audit-client-ca/ca.crt
+notifications:
+  rateLimitPerMinute: -1
+reports:
+  defaultDetailLevel: verbose
+`))
+	require.NoError(t, err)
+
+	err = cfg.Validate()
+	require.Error(t, err)
+	for _, want := range []string{
+		`apiVersion: unsupported version "nightjar.io/v1beta9"`,
+		`controller.relayBindAddress: expected host:port, got "8096"`,
+		"discovery.parseQPS: must be greater than 0",
+		`namespaceScope.configMap: expected namespace/name, got "nightjar-scope"`,
+		"indexSnapshot: the index snapshot holds one replica's index and cannot be used with sharding",
+		"dedupe.file: a file cannot be shared between shards; use configMap",
+		"dedupe: set at most one of file and configMap",
+		`dedupe.configMap: expected namespace/name, got "nightjar-dedupe"`,
+		"hubble.tls: set both certFile and keyFile, or neither",
+		"hubble.flowStats.maxEntries: must be greater than 0",
+		`accessLog.alsAddress: expected host:port, got "8094"`,
+		"audit: set both tlsCertFile and tlsKeyFile, or neither",
+		"audit.tlsClientCAFile: is set but TLS is not",
+		"notifications.rateLimitPerMinute: must be greater than 0",
+		`reports.defaultDetailLevel: must be one of summary, detailed, full, got "verbose"`,
+	} {
+		assert.Contains(t, err.Error(), want)
+	}
+}
+
+func TestValidate_AuditWebhookRequiresTLS(t *testing.T) {
+	cfg := Default()
+	cfg.Audit.WebhookAddress = ":8095"
+	assert.ErrorContains(t, cfg.Validate(), "audit: webhookAddress requires tlsCertFile, tlsKeyFile and tlsClientCAFile")
+}
+
+func TestValidate_AuditTLSRequiresClientCA(t *testing.T) {
+	cfg := Default()
+	cfg.Audit.WebhookAddress = ":8095"
+	cfg.Audit.TLSCertFile = "/var/run/nightjar/audit/tls.crt"
+	cfg.Audit.TLSKeyFile = "/var/run/nightjar/audit/tls.key"
+	assert.ErrorContains(t, cfg.Validate(), "audit.tlsClientCAFile: required with TLS")
+
+	cfg.Audit.TLSClientCAFile = "/var/run/nightjar/audit-client-ca/ca.crt"
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestValidate_RequiresKind(t *testing.T) {
+	cfg, err := Parse([]byte("apiVersion: nightjar.io/v1alpha1\n"))
+	require.NoError(t, err)
+	assert.ErrorContains(t, cfg.Validate(), `kind: expected "NightjarConfig"`)
+}
+
+func TestRestartRequired(t *testing.T) {
+	old := Default()
+
+	live := old
+	live.Notifications.RateLimitPerMinute = 10
+	live.Annotator.Debounce.Duration = time.Minute
+	live.Reports.DefaultContact = "#platform-oncall"
+	assert.Empty(t, RestartRequired(old, live))
+
+	restart := live
+	restart.Hubble.Enabled = true
+	restart.Annotator.Workers = 10
+	assert.Equal(t, []string{"hubble", "annotator"}, RestartRequired(old, restart))
+}
@@ -0,0 +1,176 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var reloads = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "nightjar_config_reloads_total",
+	Help: "Changes to the config file by result (applied, rejected).",
+}, []string{"result"})
+
+func init() {
+	ctrlmetrics.Registry.MustRegister(reloads)
+}
+
+// WatcherOptions configures a Watcher.
+type WatcherOptions struct {
+	// Interval is how often the file is checked for changes. A ConfigMap
+	// volume is updated by the kubelet within about a minute of the
+	// ConfigMap. Default: 10s.
+	Interval time.Duration
+
+	// Override is applied to every parsed file before it is validated,
+	// e.g. to let command-line flags take precedence. Optional.
+	Override func(*NightjarConfig)
+}
+
+// DefaultWatcherOptions returns sensible defaults.
+func DefaultWatcherOptions() WatcherOptions {
+	return WatcherOptions{
+		Interval: 10 * time.Second,
+	}
+}
+
+// Watcher holds the current NightjarConfig and re-reads its file for
+// changes. An invalid file is logged and ignored, so the last valid
+// configuration stays in effect.
+type Watcher struct {
+	path   string
+	logger *zap.Logger
+	opts   WatcherOptions
+
+	mu        sync.RWMutex
+	current   NightjarConfig
+	raw       []byte
+	listeners []func(NightjarConfig)
+}
+
+// NewWatcher loads the NightjarConfig at path. It returns an error if the
+// file cannot be read or is invalid.
+func NewWatcher(path string, logger *zap.Logger, opts WatcherOptions) (*Watcher, error) {
+	if opts.Interval == 0 {
+		opts.Interval = DefaultWatcherOptions().Interval
+	}
+	w := &Watcher{
+		path:   path,
+		logger: logger.Named("config"),
+		opts:   opts,
+	}
+	raw, cfg, err := w.read()
+	if err != nil {
+		return nil, err
+	}
+	w.raw, w.current = raw, cfg
+	return w, nil
+}
+
+// Current returns the configuration in effect.
+func (w *Watcher) Current() NightjarConfig {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.current
+}
+
+// OnChange registers fn to be called with the new configuration after a
+// valid change to the file. Register listeners before Start.
+func (w *Watcher) OnChange(fn func(NightjarConfig)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.listeners = append(w.listeners, fn)
+}
+
+// Start checks the file for changes every Interval. Blocks until ctx is
+// cancelled.
+func (w *Watcher) Start(ctx context.Context) error {
+	ticker := time.NewTicker(w.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			w.reload()
+		}
+	}
+}
+
+// reload re-reads the file and, if it changed and is valid, makes it the
+// current configuration and notifies listeners.
+func (w *Watcher) reload() {
+	data, err := os.ReadFile(w.path)
+	if err != nil {
+		w.logger.Warn("Failed to read config file", zap.String("path", w.path), zap.Error(err))
+		return
+	}
+	w.mu.RLock()
+	unchanged := bytes.Equal(data, w.raw)
+	w.mu.RUnlock()
+	if unchanged {
+		return
+	}
+
+	cfg, err := w.parse(data)
+	if err != nil {
+		reloads.WithLabelValues("rejected").Inc()
+		w.logger.Error("Ignoring invalid config change, keeping the current configuration",
+			zap.String("path", w.path), zap.Error(err))
+		// Remember the rejected file so the error is logged once per change.
+		w.mu.Lock()
+		w.raw = data
+		w.mu.Unlock()
+		return
+	}
+
+	w.mu.Lock()
+	old := w.current
+	w.raw, w.current = data, cfg
+	listeners := append([]func(NightjarConfig){}, w.listeners...)
+	w.mu.Unlock()
+
+	reloads.WithLabelValues("applied").Inc()
+	if sections := RestartRequired(old, cfg); len(sections) > 0 {
+		w.logger.Warn("Config changes take effect after a restart",
+			zap.String("path", w.path), zap.Strings("sections", sections))
+	}
+	w.logger.Info("Config reloaded", zap.String("path", w.path))
+	for _, fn := range listeners {
+		fn(cfg)
+	}
+}
+
+func (w *Watcher) read() ([]byte, NightjarConfig, error) {
+	data, err := os.ReadFile(w.path)
+	if err != nil {
+		return nil, NightjarConfig{}, fmt.Errorf("reading config: %w", err)
+	}
+	cfg, err := w.parse(data)
+	if err != nil {
+		return nil, NightjarConfig{}, err
+	}
+	return data, cfg, nil
+}
+
+// parse parses and validates data, applying the override in between.
+func (w *Watcher) parse(data []byte) (NightjarConfig, error) {
+	cfg, err := Parse(data)
+	if err != nil {
+		return NightjarConfig{}, fmt.Errorf("%s: %w", w.path, err)
+	}
+	if w.opts.Override != nil {
+		w.opts.Override(&cfg)
+	}
+	if err := cfg.Validate(); err != nil {
+		return NightjarConfig{}, fmt.Errorf("%s: %w", w.path, err)
+	}
+	return cfg, nil
+}
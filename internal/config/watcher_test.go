@@ -0,0 +1,60 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const minimalConfig = "apiVersion: nightjar.io/v1alpha1\nkind: NightjarConfig\n"
+
+func writeConfig(t *testing.T, path, data string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+}
+
+func TestNewWatcher_InvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, minimalConfig+"annotator:\n  workers: 0\n")
+
+	_, err := NewWatcher(path, zap.NewNop(), WatcherOptions{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "annotator.workers: must be greater than 0")
+
+	_, err = NewWatcher(filepath.Join(t.TempDir(), "missing.yaml"), zap.NewNop(), WatcherOptions{})
+	assert.Error(t, err)
+}
+
+func TestWatcher_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, minimalConfig)
+	w, err := NewWatcher(path, zap.NewNop(), WatcherOptions{
+		Override: func(c *NightjarConfig) { c.Annotator.Workers = 2 },
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, w.Current().Annotator.Workers, "override applies to the initial file")
+
+	var applied []NightjarConfig
+	w.OnChange(func(c NightjarConfig) { applied = append(applied, c) })
+
+	w.reload()
+	assert.Empty(t, applied, "unchanged file")
+
+	writeConfig(t, path, minimalConfig+"annotator:\n  debounce: 1m\n  workers: 8\n")
+	w.reload()
+	require.Len(t, applied, 1)
+	assert.Equal(t, time.Minute, applied[0].Annotator.Debounce.Duration)
+	assert.Equal(t, 2, applied[0].Annotator.Workers, "override applies to reloaded files")
+	assert.Equal(t, applied[0], w.Current())
+
+	// An invalid change keeps the current configuration.
+	writeConfig(t, path, minimalConfig+"reports:\n  debounce: -1s\n")
+	w.reload()
+	assert.Len(t, applied, 1)
+	assert.Equal(t, time.Minute, w.Current().Annotator.Debounce.Duration)
+}
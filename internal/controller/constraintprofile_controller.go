@@ -7,23 +7,32 @@ package controller
 
 import (
 	"context"
+	"time"
 
 	"go.uber.org/zap"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 
 	v1alpha1 "github.com/nightjarctl/nightjar/api/v1alpha1"
+	"github.com/nightjarctl/nightjar/internal/adapters/plugin"
 	"github.com/nightjarctl/nightjar/internal/discovery"
 )
 
+// pluginRetryInterval is how soon a profile is reconciled again when its
+// adapter plugin cannot be reached.
+const pluginRetryInterval = 30 * time.Second
+
 // ConstraintProfileReconciler reconciles ConstraintProfile resources.
 // On create/update, it registers the profile's GVR with the discovery engine.
 // On delete, it unregisters the profile and cleans up associated constraints.
+// Profiles that reference an adapter plugin acquire it from Plugins.
 type ConstraintProfileReconciler struct {
-	Client client.Client
-	Logger *zap.Logger
-	Engine *discovery.Engine
+	Client  client.Client
+	Logger  *zap.Logger
+	Engine  *discovery.Engine
+	Plugins *plugin.Manager
 }
 
 func (r *ConstraintProfileReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -35,11 +44,31 @@ func (r *ConstraintProfileReconciler) Reconcile(ctx context.Context, req ctrl.Re
 			// Profile deleted — unregister from engine
 			log.Info("ConstraintProfile deleted, unregistering")
 			r.Engine.UnregisterProfile(req.Name)
+			r.releasePlugin(req.Name)
 			return ctrl.Result{}, nil
 		}
 		return ctrl.Result{}, err
 	}
 
+	var result ctrl.Result
+	if profile.Spec.Plugin == nil {
+		r.releasePlugin(req.Name)
+	} else if r.Plugins == nil {
+		log.Warn("ConstraintProfile references an adapter plugin but plugins are not enabled",
+			zap.String("socket", profile.Spec.Plugin.Socket))
+	} else {
+		name, err := r.Plugins.Acquire(ctx, pluginOwner(req.Name), profile.Spec.Plugin.Socket)
+		if err != nil {
+			// The plugin stays pending and is registered once reachable;
+			// requeue so the profile picks up its adapter name.
+			log.Warn("Adapter plugin unavailable, will retry",
+				zap.String("socket", profile.Spec.Plugin.Socket), zap.Error(err))
+			result.RequeueAfter = pluginRetryInterval
+		} else if profile.Spec.Adapter == "" {
+			profile.Spec.Adapter = name
+		}
+	}
+
 	// Register or update the profile in the engine
 	log.Info("Reconciling ConstraintProfile",
 		zap.String("adapter", profile.Spec.Adapter),
@@ -53,12 +82,27 @@ func (r *ConstraintProfileReconciler) Reconcile(ctx context.Context, req ctrl.Re
 		return ctrl.Result{}, err
 	}
 
-	return ctrl.Result{}, nil
+	return result, nil
+}
+
+// releasePlugin drops the profile's hold on its adapter plugin, if any.
+func (r *ConstraintProfileReconciler) releasePlugin(profile string) {
+	if r.Plugins != nil {
+		r.Plugins.Release(pluginOwner(profile))
+	}
+}
+
+func pluginOwner(profile string) string {
+	return "profile/" + profile
 }
 
-// SetupWithManager registers the controller with the manager.
+// SetupWithManager registers the controller with the manager. It runs on
+// every replica, not just the leader: it only configures the local discovery
+// engine, and every replica serves reads from its own index.
 func (r *ConstraintProfileReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	needLeaderElection := false
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&v1alpha1.ConstraintProfile{}).
+		WithOptions(controller.Options{NeedLeaderElection: &needLeaderElection}).
 		Complete(r)
 }
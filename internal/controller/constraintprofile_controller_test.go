@@ -2,6 +2,8 @@ package controller
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -16,6 +18,7 @@ import (
 
 	v1alpha1 "github.com/nightjarctl/nightjar/api/v1alpha1"
 	"github.com/nightjarctl/nightjar/internal/adapters"
+	"github.com/nightjarctl/nightjar/internal/adapters/plugin"
 	"github.com/nightjarctl/nightjar/internal/discovery"
 	"github.com/nightjarctl/nightjar/internal/indexer"
 )
@@ -137,3 +140,43 @@ func TestReconcile_ProfileWithFieldPaths(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, ctrl.Result{}, result)
 }
+
+func TestReconcile_ProfileWithUnavailablePlugin(t *testing.T) {
+	dir, err := os.MkdirTemp("", "njp")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "missing.sock")
+
+	profile := &v1alpha1.ConstraintProfile{
+		ObjectMeta: metav1.ObjectMeta{Name: "plugin-profile"},
+		Spec: v1alpha1.ConstraintProfileSpec{
+			GVR: v1alpha1.GVRReference{
+				Group:    "example.nightjar.io",
+				Version:  "v1",
+				Resource: "egressallowlists",
+			},
+			Enabled: true,
+			Plugin:  &v1alpha1.PluginReference{Socket: socket},
+		},
+	}
+
+	r, _ := setupReconciler(t, profile)
+	r.Plugins = plugin.NewManager(adapters.NewRegistry(), zap.NewNop(),
+		plugin.ManagerOptions{CallTimeout: 200 * time.Millisecond})
+
+	req := ctrl.Request{NamespacedName: k8stypes.NamespacedName{Name: "plugin-profile"}}
+	result, err := r.Reconcile(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, pluginRetryInterval, result.RequeueAfter)
+
+	status := r.Plugins.Status()
+	require.Len(t, status, 1)
+	assert.Equal(t, socket, status[0].Socket)
+	assert.False(t, status[0].Healthy)
+
+	// Deleting the profile releases the pending plugin.
+	require.NoError(t, r.Client.Delete(context.Background(), profile))
+	_, err = r.Reconcile(context.Background(), req)
+	require.NoError(t, err)
+	assert.Empty(t, r.Plugins.Status())
+}
@@ -0,0 +1,80 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v1alpha1 "github.com/nightjarctl/nightjar/api/v1alpha1"
+	"github.com/nightjarctl/nightjar/internal/discovery"
+)
+
+// DiscoveryStatusName is the name of the singleton DiscoveryStatus.
+const DiscoveryStatusName = "cluster"
+
+// defaultDiscoveryStatusInterval is how often the DiscoveryStatus is refreshed
+// when Interval is unset.
+const defaultDiscoveryStatusInterval = 15 * time.Second
+
+// DiscoveryStatusPublisher mirrors the discovery engine's decisions and parse
+// errors into the cluster-scoped DiscoveryStatus resource. It is a runnable
+// rather than a reconciler: the engine, not the API server, is the source of
+// truth, so the resource is simply overwritten when the engine's view changes.
+// It runs on the leader only; with sharding, parse errors of objects in other
+// replicas' shards are not included.
+type DiscoveryStatusPublisher struct {
+	Client   client.Client
+	Logger   *zap.Logger
+	Engine   *discovery.Engine
+	Interval time.Duration
+}
+
+// Start publishes the status periodically. Blocks until ctx is cancelled.
+func (p *DiscoveryStatusPublisher) Start(ctx context.Context) error {
+	interval := p.Interval
+	if interval == 0 {
+		interval = defaultDiscoveryStatusInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := p.Publish(ctx); err != nil {
+				p.Logger.Warn("Failed to publish DiscoveryStatus", zap.Error(err))
+			}
+		}
+	}
+}
+
+// Publish creates or updates the DiscoveryStatus. Unchanged status is not
+// written.
+func (p *DiscoveryStatusPublisher) Publish(ctx context.Context) error {
+	status := p.Engine.Status()
+
+	var ds v1alpha1.DiscoveryStatus
+	err := p.Client.Get(ctx, client.ObjectKey{Name: DiscoveryStatusName}, &ds)
+	if apierrors.IsNotFound(err) {
+		ds = v1alpha1.DiscoveryStatus{ObjectMeta: metav1.ObjectMeta{Name: DiscoveryStatusName}}
+		if err := p.Client.Create(ctx, &ds); err != nil {
+			return err
+		}
+		p.Logger.Info("Created DiscoveryStatus", zap.String("name", DiscoveryStatusName))
+	} else if err != nil {
+		return err
+	}
+
+	if equality.Semantic.DeepEqual(ds.Status, status) {
+		return nil
+	}
+	ds.Status = status
+	return p.Client.Status().Update(ctx, &ds)
+}
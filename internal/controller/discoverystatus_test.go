@@ -0,0 +1,111 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	v1alpha1 "github.com/nightjarctl/nightjar/api/v1alpha1"
+	"github.com/nightjarctl/nightjar/internal/adapters"
+	"github.com/nightjarctl/nightjar/internal/discovery"
+	"github.com/nightjarctl/nightjar/internal/indexer"
+)
+
+func setupPublisher(t *testing.T) (*DiscoveryStatusPublisher, *discovery.Engine, client.Client) {
+	t.Helper()
+
+	s := runtime.NewScheme()
+	require.NoError(t, v1alpha1.AddToScheme(s))
+	c := fake.NewClientBuilder().
+		WithScheme(s).
+		WithStatusSubresource(&v1alpha1.DiscoveryStatus{}).
+		Build()
+
+	engine := discovery.NewEngine(zap.NewNop(), nil, nil, adapters.NewRegistry(), indexer.New(nil), 5*time.Minute)
+	p := &DiscoveryStatusPublisher{Client: c, Logger: zap.NewNop(), Engine: engine}
+	return p, engine, c
+}
+
+func profileFor(name, resource string, enabled bool) *v1alpha1.ConstraintProfile {
+	return &v1alpha1.ConstraintProfile{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: v1alpha1.ConstraintProfileSpec{
+			GVR:     v1alpha1.GVRReference{Group: "custom.io", Version: "v1", Resource: resource},
+			Adapter: "generic",
+			Enabled: enabled,
+		},
+	}
+}
+
+func TestDiscoveryStatusPublisher_CreatesAndUpdates(t *testing.T) {
+	p, engine, c := setupPublisher(t)
+	ctx := context.Background()
+
+	require.NoError(t, engine.RegisterProfile(profileFor("rules", "rules", true)))
+	require.NoError(t, p.Publish(ctx))
+
+	var ds v1alpha1.DiscoveryStatus
+	require.NoError(t, c.Get(ctx, client.ObjectKey{Name: DiscoveryStatusName}, &ds))
+	assert.Equal(t, 1, ds.Status.WatchedCount)
+	require.Len(t, ds.Status.Resources, 1)
+	assert.Equal(t, "rules", ds.Status.Resources[0].Resource)
+	assert.Equal(t, string(discovery.ReasonConstraintProfile), ds.Status.Resources[0].Reason)
+	assert.Equal(t, 100, ds.Status.Resources[0].Confidence)
+	assert.Contains(t, ds.Status.Resources[0].Message, "rules")
+
+	require.NoError(t, engine.RegisterProfile(profileFor("rules", "rules", false)))
+	require.NoError(t, p.Publish(ctx))
+
+	require.NoError(t, c.Get(ctx, client.ObjectKey{Name: DiscoveryStatusName}, &ds))
+	assert.Equal(t, 0, ds.Status.WatchedCount)
+	require.Len(t, ds.Status.Resources, 1)
+	assert.Equal(t, string(discovery.ReasonProfileDisabled), ds.Status.Resources[0].Reason)
+}
+
+func TestDiscoveryStatusPublisher_SkipsUnchanged(t *testing.T) {
+	p, engine, c := setupPublisher(t)
+	ctx := context.Background()
+
+	require.NoError(t, engine.RegisterProfile(profileFor("rules", "rules", true)))
+	require.NoError(t, p.Publish(ctx))
+
+	var before v1alpha1.DiscoveryStatus
+	require.NoError(t, c.Get(ctx, client.ObjectKey{Name: DiscoveryStatusName}, &before))
+
+	require.NoError(t, p.Publish(ctx))
+
+	var after v1alpha1.DiscoveryStatus
+	require.NoError(t, c.Get(ctx, client.ObjectKey{Name: DiscoveryStatusName}, &after))
+	assert.Equal(t, before.ResourceVersion, after.ResourceVersion, "unchanged status should not be written")
+}
+
+func TestDiscoveryStatusPublisher_StartStopsOnCancel(t *testing.T) {
+	p, engine, c := setupPublisher(t)
+	p.Interval = 10 * time.Millisecond
+	require.NoError(t, engine.RegisterProfile(profileFor("rules", "rules", true)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- p.Start(ctx) }()
+
+	require.Eventually(t, func() bool {
+		var ds v1alpha1.DiscoveryStatus
+		return c.Get(ctx, client.ObjectKey{Name: DiscoveryStatusName}, &ds) == nil && ds.Status.WatchedCount == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Start did not return after cancel")
+	}
+}
@@ -3,6 +3,8 @@ package correlator
 import (
 	"context"
 	"fmt"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
@@ -13,31 +15,50 @@ import (
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes"
 
+	"github.com/nightjarctl/nightjar/internal/adapters/istio"
+	"github.com/nightjarctl/nightjar/internal/audit"
+	"github.com/nightjarctl/nightjar/internal/dedupe"
+	"github.com/nightjarctl/nightjar/internal/flowstats"
 	"github.com/nightjarctl/nightjar/internal/hubble"
 	"github.com/nightjarctl/nightjar/internal/indexer"
+	"github.com/nightjarctl/nightjar/internal/scope"
+	"github.com/nightjarctl/nightjar/internal/servicemap"
 	"github.com/nightjarctl/nightjar/internal/types"
 	"github.com/nightjarctl/nightjar/internal/util"
+	"github.com/nightjarctl/nightjar/internal/workload"
 )
 
 const (
-	// Rate limit: 100 events/second
+	// Delivery rate limit: 100 notifications/second. Notifications beyond
+	// it wait in the notification queues.
 	eventRateLimit = 100
 	eventRateBurst = 200
 
 	// Deduplication window: 5 minutes
 	dedupeWindow = 5 * time.Minute
 
-	// Channel buffer size
-	notificationBuffer = 1000
+	// Channel buffer size. Kept small, so a backlog waits in the
+	// notification queues, which deliver by priority.
+	notificationBuffer = 100
 )
 
 // CorrelatedNotification pairs a Kubernetes event with a matching constraint.
 type CorrelatedNotification struct {
-	Event        *corev1.Event
-	Constraint   types.Constraint
-	Namespace    string
-	WorkloadName string
-	WorkloadKind string
+	Event      *corev1.Event
+	Constraint types.Constraint
+	Namespace  string
+
+	// The top-level workload of the event's involved object, e.g. the
+	// Deployment of a Pod, or the object itself without a workload resolver.
+	WorkloadName       string
+	WorkloadKind       string
+	WorkloadAPIVersion string
+	WorkloadUID        string
+
+	// Coalesced counts further notifications of the constraint in the
+	// namespace this one stands for, folded into it while the notification
+	// queue was full.
+	Coalesced int
 }
 
 // FlowDropNotification pairs a Hubble flow drop with a matching constraint.
@@ -45,21 +66,56 @@ type FlowDropNotification struct {
 	FlowDrop   hubble.FlowDrop
 	Constraint types.Constraint
 
-	// Source pod information
-	SourceNamespace string
-	SourcePodName   string
-	SourceWorkload  string
-	SourceLabels    map[string]string
+	// Source pod information. The workload is the pod's top-level
+	// workload, e.g. its Deployment rather than its ReplicaSet.
+	SourceNamespace    string
+	SourcePodName      string
+	SourceWorkload     string
+	SourceWorkloadKind string
+	SourceLabels       map[string]string
 
 	// Destination pod information
-	DestNamespace string
-	DestPodName   string
-	DestWorkload  string
-	DestLabels    map[string]string
+	DestNamespace    string
+	DestPodName      string
+	DestWorkload     string
+	DestWorkloadKind string
+	DestLabels       map[string]string
+
+	// DestServices are the Services the connection was addressed to, e.g.
+	// payments-api port 443. Empty without a service map or when the
+	// destination is not behind a Service.
+	DestServices []servicemap.ServiceInfo
 
 	// Connection information
 	DestPort uint32
 	Protocol string
+
+	// PolicyMatch is true when Hubble named the constraint's policy as the
+	// one that dropped the flow, and false when the constraint was matched
+	// by its workload selector.
+	PolicyMatch bool
+
+	// Coalesced counts further drops of the constraint in the namespace
+	// this one stands for ("and 312 more drops"), folded into it while the
+	// notification queue was full.
+	Coalesced int
+}
+
+// AdmissionDenialNotification pairs an admission denial read from the audit
+// log with a matching constraint.
+type AdmissionDenialNotification struct {
+	Denial     audit.Denial
+	Constraint types.Constraint
+
+	// PolicyMatch is true when the denial message named the constraint's
+	// policy or webhook, and false when the constraint was matched by the
+	// denying plugin and the target resource.
+	PolicyMatch bool
+
+	// Coalesced counts further denials of the constraint in the namespace
+	// this one stands for, folded into it while the notification queue was
+	// full.
+	Coalesced int
 }
 
 // dedupeKey uniquely identifies an event-constraint pair.
@@ -68,24 +124,90 @@ type dedupeKey struct {
 	constraintUID string
 }
 
+// String encodes the key for a dedupe journal as constraintUID|eventUID.
+func (k dedupeKey) String() string {
+	return k.constraintUID + "|" + k.eventUID
+}
+
+// parseDedupeKey decodes a key encoded by String.
+func parseDedupeKey(s string) (dedupeKey, bool) {
+	constraintUID, eventUID, ok := strings.Cut(s, "|")
+	return dedupeKey{eventUID: eventUID, constraintUID: constraintUID}, ok
+}
+
 // Correlator watches Kubernetes Warning events and correlates them with constraints.
 type Correlator struct {
 	logger        *zap.Logger
 	client        kubernetes.Interface
 	indexer       *indexer.Indexer
-	hubbleClient  *hubble.Client
+	flowSource    hubble.FlowSource
+	accessLogs    []hubble.FlowSource
+	auditSources  []audit.DenialSource
+	serviceMap    *servicemap.ServiceMap
+	workloads     *workload.Resolver
+	flowStats     *flowstats.Store
+	nsScope       *scope.Scope
 	notifications chan CorrelatedNotification
 	flowDrops     chan FlowDropNotification
+	admission     chan AdmissionDenialNotification
+	events        *notificationQueue[CorrelatedNotification]
+	flowQueue     *notificationQueue[FlowDropNotification]
+	denials       *notificationQueue[AdmissionDenialNotification]
 	limiter       *rate.Limiter
+	replay        bool
 
 	mu        sync.Mutex
 	seenPairs map[dedupeKey]time.Time
+	journal   *dedupe.Journal
 }
 
 // CorrelatorOptions configures the Correlator.
 type CorrelatorOptions struct {
 	// HubbleClient is optional; if nil, Hubble flow correlation is disabled.
 	HubbleClient *hubble.Client
+
+	// FlowSource is optional; if set, flow drops are read from it instead of
+	// HubbleClient, e.g. from a hubble.Replayer.
+	FlowSource hubble.FlowSource
+
+	// AccessLogs are optional sources of L7 denials read from Envoy access
+	// logs, e.g. an accesslog.Server. They are correlated like flow drops.
+	AccessLogs []hubble.FlowSource
+
+	// AuditSources are optional sources of admission denials read from the
+	// Kubernetes audit log, e.g. an audit.Receiver. Rejected requests create
+	// no Events, so they are only seen here.
+	AuditSources []audit.DenialSource
+
+	// Replay correlates every drop of a recorded capture: rate limiting and
+	// deduplication are disabled, and flow drop notifications wait for the
+	// consumer instead of being dropped when the channel is full.
+	Replay bool
+
+	// Scope is optional; events and flow drops in namespaces outside it are
+	// ignored. Nil correlates every namespace.
+	Scope *scope.Scope
+
+	// ServiceMap is optional; if set, flow drop notifications name the
+	// destination Services.
+	ServiceMap *servicemap.ServiceMap
+
+	// FlowStats is optional; if set, every correlated flow drop is counted
+	// in it, including drops that are rate limited or deduplicated.
+	FlowStats *flowstats.Store
+
+	// Workloads is optional; if set, events and flow drops are notified and
+	// deduplicated per top-level workload, e.g. the Deployment of a Pod,
+	// instead of per Pod.
+	Workloads *workload.Resolver
+
+	// DedupeStore is optional; if set, deduplication state is restored from
+	// it on Start and synced to it periodically, so a restart or a new
+	// leader does not notify recently notified pairs again.
+	DedupeStore dedupe.Store
+
+	// DedupeOptions configures syncing with DedupeStore.
+	DedupeOptions dedupe.Options
 }
 
 // New creates a new Correlator.
@@ -95,16 +217,48 @@ func New(idx *indexer.Indexer, client kubernetes.Interface, logger *zap.Logger)
 
 // NewWithOptions creates a new Correlator with options.
 func NewWithOptions(idx *indexer.Indexer, client kubernetes.Interface, logger *zap.Logger, opts CorrelatorOptions) *Correlator {
-	return &Correlator{
+	c := &Correlator{
 		logger:        logger.Named("correlator"),
 		client:        client,
 		indexer:       idx,
-		hubbleClient:  opts.HubbleClient,
+		flowSource:    opts.FlowSource,
+		accessLogs:    opts.AccessLogs,
+		auditSources:  opts.AuditSources,
+		serviceMap:    opts.ServiceMap,
+		workloads:     opts.Workloads,
+		flowStats:     opts.FlowStats,
+		nsScope:       opts.Scope,
 		notifications: make(chan CorrelatedNotification, notificationBuffer),
 		flowDrops:     make(chan FlowDropNotification, notificationBuffer),
+		admission:     make(chan AdmissionDenialNotification, notificationBuffer),
 		limiter:       rate.NewLimiter(eventRateLimit, eventRateBurst),
+		replay:        opts.Replay,
 		seenPairs:     make(map[dedupeKey]time.Time),
+		journal:       dedupe.NewJournal(opts.DedupeStore, "correlator", logger, opts.DedupeOptions),
+	}
+	// Assigned only when set, so a nil client leaves the interface nil.
+	if c.flowSource == nil && opts.HubbleClient != nil {
+		c.flowSource = opts.HubbleClient
 	}
+	if c.replay {
+		c.limiter = rate.NewLimiter(rate.Inf, 0)
+	}
+	c.events = newNotificationQueue(streamEvents, c.notifications, c.limiter,
+		func(n CorrelatedNotification, coalesced int) CorrelatedNotification {
+			n.Coalesced += coalesced
+			return n
+		})
+	c.flowQueue = newNotificationQueue(streamFlowDrops, c.flowDrops, c.limiter,
+		func(n FlowDropNotification, coalesced int) FlowDropNotification {
+			n.Coalesced += coalesced
+			return n
+		})
+	c.denials = newNotificationQueue(streamAdmissionDenials, c.admission, c.limiter,
+		func(n AdmissionDenialNotification, coalesced int) AdmissionDenialNotification {
+			n.Coalesced += coalesced
+			return n
+		})
+	return c
 }
 
 // Notifications returns the channel of correlated notifications.
@@ -117,25 +271,59 @@ func (c *Correlator) FlowDropNotifications() <-chan FlowDropNotification {
 	return c.flowDrops
 }
 
+// AdmissionDenialNotifications returns the channel of admission denial
+// notifications.
+func (c *Correlator) AdmissionDenialNotifications() <-chan AdmissionDenialNotification {
+	return c.admission
+}
+
 // Start begins watching events and correlating them. Blocks until context is cancelled.
 func (c *Correlator) Start(ctx context.Context) error {
 	c.logger.Info("Starting correlator")
 
-	// Start dedupe cleaner
+	// Restore pairs notified before a restart or failover, then start the
+	// dedupe cleaner and journal
+	c.restoreDedupe(ctx)
 	go c.cleanupDedupeCache(ctx)
+	go c.journal.Run(ctx, func() time.Duration { return dedupeWindow }, c.mergeSeen)
+
+	// Deliver queued notifications. The channels are closed once the
+	// queues have stopped sending.
+	var delivery sync.WaitGroup
+	for _, run := range []func(context.Context){c.events.Run, c.flowQueue.Run, c.denials.Run} {
+		delivery.Add(1)
+		go func() {
+			defer delivery.Done()
+			run(ctx)
+		}()
+	}
 
 	// Start Hubble flow processor if configured
-	if c.hubbleClient != nil {
+	if c.flowSource != nil {
 		go c.processFlowDrops(ctx)
 		c.logger.Info("Hubble flow correlation enabled")
 	}
+	for _, source := range c.accessLogs {
+		go c.consumeFlowDrops(ctx, source, "Access log")
+	}
+	if len(c.accessLogs) > 0 {
+		c.logger.Info("Access log denial correlation enabled", zap.Int("sources", len(c.accessLogs)))
+	}
+	for _, source := range c.auditSources {
+		go c.consumeAdmissionDenials(ctx, source)
+	}
+	if len(c.auditSources) > 0 {
+		c.logger.Info("Audit log admission denial correlation enabled", zap.Int("sources", len(c.auditSources)))
+	}
 
 	for {
 		if err := c.watchEvents(ctx); err != nil {
 			if ctx.Err() != nil {
 				c.logger.Info("Correlator stopped")
-				close(c.notifications)
-				close(c.flowDrops)
+				delivery.Wait()
+				c.events.close()
+				c.flowQueue.close()
+				c.denials.close()
 				return nil
 			}
 			c.logger.Error("Event watch failed, retrying", zap.Error(err))
@@ -171,9 +359,7 @@ func (c *Correlator) watchEvents(ctx context.Context) error {
 
 // handleEvent processes a single Kubernetes event.
 func (c *Correlator) handleEvent(ctx context.Context, event *corev1.Event) {
-	// Rate limit
-	if !c.limiter.Allow() {
-		c.logger.Debug("Event rate limited", zap.String("event", event.Name))
+	if !c.nsScope.Allows(event.InvolvedObject.Namespace) {
 		return
 	}
 
@@ -182,6 +368,12 @@ func (c *Correlator) handleEvent(ctx context.Context, event *corev1.Event) {
 	if ns == "" {
 		return // Skip cluster-scoped objects for now
 	}
+	owner := c.workloads.Resolve(ns, workload.Ref{
+		APIVersion: involved.APIVersion,
+		Kind:       involved.Kind,
+		Name:       involved.Name,
+		UID:        involved.UID,
+	})
 
 	// Query constraints for this namespace
 	constraints := c.indexer.ByNamespace(ns)
@@ -191,9 +383,10 @@ func (c *Correlator) handleEvent(ctx context.Context, event *corev1.Event) {
 
 	// Try to match each constraint
 	for _, constraint := range constraints {
-		// Atomic dedupe check-and-mark (avoids TOCTOU race between isDuplicate and markSeen)
+		// Atomic dedupe check-and-mark (avoids TOCTOU race between isDuplicate and markSeen).
+		// Events about the Pods of one workload are notified once.
 		key := dedupeKey{
-			eventUID:      string(event.UID),
+			eventUID:      fmt.Sprintf("workload:%s/%s/%s", ns, owner.Kind, owner.Name),
 			constraintUID: string(constraint.UID),
 		}
 		if !c.tryMarkSeen(key) {
@@ -203,23 +396,21 @@ func (c *Correlator) handleEvent(ctx context.Context, event *corev1.Event) {
 		// For now, emit all constraints in the namespace
 		// Future: add smarter matching based on event message, reason, etc.
 		notification := CorrelatedNotification{
-			Event:        event.DeepCopy(),
-			Constraint:   constraint,
-			Namespace:    ns,
-			WorkloadName: involved.Name,
-			WorkloadKind: involved.Kind,
+			Event:              event.DeepCopy(),
+			Constraint:         constraint,
+			Namespace:          ns,
+			WorkloadName:       owner.Name,
+			WorkloadKind:       owner.Kind,
+			WorkloadAPIVersion: owner.APIVersion,
+			WorkloadUID:        string(owner.UID),
 		}
 
-		select {
-		case c.notifications <- notification:
-			// Already marked seen by tryMarkSeen above.
-		case <-ctx.Done():
+		// Already marked seen by tryMarkSeen above. The queue delivers by
+		// severity and coalesces what does not fit, instead of dropping.
+		if ctx.Err() != nil {
 			return
-		default:
-			// Key is already marked seen — notification is intentionally dropped.
-			// This is preferable to a TOCTOU race that could send duplicates.
-			c.logger.Warn("Notification channel full, dropping event")
 		}
+		c.events.push(notification, ns, constraint)
 	}
 }
 
@@ -234,10 +425,42 @@ func (c *Correlator) tryMarkSeen(key dedupeKey) bool {
 			return false
 		}
 	}
-	c.seenPairs[key] = time.Now()
+	now := time.Now()
+	c.seenPairs[key] = now
+	c.journal.Record(key.String(), now)
 	return true
 }
 
+// restoreDedupe merges the pairs stored by the dedupe journal into the
+// dedupe cache. A failed restore is logged and the cache starts empty.
+func (c *Correlator) restoreDedupe(ctx context.Context) {
+	if c.journal == nil {
+		return
+	}
+	restoreCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	stored, err := c.journal.Sync(restoreCtx, dedupeWindow)
+	if err != nil {
+		c.logger.Warn("Failed to restore dedupe state, duplicates may be notified", zap.Error(err))
+		return
+	}
+	c.mergeSeen(stored)
+	c.logger.Info("Restored dedupe state", zap.Int("pairs", len(stored)))
+}
+
+// mergeSeen adds stored pairs to the dedupe cache, keeping the later time
+// of a pair in both.
+func (c *Correlator) mergeSeen(stored dedupe.Entries) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for s, seenAt := range stored {
+		key, ok := parseDedupeKey(s)
+		if ok && seenAt.After(c.seenPairs[key]) {
+			c.seenPairs[key] = seenAt
+		}
+	}
+}
+
 // cleanupDedupeCache periodically removes old entries from the dedupe cache.
 func (c *Correlator) cleanupDedupeCache(ctx context.Context) {
 	ticker := time.NewTicker(time.Minute)
@@ -260,20 +483,34 @@ func (c *Correlator) cleanupDedupeCache(ctx context.Context) {
 	}
 }
 
-// processFlowDrops reads from the Hubble client and correlates flow drops with constraints.
+// CorrelateFlows correlates flow drops from the flow source without watching
+// events, and blocks until the source is exhausted or ctx is cancelled. It
+// then closes the flow drop notification channel. Use it instead of Start to
+// analyze a recorded capture.
+func (c *Correlator) CorrelateFlows(ctx context.Context) {
+	defer close(c.flowDrops)
+	c.processFlowDrops(ctx)
+}
+
+// processFlowDrops reads from the flow source and correlates flow drops with constraints.
 func (c *Correlator) processFlowDrops(ctx context.Context) {
-	if c.hubbleClient == nil {
+	if c.flowSource == nil {
 		return
 	}
+	c.consumeFlowDrops(ctx, c.flowSource, "Hubble flow")
+}
 
-	drops := c.hubbleClient.DroppedFlows()
+// consumeFlowDrops correlates the drops of source until its channel is
+// closed or ctx is cancelled. name identifies the source in logs.
+func (c *Correlator) consumeFlowDrops(ctx context.Context, source hubble.FlowSource, name string) {
+	drops := source.DroppedFlows()
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		case drop, ok := <-drops:
 			if !ok {
-				c.logger.Info("Hubble flow channel closed")
+				c.logger.Info(name + " channel closed")
 				return
 			}
 			c.handleFlowDrop(ctx, drop)
@@ -288,70 +525,179 @@ func (c *Correlator) handleFlowDrop(ctx context.Context, drop hubble.FlowDrop) {
 		return
 	}
 
-	// Rate limit
-	if !c.limiter.Allow() {
-		c.logger.Debug("Flow drop rate limited",
-			zap.String("source", drop.Source.PodName),
-			zap.String("dest", drop.Destination.PodName))
-		return
+	// A drop is enforced by the source's egress or the destination's
+	// ingress policies. When Hubble reports the direction, only that
+	// endpoint is correlated; otherwise both are, once per namespace.
+	endpoints := []hubble.Endpoint{drop.Source, drop.Destination}
+	switch drop.Direction {
+	case hubble.DirectionEgress:
+		endpoints = endpoints[:1]
+	case hubble.DirectionIngress:
+		endpoints = endpoints[1:]
 	}
 
-	// Try to correlate with both source and destination namespaces
-	namespaces := []string{}
-	if drop.Source.Namespace != "" {
-		namespaces = append(namespaces, drop.Source.Namespace)
+	services := c.destinationServices(drop)
+	owners := flowOwners{
+		source: c.endpointWorkload(drop.Source),
+		dest:   c.endpointWorkload(drop.Destination),
 	}
-	if drop.Destination.Namespace != "" && drop.Destination.Namespace != drop.Source.Namespace {
-		namespaces = append(namespaces, drop.Destination.Namespace)
+	// Report the resolved workloads first, so the flow statistics and
+	// consumers of FlowDrop see the top-level workload too.
+	drop.Source = withWorkload(drop.Source, owners.source)
+	drop.Destination = withWorkload(drop.Destination, owners.dest)
+	seen := make(map[string]bool, len(endpoints))
+	for _, ep := range endpoints {
+		if ep.Namespace == "" || seen[ep.Namespace] || !c.nsScope.Allows(ep.Namespace) {
+			continue
+		}
+		seen[ep.Namespace] = true
+		c.correlateFlowDropInNamespace(ctx, drop, ep.Namespace, ep.Labels, services, owners)
 	}
+}
 
-	if len(namespaces) == 0 {
-		return
+// flowOwners are the top-level workloads of a flow's endpoints.
+type flowOwners struct {
+	source workload.Ref
+	dest   workload.Ref
+}
+
+// endpointWorkload returns the top-level workload of a flow endpoint: its
+// pod's owner when the resolver knows the pod, or else the first workload
+// Hubble reports, resolved in turn. It is empty for endpoints without
+// either, e.g. world or host.
+func (c *Correlator) endpointWorkload(ep hubble.Endpoint) workload.Ref {
+	if ep.Namespace == "" {
+		return workload.Ref{}
+	}
+	if ep.PodName != "" {
+		if owner := c.workloads.ResolvePod(ep.Namespace, ep.PodName); owner.Kind != "Pod" || len(ep.Workloads) == 0 {
+			return owner
+		}
 	}
+	if len(ep.Workloads) == 0 {
+		return workload.Ref{}
+	}
+	w := ep.Workloads[0]
+	return c.workloads.Resolve(ep.Namespace, workload.Ref{Kind: w.Kind, Name: w.Name})
+}
 
-	for _, ns := range namespaces {
-		c.correlateFlowDropInNamespace(ctx, drop, ns)
+// withWorkload returns ep with owner as its first workload. The endpoint's
+// workloads are copied, as the drop may be shared with other consumers.
+func withWorkload(ep hubble.Endpoint, owner workload.Ref) hubble.Endpoint {
+	if owner.Name == "" || owner.Kind == "Pod" {
+		return ep
 	}
+	ref := hubble.WorkloadRef{Kind: owner.Kind, Name: owner.Name}
+	if len(ep.Workloads) > 0 && ep.Workloads[0] == ref {
+		return ep
+	}
+	workloads := make([]hubble.WorkloadRef, 0, len(ep.Workloads)+1)
+	workloads = append(workloads, ref)
+	for _, w := range ep.Workloads {
+		if w != ref {
+			workloads = append(workloads, w)
+		}
+	}
+	ep.Workloads = workloads
+	return ep
 }
 
-// correlateFlowDropInNamespace correlates a flow drop with constraints in a specific namespace.
-func (c *Correlator) correlateFlowDropInNamespace(ctx context.Context, drop hubble.FlowDrop, namespace string) {
+// requestPath returns path without its query and fragment.
+func requestPath(path string) string {
+	if i := strings.IndexAny(path, "?#"); i >= 0 {
+		return path[:i]
+	}
+	return path
+}
+
+// flowEndpointKey identifies a flow endpoint for deduplication: by its
+// top-level workload when known, so the drops of every Pod of a Deployment
+// are notified once, and by its pod otherwise.
+func flowEndpointKey(ep hubble.Endpoint, owner workload.Ref) string {
+	if owner.Name == "" || owner.Kind == "Pod" {
+		return ep.PodName
+	}
+	return ep.Namespace + "/" + owner.Kind + "/" + owner.Name
+}
+
+// destinationServices returns the Services a dropped connection was
+// addressed to. The destination IP is the ClusterIP if the drop happened
+// before load-balancing and an endpoint IP after it; if neither resolves,
+// the Services selecting the destination pod on the port are used.
+func (c *Correlator) destinationServices(drop hubble.FlowDrop) []servicemap.ServiceInfo {
+	port := int32(drop.L4.DestinationPort)
+	if c.serviceMap == nil || port == 0 {
+		return nil
+	}
+	if drop.IP.Destination != "" {
+		if info := c.serviceMap.ResolvePort(drop.IP.Destination, port); info != nil {
+			return []servicemap.ServiceInfo{*info}
+		}
+	}
+	if drop.Destination.Namespace == "" {
+		return nil
+	}
+	var result []servicemap.ServiceInfo
+	for _, info := range c.serviceMap.ServicesForPod(drop.Destination.Namespace, drop.Destination.Labels) {
+		if info.Port == port || info.TargetPort == port {
+			result = append(result, *info)
+		}
+	}
+	return result
+}
+
+// correlateFlowDropInNamespace correlates a flow drop with constraints in a
+// specific namespace, matching selectors against the endpoint labels there.
+// Matches are counted in the flow statistics and notified.
+func (c *Correlator) correlateFlowDropInNamespace(ctx context.Context, drop hubble.FlowDrop, namespace string, matchLabels map[string]string, services []servicemap.ServiceInfo, owners flowOwners) {
 	// Query constraints for this namespace
 	constraints := c.indexer.ByNamespace(namespace)
 	if len(constraints) == 0 {
 		return
 	}
 
-	// Determine which labels to match based on namespace
-	var matchLabels map[string]string
-	if namespace == drop.Source.Namespace {
-		matchLabels = drop.Source.Labels
-	} else {
-		matchLabels = drop.Destination.Labels
-	}
-
-	// Try to find matching network policy constraints
 	for _, constraint := range constraints {
-		// Only correlate with network-related constraints
-		if constraint.ConstraintType != types.ConstraintTypeNetworkIngress &&
-			constraint.ConstraintType != types.ConstraintTypeNetworkEgress {
+		// Only correlate with network constraints of the drop's direction,
+		// or for L7 denials with the policies a proxy enforces
+		if drop.L7 != nil {
+			if !matchesL7(constraint, drop.Direction) {
+				continue
+			}
+		} else if !matchesDirection(constraint.ConstraintType, drop.Direction) {
 			continue
 		}
 
-		// Check if the constraint's workload selector matches the pod
-		if !matchesSelector(constraint.WorkloadSelector, matchLabels) {
+		// Hubble names the policies whose deny rules dropped the flow. Use
+		// them when present, and the workload selector only for drops without
+		// policy metadata, e.g. by default deny.
+		if len(drop.DeniedBy) > 0 {
+			if !deniedBy(constraint, drop.DeniedBy) {
+				continue
+			}
+		} else if !matchesSelector(constraint.WorkloadSelector, matchLabels) {
 			continue
 		}
 
+		if c.flowStats != nil {
+			c.flowStats.Record(drop, constraint, services)
+		}
+
 		// Atomic dedupe check-and-mark using flow details as the key
 		flowKey := fmt.Sprintf("flow:%s:%s:%s:%d",
-			drop.Source.PodName, drop.Destination.PodName,
+			flowEndpointKey(drop.Source, owners.source), flowEndpointKey(drop.Destination, owners.dest),
 			drop.L4.Protocol, drop.L4.DestinationPort)
+		if drop.L7 != nil {
+			// L7 denials name no source pod; tell requests apart by the
+			// caller's namespace and the request instead. The query is left
+			// out, so a client retrying with a new cache buster or token
+			// does not grow the dedupe state with every request.
+			flowKey += fmt.Sprintf(":%s:%s:%s", drop.Source.Namespace, drop.L7.Method, requestPath(drop.L7.Path))
+		}
 		key := dedupeKey{
 			eventUID:      flowKey,
 			constraintUID: string(constraint.UID),
 		}
-		if !c.tryMarkSeen(key) {
+		if !c.replay && !c.tryMarkSeen(key) {
 			continue
 		}
 
@@ -365,29 +711,206 @@ func (c *Correlator) correlateFlowDropInNamespace(ctx context.Context, drop hubb
 			DestNamespace:   drop.Destination.Namespace,
 			DestPodName:     drop.Destination.PodName,
 			DestLabels:      drop.Destination.Labels,
+			DestServices:    services,
 			DestPort:        drop.L4.DestinationPort,
 			Protocol:        string(drop.L4.Protocol),
+			PolicyMatch:     len(drop.DeniedBy) > 0,
+
+			SourceWorkload:     owners.source.Name,
+			SourceWorkloadKind: owners.source.Kind,
+			DestWorkload:       owners.dest.Name,
+			DestWorkloadKind:   owners.dest.Kind,
 		}
 
-		// Extract workload names from workload refs
-		if len(drop.Source.Workloads) > 0 {
-			notification.SourceWorkload = drop.Source.Workloads[0].Name
+		if c.replay {
+			select {
+			case c.flowDrops <- notification:
+			case <-ctx.Done():
+				return
+			}
+			continue
 		}
-		if len(drop.Destination.Workloads) > 0 {
-			notification.DestWorkload = drop.Destination.Workloads[0].Name
+
+		// Already marked seen by tryMarkSeen above.
+		if ctx.Err() != nil {
+			return
 		}
+		c.flowQueue.push(notification, namespace, constraint)
+	}
+}
 
+// consumeAdmissionDenials correlates the denials of source until its channel
+// is closed or ctx is cancelled.
+func (c *Correlator) consumeAdmissionDenials(ctx context.Context, source audit.DenialSource) {
+	denials := source.Denials()
+	for {
 		select {
-		case c.flowDrops <- notification:
-			// Already marked seen by tryMarkSeen above.
 		case <-ctx.Done():
 			return
-		default:
-			// Key is already marked seen — notification is intentionally dropped.
-			// This is preferable to a TOCTOU race that could send duplicates.
-			c.logger.Warn("Flow drop notification channel full")
+		case d, ok := <-denials:
+			if !ok {
+				c.logger.Info("Audit log channel closed")
+				return
+			}
+			c.handleAdmissionDenial(ctx, d)
+		}
+	}
+}
+
+// handleAdmissionDenial correlates an admission denial with the constraints
+// of the target object's namespace, including cluster-scoped ones. The
+// policies or webhook named in the message are matched when indexed;
+// otherwise constraints of the denying plugin that target the resource are.
+func (c *Correlator) handleAdmissionDenial(ctx context.Context, d audit.Denial) {
+	if !c.nsScope.Allows(d.Object.Namespace) {
+		return
+	}
+
+	constraints := c.indexer.ByNamespace(d.Object.Namespace)
+	matched, policyMatch := namedConstraints(constraints, d), true
+	if len(matched) == 0 {
+		matched, policyMatch = pluginConstraints(constraints, d), false
+	}
+
+	for _, constraint := range matched {
+		key := dedupeKey{
+			eventUID:      fmt.Sprintf("audit:%s:%s:%s", d.User, d.Verb, d.Object),
+			constraintUID: string(constraint.UID),
+		}
+		if !c.tryMarkSeen(key) {
+			continue
+		}
+
+		notification := AdmissionDenialNotification{
+			Denial:      d,
+			Constraint:  constraint,
+			PolicyMatch: policyMatch,
+		}
+		// Already marked seen by tryMarkSeen above.
+		if ctx.Err() != nil {
+			return
+		}
+		c.denials.push(notification, d.Object.Namespace, constraint)
+	}
+}
+
+// namedConstraints returns the constraints the denial message names: by
+// policy, Gatekeeper constraint, Kyverno policy/rule or quota name, or by
+// the name of the denying webhook.
+func namedConstraints(constraints []types.Constraint, d audit.Denial) []types.Constraint {
+	var result []types.Constraint
+	for _, c := range constraints {
+		if slices.Contains(d.Policies, c.Name) {
+			result = append(result, c)
+		}
+	}
+	if len(result) > 0 || d.Webhook == "" {
+		return result
+	}
+	for _, c := range constraints {
+		if name, _ := c.Details["webhookName"].(string); name == d.Webhook {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// pluginConstraints returns the constraints the denying plugin enforces on
+// the target resource: quotas for ResourceQuota, limit ranges for
+// LimitRanger, and admission constraints targeting the resource for
+// webhooks and ValidatingAdmissionPolicies.
+func pluginConstraints(constraints []types.Constraint, d audit.Denial) []types.Constraint {
+	var result []types.Constraint
+	for _, c := range constraints {
+		var ok bool
+		switch d.Plugin {
+		case audit.PluginResourceQuota:
+			ok = c.Source.Resource == "resourcequotas"
+		case audit.PluginLimitRanger:
+			ok = c.Source.Resource == "limitranges"
+		case audit.PluginWebhook, audit.PluginValidatingAdmissionPolicy:
+			ok = c.ConstraintType == types.ConstraintTypeAdmission && targetsResource(c.ResourceTargets, d.Object.Resource)
+		}
+		if ok {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// targetsResource reports whether targets cover resource. No targets
+// cover every resource.
+func targetsResource(targets []types.ResourceTarget, resource string) bool {
+	if len(targets) == 0 {
+		return true
+	}
+	for _, t := range targets {
+		for _, r := range t.Resources {
+			if r == "*" || strings.EqualFold(r, resource) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchesDirection reports whether a constraint of type ct can drop traffic
+// in the given direction. An unknown direction matches both network types.
+func matchesDirection(ct types.ConstraintType, direction hubble.TrafficDirection) bool {
+	switch ct {
+	case types.ConstraintTypeNetworkIngress:
+		return direction != hubble.DirectionEgress
+	case types.ConstraintTypeNetworkEgress:
+		return direction != hubble.DirectionIngress
+	default:
+		return false
+	}
+}
+
+// matchesL7 reports whether a constraint can deny requests at L7 in the
+// given direction: Istio AuthorizationPolicies and other mesh policies, and
+// network policies with L7 rules. ServiceEntries register hosts and never
+// deny a request, and the REGISTRY_ONLY outbound policy fails requests with
+// 502 rather than denying them.
+func matchesL7(c types.Constraint, direction hubble.TrafficDirection) bool {
+	switch {
+	case c.Source.Resource == authorizationPolicies:
+		return true
+	case c.ConstraintType == types.ConstraintTypeMeshPolicy:
+		return c.Source.Resource != "serviceentries" && !istio.IsOutboundPolicy(c.Tags)
+	}
+	if _, ok := c.Details["l7Types"]; !ok {
+		return false
+	}
+	return matchesDirection(c.ConstraintType, direction)
+}
+
+// authorizationPolicies is the resource of Istio AuthorizationPolicies,
+// which the generic adapter parses without a constraint type.
+const authorizationPolicies = "authorizationpolicies"
+
+// policyResources maps the policy kinds Hubble and Envoy access logs report
+// to the resources their constraints are parsed from.
+var policyResources = map[string]string{
+	"NetworkPolicy":                  "networkpolicies",
+	"CiliumNetworkPolicy":            "ciliumnetworkpolicies",
+	"CiliumClusterwideNetworkPolicy": "ciliumclusterwidenetworkpolicies",
+	"AuthorizationPolicy":            authorizationPolicies,
+}
+
+// deniedBy reports whether the constraint was parsed from one of the
+// policies. A policy of unreported kind matches on name and namespace.
+func deniedBy(constraint types.Constraint, policies []hubble.PolicyRef) bool {
+	for _, p := range policies {
+		if p.Name != constraint.Name || p.Namespace != constraint.Namespace {
+			continue
+		}
+		if resource, ok := policyResources[p.Kind]; ok && resource != constraint.Source.Resource {
+			continue
 		}
+		return true
 	}
+	return false
 }
 
 // matchesSelector checks if the given labels match the selector.
@@ -2,6 +2,7 @@ package correlator
 
 import (
 	"context"
+	"fmt"
 	"sync"
 	"testing"
 	"time"
@@ -10,15 +11,27 @@ import (
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes/fake"
+	metadatafake "k8s.io/client-go/metadata/fake"
 	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/utils/ptr"
 
+	"github.com/nightjarctl/nightjar/internal/adapters/istio"
+	"github.com/nightjarctl/nightjar/internal/audit"
+	"github.com/nightjarctl/nightjar/internal/dedupe"
+	"github.com/nightjarctl/nightjar/internal/flowstats"
 	"github.com/nightjarctl/nightjar/internal/hubble"
 	"github.com/nightjarctl/nightjar/internal/indexer"
+	"github.com/nightjarctl/nightjar/internal/scope"
+	"github.com/nightjarctl/nightjar/internal/servicemap"
 	internaltypes "github.com/nightjarctl/nightjar/internal/types"
+	"github.com/nightjarctl/nightjar/internal/workload"
 )
 
 func TestNew(t *testing.T) {
@@ -38,7 +51,7 @@ func TestNewWithOptions(t *testing.T) {
 	c := NewWithOptions(idx, nil, zap.NewNop(), opts)
 
 	require.NotNil(t, c)
-	assert.Nil(t, c.hubbleClient)
+	assert.Nil(t, c.flowSource)
 }
 
 func TestNotifications(t *testing.T) {
@@ -501,12 +514,12 @@ func TestHandleEvent_ChannelFull(t *testing.T) {
 	}
 	idx.Upsert(constraint)
 
-	// Fill the notification channel to capacity (notificationBuffer = 1000)
+	// Fill the notification channel to capacity
 	for i := 0; i < notificationBuffer; i++ {
 		c.notifications <- CorrelatedNotification{}
 	}
 
-	// Now handleEvent should hit the default branch (channel full) and not block
+	// Now handleEvent should queue the notification and not block
 	event := makeEvent("evt-overflow", "default", "my-pod", "Pod")
 
 	done := make(chan struct{})
@@ -519,8 +532,9 @@ func TestHandleEvent_ChannelFull(t *testing.T) {
 	case <-done:
 		// handleEvent returned without blocking -- correct behavior
 	case <-time.After(2 * time.Second):
-		t.Fatal("handleEvent blocked on full channel; should have hit the default branch")
+		t.Fatal("handleEvent blocked on full channel; should have queued the notification")
 	}
+	assert.Equal(t, 1, c.events.Len(), "the notification waits in the queue instead of being dropped")
 }
 
 func TestHandleEvent_ContextCanceled(t *testing.T) {
@@ -712,6 +726,33 @@ func TestHandleFlowDrop_EgressConstraint(t *testing.T) {
 	}
 }
 
+func TestHandleFlowDrop_NotAttributedToRegistryOnlyMesh(t *testing.T) {
+	idx := indexer.New(nil)
+	c := New(idx, nil, zap.NewNop())
+	idx.Upsert(istio.OutboundPolicyConstraint(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "istio", Namespace: "istio-system"},
+	}))
+
+	// Dropped by Cilium default deny: no policy is named, and the mesh-wide
+	// outbound policy must not be blamed.
+	c.handleFlowDrop(context.Background(), hubble.NewFlowDropBuilder().
+		WithSource("app-ns", "sender-pod", map[string]string{"app": "sender"}).
+		WithDestination("external-ns", "external-svc", nil).
+		WithTCP(12345, 443, hubble.TCPFlags{SYN: true}).
+		WithDropReason(hubble.DropReasonEgressDenied).
+		WithDirection(hubble.DirectionEgress).
+		Build())
+	c.handleFlowDrop(context.Background(), hubble.NewFlowDropBuilder().
+		WithSource("app-ns", "", nil).
+		WithDestination("production", "backend-0", nil).
+		WithDropReason(hubble.DropReasonPolicyL7).
+		WithDirection(hubble.DirectionEgress).
+		WithL7(hubble.L7Info{Method: "GET", Path: "/", ResponseCode: 403}).
+		Build())
+
+	assert.Empty(t, collectFlowDrops(c))
+}
+
 func TestCorrelateFlowDrop_MultipleConstraints(t *testing.T) {
 	idx := indexer.New(nil)
 	c := New(idx, nil, zap.NewNop())
@@ -987,10 +1028,11 @@ func TestHandleFlowDrop_RateLimited(t *testing.T) {
 
 	select {
 	case <-c.flowDrops:
-		t.Fatal("rate-limited flow drop should not produce notification")
+		t.Fatal("rate-limited flow drop should not be delivered yet")
 	case <-time.After(100 * time.Millisecond):
 		// Expected
 	}
+	assert.Equal(t, 1, c.flowQueue.Len(), "rate-limited flow drops wait in the queue instead of being dropped")
 }
 
 func TestHandleEvent_RateLimited(t *testing.T) {
@@ -1017,10 +1059,11 @@ func TestHandleEvent_RateLimited(t *testing.T) {
 
 	select {
 	case <-c.Notifications():
-		t.Fatal("rate-limited event should not produce notification")
+		t.Fatal("rate-limited event should not be delivered yet")
 	case <-time.After(100 * time.Millisecond):
 		// Expected
 	}
+	assert.Equal(t, 1, c.events.Len(), "rate-limited events wait in the queue instead of being dropped")
 }
 
 func TestHandleFlowDrop_ChannelFull(t *testing.T) {
@@ -1061,6 +1104,7 @@ func TestHandleFlowDrop_ChannelFull(t *testing.T) {
 	case <-time.After(2 * time.Second):
 		t.Fatal("handleFlowDrop blocked on full channel")
 	}
+	assert.Equal(t, 1, c.flowQueue.Len(), "the notification waits in the queue instead of being dropped")
 }
 
 func TestHandleFlowDrop_ContextCanceled(t *testing.T) {
@@ -1596,7 +1640,7 @@ func TestProcessFlowDrops_ContextCancelled(t *testing.T) {
 }
 
 func TestProcessFlowDrops_NilHubbleClient(t *testing.T) {
-	// processFlowDrops should return immediately when hubbleClient is nil.
+	// processFlowDrops should return immediately when no flow source is set.
 	idx := indexer.New(nil)
 	c := New(idx, nil, zap.NewNop())
 
@@ -1610,7 +1654,7 @@ func TestProcessFlowDrops_NilHubbleClient(t *testing.T) {
 	case <-done:
 		// Returned immediately.
 	case <-time.After(time.Second):
-		t.Fatal("processFlowDrops should return immediately when hubbleClient is nil")
+		t.Fatal("processFlowDrops should return immediately when no flow source is set")
 	}
 }
 
@@ -1779,3 +1823,782 @@ func TestCorrelator_TryMarkSeen_Concurrent(t *testing.T) {
 	}
 	assert.Equal(t, 1, trueCount, "exactly one goroutine should succeed for the same key")
 }
+
+func TestHandleEvent_OutOfScope(t *testing.T) {
+	idx := indexer.New(nil)
+	nsScope := scope.New(fake.NewSimpleClientset(), zap.NewNop(), scope.Options{})
+	require.NoError(t, nsScope.Update(scope.Config{ExcludeNamespaces: []string{"kube-*"}}))
+	c := NewWithOptions(idx, nil, zap.NewNop(), CorrelatorOptions{Scope: nsScope})
+
+	idx.Upsert(internaltypes.Constraint{UID: types.UID("c-1"), Name: "cluster-wide"})
+
+	c.handleEvent(context.Background(), makeEvent("evt-1", "kube-system", "coredns", "Pod"))
+	c.handleEvent(context.Background(), makeEvent("evt-2", "default", "my-pod", "Pod"))
+
+	select {
+	case notification := <-c.Notifications():
+		assert.Equal(t, "default", notification.Namespace)
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for notification")
+	}
+	assert.Empty(t, c.Notifications(), "kube-system event should be ignored")
+}
+
+func TestHandleFlowDrop_OutOfScope(t *testing.T) {
+	idx := indexer.New(nil)
+	nsScope := scope.New(fake.NewSimpleClientset(), zap.NewNop(), scope.Options{})
+	require.NoError(t, nsScope.Update(scope.Config{IncludeNamespaces: []string{"team-*"}}))
+	c := NewWithOptions(idx, nil, zap.NewNop(), CorrelatorOptions{Scope: nsScope})
+
+	for _, ns := range []string{"team-a", "production"} {
+		idx.Upsert(internaltypes.Constraint{
+			UID:            types.UID("deny-" + ns),
+			Name:           "deny-" + ns,
+			Namespace:      ns,
+			ConstraintType: internaltypes.ConstraintTypeNetworkIngress,
+		})
+	}
+
+	drop := hubble.NewFlowDropBuilder().
+		WithSource("team-a", "client", map[string]string{"app": "client"}).
+		WithDestination("production", "backend", map[string]string{"app": "backend"}).
+		WithTCP(45678, 8080, hubble.TCPFlags{SYN: true}).
+		WithDropReason(hubble.DropReasonPolicy).
+		Build()
+	c.handleFlowDrop(context.Background(), drop)
+
+	select {
+	case notification := <-c.flowDrops:
+		assert.Equal(t, "deny-team-a", notification.Constraint.Name)
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for flow drop notification")
+	}
+	assert.Empty(t, c.flowDrops, "the out-of-scope destination namespace should not be correlated")
+}
+
+// collectFlowDrops drains the flow drop notifications, keyed by constraint name.
+func collectFlowDrops(c *Correlator) map[string]FlowDropNotification {
+	received := map[string]FlowDropNotification{}
+	for {
+		select {
+		case n := <-c.flowDrops:
+			received[n.Constraint.Name] = n
+		case <-time.After(100 * time.Millisecond):
+			return received
+		}
+	}
+}
+
+func networkConstraint(uid, resource, namespace, name string, ct internaltypes.ConstraintType) internaltypes.Constraint {
+	return internaltypes.Constraint{
+		UID:            types.UID(uid),
+		Source:         schema.GroupVersionResource{Resource: resource},
+		Name:           name,
+		Namespace:      namespace,
+		ConstraintType: ct,
+		WorkloadSelector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{"app": "backend"},
+		},
+	}
+}
+
+func TestHandleFlowDrop_DeniedByNamesPolicy(t *testing.T) {
+	idx := indexer.New(nil)
+	c := New(idx, nil, zap.NewNop())
+
+	// Every constraint selects the destination; only the CNP dropped the flow.
+	idx.Upsert(networkConstraint("np-allow", "networkpolicies", "production", "allow-frontend", internaltypes.ConstraintTypeNetworkIngress))
+	idx.Upsert(networkConstraint("np-same-name", "networkpolicies", "production", "deny-external", internaltypes.ConstraintTypeNetworkIngress))
+	idx.Upsert(networkConstraint("cnp-ingress", "ciliumnetworkpolicies", "production", "deny-external", internaltypes.ConstraintTypeNetworkIngress))
+	idx.Upsert(networkConstraint("cnp-egress", "ciliumnetworkpolicies", "production", "deny-external-egress", internaltypes.ConstraintTypeNetworkEgress))
+	idx.Upsert(networkConstraint("ccnp", "ciliumclusterwidenetworkpolicies", "", "cluster-deny", internaltypes.ConstraintTypeNetworkIngress))
+
+	drop := hubble.NewFlowDropBuilder().
+		WithSource("external", "client", map[string]string{"app": "backend"}).
+		WithDestination("production", "backend-xyz", map[string]string{"app": "backend"}).
+		WithTCP(45678, 8080, hubble.TCPFlags{SYN: true}).
+		WithDropReason(hubble.DropReasonPolicy).
+		WithDirection(hubble.DirectionIngress).
+		WithDeniedBy("CiliumNetworkPolicy", "production", "deny-external").
+		WithDeniedBy("CiliumClusterwideNetworkPolicy", "", "cluster-deny").
+		Build()
+	c.handleFlowDrop(context.Background(), drop)
+
+	received := collectFlowDrops(c)
+	require.Len(t, received, 2)
+	assert.Equal(t, types.UID("cnp-ingress"), received["deny-external"].Constraint.UID)
+	assert.True(t, received["deny-external"].PolicyMatch)
+	assert.Contains(t, received, "cluster-deny")
+}
+
+func TestHandleFlowDrop_DeniedByUnknownKind(t *testing.T) {
+	idx := indexer.New(nil)
+	c := New(idx, nil, zap.NewNop())
+	idx.Upsert(networkConstraint("np", "networkpolicies", "production", "deny-external", internaltypes.ConstraintTypeNetworkIngress))
+	idx.Upsert(networkConstraint("other", "networkpolicies", "production", "allow-frontend", internaltypes.ConstraintTypeNetworkIngress))
+
+	drop := hubble.NewFlowDropBuilder().
+		WithSource("external", "client", nil).
+		WithDestination("production", "backend-xyz", map[string]string{"app": "backend"}).
+		WithDropReason(hubble.DropReasonPolicy).
+		WithDeniedBy("", "production", "deny-external").
+		Build()
+	c.handleFlowDrop(context.Background(), drop)
+
+	received := collectFlowDrops(c)
+	assert.Len(t, received, 1)
+	assert.Contains(t, received, "deny-external")
+}
+
+func TestHandleFlowDrop_DirectionWithoutPolicyMetadata(t *testing.T) {
+	tests := []struct {
+		name      string
+		direction hubble.TrafficDirection
+		want      []string
+	}{
+		{name: "ingress", direction: hubble.DirectionIngress, want: []string{"dst-ingress"}},
+		{name: "egress", direction: hubble.DirectionEgress, want: []string{"src-egress"}},
+		{name: "unknown", direction: hubble.DirectionUnknown, want: []string{"src-ingress", "src-egress", "dst-ingress", "dst-egress"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idx := indexer.New(nil)
+			c := New(idx, nil, zap.NewNop())
+			for _, ns := range []string{"src", "dst"} {
+				idx.Upsert(networkConstraint(ns+"-in", "networkpolicies", ns, ns+"-ingress", internaltypes.ConstraintTypeNetworkIngress))
+				idx.Upsert(networkConstraint(ns+"-out", "networkpolicies", ns, ns+"-egress", internaltypes.ConstraintTypeNetworkEgress))
+			}
+
+			drop := hubble.NewFlowDropBuilder().
+				WithSource("src", "client", map[string]string{"app": "backend"}).
+				WithDestination("dst", "server", map[string]string{"app": "backend"}).
+				WithTCP(45678, 8080, hubble.TCPFlags{SYN: true}).
+				WithDropReason(hubble.DropReasonPolicy).
+				WithDirection(tt.direction).
+				Build()
+			c.handleFlowDrop(context.Background(), drop)
+
+			received := collectFlowDrops(c)
+			assert.ElementsMatch(t, tt.want, keys(received))
+			for _, n := range received {
+				assert.False(t, n.PolicyMatch)
+			}
+		})
+	}
+}
+
+func TestHandleFlowDrop_IngressInSameNamespaceMatchesDestination(t *testing.T) {
+	idx := indexer.New(nil)
+	c := New(idx, nil, zap.NewNop())
+	idx.Upsert(networkConstraint("in", "networkpolicies", "production", "backend-ingress", internaltypes.ConstraintTypeNetworkIngress))
+
+	// The source does not carry the selected labels, the destination does.
+	drop := hubble.NewFlowDropBuilder().
+		WithSource("production", "frontend", map[string]string{"app": "frontend"}).
+		WithDestination("production", "backend", map[string]string{"app": "backend"}).
+		WithDropReason(hubble.DropReasonPolicy).
+		WithDirection(hubble.DirectionIngress).
+		Build()
+	c.handleFlowDrop(context.Background(), drop)
+
+	assert.Contains(t, collectFlowDrops(c), "backend-ingress")
+}
+
+func TestHandleFlowDrop_DestinationServices(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	sm := servicemap.New(client, zap.NewNop())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = sm.Start(ctx) }()
+	time.Sleep(100 * time.Millisecond)
+
+	_, err := client.CoreV1().Services("payments").Create(ctx, &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "payments-api", Namespace: "payments"},
+		Spec: corev1.ServiceSpec{
+			ClusterIP:  "10.96.0.10",
+			ClusterIPs: []string{"10.96.0.10", "fd00:96::10"},
+			Selector:   map[string]string{"app": "payments"},
+			Ports:      []corev1.ServicePort{{Name: "https", Port: 443, TargetPort: intstr.FromInt32(8443)}},
+		},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+	_, err = client.DiscoveryV1().EndpointSlices("payments").Create(ctx, &discoveryv1.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "payments-api-v6",
+			Namespace: "payments",
+			Labels:    map[string]string{discoveryv1.LabelServiceName: "payments-api"},
+		},
+		AddressType: discoveryv1.AddressTypeIPv6,
+		Endpoints:   []discoveryv1.Endpoint{{Addresses: []string{"fd00:244::7"}}},
+		Ports:       []discoveryv1.EndpointPort{{Name: ptr.To("https"), Port: ptr.To(int32(8443))}},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return sm.ResolvePort("fd00:244::7", 8443) != nil }, 5*time.Second, 10*time.Millisecond)
+
+	idx := indexer.New(nil)
+	c := NewWithOptions(idx, nil, zap.NewNop(), CorrelatorOptions{ServiceMap: sm})
+	idx.Upsert(internaltypes.Constraint{
+		UID:            types.UID("deny-payments"),
+		Name:           "deny-payments",
+		Namespace:      "payments",
+		ConstraintType: internaltypes.ConstraintTypeNetworkIngress,
+	})
+
+	tests := []struct {
+		name   string
+		destIP string
+		port   uint32
+		labels map[string]string
+	}{
+		{name: "ClusterIP", destIP: "fd00:96::10", port: 443},
+		{name: "endpoint", destIP: "fd00:244::7", port: 8443},
+		{name: "selected pod", destIP: "10.244.0.99", port: 8443, labels: map[string]string{"app": "payments"}},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			drop := hubble.NewFlowDropBuilder().
+				WithSource("shop", "checkout", nil).
+				WithDestination("payments", "payments-7d9f", tt.labels).
+				WithIP("fd00:244::1", tt.destIP).
+				WithTCP(uint32(40000+i), tt.port, hubble.TCPFlags{SYN: true}).
+				WithDropReason(hubble.DropReasonPolicy).
+				WithDirection(hubble.DirectionIngress).
+				Build()
+			drop.Source.PodName = tt.name // distinct dedupe keys
+			c.handleFlowDrop(ctx, drop)
+
+			select {
+			case n := <-c.flowDrops:
+				require.Len(t, n.DestServices, 1)
+				assert.Equal(t, "payments-api.payments:443", n.DestServices[0].String())
+			case <-time.After(time.Second):
+				t.Fatal("timeout waiting for flow drop notification")
+			}
+		})
+	}
+
+	// A port the Service does not expose resolves to no Service.
+	drop := hubble.NewFlowDropBuilder().
+		WithSource("shop", "checkout", nil).
+		WithDestination("payments", "payments-7d9f", map[string]string{"app": "payments"}).
+		WithIP("10.244.0.1", "10.244.0.99").
+		WithTCP(40100, 9090, hubble.TCPFlags{SYN: true}).
+		WithDropReason(hubble.DropReasonPolicy).
+		Build()
+	c.handleFlowDrop(ctx, drop)
+	select {
+	case n := <-c.flowDrops:
+		assert.Empty(t, n.DestServices)
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for flow drop notification")
+	}
+}
+
+// chanSource is a hubble.FlowSource backed by a channel.
+type chanSource chan hubble.FlowDrop
+
+func (s chanSource) DroppedFlows() <-chan hubble.FlowDrop { return s }
+
+func TestCorrelateFlows_Replay(t *testing.T) {
+	idx := indexer.New(nil)
+	idx.Upsert(networkConstraint("np-ingress", "networkpolicies", "production", "deny-frontend", internaltypes.ConstraintTypeNetworkIngress))
+
+	// More identical drops than the rate limiter's burst and the
+	// notification buffer; live correlation would rate limit and
+	// deduplicate all but the first.
+	const total = notificationBuffer + 500
+	src := make(chanSource, total)
+	drop := hubble.NewFlowDropBuilder().
+		WithSource("frontend", "web-0", map[string]string{"app": "web"}).
+		WithDestination("production", "backend-0", map[string]string{"app": "backend"}).
+		WithTCP(40000, 8080, hubble.TCPFlags{SYN: true}).
+		WithDropReason(hubble.DropReasonPolicy).
+		WithDirection(hubble.DirectionIngress).
+		Build()
+	for i := 0; i < total; i++ {
+		src <- drop
+	}
+	close(src)
+
+	c := NewWithOptions(idx, nil, zap.NewNop(), CorrelatorOptions{FlowSource: src, Replay: true})
+	go c.CorrelateFlows(context.Background())
+
+	// The replay only completes if sends wait for this consumer.
+	count := 0
+	for n := range c.FlowDropNotifications() {
+		assert.Equal(t, "deny-frontend", n.Constraint.Name)
+		count++
+	}
+	assert.Equal(t, total, count)
+}
+
+func TestNewWithOptions_FlowSource(t *testing.T) {
+	src := make(chanSource)
+	c := NewWithOptions(indexer.New(nil), nil, zap.NewNop(), CorrelatorOptions{FlowSource: src})
+	assert.Equal(t, hubble.FlowSource(src), c.flowSource)
+}
+
+func TestHandleFlowDrop_FlowStatsCountEveryDrop(t *testing.T) {
+	idx := indexer.New(nil)
+	idx.Upsert(networkConstraint("np-ingress", "networkpolicies", "production", "deny-frontend", internaltypes.ConstraintTypeNetworkIngress))
+	stats := flowstats.NewStore(flowstats.StoreOptions{})
+	c := NewWithOptions(idx, nil, zap.NewNop(), CorrelatorOptions{FlowStats: stats})
+
+	// Past the rate limiter's burst, and all but the first deduplicated.
+	const total = eventRateBurst + 50
+	drop := hubble.NewFlowDropBuilder().
+		WithSource("frontend", "web-0", map[string]string{"app": "web"}).
+		WithDestination("production", "backend-0", map[string]string{"app": "backend"}).
+		WithTCP(40000, 8080, hubble.TCPFlags{SYN: true}).
+		WithDropReason(hubble.DropReasonPolicy).
+		WithDirection(hubble.DirectionIngress).
+		Build()
+	for i := 0; i < total; i++ {
+		c.handleFlowDrop(context.Background(), drop)
+	}
+
+	assert.Len(t, collectFlowDrops(c), 1)
+	entries := stats.ForNamespace("production")
+	require.Len(t, entries, 1)
+	assert.Equal(t, uint64(total), entries[0].Count)
+	assert.Equal(t, "deny-frontend", entries[0].Policy.Name)
+}
+
+func TestHandleFlowDrop_L7Denial(t *testing.T) {
+	idx := indexer.New(nil)
+	c := New(idx, nil, zap.NewNop())
+
+	authz := networkConstraint("authz", "authorizationpolicies", "production", "allow-frontend", internaltypes.ConstraintTypeUnknown)
+	mesh := networkConstraint("mesh", "peerauthentications", "production", "strict-mtls", internaltypes.ConstraintTypeMeshPolicy)
+	serviceEntry := networkConstraint("se", "serviceentries", "production", "external-api", internaltypes.ConstraintTypeMeshPolicy)
+	cnpL7 := networkConstraint("cnp-l7", "ciliumnetworkpolicies", "production", "http-rules", internaltypes.ConstraintTypeNetworkIngress)
+	cnpL7.Details = map[string]interface{}{"l7Types": []string{"http"}}
+	cnpL7Egress := networkConstraint("cnp-l7-egress", "ciliumnetworkpolicies", "production", "http-egress", internaltypes.ConstraintTypeNetworkEgress)
+	cnpL7Egress.Details = map[string]interface{}{"l7Types": []string{"http"}}
+	for _, con := range []internaltypes.Constraint{
+		authz, mesh, serviceEntry, cnpL7, cnpL7Egress,
+		networkConstraint("np", "networkpolicies", "production", "l4-only", internaltypes.ConstraintTypeNetworkIngress),
+	} {
+		idx.Upsert(con)
+	}
+
+	drop := hubble.NewFlowDropBuilder().
+		WithSource("frontend", "", nil).
+		WithDestination("production", "backend-0", map[string]string{"app": "backend"}).
+		WithTCP(40000, 8080, hubble.TCPFlags{}).
+		WithDropReason(hubble.DropReasonPolicyL7).
+		WithDirection(hubble.DirectionIngress).
+		WithL7(hubble.L7Info{Method: "GET", Path: "/admin", ResponseCode: 403, Details: "rbac_access_denied_matched_policy[none]"}).
+		Build()
+	c.handleFlowDrop(context.Background(), drop)
+
+	received := collectFlowDrops(c)
+	assert.ElementsMatch(t, []string{"allow-frontend", "strict-mtls", "http-rules"}, keys(received))
+	assert.Equal(t, "/admin", received["allow-frontend"].FlowDrop.L7.Path)
+}
+
+func TestHandleFlowDrop_L7DeniedByAuthorizationPolicy(t *testing.T) {
+	idx := indexer.New(nil)
+	c := New(idx, nil, zap.NewNop())
+	idx.Upsert(networkConstraint("allow", "authorizationpolicies", "production", "allow-frontend", internaltypes.ConstraintTypeUnknown))
+	idx.Upsert(networkConstraint("deny", "authorizationpolicies", "production", "deny-admin", internaltypes.ConstraintTypeUnknown))
+
+	drop := hubble.NewFlowDropBuilder().
+		WithDestination("production", "backend-0", map[string]string{"app": "backend"}).
+		WithDropReason(hubble.DropReasonPolicyL7).
+		WithDirection(hubble.DirectionIngress).
+		WithDeniedBy("AuthorizationPolicy", "production", "deny-admin").
+		WithL7(hubble.L7Info{Method: "POST", Path: "/admin", ResponseCode: 403}).
+		Build()
+	c.handleFlowDrop(context.Background(), drop)
+
+	received := collectFlowDrops(c)
+	require.Len(t, received, 1)
+	assert.True(t, received["deny-admin"].PolicyMatch)
+}
+
+func TestHandleFlowDrop_L7DeduplicatesPerRequest(t *testing.T) {
+	idx := indexer.New(nil)
+	c := New(idx, nil, zap.NewNop())
+	idx.Upsert(networkConstraint("authz", "authorizationpolicies", "production", "allow-frontend", internaltypes.ConstraintTypeUnknown))
+
+	send := func(srcNamespace, path string) {
+		c.handleFlowDrop(context.Background(), hubble.NewFlowDropBuilder().
+			WithSource(srcNamespace, "", nil).
+			WithDestination("production", "backend-0", map[string]string{"app": "backend"}).
+			WithDropReason(hubble.DropReasonPolicyL7).
+			WithDirection(hubble.DirectionIngress).
+			WithL7(hubble.L7Info{Method: "GET", Path: path, ResponseCode: 403}).
+			Build())
+	}
+	send("frontend", "/admin")
+	send("frontend", "/admin")
+	send("frontend", "/admin?session=7f3a")
+	send("frontend", "/metrics")
+	send("batch", "/admin")
+
+	count := 0
+	for {
+		select {
+		case <-c.flowDrops:
+			count++
+			continue
+		case <-time.After(100 * time.Millisecond):
+		}
+		break
+	}
+	assert.Equal(t, 3, count)
+}
+
+func TestStart_CorrelatesAccessLogs(t *testing.T) {
+	idx := indexer.New(nil)
+	idx.Upsert(networkConstraint("authz", "authorizationpolicies", "production", "allow-frontend", internaltypes.ConstraintTypeUnknown))
+
+	src := make(chanSource, 1)
+	src <- hubble.NewFlowDropBuilder().
+		WithDestination("production", "backend-0", map[string]string{"app": "backend"}).
+		WithDropReason(hubble.DropReasonPolicyL7).
+		WithDirection(hubble.DirectionIngress).
+		WithL7(hubble.L7Info{Method: "GET", Path: "/", ResponseCode: 403}).
+		Build()
+
+	c := NewWithOptions(idx, fake.NewSimpleClientset(), zap.NewNop(), CorrelatorOptions{AccessLogs: []hubble.FlowSource{src}})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = c.Start(ctx) }()
+
+	select {
+	case n := <-c.FlowDropNotifications():
+		assert.Equal(t, "allow-frontend", n.Constraint.Name)
+	case <-time.After(2 * time.Second):
+		t.Fatal("access log denial was not correlated")
+	}
+}
+
+// collectAdmissionDenials drains the admission denial notifications, keyed by
+// constraint name.
+func collectAdmissionDenials(c *Correlator) map[string]AdmissionDenialNotification {
+	received := map[string]AdmissionDenialNotification{}
+	for {
+		select {
+		case n := <-c.admission:
+			received[n.Constraint.Name] = n
+		case <-time.After(100 * time.Millisecond):
+			return received
+		}
+	}
+}
+
+func admissionConstraint(uid, resource, namespace, name string, resources ...string) internaltypes.Constraint {
+	con := internaltypes.Constraint{
+		UID:            types.UID(uid),
+		Source:         schema.GroupVersionResource{Resource: resource},
+		Name:           name,
+		Namespace:      namespace,
+		ConstraintType: internaltypes.ConstraintTypeAdmission,
+	}
+	if len(resources) > 0 {
+		con.ResourceTargets = []internaltypes.ResourceTarget{{Resources: resources}}
+	}
+	return con
+}
+
+func TestHandleAdmissionDenial_NamedPolicies(t *testing.T) {
+	idx := indexer.New(nil)
+	c := New(idx, nil, zap.NewNop())
+	idx.Upsert(admissionConstraint("team", "k8srequiredlabels", "", "require-team", "deployments"))
+	idx.Upsert(admissionConstraint("other", "k8srequiredlabels", "", "require-owner", "deployments"))
+	idx.Upsert(admissionConstraint("kyverno", "clusterpolicies", "", "disallow-latest/validate-image-tag", "pods"))
+
+	c.handleAdmissionDenial(context.Background(), audit.Denial{
+		User:     "jane",
+		Verb:     "create",
+		Object:   audit.ObjectRef{APIGroup: "apps", Resource: "deployments", Namespace: "shop", Name: "orders"},
+		Plugin:   audit.PluginWebhook,
+		Webhook:  "validation.gatekeeper.sh",
+		Policies: []string{"require-team", "disallow-latest", "disallow-latest/validate-image-tag"},
+	})
+
+	received := collectAdmissionDenials(c)
+	assert.ElementsMatch(t, []string{"require-team", "disallow-latest/validate-image-tag"}, admissionKeys(received))
+	assert.True(t, received["require-team"].PolicyMatch)
+	assert.Equal(t, "jane", received["require-team"].Denial.User)
+}
+
+func TestHandleAdmissionDenial_NamedWebhook(t *testing.T) {
+	idx := indexer.New(nil)
+	c := New(idx, nil, zap.NewNop())
+	webhook := admissionConstraint("wh", "validatingwebhookconfigurations", "", "image-policy-images.example.com", "pods")
+	webhook.Details = map[string]interface{}{"webhookName": "images.example.com"}
+	idx.Upsert(webhook)
+	idx.Upsert(admissionConstraint("vap", "validatingadmissionpolicies", "", "require-team", "pods"))
+
+	c.handleAdmissionDenial(context.Background(), audit.Denial{
+		Object:  audit.ObjectRef{Resource: "pods", Namespace: "shop", Name: "orders-0"},
+		Plugin:  audit.PluginWebhook,
+		Webhook: "images.example.com",
+	})
+
+	received := collectAdmissionDenials(c)
+	require.Len(t, received, 1)
+	assert.True(t, received["image-policy-images.example.com"].PolicyMatch)
+}
+
+func TestHandleAdmissionDenial_ByPlugin(t *testing.T) {
+	idx := indexer.New(nil)
+	c := New(idx, nil, zap.NewNop())
+	idx.Upsert(admissionConstraint("vap-deploy", "validatingadmissionpolicies", "", "deploy-rules", "deployments"))
+	idx.Upsert(admissionConstraint("vap-all", "validatingadmissionpolicies", "", "all-rules", "*"))
+	idx.Upsert(admissionConstraint("vap-pods", "validatingadmissionpolicies", "", "pod-rules", "pods"))
+	idx.Upsert(internaltypes.Constraint{UID: "quota", Source: schema.GroupVersionResource{Resource: "resourcequotas"}, Name: "compute", Namespace: "shop", ConstraintType: internaltypes.ConstraintTypeResourceLimit})
+	idx.Upsert(internaltypes.Constraint{UID: "limits", Source: schema.GroupVersionResource{Resource: "limitranges"}, Name: "limits-Container-0", Namespace: "shop", ConstraintType: internaltypes.ConstraintTypeResourceLimit})
+
+	c.handleAdmissionDenial(context.Background(), audit.Denial{
+		Object:   audit.ObjectRef{APIGroup: "apps", Resource: "deployments", Namespace: "shop", Name: "orders"},
+		Plugin:   audit.PluginValidatingAdmissionPolicy,
+		Policies: []string{"unindexed"},
+	})
+	received := collectAdmissionDenials(c)
+	assert.ElementsMatch(t, []string{"deploy-rules", "all-rules"}, admissionKeys(received))
+	assert.False(t, received["deploy-rules"].PolicyMatch)
+
+	c.handleAdmissionDenial(context.Background(), audit.Denial{
+		Object: audit.ObjectRef{Resource: "pods", Namespace: "shop", Name: "orders-0"},
+		Plugin: audit.PluginLimitRanger,
+	})
+	assert.ElementsMatch(t, []string{"limits-Container-0"}, admissionKeys(collectAdmissionDenials(c)))
+
+	c.handleAdmissionDenial(context.Background(), audit.Denial{
+		Object: audit.ObjectRef{Resource: "pods", Namespace: "shop", Name: "orders-0"},
+		Plugin: audit.PluginPodSecurity,
+	})
+	assert.Empty(t, collectAdmissionDenials(c))
+}
+
+func TestHandleAdmissionDenial_Deduplication(t *testing.T) {
+	idx := indexer.New(nil)
+	c := New(idx, nil, zap.NewNop())
+	idx.Upsert(admissionConstraint("team", "k8srequiredlabels", "", "require-team"))
+
+	denial := audit.Denial{
+		User:     "jane",
+		Verb:     "create",
+		Object:   audit.ObjectRef{Resource: "deployments", Namespace: "shop", Name: "orders"},
+		Plugin:   audit.PluginWebhook,
+		Policies: []string{"require-team"},
+	}
+	c.handleAdmissionDenial(context.Background(), denial)
+	c.handleAdmissionDenial(context.Background(), denial)
+	denial.User = "argocd"
+	c.handleAdmissionDenial(context.Background(), denial)
+
+	assert.Len(t, c.admission, 2)
+}
+
+func TestHandleAdmissionDenial_OutOfScope(t *testing.T) {
+	idx := indexer.New(nil)
+	nsScope := scope.New(fake.NewSimpleClientset(), zap.NewNop(), scope.Options{})
+	require.NoError(t, nsScope.Update(scope.Config{IncludeNamespaces: []string{"team-*"}}))
+	c := NewWithOptions(idx, nil, zap.NewNop(), CorrelatorOptions{Scope: nsScope})
+	idx.Upsert(admissionConstraint("team", "k8srequiredlabels", "", "require-team"))
+
+	c.handleAdmissionDenial(context.Background(), audit.Denial{
+		Object:   audit.ObjectRef{Resource: "deployments", Namespace: "kube-system", Name: "dns"},
+		Plugin:   audit.PluginWebhook,
+		Policies: []string{"require-team"},
+	})
+
+	assert.Empty(t, c.admission)
+}
+
+type denialSource chan audit.Denial
+
+func (s denialSource) Denials() <-chan audit.Denial { return s }
+
+func TestStart_CorrelatesAuditSources(t *testing.T) {
+	idx := indexer.New(nil)
+	idx.Upsert(internaltypes.Constraint{UID: "quota", Source: schema.GroupVersionResource{Resource: "resourcequotas"}, Name: "compute", Namespace: "shop"})
+
+	src := make(denialSource, 1)
+	src <- audit.Denial{
+		Object:   audit.ObjectRef{Resource: "pods", Namespace: "shop", Name: "orders-0"},
+		Plugin:   audit.PluginResourceQuota,
+		Policies: []string{"compute"},
+	}
+
+	c := NewWithOptions(idx, fake.NewSimpleClientset(), zap.NewNop(), CorrelatorOptions{AuditSources: []audit.DenialSource{src}})
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() { _ = c.Start(ctx) }()
+
+	select {
+	case n := <-c.AdmissionDenialNotifications():
+		assert.Equal(t, "compute", n.Constraint.Name)
+		assert.True(t, n.PolicyMatch)
+	case <-time.After(2 * time.Second):
+		t.Fatal("admission denial was not correlated")
+	}
+
+	cancel()
+	closed := make(chan struct{})
+	go func() {
+		for range c.AdmissionDenialNotifications() {
+		}
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("admission denial channel not closed after shutdown")
+	}
+}
+
+func admissionKeys(m map[string]AdmissionDenialNotification) []string {
+	result := make([]string, 0, len(m))
+	for k := range m {
+		result = append(result, k)
+	}
+	return result
+}
+
+// ownedObject returns the metadata of an object in "production" controlled
+// by owner.
+func ownedObject(apiVersion, kind, name string, owner metav1.OwnerReference) *metav1.PartialObjectMetadata {
+	owner.Controller = ptr.To(true)
+	return &metav1.PartialObjectMetadata{
+		TypeMeta: metav1.TypeMeta{APIVersion: apiVersion, Kind: kind},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       "production",
+			Name:            name,
+			UID:             types.UID(name + "-uid"),
+			OwnerReferences: []metav1.OwnerReference{owner},
+		},
+	}
+}
+
+// newWorkloadResolver returns a synced resolver for two Pods of the
+// Deployment backend and a Pod of the Argo Rollout frontend.
+func newWorkloadResolver(t *testing.T) *workload.Resolver {
+	t.Helper()
+	scheme := metadatafake.NewTestScheme()
+	require.NoError(t, metav1.AddMetaToScheme(scheme))
+	rs := metav1.OwnerReference{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "backend-7d9f", UID: "backend-7d9f-uid"}
+	client := metadatafake.NewSimpleMetadataClient(scheme,
+		ownedObject("apps/v1", "ReplicaSet", "backend-7d9f", metav1.OwnerReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "backend", UID: "backend-uid"}),
+		ownedObject("v1", "Pod", "backend-7d9f-abcde", rs),
+		ownedObject("v1", "Pod", "backend-7d9f-fghij", rs),
+		ownedObject("apps/v1", "ReplicaSet", "frontend-5c8b", metav1.OwnerReference{APIVersion: "argoproj.io/v1alpha1", Kind: "Rollout", Name: "frontend", UID: "frontend-uid"}),
+		ownedObject("v1", "Pod", "frontend-5c8b-xyz12", metav1.OwnerReference{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "frontend-5c8b", UID: "frontend-5c8b-uid"}),
+	)
+	r := workload.NewResolver(client, zap.NewNop())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go func() { _ = r.Start(ctx) }()
+	require.Eventually(t, r.HasSynced, 2*time.Second, 10*time.Millisecond)
+	return r
+}
+
+func TestHandleEvent_ResolvesTopLevelWorkload(t *testing.T) {
+	idx := indexer.New(nil)
+	c := NewWithOptions(idx, nil, zap.NewNop(), CorrelatorOptions{Workloads: newWorkloadResolver(t)})
+	idx.Upsert(internaltypes.Constraint{UID: "quota", Name: "compute", Namespace: "production", ConstraintType: internaltypes.ConstraintTypeResourceLimit})
+
+	// Events about both Pods and the ReplicaSet are notified once, on the
+	// Deployment.
+	for i, obj := range []struct{ name, kind string }{
+		{"backend-7d9f-abcde", "Pod"},
+		{"backend-7d9f-fghij", "Pod"},
+		{"backend-7d9f", "ReplicaSet"},
+	} {
+		event := makeEvent(fmt.Sprintf("evt-%d", i), "production", obj.name, obj.kind)
+		event.InvolvedObject.APIVersion = "v1"
+		if obj.kind == "ReplicaSet" {
+			event.InvolvedObject.APIVersion = "apps/v1"
+		}
+		c.handleEvent(context.Background(), event)
+	}
+
+	require.Len(t, c.notifications, 1)
+	n := <-c.notifications
+	assert.Equal(t, "Deployment", n.WorkloadKind)
+	assert.Equal(t, "backend", n.WorkloadName)
+	assert.Equal(t, "apps/v1", n.WorkloadAPIVersion)
+	assert.Equal(t, "backend-uid", n.WorkloadUID)
+	assert.Equal(t, "backend-7d9f-abcde", n.Event.InvolvedObject.Name)
+}
+
+func TestHandleFlowDrop_ResolvesTopLevelWorkload(t *testing.T) {
+	idx := indexer.New(nil)
+	stats := flowstats.NewStore(flowstats.StoreOptions{})
+	c := NewWithOptions(idx, nil, zap.NewNop(), CorrelatorOptions{Workloads: newWorkloadResolver(t), FlowStats: stats})
+	idx.Upsert(networkConstraint("np", "networkpolicies", "production", "deny-frontend", internaltypes.ConstraintTypeNetworkIngress))
+
+	// Drops from the Rollout's Pod to both Pods of the Deployment are
+	// notified once. Hubble names only the ReplicaSet.
+	for _, pod := range []string{"backend-7d9f-abcde", "backend-7d9f-fghij"} {
+		c.handleFlowDrop(context.Background(), hubble.NewFlowDropBuilder().
+			WithSource("production", "frontend-5c8b-xyz12", map[string]string{"app": "frontend"}).
+			WithDestination("production", pod, map[string]string{"app": "backend"}).
+			WithDestinationWorkload("ReplicaSet", "backend-7d9f").
+			WithTCP(40000, 8080, hubble.TCPFlags{SYN: true}).
+			WithDropReason(hubble.DropReasonPolicy).
+			WithDirection(hubble.DirectionIngress).
+			Build())
+	}
+
+	received := collectFlowDrops(c)
+	require.Len(t, received, 1)
+	n := received["deny-frontend"]
+	assert.Equal(t, "frontend", n.SourceWorkload)
+	assert.Equal(t, "Rollout", n.SourceWorkloadKind)
+	assert.Equal(t, "backend", n.DestWorkload)
+	assert.Equal(t, "Deployment", n.DestWorkloadKind)
+	assert.Equal(t, []hubble.WorkloadRef{{Kind: "Deployment", Name: "backend"}, {Kind: "ReplicaSet", Name: "backend-7d9f"}}, n.FlowDrop.Destination.Workloads)
+
+	// Both drops are counted on the Deployment.
+	entries := stats.All()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "backend", entries[0].Key.Destination.Workload)
+	assert.Equal(t, uint64(2), entries[0].Count)
+}
+
+func TestHandleFlowDrop_UnknownPodKeepsHubbleWorkload(t *testing.T) {
+	idx := indexer.New(nil)
+	c := NewWithOptions(idx, nil, zap.NewNop(), CorrelatorOptions{Workloads: newWorkloadResolver(t)})
+	idx.Upsert(networkConstraint("np", "networkpolicies", "production", "deny-frontend", internaltypes.ConstraintTypeNetworkIngress))
+
+	c.handleFlowDrop(context.Background(), hubble.NewFlowDropBuilder().
+		WithDestination("production", "backend-0", map[string]string{"app": "backend"}).
+		WithDestinationWorkload("StatefulSet", "backend").
+		WithDropReason(hubble.DropReasonPolicy).
+		WithDirection(hubble.DirectionIngress).
+		Build())
+
+	received := collectFlowDrops(c)
+	require.Len(t, received, 1)
+	assert.Equal(t, "backend", received["deny-frontend"].DestWorkload)
+	assert.Equal(t, "StatefulSet", received["deny-frontend"].DestWorkloadKind)
+}
+
+func TestCorrelator_DedupeStoreSurvivesLeaderChange(t *testing.T) {
+	idx := indexer.New(nil)
+	store := dedupe.NewConfigMapStore(fake.NewSimpleClientset(), "nightjar-system", "nightjar-dedupe")
+	key := dedupeKey{eventUID: "workload:shop/Deployment/api", constraintUID: "c-1"}
+
+	leader := NewWithOptions(idx, nil, zap.NewNop(), CorrelatorOptions{DedupeStore: store})
+	require.True(t, leader.tryMarkSeen(key))
+	_, err := leader.journal.Sync(context.Background(), dedupeWindow)
+	require.NoError(t, err)
+
+	next := NewWithOptions(idx, nil, zap.NewNop(), CorrelatorOptions{DedupeStore: store})
+	next.restoreDedupe(context.Background())
+	assert.False(t, next.tryMarkSeen(key), "the new leader must not notify the pair again")
+	assert.True(t, next.tryMarkSeen(dedupeKey{eventUID: "workload:shop/Deployment/web", constraintUID: "c-1"}))
+}
+
+func TestDedupeKey_RoundTrip(t *testing.T) {
+	key := dedupeKey{eventUID: "flow:shop/Deployment/api|x", constraintUID: "c-1"}
+	got, ok := parseDedupeKey(key.String())
+	require.True(t, ok)
+	assert.Equal(t, key, got)
+}
@@ -15,17 +15,59 @@
 //	    Event      *corev1.Event       // the original K8s event
 //	    Constraint types.Constraint    // the matching constraint
 //	    Namespace  string              // affected namespace
-//	    WorkloadName string            // affected workload name
-//	    WorkloadKind string            // affected workload kind (Pod, Deployment, etc.)
+//	    WorkloadName string            // affected top-level workload name
+//	    WorkloadKind string            // affected workload kind (Deployment, CronJob, etc.)
+//	    WorkloadAPIVersion, WorkloadUID string
 //	}
 //
-// # Rate Limiting
+// # Workloads
 //
-// Process at most 100 events/second (token bucket). Drop excess events with a metric.
+// With a workload.Resolver (CorrelatorOptions.Workloads), the involved object
+// of an event and the pods of a flow drop are resolved to their top-level
+// workload through controller owner references: a Pod or ReplicaSet to its
+// Deployment or Argo Rollout, a Job to its CronJob. Notifications target that
+// workload and are deduplicated per workload, so the Pods of a Deployment
+// share one notification. Without a resolver, events target their involved
+// object and flow drops the first workload Hubble reports.
+//
+// # Flow Drops
+//
+// With a Hubble client, policy drops are correlated with network constraints
+// of the enforcing endpoint: the destination for ingress drops, the source for
+// egress drops, both when the direction is unknown. Drops naming their denying
+// policies match only those policies' constraints; other drops match by
+// workload selector.
+//
+// L7 denials from Envoy access logs (CorrelatorOptions.AccessLogs) arrive as
+// flow drops with FlowDrop.L7 set. They match AuthorizationPolicies, other
+// mesh policies and network constraints with L7 rules instead.
+//
+// # Admission Denials
+//
+// Rejected requests create no Events. Denials read from the audit log
+// (CorrelatorOptions.AuditSources) are correlated with the constraints of
+// the target object's namespace and emitted as AdmissionDenialNotification.
+// The policies, constraints or webhook named in the denial message are
+// matched when indexed; otherwise the denying plugin's constraints are:
+// ResourceQuotas, LimitRanges, or admission constraints targeting the
+// object's resource. Denials are deduplicated per user, verb and object.
+//
+// # Backpressure
+//
+// Notifications wait in a queue per stream (events, flow drops, admission
+// denials) and are delivered at most 100/second (token bucket), Critical
+// before Warning before Info, and round-robin across namespaces within a
+// priority. When the queue or a namespace's share of it is full, a
+// notification displaces a lower-priority one or is folded into a summary
+// of its constraint in the namespace, delivered with Coalesced set ("and 312
+// more drops"). Coalesced and dropped notifications are counted in
+// nightjar_notification_queue_items_total.
 //
 // # Deduplication
 //
-// Track (eventUID, constraintUID) pairs. Suppress duplicates within 5 minutes.
+// Track (workload, constraintUID) pairs. Suppress duplicates within 5 minutes.
+// With a dedupe.Store (CorrelatorOptions.DedupeStore), the pairs are restored
+// on Start and synced periodically, so they survive restarts and failovers.
 //
 // # Constructor
 //
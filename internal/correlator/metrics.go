@@ -0,0 +1,35 @@
+package correlator
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Label values of nightjar_notification_queue_items_total.
+const (
+	streamEvents           = "events"
+	streamFlowDrops        = "flow_drops"
+	streamAdmissionDenials = "admission_denials"
+
+	resultDelivered = "delivered"
+	resultCoalesced = "coalesced"
+	resultDropped   = "dropped"
+)
+
+// Notification queue metrics, served on the controller-runtime metrics
+// endpoint.
+var (
+	queueItemsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "nightjar_notification_queue_items_total",
+		Help: "Correlated notifications by stream (events, flow_drops, admission_denials) and result: delivered, coalesced into a summary, or dropped because summaries overflowed.",
+	}, []string{"stream", "result"})
+
+	queueDepth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "nightjar_notification_queue_depth",
+		Help: "Correlated notifications waiting for delivery, by stream.",
+	}, []string{"stream"})
+)
+
+func init() {
+	ctrlmetrics.Registry.MustRegister(queueItemsTotal, queueDepth)
+}
@@ -0,0 +1,384 @@
+package correlator
+
+import (
+	"context"
+	"sync"
+
+	"golang.org/x/time/rate"
+
+	"github.com/nightjarctl/nightjar/internal/types"
+)
+
+const (
+	// Notifications waiting for delivery per stream, and per namespace.
+	queueCapacity          = 5000
+	namespaceQueueCapacity = 500
+
+	// Summaries of notifications that did not fit, per stream. Beyond
+	// this, overflowing notifications are dropped.
+	summaryCapacity = 1000
+)
+
+// priorities is the number of delivery priorities; see priorityOf.
+const priorities = 3
+
+// priorityOf orders notifications by the constraint's severity: Critical
+// first, then Warning, then Info. An unset severity counts as Warning.
+func priorityOf(severity types.Severity) int {
+	switch severity {
+	case types.SeverityCritical:
+		return 0
+	case types.SeverityInfo:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// summaryKey identifies the notifications one summary stands for: those of
+// a constraint in a namespace.
+type summaryKey struct {
+	namespace     string
+	constraintUID string
+}
+
+// queueItem is a notification waiting for delivery. coalesced counts the
+// further notifications it summarizes.
+type queueItem[T any] struct {
+	value     T
+	key       summaryKey
+	priority  int
+	coalesced int
+}
+
+// fairQueue holds the items of one priority, a FIFO per namespace, and
+// serves the namespaces round-robin.
+type fairQueue[T any] struct {
+	items map[string][]*queueItem[T]
+	ring  []string // namespaces with items, in service order
+}
+
+func (f *fairQueue[T]) push(item *queueItem[T]) {
+	ns := item.key.namespace
+	if len(f.items[ns]) == 0 {
+		f.ring = append(f.ring, ns)
+	}
+	f.items[ns] = append(f.items[ns], item)
+}
+
+// pushFront returns an item taken by pop to the head of its namespace.
+func (f *fairQueue[T]) pushFront(item *queueItem[T]) {
+	ns := item.key.namespace
+	if len(f.items[ns]) == 0 {
+		f.ring = append([]string{ns}, f.ring...)
+	}
+	f.items[ns] = append([]*queueItem[T]{item}, f.items[ns]...)
+}
+
+// pop takes the oldest item of the next namespace in the ring, and moves
+// the namespace to the back.
+func (f *fairQueue[T]) pop() (*queueItem[T], bool) {
+	if len(f.ring) == 0 {
+		return nil, false
+	}
+	ns := f.ring[0]
+	f.ring = f.ring[1:]
+	item := f.items[ns][0]
+	f.items[ns] = f.items[ns][1:]
+	if len(f.items[ns]) > 0 {
+		f.ring = append(f.ring, ns)
+	} else {
+		delete(f.items, ns)
+	}
+	return item, true
+}
+
+// popNewest takes the newest item of namespace ns, or of the namespace
+// with the most items when ns is empty.
+func (f *fairQueue[T]) popNewest(ns string) (*queueItem[T], bool) {
+	if ns == "" {
+		for candidate, items := range f.items {
+			if ns == "" || len(items) > len(f.items[ns]) {
+				ns = candidate
+			}
+		}
+	}
+	items := f.items[ns]
+	if len(items) == 0 {
+		return nil, false
+	}
+	item := items[len(items)-1]
+	f.items[ns] = items[:len(items)-1]
+	if len(f.items[ns]) == 0 {
+		delete(f.items, ns)
+		for i, candidate := range f.ring {
+			if candidate == ns {
+				f.ring = append(f.ring[:i], f.ring[i+1:]...)
+				break
+			}
+		}
+	}
+	return item, true
+}
+
+// notificationQueue delivers the notifications of one stream to out:
+// Critical before Warning before Info, and within a priority round-robin
+// across namespaces, so a noisy namespace does not starve the others.
+// Delivery is paced by limiter.
+//
+// When the queue, or a namespace's share of it, is full, a notification
+// displaces a queued one of lower priority, or is otherwise folded into a
+// summary: a queued notification of the same constraint and namespace, or
+// the first overflowing one, which is delivered with the count of those it
+// stands for ("and 312 more") once there is room. Notifications are only
+// dropped when summaries overflow too.
+type notificationQueue[T any] struct {
+	stream    string
+	out       chan T
+	limiter   *rate.Limiter
+	summarize func(T, int) T // sets the coalesced count on a notification
+
+	mu        sync.Mutex
+	levels    [priorities]fairQueue[T]
+	queued    map[summaryKey]*queueItem[T] // newest queued item per key
+	size      int
+	perNS     map[string]int
+	summaries []*queueItem[T] // overflow waiting for room, oldest first
+	summaryOf map[summaryKey]*queueItem[T]
+	closed    bool
+	wake      chan struct{}
+}
+
+func newNotificationQueue[T any](stream string, out chan T, limiter *rate.Limiter, summarize func(T, int) T) *notificationQueue[T] {
+	q := &notificationQueue[T]{
+		stream:    stream,
+		out:       out,
+		limiter:   limiter,
+		summarize: summarize,
+		queued:    make(map[summaryKey]*queueItem[T]),
+		perNS:     make(map[string]int),
+		summaryOf: make(map[summaryKey]*queueItem[T]),
+		wake:      make(chan struct{}, 1),
+	}
+	for i := range q.levels {
+		q.levels[i].items = make(map[string][]*queueItem[T])
+	}
+	return q
+}
+
+// push queues a notification of constraint in namespace and delivers what
+// the output channel and the limiter admit without blocking; Run delivers
+// the rest.
+func (q *notificationQueue[T]) push(value T, namespace string, constraint types.Constraint) {
+	item := &queueItem[T]{
+		value:    value,
+		key:      summaryKey{namespace: namespace, constraintUID: string(constraint.UID)},
+		priority: priorityOf(constraint.Severity),
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return
+	}
+	q.admit(item)
+	q.deliverReady()
+	if q.size > 0 {
+		select {
+		case q.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// admit queues item, or folds it into a summary when the queue or its
+// namespace is full. Called with mu held.
+func (q *notificationQueue[T]) admit(item *queueItem[T]) {
+	nsFull := q.perNS[item.key.namespace] >= namespaceQueueCapacity
+	if !nsFull && q.size < queueCapacity {
+		q.enqueue(item)
+		return
+	}
+
+	// Fold into a queued notification of the same constraint, which is
+	// delivered sooner than a summary.
+	if queued, ok := q.queued[item.key]; ok {
+		queued.coalesced += 1 + item.coalesced
+		queueItemsTotal.WithLabelValues(q.stream, resultCoalesced).Add(float64(1 + item.coalesced))
+		return
+	}
+
+	// Displace the newest queued notification of the lowest lower priority:
+	// in the namespace when it is over its share, anywhere otherwise.
+	scope := ""
+	if nsFull {
+		scope = item.key.namespace
+	}
+	for p := priorities - 1; p > item.priority; p-- {
+		if victim, ok := q.levels[p].popNewest(scope); ok {
+			q.dequeued(victim)
+			q.spill(victim)
+			q.enqueue(item)
+			return
+		}
+	}
+	q.spill(item)
+}
+
+// enqueue adds item to its priority level. Called with mu held.
+func (q *notificationQueue[T]) enqueue(item *queueItem[T]) {
+	q.levels[item.priority].push(item)
+	q.queued[item.key] = item
+	q.size++
+	q.perNS[item.key.namespace]++
+	queueDepth.WithLabelValues(q.stream).Set(float64(q.size))
+}
+
+// dequeued updates the bookkeeping for an item taken from its level.
+// Called with mu held.
+func (q *notificationQueue[T]) dequeued(item *queueItem[T]) {
+	if q.queued[item.key] == item {
+		delete(q.queued, item.key)
+	}
+	q.size--
+	if q.perNS[item.key.namespace]--; q.perNS[item.key.namespace] == 0 {
+		delete(q.perNS, item.key.namespace)
+	}
+	queueDepth.WithLabelValues(q.stream).Set(float64(q.size))
+}
+
+// spill folds item into the summary of its key, or makes it the summary.
+// Called with mu held.
+func (q *notificationQueue[T]) spill(item *queueItem[T]) {
+	if summary, ok := q.summaryOf[item.key]; ok {
+		summary.coalesced += 1 + item.coalesced
+		queueItemsTotal.WithLabelValues(q.stream, resultCoalesced).Add(float64(1 + item.coalesced))
+		return
+	}
+	if len(q.summaries) >= summaryCapacity {
+		queueItemsTotal.WithLabelValues(q.stream, resultDropped).Add(float64(1 + item.coalesced))
+		return
+	}
+	q.summaries = append(q.summaries, item)
+	q.summaryOf[item.key] = item
+}
+
+// readmit moves summaries back into the queue while there is room, highest
+// priority first. Called with mu held.
+func (q *notificationQueue[T]) readmit() {
+	for q.size < queueCapacity && len(q.summaries) > 0 {
+		best := -1
+		for i, s := range q.summaries {
+			if q.perNS[s.key.namespace] >= namespaceQueueCapacity {
+				continue
+			}
+			if best < 0 || s.priority < q.summaries[best].priority {
+				best = i
+			}
+		}
+		if best < 0 {
+			return
+		}
+		summary := q.summaries[best]
+		q.summaries = append(q.summaries[:best], q.summaries[best+1:]...)
+		delete(q.summaryOf, summary.key)
+		q.enqueue(summary)
+	}
+}
+
+// pop takes the next item to deliver. Called with mu held.
+func (q *notificationQueue[T]) pop() (*queueItem[T], bool) {
+	for p := range q.levels {
+		if item, ok := q.levels[p].pop(); ok {
+			q.dequeued(item)
+			q.readmit()
+			return item, true
+		}
+	}
+	return nil, false
+}
+
+// unpop returns an item taken by pop that could not be delivered. Called
+// with mu held.
+func (q *notificationQueue[T]) unpop(item *queueItem[T]) {
+	q.levels[item.priority].pushFront(item)
+	if _, ok := q.queued[item.key]; !ok {
+		q.queued[item.key] = item
+	}
+	q.size++
+	q.perNS[item.key.namespace]++
+	queueDepth.WithLabelValues(q.stream).Set(float64(q.size))
+}
+
+// deliverReady sends queued items while the output channel has room and the
+// limiter has tokens, without blocking. Called with mu held.
+func (q *notificationQueue[T]) deliverReady() {
+	for q.size > 0 && len(q.out) < cap(q.out) && q.limiter.Allow() {
+		item, _ := q.pop()
+		select {
+		case q.out <- q.summarize(item.value, item.coalesced):
+			queueItemsTotal.WithLabelValues(q.stream, resultDelivered).Inc()
+		default:
+			// Run filled the channel meanwhile.
+			q.unpop(item)
+			return
+		}
+	}
+}
+
+// Run delivers queued items, waiting for the limiter and the consumer, until
+// ctx is cancelled. An item is taken before waiting, so no token is spent
+// when push delivered everything meanwhile; the limiter is shared with the
+// other streams. An item whose delivery is cancelled is queued again.
+func (q *notificationQueue[T]) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.wake:
+		}
+		for {
+			q.mu.Lock()
+			item, ok := q.pop()
+			q.mu.Unlock()
+			if !ok {
+				break
+			}
+			if err := q.limiter.Wait(ctx); err != nil {
+				q.requeue(item)
+				return
+			}
+			select {
+			case q.out <- q.summarize(item.value, item.coalesced):
+				queueItemsTotal.WithLabelValues(q.stream, resultDelivered).Inc()
+			case <-ctx.Done():
+				q.requeue(item)
+				return
+			}
+		}
+	}
+}
+
+// requeue returns an item Run took but did not deliver.
+func (q *notificationQueue[T]) requeue(item *queueItem[T]) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.unpop(item)
+}
+
+// close stops deliveries from push and closes the output channel. Run must
+// have returned.
+func (q *notificationQueue[T]) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	close(q.out)
+}
+
+// Len returns the number of queued notifications, excluding summaries
+// waiting for room.
+func (q *notificationQueue[T]) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.size
+}
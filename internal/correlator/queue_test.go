@@ -0,0 +1,194 @@
+package correlator
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+	"k8s.io/apimachinery/pkg/types"
+
+	internaltypes "github.com/nightjarctl/nightjar/internal/types"
+)
+
+// testNote is a queued notification in these tests.
+type testNote struct {
+	name      string
+	coalesced int
+}
+
+// newTestQueue returns a queue whose output channel never has room, so
+// everything pushed stays queued until taken with popAll.
+func newTestQueue(stream string) *notificationQueue[testNote] {
+	return newNotificationQueue(stream, make(chan testNote), rate.NewLimiter(rate.Inf, 0),
+		func(n testNote, coalesced int) testNote {
+			n.coalesced += coalesced
+			return n
+		})
+}
+
+func severityConstraint(uid string, severity internaltypes.Severity) internaltypes.Constraint {
+	return internaltypes.Constraint{UID: types.UID(uid), Severity: severity}
+}
+
+// popAll takes every queued item in delivery order, readmitting summaries
+// as room frees up.
+func popAll(q *notificationQueue[testNote]) []testNote {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var result []testNote
+	for {
+		item, ok := q.pop()
+		if !ok {
+			return result
+		}
+		result = append(result, q.summarize(item.value, item.coalesced))
+	}
+}
+
+func names(notes []testNote) []string {
+	result := make([]string, len(notes))
+	for i, n := range notes {
+		result[i] = n.name
+	}
+	return result
+}
+
+func TestNotificationQueue_Priority(t *testing.T) {
+	q := newTestQueue("test-priority")
+	q.push(testNote{name: "info"}, "shop", severityConstraint("c-info", internaltypes.SeverityInfo))
+	q.push(testNote{name: "warning"}, "shop", severityConstraint("c-warning", internaltypes.SeverityWarning))
+	q.push(testNote{name: "critical"}, "shop", severityConstraint("c-critical", internaltypes.SeverityCritical))
+	q.push(testNote{name: "unset"}, "shop", severityConstraint("c-unset", ""))
+
+	assert.Equal(t, []string{"critical", "warning", "unset", "info"}, names(popAll(q)))
+}
+
+func TestNotificationQueue_NamespaceFairness(t *testing.T) {
+	q := newTestQueue("test-fairness")
+	c := severityConstraint("c-1", internaltypes.SeverityWarning)
+	for i := 0; i < 3; i++ {
+		q.push(testNote{name: fmt.Sprintf("noisy-%d", i)}, "noisy", c)
+	}
+	q.push(testNote{name: "quiet-0"}, "quiet", c)
+
+	assert.Equal(t, []string{"noisy-0", "quiet-0", "noisy-1", "noisy-2"}, names(popAll(q)))
+}
+
+func TestNotificationQueue_CoalescesIntoQueued(t *testing.T) {
+	q := newTestQueue("test-coalesce")
+	c := severityConstraint("c-1", internaltypes.SeverityCritical)
+	for i := 0; i < namespaceQueueCapacity+312; i++ {
+		q.push(testNote{name: fmt.Sprintf("drop-%d", i)}, "shop", c)
+	}
+	assert.Equal(t, namespaceQueueCapacity, q.Len(), "the namespace is held to its share of the queue")
+	assert.Equal(t, 312.0, testutil.ToFloat64(queueItemsTotal.WithLabelValues("test-coalesce", resultCoalesced)))
+
+	// Other namespaces still queue.
+	q.push(testNote{name: "other"}, "payments", c)
+	assert.Equal(t, namespaceQueueCapacity+1, q.Len())
+
+	delivered := popAll(q)
+	require.Len(t, delivered, namespaceQueueCapacity+1)
+	last := delivered[len(delivered)-1]
+	assert.Equal(t, fmt.Sprintf("drop-%d", namespaceQueueCapacity-1), last.name)
+	assert.Equal(t, 312, last.coalesced, "and 312 more drops")
+}
+
+func TestNotificationQueue_CriticalDisplacesInfo(t *testing.T) {
+	q := newTestQueue("test-displace")
+	for i := 0; i < namespaceQueueCapacity; i++ {
+		q.push(testNote{name: fmt.Sprintf("info-%d", i)}, "shop", severityConstraint(fmt.Sprintf("c-info-%d", i), internaltypes.SeverityInfo))
+	}
+	q.push(testNote{name: "critical"}, "shop", severityConstraint("c-critical", internaltypes.SeverityCritical))
+	// The displaced Info notification's constraint overflows again: both
+	// are summarized.
+	last := namespaceQueueCapacity - 1
+	q.push(testNote{name: "info-again"}, "shop", severityConstraint(fmt.Sprintf("c-info-%d", last), internaltypes.SeverityInfo))
+
+	delivered := popAll(q)
+	require.Len(t, delivered, namespaceQueueCapacity+1)
+	assert.Equal(t, "critical", delivered[0].name, "Critical is delivered before Info")
+	summary := delivered[len(delivered)-1]
+	assert.Equal(t, fmt.Sprintf("info-%d", last), summary.name, "the displaced notification is delivered once there is room")
+	assert.Equal(t, 1, summary.coalesced)
+}
+
+func TestNotificationQueue_DropsWhenSummariesOverflow(t *testing.T) {
+	q := newTestQueue("test-drop")
+	// Fill the queue with Critical notifications, one constraint each, so
+	// nothing can be displaced or folded into a queued notification.
+	for i := 0; i < queueCapacity; i++ {
+		ns := fmt.Sprintf("ns-%d", i%(queueCapacity/namespaceQueueCapacity))
+		q.push(testNote{}, ns, severityConstraint(fmt.Sprintf("c-%d", i), internaltypes.SeverityCritical))
+	}
+	for i := 0; i < summaryCapacity+5; i++ {
+		q.push(testNote{}, "overflow", severityConstraint(fmt.Sprintf("o-%d", i), internaltypes.SeverityCritical))
+	}
+
+	assert.Equal(t, queueCapacity, q.Len())
+	assert.Equal(t, 5.0, testutil.ToFloat64(queueItemsTotal.WithLabelValues("test-drop", resultDropped)))
+	assert.Len(t, popAll(q), queueCapacity+summaryCapacity)
+}
+
+func TestNotificationQueue_DeliversWithoutBlocking(t *testing.T) {
+	out := make(chan testNote, 2)
+	q := newNotificationQueue("test-deliver", out, rate.NewLimiter(rate.Inf, 0),
+		func(n testNote, coalesced int) testNote { return n })
+	c := severityConstraint("c-1", internaltypes.SeverityWarning)
+	for i := 0; i < 3; i++ {
+		q.push(testNote{name: fmt.Sprintf("n-%d", i)}, "shop", c)
+	}
+	assert.Len(t, out, 2, "what fits in the channel is delivered at once")
+	assert.Equal(t, 1, q.Len())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		q.Run(ctx)
+		close(done)
+	}()
+
+	var got []string
+	for i := 0; i < 3; i++ {
+		select {
+		case n := <-out:
+			got = append(got, n.name)
+		case <-time.After(2 * time.Second):
+			t.Fatal("queued notification was not delivered")
+		}
+	}
+	assert.Equal(t, []string{"n-0", "n-1", "n-2"}, got)
+
+	cancel()
+	<-done
+	q.close()
+	q.push(testNote{name: "late"}, "shop", c)
+	_, open := <-out
+	assert.False(t, open, "nothing is sent after close")
+}
+
+func TestNotificationQueue_RunRequeuesWhenCancelled(t *testing.T) {
+	limiter := rate.NewLimiter(rate.Every(time.Hour), 1)
+	require.True(t, limiter.Allow(), "another stream takes the only token")
+	q := newNotificationQueue("test-requeue", make(chan testNote), limiter,
+		func(n testNote, coalesced int) testNote { return n })
+	q.push(testNote{name: "n-0"}, "shop", severityConstraint("c-1", internaltypes.SeverityWarning))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		q.Run(ctx)
+		close(done)
+	}()
+	require.Eventually(t, func() bool { return q.Len() == 0 }, 2*time.Second, 10*time.Millisecond,
+		"Run takes the item before waiting for the limiter")
+
+	cancel()
+	<-done
+	assert.Equal(t, []string{"n-0"}, names(popAll(q)), "the undelivered item is queued again")
+}
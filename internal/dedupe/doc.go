@@ -0,0 +1,41 @@
+// Package dedupe persists notification deduplication state, so restarts and
+// leader failovers do not notify every active issue again.
+//
+// # Overview
+//
+// The correlator and the dispatcher suppress a notification already sent
+// within a window (dedupe TTL) by remembering when each key was last seen.
+// They keep those maps in memory; a Journal shares them through a Store:
+//
+//	correlator/dispatcher ──Record──► Journal ──Sync──► Store (ConfigMap or file)
+//	                      ◄──merge───         ◄────────
+//
+// Sync merges the keys recorded since the last sync into the stored set,
+// keeping the later time of a key recorded on both sides, drops entries
+// older than the TTL, and returns the merged set to be merged back into
+// memory. A newly elected leader syncs before it processes anything, so it
+// inherits the marks of the previous one; shard owners sharing a store
+// inherit the marks of namespaces that move between them.
+//
+// # Stores
+//
+//	ConfigMapStore  ConfigMaps of at most 900 KiB per journal, shard 0
+//	                updated with optimistic concurrency so concurrent
+//	                writers merge
+//	FileStore       one JSON file, e.g. on a PersistentVolume, replaced
+//	                atomically
+//
+// Both keep at most MaxEntries keys per journal, dropping the oldest. A
+// Journal whose store is unavailable keeps at most twice as many marks for
+// the next Sync.
+//
+// # Usage
+//
+//	j := dedupe.NewJournal(store, "dispatcher", logger, dedupe.Options{})
+//	entries, err := j.Sync(ctx, ttl) // before processing: restore
+//	j.Record(key, time.Now())        // on every mark
+//	j.Run(ctx, ttlFunc, merge)       // sync every Interval and on shutdown
+//
+// A nil Journal records nothing and syncs nothing, so callers need no
+// checks when persistence is disabled.
+package dedupe
@@ -0,0 +1,167 @@
+package dedupe
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Options configures a Journal.
+type Options struct {
+	// Interval between syncs in Run. Marks recorded since the last sync
+	// are lost if the process crashes. Default: 30s.
+	Interval time.Duration
+
+	// MaxEntries caps the entries stored per journal, and those recorded
+	// while the store is unavailable; the oldest are dropped. Default:
+	// 5000.
+	MaxEntries int
+}
+
+// DefaultOptions returns Options with default values.
+func DefaultOptions() Options {
+	return Options{
+		Interval:   30 * time.Second,
+		MaxEntries: 5000,
+	}
+}
+
+// Journal records the dedupe marks of one component and syncs them with a
+// Store under the component's name.
+type Journal struct {
+	store  Store
+	name   string
+	logger *zap.Logger
+	opts   Options
+
+	mu      sync.Mutex
+	pending Entries // recorded since the last successful sync, at most 2*MaxEntries
+}
+
+// NewJournal creates a Journal syncing to store under name. A nil store
+// returns a nil Journal, which records and syncs nothing.
+func NewJournal(store Store, name string, logger *zap.Logger, opts Options) *Journal {
+	if store == nil {
+		return nil
+	}
+	defaults := DefaultOptions()
+	if opts.Interval == 0 {
+		opts.Interval = defaults.Interval
+	}
+	if opts.MaxEntries == 0 {
+		opts.MaxEntries = defaults.MaxEntries
+	}
+	return &Journal{
+		store:   store,
+		name:    name,
+		logger:  logger.Named("dedupe").With(zap.String("journal", name)),
+		opts:    opts,
+		pending: Entries{},
+	}
+}
+
+// Record notes that key was marked seen at the given time. It is written
+// to the store by the next Sync. While syncs fail, only the newest
+// MaxEntries to 2*MaxEntries marks are kept.
+func (j *Journal) Record(key string, at time.Time) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if at.After(j.pending[key]) {
+		j.pending[key] = at
+	}
+	if len(j.pending) > 2*j.opts.MaxEntries {
+		j.pending = merge(j.pending, nil, time.Time{}, j.opts.MaxEntries)
+	}
+}
+
+// Sync merges the recorded marks into the store, dropping entries older
+// than ttl, and returns the stored entries. On error the recorded marks are
+// kept for the next Sync. A nil Journal returns nil.
+func (j *Journal) Sync(ctx context.Context, ttl time.Duration) (Entries, error) {
+	if j == nil {
+		return nil, nil
+	}
+	j.mu.Lock()
+	pending := j.pending
+	j.pending = Entries{}
+	j.mu.Unlock()
+
+	cutoff := time.Now().Add(-ttl)
+	stored, err := j.store.Update(ctx, j.name, func(stored Entries) Entries {
+		return merge(stored, pending, cutoff, j.opts.MaxEntries)
+	})
+	if err != nil {
+		syncsTotal.WithLabelValues(j.name, resultError).Inc()
+		j.mu.Lock()
+		j.pending = merge(j.pending, pending, time.Time{}, j.opts.MaxEntries)
+		j.mu.Unlock()
+		return nil, err
+	}
+	syncsTotal.WithLabelValues(j.name, resultSuccess).Inc()
+	storedEntries.WithLabelValues(j.name).Set(float64(len(stored)))
+	return stored, nil
+}
+
+// Run syncs every Interval, passing the stored entries to apply, and once
+// more when ctx is cancelled. ttl is read before every sync, so a changed
+// dedupe window applies to the next one. Blocks until ctx is cancelled.
+func (j *Journal) Run(ctx context.Context, ttl func() time.Duration, apply func(Entries)) {
+	if j == nil {
+		return
+	}
+	ticker := time.NewTicker(j.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			syncCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			if _, err := j.Sync(syncCtx, ttl()); err != nil {
+				j.logger.Warn("Failed to save dedupe state on shutdown", zap.Error(err))
+			}
+			cancel()
+			return
+		case <-ticker.C:
+			stored, err := j.Sync(ctx, ttl())
+			if err != nil {
+				j.logger.Warn("Failed to sync dedupe state", zap.Error(err))
+				continue
+			}
+			apply(stored)
+		}
+	}
+}
+
+// merge adds pending to stored, keeping the later time of a key in both,
+// drops entries seen before cutoff, and keeps the newest max entries.
+func merge(stored, pending Entries, cutoff time.Time, max int) Entries {
+	for key, at := range pending {
+		if at.After(stored[key]) {
+			stored[key] = at
+		}
+	}
+	for key, at := range stored {
+		if at.Before(cutoff) {
+			delete(stored, key)
+		}
+	}
+	if max <= 0 || len(stored) <= max {
+		return stored
+	}
+	keys := make([]string, 0, len(stored))
+	for key := range stored {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(a, b int) bool {
+		return stored[keys[a]].After(stored[keys[b]])
+	})
+	for _, key := range keys[max:] {
+		delete(stored, key)
+	}
+	return stored
+}
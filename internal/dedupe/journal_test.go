@@ -0,0 +1,182 @@
+package dedupe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// failingStore fails every update while err is set.
+type failingStore struct {
+	Store
+	err error
+}
+
+func (s *failingStore) Update(ctx context.Context, name string, fn func(Entries) Entries) (Entries, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.Store.Update(ctx, name, fn)
+}
+
+func newConfigMapStore() Store {
+	return NewConfigMapStore(fake.NewSimpleClientset(), "nightjar-system", "nightjar-dedupe")
+}
+
+func TestMerge(t *testing.T) {
+	now := time.Now()
+	stored := Entries{
+		"kept":    now.Add(-time.Minute),
+		"newer":   now.Add(-time.Minute),
+		"expired": now.Add(-time.Hour),
+	}
+	pending := Entries{
+		"newer": now,
+		"older": now.Add(-2 * time.Minute),
+	}
+	stored["older"] = now.Add(-time.Minute)
+
+	got := merge(stored, pending, now.Add(-10*time.Minute), 0)
+	assert.Equal(t, Entries{
+		"kept":  now.Add(-time.Minute),
+		"newer": now,
+		"older": now.Add(-time.Minute),
+	}, got)
+}
+
+func TestMerge_KeepsNewestMaxEntries(t *testing.T) {
+	now := time.Now()
+	stored := Entries{"a": now.Add(-3 * time.Second), "b": now.Add(-2 * time.Second)}
+	got := merge(stored, Entries{"c": now}, now.Add(-time.Hour), 2)
+	assert.Equal(t, Entries{"b": now.Add(-2 * time.Second), "c": now}, got)
+}
+
+func TestJournal_NilIsNoop(t *testing.T) {
+	j := NewJournal(nil, "dispatcher", zap.NewNop(), Options{})
+	require.Nil(t, j)
+
+	j.Record("a", time.Now())
+	stored, err := j.Sync(context.Background(), time.Hour)
+	assert.NoError(t, err)
+	assert.Nil(t, stored)
+	j.Run(context.Background(), func() time.Duration { return time.Hour }, func(Entries) {})
+}
+
+func TestJournal_LeaderChange(t *testing.T) {
+	store := newConfigMapStore()
+	ttl := time.Hour
+
+	// The first leader records a mark and saves it on shutdown.
+	leader := NewJournal(store, "dispatcher", zap.NewNop(), Options{Interval: time.Hour})
+	leader.Record("c1|shop/Deployment/api", time.Now())
+	leader.Record("c1|shop/Deployment/expired", time.Now().Add(-2*ttl))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		leader.Run(ctx, func() time.Duration { return ttl }, func(Entries) {})
+		close(done)
+	}()
+	cancel()
+	<-done
+
+	// The next leader restores it before processing anything; the expired
+	// mark is gone.
+	next := NewJournal(store, "dispatcher", zap.NewNop(), Options{})
+	stored, err := next.Sync(context.Background(), ttl)
+	require.NoError(t, err)
+	assert.Contains(t, stored, "c1|shop/Deployment/api")
+	assert.NotContains(t, stored, "c1|shop/Deployment/expired")
+
+	// Once the TTL has passed, the mark no longer suppresses anything.
+	stored, err = next.Sync(context.Background(), 0)
+	require.NoError(t, err)
+	assert.Empty(t, stored)
+}
+
+func TestJournal_OverlappingLeadersMerge(t *testing.T) {
+	store := newConfigMapStore()
+	ttl := time.Hour
+	old := NewJournal(store, "correlator", zap.NewNop(), Options{})
+	next := NewJournal(store, "correlator", zap.NewNop(), Options{})
+
+	// During a failover the old leader may still save after the new one
+	// restored; neither overwrites the other's marks.
+	seen := time.Now().Add(-time.Minute)
+	old.Record("a", seen)
+	next.Record("a", time.Now())
+	next.Record("b", time.Now())
+	_, err := next.Sync(context.Background(), ttl)
+	require.NoError(t, err)
+	stored, err := old.Sync(context.Background(), ttl)
+	require.NoError(t, err)
+
+	assert.Len(t, stored, 2)
+	assert.True(t, stored["a"].After(seen), "the later mark of a key wins")
+}
+
+func TestJournal_SyncErrorKeepsMarks(t *testing.T) {
+	store := &failingStore{Store: newConfigMapStore(), err: errors.New("apiserver unavailable")}
+	j := NewJournal(store, "dispatcher", zap.NewNop(), Options{})
+	j.Record("a", time.Now())
+
+	_, err := j.Sync(context.Background(), time.Hour)
+	require.Error(t, err)
+
+	store.err = nil
+	stored, err := j.Sync(context.Background(), time.Hour)
+	require.NoError(t, err)
+	assert.Contains(t, stored, "a", "marks recorded before a failed sync are saved by the next")
+}
+
+func TestJournal_SyncErrorBoundsMarks(t *testing.T) {
+	store := &failingStore{Store: newConfigMapStore(), err: errors.New("apiserver unavailable")}
+	j := NewJournal(store, "dispatcher", zap.NewNop(), Options{MaxEntries: 10})
+	now := time.Now()
+	for i := 0; i < 25; i++ {
+		j.Record(fmt.Sprintf("key-%d", i), now.Add(time.Duration(i)*time.Second))
+		if i%5 == 0 {
+			_, err := j.Sync(context.Background(), time.Hour)
+			require.Error(t, err)
+		}
+	}
+	assert.LessOrEqual(t, len(j.pending), 20, "marks held for a failing store are bounded")
+
+	_, err := j.Sync(context.Background(), time.Hour)
+	require.Error(t, err)
+	assert.Len(t, j.pending, 10)
+	assert.Contains(t, j.pending, "key-24", "the newest marks are kept")
+	assert.NotContains(t, j.pending, "key-0")
+}
+
+func TestJournal_RunAppliesStoredEntries(t *testing.T) {
+	store := newConfigMapStore()
+	other := NewJournal(store, "dispatcher", zap.NewNop(), Options{})
+	other.Record("shard-b", time.Now())
+	_, err := other.Sync(context.Background(), time.Hour)
+	require.NoError(t, err)
+
+	j := NewJournal(store, "dispatcher", zap.NewNop(), Options{Interval: 10 * time.Millisecond})
+	applied := make(chan Entries, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go j.Run(ctx, func() time.Duration { return time.Hour }, func(e Entries) {
+		select {
+		case applied <- e:
+		default:
+		}
+	})
+
+	select {
+	case e := <-applied:
+		assert.Contains(t, e, "shard-b")
+	case <-time.After(2 * time.Second):
+		t.Fatal("stored entries were not applied")
+	}
+}
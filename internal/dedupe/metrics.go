@@ -0,0 +1,29 @@
+package dedupe
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Label values of nightjar_dedupe_syncs_total.
+const (
+	resultSuccess = "success"
+	resultError   = "error"
+)
+
+// Dedupe metrics, served on the controller-runtime metrics endpoint.
+var (
+	syncsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "nightjar_dedupe_syncs_total",
+		Help: "Dedupe state syncs with the store, by journal (correlator, dispatcher) and result (success, error).",
+	}, []string{"journal", "result"})
+
+	storedEntries = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "nightjar_dedupe_entries",
+		Help: "Dedupe entries in the store after the last sync, by journal.",
+	}, []string{"journal"})
+)
+
+func init() {
+	ctrlmetrics.Registry.MustRegister(syncsTotal, storedEntries)
+}
@@ -0,0 +1,339 @@
+package dedupe
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+// Entries maps a dedupe key to the time it was last seen.
+type Entries map[string]time.Time
+
+// Store persists the entries of named journals.
+type Store interface {
+	// Update replaces the entries stored under name with fn applied to
+	// them, and returns the result. The entries passed to fn are empty if
+	// none are stored. fn may be called more than once when a concurrent
+	// writer races the update.
+	Update(ctx context.Context, name string, fn func(Entries) Entries) (Entries, error)
+}
+
+// FileStore keeps the entries of every journal in a single JSON file, e.g.
+// on a PersistentVolume. It serializes updates within the process only, so
+// one replica at a time may use the file.
+type FileStore struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+// NewFileStore creates a FileStore writing to path.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{Path: path}
+}
+
+// Update reads the file, applies fn to name's entries and writes the file
+// to a temporary file next to Path that is renamed into place, so a crash
+// never leaves a partial file behind. An unreadable file is replaced.
+func (s *FileStore) Update(_ context.Context, name string, fn func(Entries) Entries) (Entries, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	journals := map[string]Entries{}
+	data, err := os.ReadFile(s.Path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return nil, fmt.Errorf("reading dedupe state: %w", err)
+	default:
+		if err := json.Unmarshal(data, &journals); err != nil {
+			journals = map[string]Entries{}
+		}
+	}
+
+	entries := journals[name]
+	if entries == nil {
+		entries = Entries{}
+	}
+	entries = fn(entries)
+	journals[name] = entries
+
+	data, err = json.Marshal(journals)
+	if err != nil {
+		return nil, fmt.Errorf("encoding dedupe state: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0o755); err != nil {
+		return nil, fmt.Errorf("creating dedupe state directory: %w", err)
+	}
+	tmp := s.Path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return nil, fmt.Errorf("writing dedupe state: %w", err)
+	}
+	if err := os.Rename(tmp, s.Path); err != nil {
+		return nil, fmt.Errorf("replacing dedupe state: %w", err)
+	}
+	return entries, nil
+}
+
+const (
+	// journalLabel marks the shards of one journal; its value is the
+	// journal name.
+	journalLabel = "nightjar.io/dedupe-journal"
+
+	// storeLabel marks the shards of one store; its value is the store
+	// name.
+	storeLabel = "nightjar.io/dedupe"
+
+	// generationAnnotation names the update that wrote a shard.
+	generationAnnotation = "nightjar.io/dedupe-generation"
+
+	// shardsAnnotation on shard 0 records how many shards the journal has.
+	shardsAnnotation = "nightjar.io/dedupe-shards"
+
+	// defaultShardSize keeps each ConfigMap well under the 1 MiB object
+	// size limit.
+	defaultShardSize = 900 * 1024
+)
+
+// ConfigMapStore splits the entries of each journal across ConfigMaps
+// <name>-<journal>-0 ... <name>-<journal>-N, each holding at most 900 KiB
+// under the data key <journal>.json, so no journal outgrows the 1 MiB
+// object size limit.
+//
+// Shard 0 is written last and names the shard count. It is updated with
+// the resourceVersion read, so replicas writing concurrently, e.g. an old
+// and a new leader during a failover, retry and merge instead of
+// overwriting each other. Every shard holds complete entries and is read
+// whatever update wrote it: a shard left by an interrupted update or
+// rewritten by a concurrent one adds or loses marks, and at worst a
+// notification is repeated.
+type ConfigMapStore struct {
+	client    kubernetes.Interface
+	namespace string
+	name      string
+	shardSize int
+}
+
+// NewConfigMapStore creates a ConfigMapStore for ConfigMaps named after
+// namespace/name. The ConfigMaps are created on the first update.
+func NewConfigMapStore(client kubernetes.Interface, namespace, name string) *ConfigMapStore {
+	return &ConfigMapStore{
+		client:    client,
+		namespace: namespace,
+		name:      name,
+		shardSize: defaultShardSize,
+	}
+}
+
+func (s *ConfigMapStore) shardName(journal string, i int) string {
+	return fmt.Sprintf("%s-%s-%d", s.name, journal, i)
+}
+
+// Update applies fn to name's entries and writes them back, retrying on
+// conflicting writes. Undecodable shards are replaced.
+func (s *ConfigMapStore) Update(ctx context.Context, name string, fn func(Entries) Entries) (Entries, error) {
+	retriable := func(err error) bool {
+		return apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err)
+	}
+
+	var entries Entries
+	err := retry.OnError(retry.DefaultRetry, retriable, func() error {
+		head, stored, err := s.load(ctx, name)
+		if err != nil {
+			return err
+		}
+		entries = fn(stored)
+		return s.save(ctx, name, head, entries)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("updating dedupe ConfigMaps %s/%s-%s: %w", s.namespace, s.name, name, err)
+	}
+	return entries, nil
+}
+
+// load reads the shards of journal. head is shard 0, or nil if the journal
+// has not been stored yet.
+func (s *ConfigMapStore) load(ctx context.Context, journal string) (*corev1.ConfigMap, Entries, error) {
+	configMaps := s.client.CoreV1().ConfigMaps(s.namespace)
+	entries := Entries{}
+	head, err := configMaps.Get(ctx, s.shardName(journal, 0), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, entries, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key := journal + ".json"
+	decodeShard(head.Data[key], entries)
+	count, err := strconv.Atoi(head.Annotations[shardsAnnotation])
+	if err != nil {
+		count = 1
+	}
+	for i := 1; i < count; i++ {
+		cm, err := configMaps.Get(ctx, s.shardName(journal, i), metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		decodeShard(cm.Data[key], entries)
+	}
+	return head, entries, nil
+}
+
+// save writes entries as shards of journal, shard 0 last, and removes the
+// shards no longer used. head is shard 0 as read by load.
+func (s *ConfigMapStore) save(ctx context.Context, journal string, head *corev1.ConfigMap, entries Entries) error {
+	shards, err := split(entries, s.shardSize)
+	if err != nil {
+		return err
+	}
+	key := journal + ".json"
+	generation := strconv.FormatInt(time.Now().UnixNano(), 10)
+	for i := len(shards) - 1; i >= 1; i-- {
+		if err := s.writeShard(ctx, journal, i, key, shards[i], generation); err != nil {
+			return err
+		}
+	}
+
+	configMaps := s.client.CoreV1().ConfigMaps(s.namespace)
+	annotations := map[string]string{
+		generationAnnotation: generation,
+		shardsAnnotation:     strconv.Itoa(len(shards)),
+	}
+	if head == nil {
+		_, err = configMaps.Create(ctx, s.newShard(journal, 0, key, shards[0], annotations), metav1.CreateOptions{})
+	} else {
+		head.Annotations = annotations
+		head.Data = map[string]string{key: shards[0]}
+		_, err = configMaps.Update(ctx, head, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return err
+	}
+
+	existing, err := configMaps.List(ctx, metav1.ListOptions{
+		LabelSelector: storeLabel + "=" + s.name + "," + journalLabel + "=" + journal,
+	})
+	if err != nil {
+		return fmt.Errorf("listing dedupe shards: %w", err)
+	}
+	prefix := s.name + "-" + journal + "-"
+	for _, cm := range existing.Items {
+		i, err := strconv.Atoi(strings.TrimPrefix(cm.Name, prefix))
+		if err != nil || i < len(shards) {
+			continue
+		}
+		err = configMaps.Delete(ctx, cm.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("deleting dedupe shard %s: %w", cm.Name, err)
+		}
+	}
+	return nil
+}
+
+func (s *ConfigMapStore) writeShard(ctx context.Context, journal string, i int, key, data, generation string) error {
+	configMaps := s.client.CoreV1().ConfigMaps(s.namespace)
+	annotations := map[string]string{generationAnnotation: generation}
+	cm, err := configMaps.Get(ctx, s.shardName(journal, i), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = configMaps.Create(ctx, s.newShard(journal, i, key, data, annotations), metav1.CreateOptions{})
+	} else if err == nil {
+		cm.Annotations = annotations
+		cm.Data = map[string]string{key: data}
+		_, err = configMaps.Update(ctx, cm, metav1.UpdateOptions{})
+	}
+	return err
+}
+
+func (s *ConfigMapStore) newShard(journal string, i int, key, data string, annotations map[string]string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      s.shardName(journal, i),
+			Namespace: s.namespace,
+			Labels: map[string]string{
+				storeLabel:                     s.name,
+				journalLabel:                   journal,
+				"app.kubernetes.io/managed-by": "nightjar",
+			},
+			Annotations: annotations,
+		},
+		Data: map[string]string{key: data},
+	}
+}
+
+// decodeShard adds the entries encoded in data to entries, keeping the
+// later time of a key in both. Undecodable data adds nothing.
+func decodeShard(data string, entries Entries) {
+	var shard Entries
+	if err := json.Unmarshal([]byte(data), &shard); err != nil {
+		return
+	}
+	for key, at := range shard {
+		if at.After(entries[key]) {
+			entries[key] = at
+		}
+	}
+}
+
+// split encodes entries as JSON objects of at most size bytes each, and
+// always returns at least one. An entry too large for a shard on its own
+// is dropped.
+func split(entries Entries, size int) ([]string, error) {
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var (
+		shards  []string
+		current = Entries{}
+		used    = 2 // {}
+	)
+	for _, key := range keys {
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := entries[key].MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+		n := len(k) + len(v) + 2 // : and ,
+		if n+2 > size {
+			continue
+		}
+		if used+n > size {
+			data, err := json.Marshal(current)
+			if err != nil {
+				return nil, err
+			}
+			shards = append(shards, string(data))
+			current, used = Entries{}, 2
+		}
+		current[key] = entries[key]
+		used += n
+	}
+	data, err := json.Marshal(current)
+	if err != nil {
+		return nil, err
+	}
+	return append(shards, string(data)), nil
+}
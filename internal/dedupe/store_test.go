@@ -0,0 +1,165 @@
+package dedupe
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func addEntries(add Entries) func(Entries) Entries {
+	return func(stored Entries) Entries {
+		for key, at := range add {
+			stored[key] = at
+		}
+		return stored
+	}
+}
+
+func TestFileStore_Update(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "state", "dedupe.json")
+	now := time.Now().UTC().Truncate(time.Second)
+
+	s := NewFileStore(path)
+	got, err := s.Update(ctx, "dispatcher", addEntries(Entries{"a": now}))
+	require.NoError(t, err)
+	assert.Equal(t, Entries{"a": now}, got)
+	_, err = s.Update(ctx, "correlator", addEntries(Entries{"b": now}))
+	require.NoError(t, err)
+
+	// A new process reads what the previous one wrote; journals are kept
+	// apart.
+	got, err = NewFileStore(path).Update(ctx, "dispatcher", addEntries(nil))
+	require.NoError(t, err)
+	assert.Len(t, got, 1)
+	assert.True(t, now.Equal(got["a"]))
+	_, err = os.Stat(path + ".tmp")
+	assert.True(t, os.IsNotExist(err), "temporary file is renamed into place")
+}
+
+func TestFileStore_ReplacesUnreadableFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dedupe.json")
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0o600))
+
+	got, err := NewFileStore(path).Update(context.Background(), "dispatcher", addEntries(Entries{"a": time.Now()}))
+	require.NoError(t, err)
+	assert.Len(t, got, 1)
+}
+
+func TestConfigMapStore_Update(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewSimpleClientset()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	s := NewConfigMapStore(client, "nightjar-system", "nightjar-dedupe")
+	_, err := s.Update(ctx, "dispatcher", addEntries(Entries{"a": now}))
+	require.NoError(t, err)
+	_, err = s.Update(ctx, "correlator", addEntries(Entries{"b": now}))
+	require.NoError(t, err)
+
+	// Journals are kept in ConfigMaps of their own.
+	cm, err := client.CoreV1().ConfigMaps("nightjar-system").Get(ctx, "nightjar-dedupe-dispatcher-0", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Contains(t, cm.Data, "dispatcher.json")
+	cm, err = client.CoreV1().ConfigMaps("nightjar-system").Get(ctx, "nightjar-dedupe-correlator-0", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Contains(t, cm.Data, "correlator.json")
+
+	got, err := NewConfigMapStore(client, "nightjar-system", "nightjar-dedupe").Update(ctx, "dispatcher", addEntries(nil))
+	require.NoError(t, err)
+	assert.Len(t, got, 1)
+	assert.True(t, now.Equal(got["a"]))
+}
+
+func TestConfigMapStore_ReplacesUndecodableEntries(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewSimpleClientset()
+	s := NewConfigMapStore(client, "nightjar-system", "nightjar-dedupe")
+	_, err := s.Update(ctx, "dispatcher", addEntries(Entries{"a": time.Now()}))
+	require.NoError(t, err)
+
+	cm, err := client.CoreV1().ConfigMaps("nightjar-system").Get(ctx, "nightjar-dedupe-dispatcher-0", metav1.GetOptions{})
+	require.NoError(t, err)
+	cm.Data["dispatcher.json"] = "not json"
+	_, err = client.CoreV1().ConfigMaps("nightjar-system").Update(ctx, cm, metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	got, err := s.Update(ctx, "dispatcher", addEntries(Entries{"b": time.Now()}))
+	require.NoError(t, err)
+	assert.Len(t, got, 1)
+	assert.Contains(t, got, "b")
+}
+
+func TestConfigMapStore_Shards(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewSimpleClientset()
+	s := NewConfigMapStore(client, "nightjar-system", "nightjar-dedupe")
+	s.shardSize = 200
+	now := time.Now().UTC().Truncate(time.Second)
+
+	add := Entries{}
+	for i := 0; i < 20; i++ {
+		add[fmt.Sprintf("c%d|shop/Deployment/api", i)] = now
+	}
+	_, err := s.Update(ctx, "dispatcher", addEntries(add))
+	require.NoError(t, err)
+
+	list, err := client.CoreV1().ConfigMaps("nightjar-system").List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Greater(t, len(list.Items), 1, "entries larger than a shard are split")
+	for _, cm := range list.Items {
+		assert.LessOrEqual(t, len(cm.Data["dispatcher.json"]), s.shardSize, cm.Name)
+	}
+
+	got, err := NewConfigMapStore(client, "nightjar-system", "nightjar-dedupe").Update(ctx, "dispatcher", addEntries(nil))
+	require.NoError(t, err)
+	assert.Len(t, got, 20)
+
+	// Fewer entries remove the shards no longer used.
+	_, err = s.Update(ctx, "dispatcher", func(Entries) Entries { return Entries{"a": now} })
+	require.NoError(t, err)
+	list, err = client.CoreV1().ConfigMaps("nightjar-system").List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	assert.Len(t, list.Items, 1)
+}
+
+func TestConfigMapStore_InterruptedUpdate(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewSimpleClientset()
+	s := NewConfigMapStore(client, "nightjar-system", "nightjar-dedupe")
+	s.shardSize = 200
+	now := time.Now().UTC().Truncate(time.Second)
+
+	add := Entries{}
+	for i := 0; i < 10; i++ {
+		add[fmt.Sprintf("c%d|shop/Deployment/api", i)] = now
+	}
+	_, err := s.Update(ctx, "dispatcher", addEntries(add))
+	require.NoError(t, err)
+
+	// Simulate an update that rewrote shard 1 but died before shard 0: its
+	// marks are read along with the rest.
+	require.NoError(t, s.writeShard(ctx, "dispatcher", 1, "dispatcher.json", `{"late":"`+now.Format(time.RFC3339)+`"}`, "newer"))
+
+	got, err := s.Update(ctx, "dispatcher", addEntries(nil))
+	require.NoError(t, err)
+	assert.Contains(t, got, "late")
+	assert.Contains(t, got, "c0|shop/Deployment/api")
+}
+
+func TestSplit_DropsOversizedEntry(t *testing.T) {
+	now := time.Now()
+	shards, err := split(Entries{"a": now, strings.Repeat("x", 300): now}, 100)
+	require.NoError(t, err)
+	require.Len(t, shards, 1)
+	assert.NotContains(t, shards[0], "xxx")
+}
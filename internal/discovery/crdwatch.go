@@ -0,0 +1,233 @@
+package discovery
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+)
+
+// crdGVR is the CustomResourceDefinition resource.
+var crdGVR = schema.GroupVersionResource{
+	Group:    "apiextensions.k8s.io",
+	Version:  "v1",
+	Resource: "customresourcedefinitions",
+}
+
+// removedGVR is a watched GVR that is no longer served at its version.
+type removedGVR struct {
+	gvr schema.GroupVersionResource
+
+	// replacement is the GVR now preferred for the same group and resource.
+	// Nil when the resource type was removed altogether.
+	replacement *schema.GroupVersionResource
+	informer    cache.SharedIndexInformer
+	queue       *gvrQueue
+}
+
+// startCRDWatch requests a rescan whenever a CRD is created, changed or
+// deleted, and returns once the CRD cache has synced. Falls back to periodic
+// rescans only if CRDs cannot be listed.
+func (e *Engine) startCRDWatch(ctx context.Context) {
+	if e.dynamicClient == nil {
+		return
+	}
+	if !e.crdsListable(ctx) {
+		e.logger.Warn("Cannot list CustomResourceDefinitions, new policy CRDs are only found by periodic rescans",
+			zap.Duration("rescan_interval", e.rescanInterval))
+		return
+	}
+
+	informer := dynamicinformer.NewFilteredDynamicInformer(
+		e.dynamicClient, crdGVR, "", 0, cache.Indexers{}, nil,
+	).Informer()
+
+	if _, err := informer.AddEventHandler(cache.ResourceEventHandlerDetailedFuncs{
+		AddFunc: func(obj interface{}, isInInitialList bool) {
+			// The initial list is covered by the scan that follows in Start.
+			if !isInInitialList {
+				e.requestRescan("added", obj)
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			if crdChanged(oldObj, newObj) {
+				e.requestRescan("updated", newObj)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			e.requestRescan("deleted", obj)
+		},
+	}); err != nil {
+		e.logger.Error("Failed to add CRD event handler", zap.Error(err))
+		return
+	}
+
+	stopCh := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-e.stopCh:
+		}
+		close(stopCh)
+	}()
+	go informer.Run(stopCh)
+	if !cache.WaitForCacheSync(stopCh, informer.HasSynced) {
+		return
+	}
+	e.logger.Info("Watching CustomResourceDefinitions for discovery")
+}
+
+// crdsListable reports whether CRDs can be listed, guarding against missing
+// RBAC and against fake clients that panic on unregistered resources.
+func (e *Engine) crdsListable(ctx context.Context) (ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			ok = false
+		}
+	}()
+	_, err := e.dynamicClient.Resource(crdGVR).List(ctx, metav1.ListOptions{Limit: 1})
+	return err == nil
+}
+
+// requestRescan schedules a rescan after the CRD settle delay. Requests made
+// while one is pending are coalesced.
+func (e *Engine) requestRescan(reason string, obj interface{}) {
+	name := ""
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		name = u.GetName()
+	}
+	e.logger.Debug("CRD changed, requesting rescan", zap.String("crd", name), zap.String("change", reason))
+
+	select {
+	case e.rescanCh <- struct{}{}:
+	default:
+	}
+}
+
+// crdChanged reports whether a CRD update can affect discovery: a spec
+// change (versions, served, storage), a policy annotation change, or a
+// status change such as becoming Established.
+func crdChanged(oldObj, newObj interface{}) bool {
+	oldCRD, ok1 := oldObj.(*unstructured.Unstructured)
+	newCRD, ok2 := newObj.(*unstructured.Unstructured)
+	if !ok1 || !ok2 {
+		return true
+	}
+	if oldCRD.GetGeneration() != newCRD.GetGeneration() {
+		return true
+	}
+	if oldCRD.GetAnnotations()[IsPolicyAnnotation] != newCRD.GetAnnotations()[IsPolicyAnnotation] {
+		return true
+	}
+	return !reflect.DeepEqual(oldCRD.Object["status"], newCRD.Object["status"])
+}
+
+// removeUnservedLocked tears down informers of watched GVRs that discovery no
+// longer serves at their version. served maps each served group/resource to
+// its preferred version. GVRs pinned by an enabled ConstraintProfile are kept
+// while their resource still exists. GVRs of failedGroups, whose discovery
+// failed, are kept whatever served says. Caller must hold e.mu.
+func (e *Engine) removeUnservedLocked(served map[schema.GroupResource]string, failedGroups map[string]bool) []removedGVR {
+	var removed []removedGVR
+	for gvr := range e.watchedGVRs {
+		if failedGroups[gvr.Group] {
+			continue
+		}
+		version, ok := served[gvr.GroupResource()]
+		if ok && (version == gvr.Version || e.pinnedByProfileLocked(gvr)) {
+			continue
+		}
+
+		r := removedGVR{gvr: gvr}
+		if ok {
+			replacement := schema.GroupVersionResource{Group: gvr.Group, Version: version, Resource: gvr.Resource}
+			if e.watchedGVRs[replacement] {
+				r.replacement = &replacement
+				r.informer = e.informers[replacement]
+				r.queue = e.queues[replacement]
+			}
+		}
+
+		for _, ps := range e.profiles {
+			if ps.gvr == gvr {
+				e.stopProfileInformerLocked(ps)
+			}
+		}
+		e.stopGVRInformerLocked(gvr)
+		removed = append(removed, r)
+	}
+	return removed
+}
+
+// pinnedByProfileLocked reports whether an enabled ConstraintProfile targets
+// exactly gvr. Caller must hold e.mu.
+func (e *Engine) pinnedByProfileLocked(gvr schema.GroupVersionResource) bool {
+	for _, ps := range e.profiles {
+		if ps.enabled && ps.gvr == gvr {
+			return true
+		}
+	}
+	return false
+}
+
+// cleanupRemoved removes constraints of torn-down GVRs. Constraints of a GVR
+// replaced by a new preferred version are removed only once the replacement
+// has re-parsed every object, so they are neither duplicated nor briefly
+// missing. Caller must NOT hold e.mu.
+func (e *Engine) cleanupRemoved(ctx context.Context, removed []removedGVR) {
+	for _, r := range removed {
+		if r.replacement == nil || r.informer == nil {
+			n := e.deleteBySource(r.gvr)
+			e.logger.Info("Resource type no longer served, stopped informer",
+				zap.String("gvr", r.gvr.String()),
+				zap.Int("removed_constraints", n),
+			)
+			continue
+		}
+
+		e.logger.Info("Preferred version changed, switching informer",
+			zap.String("from", r.gvr.String()),
+			zap.String("to", r.replacement.String()),
+		)
+		go e.finishVersionSwitch(ctx, r)
+	}
+}
+
+// finishVersionSwitch waits for the replacement informer to sync and its
+// queue to drain, then removes constraints still attributed to the old GVR
+// (objects deleted during the switch).
+func (e *Engine) finishVersionSwitch(ctx context.Context, r removedGVR) {
+	if !cache.WaitForCacheSync(ctx.Done(), r.informer.HasSynced) {
+		return
+	}
+
+	e.mu.RLock()
+	deadline := time.Now().Add(e.debounceLocked(*r.replacement) + time.Minute)
+	e.mu.RUnlock()
+
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for r.queue != nil && !r.queue.idle() && time.Now().Before(deadline) {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+
+	n := e.deleteBySource(r.gvr)
+	e.logger.Info("Version switch complete",
+		zap.String("from", r.gvr.String()),
+		zap.String("to", r.replacement.String()),
+		zap.Int("removed_stale_constraints", n),
+	)
+}
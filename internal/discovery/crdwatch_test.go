@@ -0,0 +1,261 @@
+package discovery
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+
+	v1alpha1 "github.com/nightjarctl/nightjar/api/v1alpha1"
+	"github.com/nightjarctl/nightjar/internal/adapters"
+	"github.com/nightjarctl/nightjar/internal/indexer"
+	internaltypes "github.com/nightjarctl/nightjar/internal/types"
+)
+
+var (
+	policiesV1beta1 = schema.GroupVersionResource{Group: "custom.io", Version: "v1beta1", Resource: "securitypolicies"}
+	policiesV1      = schema.GroupVersionResource{Group: "custom.io", Version: "v1", Resource: "securitypolicies"}
+)
+
+func (m *mockDiscoveryClient) setResources(resources []*metav1.APIResourceList) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.resources = resources
+}
+
+func servedPolicies(version string) []*metav1.APIResourceList {
+	return []*metav1.APIResourceList{{
+		GroupVersion: "custom.io/" + version,
+		APIResources: []metav1.APIResource{{Name: "securitypolicies"}},
+	}}
+}
+
+func newPolicy(version, name string, uid types.UID) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "custom.io/" + version,
+		"kind":       "SecurityPolicy",
+		"metadata":   map[string]interface{}{"name": name, "namespace": "default"},
+	}}
+	obj.SetUID(uid)
+	return obj
+}
+
+func newCRDWatchClient() *dynamicfake.FakeDynamicClient {
+	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		policiesV1beta1: "SecurityPolicyList",
+		policiesV1:      "SecurityPolicyList",
+		crdGVR:          "CustomResourceDefinitionList",
+	})
+}
+
+func sourcesOf(idx *indexer.Indexer) map[types.UID]schema.GroupVersionResource {
+	out := map[types.UID]schema.GroupVersionResource{}
+	for _, c := range idx.All() {
+		out[c.UID] = c.Source
+	}
+	return out
+}
+
+func TestScan_RemovedTypeTornDown(t *testing.T) {
+	mockDisc := newMockDiscovery(servedPolicies("v1"))
+	idx := indexer.New(nil)
+	engine := NewEngine(zap.NewNop(), mockDisc, newCRDWatchClient(), adapters.NewRegistry(), idx, 5*time.Minute)
+	t.Cleanup(engine.Stop)
+
+	ctx := context.Background()
+	require.NoError(t, engine.scan(ctx))
+	require.Equal(t, []schema.GroupVersionResource{policiesV1}, engine.WatchedGVRs())
+
+	idx.Upsert(internaltypes.Constraint{UID: "uid-1", Name: "p", Source: policiesV1})
+	idx.Upsert(internaltypes.Constraint{UID: "uid-2", Name: "other", Source: schema.GroupVersionResource{Version: "v1", Resource: "resourcequotas"}})
+
+	mockDisc.setResources(nil)
+	require.NoError(t, engine.scan(ctx))
+
+	assert.Empty(t, engine.WatchedGVRs())
+	engine.mu.RLock()
+	assert.Empty(t, engine.informers)
+	assert.Empty(t, engine.informerStops)
+	assert.Empty(t, engine.queues)
+	engine.mu.RUnlock()
+
+	sources := sourcesOf(idx)
+	assert.NotContains(t, sources, types.UID("uid-1"), "constraints of the removed type are deleted")
+	assert.Contains(t, sources, types.UID("uid-2"), "constraints of other types are kept")
+}
+
+func TestScan_PartialErrorKeepsWatchedTypes(t *testing.T) {
+	mockDisc := newMockDiscovery(servedPolicies("v1"))
+	idx := indexer.New(nil)
+	engine := NewEngine(zap.NewNop(), mockDisc, newCRDWatchClient(), adapters.NewRegistry(), idx, 5*time.Minute)
+	t.Cleanup(engine.Stop)
+
+	ctx := context.Background()
+	require.NoError(t, engine.scan(ctx))
+	idx.Upsert(internaltypes.Constraint{UID: "uid-1", Name: "p", Source: policiesV1})
+
+	// A failing group makes the result incomplete; missing types must not
+	// be mistaken for removed ones.
+	mockDisc.setResources(nil)
+	mockDisc.setScanErr(assert.AnError)
+	require.NoError(t, engine.scan(ctx))
+
+	assert.Equal(t, []schema.GroupVersionResource{policiesV1}, engine.WatchedGVRs())
+	assert.Equal(t, 1, idx.Count())
+}
+
+func TestScan_FailedGroupKeepsOnlyItsTypes(t *testing.T) {
+	metrics := schema.GroupVersionResource{Group: "metrics.example.io", Version: "v1", Resource: "podpolicies"}
+	resources := append(servedPolicies("v1"), &metav1.APIResourceList{
+		GroupVersion: "metrics.example.io/v1",
+		APIResources: []metav1.APIResource{{Name: "podpolicies"}},
+	})
+	mockDisc := newMockDiscovery(resources)
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		policiesV1: "SecurityPolicyList",
+		metrics:    "PodPolicyList",
+		crdGVR:     "CustomResourceDefinitionList",
+	})
+	idx := indexer.New(nil)
+	engine := NewEngine(zap.NewNop(), mockDisc, client, adapters.NewRegistry(), idx, 5*time.Minute)
+	t.Cleanup(engine.Stop)
+
+	ctx := context.Background()
+	require.NoError(t, engine.scan(ctx))
+	require.ElementsMatch(t, []schema.GroupVersionResource{policiesV1, metrics}, engine.WatchedGVRs())
+
+	// The aggregated API of metrics.example.io is down while the
+	// custom.io CRD is deleted: only the type whose group was discovered
+	// is torn down.
+	mockDisc.setResources(nil)
+	mockDisc.setScanErr(&discovery.ErrGroupDiscoveryFailed{Groups: map[schema.GroupVersion]error{
+		metrics.GroupVersion(): assert.AnError,
+	}})
+	require.NoError(t, engine.scan(ctx))
+
+	assert.Equal(t, []schema.GroupVersionResource{metrics}, engine.WatchedGVRs())
+}
+
+func TestScan_PreferredVersionSwitch(t *testing.T) {
+	ctx := context.Background()
+	dynClient := newCRDWatchClient()
+	for _, obj := range []struct {
+		gvr schema.GroupVersionResource
+		u   *unstructured.Unstructured
+	}{
+		{policiesV1beta1, newPolicy("v1beta1", "kept", "uid-1")},
+		{policiesV1, newPolicy("v1", "kept", "uid-1")},
+		// Only present in the old cache: deleted while the switch is in flight.
+		{policiesV1beta1, newPolicy("v1beta1", "gone", "uid-2")},
+	} {
+		_, err := dynClient.Resource(obj.gvr).Namespace("default").Create(ctx, obj.u, metav1.CreateOptions{})
+		require.NoError(t, err)
+	}
+
+	mockDisc := newMockDiscovery(servedPolicies("v1beta1"))
+	idx := indexer.New(nil)
+	engine := NewEngine(zap.NewNop(), mockDisc, dynClient, adapters.NewRegistry(), idx, 5*time.Minute)
+	t.Cleanup(engine.Stop)
+
+	require.NoError(t, engine.scan(ctx))
+	require.Eventually(t, func() bool { return idx.Count() == 2 }, 5*time.Second, 10*time.Millisecond)
+
+	mockDisc.setResources(servedPolicies("v1"))
+	require.NoError(t, engine.scan(ctx))
+	assert.Equal(t, []schema.GroupVersionResource{policiesV1}, engine.WatchedGVRs())
+
+	require.Eventually(t, func() bool {
+		sources := sourcesOf(idx)
+		return len(sources) == 1 && sources["uid-1"] == policiesV1
+	}, 5*time.Second, 10*time.Millisecond, "constraint re-parsed at the new version, stale one removed")
+}
+
+func TestScan_ProfilePinsNonPreferredVersion(t *testing.T) {
+	mockDisc := newMockDiscovery(servedPolicies("v1beta1"))
+	idx := indexer.New(nil)
+	engine := NewEngine(zap.NewNop(), mockDisc, newCRDWatchClient(), adapters.NewRegistry(), idx, 5*time.Minute)
+	t.Cleanup(engine.Stop)
+
+	require.NoError(t, engine.RegisterProfile(&v1alpha1.ConstraintProfile{
+		ObjectMeta: metav1.ObjectMeta{Name: "pinned"},
+		Spec: v1alpha1.ConstraintProfileSpec{
+			GVR:     v1alpha1.GVRReference{Group: "custom.io", Version: "v1beta1", Resource: "securitypolicies"},
+			Adapter: "generic",
+			Enabled: true,
+		},
+	}))
+
+	mockDisc.setResources(servedPolicies("v1"))
+	require.NoError(t, engine.scan(context.Background()))
+
+	watched := engine.WatchedGVRs()
+	assert.Contains(t, watched, policiesV1beta1, "profile-pinned version is kept")
+	assert.Contains(t, watched, policiesV1)
+}
+
+func TestStart_CRDEventTriggersRescan(t *testing.T) {
+	mockDisc := newMockDiscovery(nil)
+	dynClient := newCRDWatchClient()
+	engine := NewEngine(zap.NewNop(), mockDisc, dynClient, adapters.NewRegistry(), indexer.New(nil), time.Hour)
+	engine.crdSettleDelay = 10 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		cancel()
+		engine.Stop()
+	})
+	require.NoError(t, engine.Start(ctx))
+	assert.Empty(t, engine.WatchedGVRs())
+
+	crd := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apiextensions.k8s.io/v1",
+		"kind":       "CustomResourceDefinition",
+		"metadata":   map[string]interface{}{"name": "securitypolicies.custom.io"},
+	}}
+
+	// Created CRD: picked up without waiting for the hourly rescan.
+	mockDisc.setResources(servedPolicies("v1"))
+	_, err := dynClient.Resource(crdGVR).Create(ctx, crd, metav1.CreateOptions{})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return len(engine.WatchedGVRs()) == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	// Deleted CRD: informer torn down.
+	mockDisc.setResources(nil)
+	require.NoError(t, dynClient.Resource(crdGVR).Delete(ctx, crd.GetName(), metav1.DeleteOptions{}))
+	require.Eventually(t, func() bool { return len(engine.WatchedGVRs()) == 0 }, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestCRDChanged(t *testing.T) {
+	base := func() *unstructured.Unstructured {
+		u := &unstructured.Unstructured{Object: map[string]interface{}{}}
+		u.SetGeneration(1)
+		u.SetResourceVersion("1")
+		return u
+	}
+
+	resync := base()
+	resync.SetResourceVersion("2")
+	assert.False(t, crdChanged(base(), resync), "metadata-only change")
+
+	spec := base()
+	spec.SetGeneration(2)
+	assert.True(t, crdChanged(base(), spec), "spec change")
+
+	annotated := base()
+	annotated.SetAnnotations(map[string]string{IsPolicyAnnotation: "true"})
+	assert.True(t, crdChanged(base(), annotated), "policy annotation change")
+
+	established := base()
+	established.Object["status"] = map[string]interface{}{"storedVersions": []interface{}{"v1"}}
+	assert.True(t, crdChanged(base(), established), "status change")
+}
@@ -0,0 +1,225 @@
+package discovery
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	v1alpha1 "github.com/nightjarctl/nightjar/api/v1alpha1"
+)
+
+// Reason names the rule behind a discovery decision.
+type Reason string
+
+const (
+	// ReasonConstraintProfile: an enabled ConstraintProfile registers the type.
+	ReasonConstraintProfile Reason = "ConstraintProfile"
+	// ReasonProfileDisabled: a ConstraintProfile with enabled=false excludes it.
+	ReasonProfileDisabled Reason = "ProfileDisabled"
+	// ReasonPolicyAnnotation: the CRD carries nightjar.io/is-policy: "true".
+	ReasonPolicyAnnotation Reason = "PolicyAnnotation"
+	// ReasonAdapterRegistered: a registered adapter handles the type.
+	ReasonAdapterRegistered Reason = "AdapterRegistered"
+	// ReasonNativeConstraint: a built-in Kubernetes constraint (quotas, limit ranges).
+	ReasonNativeConstraint Reason = "NativeConstraint"
+	// ReasonPolicyGroup: the API group is a known policy group.
+	ReasonPolicyGroup Reason = "PolicyGroup"
+	// ReasonNameHint: the resource name contains a policy name hint.
+	ReasonNameHint Reason = "NameHint"
+	// ReasonNoMatch: no rule matched, the type is not watched.
+	ReasonNoMatch Reason = "NoMatch"
+)
+
+// confidence scores each reason: explicit configuration is certain,
+// name heuristics are the weakest signal.
+var confidence = map[Reason]int{
+	ReasonConstraintProfile: 100,
+	ReasonProfileDisabled:   100,
+	ReasonPolicyAnnotation:  100,
+	ReasonAdapterRegistered: 95,
+	ReasonNativeConstraint:  95,
+	ReasonPolicyGroup:       80,
+	ReasonNameHint:          50,
+	ReasonNoMatch:           70,
+}
+
+// Decision records whether a GVR is watched and why.
+type Decision struct {
+	GVR        schema.GroupVersionResource
+	Watched    bool
+	Reason     Reason
+	Message    string
+	Confidence int
+}
+
+func newDecision(gvr schema.GroupVersionResource, watched bool, reason Reason, format string, args ...interface{}) Decision {
+	return Decision{
+		GVR:        gvr,
+		Watched:    watched,
+		Reason:     reason,
+		Message:    fmt.Sprintf(format, args...),
+		Confidence: confidence[reason],
+	}
+}
+
+// parseErrorStats counts failed parses of one GVR.
+type parseErrorStats struct {
+	count   int64
+	lastErr string
+	lastAt  time.Time
+}
+
+// SetDryRun makes the engine classify resource types without starting
+// informers, so Status reports what would be watched. Call before Start.
+func (e *Engine) SetDryRun(enabled bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.dryRun = enabled
+}
+
+// classify decides whether gvr is constraint-like. Explicit configuration is
+// checked before heuristics so the strongest reason is reported.
+func (e *Engine) classify(gvr schema.GroupVersionResource, resourceName string) Decision {
+	// Snapshot all mu-protected state under a single read lock for consistency.
+	e.mu.RLock()
+	groups := e.policyGroups
+	hints := e.nameHints
+	var profileName string
+	for name, ps := range e.profiles {
+		if ps.enabled && ps.gvr == gvr {
+			profileName = name
+			break
+		}
+	}
+	annotated := e.annotatedCRDs[gvr]
+	e.mu.RUnlock()
+
+	// ConstraintProfile CRDs that register additional types
+	if profileName != "" {
+		return newDecision(gvr, true, ReasonConstraintProfile, "registered by ConstraintProfile %s", profileName)
+	}
+
+	// CRD annotation override
+	if annotated {
+		return newDecision(gvr, true, ReasonPolicyAnnotation, "CRD annotated %s", IsPolicyAnnotation)
+	}
+
+	// Does the adapter registry already handle this GVR?
+	if adapter := e.registry.ForGVR(gvr); adapter != nil {
+		return newDecision(gvr, true, ReasonAdapterRegistered, "handled by adapter %s", adapter.Name())
+	}
+
+	// Native Kubernetes constraint resources
+	if gvr.Group == "" {
+		switch resourceName {
+		case "resourcequotas", "limitranges":
+			return newDecision(gvr, true, ReasonNativeConstraint, "built-in constraint resource")
+		}
+	}
+
+	// Known policy group
+	if groups[gvr.Group] {
+		return newDecision(gvr, true, ReasonPolicyGroup, "group %s is a known policy group", gvr.Group)
+	}
+
+	// Heuristic — resource name contains policy-related substrings
+	lower := strings.ToLower(resourceName)
+	for _, hint := range hints {
+		if strings.Contains(lower, hint) {
+			return newDecision(gvr, true, ReasonNameHint, "resource name contains %q", hint)
+		}
+	}
+
+	return newDecision(gvr, false, ReasonNoMatch, "no discovery rule matched")
+}
+
+// recordParseError counts a failed parse of an object of gvr.
+func (e *Engine) recordParseError(gvr schema.GroupVersionResource, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	stats, ok := e.parseErrors[gvr]
+	if !ok {
+		stats = &parseErrorStats{}
+		e.parseErrors[gvr] = stats
+	}
+	stats.count++
+	stats.lastErr = err.Error()
+	stats.lastAt = time.Now()
+}
+
+// Status reports every discovery decision together with parse error counts,
+// in the shape of the DiscoveryStatus resource. Times are truncated to the
+// second, the precision they are stored with.
+func (e *Engine) Status() v1alpha1.DiscoveryStatusStatus {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	status := v1alpha1.DiscoveryStatusStatus{
+		DryRun:           e.dryRun,
+		LastScanComplete: e.lastScanComplete,
+	}
+	if !e.lastScan.IsZero() {
+		t := metav1.NewTime(e.lastScan.Truncate(time.Second))
+		status.LastScanTime = &t
+	}
+
+	for gvr, d := range e.decisions {
+		entry := v1alpha1.DiscoveryDecision{
+			Group:      gvr.Group,
+			Version:    gvr.Version,
+			Resource:   gvr.Resource,
+			Watched:    d.Watched,
+			Reason:     string(d.Reason),
+			Message:    d.Message,
+			Confidence: d.Confidence,
+		}
+		if stats, ok := e.parseErrors[gvr]; ok {
+			entry.ParseErrors = stats.count
+			entry.LastParseError = stats.lastErr
+			t := metav1.NewTime(stats.lastAt.Truncate(time.Second))
+			entry.LastParseErrorTime = &t
+		}
+		if d.Watched {
+			status.WatchedCount++
+		}
+		status.Resources = append(status.Resources, entry)
+	}
+	for _, stats := range e.parseErrors {
+		status.ParseErrorCount += stats.count
+	}
+
+	sort.Slice(status.Resources, func(i, j int) bool {
+		a, b := status.Resources[i], status.Resources[j]
+		if a.Group != b.Group {
+			return a.Group < b.Group
+		}
+		if a.Resource != b.Resource {
+			return a.Resource < b.Resource
+		}
+		return a.Version < b.Version
+	})
+	return status
+}
+
+// recordDecisionsLocked stores the decisions of a scan. A complete scan
+// replaces all decisions except those ConstraintProfiles made for types
+// discovery does not serve; a partial scan only updates the types it saw. Caller must hold
+// e.mu.
+func (e *Engine) recordDecisionsLocked(decisions map[schema.GroupVersionResource]Decision, complete bool) {
+	if complete {
+		for gvr, d := range e.decisions {
+			if _, seen := decisions[gvr]; !seen && (d.Reason == ReasonConstraintProfile || d.Reason == ReasonProfileDisabled) {
+				decisions[gvr] = d
+			}
+		}
+		e.decisions = decisions
+		return
+	}
+	for gvr, d := range decisions {
+		e.decisions[gvr] = d
+	}
+}
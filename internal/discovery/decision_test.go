@@ -0,0 +1,220 @@
+package discovery
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	v1alpha1 "github.com/nightjarctl/nightjar/api/v1alpha1"
+	"github.com/nightjarctl/nightjar/internal/adapters"
+	"github.com/nightjarctl/nightjar/internal/adapters/networkpolicy"
+	"github.com/nightjarctl/nightjar/internal/indexer"
+)
+
+func TestClassify(t *testing.T) {
+	registry := adapters.NewRegistry()
+	require.NoError(t, registry.Register(networkpolicy.New()))
+	engine := NewEngine(zap.NewNop(), nil, nil, registry, indexer.New(nil), 5*time.Minute)
+
+	annotatedGVR := schema.GroupVersionResource{Group: "acme.io", Version: "v1", Resource: "guards"}
+	engine.annotatedCRDs[annotatedGVR] = true
+	profileGVR := schema.GroupVersionResource{Group: "kyverno.io", Version: "v1", Resource: "clusterpolicies"}
+	require.NoError(t, engine.RegisterProfile(&v1alpha1.ConstraintProfile{
+		ObjectMeta: metav1.ObjectMeta{Name: "kyverno-tuning"},
+		Spec: v1alpha1.ConstraintProfileSpec{
+			GVR:     v1alpha1.GVRReference{Group: "kyverno.io", Version: "v1", Resource: "clusterpolicies"},
+			Adapter: "generic",
+			Enabled: true,
+		},
+	}))
+
+	tests := []struct {
+		name       string
+		gvr        schema.GroupVersionResource
+		watched    bool
+		reason     Reason
+		message    string
+		confidence int
+	}{
+		{
+			name:    "profile wins over policy group",
+			gvr:     profileGVR,
+			watched: true, reason: ReasonConstraintProfile, message: "kyverno-tuning", confidence: 100,
+		},
+		{
+			name:    "annotation",
+			gvr:     annotatedGVR,
+			watched: true, reason: ReasonPolicyAnnotation, message: IsPolicyAnnotation, confidence: 100,
+		},
+		{
+			name:    "registered adapter",
+			gvr:     schema.GroupVersionResource{Group: "networking.k8s.io", Version: "v1", Resource: "networkpolicies"},
+			watched: true, reason: ReasonAdapterRegistered, message: "networkpolicy", confidence: 95,
+		},
+		{
+			name:    "native constraint",
+			gvr:     schema.GroupVersionResource{Version: "v1", Resource: "limitranges"},
+			watched: true, reason: ReasonNativeConstraint, confidence: 95,
+		},
+		{
+			name:    "policy group",
+			gvr:     schema.GroupVersionResource{Group: "cilium.io", Version: "v2", Resource: "ciliumendpoints"},
+			watched: true, reason: ReasonPolicyGroup, message: "cilium.io", confidence: 80,
+		},
+		{
+			name:    "name hint",
+			gvr:     schema.GroupVersionResource{Group: "custom.io", Version: "v1", Resource: "egressrules"},
+			watched: true, reason: ReasonNameHint, message: `"rule"`, confidence: 50,
+		},
+		{
+			name:    "no match",
+			gvr:     schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"},
+			watched: false, reason: ReasonNoMatch, confidence: 70,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := engine.classify(tt.gvr, tt.gvr.Resource)
+			assert.Equal(t, tt.gvr, d.GVR)
+			assert.Equal(t, tt.watched, d.Watched)
+			assert.Equal(t, tt.reason, d.Reason)
+			assert.Equal(t, tt.confidence, d.Confidence)
+			assert.NotEmpty(t, d.Message)
+			if tt.message != "" {
+				assert.Contains(t, d.Message, tt.message)
+			}
+		})
+	}
+}
+
+func TestScan_RecordsDecisions(t *testing.T) {
+	mockDisc := newMockDiscovery([]*metav1.APIResourceList{
+		{GroupVersion: "custom.io/v1", APIResources: []metav1.APIResource{{Name: "securitypolicies"}}},
+		{GroupVersion: "apps/v1", APIResources: []metav1.APIResource{{Name: "deployments"}, {Name: "deployments/status"}}},
+	})
+	engine := NewEngine(zap.NewNop(), mockDisc, newCRDWatchClient(), adapters.NewRegistry(), indexer.New(nil), 5*time.Minute)
+	t.Cleanup(engine.Stop)
+
+	require.NoError(t, engine.scan(context.Background()))
+
+	status := engine.Status()
+	assert.False(t, status.DryRun)
+	assert.True(t, status.LastScanComplete)
+	require.NotNil(t, status.LastScanTime)
+	assert.Equal(t, 1, status.WatchedCount)
+	require.Len(t, status.Resources, 2, "sub-resources are not recorded")
+
+	// Sorted by group: "apps" before "custom.io".
+	assert.Equal(t, "deployments", status.Resources[0].Resource)
+	assert.False(t, status.Resources[0].Watched)
+	assert.Equal(t, string(ReasonNoMatch), status.Resources[0].Reason)
+	assert.Equal(t, "securitypolicies", status.Resources[1].Resource)
+	assert.True(t, status.Resources[1].Watched)
+	assert.Equal(t, string(ReasonNameHint), status.Resources[1].Reason)
+}
+
+func TestScan_DisabledProfileDecision(t *testing.T) {
+	mockDisc := newMockDiscovery(servedPolicies("v1"))
+	engine := NewEngine(zap.NewNop(), mockDisc, newCRDWatchClient(), adapters.NewRegistry(), indexer.New(nil), 5*time.Minute)
+	t.Cleanup(engine.Stop)
+
+	require.NoError(t, engine.RegisterProfile(&v1alpha1.ConstraintProfile{
+		ObjectMeta: metav1.ObjectMeta{Name: "off"},
+		Spec: v1alpha1.ConstraintProfileSpec{
+			GVR:     v1alpha1.GVRReference{Group: "custom.io", Version: "v1", Resource: "securitypolicies"},
+			Adapter: "generic",
+		},
+	}))
+	require.NoError(t, engine.scan(context.Background()))
+
+	assert.Empty(t, engine.WatchedGVRs())
+	status := engine.Status()
+	require.Len(t, status.Resources, 1)
+	assert.False(t, status.Resources[0].Watched)
+	assert.Equal(t, string(ReasonProfileDisabled), status.Resources[0].Reason)
+	assert.Contains(t, status.Resources[0].Message, "off")
+}
+
+func TestScan_PartialResultKeepsDecisions(t *testing.T) {
+	mockDisc := newMockDiscovery(servedPolicies("v1"))
+	engine := NewEngine(zap.NewNop(), mockDisc, newCRDWatchClient(), adapters.NewRegistry(), indexer.New(nil), 5*time.Minute)
+	t.Cleanup(engine.Stop)
+
+	ctx := context.Background()
+	require.NoError(t, engine.scan(ctx))
+
+	mockDisc.setResources(nil)
+	mockDisc.setScanErr(assert.AnError)
+	require.NoError(t, engine.scan(ctx))
+
+	status := engine.Status()
+	assert.False(t, status.LastScanComplete)
+	require.Len(t, status.Resources, 1)
+	assert.Equal(t, "securitypolicies", status.Resources[0].Resource)
+}
+
+func TestScan_DryRun(t *testing.T) {
+	mockDisc := newMockDiscovery(servedPolicies("v1"))
+	engine := NewEngine(zap.NewNop(), mockDisc, newCRDWatchClient(), adapters.NewRegistry(), indexer.New(nil), 5*time.Minute)
+	engine.SetDryRun(true)
+	t.Cleanup(engine.Stop)
+
+	require.NoError(t, engine.scan(context.Background()))
+	require.NoError(t, engine.RegisterProfile(&v1alpha1.ConstraintProfile{
+		ObjectMeta: metav1.ObjectMeta{Name: "rules"},
+		Spec: v1alpha1.ConstraintProfileSpec{
+			GVR:     v1alpha1.GVRReference{Group: "custom.io", Version: "v1", Resource: "rules"},
+			Adapter: "generic",
+			Enabled: true,
+		},
+	}))
+
+	assert.Empty(t, engine.WatchedGVRs(), "dry run starts no informers")
+	engine.mu.RLock()
+	assert.Empty(t, engine.informers)
+	assert.Empty(t, engine.queues)
+	engine.mu.RUnlock()
+
+	status := engine.Status()
+	assert.True(t, status.DryRun)
+	assert.Equal(t, 2, status.WatchedCount, "decisions report what would be watched")
+}
+
+func TestStatus_ParseErrors(t *testing.T) {
+	gvr := schema.GroupVersionResource{Group: "queue.test", Version: "v1", Resource: "broken"}
+	adapter := &countingAdapter{gvr: gvr, failures: 2}
+	registry := adapters.NewRegistry()
+	require.NoError(t, registry.Register(adapter))
+	engine := NewEngine(zap.NewNop(), nil, nil, registry, indexer.New(nil), 5*time.Minute)
+
+	d := engine.classify(gvr, gvr.Resource)
+	engine.mu.Lock()
+	engine.decisions[gvr] = d
+	engine.mu.Unlock()
+
+	ctx := context.Background()
+	obj := queuedObject("w", "uid-w", "rev-1")
+	require.Error(t, engine.upsertObject(ctx, gvr, obj))
+	require.Error(t, engine.upsertObject(ctx, gvr, obj))
+	require.NoError(t, engine.upsertObject(ctx, gvr, obj))
+
+	status := engine.Status()
+	assert.Equal(t, int64(2), status.ParseErrorCount)
+	require.Len(t, status.Resources, 1)
+	assert.Equal(t, int64(2), status.Resources[0].ParseErrors)
+	assert.Equal(t, "transient failure", status.Resources[0].LastParseError)
+	assert.NotNil(t, status.Resources[0].LastParseErrorTime)
+
+	// Parse errors are dropped with the GVR's informer.
+	engine.mu.Lock()
+	engine.stopGVRInformerLocked(gvr)
+	engine.mu.Unlock()
+	assert.Zero(t, engine.Status().ParseErrorCount)
+}
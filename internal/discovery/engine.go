@@ -2,6 +2,8 @@ package discovery
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"strings"
 	"sync"
 	"time"
@@ -10,6 +12,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8stypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/dynamic/dynamicinformer"
@@ -19,6 +22,7 @@ import (
 	"github.com/nightjarctl/nightjar/internal/adapters"
 	"github.com/nightjarctl/nightjar/internal/adapters/generic"
 	"github.com/nightjarctl/nightjar/internal/indexer"
+	"github.com/nightjarctl/nightjar/internal/scope"
 	"github.com/nightjarctl/nightjar/internal/types"
 )
 
@@ -54,6 +58,7 @@ type profileState struct {
 	adapter    string
 	fieldPaths *v1alpha1.FieldPaths
 	severity   string
+	debounce   *time.Duration // nil uses the engine default
 	enabled    bool
 	stopCh     chan struct{} // per-profile stop channel for informer lifecycle
 }
@@ -68,15 +73,28 @@ type Engine struct {
 	indexer         *indexer.Indexer
 	genericAdapter  *generic.Adapter
 
-	mu              sync.RWMutex
-	watchedGVRs     map[schema.GroupVersionResource]bool
-	informerFactory dynamicinformer.DynamicSharedInformerFactory
-	stopCh          chan struct{}
-	stopOnce        sync.Once
-	ctx             context.Context // parent context from Start(), used by profile informers
-	informers       map[schema.GroupVersionResource]cache.SharedIndexInformer
+	mu            sync.RWMutex
+	watchedGVRs   map[schema.GroupVersionResource]bool
+	informerStops map[schema.GroupVersionResource]chan struct{} // stop channels of scan-started informers
+	stopCh        chan struct{}
+	stopOnce      sync.Once
+	ctx           context.Context // parent context from Start(), used by profile informers
+	informers     map[schema.GroupVersionResource]cache.SharedIndexInformer
+	queues        map[schema.GroupVersionResource]*gvrQueue
+
+	// handlerSynced reports whether an informer's initial list has reached
+	// its event handler; synced latches GVRs whose initial list has also
+	// been parsed into the index.
+	handlerSynced map[schema.GroupVersionResource]cache.InformerSynced
+	synced        map[schema.GroupVersionResource]bool
 
 	rescanInterval time.Duration
+	queueOpts      QueueOptions
+
+	// rescanCh requests an early rescan, e.g. after a CRD changed.
+	// crdSettleDelay gives the API server time to update discovery first.
+	rescanCh       chan struct{}
+	crdSettleDelay time.Duration
 
 	// Configurable heuristics (initialized from defaults, augmented by config).
 	policyGroups map[string]bool
@@ -86,6 +104,25 @@ type Engine struct {
 	profiles        map[string]*profileState
 	annotatedCRDs   map[schema.GroupVersionResource]bool // cached CRD annotation results
 	checkAnnotation bool                                 // whether to check CRD annotations during scan
+
+	// Explainability state (protected by mu).
+	decisions        map[schema.GroupVersionResource]Decision
+	parseErrors      map[schema.GroupVersionResource]*parseErrorStats
+	lastScan         time.Time
+	lastScanComplete bool
+	dryRun           bool // classify only, never start informers
+
+	// nsScope limits which namespaces' objects are indexed; nil allows all.
+	nsScope *scope.Scope
+
+	// scopedCache keeps only a stub of each out-of-scope object in the
+	// informer caches; see SetScopedCache.
+	scopedCache bool
+
+	// produced records the constraint UIDs each source object produced,
+	// so updates and deletes remove constraints without re-parsing.
+	producedMu sync.Mutex
+	produced   map[objectKey][]k8stypes.UID
 }
 
 // NewEngine creates a new discovery engine.
@@ -114,14 +151,42 @@ func NewEngine(
 		genericAdapter:  generic.New(),
 		watchedGVRs:     make(map[schema.GroupVersionResource]bool),
 		informers:       make(map[schema.GroupVersionResource]cache.SharedIndexInformer),
+		queues:          make(map[schema.GroupVersionResource]*gvrQueue),
+		handlerSynced:   make(map[schema.GroupVersionResource]cache.InformerSynced),
+		synced:          make(map[schema.GroupVersionResource]bool),
+		informerStops:   make(map[schema.GroupVersionResource]chan struct{}),
 		stopCh:          make(chan struct{}),
+		rescanCh:        make(chan struct{}, 1),
+		crdSettleDelay:  2 * time.Second,
 		rescanInterval:  rescanInterval,
+		queueOpts:       DefaultQueueOptions(),
 		policyGroups:    groups,
 		nameHints:       hints,
 		profiles:        make(map[string]*profileState),
 		annotatedCRDs:   make(map[schema.GroupVersionResource]bool),
 		checkAnnotation: true,
+		decisions:       make(map[schema.GroupVersionResource]Decision),
+		parseErrors:     make(map[schema.GroupVersionResource]*parseErrorStats),
+		produced:        make(map[objectKey][]k8stypes.UID),
+	}
+}
+
+// SetQueueOptions configures the per-GVR parse queues. Zero fields keep their
+// defaults. Must be called before Start.
+func (e *Engine) SetQueueOptions(opts QueueOptions) {
+	defaults := DefaultQueueOptions()
+	if opts.RateLimit == 0 {
+		opts.RateLimit = defaults.RateLimit
+	}
+	if opts.Burst == 0 {
+		opts.Burst = defaults.Burst
 	}
+	if opts.MaxRetries == 0 {
+		opts.MaxRetries = defaults.MaxRetries
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.queueOpts = opts
 }
 
 // SetAdditionalGroups adds extra API groups to the policy detection heuristic.
@@ -147,20 +212,39 @@ func (e *Engine) SetCheckAnnotation(enabled bool) {
 	e.checkAnnotation = enabled
 }
 
-// Start begins the discovery loop. It performs an initial scan, then
-// rescans periodically. Call with a cancellable context.
+// Start begins the discovery loop. It performs an initial scan, then rescans
+// whenever a CustomResourceDefinition changes and, as a fallback, periodically.
+// Call with a cancellable context.
 func (e *Engine) Start(ctx context.Context) error {
 	e.logger.Info("Starting discovery engine", zap.Duration("rescan_interval", e.rescanInterval))
 
 	// Store parent context for profile informer event handlers.
 	e.ctx = ctx
 
+	// Wait for the namespace scope, then re-queue the in-scope objects that
+	// profile informers started before it synced cached as stubs, and drop
+	// what they indexed from out-of-scope namespaces.
+	e.mu.RLock()
+	nsScope := e.nsScope
+	e.mu.RUnlock()
+	if !nsScope.WaitForSync(ctx) {
+		return nil
+	}
+	if e.scopedCacheEnabled() {
+		e.requeueNamespaces(nsScope.Namespaces())
+	}
+	e.pruneOutOfScope()
+
+	// Rescan within seconds of CRDs being installed, updated or removed.
+	// Started first so CRDs created during the initial scan are not missed.
+	e.startCRDWatch(ctx)
+
 	// Initial scan
 	if err := e.scan(ctx); err != nil {
 		return err
 	}
 
-	// Periodic rescan for newly installed CRDs
+	// Periodic rescan catches aggregated APIs and missed CRD events
 	go func() {
 		ticker := time.NewTicker(e.rescanInterval)
 		defer ticker.Stop()
@@ -172,6 +256,20 @@ func (e *Engine) Start(ctx context.Context) error {
 				if err := e.scan(ctx); err != nil {
 					e.logger.Error("Periodic rescan failed", zap.Error(err))
 				}
+			case <-e.rescanCh:
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(e.crdSettleDelay):
+				}
+				// Requests that arrived while settling are served by this scan.
+				select {
+				case <-e.rescanCh:
+				default:
+				}
+				if err := e.scan(ctx); err != nil {
+					e.logger.Error("CRD-triggered rescan failed", zap.Error(err))
+				}
 			}
 		}
 	}()
@@ -186,11 +284,33 @@ func (e *Engine) scan(ctx context.Context) error {
 	lists, err := e.discoveryClient.ServerPreferredResources()
 	if err != nil {
 		// ServerPreferredResources can return partial results with an error.
-		// Log the error but continue with what we got.
+		// Log the error but continue with what we got. Watched GVRs are only
+		// torn down if their group was discovered: an aggregated APIService
+		// that is down must not remove its types, nor keep removed CRDs of
+		// other groups watched.
 		e.logger.Warn("Partial discovery result", zap.Error(err))
 	}
+	complete := err == nil
+	var failedGroups map[string]bool
+	var groupErr *discovery.ErrGroupDiscoveryFailed
+	if errors.As(err, &groupErr) {
+		failedGroups = make(map[string]bool, len(groupErr.Groups))
+		for gv := range groupErr.Groups {
+			failedGroups[gv.Group] = true
+		}
+	}
+
+	// Refresh CRD annotations first so this scan's decisions see them.
+	e.mu.RLock()
+	checkAnnotation := e.checkAnnotation
+	e.mu.RUnlock()
+	if checkAnnotation {
+		e.refreshAnnotatedCRDs(ctx)
+	}
 
 	var discovered []schema.GroupVersionResource
+	decisions := make(map[schema.GroupVersionResource]Decision)
+	served := make(map[schema.GroupResource]string) // → preferred version
 	for _, list := range lists {
 		gv, parseErr := schema.ParseGroupVersion(list.GroupVersion)
 		if parseErr != nil {
@@ -209,24 +329,20 @@ func (e *Engine) scan(ctx context.Context) error {
 				Version:  gv.Version,
 				Resource: r.Name,
 			}
+			served[gvr.GroupResource()] = gvr.Version
 
-			if e.isConstraintLike(gvr, r.Name) {
+			d := e.classify(gvr, r.Name)
+			decisions[gvr] = d
+			if d.Watched {
 				discovered = append(discovered, gvr)
 			}
 		}
 	}
 
-	// Check CRD annotations if enabled
-	e.mu.RLock()
-	checkAnnotation := e.checkAnnotation
-	e.mu.RUnlock()
-	if checkAnnotation {
-		e.refreshAnnotatedCRDs(ctx)
-	}
-
 	// Start informers for newly discovered GVRs
 	e.mu.Lock()
-	defer e.mu.Unlock()
+	e.lastScan = time.Now()
+	e.lastScanComplete = complete
 
 	e.logger.Info("Discovery scan complete",
 		zap.Int("discovered", len(discovered)),
@@ -235,13 +351,18 @@ func (e *Engine) scan(ctx context.Context) error {
 
 	// Build profile-suppressed GVR set: profiles with enabled=false exclude from discovery
 	suppressed := make(map[schema.GroupVersionResource]bool)
-	for _, ps := range e.profiles {
+	for name, ps := range e.profiles {
 		if !ps.enabled {
 			suppressed[ps.gvr] = true
+			if _, ok := decisions[ps.gvr]; ok {
+				decisions[ps.gvr] = newDecision(ps.gvr, false, ReasonProfileDisabled, "excluded by ConstraintProfile %s", name)
+			}
 		}
 	}
+	e.recordDecisionsLocked(decisions, complete)
+
 	for _, gvr := range discovered {
-		if suppressed[gvr] {
+		if suppressed[gvr] || e.dryRun {
 			continue
 		}
 		if !e.watchedGVRs[gvr] {
@@ -255,49 +376,59 @@ func (e *Engine) scan(ctx context.Context) error {
 		}
 	}
 
+	var removed []removedGVR
+	if (complete || failedGroups != nil) && !e.dryRun {
+		removed = e.removeUnservedLocked(served, failedGroups)
+	}
+	e.mu.Unlock()
+
+	e.cleanupRemoved(ctx, removed)
 	return nil
 }
 
-// startInformer creates and starts a dynamic informer for the given GVR.
+// startInformer creates and starts a dynamic informer for the given GVR with
+// its own stop channel, so it can be torn down when the type disappears.
+// Caller must hold e.mu.
 func (e *Engine) startInformer(ctx context.Context, gvr schema.GroupVersionResource) {
-	// Create informer factory if not already created
-	if e.informerFactory == nil {
-		e.informerFactory = dynamicinformer.NewFilteredDynamicSharedInformerFactory(
-			e.dynamicClient,
-			30*time.Minute, // resync period
-			"",             // all namespaces
-			nil,            // no tweaks
-		)
+	informer := e.newInformer(gvr)
+	if err := informer.SetTransform(e.transformLocked()); err != nil {
+		e.logger.Error("Failed to set informer transform", zap.String("gvr", gvr.String()), zap.Error(err))
+		return
 	}
 
-	informer := e.informerFactory.ForResource(gvr).Informer()
-
-	// Register event handlers
-	if _, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
-		AddFunc: func(obj interface{}) {
-			e.handleAdd(ctx, gvr, obj)
-		},
-		UpdateFunc: func(oldObj, newObj interface{}) {
-			e.handleUpdate(ctx, gvr, newObj)
-		},
-		DeleteFunc: func(obj interface{}) {
-			e.handleDelete(ctx, gvr, obj)
-		},
-	}); err != nil {
+	// Register event handlers. Events are parsed by the GVR's work queue.
+	e.ensureQueueLocked(ctx, gvr)
+	registration, err := informer.AddEventHandler(e.eventHandler(gvr))
+	if err != nil {
 		e.logger.Error("Failed to add event handler", zap.String("gvr", gvr.String()), zap.Error(err))
 		return
 	}
 
 	e.informers[gvr] = informer
+	e.trackSyncLocked(gvr, registration)
 
 	// Start the informer in a goroutine
-	go informer.Run(e.stopCh)
+	stopCh := make(chan struct{})
+	e.informerStops[gvr] = stopCh
+	go informer.Run(stopCh)
 
 	e.logger.Debug("Started informer for GVR",
 		zap.String("gvr", gvr.String()),
 	)
 }
 
+// newInformer creates a dynamic informer of gvr across all namespaces.
+func (e *Engine) newInformer(gvr schema.GroupVersionResource) cache.SharedIndexInformer {
+	return dynamicinformer.NewFilteredDynamicInformer(
+		e.dynamicClient,
+		gvr,
+		"",               // all namespaces
+		30*time.Minute,   // resync period
+		cache.Indexers{}, // no indexers
+		nil,              // no tweaks
+	).Informer()
+}
+
 // handleAdd processes a new object.
 func (e *Engine) handleAdd(ctx context.Context, gvr schema.GroupVersionResource, obj interface{}) {
 	// Skip if GVR has been suppressed (e.g., disabled by a ConstraintProfile)
@@ -314,20 +445,35 @@ func (e *Engine) handleAdd(ctx context.Context, gvr schema.GroupVersionResource,
 		return
 	}
 
-	constraints, err := e.parseObject(ctx, gvr, unstructuredObj)
-	if err != nil {
+	if err := e.upsertObject(ctx, gvr, unstructuredObj); err != nil {
 		e.logger.Error("Failed to parse object",
 			zap.String("gvr", gvr.String()),
 			zap.String("name", unstructuredObj.GetName()),
 			zap.String("namespace", unstructuredObj.GetNamespace()),
 			zap.Error(err),
 		)
-		return
 	}
+}
 
+// upsertObject parses obj, upserts the resulting constraints and deletes
+// those the previous version of obj produced but this one does not. Objects
+// in namespaces outside the scope are skipped.
+func (e *Engine) upsertObject(ctx context.Context, gvr schema.GroupVersionResource, obj *unstructured.Unstructured) error {
+	if !e.inScope(obj.GetNamespace()) {
+		return nil
+	}
+	constraints, err := e.parseObject(ctx, gvr, obj)
+	if err != nil {
+		e.recordParseError(gvr, err)
+		return err
+	}
 	for _, c := range constraints {
 		e.indexer.Upsert(c)
 	}
+	for _, uid := range e.recordProduced(gvr, obj, constraints) {
+		e.indexer.Delete(uid)
+	}
+	return nil
 }
 
 // handleUpdate processes an updated object.
@@ -336,10 +482,8 @@ func (e *Engine) handleUpdate(ctx context.Context, gvr schema.GroupVersionResour
 	e.handleAdd(ctx, gvr, obj)
 }
 
-// handleDelete processes a deleted object.
-// It parses the object to obtain constraint UIDs (which may differ from the
-// source object UID when an adapter maps one object to multiple constraints,
-// e.g. Kyverno rules) and removes each from the indexer.
+// handleDelete processes a deleted object and removes every constraint it
+// produced from the indexer.
 func (e *Engine) handleDelete(ctx context.Context, gvr schema.GroupVersionResource, obj interface{}) {
 	unstructuredObj, ok := obj.(*unstructured.Unstructured)
 	if !ok {
@@ -355,18 +499,23 @@ func (e *Engine) handleDelete(ctx context.Context, gvr schema.GroupVersionResour
 		}
 	}
 
-	// Parse the deleted object to discover all constraint UIDs it produced.
-	// Adapters like Kyverno generate synthetic UIDs (one per rule), so
-	// deleting only by source UID would miss them.
-	constraints, err := e.parseObject(ctx, gvr, unstructuredObj)
-	if err != nil || len(constraints) == 0 {
-		// Fallback: delete by source object UID (works for 1:1 adapters).
-		e.indexer.Delete(unstructuredObj.GetUID())
+	e.deleteObject(gvr, unstructuredObj)
+}
+
+// deleteObject removes every constraint produced by obj from the indexer.
+// Constraint UIDs may differ from the source object UID when an adapter maps
+// one object to several constraints (e.g. Kyverno rules), so they are taken
+// from what obj produced when it was last parsed. The object is not parsed
+// again: for a plugin adapter that is a call that may fail or time out.
+func (e *Engine) deleteObject(gvr schema.GroupVersionResource, obj *unstructured.Unstructured) {
+	uids, ok := e.takeProduced(gvr, obj)
+	if !ok {
+		// Never parsed by this replica; only a 1:1 constraint can be indexed.
+		e.indexer.Delete(obj.GetUID())
 		return
 	}
-
-	for _, c := range constraints {
-		e.indexer.Delete(c.UID)
+	for _, uid := range uids {
+		e.indexer.Delete(uid)
 	}
 }
 
@@ -417,53 +566,7 @@ func (e *Engine) parseObject(ctx context.Context, gvr schema.GroupVersionResourc
 // isConstraintLike determines whether a GVR is likely a constraint/policy resource.
 // Caller must NOT hold e.mu — this method acquires a read lock.
 func (e *Engine) isConstraintLike(gvr schema.GroupVersionResource, resourceName string) bool {
-	// Snapshot all mu-protected state under a single read lock for consistency.
-	e.mu.RLock()
-	groups := e.policyGroups
-	hints := e.nameHints
-	profileMatch := false
-	for _, ps := range e.profiles {
-		if ps.enabled && ps.gvr == gvr {
-			profileMatch = true
-			break
-		}
-	}
-	annotated := e.annotatedCRDs[gvr]
-	e.mu.RUnlock()
-
-	// Check 1: Is this a known policy group?
-	if groups[gvr.Group] {
-		return true
-	}
-
-	// Check 2: Does the adapter registry already handle this GVR?
-	if e.registry.ForGVR(gvr) != nil {
-		return true
-	}
-
-	// Check 3: Native Kubernetes constraint resources
-	if gvr.Group == "" {
-		switch resourceName {
-		case "resourcequotas", "limitranges":
-			return true
-		}
-	}
-
-	// Check 4: Heuristic — resource name contains policy-related substrings
-	lower := strings.ToLower(resourceName)
-	for _, hint := range hints {
-		if strings.Contains(lower, hint) {
-			return true
-		}
-	}
-
-	// Check 5: ConstraintProfile CRDs that register additional types
-	if profileMatch {
-		return true
-	}
-
-	// Check 6: CRD annotation override
-	return annotated
+	return e.classify(gvr, resourceName).Watched
 }
 
 // WatchedGVRs returns the set of GVRs currently being watched.
@@ -489,6 +592,10 @@ func (e *Engine) RegisterProfile(profile *v1alpha1.ConstraintProfile) error {
 		Resource: spec.GVR.Resource,
 	}
 
+	if err := generic.ValidateFieldPaths(spec.FieldPaths); err != nil {
+		return fmt.Errorf("profile %s: %w", name, err)
+	}
+
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
@@ -497,6 +604,9 @@ func (e *Engine) RegisterProfile(profile *v1alpha1.ConstraintProfile) error {
 		if existing.gvr != gvr || !spec.Enabled {
 			e.stopProfileInformerLocked(existing)
 		}
+		if existing.gvr != gvr {
+			delete(e.decisions, existing.gvr)
+		}
 	}
 
 	ps := &profileState{
@@ -506,15 +616,29 @@ func (e *Engine) RegisterProfile(profile *v1alpha1.ConstraintProfile) error {
 		severity:   spec.Severity,
 		enabled:    spec.Enabled,
 	}
+	if spec.DebounceSeconds != nil {
+		d := time.Duration(*spec.DebounceSeconds) * time.Second
+		ps.debounce = &d
+	}
 	e.profiles[name] = ps
 
 	if !spec.Enabled {
 		// If explicitly disabled, stop any existing informer for this GVR
 		// and clean up constraints
+		e.decisions[gvr] = newDecision(gvr, false, ReasonProfileDisabled, "excluded by ConstraintProfile %s", name)
 		e.stopGVRInformerLocked(gvr)
 		return nil
 	}
 
+	e.decisions[gvr] = newDecision(gvr, true, ReasonConstraintProfile, "registered by ConstraintProfile %s", name)
+	if e.dryRun {
+		e.logger.Info("ConstraintProfile registered (dry run, no informer)",
+			zap.String("profile", name),
+			zap.String("gvr", gvr.String()),
+		)
+		return nil
+	}
+
 	// Start informer if not already watching this GVR
 	if !e.watchedGVRs[gvr] {
 		e.watchedGVRs[gvr] = true
@@ -559,6 +683,8 @@ func (e *Engine) UnregisterProfile(name string) {
 	}
 
 	if !needed {
+		// Reclassified by the next scan if the type is still served.
+		delete(e.decisions, gvr)
 		e.stopGVRInformerLocked(gvr)
 	}
 
@@ -566,7 +692,7 @@ func (e *Engine) UnregisterProfile(name string) {
 
 	// Clean up constraints outside the lock (indexer has its own lock)
 	if !needed {
-		n := e.indexer.DeleteBySource(gvr)
+		n := e.deleteBySource(gvr)
 		e.logger.Info("ConstraintProfile unregistered, cleaned up constraints",
 			zap.String("profile", name),
 			zap.String("gvr", gvr.String()),
@@ -593,47 +719,46 @@ func (e *Engine) stopProfileInformerLocked(ps *profileState) {
 func (e *Engine) stopGVRInformerLocked(gvr schema.GroupVersionResource) {
 	delete(e.watchedGVRs, gvr)
 	delete(e.informers, gvr)
+	delete(e.handlerSynced, gvr)
+	delete(e.synced, gvr)
+	delete(e.parseErrors, gvr)
+	if stopCh, ok := e.informerStops[gvr]; ok {
+		close(stopCh)
+		delete(e.informerStops, gvr)
+	}
+	e.stopQueueLocked(gvr)
 }
 
-// startProfileInformer creates and starts a dynamic informer with a profile-specific
-// stop channel, allowing it to be stopped independently.
+// startProfileInformer creates and starts a dynamic informer with a
+// profile-specific stop channel, allowing it to be stopped independently.
+// Caller must hold e.mu.
 func (e *Engine) startProfileInformer(gvr schema.GroupVersionResource, stopCh chan struct{}) {
 	if e.dynamicClient == nil {
 		e.logger.Debug("Skipping profile informer start: no dynamic client", zap.String("gvr", gvr.String()))
 		return
 	}
 
-	// Use a dedicated factory so the informer can be stopped independently.
-	factory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(
-		e.dynamicClient,
-		30*time.Minute, // resync period
-		"",             // all namespaces
-		nil,            // no tweaks
-	)
-
-	informer := factory.ForResource(gvr).Informer()
+	informer := e.newInformer(gvr)
+	if err := informer.SetTransform(e.transformLocked()); err != nil {
+		e.logger.Error("Failed to set profile informer transform",
+			zap.String("gvr", gvr.String()), zap.Error(err))
+		return
+	}
 
 	ctx := e.ctx
 	if ctx == nil {
 		ctx = context.Background()
 	}
-	if _, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
-		AddFunc: func(obj interface{}) {
-			e.handleAdd(ctx, gvr, obj)
-		},
-		UpdateFunc: func(oldObj, newObj interface{}) {
-			e.handleUpdate(ctx, gvr, newObj)
-		},
-		DeleteFunc: func(obj interface{}) {
-			e.handleDelete(ctx, gvr, obj)
-		},
-	}); err != nil {
+	e.ensureQueueLocked(ctx, gvr)
+	registration, err := informer.AddEventHandler(e.eventHandler(gvr))
+	if err != nil {
 		e.logger.Error("Failed to add event handler for profile informer",
 			zap.String("gvr", gvr.String()), zap.Error(err))
 		return
 	}
 
 	e.informers[gvr] = informer
+	e.trackSyncLocked(gvr, registration)
 	go informer.Run(stopCh)
 }
 
@@ -652,12 +777,6 @@ func (e *Engine) refreshAnnotatedCRDs(ctx context.Context) {
 		}
 	}()
 
-	crdGVR := schema.GroupVersionResource{
-		Group:    "apiextensions.k8s.io",
-		Version:  "v1",
-		Resource: "customresourcedefinitions",
-	}
-
 	list, err := e.dynamicClient.Resource(crdGVR).List(ctx, metav1.ListOptions{})
 	if err != nil {
 		e.logger.Debug("Failed to list CRDs for annotation check", zap.Error(err))
@@ -721,6 +840,13 @@ func (e *Engine) Stop() {
 		for _, ps := range e.profiles {
 			e.stopProfileInformerLocked(ps)
 		}
+		for gvr, stopCh := range e.informerStops {
+			close(stopCh)
+			delete(e.informerStops, gvr)
+		}
+		for gvr := range e.queues {
+			e.stopQueueLocked(gvr)
+		}
 		e.mu.Unlock()
 	})
 }
@@ -2,6 +2,7 @@ package discovery
 
 import (
 	"context"
+	"errors"
 	"sync"
 	"testing"
 	"time"
@@ -424,6 +425,49 @@ func TestHandleDelete(t *testing.T) {
 	assert.Empty(t, idx.All())
 }
 
+// unavailableAdapter produces one constraint per entry in uids and fails
+// once unavailable is set, like a plugin whose process went away.
+type unavailableAdapter struct {
+	gvr         schema.GroupVersionResource
+	uids        []string
+	unavailable bool
+}
+
+func (a *unavailableAdapter) Name() string { return "unavailable" }
+func (a *unavailableAdapter) Handles() []schema.GroupVersionResource {
+	return []schema.GroupVersionResource{a.gvr}
+}
+
+func (a *unavailableAdapter) Parse(_ context.Context, obj *unstructured.Unstructured) ([]internaltypes.Constraint, error) {
+	if a.unavailable {
+		return nil, errors.New("plugin unavailable")
+	}
+	var out []internaltypes.Constraint
+	for _, uid := range a.uids {
+		out = append(out, internaltypes.Constraint{UID: types.UID(uid), Name: obj.GetName(), Namespace: obj.GetNamespace(), Source: a.gvr})
+	}
+	return out, nil
+}
+
+func TestHandleDelete_AdapterUnavailable(t *testing.T) {
+	gvr := schema.GroupVersionResource{Group: "plugin.example.com", Version: "v1", Resource: "rules"}
+	adapter := &unavailableAdapter{gvr: gvr, uids: []string{"uid-0", "uid-1", "uid-2"}}
+	registry := adapters.NewRegistry()
+	require.NoError(t, registry.Register(adapter))
+	idx := indexer.New(nil)
+	engine := NewEngine(zap.NewNop(), nil, nil, registry, idx, 5*time.Minute)
+
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "rules", "namespace": "default", "uid": "uid"},
+	}}
+	require.NoError(t, engine.upsertObject(context.Background(), gvr, obj))
+	require.Len(t, idx.All(), 3)
+
+	adapter.unavailable = true
+	engine.handleDelete(context.Background(), gvr, obj)
+	assert.Empty(t, idx.All(), "constraints are deleted without parsing the object again")
+}
+
 func TestHandleDelete_Tombstone(t *testing.T) {
 	engine, idx := setupTestEngine(t)
 
@@ -884,6 +928,29 @@ func TestRegisterProfile_StartsInformer(t *testing.T) {
 	engine.Stop()
 }
 
+func TestRegisterProfile_InvalidFieldPaths(t *testing.T) {
+	idx := indexer.New(nil)
+	registry := adapters.NewRegistry()
+	engine := NewEngine(zap.NewNop(), nil, nil, registry, idx, 5*time.Minute)
+
+	profile := &v1alpha1.ConstraintProfile{
+		ObjectMeta: metav1.ObjectMeta{Name: "bad-paths"},
+		Spec: v1alpha1.ConstraintProfileSpec{
+			GVR:     v1alpha1.GVRReference{Group: "custom.io", Version: "v1", Resource: "restrictions"},
+			Adapter: "generic",
+			Enabled: true,
+			FieldPaths: &v1alpha1.FieldPaths{
+				RulesPath: "{.spec.rules[*",
+			},
+		},
+	}
+
+	err := engine.RegisterProfile(profile)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "rulesPath")
+	assert.Empty(t, engine.WatchedGVRs())
+}
+
 func TestUnregisterProfile_CleansUpConstraints(t *testing.T) {
 	idx := indexer.New(nil)
 	registry := adapters.NewRegistry()
@@ -1064,6 +1131,52 @@ func TestParseObject_WithProfileConfig(t *testing.T) {
 	assert.Equal(t, internaltypes.SeverityCritical, c.Severity)
 }
 
+func TestUpsertObject_RemovedRulesAreDeleted(t *testing.T) {
+	idx := indexer.New(nil)
+	engine := NewEngine(zap.NewNop(), nil, nil, adapters.NewRegistry(), idx, 5*time.Minute)
+	ctx := context.Background()
+
+	gvr := schema.GroupVersionResource{Group: "firewall.corp.io", Version: "v1", Resource: "egressfirewalls"}
+	engine.mu.Lock()
+	engine.profiles["firewall"] = &profileState{
+		gvr:        gvr,
+		adapter:    "generic",
+		enabled:    true,
+		fieldPaths: &v1alpha1.FieldPaths{RulesPath: "{.spec.rules[*]}", NamePath: "name"},
+	}
+	engine.mu.Unlock()
+
+	withRules := func(names ...string) *unstructured.Unstructured {
+		rules := make([]interface{}, 0, len(names))
+		for _, name := range names {
+			rules = append(rules, map[string]interface{}{"name": name})
+		}
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "firewall.corp.io/v1",
+			"kind":       "EgressFirewall",
+			"metadata":   map[string]interface{}{"name": "egress", "namespace": "prod", "uid": "uid-egress"},
+			"spec":       map[string]interface{}{"rules": rules},
+		}}
+	}
+	names := func() []string {
+		var out []string
+		for _, c := range idx.ByNamespace("prod") {
+			out = append(out, c.Name)
+		}
+		return out
+	}
+
+	require.NoError(t, engine.upsertObject(ctx, gvr, withRules("a", "b", "c")))
+	assert.ElementsMatch(t, []string{"egress/a", "egress/b", "egress/c"}, names())
+
+	require.NoError(t, engine.upsertObject(ctx, gvr, withRules("c")))
+	assert.Equal(t, []string{"egress/c"}, names())
+	assert.Equal(t, types.UID("uid-egress-rule:c"), idx.All()[0].UID, "a named rule keeps its UID when others are removed")
+
+	engine.deleteObject(gvr, withRules())
+	assert.Empty(t, idx.All())
+}
+
 func TestRefreshAnnotatedCRDs(t *testing.T) {
 	idx := indexer.New(nil)
 	registry := adapters.NewRegistry()
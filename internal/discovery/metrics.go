@@ -0,0 +1,39 @@
+package discovery
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Parse queue metrics, served on the controller-runtime metrics endpoint.
+var (
+	queueDepth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "nightjar_discovery_queue_depth",
+		Help: "Objects waiting to be parsed, by GVR.",
+	}, []string{"gvr"})
+
+	queueEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "nightjar_discovery_queue_events_total",
+		Help: "Informer events received by the parse queue, by GVR.",
+	}, []string{"gvr"})
+
+	queueCoalesced = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "nightjar_discovery_queue_coalesced_total",
+		Help: "Informer events merged into an already pending change of the same object, by GVR.",
+	}, []string{"gvr"})
+
+	queueLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "nightjar_discovery_queue_latency_seconds",
+		Help:    "Time from an object's first pending change to its parse, by GVR.",
+		Buckets: []float64{0.01, 0.1, 0.5, 1, 5, 15, 30, 60, 300},
+	}, []string{"gvr"})
+
+	parseResults = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "nightjar_discovery_parses_total",
+		Help: "Queued parses by GVR and result (success, retry, dropped).",
+	}, []string{"gvr", "result"})
+)
+
+func init() {
+	ctrlmetrics.Registry.MustRegister(queueDepth, queueEvents, queueCoalesced, queueLatency, parseResults)
+}
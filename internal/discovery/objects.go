@@ -0,0 +1,172 @@
+package discovery
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+
+	"github.com/nightjarctl/nightjar/internal/types"
+)
+
+// lastAppliedAnnotation holds a full JSON copy of the object written by
+// kubectl apply. No adapter reads it.
+const lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// trimObject is the informer transform for watched policy objects. It drops
+// managedFields and the last-applied annotation before the object enters the
+// cache, which for large policies are often bigger than the spec itself.
+// Constraints reference the cached object directly, so this also bounds what
+// the index keeps alive.
+func trimObject(obj interface{}) (interface{}, error) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return obj, nil
+	}
+	u.SetManagedFields(nil)
+	if annotations := u.GetAnnotations(); annotations[lastAppliedAnnotation] != "" {
+		delete(annotations, lastAppliedAnnotation)
+		if len(annotations) == 0 {
+			annotations = nil
+		}
+		u.SetAnnotations(annotations)
+	}
+	return u, nil
+}
+
+// stubAnnotation marks a stub: the cached form of an object outside the
+// namespace scope with SetScopedCache.
+const stubAnnotation = "nightjar.io/out-of-scope-stub"
+
+// stubObject returns the stub cached for an object outside the scope: its
+// identity and resource version, without labels, annotations or spec. It is
+// enough to process a delete; the object is listed again if its namespace
+// enters the scope.
+func stubObject(u *unstructured.Unstructured) *unstructured.Unstructured {
+	stub := &unstructured.Unstructured{}
+	stub.SetAPIVersion(u.GetAPIVersion())
+	stub.SetKind(u.GetKind())
+	stub.SetNamespace(u.GetNamespace())
+	stub.SetName(u.GetName())
+	stub.SetUID(u.GetUID())
+	stub.SetResourceVersion(u.GetResourceVersion())
+	stub.SetAnnotations(map[string]string{stubAnnotation: "true"})
+	return stub
+}
+
+// isStub reports whether u is a stub made by stubObject.
+func isStub(u *unstructured.Unstructured) bool {
+	return u.GetAnnotations()[stubAnnotation] != ""
+}
+
+// SourceConstraints parses the cached object namespace/name of gvr and
+// returns the UIDs of the constraints it produces now. found is false when
+// the type is not watched or the object does not exist. Used to reconcile
+// constraints restored from a snapshot against the cluster.
+func (e *Engine) SourceConstraints(ctx context.Context, gvr schema.GroupVersionResource, namespace, name string) (uids []k8stypes.UID, found bool, err error) {
+	obj, ok := e.cachedObject(gvr, namespace, name)
+	if !ok {
+		return nil, false, nil
+	}
+	if !e.inScope(obj.GetNamespace()) {
+		return nil, true, nil
+	}
+	constraints, err := e.parseObject(ctx, gvr, obj)
+	if err != nil {
+		return nil, true, err
+	}
+	uids = make([]k8stypes.UID, 0, len(constraints))
+	for _, c := range constraints {
+		uids = append(uids, c.UID)
+	}
+	return uids, true, nil
+}
+
+// cachedObject looks up namespace/name in the informer cache of gvr. With
+// SetScopedCache, an object outside the scope is a stub; see isStub.
+func (e *Engine) cachedObject(gvr schema.GroupVersionResource, namespace, name string) (*unstructured.Unstructured, bool) {
+	e.mu.RLock()
+	informer := e.informers[gvr]
+	e.mu.RUnlock()
+	if informer == nil {
+		return nil, false
+	}
+
+	key := name
+	if namespace != "" {
+		key = namespace + "/" + name
+	}
+	obj, exists, err := informer.GetStore().GetByKey(key)
+	if err != nil || !exists {
+		return nil, false
+	}
+	u, ok := obj.(*unstructured.Unstructured)
+	return u, ok
+}
+
+// objectKey identifies a source object of a watched type.
+type objectKey struct {
+	gvr       schema.GroupVersionResource
+	namespace string
+	name      string
+}
+
+func keyOf(gvr schema.GroupVersionResource, obj *unstructured.Unstructured) objectKey {
+	return objectKey{gvr: gvr, namespace: obj.GetNamespace(), name: obj.GetName()}
+}
+
+// recordProduced records the UIDs of the constraints obj produced and returns
+// those it produced before but no longer does, e.g. rules removed from it.
+func (e *Engine) recordProduced(gvr schema.GroupVersionResource, obj *unstructured.Unstructured, constraints []types.Constraint) []k8stypes.UID {
+	uids := make([]k8stypes.UID, 0, len(constraints))
+	current := make(map[k8stypes.UID]bool, len(constraints))
+	for _, c := range constraints {
+		uids = append(uids, c.UID)
+		current[c.UID] = true
+	}
+
+	key := keyOf(gvr, obj)
+	e.producedMu.Lock()
+	previous := e.produced[key]
+	e.produced[key] = uids
+	e.producedMu.Unlock()
+
+	var stale []k8stypes.UID
+	for _, uid := range previous {
+		if !current[uid] {
+			stale = append(stale, uid)
+		}
+	}
+	return stale
+}
+
+// takeProduced removes and returns the UIDs recorded for obj. ok is false
+// when none were recorded, e.g. for constraints restored from a snapshot.
+func (e *Engine) takeProduced(gvr schema.GroupVersionResource, obj *unstructured.Unstructured) (uids []k8stypes.UID, ok bool) {
+	key := keyOf(gvr, obj)
+	e.producedMu.Lock()
+	defer e.producedMu.Unlock()
+	uids, ok = e.produced[key]
+	delete(e.produced, key)
+	return uids, ok
+}
+
+// forgetProduced drops the records of the objects that match, after their
+// constraints were deleted by type or namespace.
+func (e *Engine) forgetProduced(match func(objectKey) bool) {
+	e.producedMu.Lock()
+	defer e.producedMu.Unlock()
+	for key := range e.produced {
+		if match(key) {
+			delete(e.produced, key)
+		}
+	}
+}
+
+// deleteBySource deletes every constraint of gvr and returns how many were
+// deleted.
+func (e *Engine) deleteBySource(gvr schema.GroupVersionResource) int {
+	e.forgetProduced(func(key objectKey) bool { return key.gvr == gvr })
+	return e.indexer.DeleteBySource(gvr)
+}
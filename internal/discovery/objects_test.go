@@ -0,0 +1,93 @@
+package discovery
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/nightjarctl/nightjar/internal/adapters"
+	"github.com/nightjarctl/nightjar/internal/indexer"
+)
+
+func TestTrimObject(t *testing.T) {
+	obj := newPolicy("v1", "trimmed", "uid-1")
+	obj.SetManagedFields([]metav1.ManagedFieldsEntry{{Manager: "kubectl"}})
+	obj.SetAnnotations(map[string]string{
+		lastAppliedAnnotation:  `{"kind":"SecurityPolicy"}`,
+		"nightjar.io/severity": "critical",
+	})
+
+	out, err := trimObject(obj)
+	require.NoError(t, err)
+	u := out.(*unstructured.Unstructured)
+	assert.Empty(t, u.GetManagedFields())
+	assert.Equal(t, map[string]string{"nightjar.io/severity": "critical"}, u.GetAnnotations())
+
+	only := newPolicy("v1", "only-last-applied", "uid-2")
+	only.SetAnnotations(map[string]string{lastAppliedAnnotation: "{}"})
+	out, err = trimObject(only)
+	require.NoError(t, err)
+	assert.Nil(t, out.(*unstructured.Unstructured).GetAnnotations())
+
+	tombstone := "not-an-object"
+	out, err = trimObject(tombstone)
+	require.NoError(t, err)
+	assert.Equal(t, tombstone, out)
+}
+
+func TestIndex_SharesInformerCacheObject(t *testing.T) {
+	client := newCRDWatchClient()
+	ctx := context.Background()
+	obj := newPolicy("v1", "shared", "uid-shared")
+	obj.SetManagedFields([]metav1.ManagedFieldsEntry{{Manager: "kubectl"}})
+	_, err := client.Resource(policiesV1).Namespace("default").Create(ctx, obj, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	idx := indexer.New(nil)
+	engine := NewEngine(zap.NewNop(), newMockDiscovery(servedPolicies("v1")), client, adapters.NewRegistry(), idx, 5*time.Minute)
+	t.Cleanup(engine.Stop)
+	require.NoError(t, engine.scan(ctx))
+	require.Eventually(t, func() bool { return idx.Count() == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	c := idx.All()[0]
+	require.NotNil(t, c.RawObject)
+	assert.Empty(t, c.RawObject.GetManagedFields(), "cached object is trimmed")
+
+	cached, ok := engine.cachedObject(c.Source, c.RawObject.GetNamespace(), c.RawObject.GetName())
+	require.True(t, ok)
+	assert.Same(t, cached, c.RawObject, "index references the informer cache entry, not a copy")
+}
+
+func TestSourceConstraintsAndHasSynced(t *testing.T) {
+	client := newCRDWatchClient()
+	ctx := context.Background()
+	_, err := client.Resource(policiesV1).Namespace("default").Create(ctx, newPolicy("v1", "live", "uid-live"), metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	idx := indexer.New(nil)
+	engine := NewEngine(zap.NewNop(), newMockDiscovery(servedPolicies("v1")), client, adapters.NewRegistry(), idx, 5*time.Minute)
+	t.Cleanup(engine.Stop)
+	assert.False(t, engine.HasSynced(), "not synced before the first scan")
+
+	require.NoError(t, engine.scan(ctx))
+	require.Eventually(t, engine.HasSynced, 5*time.Second, 10*time.Millisecond)
+
+	uids, found, err := engine.SourceConstraints(ctx, policiesV1, "default", "live")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Contains(t, uids, types.UID("uid-live"))
+
+	_, found, err = engine.SourceConstraints(ctx, policiesV1, "default", "deleted")
+	require.NoError(t, err)
+	assert.False(t, found)
+	_, found, err = engine.SourceConstraints(ctx, policiesV1beta1, "default", "live")
+	require.NoError(t, err)
+	assert.False(t, found, "unwatched source type")
+}
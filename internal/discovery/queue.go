@@ -0,0 +1,329 @@
+package discovery
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// QueueOptions configures the per-GVR parse queues that sit between informers
+// and the indexer.
+type QueueOptions struct {
+	// Debounce delays parsing an object after its first change so that
+	// further changes within the window are coalesced into one parse.
+	// A ConstraintProfile's debounceSeconds overrides it for its GVR.
+	// Default: 0 (parse as soon as the worker is free).
+	Debounce time.Duration
+
+	// RateLimit is the maximum number of parses per second for one GVR.
+	// Default: 20.
+	RateLimit float64
+
+	// Burst is the number of parses allowed above RateLimit in a burst.
+	// Default: 50.
+	Burst int
+
+	// MaxRetries is how often a failed parse is retried with exponential
+	// backoff before the change is dropped. Default: 5.
+	MaxRetries int
+}
+
+// DefaultQueueOptions returns sensible defaults.
+func DefaultQueueOptions() QueueOptions {
+	return QueueOptions{
+		RateLimit:  20,
+		Burst:      50,
+		MaxRetries: 5,
+	}
+}
+
+// pendingChange is the coalesced state of one object awaiting a parse.
+type pendingChange struct {
+	// obj is the latest version of the object; nil if it was deleted.
+	obj *unstructured.Unstructured
+
+	// deleted is the last version seen before a deletion. Kept even if the
+	// object was re-created so constraints of the old instance are removed.
+	deleted *unstructured.Unstructured
+
+	// since is when the first coalesced change arrived.
+	since time.Time
+}
+
+// gvrQueue is the rate-limited work queue of one GVR. Items are object keys;
+// the object state lives in pending so that repeated updates to the same
+// object collapse into a single parse of its latest version.
+type gvrQueue struct {
+	gvr        schema.GroupVersionResource
+	label      string
+	queue      workqueue.RateLimitingInterface
+	limiter    *rate.Limiter
+	maxRetries int
+	done       chan struct{}
+	stopOnce   sync.Once
+	inflight   atomic.Int32
+
+	mu      sync.Mutex
+	pending map[string]*pendingChange
+}
+
+func newGVRQueue(gvr schema.GroupVersionResource, opts QueueOptions) *gvrQueue {
+	label := gvr.String()
+	return &gvrQueue{
+		gvr:   gvr,
+		label: label,
+		queue: workqueue.NewRateLimitingQueueWithConfig(
+			workqueue.NewItemExponentialFailureRateLimiter(500*time.Millisecond, 5*time.Minute),
+			workqueue.RateLimitingQueueConfig{Name: "discovery_" + label},
+		),
+		limiter:    rate.NewLimiter(rate.Limit(opts.RateLimit), opts.Burst),
+		maxRetries: opts.MaxRetries,
+		done:       make(chan struct{}),
+		pending:    make(map[string]*pendingChange),
+	}
+}
+
+// put records a change and schedules the object for parsing after delay.
+func (q *gvrQueue) put(key string, obj *unstructured.Unstructured, deleted bool, delay time.Duration) {
+	q.mu.Lock()
+	change, exists := q.pending[key]
+	if !exists {
+		change = &pendingChange{since: time.Now()}
+		q.pending[key] = change
+	}
+	if deleted {
+		change.deleted = obj
+		change.obj = nil
+	} else {
+		change.obj = obj
+	}
+	depth := len(q.pending)
+	q.mu.Unlock()
+
+	queueEvents.WithLabelValues(q.label).Inc()
+	if exists {
+		queueCoalesced.WithLabelValues(q.label).Inc()
+	}
+	queueDepth.WithLabelValues(q.label).Set(float64(depth))
+
+	if delay > 0 {
+		q.queue.AddAfter(key, delay)
+	} else {
+		q.queue.Add(key)
+	}
+}
+
+// take removes and returns the pending change for key.
+func (q *gvrQueue) take(key string) *pendingChange {
+	q.mu.Lock()
+	change := q.pending[key]
+	delete(q.pending, key)
+	depth := len(q.pending)
+	q.mu.Unlock()
+
+	queueDepth.WithLabelValues(q.label).Set(float64(depth))
+	return change
+}
+
+// restore puts back a change whose parse failed. A newer change that arrived
+// in the meantime wins, but keeps the failed change's deletion.
+func (q *gvrQueue) restore(key string, change *pendingChange) {
+	q.mu.Lock()
+	if newer, ok := q.pending[key]; ok {
+		if newer.deleted == nil {
+			newer.deleted = change.deleted
+		}
+		newer.since = change.since
+	} else {
+		q.pending[key] = change
+	}
+	depth := len(q.pending)
+	q.mu.Unlock()
+
+	queueDepth.WithLabelValues(q.label).Set(float64(depth))
+}
+
+// idle reports whether no change is pending or being processed.
+func (q *gvrQueue) idle() bool {
+	q.mu.Lock()
+	pending := len(q.pending)
+	q.mu.Unlock()
+	return pending == 0 && q.queue.Len() == 0 && q.inflight.Load() == 0
+}
+
+// shutdown stops the worker and drops the queue's metrics.
+func (q *gvrQueue) shutdown() {
+	q.stopOnce.Do(func() {
+		close(q.done)
+		q.queue.ShutDown()
+		// Drop the GVR's series so removed types don't linger in scrapes.
+		queueDepth.DeleteLabelValues(q.label)
+		queueEvents.DeleteLabelValues(q.label)
+		queueCoalesced.DeleteLabelValues(q.label)
+		queueLatency.DeleteLabelValues(q.label)
+		parseResults.DeletePartialMatch(prometheus.Labels{"gvr": q.label})
+	})
+}
+
+// ensureQueueLocked returns the parse queue for gvr, starting its worker if
+// needed. Caller must hold e.mu.
+func (e *Engine) ensureQueueLocked(ctx context.Context, gvr schema.GroupVersionResource) *gvrQueue {
+	if q, ok := e.queues[gvr]; ok {
+		return q
+	}
+	q := newGVRQueue(gvr, e.queueOpts)
+	e.queues[gvr] = q
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			q.shutdown()
+		case <-q.done:
+		}
+	}()
+	go e.runQueue(ctx, q)
+	return q
+}
+
+// stopQueueLocked shuts down the parse queue for gvr. Pending changes are
+// dropped. Caller must hold e.mu.
+func (e *Engine) stopQueueLocked(gvr schema.GroupVersionResource) {
+	if q, ok := e.queues[gvr]; ok {
+		q.shutdown()
+		delete(e.queues, gvr)
+	}
+}
+
+// eventHandler returns informer callbacks that feed the parse queue of gvr.
+func (e *Engine) eventHandler(gvr schema.GroupVersionResource) cache.ResourceEventHandlerFuncs {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			e.enqueue(gvr, obj, false)
+		},
+		UpdateFunc: func(_, newObj interface{}) {
+			e.enqueue(gvr, newObj, false)
+		},
+		DeleteFunc: func(obj interface{}) {
+			e.enqueue(gvr, obj, true)
+		},
+	}
+}
+
+// enqueue records an informer event for gvr. Events for GVRs that are no
+// longer watched, and changes to objects outside the namespace scope or
+// cached as stubs, are dropped.
+func (e *Engine) enqueue(gvr schema.GroupVersionResource, obj interface{}, deleted bool) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		e.logger.Warn("Unexpected object type in informer event", zap.String("gvr", gvr.String()))
+		return
+	}
+
+	e.mu.RLock()
+	q := e.queues[gvr]
+	delay := e.debounceLocked(gvr)
+	allowed := e.nsScope.Allows(u.GetNamespace())
+	e.mu.RUnlock()
+	if q == nil || (!deleted && (!allowed || isStub(u))) {
+		return
+	}
+
+	key, err := cache.MetaNamespaceKeyFunc(u)
+	if err != nil {
+		e.logger.Warn("Failed to build object key", zap.String("gvr", gvr.String()), zap.Error(err))
+		return
+	}
+	q.put(key, u, deleted, delay)
+}
+
+// debounceLocked returns the debounce window for gvr: the debounceSeconds of
+// an enabled ConstraintProfile for gvr, or the engine default. Caller must
+// hold e.mu (read or write).
+func (e *Engine) debounceLocked(gvr schema.GroupVersionResource) time.Duration {
+	for _, ps := range e.profiles {
+		if ps.enabled && ps.gvr == gvr && ps.debounce != nil {
+			return *ps.debounce
+		}
+	}
+	return e.queueOpts.Debounce
+}
+
+// runQueue processes the queue of one GVR until it is shut down.
+func (e *Engine) runQueue(ctx context.Context, q *gvrQueue) {
+	for {
+		item, shutdown := q.queue.Get()
+		if shutdown {
+			return
+		}
+		key := item.(string)
+
+		if err := q.limiter.Wait(ctx); err != nil {
+			q.queue.Done(key)
+			return
+		}
+		q.inflight.Add(1)
+		e.processKey(ctx, q, key)
+		q.inflight.Add(-1)
+		q.queue.Done(key)
+	}
+}
+
+// processKey applies the pending change of one object to the indexer.
+func (e *Engine) processKey(ctx context.Context, q *gvrQueue, key string) {
+	change := q.take(key)
+	if change == nil {
+		q.queue.Forget(key)
+		return
+	}
+	queueLatency.WithLabelValues(q.label).Observe(time.Since(change.since).Seconds())
+
+	if change.deleted != nil {
+		e.deleteObject(q.gvr, change.deleted)
+		change.deleted = nil
+	}
+	if change.obj == nil {
+		q.queue.Forget(key)
+		parseResults.WithLabelValues(q.label, "success").Inc()
+		return
+	}
+
+	err := e.upsertObject(ctx, q.gvr, change.obj)
+	if err == nil {
+		q.queue.Forget(key)
+		parseResults.WithLabelValues(q.label, "success").Inc()
+		return
+	}
+
+	if q.queue.NumRequeues(key) < q.maxRetries {
+		e.logger.Debug("Failed to parse object, retrying",
+			zap.String("gvr", q.gvr.String()),
+			zap.String("key", key),
+			zap.Error(err),
+		)
+		q.restore(key, change)
+		q.queue.AddRateLimited(key)
+		parseResults.WithLabelValues(q.label, "retry").Inc()
+		return
+	}
+
+	e.logger.Error("Failed to parse object, giving up",
+		zap.String("gvr", q.gvr.String()),
+		zap.String("key", key),
+		zap.Int("retries", q.maxRetries),
+		zap.Error(err),
+	)
+	q.queue.Forget(key)
+	parseResults.WithLabelValues(q.label, "dropped").Inc()
+}
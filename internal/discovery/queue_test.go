@@ -0,0 +1,218 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+
+	v1alpha1 "github.com/nightjarctl/nightjar/api/v1alpha1"
+	"github.com/nightjarctl/nightjar/internal/adapters"
+	"github.com/nightjarctl/nightjar/internal/indexer"
+	internaltypes "github.com/nightjarctl/nightjar/internal/types"
+)
+
+// countingAdapter records parses and can fail the first failures calls.
+type countingAdapter struct {
+	gvr schema.GroupVersionResource
+
+	mu       sync.Mutex
+	parses   int
+	failures int
+}
+
+func (a *countingAdapter) Name() string { return "counting" }
+
+func (a *countingAdapter) Handles() []schema.GroupVersionResource {
+	return []schema.GroupVersionResource{a.gvr}
+}
+
+func (a *countingAdapter) Parse(_ context.Context, obj *unstructured.Unstructured) ([]internaltypes.Constraint, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.parses++
+	if a.failures > 0 {
+		a.failures--
+		return nil, errors.New("transient failure")
+	}
+	return []internaltypes.Constraint{{
+		UID:     obj.GetUID(),
+		Source:  a.gvr,
+		Name:    obj.GetName(),
+		Summary: obj.GetAnnotations()["revision"],
+	}}, nil
+}
+
+func (a *countingAdapter) count() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.parses
+}
+
+func setupQueueEngine(t *testing.T, resource string, opts QueueOptions) (*Engine, *indexer.Indexer, *countingAdapter, schema.GroupVersionResource) {
+	t.Helper()
+	gvr := schema.GroupVersionResource{Group: "queue.test", Version: "v1", Resource: resource}
+	adapter := &countingAdapter{gvr: gvr}
+	registry := adapters.NewRegistry()
+	require.NoError(t, registry.Register(adapter))
+
+	idx := indexer.New(nil)
+	engine := NewEngine(zap.NewNop(), nil, nil, registry, idx, 5*time.Minute)
+	engine.SetQueueOptions(opts)
+	t.Cleanup(engine.Stop)
+	return engine, idx, adapter, gvr
+}
+
+func startQueue(engine *Engine, gvr schema.GroupVersionResource) *gvrQueue {
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+	return engine.ensureQueueLocked(context.Background(), gvr)
+}
+
+func queuedObject(name, uid, revision string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("queue.test/v1")
+	obj.SetKind("Widget")
+	obj.SetNamespace("default")
+	obj.SetName(name)
+	obj.SetUID(types.UID(uid))
+	obj.SetAnnotations(map[string]string{"revision": revision})
+	return obj
+}
+
+func TestQueue_CoalescesUpdates(t *testing.T) {
+	engine, idx, adapter, gvr := setupQueueEngine(t, "coalesce", QueueOptions{Debounce: 200 * time.Millisecond})
+	q := startQueue(engine, gvr)
+
+	for i := 0; i < 10; i++ {
+		engine.enqueue(gvr, queuedObject("w", "uid-w", fmt.Sprintf("rev-%d", i)), false)
+	}
+	assert.Equal(t, float64(1), testutil.ToFloat64(queueDepth.WithLabelValues(q.label)))
+	assert.Equal(t, float64(9), testutil.ToFloat64(queueCoalesced.WithLabelValues(q.label)))
+
+	require.Eventually(t, func() bool { return idx.Count() == 1 }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 1, adapter.count(), "ten updates should be parsed once")
+	assert.Equal(t, "rev-9", idx.All()[0].Summary)
+	assert.Equal(t, float64(0), testutil.ToFloat64(queueDepth.WithLabelValues(q.label)))
+}
+
+func TestQueue_ProfileDebounceOverridesDefault(t *testing.T) {
+	engine, idx, _, gvr := setupQueueEngine(t, "profiledebounce", QueueOptions{Debounce: time.Hour})
+	zero := 0
+	require.NoError(t, engine.RegisterProfile(&v1alpha1.ConstraintProfile{
+		ObjectMeta: metav1.ObjectMeta{Name: "fast"},
+		Spec: v1alpha1.ConstraintProfileSpec{
+			GVR:             v1alpha1.GVRReference{Group: gvr.Group, Version: gvr.Version, Resource: gvr.Resource},
+			Adapter:         "counting",
+			Enabled:         true,
+			DebounceSeconds: &zero,
+		},
+	}))
+	startQueue(engine, gvr)
+
+	engine.enqueue(gvr, queuedObject("w", "uid-w", "rev-1"), false)
+	require.Eventually(t, func() bool { return idx.Count() == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	engine.mu.RLock()
+	defer engine.mu.RUnlock()
+	assert.Equal(t, time.Duration(0), engine.debounceLocked(gvr))
+	assert.Equal(t, time.Hour, engine.debounceLocked(schema.GroupVersionResource{Resource: "other"}))
+}
+
+func TestQueue_DeleteThenRecreate(t *testing.T) {
+	engine, idx, _, gvr := setupQueueEngine(t, "recreate", QueueOptions{Debounce: 100 * time.Millisecond})
+	old := queuedObject("w", "uid-old", "rev-1")
+	require.NoError(t, engine.upsertObject(context.Background(), gvr, old))
+	startQueue(engine, gvr)
+
+	engine.enqueue(gvr, cache.DeletedFinalStateUnknown{Key: "default/w", Obj: old}, true)
+	engine.enqueue(gvr, queuedObject("w", "uid-new", "rev-2"), false)
+
+	require.Eventually(t, func() bool {
+		all := idx.All()
+		return len(all) == 1 && all[0].UID == "uid-new"
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestQueue_DeleteRemovesConstraints(t *testing.T) {
+	engine, idx, _, gvr := setupQueueEngine(t, "delete", QueueOptions{})
+	obj := queuedObject("w", "uid-w", "rev-1")
+	require.NoError(t, engine.upsertObject(context.Background(), gvr, obj))
+	startQueue(engine, gvr)
+
+	engine.enqueue(gvr, obj, true)
+	require.Eventually(t, func() bool { return idx.Count() == 0 }, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestQueue_RetriesFailedParse(t *testing.T) {
+	engine, idx, adapter, gvr := setupQueueEngine(t, "retry", QueueOptions{MaxRetries: 3})
+	adapter.failures = 1
+	q := startQueue(engine, gvr)
+
+	engine.enqueue(gvr, queuedObject("w", "uid-w", "rev-1"), false)
+	require.Eventually(t, func() bool { return idx.Count() == 1 }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 2, adapter.count())
+	assert.Equal(t, float64(1), testutil.ToFloat64(parseResults.WithLabelValues(q.label, "retry")))
+}
+
+func TestQueue_DropsAfterMaxRetries(t *testing.T) {
+	engine, idx, adapter, gvr := setupQueueEngine(t, "drop", QueueOptions{MaxRetries: 1})
+	adapter.failures = 10
+	q := startQueue(engine, gvr)
+
+	engine.enqueue(gvr, queuedObject("w", "uid-w", "rev-1"), false)
+	require.Eventually(t, func() bool {
+		return testutil.ToFloat64(parseResults.WithLabelValues(q.label, "dropped")) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 0, idx.Count())
+	assert.Equal(t, 2, adapter.count())
+}
+
+func TestQueue_RateLimit(t *testing.T) {
+	engine, idx, _, gvr := setupQueueEngine(t, "ratelimit", QueueOptions{RateLimit: 10, Burst: 1})
+	startQueue(engine, gvr)
+
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		engine.enqueue(gvr, queuedObject(fmt.Sprintf("w-%d", i), fmt.Sprintf("uid-%d", i), "rev"), false)
+	}
+	require.Eventually(t, func() bool { return idx.Count() == 6 }, 5*time.Second, 10*time.Millisecond)
+	assert.GreaterOrEqual(t, time.Since(start), 400*time.Millisecond, "6 parses at 10/s with burst 1 take at least 0.5s")
+}
+
+func TestQueue_UnwatchedGVRDropped(t *testing.T) {
+	engine, idx, adapter, gvr := setupQueueEngine(t, "unwatched", QueueOptions{})
+
+	engine.enqueue(gvr, queuedObject("w", "uid-w", "rev-1"), false)
+	engine.enqueue(gvr, "not-an-unstructured", false)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 0, idx.Count())
+	assert.Equal(t, 0, adapter.count())
+}
+
+func TestQueue_StopDropsMetrics(t *testing.T) {
+	engine, _, _, gvr := setupQueueEngine(t, "stop", QueueOptions{Debounce: time.Hour})
+	q := startQueue(engine, gvr)
+
+	engine.enqueue(gvr, queuedObject("a", "uid-a", "rev"), false)
+	engine.enqueue(gvr, queuedObject("b", "uid-b", "rev"), false)
+	assert.Equal(t, float64(2), testutil.ToFloat64(queueDepth.WithLabelValues(q.label)))
+
+	engine.mu.Lock()
+	engine.stopGVRInformerLocked(gvr)
+	engine.mu.Unlock()
+	assert.False(t, queueDepth.DeleteLabelValues(q.label), "depth series should be removed with the queue")
+	assert.False(t, queueEvents.DeleteLabelValues(q.label), "event series should be removed with the queue")
+}
@@ -0,0 +1,183 @@
+package discovery
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/nightjarctl/nightjar/internal/scope"
+)
+
+// SetScope restricts the engine to namespaced objects in the given namespace
+// scope. Cluster-scoped objects are always parsed. Must be called before Start.
+func (e *Engine) SetScope(s *scope.Scope) {
+	e.mu.Lock()
+	e.nsScope = s
+	e.mu.Unlock()
+	s.OnChange(e.onScopeChange)
+}
+
+// SetScopedCache keeps only a stub of each object outside the namespace
+// scope in the informer caches: its name, namespace, UID and resource
+// version. Every type is still watched with one cluster-wide informer, but
+// only in-scope objects are held in full, so use it when the scope is a
+// small part of the cluster, e.g. one shard of it. Objects of namespaces
+// that enter the scope are listed again. Requires a scope; must be called
+// before Start.
+func (e *Engine) SetScopedCache(enabled bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.scopedCache = enabled
+}
+
+// scopedCacheEnabled reports whether SetScopedCache is in effect.
+func (e *Engine) scopedCacheEnabled() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.scopedCache && e.nsScope != nil
+}
+
+// transformLocked returns the informer transform: trimObject and, with
+// SetScopedCache, stubObject for objects outside the scope. Caller must hold
+// e.mu.
+func (e *Engine) transformLocked() cache.TransformFunc {
+	s := e.nsScope
+	if !e.scopedCache || s == nil {
+		return trimObject
+	}
+	return func(obj interface{}) (interface{}, error) {
+		if u, ok := obj.(*unstructured.Unstructured); ok && !s.Allows(u.GetNamespace()) {
+			return stubObject(u), nil
+		}
+		return trimObject(obj)
+	}
+}
+
+// inScope reports whether objects in namespace should be indexed.
+func (e *Engine) inScope(namespace string) bool {
+	e.mu.RLock()
+	s := e.nsScope
+	e.mu.RUnlock()
+	return s.Allows(namespace)
+}
+
+// pruneOutOfScope removes constraints indexed from namespaces outside the
+// scope, e.g. by profile informers started before the scope synced.
+func (e *Engine) pruneOutOfScope() {
+	e.mu.RLock()
+	s := e.nsScope
+	e.mu.RUnlock()
+	if s == nil {
+		return
+	}
+	if deleted := e.deleteNamespacedConstraints(func(ns string) bool { return !s.Allows(ns) }); deleted > 0 {
+		e.logger.Info("Removed constraints of namespaces outside the scope", zap.Int("constraints", deleted))
+	}
+}
+
+// deleteNamespacedConstraints deletes the namespaced constraints whose
+// namespace matches and returns how many were deleted.
+func (e *Engine) deleteNamespacedConstraints(match func(namespace string) bool) int {
+	e.forgetProduced(func(key objectKey) bool { return key.namespace != "" && match(key.namespace) })
+	deleted := 0
+	for _, c := range e.indexer.All() {
+		if c.Namespace != "" && match(c.Namespace) {
+			e.indexer.Delete(c.UID)
+			deleted++
+		}
+	}
+	return deleted
+}
+
+// onScopeChange removes the constraints of namespaces that left the scope and
+// re-queues the objects of namespaces that entered it.
+func (e *Engine) onScopeChange(change scope.Change) {
+	if len(change.Removed) > 0 {
+		removed := make(map[string]bool, len(change.Removed))
+		for _, ns := range change.Removed {
+			removed[ns] = true
+		}
+		deleted := e.deleteNamespacedConstraints(func(ns string) bool { return removed[ns] })
+		e.logger.Info("Removed constraints of namespaces that left the scope",
+			zap.Strings("namespaces", change.Removed),
+			zap.Int("constraints", deleted))
+	}
+
+	if len(change.Added) == 0 {
+		return
+	}
+	queued := e.requeueNamespaces(change.Added)
+	e.logger.Info("Re-queued objects of namespaces that entered the scope",
+		zap.Strings("namespaces", change.Added),
+		zap.Int("objects", queued))
+}
+
+// requeueNamespaces queues the informer-cached objects of namespaces for
+// parsing without waiting for an informer event, and returns how many were
+// queued. Objects cached as stubs are listed again, once per type and
+// namespace; those whose watch event has since cached them in full were
+// queued by that event.
+func (e *Engine) requeueNamespaces(namespaces []string) int {
+	wanted := make(map[string]bool, len(namespaces))
+	for _, ns := range namespaces {
+		wanted[ns] = true
+	}
+
+	type cached struct {
+		gvr schema.GroupVersionResource
+		obj *unstructured.Unstructured
+	}
+	type stubbed struct {
+		gvr       schema.GroupVersionResource
+		namespace string
+	}
+	var objs []cached
+	relist := make(map[stubbed]bool)
+	e.mu.RLock()
+	ctx := e.ctx
+	for gvr, informer := range e.informers {
+		for _, item := range informer.GetStore().List() {
+			u, ok := item.(*unstructured.Unstructured)
+			switch {
+			case !ok || !wanted[u.GetNamespace()]:
+			case isStub(u):
+				relist[stubbed{gvr: gvr, namespace: u.GetNamespace()}] = true
+			default:
+				objs = append(objs, cached{gvr: gvr, obj: u})
+			}
+		}
+	}
+	e.mu.RUnlock()
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	for key := range relist {
+		list, err := e.dynamicClient.Resource(key.gvr).Namespace(key.namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			e.logger.Warn("Failed to list objects of a namespace that entered the scope",
+				zap.String("gvr", key.gvr.String()),
+				zap.String("namespace", key.namespace),
+				zap.Error(err))
+			continue
+		}
+		for i := range list.Items {
+			obj := &list.Items[i]
+			if current, ok := e.cachedObject(key.gvr, obj.GetNamespace(), obj.GetName()); ok && !isStub(current) {
+				continue
+			}
+			if _, err := trimObject(obj); err == nil {
+				objs = append(objs, cached{gvr: key.gvr, obj: obj})
+			}
+		}
+	}
+
+	for _, c := range objs {
+		e.enqueue(c.gvr, c.obj, false)
+	}
+	return len(objs)
+}
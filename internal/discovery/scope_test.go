@@ -0,0 +1,202 @@
+package discovery
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+
+	v1alpha1 "github.com/nightjarctl/nightjar/api/v1alpha1"
+	"github.com/nightjarctl/nightjar/internal/adapters"
+	"github.com/nightjarctl/nightjar/internal/indexer"
+	"github.com/nightjarctl/nightjar/internal/scope"
+	internaltypes "github.com/nightjarctl/nightjar/internal/types"
+)
+
+func TestScope_FiltersAndFollowsChanges(t *testing.T) {
+	client := newCRDWatchClient()
+	ctx := context.Background()
+	inDefault := newPolicy("v1", "allowed", "uid-default")
+	inKubeSystem := newPolicy("v1", "excluded", "uid-kube-system")
+	inKubeSystem.SetNamespace("kube-system")
+	_, err := client.Resource(policiesV1).Namespace("default").Create(ctx, inDefault, metav1.CreateOptions{})
+	require.NoError(t, err)
+	_, err = client.Resource(policiesV1).Namespace("kube-system").Create(ctx, inKubeSystem, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	nsScope := scope.New(fake.NewSimpleClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system"}},
+	), zap.NewNop(), scope.Options{})
+	scopeCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	go func() { _ = nsScope.Start(scopeCtx) }()
+	require.True(t, nsScope.WaitForSync(scopeCtx))
+	require.NoError(t, nsScope.Update(scope.Config{ExcludeNamespaces: []string{"kube-*"}}))
+
+	idx := indexer.New(nil)
+	engine := NewEngine(zap.NewNop(), newMockDiscovery(servedPolicies("v1")), client, adapters.NewRegistry(), idx, 5*time.Minute)
+	engine.SetScope(nsScope)
+	t.Cleanup(engine.Stop)
+
+	require.NoError(t, engine.scan(ctx))
+	assert.Eventually(t, func() bool {
+		_, ok := sourcesOf(idx)["uid-default"]
+		return ok
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.NotContains(t, sourcesOf(idx), types.UID("uid-kube-system"), "excluded namespace is not indexed")
+
+	// Swapping the exclusion removes default's constraints and indexes the
+	// cached kube-system object without waiting for an informer event.
+	require.NoError(t, nsScope.Update(scope.Config{ExcludeNamespaces: []string{"default"}}))
+	assert.NotContains(t, sourcesOf(idx), types.UID("uid-default"))
+	assert.Eventually(t, func() bool {
+		_, ok := sourcesOf(idx)["uid-kube-system"]
+		return ok
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestPruneOutOfScope(t *testing.T) {
+	idx := indexer.New(nil)
+	engine := NewEngine(zap.NewNop(), nil, nil, adapters.NewRegistry(), idx, 5*time.Minute)
+	idx.Upsert(internaltypes.Constraint{UID: "in-kube-system", Namespace: "kube-system"})
+	idx.Upsert(internaltypes.Constraint{UID: "in-default", Namespace: "default"})
+	idx.Upsert(internaltypes.Constraint{UID: "cluster-wide"})
+
+	engine.pruneOutOfScope()
+	assert.Equal(t, 3, idx.Count(), "no scope prunes nothing")
+
+	nsScope := scope.New(fake.NewSimpleClientset(), zap.NewNop(), scope.Options{})
+	require.NoError(t, nsScope.Update(scope.Config{ExcludeNamespaces: []string{"kube-*"}}))
+	engine.SetScope(nsScope)
+	engine.pruneOutOfScope()
+	assert.NotContains(t, sourcesOf(idx), types.UID("in-kube-system"))
+	assert.Equal(t, 2, idx.Count())
+}
+
+// ownedNamespaces is a scope.Partition owning a fixed set of namespaces.
+type ownedNamespaces struct {
+	mu    sync.Mutex
+	owned string
+}
+
+func (o *ownedNamespaces) Owns(namespace string) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return namespace == o.owned
+}
+
+func (o *ownedNamespaces) set(namespace string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.owned = namespace
+}
+
+func TestScopedCache_FollowsScope(t *testing.T) {
+	client := newCRDWatchClient()
+	ctx := context.Background()
+	inDefault := newPolicy("v1", "default-policy", "uid-default")
+	inTeamA := newPolicy("v1", "team-a-policy", "uid-team-a")
+	inTeamA.SetNamespace("team-a")
+	_, err := client.Resource(policiesV1).Namespace("default").Create(ctx, inDefault, metav1.CreateOptions{})
+	require.NoError(t, err)
+	_, err = client.Resource(policiesV1).Namespace("team-a").Create(ctx, inTeamA, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	partition := &ownedNamespaces{owned: "default"}
+	nsScope := scope.New(fake.NewSimpleClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a"}},
+	), zap.NewNop(), scope.Options{DisableConfigMap: true})
+	nsScope.SetPartition(partition)
+	scopeCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	go func() { _ = nsScope.Start(scopeCtx) }()
+	require.True(t, nsScope.WaitForSync(scopeCtx))
+
+	idx := indexer.New(nil)
+	engine := NewEngine(zap.NewNop(), newMockDiscovery(servedPolicies("v1")), client, adapters.NewRegistry(), idx, 5*time.Minute)
+	engine.SetScope(nsScope)
+	engine.SetScopedCache(true)
+	t.Cleanup(engine.Stop)
+
+	require.NoError(t, engine.scan(ctx))
+	assert.Eventually(t, func() bool { return engine.GVRSynced(policiesV1) }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, map[types.UID]schema.GroupVersionResource{"uid-default": policiesV1}, sourcesOf(idx))
+	own, found := engine.cachedObject(policiesV1, "default", "default-policy")
+	require.True(t, found)
+	assert.False(t, isStub(own))
+	other, found := engine.cachedObject(policiesV1, "team-a", "team-a-policy")
+	require.True(t, found, "one cluster-wide informer watches every namespace")
+	assert.True(t, isStub(other), "other shards' objects are cached as stubs")
+	assert.NotContains(t, other.Object, "spec")
+
+	// The shard is handed off: the old namespace's constraints go, and the
+	// new one's stubs are listed again and indexed.
+	partition.set("team-a")
+	nsScope.Repartition()
+	assert.Eventually(t, func() bool {
+		sources := sourcesOf(idx)
+		_, gained := sources["uid-team-a"]
+		_, kept := sources["uid-default"]
+		return gained && !kept
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestScopedCache_Profile(t *testing.T) {
+	client := newCRDWatchClient()
+	ctx := context.Background()
+	inDefault := newPolicy("v1", "default-policy", "uid-default")
+	inTeamA := newPolicy("v1", "team-a-policy", "uid-team-a")
+	inTeamA.SetNamespace("team-a")
+	_, err := client.Resource(policiesV1).Namespace("default").Create(ctx, inDefault, metav1.CreateOptions{})
+	require.NoError(t, err)
+	_, err = client.Resource(policiesV1).Namespace("team-a").Create(ctx, inTeamA, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	nsScope := scope.New(fake.NewSimpleClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a"}},
+	), zap.NewNop(), scope.Options{DisableConfigMap: true})
+	nsScope.SetPartition(&ownedNamespaces{owned: "default"})
+
+	idx := indexer.New(nil)
+	engine := NewEngine(zap.NewNop(), newMockDiscovery(servedPolicies("v1")), client, adapters.NewRegistry(), idx, 5*time.Minute)
+	engine.SetScope(nsScope)
+	engine.SetScopedCache(true)
+	t.Cleanup(engine.Stop)
+
+	// Profiles are registered before the scope syncs.
+	require.NoError(t, engine.RegisterProfile(&v1alpha1.ConstraintProfile{
+		ObjectMeta: metav1.ObjectMeta{Name: "policies"},
+		Spec: v1alpha1.ConstraintProfileSpec{
+			GVR:     v1alpha1.GVRReference{Group: policiesV1.Group, Version: policiesV1.Version, Resource: policiesV1.Resource},
+			Adapter: "generic",
+			Enabled: true,
+		},
+	}))
+	scopeCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	go func() { _ = nsScope.Start(scopeCtx) }()
+	require.True(t, nsScope.WaitForSync(scopeCtx))
+	assert.Eventually(t, func() bool { return engine.GVRSynced(policiesV1) }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 1, engine.requeueNamespaces(nsScope.Namespaces()))
+
+	assert.Eventually(t, func() bool {
+		_, ok := sourcesOf(idx)["uid-default"]
+		return ok
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.NotContains(t, sourcesOf(idx), types.UID("uid-team-a"))
+	other, found := engine.cachedObject(policiesV1, "team-a", "team-a-policy")
+	require.True(t, found)
+	assert.True(t, isStub(other), "profile informers cache other shards' objects as stubs")
+}
@@ -0,0 +1,87 @@
+package discovery
+
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+)
+
+// trackSyncLocked records the handler registration of a newly started
+// informer for gvr, so GVRSynced can tell when its initial list has been
+// indexed. Caller must hold e.mu.
+func (e *Engine) trackSyncLocked(gvr schema.GroupVersionResource, registration cache.ResourceEventHandlerRegistration) {
+	e.handlerSynced[gvr] = registration.HasSynced
+	delete(e.synced, gvr)
+}
+
+// GVRSynced reports whether the informer of gvr has delivered its initial
+// list and every object in it has been parsed into the index. GVRs without
+// an informer are reported synced: there is nothing to wait for. Once
+// synced, a GVR stays synced until its informer is stopped.
+func (e *Engine) GVRSynced(gvr schema.GroupVersionResource) bool {
+	e.mu.RLock()
+	synced, ok := e.gvrSyncedLocked(gvr)
+	e.mu.RUnlock()
+	if synced && !ok {
+		e.mu.Lock()
+		e.latchSyncedLocked(gvr)
+		e.mu.Unlock()
+	}
+	return synced
+}
+
+// HasSynced reports whether the initial scan has run and every watched GVR
+// has synced (see GVRSynced).
+func (e *Engine) HasSynced() bool {
+	e.mu.RLock()
+	if e.lastScan.IsZero() {
+		e.mu.RUnlock()
+		return false
+	}
+	var newlySynced []schema.GroupVersionResource
+	for gvr := range e.informers {
+		synced, latched := e.gvrSyncedLocked(gvr)
+		if !synced {
+			e.mu.RUnlock()
+			return false
+		}
+		if !latched {
+			newlySynced = append(newlySynced, gvr)
+		}
+	}
+	e.mu.RUnlock()
+
+	if len(newlySynced) > 0 {
+		e.mu.Lock()
+		for _, gvr := range newlySynced {
+			e.latchSyncedLocked(gvr)
+		}
+		e.mu.Unlock()
+	}
+	return true
+}
+
+// gvrSyncedLocked reports whether gvr is synced, and whether that was
+// already latched. Caller must hold e.mu (read or write).
+func (e *Engine) gvrSyncedLocked(gvr schema.GroupVersionResource) (synced, latched bool) {
+	if e.synced[gvr] {
+		return true, true
+	}
+	handlerSynced, ok := e.handlerSynced[gvr]
+	if !ok {
+		// No informer, or one that was torn down.
+		return true, true
+	}
+	if !handlerSynced() {
+		return false, false
+	}
+	q := e.queues[gvr]
+	return q == nil || q.idle(), false
+}
+
+// latchSyncedLocked marks gvr synced if its informer is still running.
+// Caller must hold e.mu.
+func (e *Engine) latchSyncedLocked(gvr schema.GroupVersionResource) {
+	if _, ok := e.handlerSynced[gvr]; ok {
+		e.synced[gvr] = true
+	}
+}
@@ -0,0 +1,59 @@
+package discovery
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/nightjarctl/nightjar/internal/adapters"
+	"github.com/nightjarctl/nightjar/internal/indexer"
+)
+
+func TestGVRSynced_WaitsForQueue(t *testing.T) {
+	client := newCRDWatchClient()
+	ctx := context.Background()
+	_, err := client.Resource(policiesV1).Namespace("default").Create(ctx, newPolicy("v1", "pending", "uid-pending"), metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	engine := NewEngine(zap.NewNop(), newMockDiscovery(servedPolicies("v1")), client, adapters.NewRegistry(), indexer.New(nil), 5*time.Minute)
+	t.Cleanup(engine.Stop)
+	// Parses are held back, so the initial list never finishes indexing.
+	engine.SetQueueOptions(QueueOptions{Debounce: time.Hour})
+	require.NoError(t, engine.scan(ctx))
+
+	require.Eventually(t, func() bool {
+		engine.mu.RLock()
+		defer engine.mu.RUnlock()
+		return engine.handlerSynced[policiesV1]()
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.False(t, engine.GVRSynced(policiesV1), "listed but not yet indexed")
+	assert.False(t, engine.HasSynced())
+	assert.True(t, engine.GVRSynced(policiesV1beta1), "unwatched GVRs have nothing to wait for")
+}
+
+func TestGVRSynced_LatchesUntilStopped(t *testing.T) {
+	client := newCRDWatchClient()
+	ctx := context.Background()
+	_, err := client.Resource(policiesV1).Namespace("default").Create(ctx, newPolicy("v1", "live", "uid-live"), metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	idx := indexer.New(nil)
+	engine := NewEngine(zap.NewNop(), newMockDiscovery(servedPolicies("v1")), client, adapters.NewRegistry(), idx, 5*time.Minute)
+	t.Cleanup(engine.Stop)
+	require.NoError(t, engine.scan(ctx))
+
+	require.Eventually(t, func() bool { return engine.GVRSynced(policiesV1) }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 1, idx.Count(), "synced means indexed")
+	assert.True(t, engine.HasSynced())
+
+	engine.mu.Lock()
+	assert.True(t, engine.synced[policiesV1])
+	engine.stopGVRInformerLocked(policiesV1)
+	assert.NotContains(t, engine.synced, policiesV1)
+	engine.mu.Unlock()
+}
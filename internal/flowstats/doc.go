@@ -0,0 +1,41 @@
+// Package flowstats aggregates correlated Hubble flow drops into rolling
+// per-path statistics.
+//
+// # Overview
+//
+// The correlator deduplicates flow drop notifications for five minutes, so
+// notifications alone cannot tell how often a connection is being dropped.
+// The Store counts every correlated drop instead, keyed by source workload,
+// destination workload and Service, port, protocol, direction and the policy
+// that dropped it:
+//
+//	checkout/checkout -> cache/redis (redis:6379) TCP 6379, NetworkPolicy cache/deny-all: 14302 in the last hour
+//
+// Each entry records its total count, the count within the rolling window
+// (one hour by default), first and last seen times, and a few sample flows.
+// Entries not seen within the window expire, and the least recently seen
+// entry is evicted when the store is full.
+//
+// # Consumers
+//
+// The report reconciler lists a namespace's busiest drop paths in the
+// ConstraintReport status, nightjar_query returns them alongside the
+// constraints, and every entry is exported as a Prometheus counter.
+//
+// # Privacy Considerations
+//
+// Entries hold workload and pod identities from both ends of a connection.
+// Use Entry.Scoped before showing an entry to a namespace's developers.
+//
+// # Metrics
+//
+// The store exposes the following Prometheus metrics:
+//   - nightjar_flow_drops_aggregated_total (counter, labels: source_namespace,
+//     source_workload, destination_namespace, destination_workload,
+//     destination_service, port, protocol, direction, policy_source,
+//     policy_namespace, policy): drops per entry; a series is removed when
+//     its entry expires or is evicted
+//   - nightjar_flow_drop_stats_entries (gauge): entries in the store
+//   - nightjar_flow_drop_stats_evictions_total (counter): entries evicted
+//     because the store was full
+package flowstats
@@ -0,0 +1,467 @@
+package flowstats
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/nightjarctl/nightjar/internal/hubble"
+	"github.com/nightjarctl/nightjar/internal/servicemap"
+	"github.com/nightjarctl/nightjar/internal/types"
+)
+
+// StoreOptions configures the Store.
+type StoreOptions struct {
+	// Window is the span of the rolling drop count. Entries not seen within
+	// it expire. Default: 1 hour.
+	Window time.Duration
+
+	// Resolution is the granularity of the rolling window. Default: 1 minute.
+	Resolution time.Duration
+
+	// MaxEntries bounds the number of entries; the least recently seen entry
+	// is evicted to make room. Default: 10000.
+	MaxEntries int
+
+	// MaxSamples is the number of most recent flows kept per entry.
+	// Default: 3.
+	MaxSamples int
+
+	// Logger for the store
+	Logger *zap.Logger
+}
+
+// DefaultStoreOptions returns default options for the Store.
+func DefaultStoreOptions() StoreOptions {
+	return StoreOptions{
+		Window:     time.Hour,
+		Resolution: time.Minute,
+		MaxEntries: 10000,
+		MaxSamples: 3,
+		Logger:     zap.NewNop(),
+	}
+}
+
+// Endpoint is one end of an aggregated drop path.
+type Endpoint struct {
+	Namespace string
+	// Workload is the owning workload's name, the pod name when Hubble
+	// reports no workload, or the IP for endpoints outside the cluster.
+	Workload string
+	// Service is the name of the Service the connection was addressed to.
+	// Only set on destinations.
+	Service string
+}
+
+// PolicyRef identifies the policy that dropped the flows.
+type PolicyRef struct {
+	Source    schema.GroupVersionResource
+	Namespace string
+	Name      string
+}
+
+// Key identifies an aggregated drop path.
+type Key struct {
+	Source      Endpoint
+	Destination Endpoint
+	Port        uint32
+	Protocol    string
+	Direction   hubble.TrafficDirection
+	Policy      PolicyRef
+}
+
+// Sample is a dropped flow kept as an example of an entry.
+type Sample struct {
+	Time            time.Time
+	TraceID         string
+	SourcePod       string
+	SourceIP        string
+	SourcePort      uint32
+	DestinationPod  string
+	DestinationIP   string
+	DestinationPort uint32
+}
+
+// Entry holds the statistics of a drop path.
+type Entry struct {
+	Key
+
+	// Count is the number of drops since FirstSeen.
+	Count uint64
+	// RecentCount is the number of drops within the store's window.
+	RecentCount uint64
+
+	FirstSeen time.Time
+	LastSeen  time.Time
+
+	// Samples are the most recent drops, oldest first.
+	Samples []Sample
+}
+
+// bucket counts the drops of one Resolution interval.
+type bucket struct {
+	start time.Time
+	count uint64
+}
+
+type entry struct {
+	Entry
+	buckets []bucket // ring indexed by interval
+}
+
+// Store aggregates correlated flow drops. It is safe for concurrent use.
+type Store struct {
+	opts   StoreOptions
+	logger *zap.Logger
+	now    func() time.Time
+
+	mu      sync.Mutex
+	entries map[Key]*entry
+	changed map[string]bool
+}
+
+// NewStore creates a new Store. Call Start to expire old entries.
+func NewStore(opts StoreOptions) *Store {
+	defaults := DefaultStoreOptions()
+	if opts.Logger == nil {
+		opts.Logger = defaults.Logger
+	}
+	if opts.Window <= 0 {
+		opts.Window = defaults.Window
+	}
+	if opts.Resolution <= 0 {
+		opts.Resolution = defaults.Resolution
+	}
+	if opts.Resolution > opts.Window {
+		opts.Resolution = opts.Window
+	}
+	if opts.MaxEntries <= 0 {
+		opts.MaxEntries = defaults.MaxEntries
+	}
+	if opts.MaxSamples <= 0 {
+		opts.MaxSamples = defaults.MaxSamples
+	}
+
+	return &Store{
+		opts:    opts,
+		logger:  opts.Logger.Named("flowstats"),
+		now:     time.Now,
+		entries: make(map[Key]*entry),
+		changed: make(map[string]bool),
+	}
+}
+
+// Start expires entries not seen within the window every Resolution.
+// Blocks until context is cancelled.
+func (s *Store) Start(ctx context.Context) error {
+	ticker := time.NewTicker(s.opts.Resolution)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if n := s.prune(); n > 0 {
+				s.logger.Debug("Expired flow drop statistics", zap.Int("entries", n))
+			}
+		}
+	}
+}
+
+// Record counts a drop the correlator matched to constraint. services are the
+// Services the connection was addressed to; the first one keys the entry.
+func (s *Store) Record(drop hubble.FlowDrop, constraint types.Constraint, services []servicemap.ServiceInfo) {
+	key := Key{
+		Source:      endpointOf(drop.Source, drop.IP.Source),
+		Destination: endpointOf(drop.Destination, drop.IP.Destination),
+		Port:        drop.L4.DestinationPort,
+		Protocol:    string(drop.L4.Protocol),
+		Direction:   drop.Direction,
+		Policy: PolicyRef{
+			Source:    constraint.Source,
+			Namespace: constraint.Namespace,
+			Name:      constraint.Name,
+		},
+	}
+	if len(services) > 0 {
+		key.Destination.Service = services[0].Name
+		if key.Destination.Namespace == "" {
+			key.Destination.Namespace = services[0].Namespace
+		}
+	}
+
+	now := s.now()
+	t := drop.Time
+	if t.IsZero() {
+		t = now
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok {
+		if len(s.entries) >= s.opts.MaxEntries {
+			s.evictLocked()
+		}
+		e = &entry{
+			Entry:   Entry{Key: key, FirstSeen: t},
+			buckets: make([]bucket, s.bucketCount()),
+		}
+		s.entries[key] = e
+		entriesGauge.Set(float64(len(s.entries)))
+	}
+
+	e.Count++
+	if t.Before(e.FirstSeen) {
+		e.FirstSeen = t
+	}
+	if t.After(e.LastSeen) {
+		e.LastSeen = t
+	}
+	s.countLocked(e, t)
+
+	e.Samples = append(e.Samples, Sample{
+		Time:            t,
+		TraceID:         drop.TraceID,
+		SourcePod:       drop.Source.PodName,
+		SourceIP:        drop.IP.Source,
+		SourcePort:      drop.L4.SourcePort,
+		DestinationPod:  drop.Destination.PodName,
+		DestinationIP:   drop.IP.Destination,
+		DestinationPort: drop.L4.DestinationPort,
+	})
+	if n := len(e.Samples) - s.opts.MaxSamples; n > 0 {
+		e.Samples = append(e.Samples[:0:0], e.Samples[n:]...)
+	}
+
+	aggregatedDrops.WithLabelValues(metricLabels(key)...).Inc()
+	s.markChangedLocked(key)
+}
+
+// endpointOf converts a Hubble endpoint to the aggregated Endpoint.
+func endpointOf(ep hubble.Endpoint, ip string) Endpoint {
+	result := Endpoint{Namespace: ep.Namespace, Workload: ep.PodName}
+	if len(ep.Workloads) > 0 && ep.Workloads[0].Name != "" {
+		result.Workload = ep.Workloads[0].Name
+	}
+	if result.Workload == "" {
+		result.Workload = ip
+	}
+	return result
+}
+
+// bucketCount returns the number of Resolution intervals in the window.
+func (s *Store) bucketCount() int {
+	n := int(s.opts.Window / s.opts.Resolution)
+	if s.opts.Window%s.opts.Resolution != 0 {
+		n++
+	}
+	return n
+}
+
+// countLocked adds a drop at t to the rolling window of e. Drops older than
+// the interval its ring slot now holds are outside the window.
+func (s *Store) countLocked(e *entry, t time.Time) {
+	start := t.Truncate(s.opts.Resolution)
+	b := &e.buckets[int(start.UnixNano()/int64(s.opts.Resolution))%len(e.buckets)]
+	switch {
+	case b.start.Equal(start):
+		b.count++
+	case start.After(b.start):
+		*b = bucket{start: start, count: 1}
+	}
+}
+
+// recentCount returns the drops of e within the window ending at now.
+func (s *Store) recentCount(e *entry, now time.Time) uint64 {
+	cutoff := now.Add(-s.opts.Window)
+	var n uint64
+	for _, b := range e.buckets {
+		if b.start.After(cutoff) {
+			n += b.count
+		}
+	}
+	return n
+}
+
+// evictLocked removes the least recently seen entry.
+func (s *Store) evictLocked() {
+	var oldest *entry
+	for _, e := range s.entries {
+		if oldest == nil || e.LastSeen.Before(oldest.LastSeen) {
+			oldest = e
+		}
+	}
+	if oldest != nil {
+		s.removeLocked(oldest.Key)
+		evictions.Inc()
+	}
+}
+
+// prune removes the entries not seen within the window and returns how
+// many were removed.
+func (s *Store) prune() int {
+	cutoff := s.now().Add(-s.opts.Window)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := 0
+	for key, e := range s.entries {
+		if e.LastSeen.Before(cutoff) {
+			s.removeLocked(key)
+			removed++
+		}
+	}
+	return removed
+}
+
+func (s *Store) removeLocked(key Key) {
+	delete(s.entries, key)
+	aggregatedDrops.DeleteLabelValues(metricLabels(key)...)
+	entriesGauge.Set(float64(len(s.entries)))
+	s.markChangedLocked(key)
+}
+
+func (s *Store) markChangedLocked(key Key) {
+	for _, ns := range []string{key.Source.Namespace, key.Destination.Namespace} {
+		if ns != "" {
+			s.changed[ns] = true
+		}
+	}
+}
+
+// ChangedNamespaces returns the namespaces whose entries were recorded,
+// expired or evicted since the previous call.
+func (s *Store) ChangedNamespaces() []string {
+	s.mu.Lock()
+	changed := s.changed
+	s.changed = make(map[string]bool)
+	s.mu.Unlock()
+
+	result := make([]string, 0, len(changed))
+	for ns := range changed {
+		result = append(result, ns)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// ForNamespace returns the entries whose source or destination is in
+// namespace, with the most recent drops first.
+func (s *Store) ForNamespace(namespace string) []Entry {
+	return s.list(func(k Key) bool {
+		return k.Source.Namespace == namespace || k.Destination.Namespace == namespace
+	})
+}
+
+// All returns every entry, with the most recent drops first.
+func (s *Store) All() []Entry {
+	return s.list(func(Key) bool { return true })
+}
+
+// Window returns the span of the entries' RecentCount.
+func (s *Store) Window() time.Duration {
+	return s.opts.Window
+}
+
+// Len returns the number of entries.
+func (s *Store) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// list returns copies of the matching entries, sorted by RecentCount, then
+// Count, then LastSeen, all descending.
+func (s *Store) list(match func(Key) bool) []Entry {
+	now := s.now()
+
+	s.mu.Lock()
+	var result []Entry
+	for key, e := range s.entries {
+		if !match(key) {
+			continue
+		}
+		out := e.Entry
+		out.RecentCount = s.recentCount(e, now)
+		out.Samples = append([]Sample(nil), e.Samples...)
+		result = append(result, out)
+	}
+	s.mu.Unlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.RecentCount != b.RecentCount {
+			return a.RecentCount > b.RecentCount
+		}
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.LastSeen.After(b.LastSeen)
+	})
+	return result
+}
+
+// Scoped returns the entry as it may be shown to viewers of
+// viewerNamespace at the given detail level. Below full detail, workloads,
+// Services and pods in other namespaces are removed, and so is the name of
+// a policy the viewer could not see in a constraint query; at summary level
+// the other namespace is removed too.
+func (e Entry) Scoped(viewerNamespace string, level types.DetailLevel) Entry {
+	if level == types.DetailLevelFull {
+		return e
+	}
+
+	out := e
+	out.Source = scopedEndpoint(e.Source, viewerNamespace, level)
+	out.Destination = scopedEndpoint(e.Destination, viewerNamespace, level)
+
+	showPolicy := e.Policy.Namespace == viewerNamespace
+	if level == types.DetailLevelDetailed && e.Policy.Namespace == "" {
+		showPolicy = true
+	}
+	if !showPolicy {
+		out.Policy.Name = ""
+		out.Policy.Namespace = ""
+	}
+
+	out.Samples = make([]Sample, len(e.Samples))
+	for i, sample := range e.Samples {
+		if e.Source.Namespace != viewerNamespace {
+			sample.SourcePod, sample.SourceIP = "", ""
+		}
+		if e.Destination.Namespace != viewerNamespace {
+			sample.DestinationPod, sample.DestinationIP = "", ""
+		}
+		out.Samples[i] = sample
+	}
+	return out
+}
+
+func scopedEndpoint(ep Endpoint, viewerNamespace string, level types.DetailLevel) Endpoint {
+	if ep.Namespace == viewerNamespace {
+		return ep
+	}
+	if level == types.DetailLevelDetailed {
+		return Endpoint{Namespace: ep.Namespace}
+	}
+	return Endpoint{}
+}
+
+// metricLabels returns the label values of an entry's counter.
+func metricLabels(k Key) []string {
+	return []string{
+		k.Source.Namespace, k.Source.Workload,
+		k.Destination.Namespace, k.Destination.Workload, k.Destination.Service,
+		strconv.FormatUint(uint64(k.Port), 10), k.Protocol, string(k.Direction),
+		k.Policy.Source.Resource, k.Policy.Namespace, k.Policy.Name,
+	}
+}
@@ -0,0 +1,238 @@
+package flowstats
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/nightjarctl/nightjar/internal/hubble"
+	"github.com/nightjarctl/nightjar/internal/servicemap"
+	"github.com/nightjarctl/nightjar/internal/types"
+)
+
+var (
+	t0 = time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+
+	denyCache = types.Constraint{
+		Source:    schema.GroupVersionResource{Group: "networking.k8s.io", Version: "v1", Resource: "networkpolicies"},
+		Namespace: "cache",
+		Name:      "deny-all",
+	}
+	redisService = []servicemap.ServiceInfo{{Name: "redis", Namespace: "cache", Port: 6379}}
+)
+
+// newTestStore returns a store whose clock is read from *now.
+func newTestStore(t *testing.T, opts StoreOptions, now *time.Time) *Store {
+	t.Helper()
+	s := NewStore(opts)
+	s.now = func() time.Time { return *now }
+	return s
+}
+
+// checkoutDrop is a drop from checkout to redis at time at.
+func checkoutDrop(pod string, at time.Time) hubble.FlowDrop {
+	return hubble.NewFlowDropBuilder().
+		WithTime(at).
+		WithTraceID(pod+"@"+at.Format(time.RFC3339Nano)).
+		WithSource("checkout", pod, nil).
+		WithSourceWorkload("Deployment", "checkout").
+		WithDestination("cache", "redis-0", nil).
+		WithDestinationWorkload("StatefulSet", "redis").
+		WithIP("10.0.1.5", "10.0.2.9").
+		WithTCP(40000, 6379, hubble.TCPFlags{SYN: true}).
+		WithDropReason(hubble.DropReasonPolicy).
+		WithDirection(hubble.DirectionIngress).
+		Build()
+}
+
+func TestStore_AggregatesByWorkloadAndPolicy(t *testing.T) {
+	now := t0
+	s := newTestStore(t, StoreOptions{MaxSamples: 2}, &now)
+	counter := aggregatedDrops.WithLabelValues(metricLabels(Key{
+		Source:      Endpoint{Namespace: "checkout", Workload: "checkout"},
+		Destination: Endpoint{Namespace: "cache", Workload: "redis", Service: "redis"},
+		Port:        6379,
+		Protocol:    "TCP",
+		Direction:   hubble.DirectionIngress,
+		Policy:      PolicyRef{Source: denyCache.Source, Namespace: "cache", Name: "deny-all"},
+	})...)
+	before := testutil.ToFloat64(counter)
+
+	// Replicas of one workload share an entry.
+	for i, pod := range []string{"checkout-a", "checkout-b", "checkout-a"} {
+		s.Record(checkoutDrop(pod, t0.Add(time.Duration(i)*time.Second)), denyCache, redisService)
+	}
+	now = t0.Add(time.Minute)
+
+	entries := s.All()
+	require.Len(t, entries, 1)
+	e := entries[0]
+	assert.Equal(t, Endpoint{Namespace: "checkout", Workload: "checkout"}, e.Source)
+	assert.Equal(t, Endpoint{Namespace: "cache", Workload: "redis", Service: "redis"}, e.Destination)
+	assert.Equal(t, uint32(6379), e.Port)
+	assert.Equal(t, "TCP", e.Protocol)
+	assert.Equal(t, hubble.DirectionIngress, e.Direction)
+	assert.Equal(t, PolicyRef{Source: denyCache.Source, Namespace: "cache", Name: "deny-all"}, e.Policy)
+	assert.Equal(t, uint64(3), e.Count)
+	assert.Equal(t, uint64(3), e.RecentCount)
+	assert.Equal(t, t0, e.FirstSeen)
+	assert.Equal(t, t0.Add(2*time.Second), e.LastSeen)
+
+	// Only the most recent samples are kept.
+	require.Len(t, e.Samples, 2)
+	assert.Equal(t, "checkout-b", e.Samples[0].SourcePod)
+	assert.Equal(t, "checkout-a", e.Samples[1].SourcePod)
+	assert.Equal(t, "10.0.2.9", e.Samples[1].DestinationIP)
+
+	// Another policy dropping the same path is its own entry.
+	other := denyCache
+	other.Name = "deny-checkout"
+	s.Record(checkoutDrop("checkout-a", t0), other, redisService)
+	assert.Equal(t, 2, s.Len())
+
+	assert.Equal(t, before+3, testutil.ToFloat64(counter))
+}
+
+func TestStore_RollingWindow(t *testing.T) {
+	now := t0
+	s := newTestStore(t, StoreOptions{Window: 10 * time.Minute, Resolution: time.Minute}, &now)
+
+	s.Record(checkoutDrop("checkout-a", t0), denyCache, redisService)
+	s.Record(checkoutDrop("checkout-a", t0.Add(5*time.Minute)), denyCache, redisService)
+	s.Record(checkoutDrop("checkout-a", t0.Add(9*time.Minute)), denyCache, redisService)
+
+	now = t0.Add(9 * time.Minute)
+	assert.Equal(t, uint64(3), s.All()[0].RecentCount)
+
+	// The first drop leaves the window; the total keeps it.
+	now = t0.Add(12 * time.Minute)
+	e := s.All()[0]
+	assert.Equal(t, uint64(2), e.RecentCount)
+	assert.Equal(t, uint64(3), e.Count)
+
+	// A drop in a recycled ring slot replaces the expired interval.
+	s.Record(checkoutDrop("checkout-a", t0.Add(10*time.Minute)), denyCache, redisService)
+	assert.Equal(t, uint64(3), s.All()[0].RecentCount)
+
+	// Entries not seen within the window expire.
+	now = t0.Add(25 * time.Minute)
+	assert.Equal(t, 1, s.prune())
+	assert.Zero(t, s.Len())
+}
+
+func TestStore_EvictsLeastRecentlySeen(t *testing.T) {
+	now := t0
+	s := newTestStore(t, StoreOptions{MaxEntries: 2}, &now)
+
+	policy := func(name string) types.Constraint {
+		c := denyCache
+		c.Name = name
+		return c
+	}
+	s.Record(checkoutDrop("checkout-a", t0.Add(time.Second)), policy("first"), nil)
+	s.Record(checkoutDrop("checkout-a", t0), policy("oldest"), nil)
+
+	before := testutil.ToFloat64(evictions)
+	s.Record(checkoutDrop("checkout-a", t0.Add(2*time.Second)), policy("third"), nil)
+
+	var names []string
+	for _, e := range s.All() {
+		names = append(names, e.Policy.Name)
+	}
+	assert.ElementsMatch(t, []string{"first", "third"}, names)
+	assert.Equal(t, before+1, testutil.ToFloat64(evictions))
+	assert.Equal(t, float64(2), testutil.ToFloat64(entriesGauge))
+}
+
+func TestStore_ForNamespaceAndChanges(t *testing.T) {
+	now := t0
+	s := newTestStore(t, StoreOptions{}, &now)
+
+	s.Record(checkoutDrop("checkout-a", t0), denyCache, redisService)
+	external := hubble.NewFlowDropBuilder().
+		WithTime(t0).
+		WithSource("web", "frontend-0", nil).
+		WithIP("10.0.1.7", "203.0.113.10").
+		WithTCP(40001, 443, hubble.TCPFlags{}).
+		WithDirection(hubble.DirectionEgress).
+		Build()
+	for i := 0; i < 3; i++ {
+		s.Record(external, types.Constraint{Namespace: "web", Name: "web-egress"}, nil)
+	}
+
+	assert.Equal(t, []string{"cache", "checkout", "web"}, s.ChangedNamespaces())
+	assert.Empty(t, s.ChangedNamespaces())
+
+	web := s.ForNamespace("web")
+	require.Len(t, web, 1)
+	assert.Equal(t, Endpoint{Workload: "203.0.113.10"}, web[0].Destination)
+	assert.Len(t, s.ForNamespace("cache"), 1)
+	assert.Empty(t, s.ForNamespace("other"))
+
+	// Busiest first.
+	all := s.All()
+	require.Len(t, all, 2)
+	assert.Equal(t, "web-egress", all[0].Policy.Name)
+
+	now = t0.Add(2 * time.Hour)
+	s.prune()
+	assert.Equal(t, []string{"cache", "checkout", "web"}, s.ChangedNamespaces())
+}
+
+func TestEntry_Scoped(t *testing.T) {
+	now := t0
+	s := newTestStore(t, StoreOptions{}, &now)
+	s.Record(checkoutDrop("checkout-a", t0), denyCache, redisService)
+	e := s.All()[0]
+
+	assert.Equal(t, e, e.Scoped("checkout", types.DetailLevelFull))
+
+	summary := e.Scoped("checkout", types.DetailLevelSummary)
+	assert.Equal(t, e.Source, summary.Source)
+	assert.Equal(t, Endpoint{}, summary.Destination)
+	assert.Empty(t, summary.Policy.Name)
+	assert.Equal(t, "checkout-a", summary.Samples[0].SourcePod)
+	assert.Empty(t, summary.Samples[0].DestinationPod)
+	assert.Empty(t, summary.Samples[0].DestinationIP)
+	assert.Equal(t, uint32(6379), summary.Port)
+
+	detailed := e.Scoped("checkout", types.DetailLevelDetailed)
+	assert.Equal(t, Endpoint{Namespace: "cache"}, detailed.Destination)
+	assert.Empty(t, detailed.Policy.Name)
+
+	// The policy's own namespace sees it.
+	own := e.Scoped("cache", types.DetailLevelSummary)
+	assert.Equal(t, "deny-all", own.Policy.Name)
+	assert.Equal(t, e.Destination, own.Destination)
+	assert.Equal(t, Endpoint{}, own.Source)
+
+	// Scoping does not modify the original.
+	assert.Equal(t, "redis-0", e.Samples[0].DestinationPod)
+}
+
+func TestNewStore_DefaultOptions(t *testing.T) {
+	s := NewStore(StoreOptions{Window: 30 * time.Second})
+	assert.Equal(t, 30*time.Second, s.opts.Resolution)
+	assert.Equal(t, 1, s.bucketCount())
+	assert.Equal(t, DefaultStoreOptions().MaxEntries, s.opts.MaxEntries)
+	assert.Equal(t, DefaultStoreOptions().MaxSamples, s.opts.MaxSamples)
+	assert.NotNil(t, s.logger)
+}
+
+func TestStore_StartStopsOnCancel(t *testing.T) {
+	s := NewStore(StoreOptions{Resolution: time.Millisecond, Window: time.Millisecond})
+	s.Record(checkoutDrop("checkout-a", time.Now().Add(-time.Second)), denyCache, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Start(ctx) }()
+
+	assert.Eventually(t, func() bool { return s.Len() == 0 }, time.Second, time.Millisecond)
+	cancel()
+	require.NoError(t, <-done)
+}
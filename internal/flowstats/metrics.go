@@ -0,0 +1,33 @@
+package flowstats
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Flow drop statistics metrics, served on the controller-runtime metrics endpoint.
+var (
+	aggregatedDrops = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "nightjar_flow_drops_aggregated_total",
+		Help: "Correlated flow drops by source and destination workload, port and dropping policy.",
+	}, []string{
+		"source_namespace", "source_workload",
+		"destination_namespace", "destination_workload", "destination_service",
+		"port", "protocol", "direction",
+		"policy_source", "policy_namespace", "policy",
+	})
+
+	entriesGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "nightjar_flow_drop_stats_entries",
+		Help: "Drop paths held by the flow drop statistics store.",
+	})
+
+	evictions = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "nightjar_flow_drop_stats_evictions_total",
+		Help: "Drop paths evicted from the flow drop statistics store because it was full.",
+	})
+)
+
+func init() {
+	ctrlmetrics.Registry.MustRegister(aggregatedDrops, entriesGauge, evictions)
+}
@@ -12,6 +12,7 @@ import (
 	observerpb "github.com/cilium/cilium/api/v1/observer"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/keepalive"
 )
@@ -21,6 +22,9 @@ type ClientOptions struct {
 	// RelayAddress is the gRPC address of Hubble Relay (e.g., "hubble-relay.kube-system.svc:4245")
 	RelayAddress string
 
+	// TLS secures the connection to Hubble Relay. Nil connects in plaintext.
+	TLS *TLSOptions
+
 	// ReconnectInterval is the base interval between reconnection attempts
 	ReconnectInterval time.Duration
 
@@ -50,6 +54,7 @@ type Client struct {
 	opts   ClientOptions
 	logger *zap.Logger
 
+	creds   credentials.TransportCredentials // nil for plaintext
 	conn    *grpc.ClientConn
 	state   ConnectionState
 	stateMu sync.RWMutex
@@ -64,7 +69,9 @@ type Client struct {
 	flowDrops  uint64
 }
 
-// NewClient creates a new Hubble client and starts the connection.
+// NewClient creates a new Hubble client and starts the connection. It
+// returns an error if TLS is configured and the certificates cannot be
+// loaded.
 func NewClient(ctx context.Context, opts ClientOptions) (*Client, error) {
 	if opts.Logger == nil {
 		opts.Logger = zap.NewNop()
@@ -89,6 +96,13 @@ func NewClient(ctx context.Context, opts ClientOptions) (*Client, error) {
 		stopCh: make(chan struct{}),
 		state:  StateDisconnected,
 	}
+	if opts.TLS != nil {
+		files, err := newTLSFiles(*opts.TLS, c.logger)
+		if err != nil {
+			return nil, err
+		}
+		c.creds = &reloadingCredentials{files: files}
+	}
 
 	// Start the connection loop
 	c.wg.Add(1)
@@ -208,8 +222,12 @@ func (c *Client) connectionLoop(ctx context.Context) {
 
 // connect establishes a gRPC connection to Hubble Relay.
 func (c *Client) connect(_ context.Context) error {
+	creds := c.creds
+	if creds == nil {
+		creds = insecure.NewCredentials()
+	}
 	conn, err := grpc.NewClient(c.opts.RelayAddress,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithTransportCredentials(creds),
 		grpc.WithKeepaliveParams(keepalive.ClientParameters{
 			Time:                30 * time.Second,
 			Timeout:             10 * time.Second,
@@ -287,6 +305,13 @@ func convertFlow(f *flowpb.Flow) (FlowDrop, bool) {
 	drop := FlowDrop{
 		TraceID:    f.GetUuid(),
 		DropReason: ParseDropReason(int32(f.GetDropReasonDesc())),
+		Direction:  ParseTrafficDirection(f.GetTrafficDirection()),
+	}
+
+	// Hubble fills the list for the direction the drop was enforced in.
+	drop.DeniedBy = append(parsePolicies(f.GetIngressDeniedBy()), parsePolicies(f.GetEgressDeniedBy())...)
+	if len(drop.DeniedBy) > 0 {
+		drop.PolicyName = drop.DeniedBy[0].Name
 	}
 
 	if t := f.GetTime(); t != nil {
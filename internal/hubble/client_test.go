@@ -969,3 +969,44 @@ func TestClient_recordFlowDrop_ChannelFull(t *testing.T) {
 	stats := client.Stats()
 	assert.Equal(t, uint64(2), stats.FlowDrops)
 }
+
+func TestConvertFlow_PolicyMetadata(t *testing.T) {
+	drop, ok := convertFlow(&flowpb.Flow{
+		Verdict:          flowpb.Verdict_DROPPED,
+		DropReasonDesc:   flowpb.DropReason_POLICY_DENY,
+		TrafficDirection: flowpb.TrafficDirection_INGRESS,
+		IngressDeniedBy: []*flowpb.Policy{
+			{
+				Name:      "deny-external",
+				Namespace: "production",
+				Labels: []string{
+					"k8s:io.cilium.k8s.policy.derived-from=CiliumNetworkPolicy",
+					"k8s:io.cilium.k8s.policy.name=deny-external",
+				},
+			},
+			nil,
+			{Name: "cluster-deny", Labels: []string{"k8s:io.cilium.k8s.policy.derived-from=CiliumClusterwideNetworkPolicy"}},
+		},
+	})
+	require.True(t, ok)
+
+	assert.Equal(t, DirectionIngress, drop.Direction)
+	assert.Equal(t, "deny-external", drop.PolicyName)
+	assert.Equal(t, []PolicyRef{
+		{Kind: "CiliumNetworkPolicy", Namespace: "production", Name: "deny-external"},
+		{Kind: "CiliumClusterwideNetworkPolicy", Name: "cluster-deny"},
+	}, drop.DeniedBy)
+}
+
+func TestConvertFlow_DefaultDenyHasNoPolicyMetadata(t *testing.T) {
+	drop, ok := convertFlow(&flowpb.Flow{
+		Verdict:          flowpb.Verdict_DROPPED,
+		DropReasonDesc:   flowpb.DropReason_POLICY_DENIED,
+		TrafficDirection: flowpb.TrafficDirection_EGRESS,
+	})
+	require.True(t, ok)
+
+	assert.Equal(t, DirectionEgress, drop.Direction)
+	assert.Empty(t, drop.PolicyName)
+	assert.Empty(t, drop.DeniedBy)
+}
@@ -135,6 +135,28 @@ func (b *FlowDropBuilder) WithPolicyName(name string) *FlowDropBuilder {
 	return b
 }
 
+// WithDirection sets the direction the drop was enforced in.
+func (b *FlowDropBuilder) WithDirection(direction TrafficDirection) *FlowDropBuilder {
+	b.drop.Direction = direction
+	return b
+}
+
+// WithDeniedBy adds a policy whose deny rules dropped the flow.
+func (b *FlowDropBuilder) WithDeniedBy(kind, namespace, name string) *FlowDropBuilder {
+	b.drop.DeniedBy = append(b.drop.DeniedBy, PolicyRef{
+		Kind:      kind,
+		Namespace: namespace,
+		Name:      name,
+	})
+	return b
+}
+
+// WithL7 sets the request details of a denial enforced by a proxy.
+func (b *FlowDropBuilder) WithL7(l7 L7Info) *FlowDropBuilder {
+	b.drop.L7 = &l7
+	return b
+}
+
 // Build returns the constructed FlowDrop.
 // The returned value does not share map or slice references with the builder.
 func (b *FlowDropBuilder) Build() FlowDrop {
@@ -143,6 +165,14 @@ func (b *FlowDropBuilder) Build() FlowDrop {
 	result.Destination.Labels = copyStringMap(b.drop.Destination.Labels)
 	result.Source.Workloads = copyWorkloads(b.drop.Source.Workloads)
 	result.Destination.Workloads = copyWorkloads(b.drop.Destination.Workloads)
+	if b.drop.DeniedBy != nil {
+		result.DeniedBy = append([]PolicyRef(nil), b.drop.DeniedBy...)
+	}
+	if b.drop.L7 != nil {
+		l7 := *b.drop.L7
+		l7.ResponseFlags = append([]string(nil), b.drop.L7.ResponseFlags...)
+		result.L7 = &l7
+	}
 	return result
 }
 
@@ -186,6 +216,39 @@ func ParseDropReason(code int32) DropReason {
 	}
 }
 
+// ParseTrafficDirection converts a Hubble traffic direction to our internal type.
+func ParseTrafficDirection(d flowpb.TrafficDirection) TrafficDirection {
+	switch d {
+	case flowpb.TrafficDirection_INGRESS:
+		return DirectionIngress
+	case flowpb.TrafficDirection_EGRESS:
+		return DirectionEgress
+	default:
+		return DirectionUnknown
+	}
+}
+
+// policyKindLabel is the Cilium policy label naming the kind of object a
+// policy was derived from.
+const policyKindLabel = "io.cilium.k8s.policy.derived-from"
+
+// parsePolicies converts Hubble policy references, reading each policy's
+// kind from its labels.
+func parsePolicies(policies []*flowpb.Policy) []PolicyRef {
+	var result []PolicyRef
+	for _, p := range policies {
+		if p == nil || p.GetName() == "" {
+			continue
+		}
+		result = append(result, PolicyRef{
+			Kind:      parseLabels(p.GetLabels())[policyKindLabel],
+			Namespace: p.GetNamespace(),
+			Name:      p.GetName(),
+		})
+	}
+	return result
+}
+
 // parseLabels converts Cilium proto labels ([]string in "source:key=value" format)
 // to a map[string]string, stripping the source prefix.
 func parseLabels(protoLabels []string) map[string]string {
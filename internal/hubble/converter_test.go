@@ -76,12 +76,28 @@ func TestFlowDropBuilder_UDP(t *testing.T) {
 	assert.Nil(t, drop.L4.TCP)
 }
 
+func TestFlowDropBuilder_L7(t *testing.T) {
+	b := NewFlowDropBuilder().
+		WithDestination("shop", "api-0", nil).
+		WithDropReason(DropReasonPolicyL7).
+		WithL7(L7Info{Method: "GET", Path: "/admin", ResponseCode: 403, ResponseFlags: []string{"UAEX"}})
+
+	drop := b.Build()
+	b.drop.L7.ResponseFlags[0] = "mutated"
+
+	assert.Equal(t, DropReasonPolicyL7, drop.DropReason)
+	assert.Equal(t, "GET", drop.L7.Method)
+	assert.Equal(t, uint32(403), drop.L7.ResponseCode)
+	assert.Equal(t, []string{"UAEX"}, drop.L7.ResponseFlags)
+}
+
 func TestFlowDropBuilder_Build_DoesNotShareReferences(t *testing.T) {
 	b := NewFlowDropBuilder().
 		WithSource("ns", "pod1", map[string]string{"app": "web"}).
 		WithSourceWorkload("Deployment", "web").
 		WithDestination("ns", "pod2", map[string]string{"app": "api"}).
-		WithDestinationWorkload("Deployment", "api")
+		WithDestinationWorkload("Deployment", "api").
+		WithDeniedBy("NetworkPolicy", "ns", "deny-all")
 
 	drop := b.Build()
 
@@ -90,6 +106,7 @@ func TestFlowDropBuilder_Build_DoesNotShareReferences(t *testing.T) {
 	b.WithSourceWorkload("StatefulSet", "mutated")
 	b.WithDestination("ns", "pod2-mutated", map[string]string{"app": "mutated"})
 	b.WithDestinationWorkload("StatefulSet", "mutated")
+	b.WithDeniedBy("NetworkPolicy", "ns", "mutated")
 
 	// The previously built drop must be unaffected
 	assert.Equal(t, "web", drop.Source.Labels["app"])
@@ -98,6 +115,13 @@ func TestFlowDropBuilder_Build_DoesNotShareReferences(t *testing.T) {
 	assert.Equal(t, "Deployment", drop.Source.Workloads[0].Kind)
 	assert.Len(t, drop.Destination.Workloads, 1)
 	assert.Equal(t, "Deployment", drop.Destination.Workloads[0].Kind)
+	assert.Len(t, drop.DeniedBy, 1)
+}
+
+func TestParseTrafficDirection(t *testing.T) {
+	assert.Equal(t, DirectionIngress, ParseTrafficDirection(flowpb.TrafficDirection_INGRESS))
+	assert.Equal(t, DirectionEgress, ParseTrafficDirection(flowpb.TrafficDirection_EGRESS))
+	assert.Equal(t, DirectionUnknown, ParseTrafficDirection(flowpb.TrafficDirection_TRAFFIC_DIRECTION_UNKNOWN))
 }
 
 func TestParseDropReason(t *testing.T) {
@@ -27,6 +27,25 @@
 //	        drop.Source.PodName, drop.Destination.PodName, drop.DropReason)
 //	}
 //
+// # TLS
+//
+// Set ClientOptions.TLS to connect over TLS, with a client certificate for
+// mutual TLS if the relay requires one. The certificate files are re-read
+// before every connection attempt, so rotated Secrets take effect on the next
+// reconnect while the current stream continues.
+//
+// # Replay
+//
+// A Replayer reads flows recorded with `hubble observe -o jsonpb` or written by
+// Cilium's flow exporter and emits the dropped ones like a Client. Both
+// implement FlowSource, so a capture can be correlated offline:
+//
+//	replayer := hubble.NewReplayer(file, hubble.ReplayOptions{RealTime: true})
+//	go replayer.Run(ctx)
+//	for drop := range replayer.DroppedFlows() {
+//	    // Process the drop event
+//	}
+//
 // # Graceful Degradation
 //
 // The client handles disconnections gracefully with exponential backoff reconnection.
@@ -0,0 +1,237 @@
+package hubble
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	flowpb "github.com/cilium/cilium/api/v1/flow"
+	observerpb "github.com/cilium/cilium/api/v1/observer"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// FlowSource produces flow drop events. Client streams them live from Hubble
+// Relay; Replayer reads them from a capture.
+type FlowSource interface {
+	// DroppedFlows returns a channel of flow drop events that is closed when
+	// the source is exhausted or stopped.
+	DroppedFlows() <-chan FlowDrop
+}
+
+// ReplayOptions configures a Replayer.
+type ReplayOptions struct {
+	// RealTime paces playback by the gaps between the recorded flow
+	// timestamps. When false, flows are replayed as fast as they are consumed.
+	RealTime bool
+
+	// Speed multiplies the playback rate in real-time mode (2 replays twice
+	// as fast as recorded).
+	Speed float64
+
+	// BufferSize is the size of the flow drop channel buffer
+	BufferSize int
+
+	// Logger for the replayer
+	Logger *zap.Logger
+}
+
+// DefaultReplayOptions returns default options for the Replayer.
+func DefaultReplayOptions() ReplayOptions {
+	return ReplayOptions{
+		Speed:      1,
+		BufferSize: 1000,
+		Logger:     zap.NewNop(),
+	}
+}
+
+// ReplayStats contains replay statistics.
+type ReplayStats struct {
+	// Lines is the number of non-empty lines read.
+	Lines uint64
+	// Flows is the number of flows decoded.
+	Flows uint64
+	// Drops is the number of dropped flows replayed.
+	Drops uint64
+	// Invalid is the number of lines that could not be decoded.
+	Invalid uint64
+}
+
+// Replayer reads flows captured with `hubble observe -o jsonpb` or written by
+// Cilium's flow exporter and replays the dropped ones as FlowDrop events.
+//
+// Each line may hold a GetFlowsResponse, an ExportEvent or a bare Flow. Lines
+// without a flow, such as node status events, are skipped; malformed lines are
+// logged and counted in ReplayStats.Invalid.
+type Replayer struct {
+	opts   ReplayOptions
+	logger *zap.Logger
+	r      io.Reader
+	drops  chan FlowDrop
+
+	mu    sync.Mutex
+	stats ReplayStats
+}
+
+// NewReplayer creates a Replayer that reads flows from r. Call Run to start
+// the playback.
+func NewReplayer(r io.Reader, opts ReplayOptions) *Replayer {
+	if opts.Logger == nil {
+		opts.Logger = zap.NewNop()
+	}
+	if opts.Speed <= 0 {
+		opts.Speed = DefaultReplayOptions().Speed
+	}
+	if opts.BufferSize == 0 {
+		opts.BufferSize = DefaultReplayOptions().BufferSize
+	}
+
+	return &Replayer{
+		opts:   opts,
+		logger: opts.Logger.Named("hubble-replay"),
+		r:      r,
+		drops:  make(chan FlowDrop, opts.BufferSize),
+	}
+}
+
+// DroppedFlows returns a channel of flow drop events.
+// The channel is closed when Run returns.
+func (p *Replayer) DroppedFlows() <-chan FlowDrop {
+	return p.drops
+}
+
+// Stats returns replay statistics.
+func (p *Replayer) Stats() ReplayStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.stats
+}
+
+// Run replays the capture until the reader is exhausted or ctx is cancelled.
+// Unlike Client, it waits for the consumer instead of dropping events when
+// the buffer is full. It returns an error only if reading fails.
+func (p *Replayer) Run(ctx context.Context) error {
+	defer close(p.drops)
+
+	var (
+		br       = bufio.NewReader(p.r)
+		lastTime time.Time
+	)
+	for {
+		line, readErr := br.ReadBytes('\n')
+		if readErr != nil && !errors.Is(readErr, io.EOF) {
+			return fmt.Errorf("read flows: %w", readErr)
+		}
+
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			p.count(func(s *ReplayStats) { s.Lines++ })
+
+			f, err := decodeFlow(line)
+			switch {
+			case err != nil:
+				p.count(func(s *ReplayStats) { s.Invalid++ })
+				p.logger.Warn("skipping malformed flow", zap.Uint64("line", p.Stats().Lines), zap.Error(err))
+			case f != nil:
+				p.count(func(s *ReplayStats) { s.Flows++ })
+				if p.opts.RealTime {
+					if err := p.wait(ctx, lastTime, f); err != nil {
+						return nil
+					}
+					if t := f.GetTime(); t != nil {
+						lastTime = t.AsTime()
+					}
+				}
+				if drop, ok := convertFlow(f); ok {
+					select {
+					case p.drops <- drop:
+						p.count(func(s *ReplayStats) { s.Drops++ })
+					case <-ctx.Done():
+						return nil
+					}
+				}
+			}
+		}
+
+		if readErr != nil {
+			stats := p.Stats()
+			p.logger.Info("replay finished",
+				zap.Uint64("flows", stats.Flows),
+				zap.Uint64("drops", stats.Drops),
+				zap.Uint64("invalid", stats.Invalid))
+			return nil
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// wait sleeps for the recorded gap between last and f, scaled by Speed.
+func (p *Replayer) wait(ctx context.Context, last time.Time, f *flowpb.Flow) error {
+	t := f.GetTime()
+	if last.IsZero() || t == nil {
+		return ctx.Err()
+	}
+	gap := time.Duration(float64(t.AsTime().Sub(last)) / p.opts.Speed)
+	if gap <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(gap)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *Replayer) count(fn func(*ReplayStats)) {
+	p.mu.Lock()
+	fn(&p.stats)
+	p.mu.Unlock()
+}
+
+// decodeFlow decodes one line of JSON-encoded flow output. It returns a nil
+// flow for events that do not carry one.
+func decodeFlow(line []byte) (*flowpb.Flow, error) {
+	unmarshal := protojson.UnmarshalOptions{DiscardUnknown: true}
+
+	// `hubble observe -o jsonpb` writes GetFlowsResponse messages and the
+	// flow exporter writes ExportEvent messages; both keep the flow under
+	// "flow", so decoding as either finds it.
+	var resp observerpb.GetFlowsResponse
+	if err := unmarshal.Unmarshal(line, &resp); err == nil {
+		if f := resp.GetFlow(); f != nil {
+			return f, nil
+		}
+		if resp.GetResponseTypes() != nil {
+			return nil, nil
+		}
+	}
+
+	var event observerpb.ExportEvent
+	if err := unmarshal.Unmarshal(line, &event); err == nil {
+		if f := event.GetFlow(); f != nil {
+			return f, nil
+		}
+		if event.GetResponseTypes() != nil {
+			return nil, nil
+		}
+	}
+
+	var f flowpb.Flow
+	if err := unmarshal.Unmarshal(line, &f); err != nil {
+		return nil, err
+	}
+	if f.GetVerdict() == flowpb.Verdict_VERDICT_UNKNOWN && f.GetUuid() == "" && f.GetTime() == nil {
+		// Valid JSON without any flow fields, e.g. a lost-events record.
+		return nil, nil
+	}
+	return &f, nil
+}
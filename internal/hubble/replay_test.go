@@ -0,0 +1,109 @@
+package hubble
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// replayAll runs p to completion and returns the replayed drops.
+func replayAll(t *testing.T, p *Replayer) []FlowDrop {
+	t.Helper()
+	done := make(chan error, 1)
+	go func() { done <- p.Run(context.Background()) }()
+
+	var drops []FlowDrop
+	for drop := range p.DroppedFlows() {
+		drops = append(drops, drop)
+	}
+	require.NoError(t, <-done)
+	return drops
+}
+
+func TestReplayer_HubbleObserveOutput(t *testing.T) {
+	f, err := os.Open("testdata/drops.jsonpb")
+	require.NoError(t, err)
+	defer f.Close()
+
+	p := NewReplayer(f, ReplayOptions{})
+	drops := replayAll(t, p)
+
+	require.Len(t, drops, 3)
+	ingress := drops[0]
+	assert.Equal(t, "drop-ingress", ingress.TraceID)
+	assert.Equal(t, DropReasonPolicy, ingress.DropReason)
+	assert.Equal(t, DirectionIngress, ingress.Direction)
+	assert.Equal(t, []PolicyRef{{Kind: "CiliumNetworkPolicy", Namespace: "db", Name: "db-deny"}}, ingress.DeniedBy)
+	assert.Equal(t, "db-deny", ingress.PolicyName)
+	assert.Equal(t, time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC), ingress.Time.UTC())
+	assert.Equal(t, "frontend", ingress.Source.Labels["app"])
+	assert.Equal(t, "postgres-0", ingress.Destination.PodName)
+	assert.Equal(t, []WorkloadRef{{Kind: "StatefulSet", Name: "postgres"}}, ingress.Destination.Workloads)
+	assert.Equal(t, IPInfo{Source: "10.0.1.5", Destination: "10.0.2.9"}, ingress.IP)
+	assert.Equal(t, ProtocolTCP, ingress.L4.Protocol)
+	assert.Equal(t, uint32(5432), ingress.L4.DestinationPort)
+
+	egress := drops[1]
+	assert.Equal(t, "drop-egress", egress.TraceID)
+	assert.Equal(t, DirectionEgress, egress.Direction)
+	assert.Equal(t, "web-egress", egress.PolicyName)
+	assert.Equal(t, ProtocolUDP, egress.L4.Protocol)
+
+	assert.Equal(t, "bare-flow", drops[2].TraceID)
+
+	assert.Equal(t, ReplayStats{Lines: 6, Flows: 4, Drops: 3, Invalid: 1}, p.Stats())
+}
+
+func TestReplayer_ExportEvents(t *testing.T) {
+	capture := `{"flow":{"uuid":"exported","verdict":"DROPPED","drop_reason_desc":"POLICY_DENIED"},"node_name":"node-a","time":"2026-03-02T10:00:00Z"}
+{"lost_events":{"source":"HUBBLE_RING_BUFFER","num_events_lost":3},"node_name":"node-a"}`
+
+	p := NewReplayer(strings.NewReader(capture), ReplayOptions{})
+	drops := replayAll(t, p)
+
+	require.Len(t, drops, 1)
+	assert.Equal(t, "exported", drops[0].TraceID)
+	assert.Equal(t, ReplayStats{Lines: 2, Flows: 1, Drops: 1}, p.Stats())
+}
+
+func TestReplayer_RealTime(t *testing.T) {
+	capture := `{"flow":{"time":"2026-03-02T10:00:00Z","uuid":"first","verdict":"DROPPED"}}
+{"flow":{"time":"2026-03-02T10:00:00.400Z","uuid":"second","verdict":"DROPPED"}}`
+
+	start := time.Now()
+	drops := replayAll(t, NewReplayer(strings.NewReader(capture), ReplayOptions{RealTime: true, Speed: 2}))
+	elapsed := time.Since(start)
+
+	require.Len(t, drops, 2)
+	assert.GreaterOrEqual(t, elapsed, 200*time.Millisecond, "the 400ms gap should take 200ms at 2x")
+	assert.Less(t, elapsed, 400*time.Millisecond)
+}
+
+func TestReplayer_WaitsForConsumer(t *testing.T) {
+	capture := strings.Repeat(`{"flow":{"uuid":"drop","verdict":"DROPPED"}}`+"\n", 5)
+
+	drops := replayAll(t, NewReplayer(strings.NewReader(capture), ReplayOptions{BufferSize: 1}))
+	assert.Len(t, drops, 5)
+}
+
+func TestReplayer_ContextCancelled(t *testing.T) {
+	capture := strings.Repeat(`{"flow":{"uuid":"drop","verdict":"DROPPED"}}`+"\n", 5)
+	p := NewReplayer(strings.NewReader(capture), ReplayOptions{BufferSize: 1})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.NoError(t, p.Run(ctx))
+	assert.LessOrEqual(t, p.Stats().Drops, uint64(1))
+}
+
+func TestNewReplayer_DefaultOptions(t *testing.T) {
+	p := NewReplayer(strings.NewReader(""), ReplayOptions{Speed: -1})
+	assert.Equal(t, DefaultReplayOptions().Speed, p.opts.Speed)
+	assert.Equal(t, DefaultReplayOptions().BufferSize, cap(p.drops))
+	assert.NotNil(t, p.logger)
+}
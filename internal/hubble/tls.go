@@ -0,0 +1,166 @@
+package hubble
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/credentials"
+)
+
+// TLSOptions configures TLS to Hubble Relay. The files are usually mounted
+// from Secrets. They are re-read for every new connection, so rotated
+// certificates are used from the next reconnect on, without restarting the
+// client or interrupting an established flow stream.
+type TLSOptions struct {
+	// CAFile is a PEM bundle of the CAs that sign the relay's certificate.
+	// Empty uses the system roots.
+	CAFile string
+
+	// CertFile and KeyFile are the PEM client certificate and key presented
+	// to the relay for mutual TLS. Set both or neither.
+	CertFile string
+	KeyFile  string
+
+	// ServerName is the name verified in the relay's certificate, e.g.
+	// "ui.hubble-relay.cilium.io". Default: the host of RelayAddress.
+	ServerName string
+}
+
+// tlsFiles holds the certificates last loaded from TLSOptions' files.
+type tlsFiles struct {
+	opts   TLSOptions
+	logger *zap.Logger
+
+	mu      sync.Mutex
+	raw     [][]byte // CA, cert and key file contents of the loaded state
+	roots   *x509.CertPool
+	certs   []tls.Certificate
+	loadErr error // last failed reload, logged once per change
+}
+
+func newTLSFiles(opts TLSOptions, logger *zap.Logger) (*tlsFiles, error) {
+	if (opts.CertFile == "") != (opts.KeyFile == "") {
+		return nil, fmt.Errorf("hubble TLS: set both the client certificate and key, or neither")
+	}
+	f := &tlsFiles{opts: opts, logger: logger}
+	if err := f.reload(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// refresh reloads the files if they changed. A change that fails to load is
+// logged and the previous certificates stay in use, so a rotation caught
+// halfway does not break new connections.
+func (f *tlsFiles) refresh() {
+	err := f.reload()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err != nil {
+		if f.loadErr == nil || f.loadErr.Error() != err.Error() {
+			f.logger.Warn("Failed to reload Hubble TLS certificates, keeping the previous ones", zap.Error(err))
+		}
+	}
+	f.loadErr = err
+}
+
+// reload reads the files and, if their content changed, parses them and
+// makes them current.
+func (f *tlsFiles) reload() error {
+	raw := make([][]byte, 3)
+	for i, path := range []string{f.opts.CAFile, f.opts.CertFile, f.opts.KeyFile} {
+		if path == "" {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("hubble TLS: %w", err)
+		}
+		raw[i] = data
+	}
+
+	f.mu.Lock()
+	unchanged := f.raw != nil && bytes.Equal(raw[0], f.raw[0]) && bytes.Equal(raw[1], f.raw[1]) && bytes.Equal(raw[2], f.raw[2])
+	initial := f.raw == nil
+	f.mu.Unlock()
+	if unchanged {
+		return nil
+	}
+
+	var roots *x509.CertPool
+	if raw[0] != nil {
+		roots = x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(raw[0]) {
+			return fmt.Errorf("hubble TLS: no PEM certificates in CA file %s", f.opts.CAFile)
+		}
+	}
+	var certs []tls.Certificate
+	if raw[1] != nil {
+		cert, err := tls.X509KeyPair(raw[1], raw[2])
+		if err != nil {
+			return fmt.Errorf("hubble TLS: client certificate %s: %w", f.opts.CertFile, err)
+		}
+		certs = []tls.Certificate{cert}
+	}
+
+	f.mu.Lock()
+	f.raw, f.roots, f.certs = raw, roots, certs
+	f.mu.Unlock()
+	if !initial {
+		f.logger.Info("Reloaded Hubble TLS certificates")
+	}
+	return nil
+}
+
+// config returns a tls.Config with the current certificates.
+func (f *tlsFiles) config() *tls.Config {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		ServerName:   f.opts.ServerName,
+		RootCAs:      f.roots,
+		Certificates: f.certs,
+	}
+}
+
+// reloadingCredentials are gRPC transport credentials that refresh the
+// certificates before every handshake. gRPC keeps one set of credentials
+// for the lifetime of a ClientConn, so rotation has to happen underneath.
+type reloadingCredentials struct {
+	files *tlsFiles
+}
+
+func (c *reloadingCredentials) current() credentials.TransportCredentials {
+	return credentials.NewTLS(c.files.config())
+}
+
+func (c *reloadingCredentials) ClientHandshake(ctx context.Context, authority string, rawConn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	c.files.refresh()
+	return c.current().ClientHandshake(ctx, authority, rawConn)
+}
+
+func (c *reloadingCredentials) ServerHandshake(net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return nil, nil, fmt.Errorf("hubble TLS credentials are client-only")
+}
+
+func (c *reloadingCredentials) Info() credentials.ProtocolInfo {
+	return c.current().Info()
+}
+
+func (c *reloadingCredentials) Clone() credentials.TransportCredentials {
+	return &reloadingCredentials{files: c.files}
+}
+
+// OverrideServerName is deprecated in gRPC and unused; set
+// TLSOptions.ServerName instead.
+func (c *reloadingCredentials) OverrideServerName(string) error {
+	return nil
+}
@@ -0,0 +1,258 @@
+package hubble
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	flowpb "github.com/cilium/cilium/api/v1/flow"
+	observerpb "github.com/cilium/cilium/api/v1/observer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// testCA issues certificates for the TLS tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns the PEM certificate and key of a leaf named name.
+func (ca *testCA) issue(t *testing.T, name string, usage x509.ExtKeyUsage) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// streamingObserver sends the flows written to its channel on every
+// GetFlows stream, and records the client certificate of each stream.
+type streamingObserver struct {
+	observerpb.UnimplementedObserverServer
+	flows chan *flowpb.Flow
+
+	mu      sync.Mutex
+	clients []string
+}
+
+func (s *streamingObserver) GetFlows(_ *observerpb.GetFlowsRequest, stream observerpb.Observer_GetFlowsServer) error {
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case f := <-s.flows:
+			if err := stream.Send(&observerpb.GetFlowsResponse{ResponseTypes: &observerpb.GetFlowsResponse_Flow{Flow: f}}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *streamingObserver) recordClient(cs tls.ConnectionState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(cs.PeerCertificates) > 0 {
+		s.clients = append(s.clients, cs.PeerCertificates[0].Subject.CommonName)
+	}
+	return nil
+}
+
+func (s *streamingObserver) lastClient() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.clients) == 0 {
+		return ""
+	}
+	return s.clients[len(s.clients)-1]
+}
+
+// startTLSRelay serves srv over mutual TLS with a certificate for
+// "relay.test" signed by ca, trusting client certificates of clientCAs.
+func startTLSRelay(t *testing.T, ca *testCA, srv *streamingObserver, clientCAs ...*testCA) string {
+	t.Helper()
+	certPEM, keyPEM := ca.issue(t, "relay.test", x509.ExtKeyUsageServerAuth)
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+	pool := x509.NewCertPool()
+	for _, c := range clientCAs {
+		pool.AddCert(c.cert)
+	}
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := grpc.NewServer(grpc.Creds(credentials.NewTLS(&tls.Config{
+		Certificates:     []tls.Certificate{cert},
+		ClientCAs:        pool,
+		ClientAuth:       tls.RequireAndVerifyClientCert,
+		VerifyConnection: srv.recordClient,
+	})))
+	observerpb.RegisterObserverServer(s, srv)
+	go func() { _ = s.Serve(lis) }()
+	t.Cleanup(s.Stop)
+	return lis.Addr().String()
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+}
+
+func droppedFlow(uuid string) *flowpb.Flow {
+	return &flowpb.Flow{Uuid: uuid, Verdict: flowpb.Verdict_DROPPED}
+}
+
+func receiveDrop(t *testing.T, c *Client) FlowDrop {
+	t.Helper()
+	select {
+	case drop := <-c.DroppedFlows():
+		return drop
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for flow drop")
+		return FlowDrop{}
+	}
+}
+
+func TestClient_TLS_ReloadsRotatedCertificates(t *testing.T) {
+	relayCA, clientCA, rotatedCA := newTestCA(t, "relay-ca"), newTestCA(t, "client-ca"), newTestCA(t, "rotated-client-ca")
+	srv := &streamingObserver{flows: make(chan *flowpb.Flow, 1)}
+	addr := startTLSRelay(t, relayCA, srv, clientCA, rotatedCA)
+
+	dir := t.TempDir()
+	opts := TLSOptions{
+		CAFile:     filepath.Join(dir, "ca.crt"),
+		CertFile:   filepath.Join(dir, "tls.crt"),
+		KeyFile:    filepath.Join(dir, "tls.key"),
+		ServerName: "relay.test",
+	}
+	writeFile(t, opts.CAFile, relayCA.pem)
+	certPEM, keyPEM := clientCA.issue(t, "nightjar", x509.ExtKeyUsageClientAuth)
+	writeFile(t, opts.CertFile, certPEM)
+	writeFile(t, opts.KeyFile, keyPEM)
+
+	// Cancel the context before Close so the blocked stream ends.
+	clientCtx, stop := context.WithCancel(context.Background())
+	c, err := NewClient(clientCtx, ClientOptions{RelayAddress: addr, TLS: &opts, ReconnectInterval: 50 * time.Millisecond})
+	require.NoError(t, err)
+	defer c.Close()
+	defer stop()
+
+	srv.flows <- droppedFlow("before-rotation")
+	assert.Equal(t, "before-rotation", receiveDrop(t, c).TraceID)
+	assert.Equal(t, "nightjar", srv.lastClient())
+
+	// Rotate the client certificate. A half-written rotation is ignored.
+	writeFile(t, opts.CertFile, []byte("not a certificate"))
+	rotatedCert, rotatedKey := rotatedCA.issue(t, "nightjar-rotated", x509.ExtKeyUsageClientAuth)
+	writeFile(t, opts.KeyFile, rotatedKey)
+	c.creds.(*reloadingCredentials).files.refresh()
+	writeFile(t, opts.CertFile, rotatedCert)
+
+	// The established stream keeps flowing.
+	srv.flows <- droppedFlow("after-rotation")
+	assert.Equal(t, "after-rotation", receiveDrop(t, c).TraceID)
+	assert.Zero(t, c.Stats().Reconnects)
+
+	// New connections present the rotated certificate.
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(c.creds))
+	require.NoError(t, err)
+	defer conn.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, err = observerpb.NewObserverClient(conn).GetFlows(ctx, &observerpb.GetFlowsRequest{})
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool { return srv.lastClient() == "nightjar-rotated" }, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestClient_TLS_RejectsUntrustedRelay(t *testing.T) {
+	relayCA, clientCA := newTestCA(t, "relay-ca"), newTestCA(t, "client-ca")
+	srv := &streamingObserver{flows: make(chan *flowpb.Flow, 1)}
+	addr := startTLSRelay(t, relayCA, srv, clientCA)
+
+	dir := t.TempDir()
+	opts := TLSOptions{CAFile: filepath.Join(dir, "ca.crt"), ServerName: "relay.test"}
+	writeFile(t, opts.CAFile, newTestCA(t, "other-ca").pem)
+
+	c, err := NewClient(context.Background(), ClientOptions{
+		RelayAddress:      addr,
+		TLS:               &opts,
+		ReconnectInterval: 10 * time.Millisecond,
+		Logger:            zap.NewNop(),
+	})
+	require.NoError(t, err)
+	defer c.Close()
+
+	srv.flows <- droppedFlow("never-delivered")
+	assert.Eventually(t, func() bool { return c.Stats().Reconnects > 0 }, 5*time.Second, 10*time.Millisecond)
+	select {
+	case drop := <-c.DroppedFlows():
+		t.Fatalf("received %s from an untrusted relay", drop.TraceID)
+	default:
+	}
+}
+
+func TestNewClient_TLSErrors(t *testing.T) {
+	dir := t.TempDir()
+	garbage := filepath.Join(dir, "garbage.pem")
+	writeFile(t, garbage, []byte("not a certificate"))
+
+	for name, opts := range map[string]TLSOptions{
+		"cert without key": {CertFile: garbage},
+		"missing CA file":  {CAFile: filepath.Join(dir, "missing.crt")},
+		"invalid CA file":  {CAFile: garbage},
+		"invalid key pair": {CertFile: garbage, KeyFile: garbage},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewClient(context.Background(), ClientOptions{RelayAddress: "relay:4245", TLS: &opts})
+			assert.Error(t, err)
+		})
+	}
+}
@@ -27,8 +27,43 @@ type FlowDrop struct {
 	// PolicyName is the name of the policy that caused the drop (if known)
 	PolicyName string
 
+	// Direction is whether the drop was enforced on ingress to the
+	// destination or egress from the source (if known)
+	Direction TrafficDirection
+
+	// DeniedBy lists the policies whose deny rules dropped the flow, from
+	// Hubble's ingress_denied_by and egress_denied_by. Empty for drops by
+	// default deny, where no single policy is responsible.
+	DeniedBy []PolicyRef
+
 	// TraceID is the Hubble trace ID for correlation
 	TraceID string
+
+	// L7 holds the request details of a denial enforced by an Envoy proxy,
+	// e.g. a 403 from an Istio AuthorizationPolicy. Nil for L3/L4 drops.
+	L7 *L7Info
+}
+
+// L7Info describes a request denied by an Envoy proxy, as recorded in its
+// access log.
+type L7Info struct {
+	// Method, Authority and Path are empty for TCP connections
+	Method    string
+	Authority string
+	Path      string
+
+	// ResponseCode is the HTTP status returned, 0 for TCP connections
+	ResponseCode uint32
+
+	// ResponseFlags are Envoy's response flags, e.g. UAEX
+	ResponseFlags []string
+
+	// Details is the response code or connection termination detail, e.g.
+	// rbac_access_denied_matched_policy[none]
+	Details string
+
+	// RequestID is the x-request-id of the request
+	RequestID string
 }
 
 // Endpoint represents a source or destination in a flow.
@@ -55,6 +90,26 @@ type WorkloadRef struct {
 	Name string
 }
 
+// PolicyRef identifies a network policy reported by Hubble.
+type PolicyRef struct {
+	// Kind is NetworkPolicy, CiliumNetworkPolicy or
+	// CiliumClusterwideNetworkPolicy; empty if Hubble did not report it
+	Kind string
+
+	// Namespace is empty for cluster-wide policies
+	Namespace string
+	Name      string
+}
+
+// TrafficDirection is the direction a flow was enforced in.
+type TrafficDirection string
+
+const (
+	DirectionIngress TrafficDirection = "INGRESS"
+	DirectionEgress  TrafficDirection = "EGRESS"
+	DirectionUnknown TrafficDirection = ""
+)
+
 // IPInfo contains IP-level information.
 type IPInfo struct {
 	Source      string
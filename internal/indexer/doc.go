@@ -2,8 +2,10 @@
 //
 // # Contract
 //
-// The Indexer stores Constraint objects keyed by UID. It supports O(1) upsert/delete
-// by UID and O(n) queries by namespace, label match, constraint type, and source GVR.
+// The Indexer stores Constraint objects keyed by UID, with secondary indexes by
+// namespace, constraint type, severity, and source GVR maintained on every
+// upsert/delete. Queries cost time proportional to the matching index entries,
+// not to the size of the store; label selectors are evaluated only on those.
 //
 // Thread safety: all methods are safe for concurrent use via sync.RWMutex.
 //
@@ -31,20 +33,44 @@
 //	BySourceGVR(gvr schema.GroupVersionResource) []types.Constraint
 //	  - Returns all constraints parsed from the given source GVR.
 //
+//	Query(q types.ConstraintQuery) []types.Constraint
+//	  - Returns constraints matching every set field of q. Unset fields do not filter.
+//	  - Namespace has ByNamespace semantics; Labels has ByLabels semantics and is
+//	    applied last, to the intersection of the indexed fields.
+//	  - Iterates the smallest matching index set and checks the others by UID.
+//
 //	All() []types.Constraint
 //	  - Returns all stored constraints (copy of the slice).
 //
 //	Count() int
 //	  - Returns the total number of stored constraints.
 //
+// # Change feed
+//
+// Every Upsert/Delete is assigned the next revision and kept in a bounded
+// history.
+//
+//	Revision() uint64
+//	Since(revision uint64) ([]IndexEvent, error)
+//	  - Returns retained events after revision, or ErrCompacted.
+//	Snapshot() ([]types.Constraint, uint64)
+//	  - Returns all constraints with the revision they reflect.
+//	Subscribe(filter types.ConstraintQuery) *Subscription
+//	  - Ordered, buffered stream of matching events after the current revision.
+//	  - Slow subscribers never block writers; one that falls behind the
+//	    history receives EventResync and should rebuild from Snapshot.
+//
+// The workload annotator, report reconciler and MCP server each subscribe.
+//
 // # Callback
 //
-// The Indexer accepts an optional OnChange callback that fires on every Upsert/Delete.
-// The notification dispatcher and report reconciler use this to react to index changes.
+// The Indexer also accepts an optional OnChange callback that fires
+// synchronously after every Upsert/Delete, outside the lock.
 //
 //	type OnChangeFunc func(event IndexEvent)
 //	type IndexEvent struct {
 //	    Type       string // "upsert" or "delete"
 //	    Constraint types.Constraint
+//	    Revision   uint64
 //	}
 package indexer
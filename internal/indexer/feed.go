@@ -0,0 +1,201 @@
+package indexer
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/nightjarctl/nightjar/internal/types"
+)
+
+// Index event types. EventResync is only delivered to subscribers.
+const (
+	EventUpsert = "upsert"
+	EventDelete = "delete"
+	// EventResync tells a subscriber that it fell behind the retained history
+	// and missed events. It carries no constraint; the subscriber should
+	// rebuild its state from Snapshot or Query, which reflect at least
+	// Revision.
+	EventResync = "resync"
+)
+
+const (
+	// defaultHistorySize is how many events the indexer retains for Since
+	// and for subscribers that are catching up.
+	defaultHistorySize = 4096
+
+	// subscriptionBuffer is the capacity of a subscription's event channel.
+	subscriptionBuffer = 256
+)
+
+// ErrCompacted is returned by Since when events after the requested revision
+// are no longer retained.
+var ErrCompacted = errors.New("indexer: revision compacted")
+
+// recordLocked assigns the next revision to ev, retains it, and wakes subscribers.
+// Caller must hold idx.mu for writing, so revisions follow the order in
+// which changes were applied. Never blocks.
+func (idx *Indexer) recordLocked(ev IndexEvent) IndexEvent {
+	idx.revision++
+	ev.Revision = idx.revision
+
+	idx.history = append(idx.history, ev)
+	if len(idx.history) > 2*idx.historySize {
+		// Compact in bulk so appends stay amortized O(1).
+		kept := make([]IndexEvent, idx.historySize, 2*idx.historySize)
+		copy(kept, idx.history[len(idx.history)-idx.historySize:])
+		idx.history = kept
+	}
+
+	for sub := range idx.subs {
+		sub.wake()
+	}
+	return ev
+}
+
+// Revision returns the revision of the most recent change, or 0 if the index
+// has never changed.
+func (idx *Indexer) Revision() uint64 {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.revision
+}
+
+// Snapshot returns all stored constraints together with the revision they
+// reflect. A consumer that lists with Snapshot and then reads Since(revision)
+// sees every change exactly once.
+func (idx *Indexer) Snapshot() ([]types.Constraint, uint64) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	result := make([]types.Constraint, 0, len(idx.byUID))
+	for _, c := range idx.byUID {
+		result = append(result, c)
+	}
+	return result, idx.revision
+}
+
+// Since returns the events after revision, oldest first. It returns
+// ErrCompacted if some of them are no longer retained; the caller should
+// resync from Snapshot.
+func (idx *Indexer) Since(revision uint64) ([]IndexEvent, error) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	if revision >= idx.revision {
+		return nil, nil
+	}
+	if len(idx.history) == 0 || revision+1 < idx.history[0].Revision {
+		return nil, ErrCompacted
+	}
+	// Retained revisions are contiguous, so the offset is direct.
+	start := int(revision + 1 - idx.history[0].Revision)
+	return append([]IndexEvent(nil), idx.history[start:]...), nil
+}
+
+// Subscription is an ordered, buffered stream of index events matching a
+// filter. Events are read from the indexer's history by a goroutine owned by
+// the subscription, so a slow reader never blocks Upsert or Delete; if it
+// falls further behind than the history retains, it receives an EventResync
+// instead of the missed events.
+type Subscription struct {
+	idx    *Indexer
+	filter types.ConstraintQuery
+	events chan IndexEvent
+	notify chan struct{}
+	done   chan struct{}
+	once   sync.Once
+	cursor uint64
+}
+
+// Subscribe returns a subscription to changes after the current revision
+// whose constraint matches filter, with Query semantics. An upsert also
+// matches if the version it replaced did, so subscribers see constraints
+// leave their filter. Close the subscription when done.
+func (idx *Indexer) Subscribe(filter types.ConstraintQuery) *Subscription {
+	sub := &Subscription{
+		idx:    idx,
+		filter: filter,
+		events: make(chan IndexEvent, subscriptionBuffer),
+		notify: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+
+	idx.mu.Lock()
+	sub.cursor = idx.revision
+	idx.subs[sub] = struct{}{}
+	idx.mu.Unlock()
+
+	go sub.run()
+	return sub
+}
+
+// Events returns the event stream. It is closed after Close.
+func (s *Subscription) Events() <-chan IndexEvent {
+	return s.events
+}
+
+// Close stops the subscription. Safe to call multiple times.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.idx.mu.Lock()
+		delete(s.idx.subs, s)
+		s.idx.mu.Unlock()
+		close(s.done)
+	})
+}
+
+// wake signals that new events are available. Never blocks.
+func (s *Subscription) wake() {
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Subscription) run() {
+	defer close(s.events)
+	for {
+		events, err := s.idx.Since(s.cursor)
+		if errors.Is(err, ErrCompacted) {
+			rev := s.idx.Revision()
+			if !s.send(IndexEvent{Type: EventResync, Revision: rev}) {
+				return
+			}
+			s.cursor = rev
+			continue
+		}
+		for _, ev := range events {
+			s.cursor = ev.Revision
+			if !s.matches(ev) {
+				continue
+			}
+			if !s.send(ev) {
+				return
+			}
+		}
+		if len(events) > 0 {
+			continue
+		}
+		select {
+		case <-s.notify:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *Subscription) send(ev IndexEvent) bool {
+	select {
+	case s.events <- ev:
+		return true
+	case <-s.done:
+		return false
+	}
+}
+
+func (s *Subscription) matches(ev IndexEvent) bool {
+	if matchesQuery(s.filter, ev.Constraint) {
+		return true
+	}
+	return ev.previous != nil && matchesQuery(s.filter, *ev.previous)
+}
@@ -0,0 +1,184 @@
+package indexer
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+
+	"github.com/nightjarctl/nightjar/internal/types"
+)
+
+func nextEvent(t *testing.T, sub *Subscription) IndexEvent {
+	t.Helper()
+	select {
+	case ev, ok := <-sub.Events():
+		require.True(t, ok, "subscription closed")
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for index event")
+		return IndexEvent{}
+	}
+}
+
+func TestRevisionsAndSince(t *testing.T) {
+	idx := New(nil)
+	assert.Equal(t, uint64(0), idx.Revision())
+
+	idx.Upsert(makeConstraint("uid-1", "ns-a", types.ConstraintTypeNetworkEgress, nil))
+	idx.Upsert(makeConstraint("uid-2", "ns-a", types.ConstraintTypeAdmission, nil))
+	idx.Delete("uid-1")
+	idx.Delete("missing") // no-op, no revision
+	assert.Equal(t, uint64(3), idx.Revision())
+
+	events, err := idx.Since(1)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, EventUpsert, events[0].Type)
+	assert.Equal(t, uint64(2), events[0].Revision)
+	assert.Equal(t, EventDelete, events[1].Type)
+	assert.Equal(t, k8stypes.UID("uid-1"), events[1].Constraint.UID)
+
+	events, err = idx.Since(3)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	constraints, rev := idx.Snapshot()
+	assert.Len(t, constraints, 1)
+	assert.Equal(t, uint64(3), rev)
+}
+
+func TestSince_Compacted(t *testing.T) {
+	idx := New(nil)
+	idx.historySize = 2
+	for i := 0; i < 10; i++ {
+		idx.Upsert(makeConstraint(fmt.Sprintf("uid-%d", i), "ns-a", types.ConstraintTypeNetworkEgress, nil))
+	}
+
+	_, err := idx.Since(0)
+	assert.ErrorIs(t, err, ErrCompacted)
+
+	events, err := idx.Since(8)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, uint64(9), events[0].Revision)
+	assert.Equal(t, uint64(10), events[1].Revision)
+}
+
+func TestSince_PreviousWithoutRawObject(t *testing.T) {
+	idx := New(nil)
+	old := makeConstraint("uid-1", "ns-a", types.ConstraintTypeNetworkEgress, nil)
+	old.RawObject = benchPolicy(1)
+	idx.Upsert(old)
+	updated := old
+	updated.RawObject = benchPolicy(2)
+	idx.Upsert(updated)
+
+	events, err := idx.Since(1)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.NotNil(t, events[0].previous)
+	assert.Equal(t, k8stypes.UID("uid-1"), events[0].previous.UID)
+	assert.Nil(t, events[0].previous.RawObject, "history does not keep the replaced object alive")
+	assert.Same(t, updated.RawObject, events[0].Constraint.RawObject)
+}
+
+func TestOnChangeCallback_Revision(t *testing.T) {
+	var got []uint64
+	idx := New(func(ev IndexEvent) { got = append(got, ev.Revision) })
+	idx.Upsert(makeConstraint("uid-1", "ns-a", types.ConstraintTypeNetworkEgress, nil))
+	idx.Upsert(makeConstraint("uid-2", "ns-b", types.ConstraintTypeNetworkEgress, nil))
+	idx.DeleteBySource(makeConstraint("x", "", "", nil).Source)
+	assert.Equal(t, []uint64{1, 2, 3, 4}, got)
+}
+
+func TestSubscribe_OrderedAndFiltered(t *testing.T) {
+	idx := New(nil)
+	idx.Upsert(makeConstraint("before", "ns-a", types.ConstraintTypeNetworkEgress, nil))
+
+	all := idx.Subscribe(types.ConstraintQuery{})
+	defer all.Close()
+	nsA := idx.Subscribe(types.ConstraintQuery{Namespace: "ns-a"})
+	defer nsA.Close()
+
+	idx.Upsert(makeConstraint("uid-1", "ns-a", types.ConstraintTypeNetworkEgress, nil))
+	idx.Upsert(makeConstraint("uid-2", "ns-b", types.ConstraintTypeNetworkEgress, nil))
+	idx.Delete("uid-1")
+
+	for _, want := range []struct {
+		typ string
+		uid string
+		rev uint64
+	}{{EventUpsert, "uid-1", 2}, {EventUpsert, "uid-2", 3}, {EventDelete, "uid-1", 4}} {
+		ev := nextEvent(t, all)
+		assert.Equal(t, want.typ, ev.Type)
+		assert.Equal(t, k8stypes.UID(want.uid), ev.Constraint.UID)
+		assert.Equal(t, want.rev, ev.Revision)
+	}
+
+	assert.Equal(t, uint64(2), nextEvent(t, nsA).Revision)
+	assert.Equal(t, uint64(4), nextEvent(t, nsA).Revision, "ns-b event is filtered out")
+
+	// Moving a constraint out of the filter is still delivered, so the
+	// subscriber can drop it.
+	moved := makeConstraint("uid-3", "ns-a", types.ConstraintTypeNetworkEgress, nil)
+	idx.Upsert(moved)
+	moved.Namespace = "ns-b"
+	moved.AffectedNamespaces = []string{"ns-b"}
+	idx.Upsert(moved)
+	assert.Equal(t, "ns-a", nextEvent(t, nsA).Constraint.Namespace)
+	assert.Equal(t, "ns-b", nextEvent(t, nsA).Constraint.Namespace)
+}
+
+func TestSubscribe_SlowSubscriberResyncs(t *testing.T) {
+	idx := New(nil)
+	idx.historySize = 8
+	sub := idx.Subscribe(types.ConstraintQuery{})
+	defer sub.Close()
+
+	// Nobody reads while far more events than buffer plus history are
+	// written; Upsert must not block.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < subscriptionBuffer+100; i++ {
+			idx.Upsert(makeConstraint(fmt.Sprintf("uid-%d", i), "ns-a", types.ConstraintTypeNetworkEgress, nil))
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Upsert blocked on a slow subscriber")
+	}
+
+	var last IndexEvent
+	resynced := false
+	for last.Revision < idx.Revision() {
+		ev := nextEvent(t, sub)
+		assert.Greater(t, ev.Revision, last.Revision, "events stay ordered")
+		if ev.Type == EventResync {
+			resynced = true
+		}
+		last = ev
+	}
+	assert.True(t, resynced, "subscriber that fell behind the history gets a resync")
+}
+
+func TestSubscribe_Close(t *testing.T) {
+	idx := New(nil)
+	sub := idx.Subscribe(types.ConstraintQuery{})
+	sub.Close()
+	sub.Close()
+
+	select {
+	case _, ok := <-sub.Events():
+		assert.False(t, ok)
+	case <-time.After(5 * time.Second):
+		t.Fatal("events channel not closed")
+	}
+	idx.Upsert(makeConstraint("uid-1", "ns-a", types.ConstraintTypeNetworkEgress, nil))
+	assert.Empty(t, idx.subs)
+}
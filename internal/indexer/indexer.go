@@ -2,6 +2,7 @@ package indexer
 
 import (
 	"sync"
+	"sync/atomic"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -13,50 +14,184 @@ import (
 
 // IndexEvent represents a change to the constraint index.
 type IndexEvent struct {
-	Type       string // "upsert" or "delete"
+	Type       string // EventUpsert, EventDelete, or (subscriptions only) EventResync
 	Constraint types.Constraint
+
+	// Revision orders events; it increases by one with every change.
+	Revision uint64
+
+	// previous is the version an upsert replaced, for subscription filters.
+	// Its RawObject is dropped: the history retains it, and filters do not
+	// read the object.
+	previous *types.Constraint
 }
 
 // OnChangeFunc is called when the index changes.
 type OnChangeFunc func(event IndexEvent)
 
+// uidSet is a set of constraint UIDs, the value type of every secondary index.
+type uidSet map[k8stypes.UID]struct{}
+
+func (s uidSet) has(uid k8stypes.UID) bool {
+	_, ok := s[uid]
+	return ok
+}
+
 // Indexer is a concurrent-safe in-memory store of normalized Constraint objects.
+//
+// Besides the primary byUID map it maintains secondary indexes by namespace,
+// ConstraintType, Severity and source GVR, so lookups touch only the
+// constraints that can match instead of scanning the whole store.
 type Indexer struct {
-	mu       sync.RWMutex
-	byUID    map[k8stypes.UID]types.Constraint
+	mu    sync.RWMutex
+	byUID map[k8stypes.UID]types.Constraint
+
+	// byNamespace holds namespaced constraints under their Namespace and every
+	// AffectedNamespace. clusterScoped holds those with an empty Namespace,
+	// which match every namespace. The two are disjoint.
+	byNamespace   map[string]uidSet
+	clusterScoped uidSet
+	byType        map[types.ConstraintType]uidSet
+	bySeverity    map[types.Severity]uidSet
+	bySource      map[schema.GroupVersionResource]uidSet
+
+	// Change feed: every change gets the next revision and is retained in
+	// history for Since and subscribers.
+	revision    uint64
+	history     []IndexEvent
+	historySize int
+	subs        map[*Subscription]struct{}
+
+	// stale is set while the index holds constraints restored from a
+	// snapshot that have not been reconciled against the cluster yet.
+	stale atomic.Bool
+
 	onChange OnChangeFunc
 }
 
 // New creates a new Indexer with an optional change callback.
 func New(onChange OnChangeFunc) *Indexer {
 	return &Indexer{
-		byUID:    make(map[k8stypes.UID]types.Constraint),
-		onChange: onChange,
+		byUID:         make(map[k8stypes.UID]types.Constraint),
+		byNamespace:   make(map[string]uidSet),
+		clusterScoped: make(uidSet),
+		byType:        make(map[types.ConstraintType]uidSet),
+		bySeverity:    make(map[types.Severity]uidSet),
+		bySource:      make(map[schema.GroupVersionResource]uidSet),
+		historySize:   defaultHistorySize,
+		subs:          make(map[*Subscription]struct{}),
+		onChange:      onChange,
+	}
+}
+
+// addToSet adds uid to m[key], creating the set on first use.
+func addToSet[K comparable](m map[K]uidSet, key K, uid k8stypes.UID) {
+	set := m[key]
+	if set == nil {
+		set = make(uidSet)
+		m[key] = set
+	}
+	set[uid] = struct{}{}
+}
+
+// removeFromSet removes uid from m[key], dropping the set once empty so
+// that churned keys do not accumulate.
+func removeFromSet[K comparable](m map[K]uidSet, key K, uid k8stypes.UID) {
+	set := m[key]
+	delete(set, uid)
+	if len(set) == 0 {
+		delete(m, key)
+	}
+}
+
+// namespacesOf returns the namespaces c is indexed under: its own Namespace
+// followed by its AffectedNamespaces. Duplicates are harmless.
+func namespacesOf(c types.Constraint) []string {
+	return append([]string{c.Namespace}, c.AffectedNamespaces...)
+}
+
+// indexLocked adds c to the secondary indexes. Caller must hold idx.mu.
+func (idx *Indexer) indexLocked(c types.Constraint) {
+	if c.Namespace == "" {
+		idx.clusterScoped[c.UID] = struct{}{}
+	} else {
+		for _, ns := range namespacesOf(c) {
+			addToSet(idx.byNamespace, ns, c.UID)
+		}
+	}
+	addToSet(idx.byType, c.ConstraintType, c.UID)
+	addToSet(idx.bySeverity, c.Severity, c.UID)
+	addToSet(idx.bySource, c.Source, c.UID)
+}
+
+// unindexLocked removes c from the secondary indexes. Caller must hold idx.mu.
+func (idx *Indexer) unindexLocked(c types.Constraint) {
+	if c.Namespace == "" {
+		delete(idx.clusterScoped, c.UID)
+	} else {
+		for _, ns := range namespacesOf(c) {
+			removeFromSet(idx.byNamespace, ns, c.UID)
+		}
+	}
+	removeFromSet(idx.byType, c.ConstraintType, c.UID)
+	removeFromSet(idx.bySeverity, c.Severity, c.UID)
+	removeFromSet(idx.bySource, c.Source, c.UID)
+}
+
+// removeLocked deletes the constraint with uid from all indexes and reports
+// whether it existed. Caller must hold idx.mu.
+func (idx *Indexer) removeLocked(uid k8stypes.UID) (types.Constraint, bool) {
+	c, exists := idx.byUID[uid]
+	if exists {
+		idx.unindexLocked(c)
+		delete(idx.byUID, uid)
+	}
+	return c, exists
+}
+
+// collectLocked returns the constraints for the UIDs in set. Caller must hold
+// idx.mu.
+func (idx *Indexer) collectLocked(set uidSet) []types.Constraint {
+	if len(set) == 0 {
+		return nil
+	}
+	result := make([]types.Constraint, 0, len(set))
+	for uid := range set {
+		result = append(result, idx.byUID[uid])
 	}
+	return result
 }
 
 // Upsert adds the constraint or replaces an existing one with the same UID.
 func (idx *Indexer) Upsert(c types.Constraint) {
 	idx.mu.Lock()
+	ev := IndexEvent{Type: EventUpsert, Constraint: c}
+	if prev, existed := idx.removeLocked(c.UID); existed {
+		prev.RawObject = nil
+		ev.previous = &prev
+	}
 	idx.byUID[c.UID] = c
+	idx.indexLocked(c)
+	ev = idx.recordLocked(ev)
 	idx.mu.Unlock()
 
 	if idx.onChange != nil {
-		idx.onChange(IndexEvent{Type: "upsert", Constraint: c})
+		idx.onChange(ev)
 	}
 }
 
 // Delete removes the constraint with the given UID. No-op if not found.
 func (idx *Indexer) Delete(uid k8stypes.UID) {
 	idx.mu.Lock()
-	c, exists := idx.byUID[uid]
+	c, exists := idx.removeLocked(uid)
+	var ev IndexEvent
 	if exists {
-		delete(idx.byUID, uid)
+		ev = idx.recordLocked(IndexEvent{Type: EventDelete, Constraint: c})
 	}
 	idx.mu.Unlock()
 
 	if exists && idx.onChange != nil {
-		idx.onChange(IndexEvent{Type: "delete", Constraint: c})
+		idx.onChange(ev)
 	}
 }
 
@@ -66,34 +201,16 @@ func (idx *Indexer) ByNamespace(ns string) []types.Constraint {
 	idx.mu.RLock()
 	defer idx.mu.RUnlock()
 
-	var result []types.Constraint
-	for _, c := range idx.byUID {
-		if idx.matchesNamespace(c, ns) {
-			result = append(result, c)
+	nsSet := idx.byNamespace[ns]
+	result := make([]types.Constraint, 0, len(nsSet)+len(idx.clusterScoped))
+	for _, set := range []uidSet{nsSet, idx.clusterScoped} {
+		for uid := range set {
+			result = append(result, idx.byUID[uid])
 		}
 	}
 	return result
 }
 
-// matchesNamespace checks if a constraint affects the given namespace.
-func (idx *Indexer) matchesNamespace(c types.Constraint, ns string) bool {
-	// Cluster-scoped constraints (empty namespace) match all namespaces
-	if c.Namespace == "" {
-		return true
-	}
-	// Direct namespace match
-	if c.Namespace == ns {
-		return true
-	}
-	// Check AffectedNamespaces
-	for _, affected := range c.AffectedNamespaces {
-		if affected == ns {
-			return true
-		}
-	}
-	return false
-}
-
 // ByLabels returns constraints from ByNamespace(ns) where WorkloadSelector matches labels.
 // A nil WorkloadSelector matches all labels (cluster-wide constraint).
 // An empty WorkloadSelector (non-nil, zero matchLabels) also matches all.
@@ -102,12 +219,12 @@ func (idx *Indexer) ByLabels(ns string, workloadLabels map[string]string) []type
 	defer idx.mu.RUnlock()
 
 	var result []types.Constraint
-	for _, c := range idx.byUID {
-		if !idx.matchesNamespace(c, ns) {
-			continue
-		}
-		if idx.matchesLabels(c.WorkloadSelector, workloadLabels) {
-			result = append(result, c)
+	for _, set := range []uidSet{idx.byNamespace[ns], idx.clusterScoped} {
+		for uid := range set {
+			c := idx.byUID[uid]
+			if idx.matchesLabels(c.WorkloadSelector, workloadLabels) {
+				result = append(result, c)
+			}
 		}
 	}
 	return result
@@ -122,28 +239,14 @@ func (idx *Indexer) matchesLabels(selector *metav1.LabelSelector, workloadLabels
 func (idx *Indexer) ByType(ct types.ConstraintType) []types.Constraint {
 	idx.mu.RLock()
 	defer idx.mu.RUnlock()
-
-	var result []types.Constraint
-	for _, c := range idx.byUID {
-		if c.ConstraintType == ct {
-			result = append(result, c)
-		}
-	}
-	return result
+	return idx.collectLocked(idx.byType[ct])
 }
 
 // BySourceGVR returns all constraints parsed from the given source GVR.
 func (idx *Indexer) BySourceGVR(gvr schema.GroupVersionResource) []types.Constraint {
 	idx.mu.RLock()
 	defer idx.mu.RUnlock()
-
-	var result []types.Constraint
-	for _, c := range idx.byUID {
-		if c.Source == gvr {
-			result = append(result, c)
-		}
-	}
-	return result
+	return idx.collectLocked(idx.bySource[gvr])
 }
 
 // DeleteBySource removes all constraints originating from the given GVR.
@@ -151,22 +254,19 @@ func (idx *Indexer) BySourceGVR(gvr schema.GroupVersionResource) []types.Constra
 func (idx *Indexer) DeleteBySource(gvr schema.GroupVersionResource) int {
 	idx.mu.Lock()
 	var toDelete []k8stypes.UID
-	for uid, c := range idx.byUID {
-		if c.Source == gvr {
-			toDelete = append(toDelete, uid)
-		}
+	for uid := range idx.bySource[gvr] {
+		toDelete = append(toDelete, uid)
 	}
-	deleted := make([]types.Constraint, 0, len(toDelete))
+	deleted := make([]IndexEvent, 0, len(toDelete))
 	for _, uid := range toDelete {
-		c := idx.byUID[uid]
-		delete(idx.byUID, uid)
-		deleted = append(deleted, c)
+		c, _ := idx.removeLocked(uid)
+		deleted = append(deleted, idx.recordLocked(IndexEvent{Type: EventDelete, Constraint: c}))
 	}
 	idx.mu.Unlock()
 
 	if idx.onChange != nil {
-		for _, c := range deleted {
-			idx.onChange(IndexEvent{Type: "delete", Constraint: c})
+		for _, ev := range deleted {
+			idx.onChange(ev)
 		}
 	}
 	return len(toDelete)
@@ -184,6 +284,17 @@ func (idx *Indexer) All() []types.Constraint {
 	return result
 }
 
+// SetStale marks whether the index content may be out of date, e.g. restored
+// from a snapshot before the informers synced.
+func (idx *Indexer) SetStale(stale bool) {
+	idx.stale.Store(stale)
+}
+
+// Stale reports whether the index content may be out of date.
+func (idx *Indexer) Stale() bool {
+	return idx.stale.Load()
+}
+
 // Count returns the total number of stored constraints.
 func (idx *Indexer) Count() int {
 	idx.mu.RLock()
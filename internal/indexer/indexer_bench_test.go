@@ -0,0 +1,172 @@
+package indexer
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+
+	"github.com/nightjarctl/nightjar/internal/types"
+)
+
+// benchPolicy builds a policy object of roughly the size of a typical
+// NetworkPolicy with a handful of rules.
+func benchPolicy(i int) *unstructured.Unstructured {
+	rules := make([]interface{}, 0, 4)
+	for r := 0; r < 4; r++ {
+		rules = append(rules, map[string]interface{}{
+			"ports": []interface{}{
+				map[string]interface{}{"port": int64(8000 + r), "protocol": "TCP"},
+			},
+			"to": []interface{}{
+				map[string]interface{}{"ipBlock": map[string]interface{}{"cidr": fmt.Sprintf("10.%d.0.0/16", r)}},
+			},
+		})
+	}
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "networking.k8s.io/v1",
+		"kind":       "NetworkPolicy",
+		"metadata": map[string]interface{}{
+			"name":      fmt.Sprintf("policy-%d", i),
+			"namespace": fmt.Sprintf("ns-%d", i%500),
+			"labels":    map[string]interface{}{"app.kubernetes.io/managed-by": "platform"},
+		},
+		"spec": map[string]interface{}{
+			"podSelector": map[string]interface{}{"matchLabels": map[string]interface{}{"app": fmt.Sprintf("app-%d", i)}},
+			"policyTypes": []interface{}{"Egress"},
+			"egress":      rules,
+		},
+	}}
+	obj.SetUID(k8stypes.UID(fmt.Sprintf("uid-%d", i)))
+	return obj
+}
+
+// heapInUse returns live heap bytes after a full collection.
+func heapInUse() uint64 {
+	runtime.GC()
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return m.HeapAlloc
+}
+
+// BenchmarkIndexMemory reports the heap the index adds on top of the
+// informer cache, with each constraint holding a deep copy of its source
+// object versus a reference to the cached object.
+func BenchmarkIndexMemory(b *testing.B) {
+	gvr := schema.GroupVersionResource{Group: "networking.k8s.io", Version: "v1", Resource: "networkpolicies"}
+	for _, n := range []int{10_000, 100_000} {
+		cacheObjs := make([]*unstructured.Unstructured, n)
+		for i := range cacheObjs {
+			cacheObjs[i] = benchPolicy(i)
+		}
+
+		for _, mode := range []string{"deepcopy", "shared"} {
+			b.Run(fmt.Sprintf("%s/%dk", mode, n/1000), func(b *testing.B) {
+				var perConstraint float64
+				for iter := 0; iter < b.N; iter++ {
+					before := heapInUse()
+					idx := New(nil)
+					for _, obj := range cacheObjs {
+						raw := obj
+						if mode == "deepcopy" {
+							raw = obj.DeepCopy()
+						}
+						idx.Upsert(types.Constraint{
+							UID:                obj.GetUID(),
+							Source:             gvr,
+							Name:               obj.GetName(),
+							Namespace:          obj.GetNamespace(),
+							AffectedNamespaces: []string{obj.GetNamespace()},
+							ConstraintType:     types.ConstraintTypeNetworkEgress,
+							Severity:           types.SeverityWarning,
+							RawObject:          raw,
+						})
+					}
+					after := heapInUse()
+					if after > before {
+						perConstraint = float64(after-before) / float64(n)
+					}
+					runtime.KeepAlive(idx)
+				}
+				b.ReportMetric(perConstraint, "heap-B/constraint")
+				b.ReportMetric(perConstraint*float64(n)/(1<<20), "heap-MiB")
+			})
+		}
+		runtime.KeepAlive(cacheObjs)
+	}
+}
+
+// benchIndex builds an index of n constraints spread over 500 namespaces,
+// four constraint types and 20 source GVRs, with 100 cluster-scoped ones
+// (webhooks, cluster policies) that match every namespace.
+func benchIndex(n int) *Indexer {
+	ctypes := []types.ConstraintType{
+		types.ConstraintTypeNetworkEgress, types.ConstraintTypeNetworkIngress,
+		types.ConstraintTypeAdmission, types.ConstraintTypeResourceLimit,
+	}
+	severities := []types.Severity{types.SeverityInfo, types.SeverityWarning, types.SeverityCritical}
+
+	idx := New(nil)
+	for i := 0; i < n; i++ {
+		ns := fmt.Sprintf("ns-%d", i%500)
+		c := types.Constraint{
+			UID:                k8stypes.UID(fmt.Sprintf("uid-%d", i)),
+			Source:             schema.GroupVersionResource{Group: "bench.io", Version: "v1", Resource: fmt.Sprintf("policies%d", i%20)},
+			Name:               fmt.Sprintf("policy-%d", i),
+			Namespace:          ns,
+			AffectedNamespaces: []string{ns},
+			ConstraintType:     ctypes[i%len(ctypes)],
+			Severity:           severities[i%len(severities)],
+			WorkloadSelector:   &metav1.LabelSelector{MatchLabels: map[string]string{"app": fmt.Sprintf("app-%d", i%50)}},
+		}
+		if i%(n/100) == 0 {
+			c.Namespace = ""
+			c.AffectedNamespaces = nil
+		}
+		idx.Upsert(c)
+	}
+	return idx
+}
+
+// BenchmarkQuery measures indexed lookups at 100k constraints. Each should
+// stay well under a millisecond per operation.
+func BenchmarkQuery(b *testing.B) {
+	idx := benchIndex(100_000)
+	egress := types.ConstraintTypeNetworkEgress
+	critical := types.SeverityCritical
+	source := schema.GroupVersionResource{Group: "bench.io", Version: "v1", Resource: "policies2"}
+	workload := map[string]string{"app": "app-42", "tier": "backend"}
+
+	b.Run("ByNamespace", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = idx.ByNamespace("ns-42")
+		}
+	})
+	b.Run("ByLabels", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = idx.ByLabels("ns-42", workload)
+		}
+	})
+	b.Run("Query/namespace+type+severity", func(b *testing.B) {
+		q := types.ConstraintQuery{Namespace: "ns-42", ConstraintType: &egress, Severity: &critical}
+		for i := 0; i < b.N; i++ {
+			_ = idx.Query(q)
+		}
+	})
+	b.Run("Query/namespace+source+labels", func(b *testing.B) {
+		q := types.ConstraintQuery{Namespace: "ns-42", SourceGVR: &source, Labels: workload}
+		for i := 0; i < b.N; i++ {
+			_ = idx.Query(q)
+		}
+	})
+	b.Run("Query/namespace+labels", func(b *testing.B) {
+		q := types.ConstraintQuery{Namespace: "ns-42", Labels: workload}
+		for i := 0; i < b.N; i++ {
+			_ = idx.Query(q)
+		}
+	})
+}
@@ -234,3 +234,102 @@ func TestConcurrency(t *testing.T) {
 	// No panic = success
 	assert.True(t, idx.Count() > 0)
 }
+
+func uidsOf(cs []types.Constraint) []string {
+	out := make([]string, 0, len(cs))
+	for _, c := range cs {
+		out = append(out, string(c.UID))
+	}
+	return out
+}
+
+func TestQuery(t *testing.T) {
+	idx := New(nil)
+	cilium := schema.GroupVersionResource{Group: "cilium.io", Version: "v2", Resource: "ciliumnetworkpolicies"}
+
+	egress := makeConstraint("egress-a", "ns-a", types.ConstraintTypeNetworkEgress, map[string]string{"app": "web"})
+	egress.Severity = types.SeverityCritical
+	egress.Source = cilium
+	idx.Upsert(egress)
+
+	quota := makeConstraint("quota-a", "ns-a", types.ConstraintTypeResourceLimit, nil)
+	quota.Severity = types.SeverityWarning
+	idx.Upsert(quota)
+
+	other := makeConstraint("egress-b", "ns-b", types.ConstraintTypeNetworkEgress, nil)
+	other.Severity = types.SeverityCritical
+	idx.Upsert(other)
+
+	webhook := makeConstraint("webhook", "", types.ConstraintTypeAdmission, nil)
+	webhook.AffectedNamespaces = nil
+	webhook.Severity = types.SeverityWarning
+	idx.Upsert(webhook)
+
+	egressType := types.ConstraintTypeNetworkEgress
+	critical := types.SeverityCritical
+	warning := types.SeverityWarning
+
+	tests := []struct {
+		name  string
+		query types.ConstraintQuery
+		want  []string
+	}{
+		{"empty query returns all", types.ConstraintQuery{}, []string{"egress-a", "quota-a", "egress-b", "webhook"}},
+		{"namespace includes cluster-scoped", types.ConstraintQuery{Namespace: "ns-a"}, []string{"egress-a", "quota-a", "webhook"}},
+		{"type", types.ConstraintQuery{ConstraintType: &egressType}, []string{"egress-a", "egress-b"}},
+		{"namespace and type", types.ConstraintQuery{Namespace: "ns-b", ConstraintType: &egressType}, []string{"egress-b"}},
+		{"severity", types.ConstraintQuery{Severity: &warning}, []string{"quota-a", "webhook"}},
+		{"source", types.ConstraintQuery{SourceGVR: &cilium}, []string{"egress-a"}},
+		{"all indexed fields", types.ConstraintQuery{Namespace: "ns-a", ConstraintType: &egressType, Severity: &critical, SourceGVR: &cilium}, []string{"egress-a"}},
+		{"labels filter selectors", types.ConstraintQuery{Namespace: "ns-a", Labels: map[string]string{"app": "api"}}, []string{"quota-a", "webhook"}},
+		{"labels match", types.ConstraintQuery{Namespace: "ns-a", Labels: map[string]string{"app": "web"}}, []string{"egress-a", "quota-a", "webhook"}},
+		{"unknown namespace keeps cluster-scoped", types.ConstraintQuery{Namespace: "ns-z"}, []string{"webhook"}},
+		{"no match", types.ConstraintQuery{Namespace: "ns-b", Severity: &warning, ConstraintType: &egressType}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ElementsMatch(t, tt.want, uidsOf(idx.Query(tt.query)))
+		})
+	}
+}
+
+func TestSecondaryIndexes_FollowUpdates(t *testing.T) {
+	idx := New(nil)
+	egressType := types.ConstraintTypeNetworkEgress
+	admissionType := types.ConstraintTypeAdmission
+
+	c := makeConstraint("uid-1", "ns-a", types.ConstraintTypeNetworkEgress, nil)
+	c.AffectedNamespaces = []string{"ns-a", "ns-b"}
+	idx.Upsert(c)
+	assert.Len(t, idx.ByNamespace("ns-b"), 1)
+
+	// Moving the constraint re-indexes it under its new keys only.
+	c.Namespace = "ns-c"
+	c.AffectedNamespaces = nil
+	c.ConstraintType = types.ConstraintTypeAdmission
+	idx.Upsert(c)
+	assert.Empty(t, idx.ByNamespace("ns-a"))
+	assert.Empty(t, idx.ByNamespace("ns-b"))
+	assert.Len(t, idx.ByNamespace("ns-c"), 1)
+	assert.Empty(t, idx.Query(types.ConstraintQuery{ConstraintType: &egressType}))
+	assert.Len(t, idx.Query(types.ConstraintQuery{ConstraintType: &admissionType}), 1)
+
+	// Becoming cluster-scoped makes it visible in every namespace.
+	c.Namespace = ""
+	idx.Upsert(c)
+	assert.Len(t, idx.ByNamespace("anything"), 1)
+
+	idx.Delete(c.UID)
+	assert.Empty(t, idx.ByNamespace("anything"))
+	assert.Empty(t, idx.Query(types.ConstraintQuery{ConstraintType: &admissionType}))
+	assert.Empty(t, idx.byNamespace, "empty index sets are dropped")
+	assert.Empty(t, idx.byType)
+	assert.Empty(t, idx.bySource)
+
+	idx.Upsert(makeConstraint("uid-2", "ns-a", types.ConstraintTypeNetworkEgress, nil))
+	src := schema.GroupVersionResource{Group: "test", Version: "v1", Resource: "tests"}
+	assert.Equal(t, 1, idx.DeleteBySource(src))
+	assert.Empty(t, idx.BySourceGVR(src))
+	assert.Empty(t, idx.ByNamespace("ns-a"))
+	assert.Empty(t, idx.bySeverity)
+}
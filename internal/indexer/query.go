@@ -0,0 +1,128 @@
+package indexer
+
+import (
+	k8stypes "k8s.io/apimachinery/pkg/types"
+
+	"github.com/nightjarctl/nightjar/internal/types"
+	"github.com/nightjarctl/nightjar/internal/util"
+)
+
+// candidates is one indexed filter of a query: the UID sets that satisfy it.
+// A UID satisfies the filter if any of the sets contains it. The sets of one
+// filter are disjoint, so size is exact and iterating them yields each UID
+// once.
+type candidates []uidSet
+
+func (c candidates) size() int {
+	n := 0
+	for _, set := range c {
+		n += len(set)
+	}
+	return n
+}
+
+func (c candidates) has(uid k8stypes.UID) bool {
+	for _, set := range c {
+		if set.has(uid) {
+			return true
+		}
+	}
+	return false
+}
+
+// Query returns the constraints matching every set field of q.
+//
+// Namespace, ConstraintType, Severity and SourceGVR are answered from the
+// secondary indexes: the smallest matching candidate set is iterated and
+// checked for membership in the others. Namespace has ByNamespace semantics,
+// so cluster-scoped constraints match any namespace. Labels is applied last
+// to the survivors with ByLabels semantics; a nil Labels map skips selector
+// matching. An empty query returns every constraint.
+func (idx *Indexer) Query(q types.ConstraintQuery) []types.Constraint {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var filters []candidates
+	if q.Namespace != "" {
+		filters = append(filters, candidates{idx.byNamespace[q.Namespace], idx.clusterScoped})
+	}
+	if q.ConstraintType != nil {
+		filters = append(filters, candidates{idx.byType[*q.ConstraintType]})
+	}
+	if q.Severity != nil {
+		filters = append(filters, candidates{idx.bySeverity[*q.Severity]})
+	}
+	if q.SourceGVR != nil {
+		filters = append(filters, candidates{idx.bySource[*q.SourceGVR]})
+	}
+
+	keep := func(c types.Constraint) bool {
+		return q.Labels == nil || idx.matchesLabels(c.WorkloadSelector, q.Labels)
+	}
+
+	var result []types.Constraint
+	if len(filters) == 0 {
+		for _, c := range idx.byUID {
+			if keep(c) {
+				result = append(result, c)
+			}
+		}
+		return result
+	}
+
+	// Drive the intersection from the most selective filter.
+	driver := 0
+	for i := range filters {
+		if filters[i].size() < filters[driver].size() {
+			driver = i
+		}
+	}
+
+	for _, set := range filters[driver] {
+		for uid := range set {
+			if !idx.inAll(uid, filters, driver) {
+				continue
+			}
+			if c := idx.byUID[uid]; keep(c) {
+				result = append(result, c)
+			}
+		}
+	}
+	return result
+}
+
+// inAll reports whether uid satisfies every filter other than skip.
+func (idx *Indexer) inAll(uid k8stypes.UID, filters []candidates, skip int) bool {
+	for i, f := range filters {
+		if i != skip && !f.has(uid) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesQuery reports whether c satisfies q with Query semantics.
+func matchesQuery(q types.ConstraintQuery, c types.Constraint) bool {
+	if q.Namespace != "" && c.Namespace != "" && c.Namespace != q.Namespace && !contains(c.AffectedNamespaces, q.Namespace) {
+		return false
+	}
+	if q.ConstraintType != nil && c.ConstraintType != *q.ConstraintType {
+		return false
+	}
+	if q.Severity != nil && c.Severity != *q.Severity {
+		return false
+	}
+	if q.SourceGVR != nil && c.Source != *q.SourceGVR {
+		return false
+	}
+	return q.Labels == nil || util.MatchesLabelSelector(c.WorkloadSelector, q.Labels)
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
@@ -24,7 +24,8 @@
 // The MCP server exposes these tools:
 //
 //	nightjar_query
-//	  Query constraints affecting a namespace or workload.
+//	  Query constraints affecting a namespace or workload. With Hubble enabled,
+//	  also returns the busiest flow drop paths to or from it (flow_drops).
 //	  Params: namespace (required), workload_name, workload_labels, constraint_type, severity, include_remediation
 //	  Returns: ConstraintQueryResult
 //
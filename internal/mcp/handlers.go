@@ -2,6 +2,7 @@ package mcp
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"sort"
 	"strings"
@@ -11,9 +12,12 @@ import (
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"sigs.k8s.io/yaml"
 
+	"github.com/nightjarctl/nightjar/internal/adapters/istio"
+	"github.com/nightjarctl/nightjar/internal/flowstats"
 	"github.com/nightjarctl/nightjar/internal/indexer"
 	"github.com/nightjarctl/nightjar/internal/notifier"
 	"github.com/nightjarctl/nightjar/internal/requirements"
+	"github.com/nightjarctl/nightjar/internal/scope"
 	"github.com/nightjarctl/nightjar/internal/types"
 )
 
@@ -24,6 +28,8 @@ type Handlers struct {
 	privacyResolver    PrivacyResolverFunc
 	remediationBuilder *notifier.RemediationBuilder
 	evaluator          *requirements.Evaluator
+	nsScope            *scope.Scope     // nil serves every namespace
+	flowStats          *flowstats.Store // nil omits flow drops
 }
 
 // NewHandlers creates a new Handlers instance.
@@ -55,19 +61,26 @@ func (h *Handlers) HandleQuery(w http.ResponseWriter, r *http.Request) {
 		h.writeError(w, "namespace is required", http.StatusBadRequest)
 		return
 	}
+	if !h.checkScope(w, params.Namespace) {
+		return
+	}
 
 	detailLevel := h.privacyResolver(r)
 
-	// Query constraints
-	var constraints []types.Constraint
+	// Query constraints; type and severity filters are answered by the index.
+	query := types.ConstraintQuery{Namespace: params.Namespace}
 	if len(params.WorkloadLabels) > 0 {
-		constraints = h.indexer.ByLabels(params.Namespace, params.WorkloadLabels)
-	} else {
-		constraints = h.indexer.ByNamespace(params.Namespace)
+		query.Labels = params.WorkloadLabels
 	}
-
-	// Apply filters
-	constraints = h.filterConstraints(constraints, params)
+	if params.ConstraintType != "" {
+		ct := types.ConstraintType(params.ConstraintType)
+		query.ConstraintType = &ct
+	}
+	if params.Severity != "" {
+		sev := types.Severity(params.Severity)
+		query.Severity = &sev
+	}
+	constraints := h.indexer.Query(query)
 
 	// Convert to results
 	results := make([]ConstraintResult, 0, len(constraints))
@@ -90,11 +103,40 @@ func (h *Handlers) HandleQuery(w http.ResponseWriter, r *http.Request) {
 		Namespace:   params.Namespace,
 		Constraints: results,
 		Total:       len(results),
+		FlowDrops:   h.flowDrops(params.Namespace, params.WorkloadName, detailLevel),
 	}
 
 	h.writeJSON(w, response)
 }
 
+// maxQueryFlowDrops bounds the flow drop paths returned by nightjar_query.
+const maxQueryFlowDrops = 20
+
+// flowDrops returns the busiest flow drop paths to or from namespace, limited
+// to those of workload in namespace when it is set.
+func (h *Handlers) flowDrops(namespace, workload string, detailLevel types.DetailLevel) []FlowDropResult {
+	if h.flowStats == nil {
+		return nil
+	}
+	window := h.flowStats.Window()
+
+	var results []FlowDropResult
+	for _, e := range h.flowStats.ForNamespace(namespace) {
+		if workload != "" && !flowEndpointIs(e.Source, namespace, workload) && !flowEndpointIs(e.Destination, namespace, workload) {
+			continue
+		}
+		results = append(results, ToFlowDropResult(e, detailLevel, namespace, window))
+		if len(results) == maxQueryFlowDrops {
+			break
+		}
+	}
+	return results
+}
+
+func flowEndpointIs(ep flowstats.Endpoint, namespace, workload string) bool {
+	return ep.Namespace == namespace && ep.Workload == workload
+}
+
 // HandleExplain handles the nightjar_explain tool.
 func (h *Handlers) HandleExplain(w http.ResponseWriter, r *http.Request) {
 	var params ExplainParams
@@ -107,12 +149,22 @@ func (h *Handlers) HandleExplain(w http.ResponseWriter, r *http.Request) {
 		h.writeError(w, "error_message and namespace are required", http.StatusBadRequest)
 		return
 	}
+	if !h.checkScope(w, params.Namespace) {
+		return
+	}
 
 	detailLevel := h.privacyResolver(r)
 
 	// Get constraints for namespace
 	constraints := h.indexer.ByNamespace(params.Namespace)
 
+	// A REGISTRY_ONLY mesh drops calls to unregistered hosts without any
+	// NetworkPolicy involved; name the host when we can.
+	if result, ok := h.explainRegistryOnly(params, constraints, detailLevel); ok {
+		h.writeJSON(w, result)
+		return
+	}
+
 	// Try to match error message to constraints
 	matchingConstraints, confidence, explanation := h.matchErrorToConstraints(
 		params.ErrorMessage,
@@ -172,6 +224,9 @@ func (h *Handlers) HandleCheck(w http.ResponseWriter, r *http.Request) {
 	if namespace == "" {
 		namespace = "default"
 	}
+	if !h.checkScope(w, namespace) {
+		return
+	}
 
 	labels, _ := metadata["labels"].(map[string]interface{})
 	labelMap := make(map[string]string)
@@ -241,6 +296,10 @@ func (h *Handlers) HandleListNamespaces(w http.ResponseWriter, r *http.Request)
 
 	var summaries []NamespaceSummary
 	for ns, constraints := range nsMap {
+		if !h.nsScope.Allows(ns) {
+			continue
+		}
+
 		// Deduplicate by UID
 		seen := make(map[string]bool)
 		var unique []types.Constraint
@@ -303,6 +362,9 @@ func (h *Handlers) HandleRemediation(w http.ResponseWriter, r *http.Request) {
 		h.writeError(w, "constraint_name and namespace are required", http.StatusBadRequest)
 		return
 	}
+	if !h.checkScope(w, params.Namespace) {
+		return
+	}
 
 	// Find the constraint
 	constraints := h.indexer.ByNamespace(params.Namespace)
@@ -353,6 +415,9 @@ func (h *Handlers) HandleReportResource(w http.ResponseWriter, r *http.Request)
 		h.writeError(w, "Namespace is required", http.StatusBadRequest)
 		return
 	}
+	if !h.checkScope(w, namespace) {
+		return
+	}
 
 	detailLevel := h.privacyResolver(r)
 
@@ -376,6 +441,9 @@ func (h *Handlers) HandleConstraintResource(w http.ResponseWriter, r *http.Reque
 
 	namespace := parts[0]
 	name := parts[1]
+	if !h.checkScope(w, namespace) {
+		return
+	}
 
 	detailLevel := h.privacyResolver(r)
 
@@ -472,27 +540,6 @@ func (h *Handlers) HandleCapabilitiesResource(w http.ResponseWriter, r *http.Req
 	h.writeJSON(w, response)
 }
 
-// filterConstraints filters constraints based on query parameters.
-func (h *Handlers) filterConstraints(constraints []types.Constraint, params QueryParams) []types.Constraint {
-	var result []types.Constraint
-
-	for _, c := range constraints {
-		// Filter by constraint type
-		if params.ConstraintType != "" && string(c.ConstraintType) != params.ConstraintType {
-			continue
-		}
-
-		// Filter by severity
-		if params.Severity != "" && string(c.Severity) != params.Severity {
-			continue
-		}
-
-		result = append(result, c)
-	}
-
-	return result
-}
-
 // matchErrorToConstraints tries to match an error message to relevant constraints.
 func (h *Handlers) matchErrorToConstraints(
 	errorMessage string,
@@ -580,6 +627,69 @@ func (h *Handlers) matchErrorToConstraints(
 	return matches, confidence, explanation
 }
 
+// explainRegistryOnly explains errors caused by an Istio mesh running with
+// outboundTrafficPolicy REGISTRY_ONLY when the called host has no visible
+// ServiceEntry. Returns false when the error is not attributable to it.
+func (h *Handlers) explainRegistryOnly(params ExplainParams, constraints []types.Constraint, detailLevel types.DetailLevel) (ExplainResult, bool) {
+	var meshPolicy *types.Constraint
+	for i := range constraints {
+		if istio.IsOutboundPolicy(constraints[i].Tags) {
+			meshPolicy = &constraints[i]
+			break
+		}
+	}
+	if meshPolicy == nil || !istio.IsRegistryOnlySymptom(params.ErrorMessage) {
+		return ExplainResult{}, false
+	}
+
+	host, port := istio.ExtractExternalHost(params.ErrorMessage)
+	if host != "" {
+		for _, c := range h.indexer.All() {
+			if istio.IsServiceEntrySource(c) && istio.VisibleTo(c, params.Namespace) &&
+				istio.HostMatches(host, istio.RegisteredHosts(c)) {
+				// Host is registered; the failure has another cause.
+				return ExplainResult{}, false
+			}
+		}
+	}
+
+	result := h.toConstraintResultWithRemediation(*meshPolicy, detailLevel, params.Namespace)
+	response := ExplainResult{
+		MatchingConstraints: []ConstraintResult{result},
+		Confidence:          "high",
+	}
+
+	if host == "" {
+		response.Confidence = "medium"
+		response.Explanation = "The mesh outboundTrafficPolicy is REGISTRY_ONLY, so calls to hosts without a ServiceEntry " +
+			"are sent to BlackHoleCluster (502). Add a ServiceEntry for the host your workload calls."
+		if result.Remediation != nil {
+			response.RemediationSteps = result.Remediation.Steps
+		}
+		return response, true
+	}
+
+	response.Explanation = fmt.Sprintf("Host %s is not registered; add a ServiceEntry. "+
+		"The mesh outboundTrafficPolicy is REGISTRY_ONLY, so calls to hosts without a ServiceEntry "+
+		"are sent to BlackHoleCluster (502). No NetworkPolicy is involved.", host)
+	response.RemediationSteps = []RemediationStep{
+		{
+			Type:              "yaml_patch",
+			Description:       fmt.Sprintf("Create a ServiceEntry registering %s", host),
+			Template:          istio.ServiceEntryTemplate(host, port, params.Namespace),
+			RequiresPrivilege: "namespace-admin",
+		},
+		{
+			Type:              "kubectl",
+			Description:       "List ServiceEntries visible to your namespace",
+			Command:           "kubectl get serviceentries -A",
+			RequiresPrivilege: "developer",
+			Automated:         true,
+		},
+	}
+	return response, true
+}
+
 // toConstraintResultWithRemediation converts a constraint to a result with remediation.
 func (h *Handlers) toConstraintResultWithRemediation(c types.Constraint, detailLevel types.DetailLevel, namespace string) ConstraintResult {
 	result := ToConstraintResult(c, detailLevel, namespace)
@@ -646,6 +756,16 @@ func (h *Handlers) buildReport(constraints []types.Constraint, namespace string,
 	}
 }
 
+// checkScope writes an error and returns false if namespace is outside the
+// namespace scope.
+func (h *Handlers) checkScope(w http.ResponseWriter, namespace string) bool {
+	if h.nsScope.Allows(namespace) {
+		return true
+	}
+	h.writeError(w, fmt.Sprintf("namespace %q is outside the Nightjar namespace scope", namespace), http.StatusForbidden)
+	return false
+}
+
 // writeJSON writes a JSON response.
 func (h *Handlers) writeJSON(w http.ResponseWriter, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
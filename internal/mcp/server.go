@@ -1,17 +1,22 @@
 package mcp
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"sync"
 	"time"
 
 	"go.uber.org/zap"
 
+	"github.com/nightjarctl/nightjar/internal/flowstats"
 	"github.com/nightjarctl/nightjar/internal/indexer"
 	"github.com/nightjarctl/nightjar/internal/requirements"
+	"github.com/nightjarctl/nightjar/internal/scope"
+	"github.com/nightjarctl/nightjar/internal/shard"
 	"github.com/nightjarctl/nightjar/internal/types"
 )
 
@@ -39,6 +44,16 @@ type ServerOptions struct {
 
 	// Evaluator for missing-resource detection in pre-check. May be nil.
 	Evaluator *requirements.Evaluator
+
+	// Scope rejects queries for namespaces outside it. May be nil.
+	Scope *scope.Scope
+
+	// Shards forwards tool calls about a namespace to the shard owning it.
+	// May be nil.
+	Shards *shard.Forwarder
+
+	// FlowStats adds the busiest flow drop paths to query results. May be nil.
+	FlowStats *flowstats.Store
 }
 
 // DefaultServerOptions returns sensible defaults.
@@ -88,6 +103,8 @@ func NewServer(idx *indexer.Indexer, opts ServerOptions) *Server {
 	}
 
 	s.handlers = NewHandlers(idx, opts.PrivacyResolver, opts.DefaultContact, opts.Logger, opts.Evaluator)
+	s.handlers.nsScope = opts.Scope
+	s.handlers.flowStats = opts.FlowStats
 
 	return s
 }
@@ -152,11 +169,36 @@ func (s *Server) handleTool(handler func(http.ResponseWriter, *http.Request)) ht
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
+		if s.forwardTool(w, r) {
+			return
+		}
 		w.Header().Set("Content-Type", "application/json")
 		handler(w, r)
 	}
 }
 
+// forwardTool forwards a tool call to the shard owning the namespace in its
+// parameters and reports whether it did. Calls without a namespace are
+// answered locally.
+func (s *Server) forwardTool(w http.ResponseWriter, r *http.Request) bool {
+	if s.opts.Shards == nil {
+		return false
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "reading request body: "+err.Error(), http.StatusBadRequest)
+		return true
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	var params struct {
+		Namespace string `json:"namespace"`
+	}
+	if json.Unmarshal(body, &params) != nil {
+		return false
+	}
+	return s.opts.Shards.Forward(w, r, params.Namespace, body)
+}
+
 // handleResource wraps a resource handler with common middleware.
 func (s *Server) handleResource(handler func(http.ResponseWriter, *http.Request)) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -252,7 +294,7 @@ func (s *Server) handleToolsList(w http.ResponseWriter, r *http.Request) {
 	tools := []map[string]interface{}{
 		{
 			"name":        "nightjar_query",
-			"description": "Query constraints affecting a namespace or workload",
+			"description": "Query constraints affecting a namespace or workload, and the traffic to or from it that policies are dropping",
 			"inputSchema": map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
@@ -354,11 +396,13 @@ func (s *Server) handleResourcesList(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]interface{}{"resources": resources})
 }
 
-// OnIndexChange is called when the constraint index changes.
-// It broadcasts updates to connected SSE clients.
+// OnIndexChange handles an event from an indexer subscription.
+// It broadcasts updates to connected SSE clients. A resync event carries no
+// constraint; clients should re-query what they display.
 func (s *Server) OnIndexChange(event indexer.IndexEvent) {
 	s.BroadcastEvent("constraint_change", map[string]interface{}{
 		"type":           event.Type,
+		"revision":       event.Revision,
 		"constraintUID":  string(event.Constraint.UID),
 		"constraintName": event.Constraint.Name,
 		"namespace":      event.Constraint.Namespace,
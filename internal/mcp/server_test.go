@@ -4,19 +4,30 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strconv"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	k8stypes "k8s.io/apimachinery/pkg/types"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
 
+	"github.com/nightjarctl/nightjar/internal/adapters/istio"
+	"github.com/nightjarctl/nightjar/internal/flowstats"
+	"github.com/nightjarctl/nightjar/internal/hubble"
 	"github.com/nightjarctl/nightjar/internal/indexer"
 	"github.com/nightjarctl/nightjar/internal/requirements"
+	"github.com/nightjarctl/nightjar/internal/scope"
+	"github.com/nightjarctl/nightjar/internal/shard"
 	"github.com/nightjarctl/nightjar/internal/types"
 )
 
@@ -160,6 +171,65 @@ func TestHandlers_Query_WithFilters(t *testing.T) {
 	assert.Equal(t, "NetworkEgress", result.Constraints[0].ConstraintType)
 }
 
+func TestHandlers_Query_FlowDrops(t *testing.T) {
+	server, _ := setupTestServer()
+	stats := flowstats.NewStore(flowstats.StoreOptions{})
+	server.handlers.flowStats = stats
+
+	policy := types.Constraint{
+		Name:      "restrict-egress",
+		Namespace: "team-alpha",
+		Source:    schema.GroupVersionResource{Group: "networking.k8s.io", Version: "v1", Resource: "networkpolicies"},
+	}
+	for _, workload := range []string{"api", "worker", "worker"} {
+		stats.Record(hubble.NewFlowDropBuilder().
+			WithSource("team-alpha", workload+"-0", nil).
+			WithSourceWorkload("Deployment", workload).
+			WithDestination("team-beta", "db-0", nil).
+			WithIP("10.0.1.5", "10.0.2.9").
+			WithTCP(40000, 5432, hubble.TCPFlags{SYN: true}).
+			WithDirection(hubble.DirectionEgress).
+			Build(), policy, nil)
+	}
+
+	query := func(params QueryParams) QueryResult {
+		body, _ := json.Marshal(params)
+		req := httptest.NewRequest(http.MethodPost, "/tools/nightjar_query", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+		server.handlers.HandleQuery(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		var result QueryResult
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+		return result
+	}
+
+	result := query(QueryParams{Namespace: "team-alpha"})
+	require.Len(t, result.FlowDrops, 2)
+	drop := result.FlowDrops[0]
+	assert.Equal(t, FlowEndpoint{Namespace: "team-alpha", Workload: "worker"}, drop.Source)
+	assert.Equal(t, FlowEndpoint{Namespace: "team-beta"}, drop.Destination)
+	assert.Equal(t, uint32(5432), drop.Port)
+	assert.Equal(t, "EGRESS", drop.Direction)
+	assert.Equal(t, "restrict-egress", drop.PolicyName)
+	assert.Equal(t, "NetworkPolicy", drop.PolicyKind)
+	assert.Equal(t, uint64(2), drop.Count)
+	assert.Equal(t, "detailed", drop.DetailLevel)
+	require.Len(t, drop.Samples, 2)
+	assert.Equal(t, "worker-0", drop.Samples[0].SourcePod)
+	assert.Empty(t, drop.Samples[0].DestinationPod)
+
+	// workload_name limits the paths to the workload's.
+	result = query(QueryParams{Namespace: "team-alpha", WorkloadName: "api"})
+	require.Len(t, result.FlowDrops, 1)
+	assert.Equal(t, "api", result.FlowDrops[0].Source.Workload)
+
+	// The destination namespace does not see the other namespace's policy.
+	result = query(QueryParams{Namespace: "team-beta"})
+	require.Len(t, result.FlowDrops, 2)
+	assert.Empty(t, result.FlowDrops[0].PolicyName)
+	assert.Equal(t, FlowEndpoint{Namespace: "team-alpha"}, result.FlowDrops[0].Source)
+}
+
 func TestHandlers_Explain(t *testing.T) {
 	server, _ := setupTestServer()
 
@@ -665,6 +735,81 @@ func TestHandlers_Explain_AdmissionError(t *testing.T) {
 	assert.True(t, found, "Should find an Admission constraint")
 }
 
+func setupRegistryOnlyServer() *Server {
+	server, idx := setupTestServer()
+	idx.Upsert(istio.OutboundPolicyConstraint(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "istio", Namespace: "istio-system"},
+	}))
+	idx.Upsert(types.Constraint{
+		UID:                k8stypes.UID("se-stripe"),
+		Name:               "stripe-api",
+		Namespace:          "team-alpha",
+		AffectedNamespaces: []string{"team-alpha"},
+		ConstraintType:     types.ConstraintTypeMeshPolicy,
+		Effect:             "allow",
+		Severity:           types.SeverityInfo,
+		Source:             schema.GroupVersionResource{Group: istio.Group, Version: "v1", Resource: istio.ServiceEntryResource},
+		Details:            map[string]interface{}{"hosts": []string{"api.stripe.com"}},
+	})
+	return server
+}
+
+func explain(t *testing.T, server *Server, params ExplainParams) ExplainResult {
+	t.Helper()
+	body, _ := json.Marshal(params)
+	req := httptest.NewRequest(http.MethodPost, "/tools/nightjar_explain", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	server.handlers.HandleExplain(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var result ExplainResult
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+	return result
+}
+
+func TestHandlers_Explain_IstioRegistryOnly(t *testing.T) {
+	server := setupRegistryOnlyServer()
+
+	result := explain(t, server, ExplainParams{
+		ErrorMessage: `Get "https://api.github.com/repos": 502 Bad Gateway (upstream cluster: BlackHoleCluster)`,
+		Namespace:    "team-alpha",
+	})
+
+	assert.Equal(t, "high", result.Confidence)
+	assert.Contains(t, result.Explanation, "Host api.github.com is not registered; add a ServiceEntry")
+	assert.Contains(t, result.Explanation, "No NetworkPolicy is involved")
+	require.Len(t, result.MatchingConstraints, 1)
+	assert.Equal(t, "istio-outbound-registry-only", result.MatchingConstraints[0].Name)
+	require.NotEmpty(t, result.RemediationSteps)
+	assert.Equal(t, "yaml_patch", result.RemediationSteps[0].Type)
+	assert.Contains(t, result.RemediationSteps[0].Template, "- api.github.com")
+	assert.Contains(t, result.RemediationSteps[0].Template, "namespace: team-alpha")
+}
+
+func TestHandlers_Explain_IstioRegistryOnly_HostRegistered(t *testing.T) {
+	server := setupRegistryOnlyServer()
+
+	result := explain(t, server, ExplainParams{
+		ErrorMessage: `Post "https://api.stripe.com/v1/charges": 502 Bad Gateway (upstream cluster: BlackHoleCluster)`,
+		Namespace:    "team-alpha",
+	})
+
+	assert.NotContains(t, result.Explanation, "is not registered")
+}
+
+func TestHandlers_Explain_IstioRegistryOnly_NoHost(t *testing.T) {
+	server := setupRegistryOnlyServer()
+
+	result := explain(t, server, ExplainParams{
+		ErrorMessage: "502 Bad Gateway from BlackHoleCluster",
+		Namespace:    "team-alpha",
+	})
+
+	assert.Equal(t, "medium", result.Confidence)
+	assert.Contains(t, result.Explanation, "REGISTRY_ONLY")
+}
+
 func TestHandlers_Check_WithBlockingConstraint(t *testing.T) {
 	server, idx := setupTestServer()
 
@@ -1203,6 +1348,59 @@ func TestServer_HandleTool_Middleware(t *testing.T) {
 	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
 }
 
+// otherShard owns every namespace but "local".
+type otherShard struct{}
+
+func (otherShard) Self() shard.Member { return shard.Member{Identity: "a", Host: "127.0.0.1"} }
+
+func (otherShard) Owner(namespace string) (shard.Member, bool) {
+	if namespace == "local" {
+		return shard.Member{Identity: "a", Host: "127.0.0.1"}, true
+	}
+	return shard.Member{Identity: "b", Host: "127.0.0.1"}, true
+}
+
+func (otherShard) Members() []shard.Member { return nil }
+
+func TestServer_HandleTool_ForwardsToOwningShard(t *testing.T) {
+	var forwardedBody string
+	remote := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		forwardedBody = string(body)
+		assert.Equal(t, "a", r.Header.Get(shard.ForwardedHeader))
+		assert.Equal(t, "/tools/nightjar_query", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"namespace":"team-b","total":1}`))
+	}))
+	defer remote.Close()
+	u, err := url.Parse(remote.URL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(u.Port())
+	require.NoError(t, err)
+
+	opts := DefaultServerOptions()
+	opts.Shards = shard.NewForwarder(otherShard{}, port, zap.NewNop())
+	server := NewServer(indexer.New(nil), opts)
+	var localBody string
+	handler := server.handleTool(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		localBody = string(body)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodPost, "/tools/nightjar_query", bytes.NewBufferString(`{"namespace":"team-b"}`)))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"namespace":"team-b","total":1}`, w.Body.String())
+	assert.Equal(t, `{"namespace":"team-b"}`, forwardedBody)
+	assert.Empty(t, localBody)
+
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodPost, "/tools/nightjar_query", bytes.NewBufferString(`{"namespace":"local"}`)))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"namespace":"local"}`, localBody, "the body is still readable when served locally")
+}
+
 func TestServer_HandleResource_Middleware(t *testing.T) {
 	server, _ := setupTestServer()
 
@@ -1429,3 +1627,35 @@ func TestToConstraintResult_PrivacyScoping(t *testing.T) {
 	assert.Equal(t, "secret-policy", result.Name)
 	assert.Equal(t, "kube-system", result.Namespace)
 }
+
+func TestHandlers_Scope(t *testing.T) {
+	server, _ := setupTestServer()
+	nsScope := scope.New(k8sfake.NewSimpleClientset(), zap.NewNop(), scope.Options{})
+	require.NoError(t, nsScope.Update(scope.Config{ExcludeNamespaces: []string{"team-beta"}}))
+	server.handlers.nsScope = nsScope
+
+	body, _ := json.Marshal(QueryParams{Namespace: "team-beta"})
+	w := httptest.NewRecorder()
+	server.handlers.HandleQuery(w, httptest.NewRequest(http.MethodPost, "/tools/nightjar_query", bytes.NewReader(body)))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "outside the Nightjar namespace scope")
+
+	w = httptest.NewRecorder()
+	server.handlers.HandleReportResource(w, httptest.NewRequest(http.MethodGet, "/resources/reports/team-beta", nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	body, _ = json.Marshal(QueryParams{Namespace: "team-alpha"})
+	w = httptest.NewRecorder()
+	server.handlers.HandleQuery(w, httptest.NewRequest(http.MethodPost, "/tools/nightjar_query", bytes.NewReader(body)))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	server.handlers.HandleListNamespaces(w, httptest.NewRequest(http.MethodPost, "/tools/nightjar_list_namespaces", nil))
+	var summaries []NamespaceSummary
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&summaries))
+	var names []string
+	for _, s := range summaries {
+		names = append(names, s.Namespace)
+	}
+	assert.Equal(t, []string{"team-alpha"}, names)
+}
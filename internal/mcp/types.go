@@ -7,6 +7,7 @@ import (
 
 	"k8s.io/apimachinery/pkg/runtime/schema"
 
+	"github.com/nightjarctl/nightjar/internal/flowstats"
 	"github.com/nightjarctl/nightjar/internal/types"
 )
 
@@ -25,6 +26,9 @@ type QueryResult struct {
 	Namespace   string             `json:"namespace"`
 	Constraints []ConstraintResult `json:"constraints"`
 	Total       int                `json:"total"`
+	// FlowDrops are the busiest dropped traffic paths to or from the
+	// namespace, when Hubble flow observation is enabled.
+	FlowDrops []FlowDropResult `json:"flow_drops,omitempty"`
 }
 
 type ConstraintResult struct {
@@ -43,6 +47,42 @@ type ConstraintResult struct {
 	LastObserved      string                 `json:"last_observed"`
 }
 
+// FlowDropResult aggregates the dropped flows of one traffic path.
+type FlowDropResult struct {
+	Source          FlowEndpoint `json:"source"`
+	Destination     FlowEndpoint `json:"destination"`
+	Port            uint32       `json:"port"`
+	Protocol        string       `json:"protocol,omitempty"`
+	Direction       string       `json:"direction,omitempty"`
+	PolicyName      string       `json:"policy_name,omitempty"`
+	PolicyNamespace string       `json:"policy_namespace,omitempty"`
+	PolicyKind      string       `json:"policy_kind,omitempty"`
+	Count           uint64       `json:"count"`
+	RecentCount     uint64       `json:"recent_count"`
+	Window          string       `json:"window"`
+	FirstSeen       string       `json:"first_seen"`
+	LastSeen        string       `json:"last_seen"`
+	Samples         []FlowSample `json:"samples,omitempty"`
+	DetailLevel     string       `json:"detail_level"`
+}
+
+type FlowEndpoint struct {
+	Namespace string `json:"namespace,omitempty"`
+	Workload  string `json:"workload,omitempty"`
+	Service   string `json:"service,omitempty"`
+}
+
+type FlowSample struct {
+	Time            string `json:"time"`
+	TraceID         string `json:"trace_id,omitempty"`
+	SourcePod       string `json:"source_pod,omitempty"`
+	SourceIP        string `json:"source_ip,omitempty"`
+	SourcePort      uint32 `json:"source_port,omitempty"`
+	DestinationPod  string `json:"destination_pod,omitempty"`
+	DestinationIP   string `json:"destination_ip,omitempty"`
+	DestinationPort uint32 `json:"destination_port,omitempty"`
+}
+
 // --- Tool: nightjar_explain ---
 
 type ExplainParams struct {
@@ -193,6 +233,44 @@ func ToConstraintResult(c types.Constraint, detailLevel types.DetailLevel, viewe
 	return result
 }
 
+// ToFlowDropResult converts aggregated flow drop statistics to an MCP-friendly
+// result. Applies privacy scoping based on detailLevel; window is the span of
+// the entry's RecentCount.
+func ToFlowDropResult(e flowstats.Entry, detailLevel types.DetailLevel, viewerNamespace string, window time.Duration) FlowDropResult {
+	e = e.Scoped(viewerNamespace, detailLevel)
+	result := FlowDropResult{
+		Source:      FlowEndpoint(e.Source),
+		Destination: FlowEndpoint(e.Destination),
+		Port:        e.Port,
+		Protocol:    e.Protocol,
+		Direction:   string(e.Direction),
+		Count:       e.Count,
+		RecentCount: e.RecentCount,
+		Window:      window.String(),
+		FirstSeen:   e.FirstSeen.UTC().Format(time.RFC3339),
+		LastSeen:    e.LastSeen.UTC().Format(time.RFC3339),
+		DetailLevel: string(detailLevel),
+	}
+	if e.Policy.Name != "" {
+		result.PolicyName = e.Policy.Name
+		result.PolicyNamespace = e.Policy.Namespace
+		result.PolicyKind = gvrToKind(e.Policy.Source)
+	}
+	for _, sample := range e.Samples {
+		result.Samples = append(result.Samples, FlowSample{
+			Time:            sample.Time.UTC().Format(time.RFC3339Nano),
+			TraceID:         sample.TraceID,
+			SourcePod:       sample.SourcePod,
+			SourceIP:        sample.SourceIP,
+			SourcePort:      sample.SourcePort,
+			DestinationPod:  sample.DestinationPod,
+			DestinationIP:   sample.DestinationIP,
+			DestinationPort: sample.DestinationPort,
+		})
+	}
+	return result
+}
+
 // ToConstraintResultWithRemediation includes remediation info in the result.
 func ToConstraintResultWithRemediation(c types.Constraint, detailLevel types.DetailLevel, viewerNamespace string, remediationBuilder RemediationBuilder) ConstraintResult {
 	result := ToConstraintResult(c, detailLevel, viewerNamespace)
@@ -3,6 +3,7 @@ package notifier
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -12,6 +13,7 @@ import (
 	"k8s.io/client-go/kubernetes"
 
 	"github.com/nightjarctl/nightjar/internal/correlator"
+	"github.com/nightjarctl/nightjar/internal/dedupe"
 	"github.com/nightjarctl/nightjar/internal/types"
 )
 
@@ -37,6 +39,17 @@ type dedupeKey struct {
 	workloadUID   string
 }
 
+// String encodes the key for a dedupe journal as constraintUID|workloadUID.
+func (k dedupeKey) String() string {
+	return k.constraintUID + "|" + k.workloadUID
+}
+
+// parseDedupeKey decodes a key encoded by String.
+func parseDedupeKey(s string) (dedupeKey, bool) {
+	constraintUID, workloadUID, ok := strings.Cut(s, "|")
+	return dedupeKey{constraintUID: constraintUID, workloadUID: workloadUID}, ok
+}
+
 // nsRateLimiter tracks rate limits per namespace.
 type nsRateLimiter struct {
 	mu         sync.Mutex
@@ -47,11 +60,24 @@ type nsRateLimiter struct {
 }
 
 func newNsRateLimiter(perMinute int) *nsRateLimiter {
-	return &nsRateLimiter{
+	n := &nsRateLimiter{
 		limiters:   make(map[string]*rate.Limiter),
 		lastAccess: make(map[string]time.Time),
-		rate:       rate.Limit(float64(perMinute) / 60.0),
-		burst:      max(1, perMinute/10), // 10% burst, minimum 1
+	}
+	n.SetRate(perMinute)
+	return n
+}
+
+// SetRate changes the limit of every namespace, including namespaces that
+// already have a limiter.
+func (n *nsRateLimiter) SetRate(perMinute int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.rate = rate.Limit(float64(perMinute) / 60.0)
+	n.burst = max(1, perMinute/10) // 10% burst, minimum 1
+	for _, limiter := range n.limiters {
+		limiter.SetLimit(n.rate)
+		limiter.SetBurst(n.burst)
 	}
 }
 
@@ -88,7 +114,8 @@ type Dispatcher struct {
 	nsLimiter    *nsRateLimiter
 	eventBuilder *EventBuilder
 	dedupeCache  map[dedupeKey]time.Time
-	mu           sync.Mutex
+	journal      *dedupe.Journal
+	mu           sync.Mutex // guards opts, eventBuilder and dedupeCache
 }
 
 // NewDispatcher creates a new Dispatcher.
@@ -103,9 +130,74 @@ func NewDispatcher(client kubernetes.Interface, logger *zap.Logger, opts Dispatc
 	}
 }
 
-// Start begins the background cleanup routine. Non-blocking.
+// SetOptions replaces the dispatcher's options. Notifications dispatched
+// afterwards use them; pairs already notified stay suppressed for the new
+// SuppressDuplicateMinutes.
+func (d *Dispatcher) SetOptions(opts DispatcherOptions) {
+	d.nsLimiter.SetRate(opts.RateLimitPerMinute)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.opts = opts
+	d.eventBuilder = NewEventBuilder(opts.RemediationContact)
+}
+
+// settings returns the current options and event builder.
+func (d *Dispatcher) settings() (DispatcherOptions, *EventBuilder) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.opts, d.eventBuilder
+}
+
+// SetDedupeStore persists deduplication state to store: Start restores it,
+// and it is synced periodically and on shutdown, so a restart or a new
+// leader does not notify recently notified pairs again. Call it before
+// Start.
+func (d *Dispatcher) SetDedupeStore(store dedupe.Store, opts dedupe.Options) {
+	d.journal = dedupe.NewJournal(store, "dispatcher", d.logger, opts)
+}
+
+// Start restores persisted dedupe state, if any, and begins the background
+// cleanup and sync routines. It returns once the state is restored.
 func (d *Dispatcher) Start(ctx context.Context) {
+	d.restoreDedupe(ctx)
 	go d.cleanupDedupeCache(ctx)
+	go d.journal.Run(ctx, d.dedupeWindow, d.mergeSeen)
+}
+
+// dedupeWindow returns the current duplicate suppression window.
+func (d *Dispatcher) dedupeWindow() time.Duration {
+	opts, _ := d.settings()
+	return time.Duration(opts.SuppressDuplicateMinutes) * time.Minute
+}
+
+// restoreDedupe merges the pairs stored by the dedupe journal into the
+// dedupe cache. A failed restore is logged and the cache starts empty.
+func (d *Dispatcher) restoreDedupe(ctx context.Context) {
+	if d.journal == nil {
+		return
+	}
+	restoreCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	stored, err := d.journal.Sync(restoreCtx, d.dedupeWindow())
+	if err != nil {
+		d.logger.Warn("Failed to restore dedupe state, duplicates may be notified", zap.Error(err))
+		return
+	}
+	d.mergeSeen(stored)
+	d.logger.Info("Restored dedupe state", zap.Int("pairs", len(stored)))
+}
+
+// mergeSeen adds stored pairs to the dedupe cache, keeping the later time
+// of a pair in both.
+func (d *Dispatcher) mergeSeen(stored dedupe.Entries) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for s, seenAt := range stored {
+		key, ok := parseDedupeKey(s)
+		if ok && seenAt.After(d.dedupeCache[key]) {
+			d.dedupeCache[key] = seenAt
+		}
+	}
 }
 
 // Dispatch processes a correlated notification and sends it via enabled channels.
@@ -118,10 +210,12 @@ func (d *Dispatcher) Dispatch(ctx context.Context, n correlator.CorrelatedNotifi
 		return nil
 	}
 
-	// Dedupe check (atomic check-and-mark to avoid TOCTOU race)
+	// Dedupe check (atomic check-and-mark to avoid TOCTOU race). The
+	// correlator resolves the workload to the top-level owner, so the Pods
+	// of one Deployment share a key.
 	key := dedupeKey{
 		constraintUID: string(n.Constraint.UID),
-		workloadUID:   fmt.Sprintf("%s/%s", ns, n.WorkloadName),
+		workloadUID:   fmt.Sprintf("%s/%s/%s", ns, n.WorkloadKind, n.WorkloadName),
 	}
 	if !d.tryMarkSeen(key) {
 		return nil
@@ -129,6 +223,11 @@ func (d *Dispatcher) Dispatch(ctx context.Context, n correlator.CorrelatedNotifi
 
 	// Render message at summary level (per PRIVACY_MODEL.md: developer-facing events use summary)
 	message := d.RenderMessage(n.Constraint, types.DetailLevelSummary)
+	if n.Coalesced > 0 {
+		// The correlator's queue was full and folded further notifications
+		// of the constraint in this namespace into this one.
+		message += fmt.Sprintf(" (and %d more in this namespace)", n.Coalesced)
+	}
 
 	// Create K8s Event
 	if err := d.createEvent(ctx, n, message); err != nil {
@@ -153,7 +252,7 @@ func (d *Dispatcher) DispatchDirect(ctx context.Context, c types.Constraint, ns,
 
 	key := dedupeKey{
 		constraintUID: string(c.UID),
-		workloadUID:   fmt.Sprintf("%s/%s", ns, workloadName),
+		workloadUID:   fmt.Sprintf("%s/%s/%s", ns, workloadKind, workloadName),
 	}
 	if !d.tryMarkSeen(key) {
 		return nil
@@ -167,7 +266,8 @@ func (d *Dispatcher) DispatchDirect(ctx context.Context, c types.Constraint, ns,
 		Namespace: ns,
 	}
 
-	event := d.eventBuilder.BuildEvent(c, level, workload, message)
+	_, eventBuilder := d.settings()
+	event := eventBuilder.BuildEvent(c, level, workload, message)
 
 	_, err := d.client.CoreV1().Events(ns).Create(ctx, event, metav1.CreateOptions{})
 	if err != nil {
@@ -192,8 +292,9 @@ func (d *Dispatcher) RenderMessage(c types.Constraint, level types.DetailLevel)
 // renderSummary creates a developer-safe notification without cross-namespace details.
 func (d *Dispatcher) renderSummary(c types.Constraint) string {
 	effect := genericEffect(c.ConstraintType)
+	opts, _ := d.settings()
 	return fmt.Sprintf("⚠️ %s constraint is affecting your workload. %s. Contact %s for assistance.",
-		c.ConstraintType, effect, d.opts.RemediationContact)
+		c.ConstraintType, effect, opts.RemediationContact)
 }
 
 // renderDetailed includes constraint name and specific ports (same namespace only).
@@ -205,7 +306,8 @@ func (d *Dispatcher) renderDetailed(c types.Constraint) string {
 
 	hint := c.RemediationHint
 	if hint == "" {
-		hint = fmt.Sprintf("Contact %s for assistance.", d.opts.RemediationContact)
+		opts, _ := d.settings()
+		hint = fmt.Sprintf("Contact %s for assistance.", opts.RemediationContact)
 	}
 
 	return fmt.Sprintf("⚠️ %s constraint %q: %s. %s",
@@ -252,12 +354,15 @@ func genericEffect(ct types.ConstraintType) string {
 // to populate structured annotations for agent consumption.
 func (d *Dispatcher) createEvent(ctx context.Context, n correlator.CorrelatedNotification, message string) error {
 	workload := WorkloadRef{
-		Kind:      n.WorkloadKind,
-		Name:      n.WorkloadName,
-		Namespace: n.Namespace,
+		APIVersion: n.WorkloadAPIVersion,
+		Kind:       n.WorkloadKind,
+		Name:       n.WorkloadName,
+		Namespace:  n.Namespace,
+		UID:        n.WorkloadUID,
 	}
 
-	event := d.eventBuilder.BuildEvent(n.Constraint, types.DetailLevelSummary, workload, message)
+	_, eventBuilder := d.settings()
+	event := eventBuilder.BuildEvent(n.Constraint, types.DetailLevelSummary, workload, message)
 
 	_, err := d.client.CoreV1().Events(n.Namespace).Create(ctx, event, metav1.CreateOptions{})
 	return err
@@ -275,7 +380,9 @@ func (d *Dispatcher) tryMarkSeen(key dedupeKey) bool {
 			return false
 		}
 	}
-	d.dedupeCache[key] = time.Now()
+	now := time.Now()
+	d.dedupeCache[key] = now
+	d.journal.Record(key.String(), now)
 	return true
 }
 
@@ -16,6 +16,7 @@ import (
 	"k8s.io/client-go/kubernetes/fake"
 
 	"github.com/nightjarctl/nightjar/internal/correlator"
+	"github.com/nightjarctl/nightjar/internal/dedupe"
 	"github.com/nightjarctl/nightjar/internal/types"
 )
 
@@ -194,9 +195,11 @@ func TestDispatch_CreateEvent(t *testing.T) {
 			ConstraintType: types.ConstraintTypeNetworkEgress,
 			Severity:       types.SeverityWarning,
 		},
-		Namespace:    "team-alpha",
-		WorkloadName: "my-deployment",
-		WorkloadKind: "Deployment",
+		Namespace:          "team-alpha",
+		WorkloadName:       "my-deployment",
+		WorkloadKind:       "Deployment",
+		WorkloadAPIVersion: "apps/v1",
+		WorkloadUID:        "deployment-uid",
 	}
 
 	err := d.Dispatch(ctx, notification)
@@ -213,6 +216,31 @@ func TestDispatch_CreateEvent(t *testing.T) {
 	assert.Equal(t, "my-deployment", event.InvolvedObject.Name)
 	assert.Equal(t, "Deployment", event.InvolvedObject.Kind)
 	assert.Equal(t, "team-alpha", event.InvolvedObject.Namespace)
+	assert.Equal(t, "apps/v1", event.InvolvedObject.APIVersion)
+	assert.Equal(t, k8stypes.UID("deployment-uid"), event.InvolvedObject.UID)
+}
+
+func TestDispatch_CoalescedSummary(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	d := NewDispatcher(client, zap.NewNop(), DefaultDispatcherOptions())
+	ctx := context.Background()
+
+	require.NoError(t, d.Dispatch(ctx, correlator.CorrelatedNotification{
+		Constraint: types.Constraint{
+			UID:            k8stypes.UID("test-uid"),
+			Name:           "test-constraint",
+			ConstraintType: types.ConstraintTypeNetworkEgress,
+		},
+		Namespace:    "team-alpha",
+		WorkloadName: "my-deployment",
+		WorkloadKind: "Deployment",
+		Coalesced:    312,
+	}))
+
+	events, err := client.CoreV1().Events("team-alpha").List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, events.Items, 1)
+	assert.Contains(t, events.Items[0].Message, "(and 312 more in this namespace)")
 }
 
 func TestDispatch_Deduplication(t *testing.T) {
@@ -316,6 +344,25 @@ func TestNewNsRateLimiter_MinBurst(t *testing.T) {
 	assert.True(t, limiter.Allow("test-ns"), "first call should pass with burst=1")
 }
 
+func TestDispatcher_SetOptions(t *testing.T) {
+	d := NewDispatcher(fake.NewSimpleClientset(), zap.NewNop(), DefaultDispatcherOptions())
+	require.True(t, d.nsLimiter.Allow("team-a"))
+
+	d.SetOptions(DispatcherOptions{
+		SuppressDuplicateMinutes: 5,
+		RateLimitPerMinute:       600,
+		RemediationContact:       "#platform-oncall",
+	})
+
+	assert.Contains(t, d.RenderMessage(types.Constraint{ConstraintType: types.ConstraintTypeAdmission}, types.DetailLevelSummary), "#platform-oncall")
+	assert.Equal(t, 60, d.nsLimiter.burst)
+	assert.Equal(t, 60, d.nsLimiter.limiters["team-a"].Burst(), "existing namespace limiters follow the new rate")
+
+	key := dedupeKey{constraintUID: "c", workloadUID: "w"}
+	d.dedupeCache[key] = time.Now().Add(-10 * time.Minute)
+	assert.True(t, d.tryMarkSeen(key), "suppression window shrank to 5 minutes")
+}
+
 func TestGenericEffect(t *testing.T) {
 	tests := []struct {
 		ct   types.ConstraintType
@@ -616,3 +663,64 @@ func TestDispatchDirect_FullLevel(t *testing.T) {
 	assert.Contains(t, events.Items[0].Message, "networking.k8s.io/v1/networkpolicies")
 	assert.Contains(t, events.Items[0].Message, "kube-system/full-constraint")
 }
+
+func TestDispatcher_DedupeStoreSurvivesLeaderChange(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	store := dedupe.NewConfigMapStore(client, "nightjar-system", "nightjar-dedupe")
+	n := correlator.CorrelatedNotification{
+		Constraint: types.Constraint{
+			UID:            k8stypes.UID("c-1"),
+			Name:           "deny-egress",
+			ConstraintType: types.ConstraintTypeNetworkEgress,
+		},
+		Namespace:    "shop",
+		WorkloadName: "api",
+		WorkloadKind: "Deployment",
+	}
+
+	// The first leader notifies and saves its dedupe state on shutdown.
+	leader := NewDispatcher(client, zap.NewNop(), DefaultDispatcherOptions())
+	leader.SetDedupeStore(store, dedupe.Options{})
+	ctx, cancel := context.WithCancel(context.Background())
+	leader.Start(ctx)
+	require.NoError(t, leader.Dispatch(ctx, n))
+	cancel()
+
+	require.Eventually(t, func() bool {
+		next := NewDispatcher(client, zap.NewNop(), DefaultDispatcherOptions())
+		next.SetDedupeStore(store, dedupe.Options{})
+		next.restoreDedupe(context.Background())
+		next.mu.Lock()
+		defer next.mu.Unlock()
+		_, restored := next.dedupeCache[dedupeKey{constraintUID: "c-1", workloadUID: "shop/Deployment/api"}]
+		return restored
+	}, 2*time.Second, 10*time.Millisecond, "dedupe state was not saved on shutdown")
+
+	// The next leader restores it and suppresses the duplicate.
+	next := NewDispatcher(client, zap.NewNop(), DefaultDispatcherOptions())
+	next.SetDedupeStore(store, dedupe.Options{})
+	next.Start(context.Background())
+	require.NoError(t, next.Dispatch(context.Background(), n))
+
+	events, err := client.CoreV1().Events("shop").List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	assert.Len(t, events.Items, 1, "the new leader must not notify the pair again")
+}
+
+func TestDispatcher_DedupeStoreHonorsWindow(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	store := dedupe.NewConfigMapStore(client, "nightjar-system", "nightjar-dedupe")
+	key := dedupeKey{constraintUID: "c-1", workloadUID: "shop/Deployment/api"}
+
+	old := NewDispatcher(client, zap.NewNop(), DefaultDispatcherOptions())
+	old.SetDedupeStore(store, dedupe.Options{})
+	old.journal.Record(key.String(), time.Now().Add(-2*time.Hour))
+	_, err := old.journal.Sync(context.Background(), 3*time.Hour)
+	require.NoError(t, err)
+
+	// Stored 2h ago: outside the default 60 minute window.
+	next := NewDispatcher(client, zap.NewNop(), DefaultDispatcherOptions())
+	next.SetDedupeStore(store, dedupe.Options{})
+	next.restoreDedupe(context.Background())
+	assert.True(t, next.tryMarkSeen(key), "an expired pair is notified again")
+}
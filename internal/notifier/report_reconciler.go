@@ -15,8 +15,10 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/nightjarctl/nightjar/api/v1alpha1"
+	"github.com/nightjarctl/nightjar/internal/flowstats"
 	"github.com/nightjarctl/nightjar/internal/indexer"
 	"github.com/nightjarctl/nightjar/internal/requirements"
+	"github.com/nightjarctl/nightjar/internal/scope"
 	"github.com/nightjarctl/nightjar/internal/types"
 )
 
@@ -32,6 +34,14 @@ type ReportReconcilerOptions struct {
 
 	// DefaultContact is shown in remediation steps.
 	DefaultContact string
+
+	// Scope limits reports to namespaces in scope; the reports of namespaces
+	// that leave it are deleted. Nil reports on every namespace.
+	Scope *scope.Scope
+
+	// FlowStats, if set, lists the namespace's busiest flow drop paths in
+	// each report, and reconciles reports whose drop paths changed.
+	FlowStats *flowstats.Store
 }
 
 // DefaultReportReconcilerOptions returns sensible defaults.
@@ -52,10 +62,12 @@ type ReportReconciler struct {
 	evaluator          *requirements.Evaluator
 	dynamicClient      dynamic.Interface
 	opts               ReportReconcilerOptions
+	optsMu             sync.RWMutex // guards remediationBuilder and opts other than Scope and FlowStats
 
 	mu                   sync.Mutex
 	lastReconcile        map[string]time.Time
 	pendingTriggers      map[string]bool
+	pendingDeletes       map[string]bool
 	clusterWideTriggered bool
 }
 
@@ -76,7 +88,7 @@ func NewReportReconciler(
 		opts.DefaultDetailLevel = types.DetailLevelSummary
 	}
 
-	return &ReportReconciler{
+	rr := &ReportReconciler{
 		logger:             logger.Named("report-reconciler"),
 		client:             k8sClient,
 		idx:                idx,
@@ -86,31 +98,73 @@ func NewReportReconciler(
 		opts:               opts,
 		lastReconcile:      make(map[string]time.Time),
 		pendingTriggers:    make(map[string]bool),
+		pendingDeletes:     make(map[string]bool),
 	}
+	opts.Scope.OnChange(rr.onScopeChange)
+	return rr
+}
+
+// SetOptions applies the DebounceDuration, DefaultDetailLevel and
+// DefaultContact of opts to later reconciles. Scope and FlowStats are fixed
+// when the reconciler is created.
+func (rr *ReportReconciler) SetOptions(opts ReportReconcilerOptions) {
+	defaults := DefaultReportReconcilerOptions()
+	if opts.DebounceDuration == 0 {
+		opts.DebounceDuration = defaults.DebounceDuration
+	}
+	if opts.DefaultDetailLevel == "" {
+		opts.DefaultDetailLevel = defaults.DefaultDetailLevel
+	}
+	rr.optsMu.Lock()
+	defer rr.optsMu.Unlock()
+	rr.opts.DebounceDuration = opts.DebounceDuration
+	rr.opts.DefaultDetailLevel = opts.DefaultDetailLevel
+	rr.opts.DefaultContact = opts.DefaultContact
+	rr.remediationBuilder = NewRemediationBuilder(opts.DefaultContact)
+}
+
+// settings returns the current options and remediation builder.
+func (rr *ReportReconciler) settings() (ReportReconcilerOptions, *RemediationBuilder) {
+	rr.optsMu.RLock()
+	defer rr.optsMu.RUnlock()
+	return rr.opts, rr.remediationBuilder
 }
 
 // Start begins the reconciliation loop. Blocks until context is cancelled.
 func (rr *ReportReconciler) Start(ctx context.Context) error {
+	opts, _ := rr.settings()
 	rr.logger.Info("Starting report reconciler",
-		zap.Duration("debounce", rr.opts.DebounceDuration))
+		zap.Duration("debounce", opts.DebounceDuration))
 
-	// Tick to process pending triggers
-	ticker := time.NewTicker(rr.opts.DebounceDuration / 2)
-	defer ticker.Stop()
+	// Process pending triggers every half debounce. The interval follows
+	// SetOptions.
+	timer := time.NewTimer(opts.DebounceDuration / 2)
+	defer timer.Stop()
 
 	for {
 		select {
 		case <-ctx.Done():
 			rr.logger.Info("Report reconciler stopped")
 			return nil
-		case <-ticker.C:
+		case <-timer.C:
+			rr.queueFlowStatsChanges()
 			rr.processPendingTriggers(ctx)
+			opts, _ := rr.settings()
+			timer.Reset(opts.DebounceDuration / 2)
 		}
 	}
 }
 
-// OnIndexChange is the callback for indexer.OnChangeFunc.
+// OnIndexChange handles an event from an indexer subscription.
 func (rr *ReportReconciler) OnIndexChange(event indexer.IndexEvent) {
+	// Missed events could have touched any namespace.
+	if event.Type == indexer.EventResync {
+		rr.mu.Lock()
+		rr.clusterWideTriggered = true
+		rr.mu.Unlock()
+		return
+	}
+
 	c := event.Constraint
 
 	// Trigger reconcile for all affected namespaces
@@ -130,20 +184,71 @@ func (rr *ReportReconciler) OnIndexChange(event indexer.IndexEvent) {
 
 	rr.mu.Lock()
 	for _, ns := range namespaces {
-		rr.pendingTriggers[ns] = true
+		if rr.opts.Scope.Allows(ns) {
+			rr.pendingTriggers[ns] = true
+		}
 	}
 	rr.mu.Unlock()
 }
 
+// queueFlowStatsChanges queues reports for the namespaces whose flow drop
+// statistics changed. The debounce bounds how often busy paths rewrite them.
+func (rr *ReportReconciler) queueFlowStatsChanges() {
+	if rr.opts.FlowStats == nil {
+		return
+	}
+	changed := rr.opts.FlowStats.ChangedNamespaces()
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	for _, ns := range changed {
+		if rr.opts.Scope.Allows(ns) {
+			rr.pendingTriggers[ns] = true
+		}
+	}
+}
+
+// onScopeChange queues reports for namespaces that entered the scope and
+// report deletion for namespaces that left it. Reports of namespaces handed
+// off to another shard are kept; the new owner reconciles them.
+func (rr *ReportReconciler) onScopeChange(change scope.Change) {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	for _, ns := range change.Added {
+		delete(rr.pendingDeletes, ns)
+		delete(rr.lastReconcile, ns)
+		rr.pendingTriggers[ns] = true
+	}
+	for _, ns := range change.Removed {
+		delete(rr.pendingTriggers, ns)
+		delete(rr.lastReconcile, ns)
+		if !change.Handoff {
+			rr.pendingDeletes[ns] = true
+		}
+	}
+}
+
 // processPendingTriggers reconciles reports for pending namespaces.
 func (rr *ReportReconciler) processPendingTriggers(ctx context.Context) {
 	rr.mu.Lock()
 	triggers := rr.pendingTriggers
+	deletes := rr.pendingDeletes
 	clusterWide := rr.clusterWideTriggered
 	rr.pendingTriggers = make(map[string]bool)
+	rr.pendingDeletes = make(map[string]bool)
 	rr.clusterWideTriggered = false
 	rr.mu.Unlock()
 
+	for ns := range deletes {
+		if err := rr.deleteReport(ctx, ns); err != nil {
+			rr.logger.Error("Failed to delete report of out-of-scope namespace",
+				zap.String("namespace", ns),
+				zap.Error(err))
+			rr.mu.Lock()
+			rr.pendingDeletes[ns] = true
+			rr.mu.Unlock()
+		}
+	}
+
 	// Cluster-wide trigger: list all namespaces and add them as triggers.
 	if clusterWide && rr.dynamicClient != nil {
 		nsGVR := schema.GroupVersionResource{Group: "", Version: "v1", Resource: "namespaces"}
@@ -152,18 +257,25 @@ func (rr *ReportReconciler) processPendingTriggers(ctx context.Context) {
 			rr.logger.Error("Failed to list namespaces for cluster-wide reconcile", zap.Error(err))
 		} else {
 			for _, item := range list.Items {
-				triggers[item.GetName()] = true
+				if rr.opts.Scope.Allows(item.GetName()) {
+					triggers[item.GetName()] = true
+				}
 			}
 		}
 	}
 
 	for ns := range triggers {
+		// The scope may have changed since the trigger was queued
+		if !rr.opts.Scope.Allows(ns) {
+			continue
+		}
+
 		// Check debounce
 		rr.mu.Lock()
 		lastReconcile := rr.lastReconcile[ns]
 		rr.mu.Unlock()
 
-		if time.Since(lastReconcile) < rr.opts.DebounceDuration {
+		if opts, _ := rr.settings(); time.Since(lastReconcile) < opts.DebounceDuration {
 			// Re-queue for later
 			rr.mu.Lock()
 			rr.pendingTriggers[ns] = true
@@ -183,13 +295,30 @@ func (rr *ReportReconciler) processPendingTriggers(ctx context.Context) {
 	}
 }
 
+// reportName is the name of the ConstraintReport in each namespace.
+const reportName = "constraints"
+
+// deleteReport deletes the ConstraintReport of a namespace, if any.
+func (rr *ReportReconciler) deleteReport(ctx context.Context, namespace string) error {
+	report := &v1alpha1.ConstraintReport{
+		ObjectMeta: metav1.ObjectMeta{Name: reportName, Namespace: namespace},
+	}
+	if err := rr.client.Delete(ctx, report); client.IgnoreNotFound(err) != nil {
+		return err
+	}
+	rr.mu.Lock()
+	delete(rr.lastReconcile, namespace)
+	rr.mu.Unlock()
+	rr.logger.Info("Deleted ConstraintReport of out-of-scope namespace", zap.String("namespace", namespace))
+	return nil
+}
+
 // ReconcileNamespace updates or creates the ConstraintReport for a namespace.
 func (rr *ReportReconciler) ReconcileNamespace(ctx context.Context, namespace string) error {
 	constraints := rr.idx.ByNamespace(namespace)
 
-	// Get or create the ConstraintReport
+	// Get or create the ConstraintReport (one report per namespace)
 	report := &v1alpha1.ConstraintReport{}
-	reportName := "constraints" // One report per namespace
 
 	err := rr.client.Get(ctx, client.ObjectKey{Namespace: namespace, Name: reportName}, report)
 	if err != nil {
@@ -293,21 +422,85 @@ func (rr *ReportReconciler) buildReportStatus(constraints []types.Constraint, na
 	missingResources := rr.evaluateMissingResources(namespace)
 
 	// Build machine-readable section
+	opts, _ := rr.settings()
 	status.MachineReadable = &v1alpha1.MachineReadableReport{
 		SchemaVersion:    "1",
 		GeneratedAt:      now,
-		DetailLevel:      string(rr.opts.DefaultDetailLevel),
+		DetailLevel:      string(opts.DefaultDetailLevel),
 		Constraints:      machineEntries,
 		Tags:             allTags,
 		MissingResources: missingResources,
 	}
 
+	status.FlowDrops = rr.buildFlowDrops(namespace, opts.DefaultDetailLevel)
+
 	return status
 }
 
+// maxFlowDropsPerReport bounds the flow drop paths listed in a report.
+const maxFlowDropsPerReport = 20
+
+// buildFlowDrops lists the busiest flow drop paths to or from namespace,
+// scoped to the detail level.
+func (rr *ReportReconciler) buildFlowDrops(namespace string, level types.DetailLevel) []v1alpha1.FlowDropEntry {
+	if rr.opts.FlowStats == nil {
+		return nil
+	}
+	entries := rr.opts.FlowStats.ForNamespace(namespace)
+	if len(entries) > maxFlowDropsPerReport {
+		entries = entries[:maxFlowDropsPerReport]
+	}
+	window := rr.opts.FlowStats.Window()
+
+	var result []v1alpha1.FlowDropEntry
+	for _, e := range entries {
+		result = append(result, flowDropEntry(e.Scoped(namespace, level), window))
+	}
+	return result
+}
+
+// flowDropEntry converts aggregated flow drop statistics to their
+// ConstraintReport form. window is the span of the entry's RecentCount.
+func flowDropEntry(e flowstats.Entry, window time.Duration) v1alpha1.FlowDropEntry {
+	entry := v1alpha1.FlowDropEntry{
+		Source:      v1alpha1.FlowEndpoint(e.Source),
+		Destination: v1alpha1.FlowEndpoint(e.Destination),
+		Port:        int32(e.Port),
+		Protocol:    e.Protocol,
+		Direction:   string(e.Direction),
+		Count:       int64(e.Count),
+		RecentCount: int64(e.RecentCount),
+		Window:      window.String(),
+		FirstSeen:   metav1.NewTime(e.FirstSeen),
+		LastSeen:    metav1.NewTime(e.LastSeen),
+	}
+	if e.Policy.Name != "" {
+		entry.PolicyRef = &v1alpha1.ObjectReference{
+			APIVersion: gvrToAPIVersion(e.Policy.Source),
+			Kind:       gvrToKindName(e.Policy.Source),
+			Name:       e.Policy.Name,
+			Namespace:  e.Policy.Namespace,
+		}
+	}
+	for _, sample := range e.Samples {
+		entry.Samples = append(entry.Samples, v1alpha1.FlowSample{
+			Time:            metav1.NewTime(sample.Time),
+			TraceID:         sample.TraceID,
+			SourcePod:       sample.SourcePod,
+			SourceIP:        sample.SourceIP,
+			SourcePort:      int32(sample.SourcePort),
+			DestinationPod:  sample.DestinationPod,
+			DestinationIP:   sample.DestinationIP,
+			DestinationPort: int32(sample.DestinationPort),
+		})
+	}
+	return entry
+}
+
 // buildMachineEntry builds a MachineConstraintEntry from a Constraint.
 func (rr *ReportReconciler) buildMachineEntry(c types.Constraint, viewerNamespace string) v1alpha1.MachineConstraintEntry {
-	remediation := rr.remediationBuilder.Build(c)
+	_, remediationBuilder := rr.settings()
+	remediation := remediationBuilder.Build(c)
 
 	entry := v1alpha1.MachineConstraintEntry{
 		UID:            string(c.UID),
@@ -377,7 +570,7 @@ func (rr *ReportReconciler) extractResourceMetrics(c types.Constraint) map[strin
 // scopedName returns the constraint name respecting privacy rules.
 func (rr *ReportReconciler) scopedName(c types.Constraint, viewerNamespace string) string {
 	// At summary level, only show name if same namespace
-	if rr.opts.DefaultDetailLevel == types.DetailLevelSummary {
+	if opts, _ := rr.settings(); opts.DefaultDetailLevel == types.DetailLevelSummary {
 		if c.Namespace != "" && c.Namespace != viewerNamespace {
 			return "cluster-policy"
 		}
@@ -387,7 +580,7 @@ func (rr *ReportReconciler) scopedName(c types.Constraint, viewerNamespace strin
 
 // scopedMessage returns the summary respecting privacy rules.
 func (rr *ReportReconciler) scopedMessage(c types.Constraint, viewerNamespace string) string {
-	if rr.opts.DefaultDetailLevel == types.DetailLevelSummary {
+	if opts, _ := rr.settings(); opts.DefaultDetailLevel == types.DetailLevelSummary {
 		if c.Namespace != "" && c.Namespace != viewerNamespace {
 			return genericSummary(c.ConstraintType)
 		}
@@ -436,6 +629,7 @@ func (rr *ReportReconciler) evaluateMissingResources(namespace string) []v1alpha
 	defer cancel()
 
 	var allMissing []v1alpha1.MissingResourceEntry
+	_, remediationBuilder := rr.settings()
 
 	for _, gvr := range workloadGVRs {
 		list, err := rr.dynamicClient.Resource(gvr).Namespace(namespace).List(ctx, metav1.ListOptions{
@@ -459,7 +653,7 @@ func (rr *ReportReconciler) evaluateMissingResources(namespace string) []v1alpha
 				continue
 			}
 			for _, c := range constraints {
-				allMissing = append(allMissing, constraintToMissingResourceEntry(c, workload, rr.remediationBuilder))
+				allMissing = append(allMissing, constraintToMissingResourceEntry(c, workload, remediationBuilder))
 			}
 		}
 	}
@@ -1,17 +1,28 @@
 package notifier
 
 import (
+	"context"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	k8stypes "k8s.io/apimachinery/pkg/types"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
+	"github.com/nightjarctl/nightjar/api/v1alpha1"
+	"github.com/nightjarctl/nightjar/internal/flowstats"
+	"github.com/nightjarctl/nightjar/internal/hubble"
 	"github.com/nightjarctl/nightjar/internal/indexer"
+	"github.com/nightjarctl/nightjar/internal/scope"
 	"github.com/nightjarctl/nightjar/internal/types"
 )
 
@@ -215,6 +226,22 @@ func TestReportReconciler_ScopedName(t *testing.T) {
 	}
 }
 
+func TestReportReconciler_SetOptions(t *testing.T) {
+	rr := NewReportReconciler(nil, indexer.New(nil), zap.NewNop(), DefaultReportReconcilerOptions(), nil, nil)
+	c := types.Constraint{Name: "deny-egress", Namespace: "kube-system"}
+	assert.Equal(t, "cluster-policy", rr.scopedName(c, "team-alpha"))
+
+	rr.SetOptions(ReportReconcilerOptions{
+		DefaultDetailLevel: types.DetailLevelDetailed,
+		DefaultContact:     "#platform-oncall",
+	})
+
+	opts, remediationBuilder := rr.settings()
+	assert.Equal(t, 10*time.Second, opts.DebounceDuration, "zero debounce keeps the default")
+	assert.Equal(t, "deny-egress", rr.scopedName(c, "team-alpha"))
+	assert.Equal(t, "#platform-oncall", remediationBuilder.DefaultContact)
+}
+
 func TestSeverityOrder(t *testing.T) {
 	assert.Less(t, severityOrder("Critical"), severityOrder("Warning"))
 	assert.Less(t, severityOrder("Warning"), severityOrder("Info"))
@@ -587,6 +614,21 @@ func TestReportReconciler_OnIndexChange(t *testing.T) {
 	assert.True(t, rr.pendingTriggers["team-beta"])
 }
 
+func TestReportReconciler_OnIndexChange_Resync(t *testing.T) {
+	rr := &ReportReconciler{
+		logger:          zap.NewNop(),
+		pendingTriggers: make(map[string]bool),
+		lastReconcile:   make(map[string]time.Time),
+	}
+
+	rr.OnIndexChange(indexer.IndexEvent{Type: indexer.EventResync, Revision: 42})
+
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	assert.True(t, rr.clusterWideTriggered)
+	assert.Empty(t, rr.pendingTriggers)
+}
+
 func TestReportReconciler_OnIndexChange_ClusterScoped(t *testing.T) {
 	rr := &ReportReconciler{
 		logger:          zap.NewNop(),
@@ -772,3 +814,115 @@ func TestBuildReportStatus_WithMissingResources(t *testing.T) {
 	assert.NotNil(t, status.MachineReadable.MissingResources)
 	assert.Empty(t, status.MachineReadable.MissingResources)
 }
+
+func TestBuildReportStatus_WithFlowDrops(t *testing.T) {
+	stats := flowstats.NewStore(flowstats.StoreOptions{})
+	drop := hubble.NewFlowDropBuilder().
+		WithTime(time.Now()).
+		WithSource("checkout", "checkout-a", nil).
+		WithSourceWorkload("Deployment", "checkout").
+		WithDestination("cache", "redis-0", nil).
+		WithDestinationWorkload("StatefulSet", "redis").
+		WithIP("10.0.1.5", "10.0.2.9").
+		WithTCP(40000, 6379, hubble.TCPFlags{SYN: true}).
+		WithDirection(hubble.DirectionIngress).
+		Build()
+	policy := types.Constraint{
+		Name:      "deny-all",
+		Namespace: "cache",
+		Source:    schema.GroupVersionResource{Group: "networking.k8s.io", Version: "v1", Resource: "networkpolicies"},
+	}
+	stats.Record(drop, policy, nil)
+	stats.Record(drop, policy, nil)
+
+	rr := NewReportReconciler(nil, indexer.New(nil), zap.NewNop(), ReportReconcilerOptions{
+		FlowStats: stats,
+	}, nil, nil)
+
+	// The policy's namespace sees it, but not the source's pods.
+	status := rr.buildReportStatus(nil, "cache")
+	require.Len(t, status.FlowDrops, 1)
+	entry := status.FlowDrops[0]
+	assert.Equal(t, v1alpha1.FlowEndpoint{Namespace: "cache", Workload: "redis"}, entry.Destination)
+	assert.Equal(t, v1alpha1.FlowEndpoint{}, entry.Source)
+	assert.Equal(t, int32(6379), entry.Port)
+	assert.Equal(t, "INGRESS", entry.Direction)
+	assert.Equal(t, int64(2), entry.Count)
+	assert.Equal(t, int64(2), entry.RecentCount)
+	assert.Equal(t, "1h0m0s", entry.Window)
+	assert.Equal(t, &v1alpha1.ObjectReference{
+		APIVersion: "networking.k8s.io/v1",
+		Kind:       "NetworkPolicy",
+		Name:       "deny-all",
+		Namespace:  "cache",
+	}, entry.PolicyRef)
+	require.Len(t, entry.Samples, 2)
+	assert.Equal(t, "redis-0", entry.Samples[0].DestinationPod)
+	assert.Empty(t, entry.Samples[0].SourcePod)
+
+	// Other namespaces' policies are hidden at summary level.
+	status = rr.buildReportStatus(nil, "checkout")
+	require.Len(t, status.FlowDrops, 1)
+	assert.Nil(t, status.FlowDrops[0].PolicyRef)
+	assert.Equal(t, "checkout", status.FlowDrops[0].Source.Workload)
+
+	assert.Empty(t, rr.buildReportStatus(nil, "other").FlowDrops)
+
+	// Namespaces whose drop paths changed are reconciled.
+	rr.queueFlowStatsChanges()
+	rr.mu.Lock()
+	assert.Equal(t, map[string]bool{"cache": true, "checkout": true}, rr.pendingTriggers)
+	rr.mu.Unlock()
+}
+
+func TestReportReconciler_Scope(t *testing.T) {
+	s := runtime.NewScheme()
+	require.NoError(t, v1alpha1.AddToScheme(s))
+	existing := &v1alpha1.ConstraintReport{
+		ObjectMeta: metav1.ObjectMeta{Name: reportName, Namespace: "sandbox-1"},
+	}
+	c := fake.NewClientBuilder().
+		WithScheme(s).
+		WithStatusSubresource(&v1alpha1.ConstraintReport{}).
+		WithObjects(existing).
+		Build()
+
+	nsScope := scope.New(k8sfake.NewSimpleClientset(), zap.NewNop(), scope.Options{})
+	require.NoError(t, nsScope.Update(scope.Config{ExcludeNamespaces: []string{"sandbox-*"}}))
+	idx := indexer.New(nil)
+	rr := NewReportReconciler(c, idx, zap.NewNop(), ReportReconcilerOptions{
+		DebounceDuration: time.Millisecond,
+		Scope:            nsScope,
+	}, nil, nil)
+
+	rr.OnIndexChange(indexer.IndexEvent{
+		Type: "upsert",
+		Constraint: types.Constraint{
+			Name:               "cluster-policy",
+			AffectedNamespaces: []string{"team-a", "sandbox-1"},
+		},
+	})
+	rr.mu.Lock()
+	assert.Equal(t, map[string]bool{"team-a": true}, rr.pendingTriggers)
+	rr.mu.Unlock()
+
+	// sandbox-1 left the scope: its report is deleted, team-a's is created.
+	rr.onScopeChange(scope.Change{Removed: []string{"sandbox-1"}})
+	ctx := context.Background()
+	rr.processPendingTriggers(ctx)
+
+	err := c.Get(ctx, client.ObjectKey{Namespace: "sandbox-1", Name: reportName}, &v1alpha1.ConstraintReport{})
+	assert.True(t, apierrors.IsNotFound(err), "out-of-scope report should be deleted")
+	require.NoError(t, c.Get(ctx, client.ObjectKey{Namespace: "team-a", Name: reportName}, &v1alpha1.ConstraintReport{}))
+
+	// Entering the scope again queues a report.
+	rr.onScopeChange(scope.Change{Added: []string{"sandbox-1"}})
+	require.NoError(t, nsScope.Update(scope.Config{}))
+	rr.processPendingTriggers(ctx)
+	require.NoError(t, c.Get(ctx, client.ObjectKey{Namespace: "sandbox-1", Name: reportName}, &v1alpha1.ConstraintReport{}))
+
+	// A namespace handed off to another shard keeps its report.
+	rr.onScopeChange(scope.Change{Removed: []string{"sandbox-1"}, Handoff: true})
+	rr.processPendingTriggers(ctx)
+	require.NoError(t, c.Get(ctx, client.ObjectKey{Namespace: "sandbox-1", Name: reportName}, &v1alpha1.ConstraintReport{}))
+}
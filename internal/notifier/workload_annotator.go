@@ -16,6 +16,7 @@ import (
 
 	"github.com/nightjarctl/nightjar/internal/annotations"
 	"github.com/nightjarctl/nightjar/internal/indexer"
+	"github.com/nightjarctl/nightjar/internal/scope"
 	internaltypes "github.com/nightjarctl/nightjar/internal/types"
 )
 
@@ -47,6 +48,10 @@ type WorkloadAnnotatorOptions struct {
 	// Workers is the number of concurrent workers processing annotation updates.
 	// Default: 5.
 	Workers int
+
+	// Scope limits annotation to namespaces in scope; workloads in namespaces
+	// that leave it have their annotations removed. Nil annotates every namespace.
+	Scope *scope.Scope
 }
 
 // DefaultWorkloadAnnotatorOptions returns sensible defaults.
@@ -78,7 +83,7 @@ type WorkloadAnnotator struct {
 	idx    *indexer.Indexer
 	opts   WorkloadAnnotatorOptions
 
-	mu        sync.Mutex
+	mu        sync.Mutex // guards opts.DebounceDuration, lastPatch and nsCache
 	lastPatch map[workloadKey]time.Time
 	pending   chan pendingUpdate
 	nsCache   map[string]nsWorkloadCache
@@ -98,7 +103,7 @@ func NewWorkloadAnnotator(
 		opts.Workers = 5
 	}
 
-	return &WorkloadAnnotator{
+	wa := &WorkloadAnnotator{
 		logger:    logger.Named("workload-annotator"),
 		client:    client,
 		idx:       idx,
@@ -107,12 +112,28 @@ func NewWorkloadAnnotator(
 		pending:   make(chan pendingUpdate, 1000),
 		nsCache:   make(map[string]nsWorkloadCache),
 	}
+	opts.Scope.OnChange(wa.onScopeChange)
+	return wa
+}
+
+// SetOptions applies the DebounceDuration of opts to later updates. Workers
+// and Scope are fixed when the annotator is created.
+func (wa *WorkloadAnnotator) SetOptions(opts WorkloadAnnotatorOptions) {
+	if opts.DebounceDuration == 0 {
+		opts.DebounceDuration = DefaultWorkloadAnnotatorOptions().DebounceDuration
+	}
+	wa.mu.Lock()
+	defer wa.mu.Unlock()
+	wa.opts.DebounceDuration = opts.DebounceDuration
 }
 
 // Start begins processing indexer changes. Blocks until context is cancelled.
 func (wa *WorkloadAnnotator) Start(ctx context.Context) error {
+	wa.mu.Lock()
+	debounce := wa.opts.DebounceDuration
+	wa.mu.Unlock()
 	wa.logger.Info("Starting workload annotator",
-		zap.Duration("debounce", wa.opts.DebounceDuration),
+		zap.Duration("debounce", debounce),
 		zap.Int("workers", wa.opts.Workers))
 
 	// Start workers
@@ -134,21 +155,29 @@ func (wa *WorkloadAnnotator) Start(ctx context.Context) error {
 	return nil
 }
 
-// OnIndexChange is the callback for indexer.OnChangeFunc.
-// It should be registered with the indexer at construction time.
+// OnIndexChange handles an event from an indexer subscription. Changes made
+// before it was subscribed are caught up by a resync event.
 func (wa *WorkloadAnnotator) OnIndexChange(event indexer.IndexEvent) {
+	// Missed events could have touched any namespace.
+	if event.Type == indexer.EventResync {
+		wa.queueClusterWideUpdate()
+		return
+	}
+
 	c := event.Constraint
 
 	seen := make(map[string]struct{}, len(c.AffectedNamespaces)+1)
 	for _, ns := range c.AffectedNamespaces {
 		if _, ok := seen[ns]; !ok {
 			seen[ns] = struct{}{}
-			wa.queueNamespaceUpdate(ns)
+			if wa.opts.Scope.Allows(ns) {
+				wa.queueNamespaceUpdate(ns)
+			}
 		}
 	}
 
 	if c.Namespace != "" {
-		if _, ok := seen[c.Namespace]; !ok {
+		if _, ok := seen[c.Namespace]; !ok && wa.opts.Scope.Allows(c.Namespace) {
 			wa.queueNamespaceUpdate(c.Namespace)
 		}
 	}
@@ -160,6 +189,34 @@ func (wa *WorkloadAnnotator) OnIndexChange(event indexer.IndexEvent) {
 	}
 }
 
+// onScopeChange re-annotates workloads in namespaces that entered the scope
+// and removes the annotations of workloads in namespaces that left it. The
+// debounce is reset for those namespaces so the change is not skipped.
+// Namespaces handed off to another shard keep their annotations; the new
+// owner maintains them.
+func (wa *WorkloadAnnotator) onScopeChange(change scope.Change) {
+	changed := make(map[string]bool, len(change.Added)+len(change.Removed))
+	for _, ns := range append(append([]string{}, change.Added...), change.Removed...) {
+		changed[ns] = true
+	}
+
+	wa.mu.Lock()
+	for key := range wa.lastPatch {
+		if changed[key.Namespace] {
+			delete(wa.lastPatch, key)
+		}
+	}
+	wa.mu.Unlock()
+
+	queued := change.Added
+	if !change.Handoff {
+		queued = append(append([]string{}, change.Added...), change.Removed...)
+	}
+	for _, ns := range queued {
+		wa.queueNamespaceUpdate(ns)
+	}
+}
+
 // clusterWideSentinel is a special namespace value indicating that a cluster-wide
 // update should list all namespaces and queue per-namespace updates.
 const clusterWideSentinel = "\x00cluster-wide"
@@ -230,7 +287,7 @@ func (wa *WorkloadAnnotator) listNamespaceWorkloads(ctx context.Context, namespa
 	return result, nil
 }
 
-// listAllNamespaces returns all namespace names from the cluster.
+// listAllNamespaces returns the names of all namespaces in scope.
 func (wa *WorkloadAnnotator) listAllNamespaces(ctx context.Context) ([]string, error) {
 	nsGVR := schema.GroupVersionResource{Group: "", Version: "v1", Resource: "namespaces"}
 	list, err := wa.client.Resource(nsGVR).List(ctx, metav1.ListOptions{})
@@ -240,7 +297,9 @@ func (wa *WorkloadAnnotator) listAllNamespaces(ctx context.Context) ([]string, e
 
 	var namespaces []string
 	for _, item := range list.Items {
-		namespaces = append(namespaces, item.GetName())
+		if wa.opts.Scope.Allows(item.GetName()) {
+			namespaces = append(namespaces, item.GetName())
+		}
 	}
 	return namespaces, nil
 }
@@ -251,10 +310,11 @@ func (wa *WorkloadAnnotator) QueueWorkloadUpdate(namespace, kind, name string) {
 
 	wa.mu.Lock()
 	lastPatch := wa.lastPatch[key]
+	debounce := wa.opts.DebounceDuration
 	wa.mu.Unlock()
 
 	// Check debounce
-	if time.Since(lastPatch) < wa.opts.DebounceDuration {
+	if time.Since(lastPatch) < debounce {
 		wa.logger.Debug("Debouncing workload update",
 			zap.String("namespace", namespace),
 			zap.String("kind", kind),
@@ -322,14 +382,19 @@ func (wa *WorkloadAnnotator) processUpdate(ctx context.Context, update pendingUp
 	// Check debounce again
 	wa.mu.Lock()
 	lastPatch := wa.lastPatch[key]
+	debounce := wa.opts.DebounceDuration
 	wa.mu.Unlock()
 
-	if time.Since(lastPatch) < wa.opts.DebounceDuration {
+	if time.Since(lastPatch) < debounce {
 		return
 	}
 
-	// Get constraints for this namespace
-	constraints := wa.idx.ByNamespace(key.Namespace)
+	// Get constraints for this namespace. Out-of-scope workloads get none,
+	// which removes their annotations.
+	var constraints []internaltypes.Constraint
+	if wa.opts.Scope.Allows(key.Namespace) {
+		constraints = wa.idx.ByNamespace(key.Namespace)
+	}
 
 	// Build annotation patch
 	patch := wa.buildAnnotationPatch(constraints)
@@ -15,9 +15,11 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	k8stypes "k8s.io/apimachinery/pkg/types"
 	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
 
 	"github.com/nightjarctl/nightjar/internal/annotations"
 	"github.com/nightjarctl/nightjar/internal/indexer"
+	"github.com/nightjarctl/nightjar/internal/scope"
 	"github.com/nightjarctl/nightjar/internal/types"
 )
 
@@ -479,6 +481,17 @@ func TestWorkloadAnnotator_OnIndexChange_ClusterScoped_NoAffectedNamespaces(t *t
 	assert.Equal(t, clusterWideSentinel, update.key.Namespace)
 }
 
+func TestWorkloadAnnotator_OnIndexChange_Resync(t *testing.T) {
+	dynClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	wa := NewWorkloadAnnotator(dynClient, indexer.New(nil), zap.NewNop(), DefaultWorkloadAnnotatorOptions())
+
+	wa.OnIndexChange(indexer.IndexEvent{Type: indexer.EventResync, Revision: 42})
+
+	require.Equal(t, 1, len(wa.pending))
+	update := <-wa.pending
+	assert.Equal(t, clusterWideSentinel, update.key.Namespace)
+}
+
 func TestWorkloadAnnotator_ProcessUpdate_ClusterWideSentinel(t *testing.T) {
 	scheme := runtime.NewScheme()
 	gvrToListKind := map[schema.GroupVersionResource]string{
@@ -904,3 +917,91 @@ func TestWorkloadAnnotator_Start_ContextCancellation(t *testing.T) {
 		t.Error("Start did not exit on context cancellation")
 	}
 }
+
+func TestWorkloadAnnotator_Scope_SkipsExcludedNamespaces(t *testing.T) {
+	scheme := runtime.NewScheme()
+	gvrToListKind := map[schema.GroupVersionResource]string{
+		{Group: "", Version: "v1", Resource: "namespaces"}: "NamespaceList",
+	}
+	dynClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(scheme, gvrToListKind)
+	nsGVR := schema.GroupVersionResource{Group: "", Version: "v1", Resource: "namespaces"}
+	for _, name := range []string{"team-a", "kube-system"} {
+		ns := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Namespace",
+			"metadata":   map[string]interface{}{"name": name},
+		}}
+		_, err := dynClient.Resource(nsGVR).Create(context.Background(), ns, metav1.CreateOptions{})
+		require.NoError(t, err)
+	}
+
+	nsScope := scope.New(k8sfake.NewSimpleClientset(), zap.NewNop(), scope.Options{})
+	require.NoError(t, nsScope.Update(scope.Config{ExcludeNamespaces: []string{"kube-*"}}))
+	opts := DefaultWorkloadAnnotatorOptions()
+	opts.Scope = nsScope
+	wa := NewWorkloadAnnotator(dynClient, indexer.New(nil), zap.NewNop(), opts)
+
+	wa.OnIndexChange(indexer.IndexEvent{
+		Type: "upsert",
+		Constraint: types.Constraint{
+			Name:               "cluster-policy",
+			AffectedNamespaces: []string{"team-a", "kube-system"},
+		},
+	})
+	require.Equal(t, 1, len(wa.pending))
+	assert.Equal(t, "team-a", (<-wa.pending).key.Namespace)
+
+	// Cluster-wide expansion only lists namespaces in scope.
+	wa.processUpdate(context.Background(), pendingUpdate{key: workloadKey{Namespace: clusterWideSentinel}})
+	require.Equal(t, 1, len(wa.pending))
+	assert.Equal(t, "team-a", (<-wa.pending).key.Namespace)
+}
+
+func TestWorkloadAnnotator_Scope_RemovesAnnotationsOnExclusion(t *testing.T) {
+	scheme := runtime.NewScheme()
+	gvr := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	dynClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(scheme, map[schema.GroupVersionResource]string{
+		gvr: "DeploymentList",
+		{Group: "apps", Version: "v1", Resource: "statefulsets"}: "StatefulSetList",
+		{Group: "apps", Version: "v1", Resource: "daemonsets"}:   "DaemonSetList",
+	})
+	dep := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"name": "my-app", "namespace": "sandbox-1"},
+	}}
+	_, err := dynClient.Resource(gvr).Namespace("sandbox-1").Create(context.Background(), dep, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	idx := indexer.New(nil)
+	idx.Upsert(types.Constraint{UID: "c-1", Name: "quota", Namespace: "sandbox-1", Severity: types.SeverityWarning})
+
+	nsScope := scope.New(k8sfake.NewSimpleClientset(), zap.NewNop(), scope.Options{})
+	wa := NewWorkloadAnnotator(dynClient, idx, zap.NewNop(), WorkloadAnnotatorOptions{
+		DebounceDuration: time.Hour,
+		Workers:          1,
+		Scope:            nsScope,
+	})
+
+	key := workloadKey{Namespace: "sandbox-1", Kind: "Deployment", Name: "my-app"}
+	wa.processUpdate(context.Background(), pendingUpdate{key: key})
+	annotated, err := dynClient.Resource(gvr).Namespace("sandbox-1").Get(context.Background(), "my-app", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Contains(t, annotated.GetAnnotations(), annotations.WorkloadStatus)
+
+	// Leaving the scope resets the debounce and queues the namespace.
+	require.NoError(t, nsScope.Update(scope.Config{ExcludeNamespaces: []string{"sandbox-*"}}))
+	wa.onScopeChange(scope.Change{Removed: []string{"sandbox-1"}})
+	require.Equal(t, 1, len(wa.pending))
+	wa.processUpdate(context.Background(), <-wa.pending)
+	require.Equal(t, 1, len(wa.pending))
+	wa.processUpdate(context.Background(), <-wa.pending)
+
+	cleaned, err := dynClient.Resource(gvr).Namespace("sandbox-1").Get(context.Background(), "my-app", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.NotContains(t, cleaned.GetAnnotations(), annotations.WorkloadStatus)
+
+	// A namespace handed off to another shard is left to its new owner.
+	wa.onScopeChange(scope.Change{Removed: []string{"sandbox-1"}, Handoff: true})
+	assert.Empty(t, wa.pending)
+}
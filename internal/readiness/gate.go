@@ -0,0 +1,261 @@
+// Package readiness holds the controller's reconcilers and notifiers back
+// until the discovery engine has indexed the initial list of every watched
+// resource type, so they never act on a partial index.
+//
+// Until the pipeline is ready, index events are dropped and each consumer
+// receives a single resync once it is. Afterwards, events from a resource
+// type discovered later are held per type until that type has synced too.
+package readiness
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/nightjarctl/nightjar/internal/indexer"
+	"github.com/nightjarctl/nightjar/internal/types"
+)
+
+// Source reports the sync state of the discovery pipeline.
+// *discovery.Engine implements it.
+type Source interface {
+	// HasSynced reports whether every watched GVR has synced.
+	HasSynced() bool
+	// GVRSynced reports whether gvr has synced; GVRs without an informer
+	// are reported synced.
+	GVRSynced(gvr schema.GroupVersionResource) bool
+}
+
+// Options configures a Gate.
+type Options struct {
+	// PollInterval is how often the Gate checks the sync state.
+	// Default: 1s.
+	PollInterval time.Duration
+
+	// MaxHeldEvents bounds the events held per consumer for one unsynced
+	// GVR. Beyond it the events are dropped and the consumer receives a
+	// resync when the GVR syncs. Default: 1024.
+	MaxHeldEvents int
+}
+
+// DefaultOptions returns Options with default values.
+func DefaultOptions() Options {
+	return Options{
+		PollInterval:  time.Second,
+		MaxHeldEvents: 1024,
+	}
+}
+
+// Gate forwards index events to consumers once the pipeline is ready.
+type Gate struct {
+	idx    *indexer.Indexer
+	source Source
+	logger *zap.Logger
+	opts   Options
+
+	ready     chan struct{}
+	readyOnce sync.Once
+
+	mu         sync.Mutex
+	forwarders []*forwarder
+}
+
+// New creates a Gate. The pipeline is ready once source has synced and the
+// index is not stale, e.g. while a restored snapshot is being reconciled.
+func New(idx *indexer.Indexer, source Source, logger *zap.Logger, opts Options) *Gate {
+	defaults := DefaultOptions()
+	if opts.PollInterval == 0 {
+		opts.PollInterval = defaults.PollInterval
+	}
+	if opts.MaxHeldEvents == 0 {
+		opts.MaxHeldEvents = defaults.MaxHeldEvents
+	}
+	return &Gate{
+		idx:    idx,
+		source: source,
+		logger: logger.Named("readiness"),
+		opts:   opts,
+		ready:  make(chan struct{}),
+	}
+}
+
+// Forward subscribes fn to every index change until ctx is cancelled, gated
+// by the pipeline's sync state. The first event fn receives is a resync, so
+// a consumer that starts late, e.g. after a leader failover, catches up on
+// everything. fn must be safe for concurrent use with the consumer's other
+// methods; calls to fn itself are never concurrent.
+func (g *Gate) Forward(ctx context.Context, fn func(indexer.IndexEvent)) {
+	f := &forwarder{gate: g, fn: fn, held: make(map[schema.GroupVersionResource]*heldEvents)}
+	g.mu.Lock()
+	g.forwarders = append(g.forwarders, f)
+	g.mu.Unlock()
+
+	sub := g.idx.Subscribe(types.ConstraintQuery{})
+	go func() {
+		<-ctx.Done()
+		sub.Close()
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		for i, other := range g.forwarders {
+			if other == f {
+				g.forwarders = append(g.forwarders[:i], g.forwarders[i+1:]...)
+				break
+			}
+		}
+	}()
+	go func() {
+		for event := range sub.Events() {
+			f.handle(event)
+		}
+	}()
+}
+
+// Start polls the sync state until ctx is cancelled, opening the gate once
+// the pipeline is ready and releasing held events of GVRs as they sync.
+func (g *Gate) Start(ctx context.Context) error {
+	started := time.Now()
+	ticker := time.NewTicker(g.opts.PollInterval)
+	defer ticker.Stop()
+	for {
+		if !g.Ready() && g.source.HasSynced() && !g.idx.Stale() {
+			g.readyOnce.Do(func() { close(g.ready) })
+			g.logger.Info("Pipeline ready, informers synced", zap.Duration("waited", time.Since(started)))
+		}
+
+		g.mu.Lock()
+		forwarders := append([]*forwarder(nil), g.forwarders...)
+		g.mu.Unlock()
+		for _, f := range forwarders {
+			f.poll()
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Ready reports whether the pipeline is ready. It stays ready once it was,
+// even while GVRs discovered later sync.
+func (g *Gate) Ready() bool {
+	select {
+	case <-g.ready:
+		return true
+	default:
+		return false
+	}
+}
+
+// WaitForReady blocks until the pipeline is ready. It returns false if ctx
+// is cancelled first.
+func (g *Gate) WaitForReady(ctx context.Context) bool {
+	select {
+	case <-g.ready:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// errNotReady is returned by Check until the pipeline is ready.
+var errNotReady = errors.New("waiting for informers to sync")
+
+// Check is a healthz checker that fails until the pipeline is ready.
+func (g *Gate) Check(_ *http.Request) error {
+	if !g.Ready() {
+		return errNotReady
+	}
+	return nil
+}
+
+// heldEvents are the events of one unsynced GVR awaiting delivery.
+type heldEvents struct {
+	events   []indexer.IndexEvent
+	overflow bool // events were dropped; deliver a resync instead
+}
+
+// forwarder delivers the events of one subscription to its consumer.
+type forwarder struct {
+	gate *Gate
+	fn   func(indexer.IndexEvent)
+
+	mu     sync.Mutex
+	opened bool // the initial resync was delivered
+	held   map[schema.GroupVersionResource]*heldEvents
+}
+
+// handle delivers, holds or drops one event.
+func (f *forwarder) handle(event indexer.IndexEvent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.opened {
+		// Covered by the resync delivered when the gate opens.
+		return
+	}
+	if event.Type == indexer.EventResync {
+		f.fn(event)
+		return
+	}
+
+	gvr := event.Constraint.Source
+	h, holding := f.held[gvr]
+	if f.gate.source.GVRSynced(gvr) {
+		if holding {
+			f.releaseLocked(gvr, h)
+		}
+		f.fn(event)
+		return
+	}
+	if !holding {
+		h = &heldEvents{}
+		f.held[gvr] = h
+	}
+	if h.overflow {
+		return
+	}
+	if len(h.events) >= f.gate.opts.MaxHeldEvents {
+		h.events, h.overflow = nil, true
+		return
+	}
+	h.events = append(h.events, event)
+}
+
+// poll opens the forwarder once the gate is ready and releases the held
+// events of GVRs that have synced.
+func (f *forwarder) poll() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.opened {
+		if !f.gate.Ready() {
+			return
+		}
+		f.opened = true
+		f.fn(indexer.IndexEvent{Type: indexer.EventResync, Revision: f.gate.idx.Revision()})
+		return
+	}
+	for gvr, h := range f.held {
+		if f.gate.source.GVRSynced(gvr) {
+			f.releaseLocked(gvr, h)
+		}
+	}
+}
+
+// releaseLocked delivers the held events of gvr in order. Caller must hold
+// f.mu.
+func (f *forwarder) releaseLocked(gvr schema.GroupVersionResource, h *heldEvents) {
+	delete(f.held, gvr)
+	if h.overflow {
+		f.fn(indexer.IndexEvent{Type: indexer.EventResync, Revision: f.gate.idx.Revision()})
+		return
+	}
+	for _, event := range h.events {
+		f.fn(event)
+	}
+}
@@ -0,0 +1,222 @@
+package readiness
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+
+	"github.com/nightjarctl/nightjar/internal/indexer"
+	"github.com/nightjarctl/nightjar/internal/types"
+)
+
+var (
+	networkPolicies = schema.GroupVersionResource{Group: "networking.k8s.io", Version: "v1", Resource: "networkpolicies"}
+	lateGVR         = schema.GroupVersionResource{Group: "custom.io", Version: "v1", Resource: "securitypolicies"}
+)
+
+type fakeSource struct {
+	mu       sync.Mutex
+	synced   bool
+	unsynced map[schema.GroupVersionResource]bool
+}
+
+func (f *fakeSource) HasSynced() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.synced
+}
+
+func (f *fakeSource) GVRSynced(gvr schema.GroupVersionResource) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return !f.unsynced[gvr]
+}
+
+func (f *fakeSource) set(synced bool, unsynced ...schema.GroupVersionResource) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.synced = synced
+	f.unsynced = make(map[schema.GroupVersionResource]bool)
+	for _, gvr := range unsynced {
+		f.unsynced[gvr] = true
+	}
+}
+
+// recorder collects the events delivered to a consumer.
+type recorder struct {
+	mu     sync.Mutex
+	events []indexer.IndexEvent
+}
+
+func (r *recorder) record(event indexer.IndexEvent) {
+	r.mu.Lock()
+	r.events = append(r.events, event)
+	r.mu.Unlock()
+}
+
+func (r *recorder) get() []indexer.IndexEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]indexer.IndexEvent(nil), r.events...)
+}
+
+func (r *recorder) uids() []k8stypes.UID {
+	var out []k8stypes.UID
+	for _, ev := range r.get() {
+		if ev.Type != indexer.EventResync {
+			out = append(out, ev.Constraint.UID)
+		}
+	}
+	return out
+}
+
+func constraint(uid string, source schema.GroupVersionResource) types.Constraint {
+	return types.Constraint{
+		UID:            k8stypes.UID(uid),
+		Source:         source,
+		Name:           uid,
+		Namespace:      "team-a",
+		ConstraintType: types.ConstraintTypeNetworkIngress,
+	}
+}
+
+func startGate(t *testing.T, g *Gate) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = g.Start(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+func TestGate_HoldsUntilSynced(t *testing.T) {
+	idx := indexer.New(nil)
+	source := &fakeSource{}
+	g := New(idx, source, zap.NewNop(), Options{PollInterval: 5 * time.Millisecond})
+	rec := &recorder{}
+	g.Forward(context.Background(), rec.record)
+	startGate(t, g)
+
+	assert.Error(t, g.Check(nil))
+	idx.Upsert(constraint("initial-1", networkPolicies))
+	idx.Upsert(constraint("initial-2", networkPolicies))
+	time.Sleep(30 * time.Millisecond)
+	assert.Empty(t, rec.get(), "nothing is delivered before the initial sync")
+	assert.False(t, g.Ready())
+
+	source.set(true)
+	require.True(t, g.WaitForReady(context.Background()))
+	require.Eventually(t, func() bool { return len(rec.get()) == 1 }, 5*time.Second, 5*time.Millisecond)
+	first := rec.get()[0]
+	assert.Equal(t, indexer.EventResync, first.Type, "one resync replaces the dropped events")
+	assert.Equal(t, idx.Revision(), first.Revision)
+	assert.NoError(t, g.Check(nil))
+
+	idx.Upsert(constraint("after", networkPolicies))
+	require.Eventually(t, func() bool { return len(rec.get()) == 2 }, 5*time.Second, 5*time.Millisecond)
+	assert.Equal(t, []k8stypes.UID{"after"}, rec.uids())
+}
+
+func TestGate_WaitsForStaleIndex(t *testing.T) {
+	idx := indexer.New(nil)
+	idx.SetStale(true)
+	source := &fakeSource{}
+	source.set(true)
+	g := New(idx, source, zap.NewNop(), Options{PollInterval: 5 * time.Millisecond})
+	startGate(t, g)
+
+	time.Sleep(30 * time.Millisecond)
+	assert.False(t, g.Ready(), "a restored snapshot is reconciled first")
+	idx.SetStale(false)
+	require.Eventually(t, g.Ready, 5*time.Second, 5*time.Millisecond)
+}
+
+func TestGate_PerGVRGating(t *testing.T) {
+	idx := indexer.New(nil)
+	source := &fakeSource{}
+	source.set(true)
+	g := New(idx, source, zap.NewNop(), Options{PollInterval: 5 * time.Millisecond})
+	rec := &recorder{}
+	g.Forward(context.Background(), rec.record)
+	startGate(t, g)
+	require.Eventually(t, func() bool { return len(rec.get()) == 1 }, 5*time.Second, 5*time.Millisecond)
+
+	// A new type is discovered; its initial list is held back while other
+	// types keep flowing.
+	source.set(true, lateGVR)
+	idx.Upsert(constraint("late-1", lateGVR))
+	idx.Upsert(constraint("netpol", networkPolicies))
+	idx.Upsert(constraint("late-2", lateGVR))
+	require.Eventually(t, func() bool { return len(rec.uids()) == 1 }, 5*time.Second, 5*time.Millisecond)
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, []k8stypes.UID{"netpol"}, rec.uids())
+	assert.True(t, g.Ready(), "readiness is not withdrawn for later types")
+
+	source.set(true)
+	require.Eventually(t, func() bool { return len(rec.uids()) == 3 }, 5*time.Second, 5*time.Millisecond)
+	assert.Equal(t, []k8stypes.UID{"netpol", "late-1", "late-2"}, rec.uids(), "held events are released in order")
+}
+
+func TestGate_HeldOverflowResyncs(t *testing.T) {
+	idx := indexer.New(nil)
+	source := &fakeSource{}
+	source.set(true)
+	g := New(idx, source, zap.NewNop(), Options{PollInterval: 5 * time.Millisecond, MaxHeldEvents: 3})
+	rec := &recorder{}
+	g.Forward(context.Background(), rec.record)
+	startGate(t, g)
+	require.Eventually(t, func() bool { return len(rec.get()) == 1 }, 5*time.Second, 5*time.Millisecond)
+
+	source.set(true, lateGVR)
+	for i := 0; i < 10; i++ {
+		idx.Upsert(constraint(fmt.Sprintf("late-%d", i), lateGVR))
+	}
+	time.Sleep(30 * time.Millisecond)
+	assert.Len(t, rec.get(), 1)
+
+	source.set(true)
+	require.Eventually(t, func() bool { return len(rec.get()) == 2 }, 5*time.Second, 5*time.Millisecond)
+	assert.Equal(t, indexer.EventResync, rec.get()[1].Type)
+	assert.Empty(t, rec.uids())
+}
+
+func TestGate_LateForwarderResyncs(t *testing.T) {
+	idx := indexer.New(nil)
+	source := &fakeSource{}
+	source.set(true)
+	g := New(idx, source, zap.NewNop(), Options{PollInterval: 5 * time.Millisecond})
+	startGate(t, g)
+	require.Eventually(t, g.Ready, 5*time.Second, 5*time.Millisecond)
+	idx.Upsert(constraint("before", networkPolicies))
+
+	// A consumer that starts after the gate opened, e.g. on a new leader,
+	// first catches up with a resync.
+	ctx, cancel := context.WithCancel(context.Background())
+	rec := &recorder{}
+	g.Forward(ctx, rec.record)
+	require.Eventually(t, func() bool { return len(rec.get()) == 1 }, 5*time.Second, 5*time.Millisecond)
+	assert.Equal(t, indexer.EventResync, rec.get()[0].Type)
+
+	cancel()
+	require.Eventually(t, func() bool {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		return len(g.forwarders) == 0
+	}, 5*time.Second, 5*time.Millisecond)
+	idx.Upsert(constraint("after", networkPolicies))
+	time.Sleep(30 * time.Millisecond)
+	assert.Len(t, rec.get(), 1, "no events after ctx is cancelled")
+}
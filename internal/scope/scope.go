@@ -0,0 +1,527 @@
+// Package scope decides which namespaces Nightjar watches, annotates and
+// reports on.
+//
+// The scope is configured cluster-wide in a ConfigMap holding namespace name
+// globs and label selectors. The ConfigMap and the namespaces' labels are
+// watched, so edits take effect without a restart; components subscribe with
+// OnChange to clean up after namespaces that leave the scope and to catch up
+// on namespaces that enter it.
+//
+// When the controller is sharded, a Partition further limits the scope to
+// the namespaces this replica owns.
+package scope
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/yaml"
+)
+
+// ConfigKey is the ConfigMap data key holding the scope configuration YAML.
+const ConfigKey = "scope.yaml"
+
+// Config selects namespaces by name and by label. A namespace is in scope when
+// it matches an include glob (or none are set) and the include selector (if
+// set), and matches neither an exclude glob nor the exclude selector.
+// The zero Config puts every namespace in scope.
+type Config struct {
+	// IncludeNamespaces are namespace name globs (path.Match syntax, e.g.
+	// "team-*"). Empty includes every namespace.
+	IncludeNamespaces []string `json:"includeNamespaces,omitempty"`
+
+	// ExcludeNamespaces are namespace name globs that are never in scope.
+	ExcludeNamespaces []string `json:"excludeNamespaces,omitempty"`
+
+	// IncludeSelector is a label selector (e.g. "nightjar.io/scope=enabled")
+	// that namespaces must match. Empty matches every namespace.
+	IncludeSelector string `json:"includeSelector,omitempty"`
+
+	// ExcludeSelector is a label selector; matching namespaces are never in
+	// scope. Empty excludes nothing.
+	ExcludeSelector string `json:"excludeSelector,omitempty"`
+}
+
+// ParseConfig reads the scope configuration from a ConfigMap. A ConfigMap
+// without the scope.yaml key yields the zero Config.
+func ParseConfig(cm *corev1.ConfigMap) (Config, error) {
+	var cfg Config
+	raw, ok := cm.Data[ConfigKey]
+	if !ok || raw == "" {
+		return cfg, nil
+	}
+	if err := yaml.UnmarshalStrict([]byte(raw), &cfg); err != nil {
+		return Config{}, fmt.Errorf("configmap %s/%s: invalid scope config: %w", cm.Namespace, cm.Name, err)
+	}
+	if _, err := compile(cfg); err != nil {
+		return Config{}, fmt.Errorf("configmap %s/%s: %w", cm.Namespace, cm.Name, err)
+	}
+	return cfg, nil
+}
+
+// rules is a validated Config.
+type rules struct {
+	include         []string
+	exclude         []string
+	includeSelector labels.Selector // nil matches everything
+	excludeSelector labels.Selector // nil matches nothing
+}
+
+// compile validates cfg and parses its selectors.
+func compile(cfg Config) (*rules, error) {
+	for _, glob := range append(append([]string{}, cfg.IncludeNamespaces...), cfg.ExcludeNamespaces...) {
+		if _, err := path.Match(glob, ""); err != nil {
+			return nil, fmt.Errorf("invalid namespace glob %q: %w", glob, err)
+		}
+	}
+	r := &rules{include: cfg.IncludeNamespaces, exclude: cfg.ExcludeNamespaces}
+	if cfg.IncludeSelector != "" {
+		sel, err := labels.Parse(cfg.IncludeSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid includeSelector: %w", err)
+		}
+		r.includeSelector = sel
+	}
+	if cfg.ExcludeSelector != "" {
+		sel, err := labels.Parse(cfg.ExcludeSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid excludeSelector: %w", err)
+		}
+		r.excludeSelector = sel
+	}
+	return r, nil
+}
+
+// allows reports whether a namespace with the given name and labels is in scope.
+func (r *rules) allows(name string, nsLabels labels.Set) bool {
+	if len(r.include) > 0 && !matchAny(r.include, name) {
+		return false
+	}
+	if r.includeSelector != nil && !r.includeSelector.Matches(nsLabels) {
+		return false
+	}
+	if matchAny(r.exclude, name) {
+		return false
+	}
+	if r.excludeSelector != nil && r.excludeSelector.Matches(nsLabels) {
+		return false
+	}
+	return true
+}
+
+// matchAny reports whether name matches any of the globs. Globs are validated
+// by compile, so match errors cannot occur.
+func matchAny(globs []string, name string) bool {
+	for _, glob := range globs {
+		if ok, _ := path.Match(glob, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Change lists the namespaces that entered and left the scope.
+type Change struct {
+	Added   []string
+	Removed []string
+
+	// Handoff is set when the namespaces moved between shards rather than
+	// in or out of the cluster-wide scope. Another replica takes over what
+	// this one produced for Removed namespaces, so listeners must not delete
+	// it.
+	Handoff bool
+}
+
+// Partition assigns namespaces to the replicas of a sharded controller.
+// *shard.Membership implements it.
+type Partition interface {
+	// Owns reports whether this replica owns namespace.
+	Owns(namespace string) bool
+}
+
+// ChangeFunc is called after the scope changed. It runs synchronously on the
+// watch goroutine and must not block.
+type ChangeFunc func(Change)
+
+// Options configures the Scope.
+type Options struct {
+	// Namespace of the scope ConfigMap. Default: "nightjar-system".
+	Namespace string
+
+	// ConfigMapName is the name of the scope ConfigMap. Default: "nightjar-scope".
+	ConfigMapName string
+
+	// ResyncPeriod is the informer resync period. Default: 10 minutes.
+	ResyncPeriod time.Duration
+
+	// DisableConfigMap puts every namespace in scope without reading a
+	// ConfigMap, e.g. when the scope only applies a Partition.
+	DisableConfigMap bool
+}
+
+// DefaultOptions returns sensible defaults.
+func DefaultOptions() Options {
+	return Options{
+		Namespace:     "nightjar-system",
+		ConfigMapName: "nightjar-scope",
+		ResyncPeriod:  10 * time.Minute,
+	}
+}
+
+// Scope tracks the namespace scope configuration and the labels of every
+// namespace. A nil *Scope puts every namespace in scope, so components can
+// hold one unconditionally.
+type Scope struct {
+	logger *zap.Logger
+	client kubernetes.Interface
+	opts   Options
+
+	mu         sync.RWMutex
+	rules      *rules
+	namespaces map[string]labels.Set
+	partition  Partition
+	owned      map[string]bool // ownership of known namespaces at the last notification
+	listeners  []ChangeFunc
+	synced     chan struct{} // closed once the ConfigMap and namespaces are listed
+}
+
+// New creates a Scope that puts every namespace in scope until its
+// ConfigMap is read.
+func New(client kubernetes.Interface, logger *zap.Logger, opts Options) *Scope {
+	defaults := DefaultOptions()
+	if opts.Namespace == "" {
+		opts.Namespace = defaults.Namespace
+	}
+	if opts.ConfigMapName == "" {
+		opts.ConfigMapName = defaults.ConfigMapName
+	}
+	if opts.ResyncPeriod == 0 {
+		opts.ResyncPeriod = defaults.ResyncPeriod
+	}
+	return &Scope{
+		logger:     logger.Named("scope"),
+		client:     client,
+		opts:       opts,
+		rules:      &rules{},
+		namespaces: make(map[string]labels.Set),
+		owned:      make(map[string]bool),
+		synced:     make(chan struct{}),
+	}
+}
+
+// OnChange registers fn to be called whenever namespaces enter or leave the
+// scope. Register listeners before Start.
+func (s *Scope) OnChange(fn ChangeFunc) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, fn)
+}
+
+// SetPartition limits the scope to the namespaces p assigns to this
+// replica. Call Repartition whenever the assignment changes. Must be called
+// before Start.
+func (s *Scope) SetPartition(p Partition) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.partition = p
+}
+
+// Allows reports whether namespace is in scope. The empty namespace (a
+// cluster-scoped object) is always in scope.
+func (s *Scope) Allows(namespace string) bool {
+	if s == nil || namespace == "" {
+		return true
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.rules.allows(namespace, s.namespaces[namespace]) && s.ownsLocked(namespace)
+}
+
+// ownsLocked reports whether the partition assigns namespace to this
+// replica. Caller must hold s.mu.
+func (s *Scope) ownsLocked(namespace string) bool {
+	return s.partition == nil || s.partition.Owns(namespace)
+}
+
+// Namespaces returns the known namespaces that are in scope, sorted.
+func (s *Scope) Namespaces() []string {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var result []string
+	for name, nsLabels := range s.namespaces {
+		if s.rules.allows(name, nsLabels) && s.owned[name] {
+			result = append(result, name)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// Filter returns the namespaces that are in scope, preserving order.
+func (s *Scope) Filter(namespaces []string) []string {
+	if s == nil {
+		return namespaces
+	}
+	result := make([]string, 0, len(namespaces))
+	for _, ns := range namespaces {
+		if s.Allows(ns) {
+			result = append(result, ns)
+		}
+	}
+	return result
+}
+
+// Update replaces the scope configuration and notifies listeners of the
+// namespaces that entered or left the scope. An invalid cfg is rejected and
+// the previous configuration is kept.
+func (s *Scope) Update(cfg Config) error {
+	r, err := compile(cfg)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	old := s.rules
+	s.rules = r
+	var change Change
+	for name, nsLabels := range s.namespaces {
+		if !s.owned[name] {
+			continue
+		}
+		was, is := old.allows(name, nsLabels), r.allows(name, nsLabels)
+		switch {
+		case is && !was:
+			change.Added = append(change.Added, name)
+		case was && !is:
+			change.Removed = append(change.Removed, name)
+		}
+	}
+	s.mu.Unlock()
+
+	sort.Strings(change.Added)
+	sort.Strings(change.Removed)
+	s.logger.Info("Namespace scope updated",
+		zap.Strings("include", cfg.IncludeNamespaces),
+		zap.Strings("exclude", cfg.ExcludeNamespaces),
+		zap.String("include_selector", cfg.IncludeSelector),
+		zap.String("exclude_selector", cfg.ExcludeSelector),
+		zap.Int("added", len(change.Added)),
+		zap.Int("removed", len(change.Removed)))
+	s.notify(change)
+	return nil
+}
+
+// Repartition re-reads the partition and notifies listeners of the
+// namespaces this replica gained and lost, as a handoff.
+func (s *Scope) Repartition() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	change := Change{Handoff: true}
+	for name, nsLabels := range s.namespaces {
+		was, is := s.owned[name], s.ownsLocked(name)
+		s.owned[name] = is
+		if was == is || !s.rules.allows(name, nsLabels) {
+			continue
+		}
+		if is {
+			change.Added = append(change.Added, name)
+		} else {
+			change.Removed = append(change.Removed, name)
+		}
+	}
+	s.mu.Unlock()
+
+	sort.Strings(change.Added)
+	sort.Strings(change.Removed)
+	if len(change.Added) > 0 || len(change.Removed) > 0 {
+		s.logger.Info("Namespace shard assignment changed",
+			zap.Int("added", len(change.Added)),
+			zap.Int("removed", len(change.Removed)))
+	}
+	s.notify(change)
+}
+
+// WaitForSync blocks until the scope configuration and the namespace labels
+// have been read, so that Allows gives its final answer, or until ctx is
+// cancelled. Returns false if ctx was cancelled first.
+func (s *Scope) WaitForSync(ctx context.Context) bool {
+	if s == nil {
+		return true
+	}
+	select {
+	case <-s.synced:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// setNamespace records the labels of a namespace and notifies listeners if
+// the change moved it into or out of the scope. Namespaces of the initial
+// list are recorded silently: nothing has been processed for them yet.
+func (s *Scope) setNamespace(name string, nsLabels map[string]string, initial bool) {
+	s.mu.Lock()
+	wasOwned, known := s.owned[name]
+	if !known {
+		wasOwned = s.ownsLocked(name)
+	}
+	was := wasOwned && s.rules.allows(name, s.namespaces[name])
+	s.namespaces[name] = labels.Set(nsLabels)
+	s.owned[name] = s.ownsLocked(name)
+	is := s.owned[name] && s.rules.allows(name, s.namespaces[name])
+	s.mu.Unlock()
+
+	if initial {
+		return
+	}
+	switch {
+	case is && !was:
+		s.notify(Change{Added: []string{name}})
+	case was && !is:
+		s.notify(Change{Removed: []string{name}})
+	}
+}
+
+// deleteNamespace forgets a deleted namespace. Listeners are not notified:
+// everything in the namespace is being deleted anyway.
+func (s *Scope) deleteNamespace(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.namespaces, name)
+	delete(s.owned, name)
+}
+
+// notify calls every listener with change, unless it is empty.
+func (s *Scope) notify(change Change) {
+	if len(change.Added) == 0 && len(change.Removed) == 0 {
+		return
+	}
+	s.mu.RLock()
+	listeners := append([]ChangeFunc(nil), s.listeners...)
+	s.mu.RUnlock()
+	for _, fn := range listeners {
+		fn(change)
+	}
+}
+
+// Start watches the scope ConfigMap and namespaces. The ConfigMap is read
+// before namespaces are listed, so no namespace is briefly in scope at
+// startup. Blocks until context is cancelled.
+func (s *Scope) Start(ctx context.Context) error {
+	if s.opts.DisableConfigMap {
+		s.logger.Info("Starting namespace scope watcher without a scope ConfigMap")
+		return s.watchNamespaces(ctx)
+	}
+	s.logger.Info("Starting namespace scope watcher",
+		zap.String("namespace", s.opts.Namespace),
+		zap.String("configmap", s.opts.ConfigMapName))
+
+	cmFactory := informers.NewSharedInformerFactoryWithOptions(
+		s.client,
+		s.opts.ResyncPeriod,
+		informers.WithNamespace(s.opts.Namespace),
+		informers.WithTweakListOptions(func(o *metav1.ListOptions) {
+			o.FieldSelector = fields.OneTermEqualSelector("metadata.name", s.opts.ConfigMapName).String()
+		}),
+	)
+	cmInformer := cmFactory.Core().V1().ConfigMaps().Informer()
+	if _, err := cmInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			s.handleConfigMap(obj)
+		},
+		UpdateFunc: func(_, newObj interface{}) {
+			s.handleConfigMap(newObj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			s.logger.Info("Namespace scope ConfigMap deleted, all namespaces are in scope")
+			_ = s.Update(Config{})
+		},
+	}); err != nil {
+		return fmt.Errorf("adding scope config event handler: %w", err)
+	}
+	defer cmFactory.Shutdown()
+
+	cmFactory.Start(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), cmInformer.HasSynced) {
+		return nil
+	}
+	return s.watchNamespaces(ctx)
+}
+
+// watchNamespaces records the labels of every namespace and marks the scope
+// synced once they are listed. Blocks until ctx is cancelled.
+func (s *Scope) watchNamespaces(ctx context.Context) error {
+	nsFactory := informers.NewSharedInformerFactory(s.client, s.opts.ResyncPeriod)
+	nsInformer := nsFactory.Core().V1().Namespaces().Informer()
+	if _, err := nsInformer.AddEventHandler(cache.ResourceEventHandlerDetailedFuncs{
+		AddFunc: func(obj interface{}, isInInitialList bool) {
+			if ns, ok := obj.(*corev1.Namespace); ok {
+				s.setNamespace(ns.Name, ns.Labels, isInInitialList)
+			}
+		},
+		UpdateFunc: func(_, newObj interface{}) {
+			if ns, ok := newObj.(*corev1.Namespace); ok {
+				s.setNamespace(ns.Name, ns.Labels, false)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if ns, ok := obj.(*corev1.Namespace); ok {
+				s.deleteNamespace(ns.Name)
+			}
+		},
+	}); err != nil {
+		return fmt.Errorf("adding namespace event handler: %w", err)
+	}
+
+	defer nsFactory.Shutdown()
+
+	nsFactory.Start(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), nsInformer.HasSynced) {
+		return nil
+	}
+	close(s.synced)
+	s.logger.Info("Namespace scope synced")
+
+	<-ctx.Done()
+	return nil
+}
+
+// handleConfigMap applies the configuration in the scope ConfigMap. An
+// invalid configuration is logged and the previous one is kept.
+func (s *Scope) handleConfigMap(obj interface{}) {
+	cm, ok := obj.(*corev1.ConfigMap)
+	if !ok || cm.Name != s.opts.ConfigMapName {
+		return
+	}
+	cfg, err := ParseConfig(cm)
+	if err != nil {
+		s.logger.Warn("Ignoring invalid namespace scope config, keeping the previous one", zap.Error(err))
+		return
+	}
+	if err := s.Update(cfg); err != nil {
+		s.logger.Warn("Failed to apply namespace scope config", zap.Error(err))
+	}
+}
@@ -0,0 +1,262 @@
+package scope
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/yaml"
+)
+
+func loadConfigMap(t *testing.T, filename string) *corev1.ConfigMap {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", filename))
+	require.NoError(t, err)
+	cm := &corev1.ConfigMap{}
+	require.NoError(t, yaml.Unmarshal(data, cm))
+	return cm
+}
+
+func scopeConfigMap(config string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "nightjar-scope", Namespace: "nightjar-system"},
+		Data:       map[string]string{ConfigKey: config},
+	}
+}
+
+func namespace(name string, nsLabels map[string]string) *corev1.Namespace {
+	return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: nsLabels}}
+}
+
+// changeRecorder collects the changes a Scope notifies.
+type changeRecorder struct {
+	mu      sync.Mutex
+	changes []Change
+}
+
+func (r *changeRecorder) record(c Change) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.changes = append(r.changes, c)
+}
+
+func (r *changeRecorder) all() []Change {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Change(nil), r.changes...)
+}
+
+func TestParseConfig(t *testing.T) {
+	cfg, err := ParseConfig(loadConfigMap(t, "scope_configmap.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, Config{
+		ExcludeNamespaces: []string{"kube-*", "ci-*"},
+		ExcludeSelector:   "tenant.example.com/sandbox=true",
+	}, cfg)
+
+	cfg, err = ParseConfig(&corev1.ConfigMap{})
+	require.NoError(t, err)
+	assert.Equal(t, Config{}, cfg, "missing key means everything is in scope")
+
+	for name, config := range map[string]string{
+		"invalid glob":     "includeNamespaces: ['team-[']",
+		"invalid selector": "includeSelector: 'a in (b'",
+		"unknown field":    "excludeNamespace: [kube-system]",
+		"invalid yaml":     "includeNamespaces: {",
+	} {
+		_, err := ParseConfig(scopeConfigMap(config))
+		assert.Error(t, err, name)
+	}
+}
+
+func TestRulesAllows(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    Config
+		ns     string
+		labels map[string]string
+		want   bool
+	}{
+		{name: "zero config", ns: "default", want: true},
+		{name: "include glob match", cfg: Config{IncludeNamespaces: []string{"team-*"}}, ns: "team-a", want: true},
+		{name: "include glob miss", cfg: Config{IncludeNamespaces: []string{"team-*"}}, ns: "default", want: false},
+		{name: "exclude glob", cfg: Config{ExcludeNamespaces: []string{"kube-*"}}, ns: "kube-system", want: false},
+		{name: "exclude wins over include", cfg: Config{IncludeNamespaces: []string{"*"}, ExcludeNamespaces: []string{"ci-*"}}, ns: "ci-1234", want: false},
+		{name: "include selector match", cfg: Config{IncludeSelector: "nightjar.io/scope=enabled"}, ns: "a", labels: map[string]string{"nightjar.io/scope": "enabled"}, want: true},
+		{name: "include selector miss", cfg: Config{IncludeSelector: "nightjar.io/scope=enabled"}, ns: "a", want: false},
+		{name: "exclude selector", cfg: Config{ExcludeSelector: "sandbox"}, ns: "a", labels: map[string]string{"sandbox": "yes"}, want: false},
+		{name: "exclude selector miss", cfg: Config{ExcludeSelector: "sandbox"}, ns: "a", want: true},
+		{name: "glob and selector", cfg: Config{IncludeNamespaces: []string{"team-*"}, IncludeSelector: "env=prod"}, ns: "team-a", labels: map[string]string{"env": "dev"}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := compile(tt.cfg)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, r.allows(tt.ns, tt.labels))
+		})
+	}
+}
+
+func TestScope_NilAndClusterScoped(t *testing.T) {
+	var s *Scope
+	assert.True(t, s.Allows("kube-system"), "nil scope allows everything")
+	assert.Equal(t, []string{"a", "b"}, s.Filter([]string{"a", "b"}))
+	assert.True(t, s.WaitForSync(context.Background()))
+	s.OnChange(func(Change) {})
+
+	s = New(fake.NewSimpleClientset(), zap.NewNop(), Options{})
+	require.NoError(t, s.Update(Config{IncludeNamespaces: []string{"team-*"}}))
+	assert.True(t, s.Allows(""), "cluster-scoped objects are always in scope")
+}
+
+func TestScope_UpdateNotifies(t *testing.T) {
+	s := New(fake.NewSimpleClientset(), zap.NewNop(), Options{})
+	rec := &changeRecorder{}
+	s.OnChange(rec.record)
+
+	s.setNamespace("default", nil, true)
+	s.setNamespace("kube-system", nil, true)
+	s.setNamespace("sandbox-1", map[string]string{"sandbox": "true"}, true)
+	assert.Empty(t, rec.all(), "initial namespaces are recorded silently")
+
+	require.NoError(t, s.Update(Config{ExcludeNamespaces: []string{"kube-*"}, ExcludeSelector: "sandbox=true"}))
+	assert.False(t, s.Allows("kube-system"))
+	assert.False(t, s.Allows("sandbox-1"))
+	assert.True(t, s.Allows("default"))
+	assert.Equal(t, []string{"default"}, s.Filter([]string{"default", "kube-system", "sandbox-1"}))
+
+	require.NoError(t, s.Update(Config{ExcludeNamespaces: []string{"kube-*"}}))
+	assert.Equal(t, []Change{
+		{Removed: []string{"kube-system", "sandbox-1"}},
+		{Added: []string{"sandbox-1"}},
+	}, rec.all())
+
+	// Relabelling a namespace moves it across the exclude selector.
+	require.NoError(t, s.Update(Config{ExcludeSelector: "sandbox=true"}))
+	s.setNamespace("default", map[string]string{"sandbox": "true"}, false)
+	assert.False(t, s.Allows("default"))
+	changes := rec.all()
+	assert.Equal(t, Change{Removed: []string{"default"}}, changes[len(changes)-1])
+
+	// An invalid configuration is rejected and the previous one kept.
+	require.Error(t, s.Update(Config{IncludeNamespaces: []string{"["}}))
+	assert.False(t, s.Allows("default"))
+}
+
+// fakePartition owns the namespaces in its set.
+type fakePartition struct {
+	mu    sync.Mutex
+	owned map[string]bool
+}
+
+func (p *fakePartition) Owns(namespace string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.owned[namespace]
+}
+
+func (p *fakePartition) set(namespaces ...string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.owned = make(map[string]bool)
+	for _, ns := range namespaces {
+		p.owned[ns] = true
+	}
+}
+
+func TestScope_Partition(t *testing.T) {
+	s := New(fake.NewSimpleClientset(), zap.NewNop(), Options{})
+	partition := &fakePartition{}
+	partition.set("team-a", "kube-system")
+	s.SetPartition(partition)
+	rec := &changeRecorder{}
+	s.OnChange(rec.record)
+	require.NoError(t, s.Update(Config{ExcludeNamespaces: []string{"kube-*"}}))
+
+	for _, ns := range []string{"team-a", "team-b", "kube-system"} {
+		s.setNamespace(ns, nil, true)
+	}
+	assert.True(t, s.Allows("team-a"))
+	assert.False(t, s.Allows("team-b"), "owned by another shard")
+	assert.False(t, s.Allows("kube-system"), "owned but excluded")
+	assert.Equal(t, []string{"team-a"}, s.Namespaces())
+
+	partition.set("team-b", "kube-system")
+	s.Repartition()
+	assert.Equal(t, []Change{{Added: []string{"team-b"}, Removed: []string{"team-a"}, Handoff: true}}, rec.all())
+	assert.Equal(t, []string{"team-b"}, s.Namespaces())
+
+	// A config change only reports the namespaces this shard owns.
+	require.NoError(t, s.Update(Config{}))
+	changes := rec.all()
+	assert.Equal(t, Change{Added: []string{"kube-system"}}, changes[len(changes)-1])
+}
+
+func TestScope_DisableConfigMap(t *testing.T) {
+	client := fake.NewSimpleClientset(namespace("default", nil), namespace("team-a", nil))
+	s := New(client, zap.NewNop(), Options{DisableConfigMap: true})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = s.Start(ctx) }()
+
+	waitCtx, waitCancel := context.WithTimeout(ctx, 5*time.Second)
+	defer waitCancel()
+	require.True(t, s.WaitForSync(waitCtx))
+	assert.Equal(t, []string{"default", "team-a"}, s.Namespaces())
+}
+
+func TestScope_StartHotReload(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		loadConfigMap(t, "scope_configmap.yaml"),
+		namespace("default", nil),
+		namespace("kube-system", nil),
+		namespace("ci-42", nil),
+		namespace("tenant-a", map[string]string{"tenant.example.com/sandbox": "true"}),
+	)
+	s := New(client, zap.NewNop(), Options{})
+	rec := &changeRecorder{}
+	s.OnChange(rec.record)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = s.Start(ctx) }()
+
+	waitCtx, waitCancel := context.WithTimeout(ctx, 5*time.Second)
+	defer waitCancel()
+	require.True(t, s.WaitForSync(waitCtx))
+
+	assert.True(t, s.Allows("default"))
+	assert.False(t, s.Allows("kube-system"))
+	assert.False(t, s.Allows("ci-42"))
+	assert.False(t, s.Allows("tenant-a"))
+	assert.Empty(t, rec.all(), "the config is read before namespaces, so startup changes nothing")
+
+	_, err := client.CoreV1().ConfigMaps("nightjar-system").Update(ctx,
+		scopeConfigMap("excludeNamespaces: [kube-*]"), metav1.UpdateOptions{})
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return s.Allows("ci-42") && s.Allows("tenant-a")
+	}, 5*time.Second, 10*time.Millisecond, "edited ConfigMap should be applied")
+
+	_, err = client.CoreV1().ConfigMaps("nightjar-system").Update(ctx,
+		scopeConfigMap("includeSelector: team"), metav1.UpdateOptions{})
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return !s.Allows("default") && !s.Allows("ci-42")
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, client.CoreV1().ConfigMaps("nightjar-system").Delete(ctx, "nightjar-scope", metav1.DeleteOptions{}))
+	assert.Eventually(t, func() bool {
+		return s.Allows("default") && s.Allows("kube-system")
+	}, 5*time.Second, 10*time.Millisecond, "deleting the ConfigMap puts everything in scope")
+	assert.NotEmpty(t, rec.all())
+}
@@ -13,7 +13,8 @@
 // The service map maintains:
 //   - Service → Port mappings: which named ports a service exposes
 //   - Pod → Service mappings: which services select a given pod (via label matching)
-//   - IP → Service mappings: reverse lookup from ClusterIP to service name
+//   - IP → Service mappings: reverse lookup from every ClusterIP of a
+//     dual-stack service, and from endpoint IPs on their target ports
 //
 // # Usage
 //
@@ -21,7 +22,7 @@
 //	go sm.Start(ctx)
 //
 //	// Resolve a port to a service name
-//	svc := sm.ResolvePort("production", "10.0.2.5", 9090)
+//	svc := sm.ResolvePort("10.0.2.5", 9090)
 //	if svc != nil {
 //	    fmt.Printf("Access to %s.%s:%d blocked\n", svc.Name, svc.Namespace, svc.Port)
 //	}
@@ -31,7 +32,8 @@
 //
 // # Synchronization
 //
-// The service map uses informers to watch Service and Endpoints objects.
-// Updates are applied atomically and queries are lock-free reads against
-// immutable snapshots.
+// The service map watches Service and discovery.k8s.io/v1 EndpointSlice
+// objects. A service's endpoints are the union of its slices, including one
+// per IP family on dual-stack clusters. Updates are applied atomically under
+// a lock.
 package servicemap
@@ -3,11 +3,15 @@ package servicemap
 import (
 	"context"
 	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
 	"sync"
 	"time"
 
 	"go.uber.org/zap"
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/watch"
@@ -22,6 +26,14 @@ type ServiceInfo struct {
 	PortName  string
 	Protocol  string
 	ClusterIP string
+
+	// TargetPort is the numeric target port, or 0 for a named target port
+	TargetPort int32
+}
+
+// String returns the service and port as "name.namespace:port".
+func (s ServiceInfo) String() string {
+	return fmt.Sprintf("%s.%s:%d", s.Name, s.Namespace, s.Port)
 }
 
 // EndpointInfo contains information about a service endpoint.
@@ -52,15 +64,18 @@ type ServiceMap struct {
 
 	// Pod endpoint IPs → service
 	endpointToService map[string]*ServiceInfo
+
+	// EndpointSlices by namespace/service name, then slice name
+	slices map[string]map[string][]EndpointInfo
 }
 
 // serviceEntry holds the parsed service data.
 type serviceEntry struct {
-	Name      string
-	Namespace string
-	ClusterIP string
-	Selector  labels.Selector
-	Ports     []ServiceInfo
+	Name       string
+	Namespace  string
+	ClusterIPs []string // one per IP family on dual-stack clusters
+	Selector   labels.Selector
+	Ports      []ServiceInfo
 }
 
 // New creates a new ServiceMap.
@@ -72,10 +87,11 @@ func New(client kubernetes.Interface, logger *zap.Logger) *ServiceMap {
 		ipToService:       make(map[string]*ServiceInfo),
 		portToServices:    make(map[string]map[int32][]*ServiceInfo),
 		endpointToService: make(map[string]*ServiceInfo),
+		slices:            make(map[string]map[string][]EndpointInfo),
 	}
 }
 
-// Start begins watching Services and Endpoints. Blocks until context is cancelled.
+// Start begins watching Services and EndpointSlices. Blocks until context is cancelled.
 func (sm *ServiceMap) Start(ctx context.Context) error {
 	sm.logger.Info("Starting service map")
 
@@ -90,7 +106,7 @@ func (sm *ServiceMap) Start(ctx context.Context) error {
 
 	go func() {
 		defer wg.Done()
-		sm.watchEndpoints(ctx)
+		sm.watchEndpointSlices(ctx)
 	}()
 
 	wg.Wait()
@@ -140,21 +156,21 @@ func (sm *ServiceMap) doWatchServices(ctx context.Context) error {
 	}
 }
 
-// watchEndpoints watches Endpoints resources and updates the map.
-func (sm *ServiceMap) watchEndpoints(ctx context.Context) {
+// watchEndpointSlices watches EndpointSlice resources and updates the map.
+func (sm *ServiceMap) watchEndpointSlices(ctx context.Context) {
 	for {
-		if err := sm.doWatchEndpoints(ctx); err != nil {
+		if err := sm.doWatchEndpointSlices(ctx); err != nil {
 			if ctx.Err() != nil {
 				return
 			}
-			sm.logger.Error("Endpoints watch failed, retrying", zap.Error(err))
+			sm.logger.Error("EndpointSlice watch failed, retrying", zap.Error(err))
 			time.Sleep(5 * time.Second)
 		}
 	}
 }
 
-func (sm *ServiceMap) doWatchEndpoints(ctx context.Context) error {
-	watcher, err := sm.client.CoreV1().Endpoints("").Watch(ctx, metav1.ListOptions{})
+func (sm *ServiceMap) doWatchEndpointSlices(ctx context.Context) error {
+	watcher, err := sm.client.DiscoveryV1().EndpointSlices("").Watch(ctx, metav1.ListOptions{})
 	if err != nil {
 		return err
 	}
@@ -168,15 +184,17 @@ func (sm *ServiceMap) doWatchEndpoints(ctx context.Context) error {
 			if !ok {
 				return nil
 			}
-			ep, ok := event.Object.(*corev1.Endpoints)
+
+			slice, ok := event.Object.(*discoveryv1.EndpointSlice)
 			if !ok {
 				continue
 			}
+
 			switch event.Type {
 			case watch.Added, watch.Modified:
-				sm.upsertEndpoints(ep)
+				sm.upsertEndpointSlice(slice)
 			case watch.Deleted:
-				sm.deleteEndpoints(ep.Namespace, ep.Name)
+				sm.deleteEndpointSlice(slice)
 			}
 		}
 	}
@@ -209,37 +227,51 @@ func (sm *ServiceMap) upsertService(svc *corev1.Service) {
 		selector = labels.SelectorFromSet(svc.Spec.Selector)
 	}
 
+	// ClusterIPs lists an address per IP family; older objects only set
+	// ClusterIP.
+	clusterIPs := svc.Spec.ClusterIPs
+	if len(clusterIPs) == 0 && svc.Spec.ClusterIP != "" {
+		clusterIPs = []string{svc.Spec.ClusterIP}
+	}
+	if len(clusterIPs) > 0 && clusterIPs[0] == corev1.ClusterIPNone {
+		clusterIPs = nil
+	}
+
 	// Build service entry
 	entry := &serviceEntry{
-		Name:      name,
-		Namespace: ns,
-		ClusterIP: svc.Spec.ClusterIP,
-		Selector:  selector,
-		Ports:     make([]ServiceInfo, 0, len(svc.Spec.Ports)),
+		Name:       name,
+		Namespace:  ns,
+		ClusterIPs: clusterIPs,
+		Selector:   selector,
+		Ports:      make([]ServiceInfo, 0, len(svc.Spec.Ports)),
 	}
 
 	for _, port := range svc.Spec.Ports {
-		info := ServiceInfo{
-			Name:      name,
-			Namespace: ns,
-			Port:      port.Port,
-			PortName:  port.Name,
-			Protocol:  string(port.Protocol),
-			ClusterIP: svc.Spec.ClusterIP,
-		}
-		entry.Ports = append(entry.Ports, info)
+		entry.Ports = append(entry.Ports, ServiceInfo{
+			Name:       name,
+			Namespace:  ns,
+			Port:       port.Port,
+			PortName:   port.Name,
+			Protocol:   string(port.Protocol),
+			ClusterIP:  svc.Spec.ClusterIP,
+			TargetPort: port.TargetPort.IntVal,
+		})
+	}
+
+	for i := range entry.Ports {
+		info := &entry.Ports[i]
 
 		// Index by port
-		sm.portToServices[ns][port.Port] = append(sm.portToServices[ns][port.Port], &info)
+		sm.portToServices[ns][info.Port] = append(sm.portToServices[ns][info.Port], info)
 
-		// Index by ClusterIP:port
-		if svc.Spec.ClusterIP != "" && svc.Spec.ClusterIP != "None" {
-			key := formatIPPort(svc.Spec.ClusterIP, port.Port)
-			sm.ipToService[key] = &info
+		// Index by ClusterIP:port for every IP family
+		for _, ip := range clusterIPs {
+			sm.ipToService[formatIPPort(ip, info.Port)] = info
 		}
 	}
 
 	sm.services[ns][name] = entry
+	sm.indexEndpointsLocked(ns, name)
 }
 
 // deleteService removes a service from the map.
@@ -253,6 +285,7 @@ func (sm *ServiceMap) deleteService(namespace, name string) {
 	if entry := sm.services[namespace][name]; entry != nil {
 		sm.removeServiceFromIndexes(entry)
 		delete(sm.services[namespace], name)
+		sm.indexEndpointsLocked(namespace, name)
 	}
 }
 
@@ -277,61 +310,101 @@ func (sm *ServiceMap) removeServiceFromIndexes(entry *serviceEntry) {
 		}
 
 		// Remove from IP index
-		if entry.ClusterIP != "" && entry.ClusterIP != "None" {
-			key := formatIPPort(entry.ClusterIP, port.Port)
-			delete(sm.ipToService, key)
+		for _, ip := range entry.ClusterIPs {
+			delete(sm.ipToService, formatIPPort(ip, port.Port))
 		}
 	}
 }
 
-// upsertEndpoints adds or updates endpoints in the map.
-func (sm *ServiceMap) upsertEndpoints(ep *corev1.Endpoints) {
-	sm.mu.Lock()
-	defer sm.mu.Unlock()
-
-	ns := ep.Namespace
-	name := ep.Name
-
-	// Get the corresponding service
-	var svc *serviceEntry
-	if sm.services[ns] != nil {
-		svc = sm.services[ns][name]
+// upsertEndpointSlice adds or updates an EndpointSlice in the map. A
+// service's endpoints may be split across several slices, and a dual-stack
+// service has separate slices per IP family.
+func (sm *ServiceMap) upsertEndpointSlice(slice *discoveryv1.EndpointSlice) {
+	service := slice.Labels[discoveryv1.LabelServiceName]
+	if service == "" {
+		return // Not managed for a service
 	}
-	if svc == nil {
-		return // No corresponding service
-	}
-
-	// Build endpoint IP → service mapping
-	for _, subset := range ep.Subsets {
-		for _, addr := range subset.Addresses {
-			for _, port := range subset.Ports {
-				// Find matching service port
-				for i := range svc.Ports {
-					if svc.Ports[i].PortName == port.Name || svc.Ports[i].Port == port.Port {
-						key := formatIPPort(addr.IP, port.Port)
-						sm.endpointToService[key] = &svc.Ports[i]
+
+	var endpoints []EndpointInfo
+	if slice.AddressType == discoveryv1.AddressTypeIPv4 || slice.AddressType == discoveryv1.AddressTypeIPv6 {
+		for _, ep := range slice.Endpoints {
+			var podName, nodeName string
+			if ep.TargetRef != nil && ep.TargetRef.Kind == "Pod" {
+				podName = ep.TargetRef.Name
+			}
+			if ep.NodeName != nil {
+				nodeName = *ep.NodeName
+			}
+			for _, ip := range ep.Addresses {
+				for _, port := range slice.Ports {
+					info := EndpointInfo{IP: ip, PodName: podName, NodeName: nodeName}
+					if port.Port != nil {
+						info.Port = *port.Port
+					}
+					if port.Name != nil {
+						info.PortName = *port.Name
 					}
+					endpoints = append(endpoints, info)
 				}
 			}
 		}
 	}
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	key := slice.Namespace + "/" + service
+	if sm.slices[key] == nil {
+		sm.slices[key] = make(map[string][]EndpointInfo)
+	}
+	sm.slices[key][slice.Name] = endpoints
+	sm.indexEndpointsLocked(slice.Namespace, service)
 }
 
-// deleteEndpoints removes endpoints from the map.
-func (sm *ServiceMap) deleteEndpoints(namespace, name string) {
+// deleteEndpointSlice removes an EndpointSlice from the map.
+func (sm *ServiceMap) deleteEndpointSlice(slice *discoveryv1.EndpointSlice) {
+	service := slice.Labels[discoveryv1.LabelServiceName]
+
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
+	key := slice.Namespace + "/" + service
+	if _, ok := sm.slices[key][slice.Name]; !ok {
+		return
+	}
+	delete(sm.slices[key], slice.Name)
+	if len(sm.slices[key]) == 0 {
+		delete(sm.slices, key)
+	}
+	sm.indexEndpointsLocked(slice.Namespace, service)
+}
+
+// indexEndpointsLocked rebuilds the endpoint index of a service from its
+// EndpointSlices. Called whenever the service or one of its slices changes,
+// since entries point at the service's current ports.
+func (sm *ServiceMap) indexEndpointsLocked(namespace, name string) {
 	// Remove all endpoint IPs for this service
-	// This is O(n) but typically endpoints are not deleted often
-	toDelete := make([]string, 0)
+	// This is O(n) but typically endpoints change far less often than they are read
 	for key, info := range sm.endpointToService {
 		if info.Namespace == namespace && info.Name == name {
-			toDelete = append(toDelete, key)
+			delete(sm.endpointToService, key)
 		}
 	}
-	for _, key := range toDelete {
-		delete(sm.endpointToService, key)
+
+	svc := sm.services[namespace][name]
+	if svc == nil {
+		return // Slices are kept until the service appears
+	}
+
+	for _, endpoints := range sm.slices[namespace+"/"+name] {
+		for _, ep := range endpoints {
+			// Find matching service port
+			for i := range svc.Ports {
+				if svc.Ports[i].PortName == ep.PortName {
+					sm.endpointToService[formatIPPort(ep.IP, ep.Port)] = &svc.Ports[i]
+				}
+			}
+		}
 	}
 }
 
@@ -417,7 +490,11 @@ func (sm *ServiceMap) ServiceCount() int {
 	return count
 }
 
-// formatIPPort creates a lookup key from IP and port.
+// formatIPPort creates a lookup key from IP and port. IPv6 addresses are
+// bracketed and normalized, so every spelling of an address finds it.
 func formatIPPort(ip string, port int32) string {
-	return fmt.Sprintf("%s:%d", ip, port)
+	if addr, err := netip.ParseAddr(ip); err == nil {
+		ip = addr.Unmap().String()
+	}
+	return net.JoinHostPort(ip, strconv.Itoa(int(port)))
 }
@@ -9,10 +9,31 @@ import (
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
 )
 
+// endpointSlice returns a slice of the service's endpoints with a single
+// port named "http".
+func endpointSlice(namespace, service, name string, addressType discoveryv1.AddressType, port int32, ips ...string) *discoveryv1.EndpointSlice {
+	slice := &discoveryv1.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    map[string]string{discoveryv1.LabelServiceName: service},
+		},
+		AddressType: addressType,
+		Ports:       []discoveryv1.EndpointPort{{Name: ptr.To("http"), Port: ptr.To(port)}},
+	}
+	for _, ip := range ips {
+		slice.Endpoints = append(slice.Endpoints, discoveryv1.Endpoint{Addresses: []string{ip}})
+	}
+	return slice
+}
+
 func TestNew(t *testing.T) {
 	sm := New(nil, zap.NewNop())
 	require.NotNil(t, sm)
@@ -159,7 +180,7 @@ func TestServicesForPod(t *testing.T) {
 	assert.Empty(t, services)
 }
 
-func TestUpsertEndpoints(t *testing.T) {
+func TestUpsertEndpointSlice(t *testing.T) {
 	sm := New(nil, zap.NewNop())
 
 	// First create the service
@@ -171,36 +192,22 @@ func TestUpsertEndpoints(t *testing.T) {
 		Spec: corev1.ServiceSpec{
 			ClusterIP: "10.0.0.50",
 			Ports: []corev1.ServicePort{
-				{Name: "http", Port: 8080},
+				{Name: "http", Port: 80, TargetPort: intstr.FromInt32(8080)},
 			},
 		},
 	}
 	sm.upsertService(svc)
 
-	// Then create endpoints
-	ep := &corev1.Endpoints{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      "backend",
-			Namespace: "production",
-		},
-		Subsets: []corev1.EndpointSubset{
-			{
-				Addresses: []corev1.EndpointAddress{
-					{IP: "10.0.1.10"},
-					{IP: "10.0.1.11"},
-				},
-				Ports: []corev1.EndpointPort{
-					{Name: "http", Port: 8080},
-				},
-			},
-		},
-	}
-	sm.upsertEndpoints(ep)
+	// Then its endpoints, split across two slices
+	sm.upsertEndpointSlice(endpointSlice("production", "backend", "backend-a", discoveryv1.AddressTypeIPv4, 8080, "10.0.1.10"))
+	sm.upsertEndpointSlice(endpointSlice("production", "backend", "backend-b", discoveryv1.AddressTypeIPv4, 8080, "10.0.1.11"))
 
-	// Should be able to resolve endpoint IPs
+	// Should be able to resolve endpoint IPs on the target port to the service port
 	info := sm.ResolvePort("10.0.1.10", 8080)
 	require.NotNil(t, info)
 	assert.Equal(t, "backend", info.Name)
+	assert.Equal(t, int32(80), info.Port)
+	assert.Equal(t, int32(8080), info.TargetPort)
 
 	info = sm.ResolvePort("10.0.1.11", 8080)
 	require.NotNil(t, info)
@@ -299,7 +306,9 @@ func TestFormatIPPort(t *testing.T) {
 	}{
 		{"10.0.0.1", 80, "10.0.0.1:80"},
 		{"192.168.1.100", 8080, "192.168.1.100:8080"},
-		{"::1", 443, "::1:443"},
+		{"::1", 443, "[::1]:443"},
+		{"fd00:0:0::a", 443, "[fd00::a]:443"},
+		{"::ffff:10.0.0.1", 80, "10.0.0.1:80"},
 	}
 
 	for _, tt := range tests {
@@ -308,7 +317,7 @@ func TestFormatIPPort(t *testing.T) {
 	}
 }
 
-func TestDeleteEndpoints(t *testing.T) {
+func TestDeleteEndpointSlice(t *testing.T) {
 	sm := New(nil, zap.NewNop())
 
 	// Create a service first
@@ -326,44 +335,25 @@ func TestDeleteEndpoints(t *testing.T) {
 	}
 	sm.upsertService(svc)
 
-	// Create endpoints
-	ep := &corev1.Endpoints{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      "backend",
-			Namespace: "production",
-		},
-		Subsets: []corev1.EndpointSubset{
-			{
-				Addresses: []corev1.EndpointAddress{
-					{IP: "10.0.1.10"},
-					{IP: "10.0.1.11"},
-				},
-				Ports: []corev1.EndpointPort{
-					{Name: "http", Port: 8080},
-				},
-			},
-		},
-	}
-	sm.upsertEndpoints(ep)
+	// Create endpoints in two slices
+	sliceA := endpointSlice("production", "backend", "backend-a", discoveryv1.AddressTypeIPv4, 8080, "10.0.1.10")
+	sliceB := endpointSlice("production", "backend", "backend-b", discoveryv1.AddressTypeIPv4, 8080, "10.0.1.11")
+	sm.upsertEndpointSlice(sliceA)
+	sm.upsertEndpointSlice(sliceB)
 
 	// Verify endpoints resolve before deletion
-	info := sm.ResolvePort("10.0.1.10", 8080)
-	require.NotNil(t, info)
-	assert.Equal(t, "backend", info.Name)
+	require.NotNil(t, sm.ResolvePort("10.0.1.10", 8080))
+	require.NotNil(t, sm.ResolvePort("10.0.1.11", 8080))
 
-	info = sm.ResolvePort("10.0.1.11", 8080)
+	// Deleting one slice leaves the other's endpoints
+	sm.deleteEndpointSlice(sliceA)
+	assert.Nil(t, sm.ResolvePort("10.0.1.10", 8080))
+	info := sm.ResolvePort("10.0.1.11", 8080)
 	require.NotNil(t, info)
 	assert.Equal(t, "backend", info.Name)
 
-	// Delete endpoints
-	sm.deleteEndpoints("production", "backend")
-
-	// Endpoint IPs should no longer resolve
-	info = sm.ResolvePort("10.0.1.10", 8080)
-	assert.Nil(t, info)
-
-	info = sm.ResolvePort("10.0.1.11", 8080)
-	assert.Nil(t, info)
+	sm.deleteEndpointSlice(sliceB)
+	assert.Nil(t, sm.ResolvePort("10.0.1.11", 8080))
 
 	// ClusterIP should still resolve (service not deleted)
 	info = sm.ResolvePort("10.0.0.50", 8080)
@@ -603,14 +593,14 @@ func TestDeleteService_WithOtherServicesOnSamePort(t *testing.T) {
 	assert.Equal(t, "service-b", info.Name)
 }
 
-func TestDeleteEndpoints_NonExistent(t *testing.T) {
+func TestDeleteEndpointSlice_NonExistent(t *testing.T) {
 	sm := New(nil, zap.NewNop())
 
 	// Delete from empty map should not panic
-	sm.deleteEndpoints("nonexistent-ns", "nonexistent-ep")
+	sm.deleteEndpointSlice(endpointSlice("nonexistent-ns", "nonexistent", "nonexistent-abc", discoveryv1.AddressTypeIPv4, 8080))
 	assert.Equal(t, 0, sm.ServiceCount())
 
-	// Add a service with endpoints, then delete endpoints for a different service
+	// Add a service with endpoints, then delete slices of a different service
 	svc := &corev1.Service{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      "backend",
@@ -624,28 +614,11 @@ func TestDeleteEndpoints_NonExistent(t *testing.T) {
 		},
 	}
 	sm.upsertService(svc)
+	sm.upsertEndpointSlice(endpointSlice("production", "backend", "backend-abc", discoveryv1.AddressTypeIPv4, 8080, "10.0.1.10"))
 
-	ep := &corev1.Endpoints{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      "backend",
-			Namespace: "production",
-		},
-		Subsets: []corev1.EndpointSubset{
-			{
-				Addresses: []corev1.EndpointAddress{
-					{IP: "10.0.1.10"},
-				},
-				Ports: []corev1.EndpointPort{
-					{Name: "http", Port: 8080},
-				},
-			},
-		},
-	}
-	sm.upsertEndpoints(ep)
-
-	// Delete endpoints for a different service - should not affect existing
-	sm.deleteEndpoints("production", "frontend")
-	sm.deleteEndpoints("staging", "backend")
+	// Delete slices of a different service - should not affect existing
+	sm.deleteEndpointSlice(endpointSlice("production", "frontend", "frontend-abc", discoveryv1.AddressTypeIPv4, 8080))
+	sm.deleteEndpointSlice(endpointSlice("staging", "backend", "backend-abc", discoveryv1.AddressTypeIPv4, 8080))
 
 	// Original endpoints should still resolve
 	info := sm.ResolvePort("10.0.1.10", 8080)
@@ -653,33 +626,84 @@ func TestDeleteEndpoints_NonExistent(t *testing.T) {
 	assert.Equal(t, "backend", info.Name)
 }
 
-// TestUpsertEndpoints_NoCorrespondingService covers the early-return branch in
-// upsertEndpoints when no matching service exists in the map.
-func TestUpsertEndpoints_NoCorrespondingService(t *testing.T) {
+// TestUpsertEndpointSlice_BeforeService covers slices that arrive before
+// their service: they are kept and indexed once the service appears, and
+// unindexed again when it is deleted.
+func TestUpsertEndpointSlice_BeforeService(t *testing.T) {
 	sm := New(nil, zap.NewNop())
 
-	// Upsert endpoints without a corresponding service
-	ep := &corev1.Endpoints{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      "orphan-ep",
-			Namespace: "default",
+	sm.upsertEndpointSlice(endpointSlice("default", "late-svc", "late-svc-abc", discoveryv1.AddressTypeIPv4, 8080, "10.0.5.1"))
+
+	// Endpoint IP should not resolve because there is no corresponding service
+	assert.Nil(t, sm.ResolvePort("10.0.5.1", 8080))
+
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "late-svc", Namespace: "default"},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{{Name: "http", Port: 8080}},
 		},
-		Subsets: []corev1.EndpointSubset{
-			{
-				Addresses: []corev1.EndpointAddress{
-					{IP: "10.0.5.1"},
-				},
-				Ports: []corev1.EndpointPort{
-					{Name: "http", Port: 8080},
-				},
-			},
+	}
+	sm.upsertService(svc)
+	info := sm.ResolvePort("10.0.5.1", 8080)
+	require.NotNil(t, info)
+	assert.Equal(t, "late-svc", info.Name)
+
+	sm.deleteService("default", "late-svc")
+	assert.Nil(t, sm.ResolvePort("10.0.5.1", 8080))
+}
+
+func TestUpsertEndpointSlice_IgnoresUnmanagedAndFQDN(t *testing.T) {
+	sm := New(nil, zap.NewNop())
+	sm.upsertService(&corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "backend", Namespace: "default"},
+		Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "http", Port: 8080}}},
+	})
+
+	unmanaged := endpointSlice("default", "backend", "custom", discoveryv1.AddressTypeIPv4, 8080, "10.0.5.1")
+	unmanaged.Labels = nil
+	sm.upsertEndpointSlice(unmanaged)
+	sm.upsertEndpointSlice(endpointSlice("default", "backend", "backend-fqdn", discoveryv1.AddressTypeFQDN, 8080, "backend.example.com"))
+
+	assert.Nil(t, sm.ResolvePort("10.0.5.1", 8080))
+	assert.Nil(t, sm.ResolvePort("backend.example.com", 8080))
+}
+
+func TestDualStackService(t *testing.T) {
+	sm := New(nil, zap.NewNop())
+
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "payments-api", Namespace: "payments"},
+		Spec: corev1.ServiceSpec{
+			ClusterIP:  "10.0.0.60",
+			ClusterIPs: []string{"10.0.0.60", "fd00:10:96::60"},
+			IPFamilies: []corev1.IPFamily{corev1.IPv4Protocol, corev1.IPv6Protocol},
+			Ports:      []corev1.ServicePort{{Name: "http", Port: 443, TargetPort: intstr.FromInt32(8443)}},
 		},
 	}
-	sm.upsertEndpoints(ep)
+	sm.upsertService(svc)
+	sm.upsertEndpointSlice(endpointSlice("payments", "payments-api", "payments-api-v4", discoveryv1.AddressTypeIPv4, 8443, "10.0.1.20"))
+	sm.upsertEndpointSlice(endpointSlice("payments", "payments-api", "payments-api-v6", discoveryv1.AddressTypeIPv6, 8443, "fd00:10:244::20"))
 
-	// Endpoint IP should not resolve because there is no corresponding service
-	info := sm.ResolvePort("10.0.5.1", 8080)
-	assert.Nil(t, info)
+	for _, tt := range []struct {
+		ip   string
+		port int32
+	}{
+		{"10.0.0.60", 443},
+		{"fd00:10:96::60", 443},
+		{"fd00:10:96:0:0:0:0:60", 443},
+		{"10.0.1.20", 8443},
+		{"fd00:10:244::20", 8443},
+	} {
+		info := sm.ResolvePort(tt.ip, tt.port)
+		require.NotNil(t, info, tt.ip)
+		assert.Equal(t, "payments-api.payments:443", info.String())
+	}
+
+	// Removing the IPv6 family unindexes its ClusterIP
+	svc.Spec.ClusterIPs = svc.Spec.ClusterIPs[:1]
+	sm.upsertService(svc)
+	assert.Nil(t, sm.ResolvePort("fd00:10:96::60", 443))
+	assert.NotNil(t, sm.ResolvePort("fd00:10:244::20", 8443))
 }
 
 // TestDoWatchServices verifies that doWatchServices picks up Service Add, Modify,
@@ -763,13 +787,13 @@ func TestDoWatchServices(t *testing.T) {
 	}
 }
 
-// TestDoWatchEndpoints verifies that doWatchEndpoints picks up Endpoints Add,
-// Modify, and Delete events from the fake Kubernetes client.
-func TestDoWatchEndpoints(t *testing.T) {
+// TestDoWatchEndpointSlices verifies that doWatchEndpointSlices picks up
+// EndpointSlice Add, Modify, and Delete events from the fake Kubernetes client.
+func TestDoWatchEndpointSlices(t *testing.T) {
 	client := fake.NewSimpleClientset()
 	sm := New(client, zap.NewNop())
 
-	// Pre-create a service so that upsertEndpoints has something to match against
+	// Pre-create a service so that the slices have something to match against
 	svc := &corev1.Service{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      "ep-svc",
@@ -789,29 +813,14 @@ func TestDoWatchEndpoints(t *testing.T) {
 
 	errCh := make(chan error, 1)
 	go func() {
-		errCh <- sm.doWatchEndpoints(ctx)
+		errCh <- sm.doWatchEndpointSlices(ctx)
 	}()
 
 	time.Sleep(100 * time.Millisecond)
 
-	// Create endpoints via the fake client
-	ep := &corev1.Endpoints{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      "ep-svc",
-			Namespace: "test-ns",
-		},
-		Subsets: []corev1.EndpointSubset{
-			{
-				Addresses: []corev1.EndpointAddress{
-					{IP: "10.0.2.10"},
-				},
-				Ports: []corev1.EndpointPort{
-					{Name: "http", Port: 9090},
-				},
-			},
-		},
-	}
-	_, err := client.CoreV1().Endpoints("test-ns").Create(ctx, ep, metav1.CreateOptions{})
+	// Create a slice via the fake client
+	slice := endpointSlice("test-ns", "ep-svc", "ep-svc-abc", discoveryv1.AddressTypeIPv4, 9090, "10.0.2.10")
+	_, err := client.DiscoveryV1().EndpointSlices("test-ns").Create(ctx, slice, metav1.CreateOptions{})
 	require.NoError(t, err)
 
 	time.Sleep(200 * time.Millisecond)
@@ -821,28 +830,26 @@ func TestDoWatchEndpoints(t *testing.T) {
 	require.NotNil(t, info)
 	assert.Equal(t, "ep-svc", info.Name)
 
-	// Modify endpoints: add a second address
-	ep.Subsets[0].Addresses = append(ep.Subsets[0].Addresses, corev1.EndpointAddress{IP: "10.0.2.11"})
-	_, err = client.CoreV1().Endpoints("test-ns").Update(ctx, ep, metav1.UpdateOptions{})
+	// Modify the slice: replace the address
+	slice.Endpoints = []discoveryv1.Endpoint{{Addresses: []string{"10.0.2.11"}}}
+	_, err = client.DiscoveryV1().EndpointSlices("test-ns").Update(ctx, slice, metav1.UpdateOptions{})
 	require.NoError(t, err)
 
 	time.Sleep(200 * time.Millisecond)
 
+	assert.Nil(t, sm.ResolvePort("10.0.2.10", 9090))
 	info = sm.ResolvePort("10.0.2.11", 9090)
 	require.NotNil(t, info)
 	assert.Equal(t, "ep-svc", info.Name)
 
-	// Delete endpoints
-	err = client.CoreV1().Endpoints("test-ns").Delete(ctx, "ep-svc", metav1.DeleteOptions{})
+	// Delete the slice
+	err = client.DiscoveryV1().EndpointSlices("test-ns").Delete(ctx, "ep-svc-abc", metav1.DeleteOptions{})
 	require.NoError(t, err)
 
 	time.Sleep(200 * time.Millisecond)
 
 	// Endpoint IPs should no longer resolve
-	info = sm.ResolvePort("10.0.2.10", 9090)
-	assert.Nil(t, info)
-	info = sm.ResolvePort("10.0.2.11", 9090)
-	assert.Nil(t, info)
+	assert.Nil(t, sm.ResolvePort("10.0.2.11", 9090))
 
 	cancel()
 
@@ -850,7 +857,7 @@ func TestDoWatchEndpoints(t *testing.T) {
 	case watchErr := <-errCh:
 		assert.ErrorIs(t, watchErr, context.Canceled)
 	case <-time.After(2 * time.Second):
-		t.Fatal("doWatchEndpoints did not return after context cancellation")
+		t.Fatal("doWatchEndpointSlices did not return after context cancellation")
 	}
 }
 
@@ -895,23 +902,8 @@ func TestStart(t *testing.T) {
 	assert.Equal(t, "start-svc", info.Name)
 
 	// Create endpoints for the service
-	ep := &corev1.Endpoints{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      "start-svc",
-			Namespace: "default",
-		},
-		Subsets: []corev1.EndpointSubset{
-			{
-				Addresses: []corev1.EndpointAddress{
-					{IP: "10.0.3.10"},
-				},
-				Ports: []corev1.EndpointPort{
-					{Name: "http", Port: 80},
-				},
-			},
-		},
-	}
-	_, err = client.CoreV1().Endpoints("default").Create(ctx, ep, metav1.CreateOptions{})
+	slice := endpointSlice("default", "start-svc", "start-svc-abc", discoveryv1.AddressTypeIPv4, 80, "10.0.3.10")
+	_, err = client.DiscoveryV1().EndpointSlices("default").Create(ctx, slice, metav1.CreateOptions{})
 	require.NoError(t, err)
 
 	time.Sleep(200 * time.Millisecond)
@@ -0,0 +1,239 @@
+package shard
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ForwardedHeader marks a request one member forwarded to another. Such
+// requests are always served locally, so a disagreement about ownership
+// during a rebalance cannot loop.
+const ForwardedHeader = "X-Nightjar-Shard-Forwarded"
+
+// ErrNoOwner is returned by Relay for a namespace no member owns yet, e.g.
+// while the group forms or before a leader is elected.
+var ErrNoOwner = errors.New("no member owns the namespace")
+
+// Router is the view of the shard group a Forwarder routes by.
+// *Membership implements it.
+type Router interface {
+	Self() Member
+	Owner(namespace string) (Member, bool)
+	Members() []Member
+}
+
+// Forwarder routes queries of an HTTP API to the members that can answer
+// them: queries about one namespace go to its owner, cluster-wide queries
+// to every member. It also relays items a replica received but does not own,
+// e.g. audit events posted to any replica behind a Service, to their owner.
+// A nil *Forwarder serves everything locally, so handlers can hold one
+// unconditionally.
+type Forwarder struct {
+	router Router
+	port   int
+	client *http.Client
+	logger *zap.Logger
+
+	// tokenFile, when set, holds the bearer token items are relayed with.
+	tokenFile string
+}
+
+// NewForwarder creates a Forwarder for the API served on port by every
+// member.
+func NewForwarder(router Router, port int, logger *zap.Logger) *Forwarder {
+	return &Forwarder{
+		router: router,
+		port:   port,
+		client: &http.Client{Timeout: 5 * time.Second},
+		logger: logger.Named("shard-forwarder"),
+	}
+}
+
+// SetTokenFile makes Relay present the token in path, re-read on every
+// request as the kubelet rotates it, e.g. to a RelayServer. Call it before
+// the Forwarder is used.
+func (f *Forwarder) SetTokenFile(path string) {
+	f.tokenFile = path
+}
+
+// Forward proxies r to the owner of namespace and reports whether it did.
+// body is sent in place of r's body, which the caller may have consumed to
+// find the namespace. Forwarded requests, and namespaces this replica owns
+// or that no member owns yet, are not proxied.
+func (f *Forwarder) Forward(w http.ResponseWriter, r *http.Request, namespace string, body []byte) bool {
+	if f == nil || namespace == "" || Forwarded(r) {
+		return false
+	}
+	owner, ok := f.router.Owner(namespace)
+	if !ok || owner.Identity == f.router.Self().Identity {
+		return false
+	}
+
+	resp, err := f.send(r, owner, body)
+	if err != nil {
+		f.logger.Warn("Failed to forward query to owning shard",
+			zap.String("namespace", namespace),
+			zap.String("owner", owner.Identity),
+			zap.Error(err))
+		http.Error(w, fmt.Sprintf("shard %s owning namespace %s is unreachable", owner.Identity, namespace), http.StatusBadGateway)
+		return true
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "" {
+		w.Header().Set("Content-Type", ct)
+	}
+	w.WriteHeader(resp.StatusCode)
+	_, _ = io.Copy(w, resp.Body)
+	return true
+}
+
+// Gather sends r to every other member and returns their response bodies.
+// It returns nil for forwarded requests. A member that fails or answers
+// with an error status fails the whole query: a partial cluster-wide answer
+// would look complete.
+func (f *Forwarder) Gather(r *http.Request) ([][]byte, error) {
+	if f == nil || Forwarded(r) {
+		return nil, nil
+	}
+	self := f.router.Self().Identity
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		bodies   [][]byte
+		firstErr error
+	)
+	for _, member := range f.router.Members() {
+		if member.Identity == self {
+			continue
+		}
+		wg.Add(1)
+		go func(member Member) {
+			defer wg.Done()
+			body, err := f.fetch(r, member)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("shard %s: %w", member.Identity, err)
+				}
+				return
+			}
+			bodies = append(bodies, body)
+		}(member)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return bodies, nil
+}
+
+// Relay posts body to path on every other member owning one of namespaces,
+// once per member, and reports whether this replica owns any of them. No
+// namespaces stands for the cluster scope, "". A nil *Forwarder owns
+// everything. The error reports members that were unreachable or answered
+// with an error status, and namespaces without an owner (ErrNoOwner).
+func (f *Forwarder) Relay(ctx context.Context, path string, body []byte, namespaces ...string) (bool, error) {
+	if f == nil {
+		return true, nil
+	}
+	if len(namespaces) == 0 {
+		namespaces = []string{""}
+	}
+	self := f.router.Self().Identity
+	local := false
+	owners := make(map[string]Member)
+	var errs []error
+	for _, namespace := range namespaces {
+		owner, ok := f.router.Owner(namespace)
+		switch {
+		case !ok:
+			errs = append(errs, fmt.Errorf("namespace %q: %w", namespace, ErrNoOwner))
+		case owner.Identity == self:
+			local = true
+		default:
+			owners[owner.Identity] = owner
+		}
+	}
+	for _, owner := range owners {
+		if err := f.post(ctx, owner, path, body); err != nil {
+			errs = append(errs, fmt.Errorf("shard %s: %w", owner.Identity, err))
+		}
+	}
+	return local, errors.Join(errs...)
+}
+
+// Forwarded reports whether r was forwarded by another member.
+func Forwarded(r *http.Request) bool {
+	return r.Header.Get(ForwardedHeader) != ""
+}
+
+func (f *Forwarder) fetch(r *http.Request, member Member) ([]byte, error) {
+	resp, err := f.send(r, member, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return body, nil
+}
+
+// post sends body as JSON to path on member's API.
+func (f *Forwarder) post(ctx context.Context, member Member, path string, body []byte) error {
+	url := "http://" + net.JoinHostPort(member.Host, strconv.Itoa(f.port)) + path
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(ForwardedHeader, f.router.Self().Identity)
+	if f.tokenFile != "" {
+		token, err := readToken(f.tokenFile)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// send replays r against member's API.
+func (f *Forwarder) send(r *http.Request, member Member, body []byte) (*http.Response, error) {
+	url := "http://" + net.JoinHostPort(member.Host, strconv.Itoa(f.port)) + r.URL.RequestURI()
+	req, err := http.NewRequestWithContext(r.Context(), r.Method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range []string{"Authorization", "Content-Type", "Accept"} {
+		if v := r.Header.Get(h); v != "" {
+			req.Header.Set(h, v)
+		}
+	}
+	req.Header.Set(ForwardedHeader, f.router.Self().Identity)
+	return f.client.Do(req)
+}
@@ -0,0 +1,72 @@
+package shard
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// staticRouter assigns namespaces to members by a fixed table.
+type staticRouter struct {
+	self   Member
+	owners map[string]Member
+}
+
+func (r staticRouter) Self() Member { return r.self }
+
+func (r staticRouter) Owner(namespace string) (Member, bool) {
+	m, ok := r.owners[namespace]
+	return m, ok
+}
+
+func (r staticRouter) Members() []Member { return nil }
+
+func TestForwarder_Relay(t *testing.T) {
+	var mu sync.Mutex
+	var received []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received = append(received, r.URL.Path+" "+string(body)+" "+r.Header.Get(ForwardedHeader))
+		mu.Unlock()
+	}))
+	defer srv.Close()
+	host, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+	require.NoError(t, err)
+	portNum, err := strconv.Atoi(port)
+	require.NoError(t, err)
+
+	self, other := Member{Identity: "a"}, Member{Identity: "b", Host: host}
+	f := NewForwarder(staticRouter{self: self, owners: map[string]Member{
+		"mine": self, "theirs": other, "also-theirs": other,
+	}}, portNum, zap.NewNop())
+	ctx := context.Background()
+
+	local, err := f.Relay(ctx, "/relay", []byte(`{}`), "mine")
+	assert.NoError(t, err)
+	assert.True(t, local)
+	assert.Empty(t, received)
+
+	local, err = f.Relay(ctx, "/relay", []byte(`{}`), "theirs", "also-theirs", "mine")
+	assert.NoError(t, err)
+	assert.True(t, local)
+	assert.Equal(t, []string{"/relay {} a"}, received, "posted once per member")
+
+	local, err = f.Relay(ctx, "/relay", []byte(`{}`), "unowned")
+	assert.ErrorIs(t, err, ErrNoOwner)
+	assert.False(t, local)
+
+	var nilForwarder *Forwarder
+	local, err = nilForwarder.Relay(ctx, "/relay", nil, "theirs")
+	assert.NoError(t, err)
+	assert.True(t, local, "a nil Forwarder owns everything")
+}
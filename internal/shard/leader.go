@@ -0,0 +1,91 @@
+package shard
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// leaderCacheTTL is how long Leader answers from the Lease it last read.
+const leaderCacheTTL = 5 * time.Second
+
+// Leader is a Router that assigns every namespace to the replica holding
+// the leader election Lease, so components that run on every replica can
+// hand work only the leader does to it. controller-runtime sets the holder
+// identity to <pod name>_<uuid>; the leader is reached at that pod's IP.
+type Leader struct {
+	client    kubernetes.Interface
+	namespace string
+	name      string
+	self      Member
+	now       func() time.Time
+
+	mu     sync.Mutex
+	leader Member
+	found  bool
+	readAt time.Time
+}
+
+// NewLeader creates a Leader following the Lease namespace/name. self is
+// this replica, identified by its pod name.
+func NewLeader(client kubernetes.Interface, namespace, name string, self Member) *Leader {
+	return &Leader{
+		client:    client,
+		namespace: namespace,
+		name:      name,
+		self:      self,
+		now:       time.Now,
+	}
+}
+
+// Self returns this replica.
+func (l *Leader) Self() Member {
+	return l.self
+}
+
+// Owner returns the leader, whatever the namespace. ok is false while no
+// replica holds an unexpired Lease or its pod has no IP yet. Concurrent
+// callers wait for one read of the Lease rather than each reading it.
+func (l *Leader) Owner(string) (Member, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if now := l.now(); l.readAt.IsZero() || now.Sub(l.readAt) >= leaderCacheTTL {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		l.leader, l.found = l.lookup(ctx)
+		cancel()
+		l.readAt = now
+	}
+	return l.leader, l.found
+}
+
+// Members returns the leader, if there is one.
+func (l *Leader) Members() []Member {
+	if leader, ok := l.Owner(""); ok {
+		return []Member{leader}
+	}
+	return nil
+}
+
+// lookup reads the Lease and resolves its holder to a member.
+func (l *Leader) lookup(ctx context.Context) (Member, bool) {
+	lease, err := l.client.CoordinationV1().Leases(l.namespace).Get(ctx, l.name, metav1.GetOptions{})
+	if err != nil || lease.Spec.HolderIdentity == nil || expired(lease, l.now()) {
+		return Member{}, false
+	}
+	podName, _, _ := strings.Cut(*lease.Spec.HolderIdentity, "_")
+	if podName == "" {
+		return Member{}, false
+	}
+	if podName == l.self.Identity {
+		return l.self, true
+	}
+	pod, err := l.client.CoreV1().Pods(l.namespace).Get(ctx, podName, metav1.GetOptions{})
+	if err != nil || pod.Status.PodIP == "" {
+		return Member{}, false
+	}
+	return Member{Identity: podName, Host: pod.Status.PodIP}, true
+}
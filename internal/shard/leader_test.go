@@ -0,0 +1,66 @@
+package shard
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func leaderLease(holder string, renewed time.Time) *coordinationv1.Lease {
+	duration := int32(15)
+	renew := metav1.NewMicroTime(renewed)
+	return &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{Name: "nightjar-leader", Namespace: "nightjar-system"},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       &holder,
+			LeaseDurationSeconds: &duration,
+			RenewTime:            &renew,
+		},
+	}
+}
+
+func TestLeader_Owner(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "nightjar-b", Namespace: "nightjar-system"},
+		Status:     corev1.PodStatus{PodIP: "10.0.0.2"},
+	}
+	client := fake.NewSimpleClientset(leaderLease("nightjar-b_6f1c", time.Now()), pod)
+	self := Member{Identity: "nightjar-a", Host: "10.0.0.1"}
+	l := NewLeader(client, "nightjar-system", "nightjar-leader", self)
+
+	owner, ok := l.Owner("shop")
+	assert.True(t, ok)
+	assert.Equal(t, Member{Identity: "nightjar-b", Host: "10.0.0.2"}, owner)
+	assert.Equal(t, []Member{owner}, l.Members())
+
+	// The Lease is re-read once the cached answer is old.
+	_, err := client.CoordinationV1().Leases("nightjar-system").Update(context.Background(),
+		leaderLease("nightjar-a_0b2e", time.Now()), metav1.UpdateOptions{})
+	assert.NoError(t, err)
+	owner, _ = l.Owner("shop")
+	assert.Equal(t, "nightjar-b", owner.Identity, "answers from the cache")
+	l.now = func() time.Time { return time.Now().Add(leaderCacheTTL) }
+	owner, ok = l.Owner("shop")
+	assert.True(t, ok)
+	assert.Equal(t, self, owner)
+}
+
+func TestLeader_NoLeader(t *testing.T) {
+	self := Member{Identity: "nightjar-a"}
+	_, ok := NewLeader(fake.NewSimpleClientset(), "nightjar-system", "nightjar-leader", self).Owner("shop")
+	assert.False(t, ok, "no Lease")
+
+	client := fake.NewSimpleClientset(leaderLease("nightjar-b_6f1c", time.Now().Add(-time.Minute)))
+	_, ok = NewLeader(client, "nightjar-system", "nightjar-leader", self).Owner("shop")
+	assert.False(t, ok, "expired Lease")
+
+	client = fake.NewSimpleClientset(leaderLease("nightjar-b_6f1c", time.Now()))
+	_, ok = NewLeader(client, "nightjar-system", "nightjar-leader", self).Owner("shop")
+	assert.False(t, ok, "the leader's pod is unknown")
+}
@@ -0,0 +1,361 @@
+// Package shard partitions namespaces across controller replicas for clusters
+// too large for one process to watch.
+//
+// Every replica of a shard group holds a Lease labelled with the group name
+// and renews it periodically. The replicas with an unexpired Lease form a
+// consistent-hash Ring over namespace names; each replica watches, indexes
+// and acts on only the namespaces it owns, and forwards queries about other
+// namespaces to their owner at the host recorded on its Lease.
+package shard
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// GroupLabel marks the Leases of a shard group with the group name.
+	GroupLabel = "nightjar.io/shard-group"
+
+	// HostAnnotation holds the host other members reach a member at.
+	HostAnnotation = "nightjar.io/shard-host"
+)
+
+// Member is one replica of a shard group.
+type Member struct {
+	// Identity is unique within the group, e.g. the pod name.
+	Identity string
+
+	// Host other members reach this member at, e.g. its pod IP.
+	Host string
+}
+
+// Options configures a Membership.
+type Options struct {
+	// Namespace holds the Leases. Default: "nightjar-system".
+	Namespace string
+
+	// Group names the shard group. Default: "nightjar".
+	Group string
+
+	// Identity of this replica, unique within the group. Required.
+	Identity string
+
+	// Host other members forward queries to, e.g. the pod IP.
+	Host string
+
+	// LeaseDuration is how long a member stays in the group without
+	// renewing its Lease. Default: 30s.
+	LeaseDuration time.Duration
+
+	// RenewInterval is how often the Lease is renewed and the group
+	// re-read. Default: 10s.
+	RenewInterval time.Duration
+
+	// VirtualNodes per member on the ring. Default: DefaultVirtualNodes.
+	VirtualNodes int
+}
+
+// DefaultOptions returns sensible defaults.
+func DefaultOptions() Options {
+	return Options{
+		Namespace:     "nightjar-system",
+		Group:         "nightjar",
+		LeaseDuration: 30 * time.Second,
+		RenewInterval: 10 * time.Second,
+		VirtualNodes:  DefaultVirtualNodes,
+	}
+}
+
+// Membership maintains this replica's Lease and the ring of live members.
+type Membership struct {
+	client kubernetes.Interface
+	logger *zap.Logger
+	opts   Options
+	now    func() time.Time
+
+	renewed time.Time // last successful renewal; used by sync only
+
+	mu        sync.RWMutex
+	ring      *Ring
+	members   map[string]Member
+	listeners []func()
+	synced    chan struct{} // closed once the group was first read
+	syncOnce  sync.Once
+}
+
+// New creates a Membership. It owns no namespaces until Start has read the
+// group.
+func New(client kubernetes.Interface, logger *zap.Logger, opts Options) *Membership {
+	defaults := DefaultOptions()
+	if opts.Namespace == "" {
+		opts.Namespace = defaults.Namespace
+	}
+	if opts.Group == "" {
+		opts.Group = defaults.Group
+	}
+	if opts.LeaseDuration == 0 {
+		opts.LeaseDuration = defaults.LeaseDuration
+	}
+	if opts.RenewInterval == 0 {
+		opts.RenewInterval = defaults.RenewInterval
+	}
+	if opts.VirtualNodes == 0 {
+		opts.VirtualNodes = defaults.VirtualNodes
+	}
+	return &Membership{
+		client:  client,
+		logger:  logger.Named("shard"),
+		opts:    opts,
+		now:     time.Now,
+		ring:    NewRing(nil, opts.VirtualNodes),
+		members: make(map[string]Member),
+		synced:  make(chan struct{}),
+	}
+}
+
+// Self returns this replica.
+func (m *Membership) Self() Member {
+	return Member{Identity: m.opts.Identity, Host: m.opts.Host}
+}
+
+// OnChange registers fn to be called after the set of members changed.
+// It runs on the renew goroutine and must not block. Register listeners
+// before Start.
+func (m *Membership) OnChange(fn func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.listeners = append(m.listeners, fn)
+}
+
+// Owner returns the member that owns namespace. ok is false until the
+// group has been read.
+func (m *Membership) Owner(namespace string) (Member, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	member, ok := m.members[m.ring.Owner(namespace)]
+	return member, ok
+}
+
+// Owns reports whether this replica owns namespace.
+func (m *Membership) Owns(namespace string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.ring.Owner(namespace) == m.opts.Identity
+}
+
+// Members returns the live members, sorted by identity.
+func (m *Membership) Members() []Member {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make([]Member, 0, len(m.members))
+	for _, member := range m.members {
+		out = append(out, member)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Identity < out[j].Identity })
+	return out
+}
+
+// WaitForSync blocks until the group has been read, or until ctx is
+// cancelled. Returns false if ctx was cancelled first.
+func (m *Membership) WaitForSync(ctx context.Context) bool {
+	select {
+	case <-m.synced:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Start renews this replica's Lease and re-reads the group every
+// RenewInterval. The Lease is deleted when ctx is cancelled, so the other
+// members take over its namespaces without waiting for it to expire.
+// Blocks until ctx is cancelled.
+func (m *Membership) Start(ctx context.Context) error {
+	if m.opts.Identity == "" {
+		return fmt.Errorf("shard membership: identity is required")
+	}
+	m.logger.Info("Joining shard group",
+		zap.String("group", m.opts.Group),
+		zap.String("identity", m.opts.Identity),
+		zap.String("host", m.opts.Host))
+
+	ticker := time.NewTicker(m.opts.RenewInterval)
+	defer ticker.Stop()
+	for {
+		if err := m.sync(ctx); err != nil && ctx.Err() == nil {
+			m.logger.Warn("Failed to sync shard membership", zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			leaveCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			err := m.client.CoordinationV1().Leases(m.opts.Namespace).Delete(leaveCtx, m.leaseName(), metav1.DeleteOptions{})
+			if err != nil && !apierrors.IsNotFound(err) {
+				m.logger.Warn("Failed to release shard lease", zap.Error(err))
+			}
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// sync renews this replica's Lease, reads the group and rebuilds the ring
+// if its members changed. Once renewals have failed for LeaseDuration, the
+// other members consider this replica gone and take over its namespaces, so
+// it drops itself from the ring too, even if the group cannot be read.
+func (m *Membership) sync(ctx context.Context) error {
+	renewErr := m.renew(ctx)
+	now := m.now()
+	if renewErr == nil {
+		m.renewed = now
+	}
+	joined := now.Sub(m.renewed) < m.opts.LeaseDuration
+	if renewErr != nil && (joined || m.renewed.IsZero()) {
+		return renewErr
+	}
+
+	leases, err := m.client.CoordinationV1().Leases(m.opts.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set{GroupLabel: m.opts.Group}).String(),
+	})
+	if err != nil && renewErr == nil {
+		return fmt.Errorf("listing shard leases: %w", err)
+	}
+
+	members := map[string]Member{}
+	if err != nil {
+		// Keep the members last read, without this replica.
+		m.mu.RLock()
+		for identity, member := range m.members {
+			members[identity] = member
+		}
+		m.mu.RUnlock()
+	} else {
+		for i := range leases.Items {
+			lease := &leases.Items[i]
+			if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity == "" || expired(lease, now) {
+				continue
+			}
+			identity := *lease.Spec.HolderIdentity
+			members[identity] = Member{Identity: identity, Host: lease.Annotations[HostAnnotation]}
+		}
+	}
+	delete(members, m.opts.Identity)
+	if joined {
+		members[m.opts.Identity] = m.Self()
+	}
+
+	m.mu.Lock()
+	changed := !sameMembers(m.members, members)
+	if changed {
+		identities := make([]string, 0, len(members))
+		for identity := range members {
+			identities = append(identities, identity)
+		}
+		m.ring = NewRing(identities, m.opts.VirtualNodes)
+		m.members = members
+	}
+	listeners := append([]func(){}, m.listeners...)
+	m.mu.Unlock()
+
+	first := false
+	m.syncOnce.Do(func() {
+		first = true
+		close(m.synced)
+	})
+	if changed {
+		m.logger.Info("Shard membership changed", zap.Int("members", len(members)), zap.Bool("self", joined))
+		// The initial ring is in place before anything reads it.
+		if !first {
+			for _, fn := range listeners {
+				fn()
+			}
+		}
+	}
+	return renewErr
+}
+
+// renew creates or renews this replica's Lease.
+func (m *Membership) renew(ctx context.Context) error {
+	leases := m.client.CoordinationV1().Leases(m.opts.Namespace)
+	now := metav1.NewMicroTime(m.now())
+	duration := int32(m.opts.LeaseDuration / time.Second)
+
+	lease, err := leases.Get(ctx, m.leaseName(), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		lease = &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        m.leaseName(),
+				Namespace:   m.opts.Namespace,
+				Labels:      map[string]string{GroupLabel: m.opts.Group},
+				Annotations: map[string]string{HostAnnotation: m.opts.Host},
+			},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &m.opts.Identity,
+				LeaseDurationSeconds: &duration,
+				AcquireTime:          &now,
+				RenewTime:            &now,
+			},
+		}
+		if _, err := leases.Create(ctx, lease, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("creating shard lease: %w", err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("getting shard lease: %w", err)
+	}
+
+	if lease.Labels == nil {
+		lease.Labels = make(map[string]string)
+	}
+	if lease.Annotations == nil {
+		lease.Annotations = make(map[string]string)
+	}
+	lease.Labels[GroupLabel] = m.opts.Group
+	lease.Annotations[HostAnnotation] = m.opts.Host
+	lease.Spec.HolderIdentity = &m.opts.Identity
+	lease.Spec.LeaseDurationSeconds = &duration
+	lease.Spec.RenewTime = &now
+	if _, err := leases.Update(ctx, lease, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("renewing shard lease: %w", err)
+	}
+	return nil
+}
+
+// leaseName is the name of this replica's Lease.
+func (m *Membership) leaseName() string {
+	return m.opts.Group + "-shard-" + m.opts.Identity
+}
+
+// expired reports whether lease was last renewed longer than its duration
+// ago.
+func expired(lease *coordinationv1.Lease, now time.Time) bool {
+	if lease.Spec.RenewTime == nil || lease.Spec.LeaseDurationSeconds == nil {
+		return true
+	}
+	deadline := lease.Spec.RenewTime.Add(time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second)
+	return now.After(deadline)
+}
+
+func sameMembers(a, b map[string]Member) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for identity, member := range a {
+		if b[identity] != member {
+			return false
+		}
+	}
+	return true
+}
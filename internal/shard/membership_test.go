@@ -0,0 +1,120 @@
+package shard
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func newMember(client kubernetes.Interface, identity string) *Membership {
+	return New(client, zap.NewNop(), Options{
+		Namespace: "nightjar-system",
+		Identity:  identity,
+		Host:      identity + ".nightjar.svc",
+	})
+}
+
+func TestMembership_Partitions(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	ctx := context.Background()
+	a, b := newMember(client, "a"), newMember(client, "b")
+	changes := 0
+	a.OnChange(func() { changes++ })
+
+	assert.False(t, a.Owns("team-1"), "owns nothing before the group is read")
+	require.NoError(t, a.sync(ctx))
+	require.True(t, a.WaitForSync(ctx))
+	assert.Zero(t, changes, "the initial ring is not a change")
+	assert.True(t, a.Owns("team-1"), "a lone member owns every namespace")
+
+	require.NoError(t, b.sync(ctx))
+	require.NoError(t, a.sync(ctx))
+	assert.Equal(t, 1, changes)
+	assert.Equal(t, []Member{a.Self(), b.Self()}, a.Members())
+	for _, ns := range namespaces(100) {
+		assert.NotEqual(t, a.Owns(ns), b.Owns(ns), "exactly one member owns %s", ns)
+		owner, ok := b.Owner(ns)
+		require.True(t, ok)
+		assert.Equal(t, owner.Identity == "a", a.Owns(ns))
+	}
+
+	lease, err := client.CoordinationV1().Leases("nightjar-system").Get(ctx, "nightjar-shard-b", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "nightjar", lease.Labels[GroupLabel])
+	assert.Equal(t, "b.nightjar.svc", lease.Annotations[HostAnnotation])
+}
+
+func TestMembership_ExpiredMemberLeaves(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	ctx := context.Background()
+	a, b := newMember(client, "a"), newMember(client, "b")
+	require.NoError(t, b.sync(ctx))
+	require.NoError(t, a.sync(ctx))
+	require.Len(t, a.Members(), 2)
+
+	// b stops renewing.
+	a.now = func() time.Time { return time.Now().Add(time.Minute) }
+	require.NoError(t, a.sync(ctx))
+	assert.Equal(t, []Member{a.Self()}, a.Members())
+	assert.True(t, a.Owns("team-1"))
+}
+
+func TestMembership_FailedRenewalLeaves(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	ctx := context.Background()
+	a, b := newMember(client, "a"), newMember(client, "b")
+	require.NoError(t, b.sync(ctx))
+	require.NoError(t, a.sync(ctx))
+	require.Len(t, a.Members(), 2)
+
+	// The API server rejects a's renewals and, later, its reads too.
+	failing := map[string]bool{"update": true}
+	client.PrependReactor("*", "leases", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return failing[action.GetVerb()], nil, errors.New("apiserver unavailable")
+	})
+	start := time.Now()
+	a.now = func() time.Time { return start.Add(10 * time.Second) }
+	require.Error(t, a.sync(ctx))
+	assert.Len(t, a.Members(), 2, "a keeps its namespaces while its Lease is valid")
+
+	failing["list"] = true
+	a.now = func() time.Time { return start.Add(time.Minute) }
+	require.Error(t, a.sync(ctx))
+	assert.Equal(t, []Member{b.Self()}, a.Members(), "a drops out once its Lease expired")
+	for _, ns := range namespaces(20) {
+		assert.False(t, a.Owns(ns))
+	}
+
+	// It rejoins on the next successful renewal.
+	failing = map[string]bool{}
+	a.now = time.Now
+	require.NoError(t, a.sync(ctx))
+	assert.Len(t, a.Members(), 2)
+}
+
+func TestMembership_StartReleasesLease(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	m := newMember(client, "a")
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- m.Start(ctx) }()
+	require.True(t, m.WaitForSync(context.Background()))
+
+	cancel()
+	require.NoError(t, <-done)
+	leases, err := client.CoordinationV1().Leases("nightjar-system").List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, leases.Items)
+
+	assert.Error(t, New(client, zap.NewNop(), Options{}).Start(context.Background()), "identity is required")
+}
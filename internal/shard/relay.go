@@ -0,0 +1,240 @@
+package shard
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// RelayAudience is the audience of the ServiceAccount tokens replicas
+// present to each other's relay server. A token bound to it is rejected by
+// the API server, so a relayed request cannot be replayed against it.
+const RelayAudience = "nightjar-relay"
+
+// DefaultRelayTokenFile is where the chart projects this replica's relay
+// token.
+const DefaultRelayTokenFile = "/var/run/secrets/nightjar/relay/token"
+
+// RelayServerOptions configures a RelayServer.
+type RelayServerOptions struct {
+	// Address is the address to listen on, e.g. ":8096"
+	Address string
+
+	// Client reviews the tokens callers present.
+	Client kubernetes.Interface
+
+	// TokenFile holds this replica's own token for RelayAudience. Only
+	// callers authenticated as the same ServiceAccount are served.
+	TokenFile string
+
+	// CacheTTL is how long an accepted token is trusted without another
+	// TokenReview. Default: 1 minute.
+	CacheTTL time.Duration
+
+	// Logger for the server
+	Logger *zap.Logger
+}
+
+// RelayServer serves the internal API replicas relay items to, e.g. the
+// audit and access log relays, on a listener of its own. Every request must
+// carry a bearer token for RelayAudience that a TokenReview authenticates as
+// this replica's own ServiceAccount; other requests are rejected with 401,
+// or 403 for another identity.
+type RelayServer struct {
+	opts   RelayServerOptions
+	logger *zap.Logger
+	mux    *http.ServeMux
+
+	mu       sync.Mutex
+	self     string                 // this replica's username, once reviewed
+	accepted map[[32]byte]time.Time // token hash → expiry
+}
+
+// NewRelayServer creates a RelayServer. Mount handlers with Handle, then
+// call Start.
+func NewRelayServer(opts RelayServerOptions) *RelayServer {
+	if opts.Logger == nil {
+		opts.Logger = zap.NewNop()
+	}
+	if opts.TokenFile == "" {
+		opts.TokenFile = DefaultRelayTokenFile
+	}
+	if opts.CacheTTL == 0 {
+		opts.CacheTTL = time.Minute
+	}
+	return &RelayServer{
+		opts:     opts,
+		logger:   opts.Logger.Named("shard-relay"),
+		mux:      http.NewServeMux(),
+		accepted: make(map[[32]byte]time.Time),
+	}
+}
+
+// Handle mounts h at path. Call it before Start.
+func (s *RelayServer) Handle(path string, h http.Handler) {
+	s.mux.Handle(path, h)
+}
+
+// Start listens on the configured address and serves until ctx is
+// cancelled.
+func (s *RelayServer) Start(ctx context.Context) error {
+	lis, err := net.Listen("tcp", s.opts.Address)
+	if err != nil {
+		return fmt.Errorf("listening on %s: %w", s.opts.Address, err)
+	}
+	srv := &http.Server{
+		Handler:           s,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.Serve(lis) }()
+	s.logger.Info("Relay server listening", zap.String("address", lis.Addr().String()))
+
+	select {
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return srv.Shutdown(shutdownCtx)
+	case err := <-errCh:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	}
+}
+
+// ServeHTTP authenticates req and passes it to the handler mounted at its
+// path.
+func (s *RelayServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if err := s.authenticate(req.Context(), token); err != nil {
+		var forbidden *forbiddenError
+		if errors.As(err, &forbidden) {
+			s.logger.Warn("Relay request from another identity rejected", zap.String("user", forbidden.user))
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		s.logger.Debug("Relay request rejected", zap.Error(err))
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	s.mux.ServeHTTP(w, req)
+}
+
+// forbiddenError reports a token authenticated as another user.
+type forbiddenError struct {
+	user string
+}
+
+func (e *forbiddenError) Error() string {
+	return fmt.Sprintf("user %q is not this replica's ServiceAccount", e.user)
+}
+
+// authenticate checks that token authenticates as this replica's own
+// ServiceAccount.
+func (s *RelayServer) authenticate(ctx context.Context, token string) error {
+	key := sha256.Sum256([]byte(token))
+	now := time.Now()
+	s.mu.Lock()
+	expiry, cached := s.accepted[key]
+	s.mu.Unlock()
+	if cached && now.Before(expiry) {
+		return nil
+	}
+
+	self, err := s.selfUser(ctx)
+	if err != nil {
+		return err
+	}
+	user, err := s.review(ctx, token)
+	if err != nil {
+		return err
+	}
+	if user != self {
+		return &forbiddenError{user: user}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, exp := range s.accepted {
+		if !now.Before(exp) {
+			delete(s.accepted, k)
+		}
+	}
+	s.accepted[key] = now.Add(s.opts.CacheTTL)
+	return nil
+}
+
+// selfUser returns the username of this replica's own token, reviewing it
+// on first use.
+func (s *RelayServer) selfUser(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	self := s.self
+	s.mu.Unlock()
+	if self != "" {
+		return self, nil
+	}
+
+	token, err := readToken(s.opts.TokenFile)
+	if err != nil {
+		return "", err
+	}
+	self, err = s.review(ctx, token)
+	if err != nil {
+		return "", fmt.Errorf("reviewing own relay token: %w", err)
+	}
+	s.mu.Lock()
+	s.self = self
+	s.mu.Unlock()
+	return self, nil
+}
+
+// review returns the user token authenticates as for RelayAudience.
+func (s *RelayServer) review(ctx context.Context, token string) (string, error) {
+	review, err := s.opts.Client.AuthenticationV1().TokenReviews().Create(ctx, &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{
+			Token:     token,
+			Audiences: []string{RelayAudience},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return "", fmt.Errorf("creating TokenReview: %w", err)
+	}
+	if !review.Status.Authenticated {
+		return "", fmt.Errorf("token not authenticated: %s", review.Status.Error)
+	}
+	if !slices.Contains(review.Status.Audiences, RelayAudience) {
+		return "", fmt.Errorf("token not bound to audience %s", RelayAudience)
+	}
+	return review.Status.User.Username, nil
+}
+
+// readToken reads a token file, which the kubelet rotates in place.
+func readToken(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("reading relay token: %w", err)
+	}
+	token := strings.TrimSpace(string(b))
+	if token == "" {
+		return "", fmt.Errorf("relay token file %s is empty", path)
+	}
+	return token, nil
+}
@@ -0,0 +1,129 @@
+package shard
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+const controllerSA = "system:serviceaccount:nightjar-system:nightjar"
+
+// reviewTokens answers TokenReviews of the given tokens, bound to
+// RelayAudience, with their users; other tokens are not authenticated.
+func reviewTokens(users map[string]string) (*fake.Clientset, *int) {
+	client := fake.NewSimpleClientset()
+	reviews := 0
+	client.PrependReactor("create", "tokenreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		reviews++
+		review := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenReview).DeepCopy()
+		if user, ok := users[review.Spec.Token]; ok {
+			review.Status.Authenticated = true
+			review.Status.Audiences = review.Spec.Audiences
+			review.Status.User.Username = user
+		} else {
+			review.Status.Error = "invalid bearer token"
+		}
+		return true, review, nil
+	})
+	return client, &reviews
+}
+
+func writeToken(t *testing.T, token string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(path, []byte(token+"\n"), 0o600))
+	return path
+}
+
+func TestRelayServer_Authenticates(t *testing.T) {
+	client, reviews := reviewTokens(map[string]string{
+		"self":  controllerSA,
+		"peer":  controllerSA,
+		"other": "system:serviceaccount:default:default",
+	})
+	s := NewRelayServer(RelayServerOptions{Client: client, TokenFile: writeToken(t, "self")})
+	served := 0
+	s.Handle("/relay", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { served++ }))
+
+	post := func(authorization string) int {
+		req := httptest.NewRequest(http.MethodPost, "/relay", strings.NewReader(`{}`))
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, post(""), "no token")
+	assert.Equal(t, http.StatusUnauthorized, post("Basic cGVlcg=="), "not a bearer token")
+	assert.Equal(t, http.StatusUnauthorized, post("Bearer forged"), "token not authenticated")
+	assert.Equal(t, http.StatusForbidden, post("Bearer other"), "another ServiceAccount")
+	assert.Zero(t, served)
+
+	assert.Equal(t, http.StatusOK, post("Bearer peer"))
+	assert.Equal(t, 1, served)
+
+	before := *reviews
+	assert.Equal(t, http.StatusOK, post("Bearer peer"))
+	assert.Equal(t, before, *reviews, "an accepted token is cached")
+}
+
+func TestRelayServer_RejectsTokenWithoutAudience(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "tokenreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenReview).DeepCopy()
+		review.Status.Authenticated = true
+		review.Status.User.Username = controllerSA
+		return true, review, nil
+	})
+	s := NewRelayServer(RelayServerOptions{Client: client, TokenFile: writeToken(t, "self")})
+	s.Handle("/relay", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodPost, "/relay", nil)
+	req.Header.Set("Authorization", "Bearer peer")
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestForwarder_RelayPresentsToken(t *testing.T) {
+	client, _ := reviewTokens(map[string]string{"self": controllerSA, "peer": controllerSA})
+	s := NewRelayServer(RelayServerOptions{Client: client, TokenFile: writeToken(t, "self")})
+	received := make(chan string, 1)
+	s.Handle("/relay", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Get(ForwardedHeader)
+	}))
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+	host, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+	require.NoError(t, err)
+	portNum, err := strconv.Atoi(port)
+	require.NoError(t, err)
+
+	self, other := Member{Identity: "a"}, Member{Identity: "b", Host: host}
+	router := staticRouter{self: self, owners: map[string]Member{"theirs": other}}
+
+	f := NewForwarder(router, portNum, zap.NewNop())
+	_, err = f.Relay(context.Background(), "/relay", []byte(`{}`), "theirs")
+	assert.ErrorContains(t, err, "status 401", "relayed without a token")
+
+	f.SetTokenFile(writeToken(t, "peer"))
+	_, err = f.Relay(context.Background(), "/relay", []byte(`{}`), "theirs")
+	require.NoError(t, err)
+	assert.Equal(t, "a", <-received)
+}
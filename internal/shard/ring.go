@@ -0,0 +1,82 @@
+package shard
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+)
+
+// DefaultVirtualNodes is the number of points each member places on the
+// ring. More points spread namespaces more evenly across members.
+const DefaultVirtualNodes = 128
+
+// Ring assigns keys to members by consistent hashing: each member owns the
+// arcs of the ring ending at its virtual nodes, so adding or removing a
+// member moves only the keys on its own arcs.
+type Ring struct {
+	points  []uint64
+	owners  map[uint64]string
+	members []string
+}
+
+// NewRing builds a ring of members with virtualNodes points each. A
+// virtualNodes of zero uses DefaultVirtualNodes.
+func NewRing(members []string, virtualNodes int) *Ring {
+	if virtualNodes <= 0 {
+		virtualNodes = DefaultVirtualNodes
+	}
+	r := &Ring{
+		owners:  make(map[uint64]string, len(members)*virtualNodes),
+		members: append([]string(nil), members...),
+	}
+	sort.Strings(r.members)
+	for _, m := range r.members {
+		for i := 0; i < virtualNodes; i++ {
+			p := hash(m + "#" + strconv.Itoa(i))
+			// On the rare collision the smaller member name wins, so every
+			// replica builds the same ring.
+			if owner, ok := r.owners[p]; ok && owner < m {
+				continue
+			}
+			if _, ok := r.owners[p]; !ok {
+				r.points = append(r.points, p)
+			}
+			r.owners[p] = m
+		}
+	}
+	sort.Slice(r.points, func(i, j int) bool { return r.points[i] < r.points[j] })
+	return r
+}
+
+// Owner returns the member that owns key, or "" if the ring is empty.
+func (r *Ring) Owner(key string) string {
+	if len(r.points) == 0 {
+		return ""
+	}
+	h := hash(key)
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= h })
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.owners[r.points[i]]
+}
+
+// Members returns the members of the ring, sorted.
+func (r *Ring) Members() []string {
+	return append([]string(nil), r.members...)
+}
+
+// hash is FNV-1a followed by the murmur3 finalizer: FNV alone clusters
+// short keys that differ only in their last bytes, like "team-1" and
+// "team-2", on the same arc.
+func hash(s string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(s))
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
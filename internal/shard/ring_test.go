@@ -0,0 +1,45 @@
+package shard
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func namespaces(n int) []string {
+	out := make([]string, n)
+	for i := range out {
+		out[i] = fmt.Sprintf("team-%d", i)
+	}
+	return out
+}
+
+func TestRing_Balance(t *testing.T) {
+	ring := NewRing([]string{"a", "b", "c"}, 0)
+	counts := make(map[string]int)
+	for _, ns := range namespaces(3000) {
+		counts[ring.Owner(ns)]++
+	}
+	require.Len(t, counts, 3)
+	for member, n := range counts {
+		assert.InDelta(t, 1000, n, 300, "member %s owns %d namespaces", member, n)
+	}
+	assert.Equal(t, "", NewRing(nil, 0).Owner("team-a"))
+}
+
+func TestRing_MinimalMovement(t *testing.T) {
+	before := NewRing([]string{"a", "b", "c"}, 0)
+	after := NewRing([]string{"a", "b", "c", "d"}, 0)
+	moved := 0
+	for _, ns := range namespaces(3000) {
+		from, to := before.Owner(ns), after.Owner(ns)
+		if from != to {
+			moved++
+			assert.Equal(t, "d", to, "namespaces only move to the new member")
+		}
+	}
+	assert.InDelta(t, 750, moved, 250)
+	assert.Equal(t, before.Owner("team-7"), NewRing([]string{"c", "a", "b"}, 0).Owner("team-7"), "member order does not matter")
+}
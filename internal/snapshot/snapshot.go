@@ -0,0 +1,403 @@
+// Package snapshot persists the constraint index so a restarted controller
+// serves the last known constraints immediately instead of an empty index.
+//
+// Restored constraints are provisional: the index is marked stale until the
+// discovery engine has synced every informer, after which constraints whose
+// source object is gone, or no longer produces them, are dropped.
+package snapshot
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+
+	"github.com/nightjarctl/nightjar/internal/indexer"
+	"github.com/nightjarctl/nightjar/internal/types"
+)
+
+// formatVersion is bumped on incompatible changes to the encoding; snapshots
+// of another version are ignored.
+const formatVersion = 1
+
+// ObjectRef identifies the object a constraint was parsed from. A
+// constraint's own Name may differ, e.g. one per Kyverno rule.
+type ObjectRef struct {
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+type entry struct {
+	Constraint types.Constraint `json:"constraint"`
+	Object     ObjectRef        `json:"object"`
+}
+
+type document struct {
+	Version  int       `json:"version"`
+	Revision uint64    `json:"revision"`
+	SavedAt  time.Time `json:"savedAt"`
+	Entries  []entry   `json:"entries"`
+}
+
+// refOf returns the source object of c: its RawObject when set, else the
+// constraint's own namespace and name.
+func refOf(c types.Constraint) ObjectRef {
+	if c.RawObject != nil {
+		return ObjectRef{Namespace: c.RawObject.GetNamespace(), Name: c.RawObject.GetName()}
+	}
+	return ObjectRef{Namespace: c.Namespace, Name: c.Name}
+}
+
+// encode serializes constraints as gzipped JSON. RawObjects are dropped;
+// only a reference to the source object is kept.
+func encode(constraints []types.Constraint, revision uint64, now time.Time) ([]byte, error) {
+	doc := document{
+		Version:  formatVersion,
+		Revision: revision,
+		SavedAt:  now.UTC(),
+		Entries:  make([]entry, 0, len(constraints)),
+	}
+	for _, c := range constraints {
+		ref := refOf(c)
+		c.RawObject = nil
+		doc.Entries = append(doc.Entries, entry{Constraint: c, Object: ref})
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(zw).Encode(doc); err != nil {
+		return nil, fmt.Errorf("encoding snapshot: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("compressing snapshot: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func decode(data []byte) (*document, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decompressing snapshot: %w", err)
+	}
+	raw, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("decompressing snapshot: %w", err)
+	}
+	var doc document
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("decoding snapshot: %w", err)
+	}
+	if doc.Version != formatVersion {
+		return nil, fmt.Errorf("snapshot format version %d, want %d", doc.Version, formatVersion)
+	}
+	for i := range doc.Entries {
+		normalizeDetails(doc.Entries[i].Constraint.Details)
+	}
+	return &doc, nil
+}
+
+// normalizeDetails restores the string lists adapters put in Details, e.g.
+// a ServiceEntry's hosts, which JSON decodes as []interface{}. Readers
+// assert []string, and a restored constraint must read like a parsed one.
+func normalizeDetails(details map[string]interface{}) {
+	for k, v := range details {
+		switch v := v.(type) {
+		case []interface{}:
+			if strs, ok := stringSlice(v); ok {
+				details[k] = strs
+			}
+		case map[string]interface{}:
+			normalizeDetails(v)
+		}
+	}
+}
+
+// stringSlice converts s to []string if every element is a string.
+func stringSlice(s []interface{}) ([]string, bool) {
+	result := make([]string, len(s))
+	for i, v := range s {
+		str, ok := v.(string)
+		if !ok {
+			return nil, false
+		}
+		result[i] = str
+	}
+	return result, true
+}
+
+// Source is the view of the discovery engine the Persister reconciles
+// against. *discovery.Engine implements it.
+type Source interface {
+	// HasSynced reports whether every informer has synced.
+	HasSynced() bool
+	// SourceConstraints returns the UIDs the object currently produces;
+	// found is false if the object or its type is not watched.
+	SourceConstraints(ctx context.Context, gvr schema.GroupVersionResource, namespace, name string) (uids []k8stypes.UID, found bool, err error)
+}
+
+// Options configures a Persister.
+type Options struct {
+	// Interval between saves. Saves are skipped when the index has not
+	// changed. Default: 5m.
+	Interval time.Duration
+
+	// SyncPollInterval is how often Start checks whether the source has
+	// synced after a restore. Default: 1s.
+	SyncPollInterval time.Duration
+}
+
+// DefaultOptions returns Options with default values.
+func DefaultOptions() Options {
+	return Options{
+		Interval:         5 * time.Minute,
+		SyncPollInterval: time.Second,
+	}
+}
+
+type restoredConstraint struct {
+	source schema.GroupVersionResource
+	object ObjectRef
+}
+
+// Persister restores the index from a Store at startup, reconciles the
+// restored constraints once the source has synced (Reconcile), and saves the
+// index periodically and on shutdown (Start).
+type Persister struct {
+	idx    *indexer.Indexer
+	store  Store
+	source Source
+	logger *zap.Logger
+	opts   Options
+
+	mu        sync.Mutex
+	restored  map[k8stypes.UID]restoredConstraint
+	refreshed map[k8stypes.UID]bool // restored UIDs changed since the restore
+	tracking  *indexer.Subscription
+	tracked   uint64 // revision of the last event track handled
+
+	savedRevision uint64
+}
+
+// New creates a Persister.
+func New(idx *indexer.Indexer, store Store, source Source, logger *zap.Logger, opts Options) *Persister {
+	defaults := DefaultOptions()
+	if opts.Interval == 0 {
+		opts.Interval = defaults.Interval
+	}
+	if opts.SyncPollInterval == 0 {
+		opts.SyncPollInterval = defaults.SyncPollInterval
+	}
+	return &Persister{
+		idx:    idx,
+		store:  store,
+		source: source,
+		logger: logger.Named("snapshot"),
+		opts:   opts,
+	}
+}
+
+// Restore loads the stored snapshot into the index and marks the index
+// stale. It must run before the discovery engine starts, so restored
+// constraints never overwrite fresher ones. A missing snapshot is not an
+// error; an unreadable one is logged and skipped.
+func (p *Persister) Restore(ctx context.Context) (int, error) {
+	data, err := p.store.Load(ctx)
+	if errors.Is(err, ErrNotFound) {
+		p.logger.Info("No index snapshot to restore")
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	doc, err := decode(data)
+	if err != nil {
+		p.logger.Warn("Ignoring unreadable index snapshot", zap.Error(err))
+		return 0, nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.restored = make(map[k8stypes.UID]restoredConstraint, len(doc.Entries))
+	p.refreshed = make(map[k8stypes.UID]bool)
+
+	p.idx.SetStale(true)
+	for _, e := range doc.Entries {
+		p.idx.Upsert(e.Constraint)
+		p.restored[e.Constraint.UID] = restoredConstraint{source: e.Constraint.Source, object: e.Object}
+	}
+
+	// Anything that touches a restored constraint from now on comes from
+	// the cluster and supersedes the snapshot.
+	p.tracked = p.idx.Revision()
+	p.tracking = p.idx.Subscribe(types.ConstraintQuery{})
+	go p.track(p.tracking)
+
+	p.logger.Info("Restored index snapshot",
+		zap.Int("constraints", len(doc.Entries)),
+		zap.Time("saved_at", doc.SavedAt),
+	)
+	return len(doc.Entries), nil
+}
+
+func (p *Persister) track(sub *indexer.Subscription) {
+	for event := range sub.Events() {
+		p.mu.Lock()
+		p.observeLocked(event)
+		p.mu.Unlock()
+	}
+}
+
+// observeLocked records that event superseded a restored constraint. After
+// an EventResync, changes the feed skipped are missing from p.refreshed;
+// reconcile checks those constraints against the source like any other.
+// Caller must hold p.mu.
+func (p *Persister) observeLocked(event indexer.IndexEvent) {
+	if p.restored == nil || event.Revision <= p.tracked {
+		return
+	}
+	p.tracked = event.Revision
+	if event.Type == indexer.EventResync {
+		return
+	}
+	if _, ok := p.restored[event.Constraint.UID]; ok {
+		p.refreshed[event.Constraint.UID] = true
+	}
+}
+
+// Reconcile waits until the source has synced, then reconciles the
+// restored constraints. It returns at once if nothing was restored. Every
+// replica runs it, since every replica serves its own index.
+func (p *Persister) Reconcile(ctx context.Context) error {
+	p.mu.Lock()
+	restoring := p.tracking != nil
+	p.mu.Unlock()
+	if !restoring {
+		return nil
+	}
+
+	ticker := time.NewTicker(p.opts.SyncPollInterval)
+	defer ticker.Stop()
+	for !p.source.HasSynced() {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+	p.reconcile(ctx)
+	return nil
+}
+
+// Start saves the index every Interval and once more when ctx is
+// cancelled. Replicas share the store, so only the leader runs it.
+func (p *Persister) Start(ctx context.Context) error {
+	ticker := time.NewTicker(p.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			saveCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			p.save(saveCtx)
+			cancel()
+			return nil
+		case <-ticker.C:
+			p.save(ctx)
+		}
+	}
+}
+
+// reconcile drops restored constraints the cluster no longer produces and
+// clears the stale mark. Every restored constraint is checked against the
+// source, except those changed since the restore: the index already holds
+// their current state. A constraint is kept when the source cannot tell,
+// e.g. on a parse error.
+func (p *Persister) reconcile(ctx context.Context) {
+	p.mu.Lock()
+	p.tracking.Close()
+	p.tracking = nil
+	// Catch up on events the subscription had not delivered yet. If they
+	// were compacted away, the constraints they changed are checked below.
+	events, _ := p.idx.Since(p.tracked)
+	for _, event := range events {
+		p.observeLocked(event)
+	}
+	restored, refreshed := p.restored, p.refreshed
+	p.restored, p.refreshed = nil, nil
+	p.mu.Unlock()
+
+	type objectKey struct {
+		source schema.GroupVersionResource
+		object ObjectRef
+	}
+	type produced struct {
+		uids  map[k8stypes.UID]bool
+		found bool
+		err   error
+	}
+	cache := make(map[objectKey]produced)
+
+	dropped := 0
+	for uid, r := range restored {
+		if refreshed[uid] {
+			continue
+		}
+		key := objectKey{source: r.source, object: r.object}
+		res, ok := cache[key]
+		if !ok {
+			uids, found, err := p.source.SourceConstraints(ctx, r.source, r.object.Namespace, r.object.Name)
+			res = produced{uids: make(map[k8stypes.UID]bool, len(uids)), found: found, err: err}
+			for _, u := range uids {
+				res.uids[u] = true
+			}
+			cache[key] = res
+		}
+		if res.err != nil || (res.found && res.uids[uid]) {
+			continue
+		}
+		p.idx.Delete(uid)
+		dropped++
+	}
+
+	p.idx.SetStale(false)
+	p.logger.Info("Reconciled restored index snapshot",
+		zap.Int("restored", len(restored)),
+		zap.Int("dropped", dropped),
+	)
+}
+
+// save writes the index to the store if it changed since the last save.
+// A stale index is never saved, so unreconciled constraints are not
+// persisted again.
+func (p *Persister) save(ctx context.Context) {
+	if p.idx.Stale() {
+		return
+	}
+	constraints, revision := p.idx.Snapshot()
+	if revision == p.savedRevision {
+		return
+	}
+	data, err := encode(constraints, revision, time.Now())
+	if err != nil {
+		p.logger.Error("Failed to encode index snapshot", zap.Error(err))
+		return
+	}
+	if err := p.store.Save(ctx, data); err != nil {
+		p.logger.Error("Failed to save index snapshot", zap.Error(err))
+		return
+	}
+	p.savedRevision = revision
+	p.logger.Debug("Saved index snapshot",
+		zap.Int("constraints", len(constraints)),
+		zap.Uint64("revision", revision),
+		zap.Int("bytes", len(data)),
+	)
+}
@@ -0,0 +1,299 @@
+package snapshot
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/nightjarctl/nightjar/internal/adapters/istio"
+	"github.com/nightjarctl/nightjar/internal/indexer"
+	"github.com/nightjarctl/nightjar/internal/types"
+)
+
+var policiesGVR = schema.GroupVersionResource{Group: "kyverno.io", Version: "v1", Resource: "policies"}
+
+func ruleConstraint(uid, policy, ns string) types.Constraint {
+	raw := &unstructured.Unstructured{}
+	raw.SetName(policy)
+	raw.SetNamespace(ns)
+	return types.Constraint{
+		UID:            k8stypes.UID(uid),
+		Source:         policiesGVR,
+		Name:           policy + "/" + uid,
+		Namespace:      ns,
+		ConstraintType: types.ConstraintTypeAdmission,
+		Severity:       types.SeverityWarning,
+		Summary:        "Requires team label",
+		RawObject:      raw,
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	c := ruleConstraint("rule-1", "require-labels", "team-a")
+	c.WorkloadSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}
+	c.Tags = []string{"admission"}
+
+	data, err := encode([]types.Constraint{c}, 7, time.Unix(100, 0))
+	require.NoError(t, err)
+
+	doc, err := decode(data)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(7), doc.Revision)
+	require.Len(t, doc.Entries, 1)
+	got := doc.Entries[0]
+	assert.Equal(t, ObjectRef{Namespace: "team-a", Name: "require-labels"}, got.Object)
+	assert.Nil(t, got.Constraint.RawObject, "raw objects are not persisted")
+	c.RawObject = nil
+	assert.Equal(t, c, got.Constraint)
+
+	_, err = decode([]byte("not gzip"))
+	assert.Error(t, err)
+}
+
+func TestEncodeDecode_ServiceEntryDetails(t *testing.T) {
+	se := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "networking.istio.io/v1",
+		"kind":       "ServiceEntry",
+		"metadata":   map[string]interface{}{"name": "stripe", "namespace": "shop", "uid": "se-1"},
+		"spec": map[string]interface{}{
+			"hosts":    []interface{}{"api.stripe.com"},
+			"exportTo": []interface{}{"."},
+			"ports":    []interface{}{map[string]interface{}{"number": int64(443), "protocol": "TLS"}},
+		},
+	}}
+	parsed, err := istio.New().Parse(context.Background(), se)
+	require.NoError(t, err)
+	require.Len(t, parsed, 1)
+
+	data, err := encode(parsed, 1, time.Unix(100, 0))
+	require.NoError(t, err)
+	doc, err := decode(data)
+	require.NoError(t, err)
+	restored := doc.Entries[0].Constraint
+
+	assert.True(t, istio.VisibleTo(restored, "shop"))
+	assert.False(t, istio.VisibleTo(restored, "billing"), "exportTo survives the round trip")
+	assert.Equal(t, []string{"api.stripe.com"}, istio.RegisteredHosts(restored))
+	parsed[0].RawObject = nil
+	assert.Equal(t, parsed[0].Details, restored.Details)
+}
+
+func TestFileStore(t *testing.T) {
+	store := NewFileStore(filepath.Join(t.TempDir(), "nested", "index.snap"))
+	ctx := context.Background()
+
+	_, err := store.Load(ctx)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, store.Save(ctx, []byte("first")))
+	require.NoError(t, store.Save(ctx, []byte("second")))
+	data, err := store.Load(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "second", string(data))
+}
+
+func TestConfigMapStore_Shards(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	store := NewConfigMapStore(client, "nightjar-system", "nightjar-index")
+	store.shardSize = 4
+	ctx := context.Background()
+
+	_, err := store.Load(ctx)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, store.Save(ctx, []byte("0123456789")))
+	list, err := client.CoreV1().ConfigMaps("nightjar-system").List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	assert.Len(t, list.Items, 3)
+	data, err := store.Load(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "0123456789", string(data))
+
+	// A smaller snapshot removes the shards it no longer uses.
+	require.NoError(t, store.Save(ctx, []byte("abcde")))
+	list, err = client.CoreV1().ConfigMaps("nightjar-system").List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	assert.Len(t, list.Items, 2)
+	data, err = store.Load(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "abcde", string(data))
+}
+
+func TestConfigMapStore_InterruptedSave(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	store := NewConfigMapStore(client, "nightjar-system", "nightjar-index")
+	store.shardSize = 4
+	ctx := context.Background()
+	require.NoError(t, store.Save(ctx, []byte("0123456789")))
+
+	// Simulate a save that rewrote shard 1 but died before shard 0.
+	require.NoError(t, store.writeShard(ctx, 1, []byte("xxxx"), map[string]string{generationAnnotation: "newer"}))
+
+	_, err := store.Load(ctx)
+	assert.ErrorContains(t, err, "another generation")
+}
+
+type fakeSource struct {
+	mu       sync.Mutex
+	synced   bool
+	produces map[ObjectRef][]k8stypes.UID
+}
+
+func (f *fakeSource) HasSynced() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.synced
+}
+
+func (f *fakeSource) SourceConstraints(_ context.Context, _ schema.GroupVersionResource, namespace, name string) ([]k8stypes.UID, bool, error) {
+	uids, ok := f.produces[ObjectRef{Namespace: namespace, Name: name}]
+	return uids, ok, nil
+}
+
+func (f *fakeSource) setSynced() {
+	f.mu.Lock()
+	f.synced = true
+	f.mu.Unlock()
+}
+
+func uidSet(cs []types.Constraint) map[k8stypes.UID]bool {
+	out := make(map[k8stypes.UID]bool, len(cs))
+	for _, c := range cs {
+		out[c.UID] = true
+	}
+	return out
+}
+
+func TestPersister_RestoreReconcileSave(t *testing.T) {
+	ctx := context.Background()
+	store := NewFileStore(filepath.Join(t.TempDir(), "index.snap"))
+
+	// Save the index of the previous controller.
+	previous := indexer.New(nil)
+	for _, c := range []types.Constraint{
+		ruleConstraint("kept", "require-labels", "team-a"),
+		ruleConstraint("rule-removed", "require-labels", "team-a"),
+		ruleConstraint("policy-deleted", "deny-all", "team-a"),
+		ruleConstraint("refreshed", "recreated", "team-b"),
+	} {
+		previous.Upsert(c)
+	}
+	opts := Options{Interval: time.Hour, SyncPollInterval: 5 * time.Millisecond}
+	New(previous, store, nil, zap.NewNop(), opts).save(ctx)
+
+	// While it was down, one rule and one policy were deleted.
+	source := &fakeSource{produces: map[ObjectRef][]k8stypes.UID{
+		{Namespace: "team-a", Name: "require-labels"}: {"kept"},
+	}}
+	idx := indexer.New(nil)
+	p := New(idx, store, source, zap.NewNop(), opts)
+	n, err := p.Restore(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 4, n)
+	assert.Equal(t, 4, idx.Count())
+	assert.True(t, idx.Stale())
+
+	// The engine re-indexes one constraint from a source it cannot report.
+	idx.Upsert(ruleConstraint("refreshed", "recreated", "team-b"))
+
+	runCtx, cancel := context.WithCancel(ctx)
+	reconciled := make(chan error, 1)
+	go func() { reconciled <- p.Reconcile(runCtx) }()
+
+	time.Sleep(20 * time.Millisecond)
+	assert.True(t, idx.Stale(), "stays stale until the source has synced")
+	assert.Equal(t, 4, idx.Count())
+
+	source.setSynced()
+	require.NoError(t, <-reconciled)
+	assert.False(t, idx.Stale())
+	assert.Equal(t, map[k8stypes.UID]bool{"kept": true, "refreshed": true}, uidSet(idx.All()))
+
+	// Shutdown saves the reconciled index.
+	done := make(chan error, 1)
+	go func() { done <- p.Start(runCtx) }()
+	cancel()
+	require.NoError(t, <-done)
+	data, err := store.Load(ctx)
+	require.NoError(t, err)
+	doc, err := decode(data)
+	require.NoError(t, err)
+	assert.Len(t, doc.Entries, 2)
+}
+
+func TestPersister_ReconcileAfterFeedLag(t *testing.T) {
+	ctx := context.Background()
+	store := NewFileStore(filepath.Join(t.TempDir(), "index.snap"))
+	previous := indexer.New(nil)
+	previous.Upsert(ruleConstraint("kept", "require-labels", "team-a"))
+	previous.Upsert(ruleConstraint("policy-deleted", "deny-all", "team-a"))
+	opts := Options{Interval: time.Hour, SyncPollInterval: 5 * time.Millisecond}
+	New(previous, store, nil, zap.NewNop(), opts).save(ctx)
+
+	source := &fakeSource{synced: true, produces: map[ObjectRef][]k8stypes.UID{
+		{Namespace: "team-a", Name: "require-labels"}: {"kept"},
+	}}
+	idx := indexer.New(nil)
+	p := New(idx, store, source, zap.NewNop(), opts)
+	_, err := p.Restore(ctx)
+	require.NoError(t, err)
+
+	// The engine indexes so much before the source syncs that the history
+	// no longer reaches back to the restore.
+	p.tracking.Close()
+	for i := 0; i < 10000; i++ {
+		idx.Upsert(ruleConstraint(fmt.Sprintf("new-%d", i), "bulk", "team-c"))
+	}
+	idx.Upsert(ruleConstraint("kept", "require-labels", "team-a"))
+
+	require.NoError(t, p.Reconcile(ctx))
+	assert.False(t, idx.Stale())
+	assert.True(t, uidSet(idx.All())["kept"])
+	assert.False(t, uidSet(idx.All())["policy-deleted"], "restored constraints are checked even after the feed lagged")
+}
+
+func TestPersister_NoSnapshot(t *testing.T) {
+	idx := indexer.New(nil)
+	store := NewFileStore(filepath.Join(t.TempDir(), "index.snap"))
+	p := New(idx, store, &fakeSource{}, zap.NewNop(), Options{})
+
+	n, err := p.Restore(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, n)
+	assert.False(t, idx.Stale())
+	assert.NoError(t, p.Reconcile(context.Background()), "returns at once without a restore")
+
+	// Nothing changed since start: nothing to save.
+	p.save(context.Background())
+	_, err = store.Load(context.Background())
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	idx.Upsert(ruleConstraint("new", "require-labels", "team-a"))
+	p.save(context.Background())
+	_, err = store.Load(context.Background())
+	assert.NoError(t, err)
+}
+
+func TestPersister_StaleIndexNotSaved(t *testing.T) {
+	idx := indexer.New(nil)
+	store := NewFileStore(filepath.Join(t.TempDir(), "index.snap"))
+	p := New(idx, store, &fakeSource{}, zap.NewNop(), Options{})
+
+	idx.Upsert(ruleConstraint("restored", "require-labels", "team-a"))
+	idx.SetStale(true)
+	p.save(context.Background())
+	_, err := store.Load(context.Background())
+	assert.ErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,205 @@
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ErrNotFound is returned by Store.Load when no snapshot has been saved.
+var ErrNotFound = errors.New("snapshot: not found")
+
+// Store persists an encoded snapshot.
+type Store interface {
+	// Save replaces the stored snapshot with data.
+	Save(ctx context.Context, data []byte) error
+	// Load returns the stored snapshot, or ErrNotFound.
+	Load(ctx context.Context) ([]byte, error)
+}
+
+// FileStore keeps the snapshot in a single file, e.g. on a PersistentVolume.
+type FileStore struct {
+	Path string
+}
+
+// NewFileStore creates a FileStore writing to path.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{Path: path}
+}
+
+// Save writes data to a temporary file next to Path and renames it into
+// place, so a crash never leaves a partial snapshot behind.
+func (s *FileStore) Save(_ context.Context, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0o755); err != nil {
+		return fmt.Errorf("creating snapshot directory: %w", err)
+	}
+	tmp := s.Path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("writing snapshot: %w", err)
+	}
+	if err := os.Rename(tmp, s.Path); err != nil {
+		return fmt.Errorf("replacing snapshot: %w", err)
+	}
+	return nil
+}
+
+// Load reads the snapshot file.
+func (s *FileStore) Load(_ context.Context) ([]byte, error) {
+	data, err := os.ReadFile(s.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading snapshot: %w", err)
+	}
+	return data, nil
+}
+
+const (
+	// shardKey is the binaryData key holding a shard's bytes.
+	shardKey = "snapshot"
+
+	// snapshotLabel marks the shards of one snapshot; its value is the
+	// store name.
+	snapshotLabel = "nightjar.io/index-snapshot"
+
+	// generationAnnotation ties shards written by the same Save together.
+	generationAnnotation = "nightjar.io/snapshot-generation"
+
+	// shardsAnnotation on shard 0 records how many shards the generation has.
+	shardsAnnotation = "nightjar.io/snapshot-shards"
+
+	// defaultShardSize keeps each ConfigMap well under the 1 MiB object
+	// size limit.
+	defaultShardSize = 900 * 1024
+)
+
+// ConfigMapStore splits the snapshot across ConfigMaps <name>-0 ... <name>-N
+// in one namespace. Shard 0 is written last and names the generation and
+// shard count, so a Save interrupted halfway is detected by Load instead of
+// being read as a mix of two snapshots.
+type ConfigMapStore struct {
+	client    kubernetes.Interface
+	namespace string
+	name      string
+	shardSize int
+}
+
+// NewConfigMapStore creates a ConfigMapStore for namespace/name.
+func NewConfigMapStore(client kubernetes.Interface, namespace, name string) *ConfigMapStore {
+	return &ConfigMapStore{
+		client:    client,
+		namespace: namespace,
+		name:      name,
+		shardSize: defaultShardSize,
+	}
+}
+
+func (s *ConfigMapStore) shardName(i int) string {
+	return fmt.Sprintf("%s-%d", s.name, i)
+}
+
+// Save writes data as a new generation of shards and removes shards the new
+// generation no longer uses.
+func (s *ConfigMapStore) Save(ctx context.Context, data []byte) error {
+	var shards [][]byte
+	for len(data) > s.shardSize {
+		shards = append(shards, data[:s.shardSize])
+		data = data[s.shardSize:]
+	}
+	shards = append(shards, data)
+
+	generation := strconv.FormatInt(time.Now().UnixNano(), 10)
+	for i := len(shards) - 1; i >= 0; i-- {
+		annotations := map[string]string{generationAnnotation: generation}
+		if i == 0 {
+			annotations[shardsAnnotation] = strconv.Itoa(len(shards))
+		}
+		if err := s.writeShard(ctx, i, shards[i], annotations); err != nil {
+			return err
+		}
+	}
+
+	existing, err := s.client.CoreV1().ConfigMaps(s.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: snapshotLabel + "=" + s.name,
+	})
+	if err != nil {
+		return fmt.Errorf("listing snapshot shards: %w", err)
+	}
+	for _, cm := range existing.Items {
+		i, err := strconv.Atoi(strings.TrimPrefix(cm.Name, s.name+"-"))
+		if err != nil || i < len(shards) {
+			continue
+		}
+		err = s.client.CoreV1().ConfigMaps(s.namespace).Delete(ctx, cm.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("deleting snapshot shard %s: %w", cm.Name, err)
+		}
+	}
+	return nil
+}
+
+func (s *ConfigMapStore) writeShard(ctx context.Context, i int, data []byte, annotations map[string]string) error {
+	configMaps := s.client.CoreV1().ConfigMaps(s.namespace)
+	cm, err := configMaps.Get(ctx, s.shardName(i), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = configMaps.Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        s.shardName(i),
+				Namespace:   s.namespace,
+				Labels:      map[string]string{snapshotLabel: s.name, "app.kubernetes.io/managed-by": "nightjar"},
+				Annotations: annotations,
+			},
+			BinaryData: map[string][]byte{shardKey: data},
+		}, metav1.CreateOptions{})
+	} else if err == nil {
+		cm.Annotations = annotations
+		cm.BinaryData = map[string][]byte{shardKey: data}
+		_, err = configMaps.Update(ctx, cm, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("writing snapshot shard %s: %w", s.shardName(i), err)
+	}
+	return nil
+}
+
+// Load reassembles the generation named by shard 0.
+func (s *ConfigMapStore) Load(ctx context.Context) ([]byte, error) {
+	configMaps := s.client.CoreV1().ConfigMaps(s.namespace)
+	head, err := configMaps.Get(ctx, s.shardName(0), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading snapshot shard %s: %w", s.shardName(0), err)
+	}
+
+	generation := head.Annotations[generationAnnotation]
+	count, err := strconv.Atoi(head.Annotations[shardsAnnotation])
+	if err != nil || count < 1 {
+		return nil, fmt.Errorf("snapshot shard %s: invalid shard count %q", head.Name, head.Annotations[shardsAnnotation])
+	}
+
+	data := append([]byte(nil), head.BinaryData[shardKey]...)
+	for i := 1; i < count; i++ {
+		cm, err := configMaps.Get(ctx, s.shardName(i), metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("reading snapshot shard %s: %w", s.shardName(i), err)
+		}
+		if cm.Annotations[generationAnnotation] != generation {
+			return nil, fmt.Errorf("snapshot shard %s belongs to another generation; the last save was interrupted", cm.Name)
+		}
+		data = append(data, cm.BinaryData[shardKey]...)
+	}
+	return data, nil
+}
@@ -36,7 +36,8 @@ type Adapter interface {
 	//   - Must not panic; return errors instead.
 	//   - Should populate Summary with a human-readable description.
 	//   - Should populate AffectedNamespaces/WorkloadSelector when determinable.
-	//   - May leave RawObject nil if the indexer should not store it (memory optimization).
+	//   - Should set RawObject to obj itself rather than a copy; it is shared
+	//     with the informer cache. May leave it nil to not store it at all.
 	Parse(ctx context.Context, obj *unstructured.Unstructured) ([]Constraint, error)
 }
 
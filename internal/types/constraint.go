@@ -75,7 +75,10 @@ type Constraint struct {
 	// Tags for agent filtering (e.g., "network", "egress", "port-restriction").
 	Tags []string
 
-	// Reference back to the original Kubernetes object
+	// Reference back to the original Kubernetes object. Adapters store the
+	// object they were given, which for discovered constraints is the
+	// informer cache entry itself, so it is shared and must be treated as
+	// read-only. DeepCopy it before modifying.
 	RawObject *unstructured.Unstructured
 }
 
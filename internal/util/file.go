@@ -0,0 +1,38 @@
+package util
+
+import (
+	"errors"
+	"io"
+	"os"
+)
+
+// ReopenIfRotated returns the file now at path if f, opened from path, was
+// replaced or truncated, or nil if f is still current. A truncated f is
+// rewound and returned itself. A missing file is not an error, as log
+// writers create the new file after renaming the old one.
+func ReopenIfRotated(f *os.File, path string) (*os.File, error) {
+	current, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	latest, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if os.SameFile(current, latest) {
+		offset, err := f.Seek(0, io.SeekCurrent)
+		if err != nil || latest.Size() >= offset {
+			return nil, err
+		}
+		// Truncated in place, e.g. by copytruncate.
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		return f, nil
+	}
+	return os.Open(path)
+}
@@ -14,6 +14,16 @@ func MatchesLabelSelector(selector *metav1.LabelSelector, lbls map[string]string
 	if len(selector.MatchLabels) == 0 && len(selector.MatchExpressions) == 0 {
 		return true
 	}
+	// Plain matchLabels selectors are by far the most common; compare them
+	// directly instead of building a labels.Selector on every call.
+	if len(selector.MatchExpressions) == 0 {
+		for k, want := range selector.MatchLabels {
+			if got, ok := lbls[k]; !ok || got != want {
+				return false
+			}
+		}
+		return true
+	}
 	sel, err := metav1.LabelSelectorAsSelector(selector)
 	if err != nil {
 		return false
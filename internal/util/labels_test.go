@@ -50,6 +50,22 @@ func TestMatchesLabelSelector(t *testing.T) {
 			labels:   map[string]string{"version": "v1"},
 			expected: false,
 		},
+		{
+			name: "empty value requires the key",
+			selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"canary": ""},
+			},
+			labels:   map[string]string{"app": "foo"},
+			expected: false,
+		},
+		{
+			name: "empty value matches present key",
+			selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"canary": ""},
+			},
+			labels:   map[string]string{"canary": ""},
+			expected: true,
+		},
 		{
 			name: "nil labels with non-nil selector",
 			selector: &metav1.LabelSelector{
@@ -0,0 +1,29 @@
+// Package workload resolves Pods and other controlled objects to the
+// top-level workload that owns them.
+//
+// # Overview
+//
+// Warning Events and flow drops name a Pod such as api-7d9f-abcde, or its
+// ReplicaSet api-7d9f. Notifications, deduplication and annotations should
+// target the Deployment api instead, which outlives every Pod and is what
+// developers deploy. The Resolver follows controller owner references:
+//
+//	Pod ──► ReplicaSet ──► Deployment (or Argo Rollout)
+//	Pod ──► Job ──► CronJob
+//	Pod ──► StatefulSet, DaemonSet or any custom controller
+//
+// Pods, ReplicaSets and Jobs are walked through; an owner of any other kind,
+// including custom resources, is the top-level workload. Only metadata is
+// cached, via metadata informers, trimmed to names, UIDs and owner
+// references.
+//
+// # Usage
+//
+//	r := workload.NewResolver(metadataClient, logger)
+//	go r.Start(ctx)
+//
+//	top := r.ResolvePod("shop", "api-7d9f-abcde") // Deployment/api
+//
+// An object that is not cached, e.g. a Pod that was already deleted,
+// resolves to itself, and an owner that is not cached to the owner.
+package workload
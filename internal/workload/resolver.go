@@ -0,0 +1,184 @@
+package workload
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/metadata/metadatainformer"
+	"k8s.io/client-go/tools/cache"
+)
+
+// maxDepth bounds the owner chain walked, guarding against ownership cycles.
+const maxDepth = 8
+
+// Ref identifies a workload in a namespace.
+type Ref struct {
+	APIVersion string
+	Kind       string
+	Name       string
+	UID        types.UID
+}
+
+// String returns the workload as Kind/name.
+func (r Ref) String() string {
+	return r.Kind + "/" + r.Name
+}
+
+// intermediate is a kind that is usually owned by a workload, and whose
+// metadata is cached to follow its owner reference.
+type intermediate struct {
+	group string
+	kind  string
+	gvr   schema.GroupVersionResource
+}
+
+// intermediates are walked through: Pods to their ReplicaSet, Job or other
+// controller, ReplicaSets to their Deployment or Argo Rollout, and Jobs to
+// their CronJob. Owners of any other kind are top-level.
+var intermediates = []intermediate{
+	{kind: "Pod", gvr: schema.GroupVersionResource{Version: "v1", Resource: "pods"}},
+	{group: "apps", kind: "ReplicaSet", gvr: schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "replicasets"}},
+	{group: "batch", kind: "Job", gvr: schema.GroupVersionResource{Group: "batch", Version: "v1", Resource: "jobs"}},
+}
+
+// Resolver resolves objects to the top-level workload that controls them,
+// following controller owner references through cached object metadata.
+type Resolver struct {
+	logger    *zap.Logger
+	factory   metadatainformer.SharedInformerFactory
+	informers map[schema.GroupKind]cache.SharedIndexInformer
+}
+
+// NewResolver creates a Resolver. Call Start to fill its caches; until they
+// sync, objects resolve to themselves.
+func NewResolver(client metadata.Interface, logger *zap.Logger) *Resolver {
+	r := &Resolver{
+		logger:    logger.Named("workload-resolver"),
+		factory:   metadatainformer.NewSharedInformerFactory(client, 30*time.Minute),
+		informers: make(map[schema.GroupKind]cache.SharedIndexInformer, len(intermediates)),
+	}
+	for _, i := range intermediates {
+		informer := r.factory.ForResource(i.gvr).Informer()
+		if err := informer.SetTransform(trimMetadata); err != nil {
+			r.logger.Error("Failed to set informer transform", zap.String("gvr", i.gvr.String()), zap.Error(err))
+		}
+		r.informers[schema.GroupKind{Group: i.group, Kind: i.kind}] = informer
+	}
+	return r
+}
+
+// Start runs the informers. Blocks until ctx is cancelled.
+func (r *Resolver) Start(ctx context.Context) error {
+	r.logger.Info("Starting workload resolver")
+	r.factory.Start(ctx.Done())
+	for gvr, synced := range r.factory.WaitForCacheSync(ctx.Done()) {
+		if !synced && ctx.Err() == nil {
+			return fmt.Errorf("syncing %s metadata", gvr.Resource)
+		}
+	}
+	r.logger.Info("Workload resolver synced")
+	<-ctx.Done()
+	r.factory.Shutdown()
+	return nil
+}
+
+// HasSynced reports whether every informer has synced.
+func (r *Resolver) HasSynced() bool {
+	for _, informer := range r.informers {
+		if !informer.HasSynced() {
+			return false
+		}
+	}
+	return true
+}
+
+// WaitForSync blocks until every informer has synced. It returns false if
+// ctx is cancelled first. A nil Resolver is always synced.
+func (r *Resolver) WaitForSync(ctx context.Context) bool {
+	if r == nil {
+		return true
+	}
+	return cache.WaitForCacheSync(ctx.Done(), r.HasSynced)
+}
+
+// Resolve returns the top-level workload that controls obj in namespace,
+// e.g. the Deployment of a Pod's ReplicaSet, or obj itself when it has no
+// controller. An owner that is not cached, e.g. because it was deleted, is
+// returned as is. A nil Resolver returns obj.
+func (r *Resolver) Resolve(namespace string, obj Ref) Ref {
+	if r == nil {
+		return obj
+	}
+	current := obj
+	for depth := 0; depth < maxDepth; depth++ {
+		informer, ok := r.informerFor(current)
+		if !ok {
+			return current
+		}
+		item, exists, err := informer.GetIndexer().GetByKey(namespace + "/" + current.Name)
+		if err != nil || !exists {
+			return current
+		}
+		meta, ok := item.(*metav1.PartialObjectMetadata)
+		if !ok {
+			return current
+		}
+		// A cached object of the same name may be a later incarnation.
+		if current.UID != "" && meta.UID != current.UID {
+			return current
+		}
+		owner := metav1.GetControllerOfNoCopy(meta)
+		if owner == nil {
+			return Ref{APIVersion: current.APIVersion, Kind: current.Kind, Name: current.Name, UID: meta.UID}
+		}
+		current = Ref{APIVersion: owner.APIVersion, Kind: owner.Kind, Name: owner.Name, UID: owner.UID}
+	}
+	return current
+}
+
+// informerFor returns the informer of ref's kind. Without an API version,
+// e.g. in a Hubble workload reference, the kind alone is matched.
+func (r *Resolver) informerFor(ref Ref) (cache.SharedIndexInformer, bool) {
+	if ref.APIVersion == "" {
+		for gk, informer := range r.informers {
+			if gk.Kind == ref.Kind {
+				return informer, true
+			}
+		}
+		return nil, false
+	}
+	gk := schema.FromAPIVersionAndKind(ref.APIVersion, ref.Kind).GroupKind()
+	informer, ok := r.informers[gk]
+	return informer, ok
+}
+
+// ResolvePod returns the top-level workload that controls the Pod. A nil
+// Resolver returns the Pod.
+func (r *Resolver) ResolvePod(namespace, name string) Ref {
+	return r.Resolve(namespace, Ref{APIVersion: "v1", Kind: "Pod", Name: name})
+}
+
+// trimMetadata keeps only what the owner chain needs, so the caches hold a
+// name, UID and owner references per object.
+func trimMetadata(obj interface{}) (interface{}, error) {
+	meta, ok := obj.(*metav1.PartialObjectMetadata)
+	if !ok {
+		return obj, nil
+	}
+	return &metav1.PartialObjectMetadata{
+		TypeMeta: meta.TypeMeta,
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            meta.Name,
+			Namespace:       meta.Namespace,
+			UID:             meta.UID,
+			ResourceVersion: meta.ResourceVersion,
+			OwnerReferences: meta.OwnerReferences,
+		},
+	}, nil
+}
@@ -0,0 +1,121 @@
+package workload
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	"k8s.io/utils/ptr"
+)
+
+func object(apiVersion, kind, name string, owner *Ref) *metav1.PartialObjectMetadata {
+	obj := &metav1.PartialObjectMetadata{
+		TypeMeta:   metav1.TypeMeta{APIVersion: apiVersion, Kind: kind},
+		ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: name, UID: types.UID(name + "-uid")},
+	}
+	if owner != nil {
+		obj.OwnerReferences = []metav1.OwnerReference{{
+			APIVersion: owner.APIVersion,
+			Kind:       owner.Kind,
+			Name:       owner.Name,
+			UID:        owner.UID,
+			Controller: ptr.To(true),
+		}}
+	}
+	return obj
+}
+
+func newTestResolver(t *testing.T, objects ...runtime.Object) *Resolver {
+	t.Helper()
+	scheme := metadatafake.NewTestScheme()
+	require.NoError(t, metav1.AddMetaToScheme(scheme))
+	r := NewResolver(metadatafake.NewSimpleMetadataClient(scheme, objects...), zap.NewNop())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- r.Start(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		require.NoError(t, <-done)
+	})
+	require.Eventually(t, r.HasSynced, 2*time.Second, 10*time.Millisecond)
+	return r
+}
+
+func TestResolver_Resolve(t *testing.T) {
+	deployment := &Ref{APIVersion: "apps/v1", Kind: "Deployment", Name: "api", UID: "api-uid"}
+	rollout := &Ref{APIVersion: "argoproj.io/v1alpha1", Kind: "Rollout", Name: "checkout", UID: "checkout-uid"}
+	cronJob := &Ref{APIVersion: "batch/v1", Kind: "CronJob", Name: "nightly", UID: "nightly-uid"}
+	r := newTestResolver(t,
+		object("apps/v1", "ReplicaSet", "api-7d9f", deployment),
+		object("v1", "Pod", "api-7d9f-abcde", &Ref{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "api-7d9f", UID: "api-7d9f-uid"}),
+		object("apps/v1", "ReplicaSet", "checkout-5c8b", rollout),
+		object("v1", "Pod", "checkout-5c8b-xyz12", &Ref{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "checkout-5c8b", UID: "checkout-5c8b-uid"}),
+		object("batch/v1", "Job", "nightly-28001", cronJob),
+		object("v1", "Pod", "nightly-28001-q7k2p", &Ref{APIVersion: "batch/v1", Kind: "Job", Name: "nightly-28001", UID: "nightly-28001-uid"}),
+		object("v1", "Pod", "debug", nil),
+		object("v1", "Pod", "orphaned-rs-pod", &Ref{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "deleted", UID: "deleted-uid"}),
+	)
+
+	tests := []struct {
+		name string
+		obj  Ref
+		want Ref
+	}{
+		{name: "pod of deployment", obj: Ref{APIVersion: "v1", Kind: "Pod", Name: "api-7d9f-abcde"}, want: *deployment},
+		{name: "replicaset of deployment", obj: Ref{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "api-7d9f"}, want: *deployment},
+		{name: "pod of argo rollout", obj: Ref{APIVersion: "v1", Kind: "Pod", Name: "checkout-5c8b-xyz12"}, want: *rollout},
+		{name: "pod of cronjob", obj: Ref{APIVersion: "v1", Kind: "Pod", Name: "nightly-28001-q7k2p"}, want: *cronJob},
+		{name: "job without api version", obj: Ref{Kind: "Job", Name: "nightly-28001"}, want: *cronJob},
+		{name: "bare pod", obj: Ref{APIVersion: "v1", Kind: "Pod", Name: "debug"}, want: Ref{APIVersion: "v1", Kind: "Pod", Name: "debug", UID: "debug-uid"}},
+		{name: "uncached owner", obj: Ref{APIVersion: "v1", Kind: "Pod", Name: "orphaned-rs-pod"}, want: Ref{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "deleted", UID: "deleted-uid"}},
+		{name: "uncached pod", obj: Ref{APIVersion: "v1", Kind: "Pod", Name: "gone"}, want: Ref{APIVersion: "v1", Kind: "Pod", Name: "gone"}},
+		{name: "top-level kind", obj: Ref{APIVersion: "apps/v1", Kind: "StatefulSet", Name: "db"}, want: Ref{APIVersion: "apps/v1", Kind: "StatefulSet", Name: "db"}},
+		{name: "recreated pod", obj: Ref{APIVersion: "v1", Kind: "Pod", Name: "api-7d9f-abcde", UID: "old-uid"}, want: Ref{APIVersion: "v1", Kind: "Pod", Name: "api-7d9f-abcde", UID: "old-uid"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, r.Resolve("shop", tt.obj))
+		})
+	}
+
+	assert.Equal(t, *deployment, r.ResolvePod("shop", "api-7d9f-abcde"))
+	assert.Equal(t, Ref{APIVersion: "v1", Kind: "Pod", Name: "api-7d9f-abcde"}, r.ResolvePod("other", "api-7d9f-abcde"))
+}
+
+func TestResolver_Cycle(t *testing.T) {
+	r := newTestResolver(t,
+		object("apps/v1", "ReplicaSet", "a", &Ref{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "b", UID: "b-uid"}),
+		object("apps/v1", "ReplicaSet", "b", &Ref{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "a", UID: "a-uid"}),
+	)
+
+	got := r.Resolve("shop", Ref{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "a"})
+	assert.Equal(t, "ReplicaSet", got.Kind)
+}
+
+func TestResolver_Nil(t *testing.T) {
+	var r *Resolver
+	pod := Ref{APIVersion: "v1", Kind: "Pod", Name: "api-7d9f-abcde"}
+	assert.Equal(t, pod, r.Resolve("shop", pod))
+}
+
+func TestTrimMetadata(t *testing.T) {
+	obj := object("v1", "Pod", "api", &Ref{Kind: "ReplicaSet", Name: "api-7d9f"})
+	obj.Labels = map[string]string{"app": "api"}
+	obj.ManagedFields = []metav1.ManagedFieldsEntry{{Manager: "kubectl"}}
+
+	trimmed, err := trimMetadata(obj)
+	require.NoError(t, err)
+	meta := trimmed.(*metav1.PartialObjectMetadata)
+	assert.Equal(t, "api", meta.Name)
+	assert.Len(t, meta.OwnerReferences, 1)
+	assert.Nil(t, meta.Labels)
+	assert.Nil(t, meta.ManagedFields)
+}
@@ -0,0 +1,238 @@
+// Package conformance verifies that a running adapter plugin implements the
+// Nightjar adapter plugin protocol correctly.
+//
+// Plugin authors call Run from a test after starting their plugin on a Unix
+// socket:
+//
+//	func TestConformance(t *testing.T) {
+//		socket := startPlugin(t)
+//		conformance.Run(t, conformance.Options{
+//			Socket:   socket,
+//			Fixtures: []*unstructured.Unstructured{loadFixture(t, "testdata/policy.yaml")},
+//		})
+//	}
+package conformance
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/nightjarctl/nightjar/pkg/adapterplugin"
+)
+
+// unsupportedVersion is a protocol version no plugin is expected to speak.
+const unsupportedVersion = 1 << 20
+
+var validConstraintTypes = map[string]bool{
+	"NetworkIngress": true, "NetworkEgress": true, "Admission": true,
+	"ResourceLimit": true, "MeshPolicy": true, "MissingResource": true, "Unknown": true,
+}
+
+var validSeverities = map[string]bool{"Critical": true, "Warning": true, "Info": true}
+
+// Options configures a conformance run.
+type Options struct {
+	// Socket is the Unix socket the plugin listens on.
+	Socket string
+
+	// Fixtures are objects of the handled types. Each must parse into at
+	// least one valid constraint.
+	Fixtures []*unstructured.Unstructured
+
+	// Timeout bounds every call. The controller applies the same kind of
+	// limit, so slow plugins fail here rather than in production.
+	// Default: 5 seconds.
+	Timeout time.Duration
+}
+
+// Run executes the conformance suite as subtests of t.
+func Run(t *testing.T, opts Options) {
+	t.Helper()
+	if opts.Timeout == 0 {
+		opts.Timeout = 5 * time.Second
+	}
+
+	conn, err := grpc.NewClient("unix://"+opts.Socket, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("dialing %s: %v", opts.Socket, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	s := &suite{
+		opts:   opts,
+		client: adapterplugin.NewAdapterPluginClient(conn),
+		health: healthpb.NewHealthClient(conn),
+	}
+
+	t.Run("Handshake", s.testHandshake)
+	t.Run("HandshakeRejectsUnknownVersion", s.testHandshakeRejectsUnknownVersion)
+	t.Run("Health", s.testHealth)
+	t.Run("ParseFixtures", s.testParseFixtures)
+	t.Run("ParseIsDeterministic", s.testParseIsDeterministic)
+	t.Run("ParseRejectsUnknownVersion", s.testParseRejectsUnknownVersion)
+	t.Run("ParseRejectsEmptyObject", s.testParseRejectsEmptyObject)
+	t.Run("HealthyAfterErrors", s.testHealth)
+}
+
+type suite struct {
+	opts   Options
+	client adapterplugin.AdapterPluginClient
+	health healthpb.HealthClient
+}
+
+func (s *suite) ctx(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), s.opts.Timeout)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func (s *suite) handshake(t *testing.T) *adapterplugin.HandshakeResponse {
+	t.Helper()
+	resp, err := s.client.Handshake(s.ctx(t), &adapterplugin.HandshakeRequest{
+		ProtocolVersions:  adapterplugin.SupportedProtocolVersions,
+		ControllerVersion: "conformance",
+	})
+	if err != nil {
+		t.Fatalf("Handshake: %v", err)
+	}
+	return resp
+}
+
+func (s *suite) parse(t *testing.T, version int, obj *unstructured.Unstructured) (*adapterplugin.ParseResponse, error) {
+	t.Helper()
+	raw, err := obj.MarshalJSON()
+	if err != nil {
+		t.Fatalf("encoding fixture %s: %v", obj.GetName(), err)
+	}
+	return s.client.Parse(s.ctx(t), &adapterplugin.ParseRequest{
+		ProtocolVersion: version,
+		Object:          raw,
+	})
+}
+
+func (s *suite) testHandshake(t *testing.T) {
+	resp := s.handshake(t)
+
+	if _, ok := adapterplugin.NegotiateVersion([]int{resp.ProtocolVersion}, adapterplugin.SupportedProtocolVersions); !ok {
+		t.Errorf("selected protocol version %d, want one of %v", resp.ProtocolVersion, adapterplugin.SupportedProtocolVersions)
+	}
+	if resp.Name == "" {
+		t.Error("adapter name is empty")
+	}
+	if len(resp.Handles) == 0 {
+		t.Error("plugin handles no GVRs")
+	}
+	for _, gvr := range resp.Handles {
+		if gvr.Version == "" || gvr.Resource == "" {
+			t.Errorf("handled GVR %+v must set version and resource", gvr)
+		}
+	}
+}
+
+func (s *suite) testHandshakeRejectsUnknownVersion(t *testing.T) {
+	_, err := s.client.Handshake(s.ctx(t), &adapterplugin.HandshakeRequest{
+		ProtocolVersions: []int{unsupportedVersion},
+	})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Handshake with unknown version: got %v, want FailedPrecondition", err)
+	}
+}
+
+func (s *suite) testHealth(t *testing.T) {
+	resp, err := s.health.Check(s.ctx(t), &healthpb.HealthCheckRequest{Service: adapterplugin.ServiceName})
+	if err != nil {
+		t.Fatalf("health check: %v", err)
+	}
+	if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("health status %s, want SERVING", resp.GetStatus())
+	}
+}
+
+func (s *suite) testParseFixtures(t *testing.T) {
+	if len(s.opts.Fixtures) == 0 {
+		t.Skip("no fixtures provided")
+	}
+	version := s.handshake(t).ProtocolVersion
+
+	for _, obj := range s.opts.Fixtures {
+		resp, err := s.parse(t, version, obj)
+		if err != nil {
+			t.Errorf("Parse %s: %v", obj.GetName(), err)
+			continue
+		}
+		if len(resp.Constraints) == 0 {
+			t.Errorf("Parse %s returned no constraints", obj.GetName())
+		}
+
+		uids := map[string]bool{}
+		for i, c := range resp.Constraints {
+			if c.Name == "" {
+				t.Errorf("%s: constraint %d has no name", obj.GetName(), i)
+			}
+			if !validConstraintTypes[c.ConstraintType] {
+				t.Errorf("%s: constraint %q has invalid constraintType %q", obj.GetName(), c.Name, c.ConstraintType)
+			}
+			if !validSeverities[c.Severity] {
+				t.Errorf("%s: constraint %q has invalid severity %q", obj.GetName(), c.Name, c.Severity)
+			}
+			if c.UID != "" {
+				if uids[c.UID] {
+					t.Errorf("%s: duplicate constraint uid %q", obj.GetName(), c.UID)
+				}
+				uids[c.UID] = true
+			}
+		}
+	}
+}
+
+func (s *suite) testParseIsDeterministic(t *testing.T) {
+	if len(s.opts.Fixtures) == 0 {
+		t.Skip("no fixtures provided")
+	}
+	version := s.handshake(t).ProtocolVersion
+
+	for _, obj := range s.opts.Fixtures {
+		first, err1 := s.parse(t, version, obj)
+		second, err2 := s.parse(t, version, obj)
+		if err1 != nil || err2 != nil {
+			t.Errorf("Parse %s: %v / %v", obj.GetName(), err1, err2)
+			continue
+		}
+		a, _ := json.Marshal(first)
+		b, _ := json.Marshal(second)
+		if string(a) != string(b) {
+			t.Errorf("Parse %s is not deterministic:\n%s\n%s", obj.GetName(), a, b)
+		}
+	}
+}
+
+func (s *suite) testParseRejectsUnknownVersion(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "example.com/v1", "kind": "Example",
+		"metadata": map[string]interface{}{"name": "conformance"},
+	}}
+	if len(s.opts.Fixtures) > 0 {
+		obj = s.opts.Fixtures[0]
+	}
+	_, err := s.parse(t, unsupportedVersion, obj)
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Parse with unknown version: got %v, want FailedPrecondition", err)
+	}
+}
+
+func (s *suite) testParseRejectsEmptyObject(t *testing.T) {
+	version := s.handshake(t).ProtocolVersion
+	_, err := s.client.Parse(s.ctx(t), &adapterplugin.ParseRequest{ProtocolVersion: version})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Parse of empty object: got %v, want InvalidArgument", err)
+	}
+}
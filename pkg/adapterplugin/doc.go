@@ -0,0 +1,35 @@
+// Package adapterplugin defines the out-of-process adapter protocol and an SDK
+// for writing adapter plugins.
+//
+// An adapter plugin is a separate process — typically a sidecar sharing a
+// socket directory with the controller — that parses policy objects Nightjar
+// cannot parse in-tree. The controller wraps each plugin in a types.Adapter
+// and registers it in the adapter registry, so plugins take part in discovery
+// exactly like built-in adapters.
+//
+// # Transport
+//
+// gRPC over a Unix domain socket. The AdapterPlugin service
+// (nightjar.adapter.v1.AdapterPlugin) uses JSON-encoded messages (content
+// subtype "json") so plugins need no generated protobuf code. Plugins must
+// also serve the standard grpc.health.v1.Health service; the controller checks
+// the AdapterPlugin service name periodically.
+//
+// # Methods
+//
+//   - Handshake: the controller offers the protocol versions it supports and
+//     the plugin answers with the highest common version, its adapter name and
+//     the GVRs it handles. No common version is a FailedPrecondition error.
+//   - Parse: one Kubernetes object in, zero or more Constraints out. Parse
+//     errors are returned as InvalidArgument.
+//
+// # Writing a plugin
+//
+// Implement Handler and call Serve:
+//
+//	srv := adapterplugin.NewServer(myHandler{}, adapterplugin.ServerOptions{AdapterVersion: "1.0.0"})
+//	if err := srv.Serve(ctx, "/var/run/nightjar/plugins/acme.sock"); err != nil { ... }
+//
+// The conformance subpackage verifies a running plugin against the protocol.
+// See cmd/nightjar-adapter-example for a complete reference plugin.
+package adapterplugin
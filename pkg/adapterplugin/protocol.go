@@ -0,0 +1,140 @@
+package adapterplugin
+
+import (
+	"encoding/json"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ProtocolVersion is the newest protocol version implemented by this package.
+const ProtocolVersion = 1
+
+// SupportedProtocolVersions lists every protocol version this package can
+// speak, newest first.
+var SupportedProtocolVersions = []int{ProtocolVersion}
+
+// ServiceName is the fully-qualified gRPC service name, also used as the
+// service name for grpc.health.v1 checks.
+const ServiceName = "nightjar.adapter.v1.AdapterPlugin"
+
+// GVR identifies a Kubernetes resource type on the wire.
+type GVR struct {
+	Group    string `json:"group"`
+	Version  string `json:"version"`
+	Resource string `json:"resource"`
+}
+
+// ToSchema converts the wire GVR to a schema.GroupVersionResource.
+func (g GVR) ToSchema() schema.GroupVersionResource {
+	return schema.GroupVersionResource{Group: g.Group, Version: g.Version, Resource: g.Resource}
+}
+
+// FromSchema converts a schema.GroupVersionResource to its wire form.
+func FromSchema(gvr schema.GroupVersionResource) GVR {
+	return GVR{Group: gvr.Group, Version: gvr.Version, Resource: gvr.Resource}
+}
+
+// HandshakeRequest is sent by the controller when it connects to a plugin.
+type HandshakeRequest struct {
+	// ProtocolVersions lists the versions the controller supports.
+	ProtocolVersions []int `json:"protocolVersions"`
+
+	// ControllerVersion is informational.
+	ControllerVersion string `json:"controllerVersion,omitempty"`
+}
+
+// HandshakeResponse describes the plugin.
+type HandshakeResponse struct {
+	// ProtocolVersion is the version selected by the plugin.
+	ProtocolVersion int `json:"protocolVersion"`
+
+	// Name is the adapter name. It must be unique across all adapters.
+	Name string `json:"name"`
+
+	// Handles lists the GVRs the adapter parses.
+	Handles []GVR `json:"handles"`
+
+	// AdapterVersion is informational.
+	AdapterVersion string `json:"adapterVersion,omitempty"`
+}
+
+// ParseRequest asks the plugin to parse one object.
+type ParseRequest struct {
+	// ProtocolVersion is the version agreed during the handshake.
+	ProtocolVersion int `json:"protocolVersion"`
+
+	// GVR is the resource the object was read from.
+	GVR GVR `json:"gvr"`
+
+	// Object is the JSON-encoded Kubernetes object.
+	Object json.RawMessage `json:"object"`
+}
+
+// ParseResponse carries the constraints produced from one object.
+type ParseResponse struct {
+	Constraints []Constraint `json:"constraints"`
+}
+
+// Constraint is the wire form of a Nightjar constraint. Fields mirror the
+// controller's internal constraint model; the controller fills in the source
+// GVR and the raw object.
+type Constraint struct {
+	// UID must be unique per constraint. Defaults to the object UID, suffixed
+	// with "-{index}" when an object produces several constraints.
+	UID string `json:"uid,omitempty"`
+
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+
+	AffectedNamespaces []string              `json:"affectedNamespaces,omitempty"`
+	NamespaceSelector  *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	WorkloadSelector   *metav1.LabelSelector `json:"workloadSelector,omitempty"`
+	ResourceTargets    []ResourceTarget      `json:"resourceTargets,omitempty"`
+
+	// ConstraintType is one of NetworkIngress, NetworkEgress, Admission,
+	// ResourceLimit, MeshPolicy, MissingResource or Unknown.
+	ConstraintType string `json:"constraintType"`
+	Effect         string `json:"effect,omitempty"`
+
+	// Severity is one of Critical, Warning or Info.
+	Severity string `json:"severity"`
+
+	Details         map[string]interface{} `json:"details,omitempty"`
+	Summary         string                 `json:"summary,omitempty"`
+	RemediationHint string                 `json:"remediationHint,omitempty"`
+	Remediation     []RemediationStep      `json:"remediation,omitempty"`
+	Tags            []string               `json:"tags,omitempty"`
+}
+
+// ResourceTarget identifies resource types an admission constraint applies to.
+type ResourceTarget struct {
+	APIGroups []string `json:"apiGroups,omitempty"`
+	Resources []string `json:"resources,omitempty"`
+}
+
+// RemediationStep is a single actionable step to resolve a constraint issue.
+type RemediationStep struct {
+	Type              string `json:"type"`
+	Description       string `json:"description"`
+	Command           string `json:"command,omitempty"`
+	Patch             string `json:"patch,omitempty"`
+	Template          string `json:"template,omitempty"`
+	URL               string `json:"url,omitempty"`
+	Contact           string `json:"contact,omitempty"`
+	RequiresPrivilege string `json:"requiresPrivilege,omitempty"`
+}
+
+// NegotiateVersion returns the highest version present in both offered and
+// supported.
+func NegotiateVersion(offered, supported []int) (int, bool) {
+	best, ok := 0, false
+	for _, o := range offered {
+		for _, s := range supported {
+			if o == s && (!ok || o > best) {
+				best, ok = o, true
+			}
+		}
+	}
+	return best, ok
+}
@@ -0,0 +1,32 @@
+package adapterplugin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiateVersion(t *testing.T) {
+	tests := []struct {
+		name      string
+		offered   []int
+		supported []int
+		want      int
+		wantOK    bool
+	}{
+		{"exact match", []int{1}, []int{1}, 1, true},
+		{"highest common", []int{1, 2, 3}, []int{3, 2}, 3, true},
+		{"order independent", []int{2, 1}, []int{1, 2}, 2, true},
+		{"no overlap", []int{2}, []int{1}, 0, false},
+		{"nothing offered", nil, []int{1}, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := NegotiateVersion(tt.offered, tt.supported)
+			assert.Equal(t, tt.wantOK, ok)
+			if ok {
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
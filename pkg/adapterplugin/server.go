@@ -0,0 +1,148 @@
+package adapterplugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Handler is implemented by plugin authors. It mirrors the controller's
+// in-tree adapter interface.
+type Handler interface {
+	// Name returns the adapter name. It must be unique across all adapters.
+	Name() string
+
+	// Handles returns the GVRs this adapter parses.
+	Handles() []schema.GroupVersionResource
+
+	// Parse converts one object into constraints.
+	Parse(ctx context.Context, obj *unstructured.Unstructured) ([]Constraint, error)
+}
+
+// ServerOptions configures a plugin Server.
+type ServerOptions struct {
+	// AdapterVersion is reported during the handshake. Informational.
+	AdapterVersion string
+
+	// ProtocolVersions overrides the protocol versions the server accepts.
+	// Default: SupportedProtocolVersions.
+	ProtocolVersions []int
+}
+
+// Server serves a Handler over the AdapterPlugin protocol.
+type Server struct {
+	handler Handler
+	opts    ServerOptions
+	health  *health.Server
+}
+
+// NewServer creates a plugin server for h.
+func NewServer(h Handler, opts ServerOptions) *Server {
+	if len(opts.ProtocolVersions) == 0 {
+		opts.ProtocolVersions = SupportedProtocolVersions
+	}
+	return &Server{
+		handler: h,
+		opts:    opts,
+		health:  health.NewServer(),
+	}
+}
+
+// Register registers the AdapterPlugin and health services on gs. Use this to
+// serve on a custom listener; Serve does it for Unix sockets.
+func (s *Server) Register(gs *grpc.Server) {
+	RegisterAdapterPluginServer(gs, s)
+	healthpb.RegisterHealthServer(gs, s.health)
+	s.health.SetServingStatus(ServiceName, healthpb.HealthCheckResponse_SERVING)
+}
+
+// SetServing updates the health status reported for the AdapterPlugin service.
+func (s *Server) SetServing(serving bool) {
+	st := healthpb.HealthCheckResponse_SERVING
+	if !serving {
+		st = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	s.health.SetServingStatus(ServiceName, st)
+}
+
+// Serve listens on the Unix socket at socketPath and serves until ctx is
+// cancelled. A stale socket file left by a previous run is removed.
+func (s *Server) Serve(ctx context.Context, socketPath string) error {
+	if err := os.Remove(socketPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("removing stale socket %s: %w", socketPath, err)
+	}
+	lis, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return fmt.Errorf("listening on %s: %w", socketPath, err)
+	}
+	defer os.Remove(socketPath)
+
+	gs := grpc.NewServer()
+	s.Register(gs)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- gs.Serve(lis)
+	}()
+
+	select {
+	case <-ctx.Done():
+		s.health.Shutdown()
+		gs.GracefulStop()
+		return nil
+	case err := <-errCh:
+		return err
+	}
+}
+
+// Handshake implements AdapterPluginServer.
+func (s *Server) Handshake(_ context.Context, req *HandshakeRequest) (*HandshakeResponse, error) {
+	version, ok := NegotiateVersion(req.ProtocolVersions, s.opts.ProtocolVersions)
+	if !ok {
+		return nil, status.Errorf(codes.FailedPrecondition,
+			"no common protocol version: controller offers %v, plugin supports %v",
+			req.ProtocolVersions, s.opts.ProtocolVersions)
+	}
+
+	handles := make([]GVR, 0, len(s.handler.Handles()))
+	for _, gvr := range s.handler.Handles() {
+		handles = append(handles, FromSchema(gvr))
+	}
+
+	return &HandshakeResponse{
+		ProtocolVersion: version,
+		Name:            s.handler.Name(),
+		Handles:         handles,
+		AdapterVersion:  s.opts.AdapterVersion,
+	}, nil
+}
+
+// Parse implements AdapterPluginServer.
+func (s *Server) Parse(ctx context.Context, req *ParseRequest) (*ParseResponse, error) {
+	if _, ok := NegotiateVersion([]int{req.ProtocolVersion}, s.opts.ProtocolVersions); !ok {
+		return nil, status.Errorf(codes.FailedPrecondition, "unsupported protocol version %d", req.ProtocolVersion)
+	}
+	if len(req.Object) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "object is empty")
+	}
+
+	obj := &unstructured.Unstructured{}
+	if err := obj.UnmarshalJSON(req.Object); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "decoding object: %v", err)
+	}
+	constraints, err := s.handler.Parse(ctx, obj)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &ParseResponse{Constraints: constraints}, nil
+}
@@ -0,0 +1,92 @@
+package adapterplugin
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+// AdapterPluginServer is the server API of the AdapterPlugin service.
+type AdapterPluginServer interface {
+	Handshake(context.Context, *HandshakeRequest) (*HandshakeResponse, error)
+	Parse(context.Context, *ParseRequest) (*ParseResponse, error)
+}
+
+// AdapterPluginClient is the client API of the AdapterPlugin service.
+type AdapterPluginClient interface {
+	Handshake(ctx context.Context, in *HandshakeRequest, opts ...grpc.CallOption) (*HandshakeResponse, error)
+	Parse(ctx context.Context, in *ParseRequest, opts ...grpc.CallOption) (*ParseResponse, error)
+}
+
+// RegisterAdapterPluginServer registers srv on s.
+func RegisterAdapterPluginServer(s grpc.ServiceRegistrar, srv AdapterPluginServer) {
+	s.RegisterService(&serviceDesc, srv)
+}
+
+// NewAdapterPluginClient returns a client for the AdapterPlugin service.
+func NewAdapterPluginClient(cc grpc.ClientConnInterface) AdapterPluginClient {
+	return &adapterPluginClient{cc: cc}
+}
+
+type adapterPluginClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func (c *adapterPluginClient) Handshake(ctx context.Context, in *HandshakeRequest, opts ...grpc.CallOption) (*HandshakeResponse, error) {
+	out := new(HandshakeResponse)
+	opts = append([]grpc.CallOption{grpc.CallContentSubtype(codecName)}, opts...)
+	if err := c.cc.Invoke(ctx, "/"+ServiceName+"/Handshake", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adapterPluginClient) Parse(ctx context.Context, in *ParseRequest, opts ...grpc.CallOption) (*ParseResponse, error) {
+	out := new(ParseResponse)
+	opts = append([]grpc.CallOption{grpc.CallContentSubtype(codecName)}, opts...)
+	if err := c.cc.Invoke(ctx, "/"+ServiceName+"/Parse", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func handshakeHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HandshakeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdapterPluginServer).Handshake(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + ServiceName + "/Handshake"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdapterPluginServer).Handshake(ctx, req.(*HandshakeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func parseHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ParseRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdapterPluginServer).Parse(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + ServiceName + "/Parse"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdapterPluginServer).Parse(ctx, req.(*ParseRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: ServiceName,
+	HandlerType: (*AdapterPluginServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "Handshake", Handler: handshakeHandler},
+		{MethodName: "Parse", Handler: parseHandler},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "nightjar/adapter/v1/plugin",
+}